@@ -514,6 +514,32 @@ const calculateROIScore = (targetURL) => {
     breakdown.push({ label: 'Non-standard port (potential dev/staging environment)', points: 10, category: 'infra' });
   }
 
+  if (targetURL.edge_blocking) {
+    score -= 10;
+    breakdown.push({ label: `WAF blocks attack payloads (${targetURL.waf_provider || targetURL.edge_provider})`, points: -10, category: 'infra' });
+  } else if (targetURL.edge_provider && !targetURL.waf_provider) {
+    score += 5;
+    breakdown.push({ label: `Behind ${targetURL.edge_provider} CDN without a WAF`, points: 5, category: 'infra' });
+  } else if (targetURL.edge_checked && !targetURL.edge_provider) {
+    score += 10;
+    breakdown.push({ label: 'No CDN/WAF in front (payloads reach the application directly)', points: 10, category: 'infra' });
+  }
+
+  if (targetURL.kev_count > 0) {
+    score += 25;
+    breakdown.push({ label: `Runs software with ${targetURL.kev_count} known exploited CVE(s) (CISA KEV)`, points: 25, category: 'tech' });
+  }
+  if (targetURL.max_cvss >= 9) {
+    score += 15;
+    breakdown.push({ label: `Critical CVE for detected version (CVSS ${targetURL.max_cvss})`, points: 15, category: 'tech' });
+  } else if (targetURL.max_cvss >= 7) {
+    score += 10;
+    breakdown.push({ label: `High severity CVE for detected version (CVSS ${targetURL.max_cvss})`, points: 10, category: 'tech' });
+  } else if (targetURL.vuln_count > 0) {
+    score += 5;
+    breakdown.push({ label: `${targetURL.vuln_count} CVE(s) match detected versions`, points: 5, category: 'tech' });
+  }
+
   const sslChecks = [
     ['has_deprecated_tls', 'Deprecated TLS'],
     ['has_expired_ssl', 'Expired SSL'],
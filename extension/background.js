@@ -4,6 +4,7 @@ let captureSession = {
   active: false,
   tabId: null,
   sessionId: null,
+  captureToken: null,
   scopeTargetId: null,
   settings: null,
   stats: {
@@ -144,7 +145,8 @@ async function startCaptureSession(settings) {
     console.log('[MANUAL-CRAWL] Notifying framework at:', frameworkApiUrl);
     const notifyResult = await notifyFramework('start', { 
       targetUrl: settings.targetUrl,
-      scopeTargetId: settings.scopeTargetId
+      scopeTargetId: settings.scopeTargetId,
+      label: settings.label || ''
     });
     console.log('[MANUAL-CRAWL] ✓ Framework notified, result:', notifyResult);
     
@@ -153,6 +155,7 @@ async function startCaptureSession(settings) {
     }
     
     captureSession.sessionId = notifyResult.data.sessionId;
+    captureSession.captureToken = notifyResult.data.captureToken;
     captureSession.scopeTargetId = notifyResult.data.scopeTargetId || settings.scopeTargetId;
     console.log('[MANUAL-CRAWL] ✓ Session ID:', captureSession.sessionId);
     console.log('[MANUAL-CRAWL] ✓ Scope Target ID:', captureSession.scopeTargetId);
@@ -195,6 +198,7 @@ async function startCaptureSession(settings) {
     captureSession.active = false;
     captureSession.tabId = null;
     captureSession.sessionId = null;
+    captureSession.captureToken = null;
     captureSession.scopeTargetId = null;
     captureSession.settings = null;
     
@@ -214,6 +218,7 @@ async function stopCaptureSession() {
     
     console.log('[MANUAL-CRAWL] Notifying framework of stop...');
     await notifyFramework('stop', { 
+      captureToken: captureSession.captureToken,
       stats: captureSession.stats 
     });
     
@@ -223,6 +228,7 @@ async function stopCaptureSession() {
     captureSession.active = false;
     captureSession.tabId = null;
     captureSession.sessionId = null;
+    captureSession.captureToken = null;
     captureSession.scopeTargetId = null;
     captureSession.settings = null;
     captureSession.capturedEndpoints = new Set();
@@ -449,6 +455,7 @@ async function processAndSendRequest(request) {
     const contentType = request.headers?.['content-type'] || request.headers?.['Content-Type'] || '';
     
     const captureData = {
+      scopeTargetId: captureSession.scopeTargetId,
       url: request.url,
       endpoint: endpoint,
       method: request.method,
@@ -569,6 +576,7 @@ async function sendToFramework(data) {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-Capture-Token': captureSession.captureToken || '',
       },
       body: JSON.stringify(data)
     });
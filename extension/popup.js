@@ -33,17 +33,20 @@ function initializeEventListeners() {
   
   document.getElementById('includeSubdomains').addEventListener('change', saveSettings);
   document.getElementById('captureStatic').addEventListener('change', saveSettings);
+  document.getElementById('sessionLabel').addEventListener('change', saveSettings);
 }
 
 async function loadSettings() {
   const result = await chrome.storage.local.get([
     'includeSubdomains', 
     'captureStatic', 
+    'sessionLabel',
     'frameworkUrl'
   ]);
   
   document.getElementById('includeSubdomains').checked = result.includeSubdomains !== false;
   document.getElementById('captureStatic').checked = result.captureStatic || false;
+  document.getElementById('sessionLabel').value = result.sessionLabel || '';
   
   frameworkUrl = result.frameworkUrl || 'http://localhost';
   document.getElementById('frameworkUrl').value = frameworkUrl;
@@ -52,7 +55,8 @@ async function loadSettings() {
 async function saveSettings() {
   const settings = {
     includeSubdomains: document.getElementById('includeSubdomains').checked,
-    captureStatic: document.getElementById('captureStatic').checked
+    captureStatic: document.getElementById('captureStatic').checked,
+    sessionLabel: document.getElementById('sessionLabel').value.trim()
   };
   
   await chrome.storage.local.set(settings);
@@ -286,6 +290,7 @@ async function startCapture() {
     const settings = {
       targetUrl: targetUrl,
       scopeTargetId: scopeTargetId,
+      label: document.getElementById('sessionLabel').value.trim(),
       includeSubdomains: document.getElementById('includeSubdomains').checked,
       captureStatic: document.getElementById('captureStatic').checked
     };
@@ -0,0 +1,394 @@
+// Package asndb resolves IP addresses to their origin ASN, organization and
+// country from public datasets held in memory, without network lookups.
+package asndb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ars0n-framework-v2-server/iptrie"
+)
+
+// Record is what is known about the holder of a prefix. Prefixes only found in
+// RIR delegated files have a registry and country but no ASN.
+type Record struct {
+	ASN      uint32 `json:"asn,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Registry string `json:"registry,omitempty"`
+}
+
+// ASNString formats the ASN the way the online lookups return it.
+func (r Record) ASNString() string {
+	if r.ASN == 0 {
+		return ""
+	}
+	return "AS" + strconv.FormatUint(uint64(r.ASN), 10)
+}
+
+// Source is a dataset file and where a refresh downloads it from.
+type Source struct {
+	File string
+	URL  string
+}
+
+// Sources are read in this order, so the routed prefixes of iptoasn override the
+// broader RIR allocations they fall in.
+var Sources = []Source{
+	{File: "delegated-arin-extended-latest", URL: "https://ftp.arin.net/pub/stats/arin/delegated-arin-extended-latest"},
+	{File: "delegated-ripencc-extended-latest", URL: "https://ftp.ripe.net/pub/stats/ripencc/delegated-ripencc-extended-latest"},
+	{File: "delegated-apnic-extended-latest", URL: "https://ftp.apnic.net/stats/apnic/delegated-apnic-extended-latest"},
+	{File: "delegated-lacnic-extended-latest", URL: "https://ftp.lacnic.net/pub/stats/lacnic/delegated-lacnic-extended-latest"},
+	{File: "delegated-afrinic-extended-latest", URL: "https://ftp.afrinic.net/pub/stats/afrinic/delegated-afrinic-extended-latest"},
+	{File: "ip2asn-combined.tsv.gz", URL: "https://iptoasn.com/data/ip2asn-combined.tsv.gz"},
+}
+
+// SourceStatus describes one dataset file in a database.
+type SourceStatus struct {
+	File     string    `json:"file"`
+	Format   string    `json:"format"`
+	Prefixes int       `json:"prefixes"`
+	Modified time.Time `json:"modified"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// DB is a loaded set of datasets. Records are deduplicated and the trie holds
+// indexes into them, so a full routing table stays compact.
+type DB struct {
+	trie     iptrie.Trie[uint32]
+	records  []Record
+	byRecord map[Record]uint32
+	Path     string         `json:"path"`
+	Prefixes int            `json:"prefixes"`
+	Records  int            `json:"records"`
+	Sources  []SourceStatus `json:"sources"`
+	LoadedAt time.Time      `json:"loaded_at"`
+	// LastAttempt is when a refresh last tried to download the datasets,
+	// whether or not any download succeeded.
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+var current atomic.Pointer[DB]
+
+func init() {
+	current.Store(&DB{Sources: []SourceStatus{}})
+}
+
+// Current returns the database in use. It is empty until datasets are loaded.
+func Current() *DB {
+	return current.Load()
+}
+
+// SetDB swaps the database used by every lookup. Lookups in flight finish
+// against the database they started with.
+func SetDB(db *DB) {
+	if db != nil {
+		current.Store(db)
+	}
+}
+
+// Lookup returns the record of the most specific prefix containing ip.
+func (db *DB) Lookup(ip string) (Record, netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return Record{}, netip.Prefix{}, false
+	}
+	prefix, index, ok := db.trie.Lookup(addr)
+	if !ok {
+		return Record{}, netip.Prefix{}, false
+	}
+	return db.records[index], prefix, true
+}
+
+// OldestSource is the modification time of the stalest file in the database.
+func (db *DB) OldestSource() time.Time {
+	var oldest time.Time
+	for _, s := range db.Sources {
+		if s.Error == "" && (oldest.IsZero() || s.Modified.Before(oldest)) {
+			oldest = s.Modified
+		}
+	}
+	return oldest
+}
+
+func (db *DB) insertRange(start, end netip.Addr, record Record) int {
+	index, ok := db.byRecord[record]
+	if !ok {
+		index = uint32(len(db.records))
+		db.records = append(db.records, record)
+		db.byRecord[record] = index
+	}
+	prefixes := iptrie.RangePrefixes(start, end)
+	for _, p := range prefixes {
+		db.trie.Insert(p, index)
+	}
+	return len(prefixes)
+}
+
+// parseIPToASN reads the iptoasn.com TSV format:
+// range_start, range_end, AS_number, country_code, AS_description.
+// Ranges announced by no one (AS 0) are skipped.
+func (db *DB) parseIPToASN(r io.Reader) (int, error) {
+	prefixes, lines := 0, 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		lines++
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil || asn == 0 {
+			continue
+		}
+		start, err1 := netip.ParseAddr(fields[0])
+		end, err2 := netip.ParseAddr(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		country := fields[3]
+		if country == "None" {
+			country = ""
+		}
+		prefixes += db.insertRange(start, end, Record{ASN: uint32(asn), Org: strings.TrimSpace(fields[4]), Country: country})
+	}
+	if err := scanner.Err(); err != nil {
+		return prefixes, err
+	}
+	if lines == 0 {
+		return 0, fmt.Errorf("not an iptoasn TSV file")
+	}
+	return prefixes, nil
+}
+
+// parseDelegated reads an RIR delegated (extended) stats file:
+// registry|cc|type|start|value|date|status. For ipv4 the value is an address
+// count, for ipv6 a prefix length.
+func (db *DB) parseDelegated(r io.Reader) (int, error) {
+	prefixes, lines := 0, 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) < 7 || fields[1] == "*" {
+			continue
+		}
+		lines++
+		if status := fields[6]; status != "allocated" && status != "assigned" {
+			continue
+		}
+		record := Record{Country: strings.ToUpper(fields[1]), Registry: fields[0]}
+		start, err := netip.ParseAddr(fields[3])
+		if err != nil {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[4], 10, 64)
+		if err != nil || value == 0 {
+			continue
+		}
+		switch fields[2] {
+		case "ipv4":
+			if !start.Is4() {
+				continue
+			}
+			v := uint64(start.As4()[0])<<24 | uint64(start.As4()[1])<<16 | uint64(start.As4()[2])<<8 | uint64(start.As4()[3])
+			last := v + value - 1
+			if last > 0xffffffff {
+				continue
+			}
+			end := netip.AddrFrom4([4]byte{byte(last >> 24), byte(last >> 16), byte(last >> 8), byte(last)})
+			prefixes += db.insertRange(start, end, record)
+		case "ipv6":
+			p := netip.PrefixFrom(start, int(value))
+			if !p.IsValid() {
+				continue
+			}
+			prefixes += db.insertRange(p.Masked().Addr(), iptrie.LastAddr(p), record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return prefixes, err
+	}
+	if lines == 0 {
+		return 0, fmt.Errorf("not an RIR delegated file")
+	}
+	return prefixes, nil
+}
+
+func openDataset(path string, gzipped bool) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !gzipped {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// datasetFormat tells the formats apart by file name: iptoasn files end in
+// .tsv or .tsv.gz, RIR files start with delegated-.
+func datasetFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tsv") || strings.HasSuffix(name, ".tsv.gz"):
+		return "iptoasn"
+	case strings.HasPrefix(name, "delegated-"):
+		return "rir_delegated"
+	}
+	return ""
+}
+
+// Load builds a database from every dataset in dir: the known sources in order,
+// then any other iptoasn or delegated files found there.
+func Load(dir string) (*DB, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	known := make(map[string]bool)
+	for _, source := range Sources {
+		known[source.File] = true
+		if _, err := os.Stat(filepath.Join(dir, source.File)); err == nil {
+			names = append(names, source.File)
+		}
+	}
+	var iptoasnNames []string
+	for _, e := range entries {
+		switch {
+		case known[e.Name()] || e.IsDir() || strings.HasSuffix(e.Name(), ".tmp"):
+		case datasetFormat(e.Name()) == "rir_delegated":
+			names = append(names, e.Name())
+		case datasetFormat(e.Name()) == "iptoasn":
+			iptoasnNames = append(iptoasnNames, e.Name())
+		}
+	}
+	names = append(names, iptoasnNames...)
+
+	db := &DB{Path: dir, byRecord: make(map[Record]uint32), Sources: []SourceStatus{}, LoadedAt: time.Now()}
+	if data, err := os.ReadFile(filepath.Join(dir, attemptFile)); err == nil {
+		db.LastAttempt, _ = time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		status := SourceStatus{File: name, Format: datasetFormat(name)}
+		if info, err := os.Stat(path); err == nil {
+			status.Modified = info.ModTime()
+		}
+		f, err := openDataset(path, strings.HasSuffix(name, ".gz"))
+		if err == nil {
+			if status.Format == "iptoasn" {
+				status.Prefixes, err = db.parseIPToASN(f)
+			} else {
+				status.Prefixes, err = db.parseDelegated(f)
+			}
+			f.Close()
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		db.Sources = append(db.Sources, status)
+	}
+	db.trie.Compact()
+	db.Prefixes = db.trie.Len()
+	db.Records = len(db.records)
+	db.byRecord = nil
+	return db, nil
+}
+
+// attemptFile records when Refresh last ran. The datasets' modification times
+// only move on a successful download, so they cannot tell a failing refresh
+// from one that never ran.
+const attemptFile = "last-refresh-attempt"
+
+// Refresh downloads every source into dir, then loads and swaps in the result.
+// A failed download keeps the file already on disk.
+func Refresh(client *http.Client, dir string) (*DB, []error) {
+	var errs []error
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, []error{err}
+	}
+	attempt := []byte(time.Now().UTC().Format(time.RFC3339) + "\n")
+	if err := os.WriteFile(filepath.Join(dir, attemptFile), attempt, 0o644); err != nil {
+		errs = append(errs, err)
+	}
+	for _, source := range Sources {
+		if err := download(client, source, filepath.Join(dir, source.File)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.File, err))
+		}
+	}
+	db, err := Load(dir)
+	if err != nil {
+		return nil, append(errs, err)
+	}
+	SetDB(db)
+	return db, errs
+}
+
+func download(client *http.Client, source Source, path string) error {
+	resp, err := client.Get(source.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, io.LimitReader(resp.Body, 512<<20))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// Never replace a good file with something that does not parse
+		err = validate(tmp, source.File)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// validate parses the start of a downloaded file in the format of file.
+func validate(path, file string) error {
+	f, err := openDataset(path, strings.HasSuffix(file, ".gz"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	db := &DB{byRecord: make(map[Record]uint32)}
+	head := io.LimitReader(f, 1<<20)
+	if datasetFormat(file) == "iptoasn" {
+		_, err = db.parseIPToASN(head)
+	} else {
+		_, err = db.parseDelegated(head)
+	}
+	return err
+}
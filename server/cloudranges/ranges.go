@@ -0,0 +1,341 @@
+// Package cloudranges attributes IP addresses to cloud and CDN providers using
+// the range files the providers publish.
+package cloudranges
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ars0n-framework-v2-server/iptrie"
+)
+
+const (
+	ProviderAWS        = "aws"
+	ProviderGCP        = "gcp"
+	ProviderAzure      = "azure"
+	ProviderCloudflare = "cloudflare"
+	ProviderFastly     = "fastly"
+)
+
+// Range is one published prefix and what the provider says runs in it.
+type Range struct {
+	Prefix   netip.Prefix `json:"prefix"`
+	Provider string       `json:"provider"`
+	Region   string       `json:"region,omitempty"`
+	Service  string       `json:"service,omitempty"`
+}
+
+// Source is a range file and where a refresh downloads it from. Azure rotates
+// the download link of its service tags weekly, so it has no default URL and
+// is read from disk unless AZURE_SERVICE_TAGS_URL is set.
+type Source struct {
+	Provider string
+	File     string
+	URL      string
+	parse    func([]byte) ([]Range, error)
+}
+
+var Sources = []Source{
+	{Provider: ProviderAWS, File: "aws-ip-ranges.json", URL: "https://ip-ranges.amazonaws.com/ip-ranges.json", parse: ParseAWS},
+	{Provider: ProviderGCP, File: "gcp-cloud.json", URL: "https://www.gstatic.com/ipranges/cloud.json", parse: ParseGCP},
+	{Provider: ProviderAzure, File: "azure-service-tags.json", URL: os.Getenv("AZURE_SERVICE_TAGS_URL"), parse: ParseAzure},
+	{Provider: ProviderCloudflare, File: "cloudflare-ips-v4.txt", URL: "https://www.cloudflare.com/ips-v4", parse: prefixList(ProviderCloudflare)},
+	{Provider: ProviderCloudflare, File: "cloudflare-ips-v6.txt", URL: "https://www.cloudflare.com/ips-v6", parse: prefixList(ProviderCloudflare)},
+	{Provider: ProviderFastly, File: "fastly-public-ip-list.json", URL: "https://api.fastly.com/public-ip-list", parse: ParseFastly},
+}
+
+func ParseAWS(data []byte) ([]Range, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, p := range doc.Prefixes {
+		ranges = appendRange(ranges, p.IPPrefix, ProviderAWS, p.Region, p.Service)
+	}
+	for _, p := range doc.IPv6Prefixes {
+		ranges = appendRange(ranges, p.IPv6Prefix, ProviderAWS, p.Region, p.Service)
+	}
+	return ranges, nil
+}
+
+func ParseGCP(data []byte) ([]Range, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, p := range doc.Prefixes {
+		prefix := p.IPv4Prefix
+		if prefix == "" {
+			prefix = p.IPv6Prefix
+		}
+		ranges = appendRange(ranges, prefix, ProviderGCP, p.Scope, p.Service)
+	}
+	return ranges, nil
+}
+
+// ParseAzure reads a ServiceTags_Public file. The service is the tag's system
+// service, falling back to the tag name without its region.
+func ParseAzure(data []byte) ([]Range, error) {
+	var doc struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, v := range doc.Values {
+		service := v.Properties.SystemService
+		if service == "" {
+			service, _, _ = strings.Cut(v.Name, ".")
+		}
+		for _, prefix := range v.Properties.AddressPrefixes {
+			ranges = appendRange(ranges, prefix, ProviderAzure, v.Properties.Region, service)
+		}
+	}
+	return ranges, nil
+}
+
+func ParseFastly(data []byte) ([]Range, error) {
+	var doc struct {
+		Addresses     []string `json:"addresses"`
+		IPv6Addresses []string `json:"ipv6_addresses"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var ranges []Range
+	for _, prefix := range append(doc.Addresses, doc.IPv6Addresses...) {
+		ranges = appendRange(ranges, prefix, ProviderFastly, "", "CDN")
+	}
+	return ranges, nil
+}
+
+// prefixList parses one CIDR per line, as Cloudflare publishes them.
+func prefixList(provider string) func([]byte) ([]Range, error) {
+	return func(data []byte) ([]Range, error) {
+		var ranges []Range
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, err := netip.ParsePrefix(line); err != nil {
+				return nil, fmt.Errorf("not a prefix list: %q", line)
+			}
+			ranges = appendRange(ranges, line, provider, "", "CDN")
+		}
+		return ranges, scanner.Err()
+	}
+}
+
+func appendRange(ranges []Range, prefix, provider, region, service string) []Range {
+	p, err := netip.ParsePrefix(strings.TrimSpace(prefix))
+	if err != nil {
+		return ranges
+	}
+	return append(ranges, Range{Prefix: p.Masked(), Provider: provider, Region: region, Service: service})
+}
+
+// genericServices cover a provider's whole footprint. When a prefix is listed
+// under one of them and under a specific service, the specific one is kept.
+var genericServices = map[string]bool{"AMAZON": true, "AzureCloud": true, "": true}
+
+// SourceStatus describes one range file in an index.
+type SourceStatus struct {
+	Provider    string    `json:"provider"`
+	File        string    `json:"file"`
+	Ranges      int       `json:"ranges"`
+	Modified    time.Time `json:"modified"`
+	Refreshable bool      `json:"refreshable"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Index is a loaded set of range files.
+type Index struct {
+	trie     iptrie.Trie[Range]
+	Path     string         `json:"path"`
+	Ranges   int            `json:"ranges"`
+	Sources  []SourceStatus `json:"sources"`
+	LoadedAt time.Time      `json:"loaded_at"`
+}
+
+var current atomic.Pointer[Index]
+
+func init() {
+	current.Store(&Index{Sources: []SourceStatus{}})
+}
+
+// Current returns the index in use. It is empty until range files are loaded.
+func Current() *Index {
+	return current.Load()
+}
+
+// SetIndex swaps the index used by every lookup.
+func SetIndex(index *Index) {
+	if index != nil {
+		current.Store(index)
+	}
+}
+
+// Lookup returns the most specific published range containing ip.
+func (x *Index) Lookup(ip string) (Range, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return Range{}, false
+	}
+	_, r, ok := x.trie.Lookup(addr)
+	return r, ok
+}
+
+// OldestSource is the modification time of the stalest file in the index
+// that a refresh can replace. Files without a download URL, like Azure's by
+// default, are left out: refreshing would never make them newer.
+func (x *Index) OldestSource() time.Time {
+	var oldest time.Time
+	for _, s := range x.Sources {
+		if s.Error == "" && s.Refreshable && (oldest.IsZero() || s.Modified.Before(oldest)) {
+			oldest = s.Modified
+		}
+	}
+	return oldest
+}
+
+// Load reads every known range file present in dir. Azure's weekly download
+// name, ServiceTags_Public_*.json, is picked up when azure-service-tags.json is
+// absent. Missing files are skipped; unreadable ones are reported per source.
+func Load(dir string) (*Index, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{Path: dir, Sources: []SourceStatus{}, LoadedAt: time.Now()}
+	for _, source := range Sources {
+		path := filepath.Join(dir, source.File)
+		if _, err := os.Stat(path); os.IsNotExist(err) && source.Provider == ProviderAzure {
+			var names []string
+			for _, e := range entries {
+				if strings.HasPrefix(e.Name(), "ServiceTags_Public") && strings.HasSuffix(e.Name(), ".json") {
+					names = append(names, e.Name())
+				}
+			}
+			if len(names) == 0 {
+				continue
+			}
+			sort.Strings(names)
+			path = filepath.Join(dir, names[len(names)-1])
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		status := SourceStatus{Provider: source.Provider, File: filepath.Base(path), Modified: info.ModTime(), Refreshable: source.URL != ""}
+		data, err := os.ReadFile(path)
+		var ranges []Range
+		if err == nil {
+			ranges, err = source.parse(data)
+		}
+		if err != nil {
+			status.Error = err.Error()
+			index.Sources = append(index.Sources, status)
+			continue
+		}
+		for _, r := range ranges {
+			if existing, ok := index.trie.Get(r.Prefix); ok && !genericServices[existing.Service] {
+				continue
+			}
+			index.trie.Insert(r.Prefix, r)
+		}
+		status.Ranges = len(ranges)
+		index.Sources = append(index.Sources, status)
+	}
+	index.Ranges = index.trie.Len()
+	return index, nil
+}
+
+// Refresh downloads every source with a URL into dir, then loads and swaps in
+// the result. A failed download keeps the file already on disk.
+func Refresh(client *http.Client, dir string) (*Index, []error) {
+	var errs []error
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, []error{err}
+	}
+	for _, source := range Sources {
+		if source.URL == "" {
+			continue
+		}
+		if err := download(client, source, filepath.Join(dir, source.File)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.File, err))
+		}
+	}
+	index, err := Load(dir)
+	if err != nil {
+		return nil, append(errs, err)
+	}
+	SetIndex(index)
+	return index, errs
+}
+
+func download(client *http.Client, source Source, path string) error {
+	resp, err := client.Get(source.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	// Never replace a good file with something that does not parse
+	if ranges, err := source.parse(data); err != nil || len(ranges) == 0 {
+		return fmt.Errorf("downloaded file has no usable ranges")
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
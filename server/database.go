@@ -325,6 +325,49 @@ func createTables() {
 			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
 		);`,
 
+		`CREATE TABLE IF NOT EXISTS dns_resolvers (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			address TEXT NOT NULL UNIQUE,
+			label TEXT,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			healthy BOOLEAN NOT NULL DEFAULT FALSE,
+			latency_ms INTEGER,
+			last_checked TIMESTAMP,
+			last_error TEXT,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS subdomain_permutation_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			domain TEXT NOT NULL,
+			status VARCHAR(50) NOT NULL,
+			result TEXT,
+			error TEXT,
+			stderr TEXT,
+			command TEXT,
+			execution_time TEXT,
+			budget INTEGER NOT NULL DEFAULT 5000,
+			candidates INTEGER NOT NULL DEFAULT 0,
+			patterns JSONB,
+			tool_version TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS subdomain_permutation_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL REFERENCES subdomain_permutation_scans(scan_id) ON DELETE CASCADE,
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			subdomain TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			score DOUBLE PRECISION NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, subdomain)
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS gospider_scans (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			scan_id UUID NOT NULL UNIQUE,
@@ -788,6 +831,205 @@ func createTables() {
 			UNIQUE(scan_id, ip_address, port, protocol)
 		);`,
 
+		`CREATE TABLE IF NOT EXISTS discovered_services (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID REFERENCES ip_port_scans(scan_id) ON DELETE CASCADE,
+			ip_address INET NOT NULL,
+			hostname TEXT,
+			port INT NOT NULL,
+			transport VARCHAR(10) NOT NULL DEFAULT 'tcp',
+			service VARCHAR(50) NOT NULL,
+			product TEXT,
+			version TEXT,
+			banner TEXT,
+			tls BOOLEAN DEFAULT false,
+			details JSONB,
+			discovered_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, ip_address, port, transport)
+		);`,
+		`CREATE INDEX IF NOT EXISTS discovered_services_service_idx ON discovered_services (service);`,
+
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS port_profile TEXT;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS port_spec TEXT;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS discovery_port_spec TEXT;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS services_found INT DEFAULT 0;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS pause_requested BOOLEAN DEFAULT false;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS rescan_after_days INT;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS resume_count INT DEFAULT 0;`,
+		`ALTER TABLE ip_port_scans ADD COLUMN IF NOT EXISTS last_checkpoint_at TIMESTAMP;`,
+		`ALTER TABLE discovered_live_ips ADD COLUMN IF NOT EXISTS ports_scanned_at TIMESTAMP;`,
+		`CREATE INDEX IF NOT EXISTS discovered_live_ips_scan_id_idx ON discovered_live_ips (scan_id);`,
+
+		`CREATE TABLE IF NOT EXISTS ip_port_scan_range_checkpoints (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL REFERENCES ip_port_scans(scan_id) ON DELETE CASCADE,
+			cidr_block TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			ips_planned INT,
+			live_ips INT,
+			skip_reason TEXT,
+			completed_at TIMESTAMP,
+			UNIQUE(scan_id, cidr_block)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS vhost_discovery_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			status VARCHAR(50) NOT NULL,
+			total_targets INT DEFAULT 0,
+			total_candidates INT DEFAULT 0,
+			hits_found INT DEFAULT 0,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS vhost_discovery_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL REFERENCES vhost_discovery_scans(scan_id) ON DELETE CASCADE,
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			ip_address TEXT NOT NULL,
+			port INT NOT NULL,
+			protocol VARCHAR(10) NOT NULL,
+			hostname TEXT NOT NULL,
+			url TEXT NOT NULL,
+			status_code INT,
+			content_length INT,
+			title TEXT,
+			baseline_status INT,
+			baseline_length INT,
+			reason TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, ip_address, port, hostname)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS origin_discovery_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			status VARCHAR(50) NOT NULL,
+			fronted_targets INT DEFAULT 0,
+			candidates_tested INT DEFAULT 0,
+			origins_found INT DEFAULT 0,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS origin_discovery_results (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL REFERENCES origin_discovery_scans(scan_id) ON DELETE CASCADE,
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			target_url_id UUID,
+			url TEXT NOT NULL,
+			hostname TEXT NOT NULL,
+			cdn_provider TEXT,
+			ip_address TEXT NOT NULL,
+			port INT NOT NULL,
+			protocol VARCHAR(10) NOT NULL,
+			score INT NOT NULL,
+			evidence JSONB,
+			sources TEXT[],
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scan_id, hostname, ip_address)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS edge_classification_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			status VARCHAR(50) NOT NULL,
+			active_probe BOOLEAN DEFAULT false,
+			urls_classified INT DEFAULT 0,
+			servers_classified INT DEFAULT 0,
+			edge_protected INT DEFAULT 0,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS certificate_names (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			root_domain TEXT,
+			classification VARCHAR(20) NOT NULL CHECK (classification IN ('in_scope', 'same_org', 'unrelated')),
+			organization TEXT,
+			source VARCHAR(30) NOT NULL,
+			source_host TEXT NOT NULL,
+			cert_sha256 VARCHAR(64) NOT NULL,
+			dismissed BOOLEAN DEFAULT false,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_seen TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, name)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS favicon_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			status VARCHAR(50) NOT NULL,
+			targets_checked INT DEFAULT 0,
+			favicons_found INT DEFAULT 0,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS favicon_pivot_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			favicon_hash INT NOT NULL,
+			favicon_md5 VARCHAR(32) NOT NULL,
+			sources TEXT[] NOT NULL,
+			status VARCHAR(50) NOT NULL,
+			result JSONB,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS cloud_exposure_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			status VARCHAR(50) NOT NULL,
+			test_write BOOLEAN DEFAULT false,
+			test_acl BOOLEAN DEFAULT false,
+			buckets_checked INT DEFAULT 0,
+			exposed_count INT DEFAULT 0,
+			error_message TEXT,
+			execution_time TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			auto_scan_session_id UUID REFERENCES auto_scan_sessions(id) ON DELETE SET NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS cloud_exposure_findings (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL,
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			provider VARCHAR(10) NOT NULL CHECK (provider IN ('s3', 'gcs', 'azure')),
+			account TEXT NOT NULL DEFAULT '',
+			bucket TEXT NOT NULL,
+			check_name VARCHAR(10) NOT NULL CHECK (check_name IN ('list', 'read', 'write', 'acl')),
+			url TEXT NOT NULL,
+			exposed BOOLEAN NOT NULL,
+			status_code INT,
+			severity VARCHAR(10) NOT NULL,
+			evidence JSONB,
+			source VARCHAR(30),
+			source_url TEXT,
+			first_seen TIMESTAMP DEFAULT NOW(),
+			last_checked TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, provider, account, bucket, check_name)
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS target_urls (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			url TEXT NOT NULL,
@@ -827,6 +1069,119 @@ func createTables() {
 			UNIQUE(url, scope_target_id)
 		);`,
 
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS edge_provider TEXT;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS edge_category VARCHAR(20);`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS waf_provider TEXT;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS edge_blocking BOOLEAN DEFAULT false;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS edge_signals JSONB;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS edge_checked_at TIMESTAMP;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS edge_provider TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS edge_category VARCHAR(20);`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS waf_provider TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS edge_blocking BOOLEAN DEFAULT false;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS edge_signals JSONB;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS edge_checked_at TIMESTAMP;`,
+
+		`ALTER TABLE discovered_live_ips ADD COLUMN IF NOT EXISTS asn_number TEXT;`,
+		`ALTER TABLE discovered_live_ips ADD COLUMN IF NOT EXISTS asn_organization TEXT;`,
+		`ALTER TABLE discovered_live_ips ADD COLUMN IF NOT EXISTS asn_country TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS asn_number TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS asn_organization TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS asn_country TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS address_family TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS stack_exposure TEXT;`,
+
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS favicon_url TEXT;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS favicon_hash INT;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS favicon_md5 VARCHAR(32);`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS favicon_product TEXT;`,
+		`ALTER TABLE target_urls ADD COLUMN IF NOT EXISTS favicon_checked_at TIMESTAMP;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS favicon_url TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS favicon_hash INT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS favicon_md5 VARCHAR(32);`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS favicon_product TEXT;`,
+		`ALTER TABLE live_web_servers ADD COLUMN IF NOT EXISTS favicon_checked_at TIMESTAMP;`,
+		`CREATE INDEX IF NOT EXISTS target_urls_favicon_hash_idx ON target_urls (favicon_hash);`,
+		`CREATE INDEX IF NOT EXISTS live_web_servers_favicon_hash_idx ON live_web_servers (favicon_hash);`,
+
+		`CREATE TABLE IF NOT EXISTS technology_detections (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			target_url_id UUID NOT NULL REFERENCES target_urls(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			name TEXT NOT NULL,
+			version TEXT,
+			categories TEXT[],
+			confidence INT NOT NULL,
+			evidence JSONB,
+			rules_version TEXT NOT NULL,
+			detected_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(target_url_id, name)
+		);`,
+
+		`ALTER TABLE technology_detections ADD COLUMN IF NOT EXISTS cpe TEXT;`,
+
+		`CREATE TABLE IF NOT EXISTS cve_entries (
+			cve_id VARCHAR(32) PRIMARY KEY,
+			description TEXT,
+			cvss_score NUMERIC(3,1),
+			severity VARCHAR(20),
+			published_at TIMESTAMP,
+			last_modified_at TIMESTAMP,
+			imported_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS cve_cpe_matches (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			cve_id VARCHAR(32) NOT NULL REFERENCES cve_entries(cve_id) ON DELETE CASCADE,
+			vendor TEXT NOT NULL,
+			product TEXT NOT NULL,
+			version TEXT NOT NULL,
+			version_start_including TEXT,
+			version_start_excluding TEXT,
+			version_end_including TEXT,
+			version_end_excluding TEXT
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS cve_cpe_matches_product_idx ON cve_cpe_matches (vendor, product);`,
+		`CREATE INDEX IF NOT EXISTS cve_cpe_matches_cve_idx ON cve_cpe_matches (cve_id);`,
+
+		`CREATE TABLE IF NOT EXISTS cisa_kev (
+			cve_id VARCHAR(32) PRIMARY KEY,
+			vendor_project TEXT,
+			product TEXT,
+			vulnerability_name TEXT,
+			date_added DATE,
+			due_date DATE,
+			known_ransomware_use BOOLEAN DEFAULT false,
+			short_description TEXT,
+			required_action TEXT
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS cve_feed_imports (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			feed_type VARCHAR(10) NOT NULL CHECK (feed_type IN ('nvd', 'kev')),
+			source TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			entries INT DEFAULT 0,
+			error_message TEXT,
+			started_at TIMESTAMP DEFAULT NOW(),
+			finished_at TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS technology_vulnerabilities (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			asset_kind VARCHAR(30) NOT NULL CHECK (asset_kind IN ('target_url', 'attack_surface_asset')),
+			asset_id UUID NOT NULL,
+			asset_label TEXT NOT NULL,
+			technology TEXT NOT NULL,
+			version TEXT NOT NULL,
+			cve_id VARCHAR(32) NOT NULL,
+			matched_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(asset_kind, asset_id, technology, cve_id)
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS dns_records (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			scan_id UUID NOT NULL,
@@ -957,6 +1312,9 @@ func createTables() {
 			UNIQUE(scope_target_id, cidr_block, source)
 		);`,
 
+		`ALTER TABLE consolidated_network_ranges ADD COLUMN IF NOT EXISTS contained_in TEXT;`,
+		`ALTER TABLE consolidated_network_ranges ADD COLUMN IF NOT EXISTS covering_cidr TEXT;`,
+
 		`CREATE TABLE IF NOT EXISTS metabigor_network_ranges (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			scan_id UUID NOT NULL,
@@ -1183,7 +1541,7 @@ func createTables() {
 		`CREATE TABLE IF NOT EXISTS consolidated_attack_surface_assets (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
-			asset_type VARCHAR(50) NOT NULL CHECK (asset_type IN ('asn', 'network_range', 'ip_address', 'live_web_server', 'cloud_asset', 'fqdn')),
+			asset_type VARCHAR(50) NOT NULL CHECK (asset_type IN ('asn', 'network_range', 'ip_address', 'live_web_server', 'cloud_asset', 'fqdn', 'service')),
 			asset_identifier TEXT NOT NULL,
 			asset_subtype VARCHAR(50),
 			
@@ -1271,6 +1629,15 @@ func createTables() {
 			UNIQUE(scope_target_id, asset_type, asset_identifier)
 		);`,
 
+		`ALTER TABLE consolidated_attack_surface_assets DROP CONSTRAINT IF EXISTS consolidated_attack_surface_assets_asset_type_check;`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD CONSTRAINT consolidated_attack_surface_assets_asset_type_check
+			CHECK (asset_type IN ('asn', 'network_range', 'ip_address', 'live_web_server', 'cloud_asset', 'fqdn', 'service'));`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS service_product TEXT;`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS service_version TEXT;`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS service_banner TEXT;`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS service_tls BOOLEAN;`,
+		`ALTER TABLE consolidated_attack_surface_assets ADD COLUMN IF NOT EXISTS service_details JSONB;`,
+
 		`CREATE TABLE IF NOT EXISTS consolidated_attack_surface_relationships (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			parent_asset_id UUID NOT NULL REFERENCES consolidated_attack_surface_assets(id) ON DELETE CASCADE,
@@ -1395,6 +1762,10 @@ func createTables() {
 		`CREATE INDEX IF NOT EXISTS idx_manual_crawl_captures_scope_target ON manual_crawl_captures(scope_target_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_manual_crawl_captures_endpoint ON manual_crawl_captures(endpoint);`,
 		`CREATE INDEX IF NOT EXISTS idx_manual_crawl_captures_method ON manual_crawl_captures(method);`,
+		`ALTER TABLE manual_crawl_sessions ADD COLUMN IF NOT EXISTS capture_token TEXT;`,
+		`ALTER TABLE manual_crawl_sessions ADD COLUMN IF NOT EXISTS label TEXT;`,
+		`ALTER TABLE manual_crawl_sessions ADD COLUMN IF NOT EXISTS last_capture_at TIMESTAMP;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_manual_crawl_sessions_capture_token ON manual_crawl_sessions(capture_token);`,
 
 		`CREATE TABLE IF NOT EXISTS consolidated_url_endpoints (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -1589,6 +1960,161 @@ func createTables() {
 		`CREATE INDEX IF NOT EXISTS idx_parameter_enumeration_results_scan_id ON parameter_enumeration_results(scan_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_parameter_enumeration_results_scope_target ON parameter_enumeration_results(scope_target_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_parameter_enumeration_results_endpoint ON parameter_enumeration_results(endpoint_url);`,
+
+		`CREATE TABLE IF NOT EXISTS tool_inventory (
+			tool_name VARCHAR(100) PRIMARY KEY,
+			mode VARCHAR(50),
+			target TEXT,
+			available BOOLEAN DEFAULT false,
+			version TEXT,
+			error TEXT,
+			checked_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS tool_executor_configs (
+			tool_name VARCHAR(100) PRIMARY KEY,
+			mode VARCHAR(50) NOT NULL,
+			container TEXT,
+			image TEXT,
+			command JSONB DEFAULT '[]',
+			docker_host TEXT,
+			work_dir TEXT,
+			run_args JSONB DEFAULT '[]',
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		// Record the tool version that produced each scan
+		`ALTER TABLE amass_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE amass_intel_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE amass_enum_company_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE gau_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE gau_url_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE httpx_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE subfinder_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE sublist3r_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE assetfinder_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE shuffledns_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE shufflednscustom_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE cewl_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE gospider_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE gospider_url_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE subdomainizer_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE nuclei_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE nuclei_screenshots ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE metadata_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE katana_company_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE katana_url_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE dnsx_company_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE ffuf_url_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE github_recon_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE cloud_enum_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE metabigor_company_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE linkfinder_url_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE waybackurls_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE arjun_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE parameth_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+		`ALTER TABLE x8_scans ADD COLUMN IF NOT EXISTS tool_version TEXT;`,
+
+		`CREATE TABLE IF NOT EXISTS scan_workers (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			tools JSONB DEFAULT '[]',
+			status VARCHAR(50) DEFAULT 'online',
+			address TEXT,
+			version TEXT,
+			concurrency INTEGER DEFAULT 1,
+			last_heartbeat TIMESTAMP DEFAULT NOW(),
+			registered_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS scan_worker_jobs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			tool VARCHAR(100) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			args JSONB DEFAULT '[]',
+			stdin TEXT,
+			timeout_seconds INTEGER DEFAULT 0,
+			status VARCHAR(50) DEFAULT 'queued',
+			worker_id UUID REFERENCES scan_workers(id) ON DELETE SET NULL,
+			attempts INTEGER DEFAULT 0,
+			exit_code INTEGER,
+			error TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			started_at TIMESTAMP,
+			completed_at TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_scan_worker_jobs_status ON scan_worker_jobs(status, tool, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_scan_worker_jobs_worker ON scan_worker_jobs(worker_id);`,
+		`CREATE TABLE IF NOT EXISTS egress_proxy_pools (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(255) NOT NULL UNIQUE,
+			strategy VARCHAR(50) DEFAULT 'round_robin',
+			enabled BOOLEAN DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS egress_proxies (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			pool_id UUID NOT NULL REFERENCES egress_proxy_pools(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			enabled BOOLEAN DEFAULT TRUE,
+			healthy BOOLEAN DEFAULT TRUE,
+			last_checked TIMESTAMP,
+			last_error TEXT,
+			exit_ip TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS egress_policies (
+			scope VARCHAR(100) PRIMARY KEY,
+			pool_id UUID NOT NULL REFERENCES egress_proxy_pools(id) ON DELETE CASCADE,
+			enabled BOOLEAN DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS http_request_profiles (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope_target_id UUID NOT NULL REFERENCES scope_targets(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			headers JSONB DEFAULT '{}',
+			cookies JSONB DEFAULT '{}',
+			bearer_token TEXT,
+			user_agent TEXT,
+			login_script TEXT,
+			session_ttl_seconds INTEGER DEFAULT 3600,
+			is_default BOOLEAN DEFAULT FALSE,
+			session_headers JSONB DEFAULT '{}',
+			session_refreshed_at TIMESTAMP,
+			session_error TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(scope_target_id, name)
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_http_request_profiles_default ON http_request_profiles(scope_target_id) WHERE is_default;`,
+		`CREATE TABLE IF NOT EXISTS port_scan_profiles (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(100) NOT NULL UNIQUE,
+			description TEXT,
+			ports TEXT NOT NULL,
+			discovery_ports TEXT,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS imported_scans (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scan_id UUID NOT NULL UNIQUE,
+			scope_target_id UUID REFERENCES scope_targets(id) ON DELETE CASCADE,
+			format VARCHAR(50) NOT NULL,
+			filename TEXT,
+			status VARCHAR(50) NOT NULL,
+			result TEXT,
+			linked_scan_ids TEXT[],
+			summary JSONB DEFAULT '{}',
+			error TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_imported_scans_scope_target ON imported_scans(scope_target_id, format);`,
 	}
 
 	for _, query := range queries {
@@ -1613,11 +2139,19 @@ func createTables() {
 		DELETE FROM shuffledns_scans WHERE status = 'pending';
 		DELETE FROM cewl_scans WHERE status = 'pending';
 		DELETE FROM shufflednscustom_scans WHERE status = 'pending';
+		DELETE FROM subdomain_permutation_scans WHERE status = 'pending' OR status = 'running';
 		DELETE FROM gospider_scans WHERE status = 'pending';
 		DELETE FROM subdomainizer_scans WHERE status = 'pending';
 		DELETE FROM nuclei_screenshots WHERE status = 'pending';
 		DELETE FROM metadata_scans WHERE status = 'pending';
 		DELETE FROM ip_port_scans WHERE status = 'pending';
+		DELETE FROM vhost_discovery_scans WHERE status = 'pending' OR status = 'running';
+		DELETE FROM origin_discovery_scans WHERE status = 'pending' OR status = 'running';
+		DELETE FROM edge_classification_scans WHERE status = 'pending' OR status = 'running';
+		DELETE FROM cve_feed_imports WHERE status = 'running';
+		DELETE FROM favicon_scans WHERE status = 'pending' OR status = 'running';
+		DELETE FROM favicon_pivot_scans WHERE status = 'pending' OR status = 'running';
+		DELETE FROM cloud_exposure_scans WHERE status = 'pending' OR status = 'running';
 		DELETE FROM katana_company_scans WHERE status = 'pending' OR status = 'running';
 		DELETE FROM amass_enum_company_scans WHERE status = 'pending' OR status = 'running';
 		DELETE FROM nuclei_scans WHERE status = 'pending' OR status = 'running';`
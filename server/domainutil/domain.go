@@ -0,0 +1,191 @@
+// Package domainutil parses and normalizes host names using the Public Suffix
+// List, so "a.b.example.co.uk" has the root domain "example.co.uk" and
+// "user.github.io" is treated as its own registrable domain.
+package domainutil
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// toASCII converts the IDN labels of host to punycode. ASCII labels are left
+// alone so names such as "_dmarc.example.com", which IDNA's STD3 rules reject,
+// still reach validASCII.
+func toASCII(host string) (string, error) {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, err := idna.Lookup.ToASCII(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// ToUnicode converts punycode labels back to Unicode for display. Labels that
+// do not decode are kept as they are.
+func ToUnicode(host string) string {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if strings.HasPrefix(strings.ToLower(label), "xn--") {
+			if decoded, err := idna.Lookup.ToUnicode(label); err == nil {
+				labels[i] = decoded
+			}
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// Normalize lower-cases a host name, strips a wildcard prefix, port and trailing
+// dot, and converts IDN labels to punycode. It rejects anything that is not a
+// syntactically valid DNS name with at least two labels.
+func Normalize(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimPrefix(host, "*.")
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	ascii, err := toASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid IDN %q: %v", host, err)
+	}
+	if !validASCII(ascii) {
+		return "", fmt.Errorf("invalid domain %q", host)
+	}
+	return ascii, nil
+}
+
+func validASCII(domain string) bool {
+	if len(domain) == 0 || len(domain) > 253 || IsIP(domain) {
+		return false
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsValid reports whether host normalizes to a valid domain name.
+func IsValid(host string) bool {
+	_, err := Normalize(host)
+	return err == nil
+}
+
+// IsIP reports whether s is an IPv4 or IPv6 address (brackets allowed).
+func IsIP(s string) bool {
+	return net.ParseIP(strings.Trim(s, "[]")) != nil
+}
+
+// PublicSuffix returns the effective TLD of host (e.g. "co.uk", "github.io") and
+// whether it comes from the ICANN section of the list.
+func PublicSuffix(host string) (string, bool) {
+	domain, err := Normalize(host)
+	if err != nil {
+		return "", false
+	}
+	return Current().publicSuffix(domain)
+}
+
+// RootDomain returns the registrable domain (eTLD+1) of host, or "" for IPs,
+// invalid names and names that are themselves public suffixes.
+func RootDomain(host string) string {
+	domain, err := Normalize(host)
+	if err != nil {
+		return ""
+	}
+	suffix, _ := Current().publicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return ""
+	}
+	rest := strings.TrimSuffix(domain[:len(domain)-len(suffix)], ".")
+	if i := strings.LastIndexByte(rest, '.'); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return rest + "." + suffix
+}
+
+// Labels returns the labels of host that sit below its public suffix, e.g.
+// ["api", "example"] for "api.example.co.uk".
+func Labels(host string) []string {
+	domain, err := Normalize(host)
+	if err != nil {
+		return nil
+	}
+	suffix, _ := Current().publicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(domain[:len(domain)-len(suffix)], "."), ".")
+}
+
+// IsSubdomainOf reports whether host equals parent or sits below it, comparing
+// normalized names so "EXAMPLE.com." matches and "notexample.com" does not.
+func IsSubdomainOf(host, parent string) bool {
+	h, err := Normalize(host)
+	if err != nil {
+		return false
+	}
+	p, err := Normalize(parent)
+	if err != nil {
+		return false
+	}
+	return h == p || strings.HasSuffix(h, "."+p)
+}
+
+// HostFromURL extracts the host name from a URL or a bare host[:port][/path].
+// The result is lower-cased but not validated, so IPs are returned as-is.
+func HostFromURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+// DomainFromURL is HostFromURL followed by Normalize; it returns "" for IPs and
+// anything that is not a valid domain.
+func DomainFromURL(raw string) string {
+	domain, err := Normalize(HostFromURL(raw))
+	if err != nil {
+		return ""
+	}
+	return domain
+}
@@ -0,0 +1,107 @@
+package domainutil
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{"Example.COM", "example.com", false},
+		{"  api.example.com:8443 ", "api.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"example.com.", "example.com", false},
+		{"*.Example.com.", "example.com", false},
+		{"_dmarc.example.com", "_dmarc.example.com", false},
+		{"bücher.de", "xn--bcher-kva.de", false},
+		{"BÜCHER.de.", "xn--bcher-kva.de", false},
+		{"例子.公司.cn", "xn--fsqu00a.xn--55qx5d.cn", false},
+		{"xn--bcher-kva.de", "xn--bcher-kva.de", false},
+		{"localhost", "", true},
+		{"192.168.1.1", "", true},
+		{"[::1]:443", "", true},
+		{"-bad.example.com", "", true},
+		{"a..example.com", "", true},
+		{"exa mple.com", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.host)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, error %v", tt.host, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"xn--bcher-kva.de", "bücher.de"},
+		{"XN--BCHER-KVA.de", "bücher.de"},
+		{"xn--fsqu00a.xn--55qx5d.cn", "例子.公司.cn"},
+		{"xn--99999999999.example.com", "xn--99999999999.example.com"},
+		{"example.com", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := ToUnicode(tt.host); got != tt.want {
+			t.Errorf("ToUnicode(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRootDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"a.b.example.com", "example.com"},
+		{"*.api.example.com", "example.com"},
+		{"www.example.com.", "example.com"},
+		{"a.b.example.co.uk", "example.co.uk"},
+		{"co.uk", ""},
+		{"user.github.io", "user.github.io"},
+		{"docs.user.github.io", "user.github.io"},
+		{"github.io", ""},
+		{"bucket.s3.amazonaws.com", "bucket.s3.amazonaws.com"},
+		{"shop.bücher.de", "xn--bcher-kva.de"},
+		{"www.例子.公司.cn", "xn--fsqu00a.xn--55qx5d.cn"},
+		{"foo.bar.ck", "foo.bar.ck"},
+		{"www.ck", "www.ck"},
+		{"10.0.0.1", ""},
+		{"not a domain", ""},
+	}
+	for _, tt := range tests {
+		if got := RootDomain(tt.host); got != tt.want {
+			t.Errorf("RootDomain(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestIsSubdomainOf(t *testing.T) {
+	tests := []struct {
+		host, parent string
+		want         bool
+	}{
+		{"api.example.com", "example.com", true},
+		{"example.com", "example.com", true},
+		{"EXAMPLE.com.", "example.com", true},
+		{"api.example.com", "*.example.com", true},
+		{"notexample.com", "example.com", false},
+		{"example.com", "api.example.com", false},
+		{"a.user.github.io", "user.github.io", true},
+		{"other.github.io", "user.github.io", false},
+		{"shop.bücher.de", "xn--bcher-kva.de", true},
+		{"shop.xn--bcher-kva.de", "Bücher.de.", true},
+		{"10.0.0.1", "example.com", false},
+		{"api.example.com", "", false},
+	}
+	for _, tt := range tests {
+		if got := IsSubdomainOf(tt.host, tt.parent); got != tt.want {
+			t.Errorf("IsSubdomainOf(%q, %q) = %v, want %v", tt.host, tt.parent, got, tt.want)
+		}
+	}
+}
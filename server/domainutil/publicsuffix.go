@@ -0,0 +1,181 @@
+package domainutil
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultListURL is where Refresh downloads the current list from.
+const DefaultListURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+//go:embed public_suffix_list.dat
+var embeddedList []byte
+
+type suffixRule struct {
+	exception bool
+	icann     bool
+}
+
+// List is a parsed Public Suffix List. Rules are stored in their ASCII (punycode) form.
+// LoadedAt is when the list was swapped in and is zero for the embedded copy;
+// FetchedAt is when it was last downloaded, also zero for the embedded copy, and
+// is what decides whether a refresh is due.
+type List struct {
+	rules     map[string]suffixRule
+	Source    string    `json:"source"`
+	Rules     int       `json:"rules"`
+	LoadedAt  time.Time `json:"loaded_at"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+var current atomic.Pointer[List]
+
+func init() {
+	list, err := ParseList(bytes.NewReader(embeddedList), "embedded")
+	if err != nil {
+		panic(fmt.Sprintf("domainutil: embedded public suffix list is invalid: %v", err))
+	}
+	current.Store(list)
+}
+
+// ParseList reads a list in the publicsuffix.org format.
+func ParseList(r io.Reader, source string) (*List, error) {
+	list := &List{rules: make(map[string]suffixRule), Source: source}
+	icann := false
+	sawICANN := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+			icann, sawICANN = true, true
+			continue
+		case strings.Contains(line, "===END ICANN DOMAINS==="):
+			icann = false
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+
+		rule := suffixRule{icann: icann}
+		if strings.HasPrefix(line, "!") {
+			rule.exception = true
+			line = line[1:]
+		}
+		name, err := toASCII(strings.ToLower(line))
+		if err != nil {
+			continue
+		}
+		list.rules[name] = rule
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !sawICANN || len(list.rules) < 1000 {
+		return nil, fmt.Errorf("not a public suffix list (%d rules)", len(list.rules))
+	}
+	list.Rules = len(list.rules)
+	return list, nil
+}
+
+// Current returns the list in use.
+func Current() *List {
+	return current.Load()
+}
+
+// SetList swaps the list used by every lookup.
+func SetList(list *List) {
+	if list != nil {
+		current.Store(list)
+	}
+}
+
+// publicSuffix returns the public suffix of an ASCII, lower-case domain. Domains
+// matching no rule fall back to their last label, as the list's "*" rule requires.
+func (l *List) publicSuffix(domain string) (string, bool) {
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		if rule, ok := l.rules[name]; ok {
+			if rule.exception {
+				return strings.Join(labels[i+1:], "."), rule.icann
+			}
+			return name, rule.icann
+		}
+		if i+1 < len(labels) {
+			if rule, ok := l.rules["*."+strings.Join(labels[i+1:], ".")]; ok && !rule.exception {
+				return name, rule.icann
+			}
+		}
+	}
+	return labels[len(labels)-1], false
+}
+
+// LoadCachedList replaces the embedded list with a previously downloaded copy, if present.
+func LoadCachedList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	list, err := ParseList(f, path)
+	if err != nil {
+		return err
+	}
+	list.LoadedAt = time.Now()
+	if info, statErr := f.Stat(); statErr == nil {
+		list.FetchedAt = info.ModTime()
+	}
+	SetList(list)
+	return nil
+}
+
+// RefreshList downloads the list, swaps it in and writes it to cachePath so the
+// next start does not fall back to the embedded copy.
+func RefreshList(client *http.Client, listURL, cachePath string) (*List, error) {
+	if listURL == "" {
+		listURL = DefaultListURL
+	}
+	resp, err := client.Get(listURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", listURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := ParseList(bytes.NewReader(data), listURL)
+	if err != nil {
+		return nil, err
+	}
+	list.LoadedAt = time.Now()
+	list.FetchedAt = list.LoadedAt
+	if cachePath != "" {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+			tmp := cachePath + ".tmp"
+			if err := os.WriteFile(tmp, data, 0644); err == nil {
+				os.Rename(tmp, cachePath)
+			}
+		}
+	}
+	SetList(list)
+	return list, nil
+}
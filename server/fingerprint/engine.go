@@ -0,0 +1,206 @@
+package fingerprint
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxHTMLBytes bounds how much of a body the html patterns run over.
+const maxHTMLBytes = 512 << 10
+
+var (
+	scriptSrcRegex    = regexp.MustCompile(`(?i)<script[^>]+src\s*=\s*["']?([^"'\s>]+)`)
+	inlineScriptRegex = regexp.MustCompile(`(?is)<script[^>]*>(.*?)</script>`)
+	metaTagRegex      = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	metaNameRegex     = regexp.MustCompile(`(?i)\b(?:name|property|http-equiv)\s*=\s*["']([^"']+)["']`)
+	metaContentRegex  = regexp.MustCompile(`(?i)\bcontent\s*=\s*["']([^"']*)["']`)
+)
+
+// Input is a stored response. Nothing is fetched: fingerprints run over what
+// earlier scans already saved.
+type Input struct {
+	URL     string
+	Headers http.Header
+	Body    string
+}
+
+type Detection struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Categories []string `json:"categories"`
+	Confidence int      `json:"confidence"`
+	Website    string   `json:"website,omitempty"`
+	CPE        string   `json:"cpe,omitempty"`
+	Evidence   []string `json:"evidence"`
+}
+
+// document is an Input broken into the parts the rule types look at.
+type document struct {
+	url        string
+	headers    http.Header
+	cookies    map[string]string
+	html       string
+	scriptSrcs []string
+	scripts    string
+	meta       map[string][]string
+}
+
+func newDocument(in Input) document {
+	doc := document{url: in.URL, headers: in.Headers, cookies: make(map[string]string), meta: make(map[string][]string)}
+	if doc.headers == nil {
+		doc.headers = http.Header{}
+	}
+	for _, c := range (&http.Response{Header: doc.headers}).Cookies() {
+		doc.cookies[c.Name] = c.Value
+	}
+
+	doc.html = in.Body
+	if len(doc.html) > maxHTMLBytes {
+		doc.html = doc.html[:maxHTMLBytes]
+	}
+	for _, m := range scriptSrcRegex.FindAllStringSubmatch(doc.html, -1) {
+		doc.scriptSrcs = append(doc.scriptSrcs, m[1])
+	}
+	var inline []string
+	for _, m := range inlineScriptRegex.FindAllStringSubmatch(doc.html, -1) {
+		if strings.TrimSpace(m[1]) != "" {
+			inline = append(inline, m[1])
+		}
+	}
+	doc.scripts = strings.Join(inline, "\n")
+	for _, tag := range metaTagRegex.FindAllString(doc.html, -1) {
+		name := metaNameRegex.FindStringSubmatch(tag)
+		content := metaContentRegex.FindStringSubmatch(tag)
+		if name != nil && content != nil {
+			key := strings.ToLower(name[1])
+			doc.meta[key] = append(doc.meta[key], content[1])
+		}
+	}
+	return doc
+}
+
+// detection accumulates matches for one technology.
+type detection struct {
+	tech       *technology
+	confidence int
+	version    string
+	evidence   []string
+}
+
+func (d *detection) add(p pattern, version, evidence string) {
+	d.confidence += p.confidence
+	if len(version) > len(d.version) {
+		d.version = version
+	}
+	d.evidence = append(d.evidence, evidence)
+}
+
+// Analyze fingerprints one stored response with the current ruleset.
+func Analyze(in Input) []Detection {
+	return Current().Analyze(in)
+}
+
+// Analyze fingerprints one stored response. Confidence follows Wappalyzer: every
+// matching pattern adds its confidence and the total is capped at 100.
+func (r *Ruleset) Analyze(in Input) []Detection {
+	doc := newDocument(in)
+	found := make(map[string]*detection)
+	get := func(t *technology) *detection {
+		if d, ok := found[t.name]; ok {
+			return d
+		}
+		d := &detection{tech: t}
+		found[t.name] = d
+		return d
+	}
+	matchList := func(t *technology, patterns []pattern, values []string, kind string) {
+		for _, p := range patterns {
+			for _, v := range values {
+				if ok, version := p.match(v); ok {
+					get(t).add(p, version, kind)
+					break
+				}
+			}
+		}
+	}
+
+	for _, t := range r.technologies {
+		for name, patterns := range t.headers {
+			if values, ok := doc.headers[http.CanonicalHeaderKey(name)]; ok {
+				matchList(t, patterns, values, "headers:"+name)
+			}
+		}
+		for name, patterns := range t.cookies {
+			if value, ok := doc.cookies[name]; ok {
+				matchList(t, patterns, []string{value}, "cookies:"+name)
+			}
+		}
+		for name, patterns := range t.meta {
+			if values, ok := doc.meta[name]; ok {
+				matchList(t, patterns, values, "meta:"+name)
+			}
+		}
+		if doc.html != "" {
+			matchList(t, t.html, []string{doc.html}, "html")
+		}
+		matchList(t, t.scriptSrc, doc.scriptSrcs, "scriptSrc")
+		if doc.scripts != "" {
+			matchList(t, t.scripts, []string{doc.scripts}, "scripts")
+		}
+		if doc.url != "" {
+			matchList(t, t.url, []string{doc.url}, "url")
+		}
+		// JS globals cannot be evaluated offline. A global referenced by name in an
+		// inline script counts at half confidence, with no version.
+		for chain := range t.js {
+			if doc.scripts != "" && strings.Contains(doc.scripts, chain) {
+				get(t).add(pattern{confidence: 50}, "", "js:"+chain)
+			}
+		}
+	}
+
+	// Implied technologies inherit the lower of the two confidences
+	for changed := true; changed; {
+		changed = false
+		for _, d := range found {
+			for _, imp := range d.tech.implies {
+				implied, ok := r.byName[imp.name]
+				if !ok {
+					continue
+				}
+				confidence := min(d.confidence, imp.confidence)
+				if existing, ok := found[imp.name]; ok {
+					if existing.confidence < confidence {
+						existing.confidence = confidence
+						changed = true
+					}
+					continue
+				}
+				found[imp.name] = &detection{tech: implied, confidence: confidence, evidence: []string{"implied by " + d.tech.name}}
+				changed = true
+			}
+		}
+	}
+	for _, d := range found {
+		for _, excluded := range d.tech.excludes {
+			delete(found, excluded)
+		}
+	}
+
+	detections := make([]Detection, 0, len(found))
+	for _, d := range found {
+		detections = append(detections, Detection{
+			Name:       d.tech.name,
+			Version:    d.version,
+			Categories: r.categoryNames(d.tech),
+			Confidence: min(d.confidence, 100),
+			Website:    d.tech.website,
+			CPE:        d.tech.cpe,
+			Evidence:   d.evidence,
+		})
+	}
+	sort.Slice(detections, func(i, j int) bool { return detections[i].Name < detections[j].Name })
+	return detections
+}
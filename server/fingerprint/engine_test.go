@@ -0,0 +1,203 @@
+package fingerprint
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// testRules is a trimmed Wappalyzer file in the classic layout. Patterns are
+// JSON strings, so `\\;` in the source is the `\;` separator Wappalyzer uses.
+const testRules = `{
+	"categories": {
+		"1": {"name": "CMS"},
+		"10": {"name": "Analytics"},
+		"22": {"name": "Web servers"},
+		"27": {"name": "Programming languages"},
+		"34": {"name": "Databases"},
+		"59": {"name": "JavaScript libraries"}
+	},
+	"technologies": {
+		"Nginx": {
+			"cats": [22],
+			"website": "https://nginx.org/en",
+			"cpe": "cpe:2.3:a:f5:nginx:*:*:*:*:*:*:*:*",
+			"headers": {"Server": "nginx(?:/([\\d.]+))?\\;version:\\1"}
+		},
+		"Tengine": {
+			"cats": [22],
+			"headers": {"Server": "^Tengine"},
+			"excludes": "Nginx"
+		},
+		"PHP": {
+			"cats": [27],
+			"headers": {"X-Powered-By": "^php/?([\\d.]+)?\\;version:\\1"},
+			"cookies": {"PHPSESSID": ""}
+		},
+		"MySQL": {"cats": [34]},
+		"WordPress": {
+			"cats": [1],
+			"meta": {"generator": "^WordPress ?([\\d.]+)?\\;version:\\1"},
+			"html": "<link[^>]+/wp-content/\\;confidence:50",
+			"scriptSrc": ["/wp-includes/\\;confidence:30", "wp-(?!json)\\;confidence:10"],
+			"implies": ["PHP\\;confidence:50", "MySQL"]
+		},
+		"Google Analytics": {
+			"cats": [10],
+			"scriptSrc": "google-analytics\\.com/(?:ga|urchin|(analytics))\\.js\\;version:\\1?UA:GA"
+		},
+		"jQuery": {
+			"cats": [59],
+			"scriptSrc": "jquery[.-]([\\d.]+)(?:\\.min)?\\.js\\;version:\\1",
+			"js": {"jQuery.fn.jquery": "([\\d.]+)\\;version:\\1"}
+		}
+	}
+}`
+
+func loadTestRules(t *testing.T) *Ruleset {
+	t.Helper()
+	rules, err := ParseRules(map[string][]byte{"technologies.json": []byte(testRules)}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestParseRules(t *testing.T) {
+	rules := loadTestRules(t)
+	if rules.Technologies != 7 || rules.Categories != 6 {
+		t.Errorf("parsed %d technologies and %d categories, want 7 and 6", rules.Technologies, rules.Categories)
+	}
+	// The lookahead in WordPress's second scriptSrc pattern does not compile in RE2
+	if rules.SkippedPatterns != 1 {
+		t.Errorf("SkippedPatterns = %d, want 1", rules.SkippedPatterns)
+	}
+	if cpe, name := rules.CPE("NGINX"); cpe != "cpe:2.3:a:f5:nginx:*:*:*:*:*:*:*:*" || name != "Nginx" {
+		t.Errorf("CPE(NGINX) = %q, %q", cpe, name)
+	}
+	if _, err := ParseRules(map[string][]byte{"categories.json": []byte(`{"1": {"name": "CMS"}}`)}, "test"); err == nil {
+		t.Error("ParseRules accepted files with no technologies")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	rules := loadTestRules(t)
+
+	type result struct {
+		version    string
+		confidence int
+	}
+	tests := []struct {
+		name string
+		in   Input
+		want map[string]result
+	}{
+		{
+			"header version",
+			Input{Headers: http.Header{"Server": {"nginx/1.25.3"}}},
+			map[string]result{"Nginx": {"1.25.3", 100}},
+		},
+		{
+			"header without version",
+			Input{Headers: http.Header{"Server": {"nginx"}}},
+			map[string]result{"Nginx": {"", 100}},
+		},
+		{
+			"confidences sum and implied technologies take the lower confidence",
+			Input{Body: `<link rel="stylesheet" href="/wp-content/themes/a.css"><script src="/wp-includes/js/b.js"></script>`},
+			map[string]result{"WordPress": {"", 80}, "PHP": {"", 50}, "MySQL": {"", 80}},
+		},
+		{
+			"confidence caps at 100 and a direct match beats an implied one",
+			Input{
+				Headers: http.Header{"X-Powered-By": {"PHP/8.2.1"}},
+				Body:    `<meta name="generator" content="WordPress 6.4.2"><link href="/wp-content/a.css"><script src="/wp-includes/b.js"></script>`,
+			},
+			map[string]result{"WordPress": {"6.4.2", 100}, "PHP": {"8.2.1", 100}, "MySQL": {"", 100}},
+		},
+		{
+			"cookie presence",
+			Input{Headers: http.Header{"Set-Cookie": {"PHPSESSID=abc; path=/"}}},
+			map[string]result{"PHP": {"", 100}},
+		},
+		{
+			"ternary version when the group matched",
+			Input{Body: `<script src="https://www.google-analytics.com/analytics.js"></script>`},
+			map[string]result{"Google Analytics": {"UA", 100}},
+		},
+		{
+			"ternary version when the group did not match",
+			Input{Body: `<script src="https://ssl.google-analytics.com/ga.js"></script>`},
+			map[string]result{"Google Analytics": {"GA", 100}},
+		},
+		{
+			"script source version",
+			Input{Body: `<script src="/static/jquery-3.7.1.min.js"></script>`},
+			map[string]result{"jQuery": {"3.7.1", 100}},
+		},
+		{
+			"js global named in an inline script counts at half confidence",
+			Input{Body: `<script>console.log(jQuery.fn.jquery)</script>`},
+			map[string]result{"jQuery": {"", 50}},
+		},
+		{
+			"excludes",
+			Input{Headers: http.Header{"Server": {"Tengine/2.3.3 nginx/1.18.0"}}},
+			map[string]result{"Tengine": {"", 100}},
+		},
+		{
+			"nothing matches",
+			Input{Headers: http.Header{"Server": {"Apache"}}, Body: "<html></html>"},
+			map[string]result{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]result)
+			for _, d := range rules.Analyze(tt.in) {
+				got[d.Name] = result{d.Version, d.Confidence}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDetectionFields(t *testing.T) {
+	detections := loadTestRules(t).Analyze(Input{Headers: http.Header{"Server": {"nginx/1.25.3"}}})
+	want := []Detection{{
+		Name:       "Nginx",
+		Version:    "1.25.3",
+		Categories: []string{"Web servers"},
+		Confidence: 100,
+		Website:    "https://nginx.org/en",
+		CPE:        "cpe:2.3:a:f5:nginx:*:*:*:*:*:*:*:*",
+		Evidence:   []string{"headers:server"},
+	}}
+	if !reflect.DeepEqual(detections, want) {
+		t.Errorf("Analyze() = %+v, want %+v", detections, want)
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		template string
+		groups   []string
+		want     string
+	}{
+		{"", []string{"jquery-1.2.js", "1.2"}, ""},
+		{`\1`, []string{"jquery-1.2.js", "1.2"}, "1.2"},
+		{`\1.\2`, []string{"v3-4", "3", "4"}, "3.4"},
+		{`\1?UA:GA`, []string{"analytics.js", "analytics"}, "UA"},
+		{`\1?UA:GA`, []string{"ga.js", ""}, "GA"},
+		{`\1?\1:legacy`, []string{"v2", "2"}, "2"},
+		{`\3`, []string{"a", "b"}, ""},
+		{" 1.0 ", []string{"x"}, "1.0"},
+	}
+	for _, tt := range tests {
+		if got := resolveVersion(tt.template, tt.groups); got != tt.want {
+			t.Errorf("resolveVersion(%q, %q) = %q, want %q", tt.template, tt.groups, got, tt.want)
+		}
+	}
+}
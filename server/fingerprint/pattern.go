@@ -0,0 +1,85 @@
+package fingerprint
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pattern is one Wappalyzer pattern: a regex plus the optional version template
+// and confidence that follow it, as in `jquery-([\d.]+)\.js\;version:\1\;confidence:50`.
+type pattern struct {
+	re         *regexp.Regexp
+	version    string
+	confidence int
+}
+
+// splitPattern separates the regex from its \; attributes.
+func splitPattern(raw string) (expr, version string, confidence int) {
+	parts := strings.Split(raw, "\\;")
+	confidence = 100
+	for _, attr := range parts[1:] {
+		key, value, found := strings.Cut(attr, ":")
+		if !found {
+			continue
+		}
+		switch key {
+		case "version":
+			version = value
+		case "confidence":
+			if n, err := strconv.Atoi(value); err == nil {
+				confidence = n
+			}
+		}
+	}
+	return parts[0], version, confidence
+}
+
+// parsePattern compiles a Wappalyzer pattern. Wappalyzer patterns are JavaScript
+// regexes; ones Go's RE2 cannot compile (lookarounds, backreferences) return ok=false.
+func parsePattern(raw string) (pattern, bool) {
+	expr, version, confidence := splitPattern(raw)
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return pattern{}, false
+	}
+	return pattern{re: re, version: version, confidence: confidence}, true
+}
+
+// match reports whether value matches and the version it yields, if any.
+func (p pattern) match(value string) (bool, string) {
+	groups := p.re.FindStringSubmatch(value)
+	if groups == nil {
+		return false, ""
+	}
+	return true, resolveVersion(p.version, groups)
+}
+
+var versionGroupRegex = regexp.MustCompile(`\\(\d+)`)
+var versionTernaryRegex = regexp.MustCompile(`\\(\d+)\?([^:]*):(.*)`)
+
+// resolveVersion fills a version template: \1 is replaced by the first group and
+// \1?a:b picks a or b depending on whether the group matched.
+func resolveVersion(template string, groups []string) string {
+	if template == "" {
+		return ""
+	}
+	group := func(s string) string {
+		n, _ := strconv.Atoi(s)
+		if n < len(groups) {
+			return groups[n]
+		}
+		return ""
+	}
+	if m := versionTernaryRegex.FindStringSubmatch(template); m != nil {
+		if group(m[1]) != "" {
+			template = m[2]
+		} else {
+			template = m[3]
+		}
+	}
+	version := versionGroupRegex.ReplaceAllStringFunc(template, func(ref string) string {
+		return group(ref[1:])
+	})
+	return strings.TrimSpace(version)
+}
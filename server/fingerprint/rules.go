@@ -0,0 +1,308 @@
+package fingerprint
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed technologies.json
+var embeddedRules []byte
+
+type implication struct {
+	name       string
+	confidence int
+}
+
+type technology struct {
+	name       string
+	categories []int
+	website    string
+	cpe        string
+	headers    map[string][]pattern
+	cookies    map[string][]pattern
+	meta       map[string][]pattern
+	js         map[string][]pattern
+	html       []pattern
+	scriptSrc  []pattern
+	scripts    []pattern
+	url        []pattern
+	implies    []implication
+	excludes   []string
+}
+
+// Ruleset is a parsed set of Wappalyzer-format technology rules. Version is a
+// hash of the rule files, so stored detections can be traced back to the rules
+// that produced them.
+type Ruleset struct {
+	technologies    []*technology
+	byName          map[string]*technology
+	byLowerName     map[string]*technology
+	categories      map[int]string
+	Source          string    `json:"source"`
+	Version         string    `json:"version"`
+	Technologies    int       `json:"technologies"`
+	Categories      int       `json:"categories"`
+	SkippedPatterns int       `json:"skipped_patterns"`
+	LoadedAt        time.Time `json:"loaded_at"`
+}
+
+var current atomic.Pointer[Ruleset]
+
+func init() {
+	rules, err := ParseRules(map[string][]byte{"technologies.json": embeddedRules}, "embedded")
+	if err != nil {
+		panic(fmt.Sprintf("fingerprint: embedded rules are invalid: %v", err))
+	}
+	current.Store(rules)
+}
+
+// Current returns the ruleset in use.
+func Current() *Ruleset {
+	return current.Load()
+}
+
+// SetRules swaps the ruleset used by Analyze.
+func SetRules(rules *Ruleset) {
+	if rules != nil {
+		current.Store(rules)
+	}
+}
+
+// flexible accepts the string-or-array values Wappalyzer uses for most fields.
+type flexible []string
+
+func (f *flexible) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*f = flexible{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*f = many
+	return nil
+}
+
+type rawTechnology struct {
+	Cats      []int               `json:"cats"`
+	Website   string              `json:"website"`
+	CPE       string              `json:"cpe"`
+	Headers   map[string]flexible `json:"headers"`
+	Cookies   map[string]flexible `json:"cookies"`
+	Meta      map[string]flexible `json:"meta"`
+	JS        map[string]flexible `json:"js"`
+	HTML      flexible            `json:"html"`
+	ScriptSrc flexible            `json:"scriptSrc"`
+	Scripts   flexible            `json:"scripts"`
+	URL       flexible            `json:"url"`
+	Implies   flexible            `json:"implies"`
+	Excludes  flexible            `json:"excludes"`
+}
+
+type rawCategory struct {
+	Name string `json:"name"`
+}
+
+// ParseRules builds a ruleset from Wappalyzer rule files keyed by file name. A
+// file may hold the classic {"categories": …, "technologies": …} layout, just a
+// categories map (categories.json) or just a map of technologies (a.json, b.json…).
+func ParseRules(files map[string][]byte, source string) (*Ruleset, error) {
+	rules := &Ruleset{byName: make(map[string]*technology), byLowerName: make(map[string]*technology), categories: make(map[int]string), Source: source, LoadedAt: time.Now()}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	raw := make(map[string]rawTechnology)
+	for _, name := range names {
+		data := files[name]
+		hash.Write([]byte(name))
+		hash.Write(data)
+
+		var envelope struct {
+			Categories   map[string]rawCategory   `json:"categories"`
+			Technologies map[string]rawTechnology `json:"technologies"`
+			Apps         map[string]rawTechnology `json:"apps"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if envelope.Technologies != nil || envelope.Apps != nil || envelope.Categories != nil {
+			addCategories(rules, envelope.Categories)
+			for techName, t := range envelope.Technologies {
+				raw[techName] = t
+			}
+			for techName, t := range envelope.Apps {
+				raw[techName] = t
+			}
+			continue
+		}
+
+		if strings.EqualFold(filepath.Base(name), "categories.json") {
+			var categories map[string]rawCategory
+			if err := json.Unmarshal(data, &categories); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			addCategories(rules, categories)
+			continue
+		}
+		var techs map[string]rawTechnology
+		if err := json.Unmarshal(data, &techs); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for techName, t := range techs {
+			raw[techName] = t
+		}
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no technologies found")
+	}
+
+	for name, r := range raw {
+		t := &technology{
+			name:       name,
+			categories: r.Cats,
+			website:    r.Website,
+			cpe:        r.CPE,
+			headers:    rules.compileMap(r.Headers, true),
+			cookies:    rules.compileMap(r.Cookies, false),
+			meta:       rules.compileMap(r.Meta, true),
+			js:         rules.compileMap(r.JS, false),
+			html:       rules.compileList(r.HTML),
+			scriptSrc:  rules.compileList(r.ScriptSrc),
+			scripts:    rules.compileList(r.Scripts),
+			url:        rules.compileList(r.URL),
+			excludes:   r.Excludes,
+		}
+		// implies entries are names rather than regexes, but share the \; syntax
+		for _, implied := range r.Implies {
+			name, _, confidence := splitPattern(implied)
+			t.implies = append(t.implies, implication{name: name, confidence: confidence})
+		}
+		rules.technologies = append(rules.technologies, t)
+		rules.byName[name] = t
+		rules.byLowerName[strings.ToLower(name)] = t
+	}
+	sort.Slice(rules.technologies, func(i, j int) bool { return rules.technologies[i].name < rules.technologies[j].name })
+
+	rules.Version = hex.EncodeToString(hash.Sum(nil))[:12]
+	rules.Technologies = len(rules.technologies)
+	rules.Categories = len(rules.categories)
+	return rules, nil
+}
+
+func addCategories(rules *Ruleset, categories map[string]rawCategory) {
+	for id, c := range categories {
+		var n int
+		if _, err := fmt.Sscanf(id, "%d", &n); err == nil {
+			rules.categories[n] = c.Name
+		}
+	}
+}
+
+func (r *Ruleset) compileList(raw flexible) []pattern {
+	var patterns []pattern
+	for _, s := range raw {
+		if p, ok := parsePattern(s); ok {
+			patterns = append(patterns, p)
+		} else {
+			r.SkippedPatterns++
+		}
+	}
+	return patterns
+}
+
+func (r *Ruleset) compileMap(raw map[string]flexible, lowerKeys bool) map[string][]pattern {
+	if len(raw) == 0 {
+		return nil
+	}
+	compiled := make(map[string][]pattern, len(raw))
+	for key, values := range raw {
+		if lowerKeys {
+			key = strings.ToLower(key)
+		}
+		// An empty pattern compiles to one matching anything: "present with any value"
+		if patterns := r.compileList(values); len(patterns) > 0 {
+			compiled[key] = patterns
+		}
+	}
+	return compiled
+}
+
+// LoadRules reads rules from a single JSON file or from a directory of them, such
+// as a checkout of Wappalyzer's src/ folder (categories.json plus technologies/*.json).
+func LoadRules(path string) (*Ruleset, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Base(path)] = data
+	} else {
+		err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			// Skip non-rule JSON that ships alongside the rules, such as groups.json
+			if !bytes.Contains(data, []byte(`"cats"`)) && !strings.EqualFold(fi.Name(), "categories.json") {
+				return nil
+			}
+			rel, _ := filepath.Rel(path, p)
+			files[rel] = data
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	rules, err := ParseRules(files, path)
+	if err != nil {
+		return nil, err
+	}
+	rules.LoadedAt = info.ModTime()
+	return rules, nil
+}
+
+// categoryNames returns the names of a technology's categories.
+func (r *Ruleset) categoryNames(t *technology) []string {
+	names := make([]string, 0, len(t.categories))
+	for _, id := range t.categories {
+		if name, ok := r.categories[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CPE returns the CPE 2.3 prefix a rule declares for a technology, looked up by
+// name without regard to case, and the rule's own spelling of the name.
+func (r *Ruleset) CPE(name string) (cpe, canonical string) {
+	t, ok := r.byLowerName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", ""
+	}
+	return t.cpe, t.name
+}
@@ -0,0 +1,95 @@
+package iptrie
+
+import (
+	"math/big"
+	"net/netip"
+	"sort"
+)
+
+// lastAddr is the highest address inside p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	k, maxBits := key(p.Addr())
+	for i := p.Bits(); i < maxBits; i++ {
+		k[i/8] |= 1 << (7 - uint(i%8))
+	}
+	if maxBits == 32 {
+		return netip.AddrFrom4([4]byte(k[:4]))
+	}
+	return netip.AddrFrom16(k)
+}
+
+// LastAddr is the highest address inside p.
+func LastAddr(p netip.Prefix) netip.Addr {
+	return lastAddr(p.Masked())
+}
+
+// RangePrefixes splits the inclusive range start-end into the fewest prefixes
+// that cover it exactly. Both ends must be of the same family.
+func RangePrefixes(start, end netip.Addr) []netip.Prefix {
+	start, end = start.Unmap(), end.Unmap()
+	if !start.IsValid() || !end.IsValid() || start.Is4() != end.Is4() || end.Less(start) {
+		return nil
+	}
+	var prefixes []netip.Prefix
+	for {
+		// The largest block starting at start that does not run past end
+		p := netip.PrefixFrom(start, start.BitLen())
+		for bits := 0; bits <= start.BitLen(); bits++ {
+			candidate := netip.PrefixFrom(start, bits)
+			if candidate.Masked().Addr() == start && !end.Less(lastAddr(candidate.Masked())) {
+				p = candidate.Masked()
+				break
+			}
+		}
+		prefixes = append(prefixes, p)
+		last := lastAddr(p)
+		if last == end {
+			return prefixes
+		}
+		start = last.Next()
+	}
+}
+
+// AddressCount is the number of addresses in p.
+func AddressCount(p netip.Prefix) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+}
+
+// Aggregate returns the minimal set of prefixes covering exactly the addresses
+// of the given ones: duplicates and nested prefixes are dropped and adjacent
+// halves merged into their parent. The result is sorted, IPv4 first.
+func Aggregate(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p = normalize(p); p.IsValid() {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	var result []netip.Prefix
+	for _, p := range sorted {
+		if n := len(result); n > 0 && result[n-1].Overlaps(p) {
+			// Sorted by address then size, so an overlap means p is inside the last one
+			continue
+		}
+		result = append(result, p)
+		for len(result) >= 2 {
+			a, b := result[len(result)-2], result[len(result)-1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().Is4() != b.Addr().Is4() {
+				break
+			}
+			parent := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+			if parent.Addr() != a.Addr() || !parent.Contains(b.Addr()) {
+				break
+			}
+			result = append(result[:len(result)-2], parent)
+		}
+	}
+	return result
+}
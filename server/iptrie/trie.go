@@ -0,0 +1,266 @@
+// Package iptrie is a path-compressed binary radix tree keyed by IP prefix, for
+// longest-prefix lookups over large range files.
+package iptrie
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// node keeps only the key bits; the prefix is rebuilt from the tree it hangs
+// off. Nodes live in one slice and refer to their children by index, which
+// keeps full-table datasets small and cheap for the garbage collector.
+type node[T any] struct {
+	key      [16]byte
+	bits     uint8
+	hasValue bool
+	child    [2]uint32
+	value    T
+}
+
+func (n *node[T]) prefix(is4 bool) netip.Prefix {
+	if is4 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(n.key[:4])), int(n.bits))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(n.key), int(n.bits))
+}
+
+// Trie maps IPv4 and IPv6 prefixes to values. It is not safe for concurrent
+// writes; build it once and publish it, after which lookups may run in parallel.
+// Node references are slice index plus one, so zero means none.
+type Trie[T any] struct {
+	nodes  []node[T]
+	v4, v6 uint32
+	size   int
+}
+
+// key returns the prefix bits of p, left aligned, and how many of them count.
+func key(addr netip.Addr) ([16]byte, int) {
+	var k [16]byte
+	if addr.Is4() {
+		a := addr.As4()
+		copy(k[:], a[:])
+		return k, 32
+	}
+	return addr.As16(), 128
+}
+
+func bit(k [16]byte, i int) int {
+	return int(k[i/8]>>(7-uint(i%8))) & 1
+}
+
+// commonBits counts the leading bits a and b share, up to max.
+func commonBits(a, b [16]byte, max int) int {
+	n := 0
+	for i := 0; i < 16 && n < max; i++ {
+		if x := a[i] ^ b[i]; x != 0 {
+			n += bits.LeadingZeros8(x)
+			break
+		}
+		n += 8
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func maskKey(k [16]byte, n int) [16]byte {
+	for i := range k {
+		switch {
+		case n >= 8:
+			n -= 8
+		case n > 0:
+			k[i] &= ^byte(0xff >> uint(n))
+			n = 0
+		default:
+			k[i] = 0
+		}
+	}
+	return k
+}
+
+func (t *Trie[T]) node(ref uint32) *node[T] {
+	if ref == 0 {
+		return nil
+	}
+	return &t.nodes[ref-1]
+}
+
+func (t *Trie[T]) add(n node[T]) uint32 {
+	t.nodes = append(t.nodes, n)
+	return uint32(len(t.nodes))
+}
+
+func (t *Trie[T]) root(addr netip.Addr) uint32 {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// Insert sets the value for a prefix, replacing any value already stored for
+// exactly that prefix. IPv4-mapped IPv6 prefixes are stored as IPv4.
+func (t *Trie[T]) Insert(p netip.Prefix, value T) {
+	p = normalize(p)
+	if !p.IsValid() {
+		return
+	}
+	k, _ := key(p.Addr())
+	plen := p.Bits()
+	leaf := node[T]{key: k, bits: uint8(plen), value: value, hasValue: true}
+
+	// set replaces the reference that led to the current node
+	set := func(ref uint32) {
+		if p.Addr().Is4() {
+			t.v4 = ref
+		} else {
+			t.v6 = ref
+		}
+	}
+	ref := t.root(p.Addr())
+	for {
+		if ref == 0 {
+			set(t.add(leaf))
+			t.size++
+			return
+		}
+		n := t.node(ref)
+		nbits := int(n.bits)
+		common := commonBits(k, n.key, min(plen, nbits))
+		switch {
+		case common == nbits && common == plen:
+			if !n.hasValue {
+				t.size++
+			}
+			n.value, n.hasValue = value, true
+			return
+		case common == nbits:
+			side := bit(k, nbits)
+			parent := ref
+			set = func(ref uint32) { t.node(parent).child[side] = ref }
+			ref = n.child[side]
+		case common == plen:
+			leaf.child[bit(n.key, plen)] = ref
+			set(t.add(leaf))
+			t.size++
+			return
+		default:
+			branch := node[T]{key: maskKey(k, common), bits: uint8(common)}
+			branch.child[bit(n.key, common)] = ref
+			branch.child[bit(k, common)] = t.add(leaf)
+			set(t.add(branch))
+			t.size++
+			return
+		}
+	}
+}
+
+// Get returns the value stored for exactly p.
+func (t *Trie[T]) Get(p netip.Prefix) (T, bool) {
+	var zero T
+	p = normalize(p)
+	if !p.IsValid() {
+		return zero, false
+	}
+	k, _ := key(p.Addr())
+	for n := t.node(t.root(p.Addr())); n != nil && int(n.bits) <= p.Bits(); n = t.node(n.child[bit(k, int(n.bits))]) {
+		if commonBits(k, n.key, int(n.bits)) < int(n.bits) {
+			break
+		}
+		if int(n.bits) == p.Bits() {
+			return n.value, n.hasValue
+		}
+	}
+	return zero, false
+}
+
+// Lookup returns the most specific prefix containing addr and its value.
+func (t *Trie[T]) Lookup(addr netip.Addr) (netip.Prefix, T, bool) {
+	var zero T
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return netip.Prefix{}, zero, false
+	}
+	k, maxBits := key(addr)
+	var best *node[T]
+	for n := t.node(t.root(addr)); n != nil; n = t.node(n.child[bit(k, int(n.bits))]) {
+		if commonBits(k, n.key, int(n.bits)) < int(n.bits) {
+			break
+		}
+		if n.hasValue {
+			best = n
+		}
+		if int(n.bits) == maxBits {
+			break
+		}
+	}
+	if best == nil {
+		return netip.Prefix{}, zero, false
+	}
+	return best.prefix(addr.Is4()), best.value, true
+}
+
+// Parent returns the most specific stored prefix strictly containing p.
+func (t *Trie[T]) Parent(p netip.Prefix) (netip.Prefix, T, bool) {
+	var zero T
+	p = normalize(p)
+	if !p.IsValid() {
+		return netip.Prefix{}, zero, false
+	}
+	k, _ := key(p.Addr())
+	var best *node[T]
+	for n := t.node(t.root(p.Addr())); n != nil && int(n.bits) < p.Bits(); n = t.node(n.child[bit(k, int(n.bits))]) {
+		if commonBits(k, n.key, int(n.bits)) < int(n.bits) {
+			break
+		}
+		if n.hasValue {
+			best = n
+		}
+	}
+	if best == nil {
+		return netip.Prefix{}, zero, false
+	}
+	return best.prefix(p.Addr().Is4()), best.value, true
+}
+
+// Len is the number of prefixes stored.
+func (t *Trie[T]) Len() int {
+	return t.size
+}
+
+// Compact releases the spare capacity left over from building the trie.
+func (t *Trie[T]) Compact() {
+	t.nodes = append([]node[T](nil), t.nodes...)
+}
+
+// Walk calls fn for every stored prefix in address order until fn returns false.
+func (t *Trie[T]) Walk(fn func(netip.Prefix, T) bool) {
+	var walk func(ref uint32, is4 bool) bool
+	walk = func(ref uint32, is4 bool) bool {
+		n := t.node(ref)
+		if n == nil {
+			return true
+		}
+		if n.hasValue && !fn(n.prefix(is4), n.value) {
+			return false
+		}
+		return walk(n.child[0], is4) && walk(n.child[1], is4)
+	}
+	if walk(t.v4, true) {
+		walk(t.v6, false)
+	}
+}
+
+func normalize(p netip.Prefix) netip.Prefix {
+	if !p.IsValid() {
+		return p
+	}
+	if p.Addr().Is4In6() {
+		if p.Bits() < 96 {
+			return netip.Prefix{}
+		}
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked()
+}
@@ -10,11 +10,11 @@ import (
 	"math"
 	"net/http"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
+	"ars0n-framework-v2-server/domainutil"
 	"ars0n-framework-v2-server/utils"
 
 	"github.com/google/uuid"
@@ -30,6 +30,13 @@ func min(a, b int) int {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		if err := utils.RunWorker(utils.WorkerConfigFromEnv()); err != nil {
+			log.Fatalf("Worker exited: %v", err)
+		}
+		return
+	}
+
 	connStr := os.Getenv("DATABASE_URL")
 	if connStr == "" {
 		log.Fatal("Environment variable DATABASE_URL is not set")
@@ -56,6 +63,15 @@ func main() {
 
 	createTables()
 
+	go utils.RunToolPreflight()
+	utils.StartScanWorkerReaper()
+	utils.MarkInterruptedIPPortScans()
+	utils.InitPublicSuffixList()
+	utils.InitFingerprintRules()
+	utils.InitCloudRanges()
+	utils.InitASNDatabase()
+	utils.StartResolverPoolValidator()
+
 	r := mux.NewRouter()
 
 	// Apply CORS middleware first
@@ -125,6 +141,7 @@ func main() {
 	r.HandleFunc("/consolidated-company-domains/{id}", utils.GetConsolidatedCompanyDomains).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidate-network-ranges/{id}", utils.HandleConsolidateNetworkRanges).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidated-network-ranges/{id}", utils.GetConsolidatedNetworkRanges).Methods("GET", "OPTIONS")
+	r.HandleFunc("/network-range-coverage/{id}", utils.GetNetworkRangeCoverage).Methods("GET", "OPTIONS")
 	r.HandleFunc("/consolidate-attack-surface/{scope_target_id}", utils.ConsolidateAttackSurface).Methods("POST", "OPTIONS")
 	r.HandleFunc("/investigate-fqdns/{scope_target_id}", utils.InvestigateFQDNs).Methods("POST", "OPTIONS")
 	r.HandleFunc("/attack-surface-asset-counts/{scope_target_id}", utils.GetAttackSurfaceAssetCounts).Methods("GET", "OPTIONS")
@@ -141,6 +158,11 @@ func main() {
 	r.HandleFunc("/cewl-wordlist/run", utils.RunShuffleDNSWithWordlist).Methods("POST", "OPTIONS")
 	r.HandleFunc("/cewl-wordlist/{scan_id}", utils.GetShuffleDNSScanStatus).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/scope-targets/{id}/shufflednscustom-scans", utils.GetShuffleDNSCustomScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/subdomain-permutation/run", utils.RunSubdomainPermutationScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/subdomain-permutation/{scan_id}", utils.GetSubdomainPermutationScanStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/subdomain-permutation/{scan_id}/results", utils.GetSubdomainPermutationResults).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/subdomain-permutation", utils.GetSubdomainPermutationScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/subdomain-permutation/preview", utils.PreviewSubdomainPermutations).Methods("POST", "OPTIONS")
 	r.HandleFunc("/gospider/run", utils.RunGoSpiderScan).Methods("POST", "OPTIONS")
 	r.HandleFunc("/gospider/{scan_id}", utils.GetGoSpiderScanStatus).Methods("GET", "OPTIONS")
 	r.HandleFunc("/scopetarget/{id}/scans/gospider", utils.GetGoSpiderScansForScopeTarget).Methods("GET", "OPTIONS")
@@ -225,6 +247,41 @@ func main() {
 	r.HandleFunc("/scopetarget/{id}/scans/ip-port", utils.GetIPPortScansForScopeTarget).Methods("GET", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/live-web-servers", utils.GetLiveWebServers).Methods("GET", "OPTIONS")
 	r.HandleFunc("/ip-port-scan/{scan_id}/discovered-ips", utils.GetDiscoveredIPs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ip-port-scan/{scan_id}/services", utils.GetDiscoveredServices).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ip-port-scan/{scan_id}/checkpoints", utils.GetIPPortScanCheckpoints).Methods("GET", "OPTIONS")
+	r.HandleFunc("/ip-port-scan/{scan_id}/pause", utils.PauseIPPortScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/ip-port-scan/{scan_id}/resume", utils.ResumeIPPortScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/port-profiles", utils.GetPortProfiles).Methods("GET", "OPTIONS")
+	r.HandleFunc("/port-profiles", utils.CreatePortProfile).Methods("POST", "OPTIONS")
+	r.HandleFunc("/port-profiles/{profile_id}", utils.UpdatePortProfile).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/port-profiles/{profile_id}", utils.DeletePortProfile).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/vhost-discovery/run", utils.RunVhostDiscoveryScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/vhost-discovery/{scan_id}/results", utils.GetVhostDiscoveryResults).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/vhost-discovery", utils.GetVhostDiscoveryScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/origin-discovery/run", utils.RunOriginDiscoveryScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/origin-discovery/{scan_id}/results", utils.GetOriginDiscoveryResults).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/origin-discovery", utils.GetOriginDiscoveryScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/edge-classification/run", utils.RunEdgeClassificationScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/edge-classification", utils.GetEdgeClassificationScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/fingerprint/rules", utils.GetFingerprintRules).Methods("GET", "OPTIONS")
+	r.HandleFunc("/fingerprint/rules/reload", utils.ReloadFingerprintRules).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/fingerprint", utils.RunTechnologyFingerprint).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/technologies", utils.GetTechnologyDetections).Methods("GET", "OPTIONS")
+	r.HandleFunc("/vulnerabilities/feeds/import", utils.ImportVulnerabilityFeeds).Methods("POST", "OPTIONS")
+	r.HandleFunc("/vulnerabilities/feeds", utils.GetVulnerabilityFeedImports).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/vulnerabilities/match", utils.RunVulnerabilityMatch).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/vulnerabilities", utils.GetScopeTargetVulnerabilities).Methods("GET", "OPTIONS")
+	r.HandleFunc("/favicon/run", utils.RunFaviconScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/favicon", utils.GetFaviconScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/favicons", utils.GetFaviconGroups).Methods("GET", "OPTIONS")
+	r.HandleFunc("/favicon-pivot/run", utils.RunFaviconPivotScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/favicon-pivot", utils.GetFaviconPivotScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/certificate-names", utils.GetCertificateNames).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cloud-exposure/run", utils.RunCloudExposureScan).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/scans/cloud-exposure", utils.GetCloudExposureScansForScopeTarget).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/cloud-exposure", utils.GetCloudExposureFindings).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/import-results", utils.ImportScanResults).Methods("POST", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/imports", utils.GetImportedScans).Methods("GET", "OPTIONS")
 
 	// Company domain management routes
 	r.HandleFunc("/api/company-domains/{scope_target_id}/{tool}", getCompanyDomainsByTool).Methods("GET", "OPTIONS")
@@ -365,6 +422,7 @@ func main() {
 	r.HandleFunc("/security-controls/{scope_target_id}/notes", utils.CreateSecurityControlNote).Methods("POST", "OPTIONS")
 	r.HandleFunc("/security-controls/notes/{note_id}", utils.UpdateSecurityControlNote).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/security-controls/notes/{note_id}", utils.DeleteSecurityControlNote).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/security-controls/{scope_target_id}/edge", utils.GetEdgeControlsSummary).Methods("GET", "OPTIONS")
 
 	r.HandleFunc("/threat-model/{scope_target_id}", utils.GetThreatModel).Methods("GET", "OPTIONS")
 	r.HandleFunc("/threat-model/{scope_target_id}", utils.CreateThreatModel).Methods("POST", "OPTIONS")
@@ -372,6 +430,52 @@ func main() {
 	r.HandleFunc("/threat-model/{threat_id}", utils.DeleteThreatModel).Methods("DELETE", "OPTIONS")
 
 	r.HandleFunc("/health", utils.HealthCheck).Methods("GET", "OPTIONS")
+	r.HandleFunc("/tools/status", utils.GetToolStatuses).Methods("GET", "OPTIONS")
+	r.HandleFunc("/tools/status/{tool}", utils.GetToolStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/tools/preflight", utils.RunToolPreflightHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/tools/executors", utils.GetToolExecutorConfigs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/tools/executors/{tool}", utils.SaveToolExecutorConfig).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/tools/executors/{tool}", utils.DeleteToolExecutorConfig).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/workers/register", utils.RegisterScanWorker).Methods("POST", "OPTIONS")
+	r.HandleFunc("/workers/heartbeat", utils.ScanWorkerHeartbeat).Methods("POST", "OPTIONS")
+	r.HandleFunc("/workers/jobs/claim", utils.ClaimScanWorkerJob).Methods("POST", "OPTIONS")
+	r.HandleFunc("/workers/jobs/{job_id}/output", utils.PostScanWorkerJobOutput).Methods("POST", "OPTIONS")
+	r.HandleFunc("/workers/jobs/{job_id}/complete", utils.CompleteScanWorkerJob).Methods("POST", "OPTIONS")
+	r.HandleFunc("/workers", utils.GetScanWorkers).Methods("GET", "OPTIONS")
+	r.HandleFunc("/workers/jobs", utils.GetScanWorkerJobs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/workers/{id}", utils.DeleteScanWorker).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/egress/pools", utils.GetEgressPools).Methods("GET", "OPTIONS")
+	r.HandleFunc("/egress/pools", utils.CreateEgressPool).Methods("POST", "OPTIONS")
+	r.HandleFunc("/egress/pools/{id}", utils.UpdateEgressPool).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/egress/pools/{id}", utils.DeleteEgressPool).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/egress/pools/{id}/proxies", utils.AddEgressProxy).Methods("POST", "OPTIONS")
+	r.HandleFunc("/egress/proxies/{id}", utils.DeleteEgressProxy).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/egress/proxies/{id}/test", utils.TestEgressProxy).Methods("POST", "OPTIONS")
+	r.HandleFunc("/egress/policies", utils.GetEgressPolicies).Methods("GET", "OPTIONS")
+	r.HandleFunc("/egress/policies/{scope}", utils.SaveEgressPolicy).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/egress/policies/{scope}", utils.DeleteEgressPolicy).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/domains/psl", utils.GetPublicSuffixListStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/domains/psl/refresh", utils.RefreshPublicSuffixList).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cloud-ranges", utils.GetCloudRangesStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/cloud-ranges/refresh", utils.RefreshCloudRanges).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cloud-ranges/reload", utils.ReloadCloudRanges).Methods("POST", "OPTIONS")
+	r.HandleFunc("/cloud-ranges/lookup", utils.LookupCloudRange).Methods("GET", "OPTIONS")
+	r.HandleFunc("/asn-db", utils.GetASNDatabaseStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/asn-db/refresh", utils.RefreshASNDatabase).Methods("POST", "OPTIONS")
+	r.HandleFunc("/asn-db/reload", utils.ReloadASNDatabase).Methods("POST", "OPTIONS")
+	r.HandleFunc("/asn-db/lookup", utils.LookupASN).Methods("GET", "OPTIONS")
+	r.HandleFunc("/dns/resolvers", utils.GetDNSResolvers).Methods("GET", "OPTIONS")
+	r.HandleFunc("/dns/resolvers", utils.AddDNSResolvers).Methods("POST", "OPTIONS")
+	r.HandleFunc("/dns/resolvers/validate", utils.ValidateDNSResolvers).Methods("POST", "OPTIONS")
+	r.HandleFunc("/dns/resolvers/{id}", utils.UpdateDNSResolver).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/dns/resolvers/{id}", utils.DeleteDNSResolver).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/request-profiles", utils.GetRequestProfiles).Methods("GET", "OPTIONS")
+	r.HandleFunc("/scopetarget/{id}/request-profiles", utils.CreateRequestProfile).Methods("POST", "OPTIONS")
+	r.HandleFunc("/request-profiles/{profile_id}", utils.GetRequestProfile).Methods("GET", "OPTIONS")
+	r.HandleFunc("/request-profiles/{profile_id}", utils.UpdateRequestProfile).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/request-profiles/{profile_id}", utils.DeleteRequestProfile).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/request-profiles/{profile_id}/default", utils.SetDefaultRequestProfile).Methods("POST", "OPTIONS")
+	r.HandleFunc("/request-profiles/{profile_id}/login", utils.RefreshRequestProfileSession).Methods("POST", "OPTIONS")
 	r.HandleFunc("/manual-crawl/start", utils.StartManualCrawl).Methods("POST", "OPTIONS")
 	r.HandleFunc("/manual-crawl/capture", utils.CaptureManualCrawlRequest).Methods("POST", "OPTIONS")
 	r.HandleFunc("/manual-crawl/stop", utils.StopManualCrawl).Methods("POST", "OPTIONS")
@@ -380,6 +484,7 @@ func main() {
 	r.HandleFunc("/manual-crawl/sessions/{scope_target_id}", utils.GetManualCrawlSessions).Methods("GET", "OPTIONS")
 	r.HandleFunc("/manual-crawl/captures/{session_id}", utils.GetManualCrawlCaptures).Methods("GET", "OPTIONS")
 	r.HandleFunc("/manual-crawl/endpoints/{scope_target_id}", utils.GetManualCrawlEndpoints).Methods("GET", "OPTIONS")
+	r.HandleFunc("/manual-crawl/import/{scope_target_id}", utils.ImportManualCrawlHistory).Methods("POST", "OPTIONS")
 
 	r.HandleFunc("/consolidated-endpoints/{scope_target_id}/consolidate", utils.ConsolidateURLEndpoints).Methods("POST", "OPTIONS")
 	r.HandleFunc("/consolidated-endpoints/{scope_target_id}", utils.GetConsolidatedURLEndpoints).Methods("GET", "OPTIONS")
@@ -2005,6 +2110,8 @@ func getCompanyDomainsByTool(w http.ResponseWriter, r *http.Request) {
 		domains, err = utils.GetShodanCompanyDomainsForTool(scopeTargetID)
 	case "live_web_servers":
 		domains, err = utils.GetLiveWebServerDomainsForTool(scopeTargetID)
+	case "tls_certificates":
+		domains, err = utils.GetTLSCertificateDomainsForTool(scopeTargetID)
 	default:
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
 		return
@@ -2063,6 +2170,8 @@ func deleteCompanyDomainFromTool(w http.ResponseWriter, r *http.Request) {
 		success, err = utils.DeleteShodanCompanyDomainFromTool(scopeTargetID, domain)
 	case "live_web_servers":
 		success, err = utils.DeleteLiveWebServerDomainFromTool(scopeTargetID, domain)
+	case "tls_certificates":
+		success, err = utils.DeleteTLSCertificateDomainFromTool(scopeTargetID, domain)
 	default:
 		log.Printf("[DOMAIN-API] [ERROR] Invalid tool specified: %s", tool)
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
@@ -2128,6 +2237,8 @@ func deleteAllCompanyDomainsFromTool(w http.ResponseWriter, r *http.Request) {
 		count, err = utils.DeleteAllShodanCompanyDomainsFromTool(scopeTargetID)
 	case "live_web_servers":
 		count, err = utils.DeleteAllLiveWebServerDomainsFromTool(scopeTargetID)
+	case "tls_certificates":
+		count, err = utils.DeleteAllTLSCertificateDomainsFromTool(scopeTargetID)
 	default:
 		log.Printf("[DOMAIN-API] [ERROR] Invalid tool specified: %s", tool)
 		http.Error(w, "Invalid tool specified", http.StatusBadRequest)
@@ -2933,18 +3044,14 @@ func buildWordlistFromDomains(w http.ResponseWriter, r *http.Request) {
 
 	// Extract unique words from domains
 	domainWords := make(map[string]bool)
-	tlds := map[string]bool{
-		"com": true, "net": true, "org": true, "edu": true, "gov": true,
-		"mil": true, "co": true, "uk": true, "au": true, "ca": true,
-		"de": true, "fr": true, "jp": true, "br": true, "mx": true,
-		"in": true, "cn": true, "ru": true, "kr": true, "it": true,
+	stopWords := map[string]bool{
 		"www": true, "mail": true, "ftp": true, "smtp": true, "pop": true,
 		"imap": true, "ns": true, "dns": true,
 	}
 
 	for _, domain := range allDomains {
-		// Split domain by dots and extract words
-		parts := strings.Split(strings.ToLower(domain), ".")
+		// Split the labels below the public suffix so "co.uk" or "github.io" never become words
+		parts := domainutil.Labels(domain)
 		for _, part := range parts {
 			// Further split by hyphens and underscores
 			subParts := strings.FieldsFunc(part, func(c rune) bool {
@@ -2953,7 +3060,7 @@ func buildWordlistFromDomains(w http.ResponseWriter, r *http.Request) {
 
 			for _, word := range subParts {
 				word = strings.TrimSpace(word)
-				if len(word) >= 2 && !tlds[word] && word != "" {
+				if len(word) >= 2 && !stopWords[word] && word != "" {
 					domainWords[word] = true
 				}
 			}
@@ -2961,8 +3068,10 @@ func buildWordlistFromDomains(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build wordlist in container using the same pattern as resolvers
-	containerName := "ars0n-framework-v2-cloud_enum-1"
+	executor := utils.ToolExecutor("cloud_enum")
 	tempFile := fmt.Sprintf("/tmp/generated_%s_%s.txt", wordlistType, scopeTargetID)
+	toolTempFile := executor.Path(tempFile)
+	baseWordlist := executor.Path("/app/rs0nfuzz.txt")
 
 	// Create the wordlist generation script
 	var domainWordsList []string
@@ -2974,7 +3083,7 @@ func buildWordlistFromDomains(w http.ResponseWriter, r *http.Request) {
 
 	createScript := fmt.Sprintf(`
 		# Copy base rs0nfuzz.txt wordlist
-		cp /app/rs0nfuzz.txt %s
+		cp %s %s
 		
 		# Add domain-derived words
 		cat << 'EOF' >> %s
@@ -2983,13 +3092,12 @@ EOF
 		
 		# Remove duplicates and sort
 		sort %s | uniq > %s.tmp && mv %s.tmp %s
-	`, tempFile, tempFile, domainWordsContent, tempFile, tempFile, tempFile, tempFile)
+	`, baseWordlist, toolTempFile, toolTempFile, domainWordsContent, toolTempFile, toolTempFile, toolTempFile, toolTempFile)
 
 	log.Printf("[BUILD-WORDLIST] Extracted %d unique words from domains", len(domainWordsList))
 
 	// First test if rs0nfuzz.txt exists in container
-	testCmd := exec.Command("docker", "exec", containerName, "test", "-f", "/app/rs0nfuzz.txt")
-	if err := testCmd.Run(); err != nil {
+	if _, err := executor.Exec(context.Background(), utils.ExecRequest{Args: []string{"test", "-f", baseWordlist}}); err != nil {
 		log.Printf("[BUILD-WORDLIST] rs0nfuzz.txt not found in container, using fallback approach")
 
 		// Fallback: Create wordlist with domain words + basic terms
@@ -3018,11 +3126,10 @@ EOF
 	log.Printf("[BUILD-WORDLIST] rs0nfuzz.txt found in container, executing script...")
 
 	// Execute script in container
-	cmd := exec.Command("docker", "exec", containerName, "sh", "-c", createScript)
-	output, err := cmd.CombinedOutput()
+	scriptResult, err := executor.Exec(context.Background(), utils.ExecRequest{Args: []string{"sh", "-c", createScript}})
 	if err != nil {
 		log.Printf("[BUILD-WORDLIST] Error creating wordlist in container: %v", err)
-		log.Printf("[BUILD-WORDLIST] Container output: %s", string(output))
+		log.Printf("[BUILD-WORDLIST] Container output: %s", scriptResult.Stdout+scriptResult.Stderr)
 		http.Error(w, "Failed to generate wordlist", http.StatusInternalServerError)
 		return
 	}
@@ -3030,11 +3137,8 @@ EOF
 
 	// Copy the generated wordlist back to host for download
 	hostTempFile := fmt.Sprintf("/tmp/wordlist_%s_%s.txt", wordlistType, scopeTargetID)
-	copyCmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", containerName, tempFile), hostTempFile)
-	copyOutput, copyErr := copyCmd.CombinedOutput()
-	if copyErr != nil {
+	if copyErr := executor.CopyFrom(context.Background(), tempFile, hostTempFile); copyErr != nil {
 		log.Printf("[BUILD-WORDLIST] Error copying wordlist from container: %v", copyErr)
-		log.Printf("[BUILD-WORDLIST] Copy output: %s", string(copyOutput))
 		http.Error(w, "Failed to retrieve wordlist", http.StatusInternalServerError)
 		return
 	}
@@ -3078,8 +3182,7 @@ EOF
 	os.Remove(hostTempFile)
 
 	// Clean up container temp file
-	cleanupCmd := exec.Command("docker", "exec", containerName, "rm", "-f", tempFile)
-	cleanupCmd.Run()
+	executor.Exec(context.Background(), utils.ExecRequest{Args: []string{"rm", "-f", toolTempFile}})
 }
 
 func getConsolidatedRootDomains(scopeTargetID string) ([]string, error) {
@@ -3177,7 +3280,7 @@ func getLiveWebServerDomains(scopeTargetID string) ([]string, error) {
 		if err := rows.Scan(&url); err == nil {
 			// Extract domain from URL
 			if url != "" {
-				if domain := extractDomainFromURL(url); domain != "" && !isIPv4Address(domain) {
+				if domain := domainutil.DomainFromURL(url); domain != "" {
 					domainMap[domain] = true
 				}
 			}
@@ -3192,32 +3295,6 @@ func getLiveWebServerDomains(scopeTargetID string) ([]string, error) {
 	return domains, nil
 }
 
-// Helper function to extract domain from URL
-func extractDomainFromURL(urlStr string) string {
-	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-		urlStr = "http://" + urlStr
-	}
-
-	// Simple domain extraction from URL
-	parts := strings.Split(urlStr, "/")
-	if len(parts) >= 3 {
-		hostPart := parts[2]
-		// Remove port if present
-		if colonIndex := strings.Index(hostPart, ":"); colonIndex != -1 {
-			hostPart = hostPart[:colonIndex]
-		}
-		return hostPart
-	}
-	return ""
-}
-
-// Helper function to check if a string is an IPv4 address
-func isIPv4Address(s string) bool {
-	return strings.Contains(s, ".") &&
-		len(strings.Split(s, ".")) == 4 &&
-		!strings.Contains(s, " ")
-}
-
 func getKatanaCompanyConfig(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	scopeTargetID := vars["scope_target_id"]
@@ -3748,6 +3825,15 @@ func startNucleiScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !utils.RequireTools(w, "nuclei") {
+		return
+	}
+
+	requestProfileID := r.URL.Query().Get("request_profile_id")
+	if !utils.CheckScanRequestProfile(w, scopeTargetID, requestProfileID) {
+		return
+	}
+
 	log.Printf("[INFO] Starting Nuclei scan for scope target: %s", scopeTargetID)
 
 	var targets, templates, severities, templateIDs, excludeIDs, excludeTags []string
@@ -3805,6 +3891,8 @@ func startNucleiScan(w http.ResponseWriter, r *http.Request) {
 		mode = "attack_surface"
 	}
 
+	utils.RecordScanToolVersion("nuclei_scans", scanID, "nuclei")
+
 	go func() {
 		log.Printf("[INFO] Starting background Nuclei scan %s (target_mode: %s)", scanID, mode)
 
@@ -3825,11 +3913,11 @@ func startNucleiScan(w http.ResponseWriter, r *http.Request) {
 
 		if mode == "httpx" {
 			outputFile, findings, scanErr = utils.ExecuteNucleiScanDirect(
-				targets, templates, severities, templateIDs, excludeIDs, excludeTags,
+				scopeTargetID, requestProfileID, targets, templates, severities, templateIDs, excludeIDs, excludeTags,
 				uploadedTemplates, advancedConfig)
 		} else {
 			outputFile, findings, scanErr = utils.ExecuteNucleiScanForScopeTarget(
-				scopeTargetID, targets, templates, severities, templateIDs, excludeIDs, excludeTags,
+				scopeTargetID, requestProfileID, targets, templates, severities, templateIDs, excludeIDs, excludeTags,
 				uploadedTemplates, advancedConfig, dbPool)
 		}
 
@@ -3947,15 +4035,14 @@ func listNucleiTemplates(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[INFO] Fetching Nuclei template list from container")
 
-	cmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "nuclei", "-tl")
-	output, err := cmd.Output()
+	listResult, err := utils.ToolExecutor("nuclei").Run(context.Background(), utils.ExecRequest{Args: []string{"-tl"}})
 	if err != nil {
 		log.Printf("[ERROR] Failed to list Nuclei templates: %v", err)
 		http.Error(w, "Failed to list templates", http.StatusInternalServerError)
 		return
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(strings.TrimSpace(listResult.Stdout), "\n")
 	dirMap := make(map[string]int)
 	var templates []map[string]interface{}
 
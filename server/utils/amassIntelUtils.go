@@ -1,13 +1,11 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -50,6 +48,10 @@ type IntelASNResponse struct {
 }
 
 func RunAmassIntelScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "amass") {
+		return
+	}
+
 	var payload struct {
 		CompanyName       string  `json:"company_name" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -87,6 +89,7 @@ func RunAmassIntelScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("amass_intel_scans", scanID, "amass")
 	go ExecuteAmassIntelScan(scanID, companyName)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -97,33 +100,24 @@ func ExecuteAmassIntelScan(scanID, companyName string) {
 	log.Printf("[INFO] Starting Amass Intel scan for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
-	cmd := exec.Command(
-		"docker", "run", "--rm",
-		"caffix/amass",
+	execResult, err := ToolExecutor("amass").Run(context.Background(), ExecRequest{Args: []string{
 		"intel",
 		"-org", companyName,
 		"-whois",
 		"-active",
 		"-timeout", "120",
-	)
-
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	}})
 	execTime := time.Since(startTime).String()
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
 	if err != nil {
 		log.Printf("[ERROR] Amass Intel scan failed for %s: %v", companyName, err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateIntelScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateIntelScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] Amass Intel scan completed in %s for company %s", execTime, companyName)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
@@ -135,7 +129,7 @@ func ExecuteAmassIntelScan(scanID, companyName string) {
 		log.Printf("[WARN] No output from Amass Intel scan for company %s", companyName)
 	}
 
-	UpdateIntelScanStatus(scanID, "success", "{}", stderr.String(), cmd.String(), execTime)
+	UpdateIntelScanStatus(scanID, "success", "{}", execResult.Stderr, execResult.Command, execTime)
 	log.Printf("[INFO] Intel scan status updated for scan %s", scanID)
 }
 
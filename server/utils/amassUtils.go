@@ -1,14 +1,12 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -550,6 +548,10 @@ func GetAmassScanStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunAmassScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "amass") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -588,6 +590,7 @@ func RunAmassScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("amass_scans", scanID, "amass")
 	go ExecuteAndParseAmassScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -602,52 +605,24 @@ func ExecuteAndParseAmassScan(scanID, domain string) {
 	rateLimit := GetAmassRateLimit()
 	log.Printf("[INFO] Using rate limit of %d for Amass scan", rateLimit)
 
-	cmd := exec.Command(
-		"docker", "run", "--rm",
-		"caffix/amass",
+	execResult, err := ToolExecutor("amass").Run(context.Background(), ExecRequest{Args: append([]string{
 		"enum", "-active", "-alts", "-brute", "-nocolor",
 		"-min-for-recursive", "2", "-timeout", "60",
 		"-d", domain,
-		"-r", "8.8.8.8",
-		"-r", "1.1.1.1",
-		"-r", "9.9.9.9",
-		"-r", "64.6.64.6",
-		"-r", "208.67.222.222",
-		"-r", "208.67.220.220",
-		"-r", "8.26.56.26",
-		"-r", "8.20.247.20",
-		"-r", "185.228.168.9",
-		"-r", "185.228.169.9",
-		"-r", "76.76.19.19",
-		"-r", "76.223.122.150",
-		"-r", "198.101.242.72",
-		"-r", "176.103.130.130",
-		"-r", "176.103.130.131",
-		"-r", "94.140.14.14",
-		"-r", "94.140.15.15",
-		"-r", "1.0.0.1",
-		"-r", "77.88.8.8",
-		"-r", "77.88.8.1",
 		"-rqps", fmt.Sprintf("%d", rateLimit),
-	)
-
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	}, ResolverArgs("-r", 25)...)})
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] Amass scan failed for %s: %v", domain, err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] Amass scan completed in %s for domain %s", execTime, domain)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
@@ -659,7 +634,7 @@ func ExecuteAndParseAmassScan(scanID, domain string) {
 		log.Printf("[WARN] No output from Amass scan for domain %s", domain)
 	}
 
-	UpdateScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+	UpdateScanStatus(scanID, "success", result, execResult.Stderr, execResult.Command, execTime)
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
 }
 
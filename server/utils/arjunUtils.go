@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -87,14 +85,22 @@ func GetArjunConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunArjunScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "arjun") {
+		return
+	}
+
 	var req struct {
-		ScopeTargetID string `json:"scope_target_id"`
+		ScopeTargetID    string `json:"scope_target_id"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !CheckScanRequestProfile(w, req.ScopeTargetID, req.RequestProfileID) {
+		return
+	}
 
 	scanID := uuid.New().String()
 
@@ -108,7 +114,8 @@ func RunArjunScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteArjunScan(scanID, req.ScopeTargetID)
+	RecordScanToolVersion("arjun_scans", scanID, "arjun")
+	go ExecuteArjunScan(scanID, req.ScopeTargetID, req.RequestProfileID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -117,7 +124,7 @@ func RunArjunScan(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func ExecuteArjunScan(scanID, scopeTargetID string) {
+func ExecuteArjunScan(scanID, scopeTargetID, requestProfileID string) {
 	startTime := time.Now()
 
 	UpdateArjunScanStatus(scanID, "running", "", "")
@@ -169,20 +176,22 @@ func ExecuteArjunScan(scanID, scopeTargetID string) {
 	updateTotalQuery := `UPDATE arjun_scans SET total_endpoints = $1 WHERE scan_id = $2`
 	dbPool.Exec(context.Background(), updateTotalQuery, len(endpoints), scanID)
 
+	ctx := context.Background()
+	executor := ToolExecutor("arjun")
+
 	urlsFile := fmt.Sprintf("/tmp/arjun_urls_%s.txt", scanID)
 	urlsContent := strings.Join(endpoints, "\n")
-	if err := os.WriteFile(urlsFile, []byte(urlsContent), 0644); err != nil {
+	if err := WriteToolFile(ctx, executor, urlsFile, []byte(urlsContent)); err != nil {
 		UpdateArjunScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create URLs file: %v", err))
 		return
 	}
-	defer os.Remove(urlsFile)
 
 	outputFile := fmt.Sprintf("/tmp/arjun_output_%s.json", scanID)
-	defer os.Remove(outputFile)
+	defer executor.Exec(ctx, ExecRequest{Args: []string{"rm", "-f", executor.Path(urlsFile), executor.Path(outputFile)}})
 
 	args := []string{
-		"-i", urlsFile,
-		"-o", outputFile,
+		"-i", executor.Path(urlsFile),
+		"-o", executor.Path(outputFile),
 		"-t", fmt.Sprintf("%d", config.Threads),
 		"--stable",
 	}
@@ -211,29 +220,30 @@ func ExecuteArjunScan(scanID, scopeTargetID string) {
 		args = append(args, "--passive")
 	}
 
-	if len(config.Headers) > 0 {
-		for _, header := range config.Headers {
-			for key, value := range header {
-				args = append(args, "-H", fmt.Sprintf("%s: %s", key, value))
-			}
+	// arjun reads every header from one --headers value, so the configured and
+	// profile headers are passed together.
+	var headers []string
+	for _, header := range config.Headers {
+		for key, value := range header {
+			headers = append(headers, fmt.Sprintf("%s: %s", key, value))
 		}
 	}
+	headers = append(headers, RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)...)
+	args = append(args, RequestHeaderArgs("arjun", headers)...)
 
-	cmdStr := fmt.Sprintf("docker exec ars0n-framework-v2-arjun-1 arjun %s", strings.Join(args, " "))
-
-	cmd := exec.Command("docker", append([]string{"exec", "ars0n-framework-v2-arjun-1", "arjun"}, args...)...)
-	output, err := cmd.CombinedOutput()
+	execResult, err := executor.Run(ctx, ExecRequest{Args: args})
+	cmdStr := execResult.Command
+	output := execResult.Stdout + execResult.Stderr
 
 	executionTime := time.Since(startTime).String()
 
 	if err != nil {
-		UpdateArjunScanStatus(scanID, "error", string(output), fmt.Sprintf("Arjun execution failed: %v", err))
+		UpdateArjunScanStatus(scanID, "error", output, fmt.Sprintf("Arjun execution failed: %v", err))
 		return
 	}
 
 	parametersFound := 0
-	if _, err := os.Stat(outputFile); err == nil {
-		outputData, _ := os.ReadFile(outputFile)
+	if outputData, err := ReadToolFile(ctx, executor, outputFile); err == nil {
 		if len(outputData) > 0 {
 			var results map[string]interface{}
 			if err := json.Unmarshal(outputData, &results); err == nil {
@@ -262,7 +272,7 @@ func ExecuteArjunScan(scanID, scopeTargetID string) {
 		    execution_time = $3, command = $4, stdout = $5
 		WHERE scan_id = $6
 	`
-	dbPool.Exec(context.Background(), updateQuery, len(endpoints), parametersFound, executionTime, cmdStr, string(output), scanID)
+	dbPool.Exec(context.Background(), updateQuery, len(endpoints), parametersFound, executionTime, cmdStr, output, scanID)
 }
 
 func UpdateArjunScanStatus(scanID, status, stdout, errorMsg string) {
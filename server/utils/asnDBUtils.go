@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"ars0n-framework-v2-server/asndb"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	asnDBMaxAge = 7 * 24 * time.Hour
+	// asnDBRetryInterval spaces out refreshes while downloads keep failing.
+	asnDBRetryInterval = 12 * time.Hour
+)
+
+func asnDBPath() string {
+	if path := os.Getenv("ASN_DB_PATH"); path != "" {
+		return path
+	}
+	return "/app/feeds/asn"
+}
+
+func refreshASNDatabase() (*asndb.DB, []error) {
+	client := egressClient(EgressScopeDownload, &http.Client{Timeout: 10 * time.Minute})
+	return asndb.Refresh(client, asnDBPath())
+}
+
+// refreshASNDatabaseIfStale downloads the datasets when none are loaded or the
+// oldest is more than a week old, unless a refresh was already tried recently.
+func refreshASNDatabaseIfStale() {
+	db := asndb.Current()
+	if db.Prefixes > 0 && time.Since(db.OldestSource()) < asnDBMaxAge {
+		return
+	}
+	if time.Since(db.LastAttempt) < asnDBRetryInterval {
+		return
+	}
+	db, errs := refreshASNDatabase()
+	for _, err := range errs {
+		log.Printf("[ASN-DB] [WARN] Refresh: %v", err)
+	}
+	if db != nil {
+		log.Printf("[ASN-DB] [INFO] Refreshed ASN database (%d prefixes, %d records)", db.Prefixes, db.Records)
+	}
+}
+
+// InitASNDatabase loads the IP-to-ASN datasets in the background, since a full
+// table takes a few seconds to index, and unless ASN_DB_AUTO_REFRESH=false keeps
+// them fresh with a daily job. Lookups fall back to the online services until then.
+func InitASNDatabase() {
+	go func() {
+		path := asnDBPath()
+		db, err := asndb.Load(path)
+		switch {
+		case err == nil:
+			asndb.SetDB(db)
+			log.Printf("[ASN-DB] [INFO] Loaded %d prefixes (%d records) from %s", db.Prefixes, db.Records, path)
+		case !os.IsNotExist(err):
+			log.Printf("[ASN-DB] [WARN] Ignoring ASN datasets at %s: %v", path, err)
+		}
+		if os.Getenv("ASN_DB_AUTO_REFRESH") == "false" {
+			return
+		}
+
+		refreshASNDatabaseIfStale()
+		for range time.Tick(24 * time.Hour) {
+			refreshASNDatabaseIfStale()
+		}
+	}()
+}
+
+// lookupLocalASN resolves an IP against the loaded datasets. asn is the plain
+// number, as consolidated assets store it, and is empty for prefixes only known
+// from RIR allocations.
+func lookupLocalASN(ip string) (asn, org, country string, ok bool) {
+	record, _, ok := asndb.Current().Lookup(ip)
+	if !ok {
+		return "", "", "", false
+	}
+	if record.ASN != 0 {
+		asn = strconv.FormatUint(uint64(record.ASN), 10)
+	}
+	return asn, record.Org, record.Country, true
+}
+
+// GetASNDatabaseStatus reports which datasets are loaded.
+func GetASNDatabaseStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"database": asndb.Current(),
+		"path":     asnDBPath(),
+	})
+}
+
+// RefreshASNDatabase downloads the datasets and swaps them in.
+func RefreshASNDatabase(w http.ResponseWriter, r *http.Request) {
+	db, errs := refreshASNDatabase()
+	messages := []string{}
+	for _, err := range errs {
+		log.Printf("[ASN-DB] [WARN] Refresh: %v", err)
+		messages = append(messages, err.Error())
+	}
+	if db == nil {
+		http.Error(w, "Failed to refresh ASN database", http.StatusBadGateway)
+		return
+	}
+	log.Printf("[ASN-DB] [INFO] Refreshed ASN database (%d prefixes, %d records)", db.Prefixes, db.Records)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"database": db,
+		"path":     asnDBPath(),
+		"errors":   messages,
+	})
+}
+
+// ReloadASNDatabase re-reads the datasets after they were updated on disk.
+func ReloadASNDatabase(w http.ResponseWriter, r *http.Request) {
+	db, err := asndb.Load(asnDBPath())
+	if err != nil {
+		log.Printf("[ASN-DB] [ERROR] Reload failed: %v", err)
+		http.Error(w, "Failed to load ASN datasets: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	asndb.SetDB(db)
+	log.Printf("[ASN-DB] [INFO] Loaded %d prefixes (%d records)", db.Prefixes, db.Records)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"database": db,
+		"path":     asnDBPath(),
+	})
+}
+
+// LookupASN resolves a single ?ip= against the loaded datasets.
+func LookupASN(w http.ResponseWriter, r *http.Request) {
+	ip := r.URL.Query().Get("ip")
+	if ip == "" {
+		http.Error(w, "ip is required", http.StatusBadRequest)
+		return
+	}
+	record, prefix, ok := asndb.Current().Lookup(ip)
+	response := map[string]interface{}{"ip": ip, "matched": ok}
+	if ok {
+		response["prefix"] = prefix.String()
+		response["record"] = record
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// enrichLocalASN fills in ASN, organization and country for consolidated IP and
+// live web server assets that no network range source covered.
+func enrichLocalASN(scopeTargetID string) (int, error) {
+	if asndb.Current().Prefixes == 0 {
+		log.Printf("[ASN-DB] [INFO] No ASN datasets loaded, skipping local enrichment")
+		return 0, nil
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, ip_address FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type IN ('ip_address', 'live_web_server')
+			AND ip_address IS NOT NULL AND ip_address != ''
+			AND (asn_number IS NULL OR asn_number = '')`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var id, ip string
+		if rows.Scan(&id, &ip) != nil {
+			continue
+		}
+		if asn, org, country, ok := lookupLocalASN(ip); ok {
+			batch.Queue(`
+				UPDATE consolidated_attack_surface_assets
+				SET asn_number = NULLIF($1, ''), asn_organization = NULLIF($2, ''),
+					asn_country = COALESCE(NULLIF($3, ''), asn_country)
+				WHERE id = $4`,
+				asn, org, country, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := dbPool.SendBatch(context.Background(), batch).Close(); err != nil {
+		return 0, err
+	}
+	return batch.Len(), nil
+}
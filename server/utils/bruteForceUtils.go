@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -9,7 +8,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -53,6 +51,10 @@ type CeWLScanStatus struct {
 }
 
 func RunShuffleDNSScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "shuffledns") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -91,6 +93,7 @@ func RunShuffleDNSScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("shuffledns_scans", scanID, "shuffledns")
 	go ExecuteAndParseShuffleDNSScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -98,6 +101,10 @@ func RunShuffleDNSScan(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunCeWLScansForUrls(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "cewl") {
+		return
+	}
+
 	var payload struct {
 		URLs []string `json:"urls" binding:"required"`
 	}
@@ -115,6 +122,7 @@ func RunCeWLScansForUrls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("cewl_scans", scanID, "cewl")
 	go ExecuteAndParseCeWLScansForUrls(scanID, payload.URLs)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -122,6 +130,10 @@ func RunCeWLScansForUrls(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunShuffleDNSWithWordlist(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "shuffledns") {
+		return
+	}
+
 	var payload struct {
 		Wordlist string `json:"wordlist" binding:"required"`
 	}
@@ -139,6 +151,7 @@ func RunShuffleDNSWithWordlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("shuffledns_scans", scanID, "shuffledns")
 	go ExecuteAndParseShuffleDNSWithWordlist(scanID, payload.Wordlist)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -170,45 +183,39 @@ func ExecuteAndParseShuffleDNSWithWordlist(scanID, wordlist string) {
 		return
 	}
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"shuffledns",
+	shuffleExecutor := ToolExecutor("shuffledns")
+	resolverFile, removeResolverFile := WriteResolverFile(context.Background(), shuffleExecutor, nil, "/app/wordlists/resolvers.txt")
+	defer removeResolverFile()
+	execResult, err := shuffleExecutor.Run(context.Background(), ExecRequest{Args: []string{
 		"-d", wordlistFile,
 		"-w", "/app/wordlists/all.txt",
-		"-r", "/app/wordlists/resolvers.txt",
+		"-r", resolverFile,
 		"-silent",
 		"-massdns", "/usr/local/bin/massdns",
 		"-t", fmt.Sprintf("%d", rateLimit),
 		"-mode", "bruteforce",
-	)
+	}})
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] ShuffleDNS scan failed for wordlist: %v", err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateShuffleDNSScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateShuffleDNSScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] ShuffleDNS scan completed in %s for wordlist", execTime)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
 	if result == "" {
 		log.Printf("[WARN] No output from ShuffleDNS scan")
-		UpdateShuffleDNSScanStatus(scanID, "completed", "", "No results found", cmd.String(), execTime)
+		UpdateShuffleDNSScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
 	} else {
 		log.Printf("[DEBUG] ShuffleDNS output: %s", result)
-		UpdateShuffleDNSScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+		UpdateShuffleDNSScanStatus(scanID, "success", result, execResult.Stderr, execResult.Command, execTime)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -251,45 +258,39 @@ func ExecuteAndParseShuffleDNSScan(scanID, domain string) {
 		return
 	}
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"shuffledns",
+	shuffleExecutor := ToolExecutor("shuffledns")
+	resolverFile, removeResolverFile := WriteResolverFile(context.Background(), shuffleExecutor, nil, "/app/wordlists/resolvers.txt")
+	defer removeResolverFile()
+	execResult, err := shuffleExecutor.Run(context.Background(), ExecRequest{Args: []string{
 		"-d", domain,
 		"-w", "/app/wordlists/all.txt",
-		"-r", "/app/wordlists/resolvers.txt",
+		"-r", resolverFile,
 		"-silent",
 		"-massdns", "/usr/local/bin/massdns",
 		"-t", fmt.Sprintf("%d", rateLimit),
 		"-mode", "bruteforce",
-	)
+	}})
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] ShuffleDNS scan failed for %s: %v", domain, err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateShuffleDNSScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateShuffleDNSScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] ShuffleDNS scan completed in %s for domain %s", execTime, domain)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
 	if result == "" {
 		log.Printf("[WARN] No output from ShuffleDNS scan")
-		UpdateShuffleDNSScanStatus(scanID, "completed", "", "No results found", cmd.String(), execTime)
+		UpdateShuffleDNSScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
 	} else {
 		log.Printf("[DEBUG] ShuffleDNS output: %s", result)
-		UpdateShuffleDNSScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+		UpdateShuffleDNSScanStatus(scanID, "success", result, execResult.Stderr, execResult.Command, execTime)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -311,7 +312,7 @@ func GetShuffleDNSScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan ShuffleDNSScanStatus
-	query := `SELECT * FROM shuffledns_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM shuffledns_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -368,7 +369,7 @@ func GetShuffleDNSScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM shuffledns_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM shuffledns_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -422,6 +423,10 @@ func GetShuffleDNSScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunCeWLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "cewl") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -461,6 +466,7 @@ func RunCeWLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("cewl_scans", scanID, "cewl")
 	go ExecuteAndParseCeWLScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -535,10 +541,6 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 
 		// Build CeWL command
 		cmdArgs := []string{
-			"docker", "exec",
-			"ars0n-framework-v2-cewl-1",
-			"timeout", "600",
-			"ruby", "/app/cewl.rb",
 			cleanURL,
 			"-d", "2",
 			"-m", "5",
@@ -551,21 +553,17 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 			cmdArgs = append(cmdArgs, "--ua", customUserAgent)
 		}
 
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-
 		log.Printf("[DEBUG] Running CeWL on URL: %s", cleanURL)
-		err := cmd.Run()
+		ctx, cancel := context.WithTimeout(context.Background(), 600*time.Second)
+		execResult, err := ToolExecutor("cewl").Run(ctx, ExecRequest{Args: cmdArgs})
+		cancel()
 		if err != nil {
 			log.Printf("[WARN] CeWL failed for URL %s: %v", cleanURL, err)
-			log.Printf("[WARN] stderr: %s", stderr.String())
+			log.Printf("[WARN] stderr: %s", execResult.Stderr)
 			continue
 		}
 
-		output := stdout.String()
+		output := execResult.Stdout
 
 		// Process CeWL output
 		words := strings.Split(output, "\n")
@@ -630,31 +628,6 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 		log.Printf("[DEBUG] Wordlist file size: %d bytes", len(content))
 	}
 
-	// Copy wordlist to container
-	copyCmd := exec.Command(
-		"docker", "cp",
-		wordlistFile,
-		"ars0n-framework-v2-shuffledns-1:/tmp/wordlist.txt")
-	if err := copyCmd.Run(); err != nil {
-		log.Printf("[ERROR] Failed to copy wordlist to container: %v", err)
-		UpdateCeWLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to copy wordlist to container: %v", err), "", time.Since(startTime).String())
-		return
-	}
-
-	log.Printf("[DEBUG] Wordlist copied to ShuffleDNS container")
-
-	// Verify file in container
-	checkCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"cat", "/tmp/wordlist.txt",
-	)
-	var checkOutput bytes.Buffer
-	checkCmd.Stdout = &checkOutput
-	if err := checkCmd.Run(); err == nil {
-		log.Printf("[DEBUG] Wordlist in container size: %d bytes", len(checkOutput.String()))
-	}
-
 	// Store the wordlist in CeWL results
 	UpdateCeWLScanStatus(scanID, "success", strings.Join(wordlist, "\n"), "", "", time.Since(startTime).String())
 
@@ -683,50 +656,20 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 		return
 	}
 
-	// Debug: Check resolvers file
-	resolversCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"cat", "/app/wordlists/resolvers.txt",
-	)
-	var resolversOutput bytes.Buffer
-	resolversCmd.Stdout = &resolversOutput
-	if err := resolversCmd.Run(); err == nil {
-		log.Printf("[DEBUG] Resolvers file size: %d bytes", len(resolversOutput.String()))
-	} else {
-		log.Printf("[ERROR] Failed to read resolvers file: %v", err)
-	}
-
 	// Run ShuffleDNS with the combined wordlist
-	shuffleCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-shuffledns-1",
-		"shuffledns",
-		"-d", domain,
-		"-w", "/tmp/wordlist.txt",
-		"-r", "/app/wordlists/resolvers.txt",
-		"-silent",
-		"-massdns", "/usr/local/bin/massdns",
-		"-mode", "bruteforce",
-	)
-
-	var shuffleStdout, shuffleStderr bytes.Buffer
-	shuffleCmd.Stdout = &shuffleStdout
-	shuffleCmd.Stderr = &shuffleStderr
-
-	log.Printf("[DEBUG] Running ShuffleDNS command: %s", shuffleCmd.String())
-	err = shuffleCmd.Run()
+	shuffleResultExec, err := runShuffleDNSWordlist(context.Background(), domain, wordlistFile, 0)
+	log.Printf("[DEBUG] Ran ShuffleDNS command: %s", shuffleResultExec.Command)
 	shuffleExecTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] ShuffleDNS custom scan failed: %v", err)
-		log.Printf("[DEBUG] ShuffleDNS stderr: %s", shuffleStderr.String())
-		log.Printf("[DEBUG] ShuffleDNS stdout: %s", shuffleStdout.String())
-		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "error", "", shuffleStderr.String(), shuffleCmd.String(), shuffleExecTime)
+		log.Printf("[DEBUG] ShuffleDNS stderr: %s", shuffleResultExec.Stderr)
+		log.Printf("[DEBUG] ShuffleDNS stdout: %s", shuffleResultExec.Stdout)
+		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "error", "", shuffleResultExec.Stderr, shuffleResultExec.Command, shuffleExecTime)
 		return
 	}
 
-	shuffleResult := shuffleStdout.String()
+	shuffleResult := shuffleResultExec.Stdout
 	log.Printf("[DEBUG] ShuffleDNS stdout length: %d bytes", len(shuffleResult))
 	if len(shuffleResult) > 0 {
 		log.Printf("[DEBUG] ShuffleDNS results: %s", shuffleResult)
@@ -734,15 +677,46 @@ func ExecuteAndParseCeWLScan(scanID, domain string) {
 
 	if shuffleResult == "" {
 		log.Printf("[WARN] No results found from ShuffleDNS scan")
-		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "completed", "", "No results found", shuffleCmd.String(), shuffleExecTime)
+		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "completed", "", "No results found", shuffleResultExec.Command, shuffleExecTime)
 	} else {
 		log.Printf("[INFO] ShuffleDNS found results")
-		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "success", shuffleResult, shuffleStderr.String(), shuffleCmd.String(), shuffleExecTime)
+		UpdateShuffleDNSCustomScanStatus(shuffleDNSScanID, "success", shuffleResult, shuffleResultExec.Stderr, shuffleResultExec.Command, shuffleExecTime)
 	}
 
 	log.Printf("[DEBUG] ====== Completed CeWL + ShuffleDNS Process ======")
 }
 
+// runShuffleDNSWordlist brute-forces domain with a locally generated wordlist. It is
+// the custom-wordlist path shared by CeWL and the subdomain permutation engine.
+// A rateLimit of 0 leaves shuffledns' -t at its default.
+func runShuffleDNSWordlist(ctx context.Context, domain, wordlistFile string, rateLimit int) (*ExecResult, error) {
+	shuffleExecutor := ToolExecutor("shuffledns")
+	toolWordlist := fmt.Sprintf("/tmp/shuffledns-wordlist-%s.txt", uuid.New().String())
+	if err := shuffleExecutor.CopyTo(ctx, wordlistFile, toolWordlist); err != nil {
+		err = fmt.Errorf("failed to copy wordlist to shuffledns: %v", err)
+		return &ExecResult{Stderr: err.Error()}, err
+	}
+	defer shuffleExecutor.Exec(context.Background(), ExecRequest{Args: []string{"rm", "-f", shuffleExecutor.Path(toolWordlist)}})
+
+	if content, err := ReadToolFile(ctx, shuffleExecutor, toolWordlist); err == nil {
+		log.Printf("[DEBUG] Wordlist in shuffledns size: %d bytes", len(content))
+	}
+
+	resolverFile, removeResolverFile := WriteResolverFile(ctx, shuffleExecutor, nil, "/app/wordlists/resolvers.txt")
+	defer removeResolverFile()
+	args := []string{
+		"-d", domain,
+		"-w", shuffleExecutor.Path(toolWordlist),
+		"-r", resolverFile,
+		"-silent",
+		"-massdns", "/usr/local/bin/massdns",
+	}
+	if rateLimit > 0 {
+		args = append(args, "-t", fmt.Sprintf("%d", rateLimit))
+	}
+	return shuffleExecutor.Run(ctx, ExecRequest{Args: append(args, "-mode", "bruteforce")})
+}
+
 func UpdateCeWLScanStatus(scanID, status, result, stderr, command, execTime string) {
 	log.Printf("[INFO] Updating CeWL scan status for %s to %s", scanID, status)
 	query := `UPDATE cewl_scans SET status = $1, result = $2, stderr = $3, command = $4, execution_time = $5 WHERE scan_id = $6`
@@ -759,7 +733,7 @@ func GetCeWLScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan CeWLScanStatus
-	query := `SELECT * FROM cewl_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, url, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM cewl_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -816,7 +790,7 @@ func GetCeWLScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM cewl_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, url, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM cewl_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -890,7 +864,7 @@ func GetShuffleDNSCustomScansForScopeTarget(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	query := `SELECT * FROM shufflednscustom_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM shufflednscustom_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
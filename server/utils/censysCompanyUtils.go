@@ -140,7 +140,7 @@ func ExecuteCensysCompanyScan(scanID, companyName string) {
 
 	log.Printf("[CENSYS-COMPANY] [INFO] Successfully retrieved Censys API credentials")
 
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := egressClient(EgressScopeCensys, &http.Client{Timeout: 60 * time.Second})
 
 	url := fmt.Sprintf("https://search.censys.io/api/v2/certificates/search?q=parsed.subject.organization:%%22%s%%22&per_page=100", companyName)
 	req, err := http.NewRequest("GET", url, nil)
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	CertNameInScope   = "in_scope"
+	CertNameSameOrg   = "same_org"
+	CertNameUnrelated = "unrelated"
+)
+
+// certObservation is what is kept from a certificate seen on a live host.
+type certObservation struct {
+	Host          string
+	Names         []string
+	Organizations []string
+	SHA256        string
+}
+
+var singleLabelCertName = regexp.MustCompile(`^[a-z0-9_](?:[a-z0-9_-]{0,61}[a-z0-9_])?$`)
+
+func newCertObservation(host string, cert *x509.Certificate) certObservation {
+	sum := sha256.Sum256(cert.Raw)
+	obs := certObservation{Host: host, Organizations: cert.Subject.Organization, SHA256: hex.EncodeToString(sum[:])}
+	seen := make(map[string]bool)
+	for _, name := range append([]string{cert.Subject.CommonName}, cert.DNSNames...) {
+		normalized, err := domainutil.Normalize(strings.TrimPrefix(name, "*."))
+		if err != nil {
+			// Internal certificates often name single-label hosts such as "intranet",
+			// which have no root domain but are still worth keeping
+			normalized = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+			if !singleLabelCertName.MatchString(normalized) {
+				continue
+			}
+		}
+		if domainutil.IsIP(normalized) || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		obs.Names = append(obs.Names, normalized)
+	}
+	return obs
+}
+
+var orgSuffixRegex = regexp.MustCompile(`\b(inc|incorporated|llc|ltd|limited|corp|corporation|co|company|gmbh|plc|sa|ag|bv|nv|pty|srl|holdings)\b`)
+var orgPunctuationRegex = regexp.MustCompile(`[^a-z0-9 ]+`)
+
+// normalizeOrg reduces an organization name to comparable words, so that
+// "Example, Inc." and "EXAMPLE Inc" compare equal.
+func normalizeOrg(org string) string {
+	org = orgPunctuationRegex.ReplaceAllString(strings.ToLower(org), " ")
+	org = orgSuffixRegex.ReplaceAllString(org, " ")
+	return strings.Join(strings.Fields(org), " ")
+}
+
+// sharedCertOrgs issue or serve certificates on behalf of many unrelated
+// customers, so a matching organization says nothing about ownership.
+var sharedCertOrgs = []string{"cloudflare", "amazon", "google", "microsoft", "akamai", "fastly", "incapsula", "imperva", "sucuri", "automattic", "github", "heroku", "salesforce", "shopify", "wpengine", "zscaler"}
+
+func isSharedCertOrg(normalized string) bool {
+	for _, shared := range sharedCertOrgs {
+		if strings.Contains(normalized, shared) {
+			return true
+		}
+	}
+	return false
+}
+
+// certScope is what a scope target's certificate names are classified against.
+type certScope struct {
+	scopeTargetID  string
+	wildcardBase   string
+	companyDomains []string
+	orgs           map[string]bool
+}
+
+// loadCertScope loads the in-scope domains and the organization names that mark
+// a certificate as belonging to the target: the company name for Company
+// targets, plus organizations seen on certificates for in-scope names.
+func loadCertScope(scopeTargetID string) (*certScope, error) {
+	scope := &certScope{scopeTargetID: scopeTargetID, orgs: make(map[string]bool)}
+	var targetType, target string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT type, scope_target FROM scope_targets WHERE id = $1`, scopeTargetID).Scan(&targetType, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	switch targetType {
+	case "Wildcard":
+		scope.wildcardBase = strings.TrimPrefix(target, "*.")
+	case "Company":
+		if org := normalizeOrg(target); org != "" {
+			scope.orgs[org] = true
+		}
+		rows, err := dbPool.Query(context.Background(),
+			`SELECT domain FROM consolidated_company_domains WHERE scope_target_id = $1`, scopeTargetID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var domain string
+			if rows.Scan(&domain) == nil {
+				scope.companyDomains = append(scope.companyDomains, domain)
+			}
+		}
+		rows.Close()
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT organization FROM certificate_names
+		WHERE scope_target_id = $1 AND classification = 'in_scope' AND organization IS NOT NULL`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var org string
+		if rows.Scan(&org) == nil {
+			if normalized := normalizeOrg(org); normalized != "" && !isSharedCertOrg(normalized) {
+				scope.orgs[normalized] = true
+			}
+		}
+	}
+	return scope, nil
+}
+
+func (s *certScope) inScope(name string) bool {
+	if s.wildcardBase != "" {
+		return domainutil.IsSubdomainOf(name, s.wildcardBase)
+	}
+	for _, domain := range s.companyDomains {
+		if domainutil.IsSubdomainOf(name, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *certScope) sameOrg(organizations []string) bool {
+	for _, org := range organizations {
+		normalized := normalizeOrg(org)
+		if normalized == "" || isSharedCertOrg(normalized) {
+			continue
+		}
+		if s.orgs[normalized] {
+			return true
+		}
+	}
+	return false
+}
+
+// recordCertificateNames stores every name on a certificate for the scope target.
+// Names under an in-scope domain are fed into consolidation; other names on a
+// certificate shared with an in-scope name or issued to the target's
+// organization are surfaced for company domain review.
+func recordCertificateNames(scope *certScope, source string, obs certObservation) {
+	if scope == nil || len(obs.Names) == 0 {
+		return
+	}
+	organization := strings.Join(obs.Organizations, ", ")
+
+	// A certificate covering an in-scope name teaches us the organization, and
+	// its other names are siblings unless a shared hosting provider issued it
+	coversScope := false
+	for _, name := range obs.Names {
+		if scope.inScope(name) {
+			coversScope = true
+			break
+		}
+	}
+	shared := false
+	for _, org := range obs.Organizations {
+		normalized := normalizeOrg(org)
+		if isSharedCertOrg(normalized) {
+			shared = true
+		} else if coversScope && normalized != "" {
+			scope.orgs[normalized] = true
+		}
+	}
+	sameOrg := (coversScope && !shared) || scope.sameOrg(obs.Organizations)
+
+	for _, name := range obs.Names {
+		classification := CertNameUnrelated
+		switch {
+		case scope.inScope(name):
+			classification = CertNameInScope
+		case sameOrg:
+			classification = CertNameSameOrg
+		}
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO certificate_names (
+				scope_target_id, name, root_domain, classification, organization, source, source_host, cert_sha256
+			) VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7, $8)
+			ON CONFLICT (scope_target_id, name) DO UPDATE SET
+				classification = CASE
+					WHEN certificate_names.classification = 'in_scope' THEN 'in_scope'
+					WHEN EXCLUDED.classification = 'unrelated' THEN certificate_names.classification
+					ELSE EXCLUDED.classification
+				END,
+				organization = COALESCE(EXCLUDED.organization, certificate_names.organization),
+				source = EXCLUDED.source, source_host = EXCLUDED.source_host,
+				cert_sha256 = EXCLUDED.cert_sha256, last_seen = NOW()`,
+			scope.scopeTargetID, name, domainutil.RootDomain(name), classification, organization, source, obs.Host, obs.SHA256)
+		if err != nil {
+			log.Printf("[CERT-HARVEST] [ERROR] Failed to store certificate name %s: %v", name, err)
+		}
+	}
+}
+
+// harvestLiveWebServerCerts records the certificates served by raw IPs found in
+// an IP/Port scan.
+func harvestLiveWebServerCerts(scopeTargetID string, servers []LiveWebServer) {
+	scope, err := loadCertScope(scopeTargetID)
+	if err != nil {
+		log.Printf("[CERT-HARVEST] [ERROR] Failed to load scope for %s: %v", scopeTargetID, err)
+		return
+	}
+	certs := 0
+	for _, server := range servers {
+		if server.certificate == nil {
+			continue
+		}
+		recordCertificateNames(scope, "ip_port_scan", newCertObservation(server.IPAddress, server.certificate))
+		certs++
+	}
+	if certs > 0 {
+		log.Printf("[CERT-HARVEST] [INFO] Recorded names from %d certificates served by live web servers", certs)
+	}
+}
+
+// GetCertificateNames lists harvested certificate names, optionally filtered by
+// ?classification=in_scope|same_org|unrelated.
+func GetCertificateNames(w http.ResponseWriter, r *http.Request) {
+	classification := r.URL.Query().Get("classification")
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT name, COALESCE(root_domain, ''), classification, COALESCE(organization, ''), source, source_host,
+			cert_sha256, dismissed, first_seen, last_seen
+		FROM certificate_names
+		WHERE scope_target_id = $1 AND ($2 = '' OR classification = $2)
+		ORDER BY classification, root_domain, name`, mux.Vars(r)["id"], classification)
+	if err != nil {
+		http.Error(w, "Failed to get certificate names", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	names := []map[string]interface{}{}
+	for rows.Next() {
+		var name, rootDomain, class, organization, source, sourceHost, certSHA256 string
+		var dismissed bool
+		var firstSeen, lastSeen time.Time
+		if err := rows.Scan(&name, &rootDomain, &class, &organization, &source, &sourceHost, &certSHA256, &dismissed, &firstSeen, &lastSeen); err != nil {
+			continue
+		}
+		names = append(names, map[string]interface{}{
+			"name":           name,
+			"root_domain":    rootDomain,
+			"classification": class,
+			"organization":   organization,
+			"source":         source,
+			"source_host":    sourceHost,
+			"cert_sha256":    certSHA256,
+			"dismissed":      dismissed,
+			"first_seen":     firstSeen.Format(time.RFC3339),
+			"last_seen":      lastSeen.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -56,6 +55,10 @@ type CloudEnumConfig struct {
 }
 
 func RunCloudEnumScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "cloud_enum") {
+		return
+	}
+
 	log.Printf("[CLOUD-ENUM] [INFO] Starting Cloud Enum scan request handling")
 	var payload struct {
 		CompanyName       string  `json:"company_name" binding:"required"`
@@ -124,6 +127,7 @@ func RunCloudEnumScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[CLOUD-ENUM] [INFO] Successfully created Cloud Enum scan record in database")
 
+	RecordScanToolVersion("cloud_enum_scans", scanID, "cloud_enum")
 	go ExecuteAndParseCloudEnumScan(scanID, companyName)
 
 	log.Printf("[CLOUD-ENUM] [INFO] Cloud Enum scan initiated successfully, returning scan ID: %s", scanID)
@@ -150,14 +154,12 @@ func ExecuteAndParseCloudEnumScan(scanID, companyName string) {
 	log.Printf("[CLOUD-ENUM] [INFO] Loaded config for company %s: keywords=%v, dns_mode=%s, resolver_config=%s",
 		companyName, config.Keywords, config.DNSResolverMode, config.ResolverConfig)
 
-	containerName := "ars0n-framework-v2-cloud_enum-1"
+	executor := ToolExecutor("cloud_enum")
 	logFile := fmt.Sprintf("/tmp/cloud_enum_%s.json", scanID)
 
 	// Build base command
 	command := []string{
-		"docker", "exec", containerName,
-		"python", "cloud_enum.py",
-		"-l", logFile,
+		"-l", executor.Path(logFile),
 		"-f", "json",
 	}
 
@@ -173,16 +175,19 @@ func ExecuteAndParseCloudEnumScan(scanID, companyName string) {
 	// Add DNS resolver configuration
 	if config.DNSResolverMode == "multiple" {
 		if config.ResolverConfig == "default" {
-			// Use built-in resolver file
-			command = append(command, "-nsf", "/app/resolvers.txt")
+			// Use the validated resolver pool, falling back to the built-in file
+			resolverFile, removeResolverFile := WriteResolverFile(context.Background(), executor, nil, "/app/resolvers.txt")
+			defer removeResolverFile()
+			command = append(command, "-nsf", resolverFile)
 		} else if config.ResolverConfig == "custom" && config.ResolverFilePath != "" {
 			// Copy custom resolver file to container
-			copyResolverFile(containerName, config.ResolverFilePath, scanID)
-			command = append(command, "-nsf", fmt.Sprintf("/tmp/custom_resolvers_%s.txt", scanID))
+			copyResolverFile(executor, config.ResolverFilePath, scanID)
+			command = append(command, "-nsf", executor.Path(fmt.Sprintf("/tmp/custom_resolvers_%s.txt", scanID)))
 		} else if config.ResolverConfig == "hybrid" {
-			// Create hybrid resolver file
-			createHybridResolverFile(containerName, config.AdditionalResolvers, scanID)
-			command = append(command, "-nsf", fmt.Sprintf("/tmp/hybrid_resolvers_%s.txt", scanID))
+			// Combine the resolver pool with the additional resolvers
+			resolverFile, removeResolverFile := WriteResolverFile(context.Background(), executor, strings.Split(config.AdditionalResolvers, "\n"), "/app/resolvers.txt")
+			defer removeResolverFile()
+			command = append(command, "-nsf", resolverFile)
 		}
 	} else if config.DNSResolverMode == "single" && config.CustomDNSServer != "" {
 		// Use single DNS server
@@ -212,14 +217,14 @@ func ExecuteAndParseCloudEnumScan(scanID, companyName string) {
 
 	// Add custom mutation file if available
 	if config.MutationsFilePath != "" {
-		copyMutationFile(containerName, config.MutationsFilePath, scanID)
-		command = append(command, "-m", fmt.Sprintf("/tmp/custom_mutations_%s.txt", scanID))
+		copyMutationFile(executor, config.MutationsFilePath, scanID)
+		command = append(command, "-m", executor.Path(fmt.Sprintf("/tmp/custom_mutations_%s.txt", scanID)))
 	}
 
 	// Add custom brute force file if available
 	if config.BruteFilePath != "" {
-		copyBruteFile(containerName, config.BruteFilePath, scanID)
-		command = append(command, "-b", fmt.Sprintf("/tmp/custom_brute_%s.txt", scanID))
+		copyBruteFile(executor, config.BruteFilePath, scanID)
+		command = append(command, "-b", executor.Path(fmt.Sprintf("/tmp/custom_brute_%s.txt", scanID)))
 	}
 
 	// Add service selection flags
@@ -255,23 +260,19 @@ func ExecuteAndParseCloudEnumScan(scanID, companyName string) {
 	}
 
 	log.Printf("[CLOUD-ENUM] [DEBUG] Executing command: %v", command)
-	cmd := exec.Command(command[0], command[1:]...)
-
-	stdout, err := cmd.CombinedOutput()
+	execResult, err := executor.Run(context.Background(), ExecRequest{Args: command})
 	if err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to execute cloud_enum: %v", err)
-		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to execute cloud_enum: %v", err), strings.Join(command, " "), time.Since(startTime).String())
+		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to execute cloud_enum: %v", err), execResult.Command, time.Since(startTime).String())
 		return
 	}
 
-	log.Printf("[CLOUD-ENUM] [DEBUG] Command stdout: %s", string(stdout))
+	log.Printf("[CLOUD-ENUM] [DEBUG] Command stdout: %s", execResult.Stdout+execResult.Stderr)
 
-	catCommand := []string{"docker", "exec", containerName, "cat", logFile}
-	catCmd := exec.Command(catCommand[0], catCommand[1:]...)
-	resultOutput, err := catCmd.Output()
+	resultOutput, err := ReadToolFile(context.Background(), executor, logFile)
 	if err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to read results file: %v", err)
-		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read results file: %v", err), strings.Join(command, " "), time.Since(startTime).String())
+		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read results file: %v", err), execResult.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -297,12 +298,12 @@ func ExecuteAndParseCloudEnumScan(scanID, companyName string) {
 	resultJSON, err := json.Marshal(cloudEnumResults)
 	if err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to marshal results: %v", err)
-		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to marshal results: %v", err), strings.Join(command, " "), time.Since(startTime).String())
+		UpdateCloudEnumScanStatus(scanID, "error", "", fmt.Sprintf("Failed to marshal results: %v", err), execResult.Command, time.Since(startTime).String())
 		return
 	}
 
 	log.Printf("[CLOUD-ENUM] [DEBUG] Processed %d cloud resources", len(cloudEnumResults))
-	UpdateCloudEnumScanStatus(scanID, "success", string(resultJSON), "", strings.Join(command, " "), time.Since(startTime).String())
+	UpdateCloudEnumScanStatus(scanID, "success", string(resultJSON), "", execResult.Command, time.Since(startTime).String())
 	log.Printf("[CLOUD-ENUM] [INFO] Cloud Enum scan completed and results stored successfully for company %s", companyName)
 }
 
@@ -544,61 +545,34 @@ func loadCloudEnumConfig(scopeTargetID string) CloudEnumConfig {
 	return config
 }
 
-// copyResolverFile copies a custom resolver file to the container
-func copyResolverFile(containerName, sourcePath, scanID string) {
+// copyResolverFile copies a custom resolver file to the cloud_enum tool
+func copyResolverFile(executor Executor, sourcePath, scanID string) {
 	destPath := fmt.Sprintf("/tmp/custom_resolvers_%s.txt", scanID)
 
 	// Copy file to container
-	copyCmd := exec.Command("docker", "cp", sourcePath, fmt.Sprintf("%s:%s", containerName, destPath))
-	if err := copyCmd.Run(); err != nil {
+	if err := executor.CopyTo(context.Background(), sourcePath, destPath); err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to copy resolver file to container: %v", err)
 	} else {
 		log.Printf("[CLOUD-ENUM] [INFO] Copied resolver file to container: %s", destPath)
 	}
 }
 
-// createHybridResolverFile creates a hybrid resolver file combining defaults with additional resolvers
-func createHybridResolverFile(containerName, additionalResolvers, scanID string) {
-	destPath := fmt.Sprintf("/tmp/hybrid_resolvers_%s.txt", scanID)
-
-	// Create command to combine default resolvers with additional ones
-	createScript := fmt.Sprintf(`
-		# Copy default resolvers
-		cp /app/resolvers.txt %s
-		
-		# Add additional resolvers
-		cat << 'EOF' >> %s
-%s
-EOF
-	`, destPath, destPath, additionalResolvers)
-
-	// Execute script in container
-	cmd := exec.Command("docker", "exec", containerName, "sh", "-c", createScript)
-	if err := cmd.Run(); err != nil {
-		log.Printf("[CLOUD-ENUM] [ERROR] Failed to create hybrid resolver file: %v", err)
-	} else {
-		log.Printf("[CLOUD-ENUM] [INFO] Created hybrid resolver file: %s", destPath)
-	}
-}
-
-// copyMutationFile copies a custom mutation file to the container
-func copyMutationFile(containerName, sourcePath, scanID string) {
+// copyMutationFile copies a custom mutation file to the cloud_enum tool
+func copyMutationFile(executor Executor, sourcePath, scanID string) {
 	destPath := fmt.Sprintf("/tmp/custom_mutations_%s.txt", scanID)
 
-	copyCmd := exec.Command("docker", "cp", sourcePath, fmt.Sprintf("%s:%s", containerName, destPath))
-	if err := copyCmd.Run(); err != nil {
+	if err := executor.CopyTo(context.Background(), sourcePath, destPath); err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to copy mutation file to container: %v", err)
 	} else {
 		log.Printf("[CLOUD-ENUM] [INFO] Copied mutation file to container: %s", destPath)
 	}
 }
 
-// copyBruteFile copies a custom brute force file to the container
-func copyBruteFile(containerName, sourcePath, scanID string) {
+// copyBruteFile copies a custom brute force file to the cloud_enum tool
+func copyBruteFile(executor Executor, sourcePath, scanID string) {
 	destPath := fmt.Sprintf("/tmp/custom_brute_%s.txt", scanID)
 
-	copyCmd := exec.Command("docker", "cp", sourcePath, fmt.Sprintf("%s:%s", containerName, destPath))
-	if err := copyCmd.Run(); err != nil {
+	if err := executor.CopyTo(context.Background(), sourcePath, destPath); err != nil {
 		log.Printf("[CLOUD-ENUM] [ERROR] Failed to copy brute file to container: %v", err)
 	} else {
 		log.Printf("[CLOUD-ENUM] [INFO] Copied brute file to container: %s", destPath)
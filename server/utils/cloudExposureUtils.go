@@ -0,0 +1,790 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	StorageProviderS3    = "s3"
+	StorageProviderGCS   = "gcs"
+	StorageProviderAzure = "azure"
+
+	cloudExposureReadBytes = 1024
+	azureStorageAPIVersion = "2021-08-06"
+)
+
+// storageTarget is a bucket (S3/GCS) or container (Azure) to test. Endpoint
+// overrides the provider's public endpoint and is limited to the provider's own
+// hosts or the operator-configured S3_ENDPOINT, GCS_ENDPOINT or
+// AZURE_BLOB_ENDPOINT (a local MinIO, fake-gcs-server or Azurite); Azure
+// endpoints include the account. Region is the S3 bucket region, learned from
+// the discovered URL or the x-amz-bucket-region response header.
+type storageTarget struct {
+	Provider  string `json:"provider"`
+	Account   string `json:"account,omitempty"`
+	Bucket    string `json:"bucket"`
+	Object    string `json:"object,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Source    string `json:"source,omitempty"`
+	SourceURL string `json:"source_url,omitempty"`
+}
+
+// Bucket and account names end up in hostnames, so manual targets must be
+// plain storage names.
+var (
+	storageBucketName  = regexp.MustCompile(`(?i)^[a-z0-9][a-z0-9._-]{1,221}$`)
+	storageAccountName = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+)
+
+func (t storageTarget) key() string {
+	return t.Provider + "|" + t.Account + "|" + t.Bucket
+}
+
+func (t storageTarget) endpoint() string {
+	if t.Endpoint != "" {
+		return strings.TrimRight(t.Endpoint, "/")
+	}
+	return configuredStorageEndpoint(t.Provider)
+}
+
+func configuredStorageEndpoint(provider string) string {
+	env := map[string]string{
+		StorageProviderS3:    "S3_ENDPOINT",
+		StorageProviderGCS:   "GCS_ENDPOINT",
+		StorageProviderAzure: "AZURE_BLOB_ENDPOINT",
+	}[provider]
+	if env == "" {
+		return ""
+	}
+	return strings.TrimRight(os.Getenv(env), "/")
+}
+
+// allowedStorageEndpoint reports whether a caller-supplied endpoint may be used
+// for a provider: the configured endpoint itself, or an HTTPS URL on one of the
+// provider's public storage hosts. Anything else would let a request body point
+// the scanner at arbitrary internal hosts.
+func allowedStorageEndpoint(provider, endpoint string) bool {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if configured := configuredStorageEndpoint(provider); configured != "" && endpoint == configured {
+		return true
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" ||
+		strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	switch provider {
+	case StorageProviderS3:
+		if !strings.HasSuffix(host, ".amazonaws.com") {
+			return false
+		}
+		labels := strings.Split(strings.TrimSuffix(host, ".amazonaws.com"), ".")
+		return labels[0] == "s3" || strings.HasPrefix(labels[0], "s3-")
+	case StorageProviderGCS:
+		return host == "storage.googleapis.com"
+	case StorageProviderAzure:
+		account := strings.TrimSuffix(host, ".blob.core.windows.net")
+		return account != host && account != "" && !strings.Contains(account, ".")
+	}
+	return false
+}
+
+// s3RegionFromLabels reads the region out of the labels following the s3 label
+// of an S3 hostname: s3.<region>, s3-<region> or s3.dualstack.<region>.
+func s3RegionFromLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	if region := strings.TrimPrefix(labels[0], "s3-"); region != labels[0] {
+		if region == "external-1" {
+			return "us-east-1"
+		}
+		return region
+	}
+	for _, label := range labels[1:] {
+		switch {
+		case label == "dualstack" || label == "s3-website":
+			continue
+		case label == "amazonaws" || label == "com":
+			return ""
+		case strings.Count(label, "-") >= 2:
+			return label
+		}
+	}
+	return ""
+}
+
+// baseURL is the bucket or container URL every check is built on. Overridden
+// endpoints are always addressed path-style.
+func (t storageTarget) baseURL() string {
+	if endpoint := t.endpoint(); endpoint != "" {
+		return endpoint + "/" + t.Bucket
+	}
+	switch t.Provider {
+	case StorageProviderS3:
+		host := "s3.amazonaws.com"
+		if t.Region != "" {
+			host = "s3." + t.Region + ".amazonaws.com"
+		}
+		// Dotted bucket names break the wildcard certificate on virtual-hosted URLs
+		if strings.Contains(t.Bucket, ".") {
+			return "https://" + host + "/" + t.Bucket
+		}
+		return "https://" + t.Bucket + "." + host
+	case StorageProviderGCS:
+		return "https://storage.googleapis.com/" + t.Bucket
+	default:
+		return "https://" + t.Account + ".blob.core.windows.net/" + t.Bucket
+	}
+}
+
+func (t storageTarget) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return t.baseURL() + "/" + strings.Join(segments, "/")
+}
+
+// parseStorageTarget extracts the provider, bucket and optional object key from
+// an S3, GCS or Azure Blob URL or hostname, in virtual-hosted or path style.
+func parseStorageTarget(raw string) (storageTarget, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return storageTarget{}, false
+	}
+	if strings.HasPrefix(raw, "gs://") {
+		raw = "https://storage.googleapis.com/" + strings.TrimPrefix(raw, "gs://")
+	} else if strings.HasPrefix(raw, "s3://") {
+		raw = "https://s3.amazonaws.com/" + strings.TrimPrefix(raw, "s3://")
+	} else if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return storageTarget{}, false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	path := strings.Trim(parsed.Path, "/")
+	firstSegment := func() (string, string) {
+		bucket, object, _ := strings.Cut(path, "/")
+		return bucket, object
+	}
+
+	var t storageTarget
+	switch {
+	case strings.HasSuffix(host, ".amazonaws.com"):
+		labels := strings.Split(host, ".")
+		s3Label := -1
+		for i, label := range labels {
+			if label == "s3" || strings.HasPrefix(label, "s3-") {
+				s3Label = i
+				break
+			}
+		}
+		if s3Label < 0 {
+			return storageTarget{}, false
+		}
+		t.Provider = StorageProviderS3
+		t.Region = s3RegionFromLabels(labels[s3Label:])
+		if s3Label == 0 {
+			t.Bucket, t.Object = firstSegment()
+		} else {
+			t.Bucket, t.Object = strings.Join(labels[:s3Label], "."), path
+		}
+	case host == "storage.googleapis.com" || host == "storage.cloud.google.com":
+		t.Provider = StorageProviderGCS
+		t.Bucket, t.Object = firstSegment()
+	case strings.HasSuffix(host, ".storage.googleapis.com"):
+		t.Provider = StorageProviderGCS
+		t.Bucket, t.Object = strings.TrimSuffix(host, ".storage.googleapis.com"), path
+	case strings.HasSuffix(host, ".blob.core.windows.net"):
+		t.Provider = StorageProviderAzure
+		t.Account = strings.TrimSuffix(host, ".blob.core.windows.net")
+		t.Bucket, t.Object = firstSegment()
+	default:
+		return storageTarget{}, false
+	}
+	if t.Bucket == "" {
+		return storageTarget{}, false
+	}
+	return t, true
+}
+
+// loadStorageTargets collects buckets from the latest successful cloud_enum
+// scan and the katana company cloud assets of a scope target.
+func loadStorageTargets(scopeTargetID string) ([]storageTarget, error) {
+	var targets []storageTarget
+
+	var result string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT COALESCE(result, '') FROM cloud_enum_scans
+		WHERE scope_target_id = $1 AND status = 'success'
+		ORDER BY created_at DESC LIMIT 1`, scopeTargetID).Scan(&result)
+	if err == nil && result != "" {
+		var enumResults []CloudEnumResult
+		if err := json.Unmarshal([]byte(result), &enumResults); err != nil {
+			log.Printf("[CLOUD-EXPOSURE] [WARN] Failed to parse cloud_enum results: %v", err)
+		}
+		for _, enumResult := range enumResults {
+			if t, ok := parseStorageTarget(enumResult.Target); ok {
+				t.Source, t.SourceURL = "cloud_enum", enumResult.Target
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT COALESCE(asset_url, ''), asset_domain FROM katana_company_cloud_assets
+		WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var assetURL, assetDomain string
+		if rows.Scan(&assetURL, &assetDomain) != nil {
+			continue
+		}
+		for _, candidate := range []string{assetURL, assetDomain} {
+			if t, ok := parseStorageTarget(candidate); ok {
+				t.Source, t.SourceURL = "katana_company", candidate
+				targets = append(targets, t)
+				break
+			}
+		}
+	}
+	return targets, nil
+}
+
+// storageFinding is the outcome of one check against one bucket.
+type storageFinding struct {
+	Check      string
+	URL        string
+	Exposed    bool
+	StatusCode int
+	Severity   string
+	Evidence   map[string]interface{}
+}
+
+type storageResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func storageRequest(client *http.Client, method, target string, body []byte, headers map[string]string) (storageResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return storageResponse{}, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return storageResponse{}, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, vhostBodyLimit))
+	return storageResponse{status: resp.StatusCode, header: resp.Header, body: respBody}, nil
+}
+
+// storageErrorCode pulls <Code> out of an S3, GCS XML or Azure error body.
+func storageErrorCode(body []byte) string {
+	var e struct {
+		Code string `xml:"Code"`
+	}
+	if xml.Unmarshal(body, &e) == nil {
+		return e.Code
+	}
+	return ""
+}
+
+func storageEvidence(method, target string, resp storageResponse, err error) map[string]interface{} {
+	evidence := map[string]interface{}{"request": method + " " + target}
+	if err != nil {
+		evidence["error"] = err.Error()
+		return evidence
+	}
+	evidence["status_code"] = resp.status
+	if code := storageErrorCode(resp.body); code != "" {
+		evidence["error_code"] = code
+	}
+	return evidence
+}
+
+func (t storageTarget) providerHeaders() map[string]string {
+	if t.Provider == StorageProviderAzure {
+		return map[string]string{"x-ms-version": azureStorageAPIVersion}
+	}
+	return map[string]string{}
+}
+
+type cloudExposureOptions struct {
+	write bool
+	acl   bool
+}
+
+// checkStorageTarget tests a bucket for anonymous listing, then object read
+// using the listed keys or the object it was discovered through, and, when
+// opted in, anonymous write and ACL/IAM policy exposure.
+func checkStorageTarget(client *http.Client, t storageTarget, opts cloudExposureOptions) []storageFinding {
+	var findings []storageFinding
+
+	listURL := t.baseURL() + "?list-type=2&max-keys=20"
+	if t.Provider == StorageProviderAzure {
+		listURL = t.baseURL() + "?restype=container&comp=list&maxresults=20"
+	}
+	resp, err := storageRequest(client, http.MethodGet, listURL, nil, t.providerHeaders())
+	// S3 answers requests for a bucket outside the addressed region with its
+	// actual region; retry against the regional endpoint so path-style URLs for
+	// dotted bucket names reach the bucket.
+	if region := resp.header.Get("x-amz-bucket-region"); err == nil && t.Provider == StorageProviderS3 &&
+		t.endpoint() == "" && region != "" && region != t.Region {
+		t.Region = region
+		listURL = t.baseURL() + "?list-type=2&max-keys=20"
+		resp, err = storageRequest(client, http.MethodGet, listURL, nil, t.providerHeaders())
+	}
+	list := storageFinding{Check: "list", URL: listURL, StatusCode: resp.status, Severity: "info", Evidence: storageEvidence(http.MethodGet, listURL, resp, err)}
+	if t.Region != "" {
+		list.Evidence["region"] = t.Region
+	}
+	if err != nil {
+		return append(findings, list)
+	}
+	var keys []string
+	if resp.status == http.StatusOK {
+		var listing struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			IsTruncated bool `xml:"IsTruncated"`
+		}
+		if xml.Unmarshal(resp.body, &listing) == nil {
+			for _, c := range listing.Contents {
+				keys = append(keys, c.Key)
+			}
+			for _, b := range listing.Blobs {
+				keys = append(keys, b.Name)
+			}
+			list.Exposed, list.Severity = true, "high"
+			list.Evidence["object_count"] = len(keys)
+			list.Evidence["truncated"] = listing.IsTruncated
+			list.Evidence["sample_keys"] = keys[:min(len(keys), 5)]
+		}
+	}
+	findings = append(findings, list)
+	switch list.Evidence["error_code"] {
+	case "NoSuchBucket", "ContainerNotFound":
+		return findings
+	}
+
+	readKey := t.Object
+	for _, key := range keys {
+		if readKey != "" {
+			break
+		}
+		if !strings.HasSuffix(key, "/") {
+			readKey = key
+		}
+	}
+	if readKey != "" {
+		findings = append(findings, checkStorageRead(client, t, readKey))
+	}
+	if opts.write {
+		findings = append(findings, checkStorageWrite(client, t))
+	}
+	if opts.acl {
+		findings = append(findings, checkStorageACL(client, t))
+	}
+	return findings
+}
+
+func checkStorageRead(client *http.Client, t storageTarget, key string) storageFinding {
+	objectURL := t.objectURL(key)
+	headers := t.providerHeaders()
+	headers["Range"] = fmt.Sprintf("bytes=0-%d", cloudExposureReadBytes-1)
+	resp, err := storageRequest(client, http.MethodGet, objectURL, nil, headers)
+	read := storageFinding{Check: "read", URL: objectURL, StatusCode: resp.status, Severity: "info", Evidence: storageEvidence(http.MethodGet, objectURL, resp, err)}
+	read.Evidence["key"] = key
+	if err == nil && (resp.status == http.StatusOK || resp.status == http.StatusPartialContent) {
+		// Keep a digest of the sample rather than the content itself
+		sum := sha256.Sum256(resp.body)
+		read.Exposed, read.Severity = true, "medium"
+		read.Evidence["content_type"] = resp.header.Get("Content-Type")
+		read.Evidence["content_range"] = resp.header.Get("Content-Range")
+		read.Evidence["sample_bytes"] = len(resp.body)
+		read.Evidence["sample_sha256"] = hex.EncodeToString(sum[:])
+	}
+	return read
+}
+
+// checkStorageWrite uploads a uniquely named marker object and deletes it again
+// if the upload was accepted.
+func checkStorageWrite(client *http.Client, t storageTarget) storageFinding {
+	key := "ars0n-exposure-check-" + uuid.New().String() + ".txt"
+	objectURL := t.objectURL(key)
+	headers := t.providerHeaders()
+	headers["Content-Type"] = "text/plain"
+	if t.Provider == StorageProviderAzure {
+		headers["x-ms-blob-type"] = "BlockBlob"
+	}
+	body := []byte("Anonymous write exposure check. This object is safe to delete.\n")
+	resp, err := storageRequest(client, http.MethodPut, objectURL, body, headers)
+	write := storageFinding{Check: "write", URL: objectURL, StatusCode: resp.status, Severity: "info", Evidence: storageEvidence(http.MethodPut, objectURL, resp, err)}
+	if err != nil || (resp.status != http.StatusOK && resp.status != http.StatusCreated) {
+		return write
+	}
+
+	write.Exposed, write.Severity = true, "critical"
+	write.Evidence["key"] = key
+	cleanup, err := storageRequest(client, http.MethodDelete, objectURL, nil, t.providerHeaders())
+	switch {
+	case err != nil:
+		write.Evidence["cleanup_error"] = err.Error()
+	default:
+		write.Evidence["cleanup_status"] = cleanup.status
+		write.Evidence["anonymous_delete"] = cleanup.status >= 200 && cleanup.status < 300
+	}
+	if err != nil || cleanup.status >= 300 {
+		log.Printf("[CLOUD-EXPOSURE] [WARN] Marker object %s could not be removed anonymously", objectURL)
+	}
+	return write
+}
+
+var publicStorageGrantees = []string{"AllUsers", "AuthenticatedUsers", "allUsers", "allAuthenticatedUsers"}
+
+func isPublicGrantee(grantee string) bool {
+	for _, public := range publicStorageGrantees {
+		if strings.HasSuffix(grantee, public) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStorageACL reads the bucket's access policy anonymously: the S3 ACL, the
+// GCS IAM policy or the Azure container ACL. A readable policy is exposed, and
+// one granting write or admin rights to everyone is critical.
+func checkStorageACL(client *http.Client, t storageTarget) storageFinding {
+	aclURL := t.baseURL() + "?acl"
+	switch t.Provider {
+	case StorageProviderGCS:
+		endpoint := t.endpoint()
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		aclURL = endpoint + "/storage/v1/b/" + url.PathEscape(t.Bucket) + "/iam"
+	case StorageProviderAzure:
+		aclURL = t.baseURL() + "?restype=container&comp=acl"
+	}
+	resp, err := storageRequest(client, http.MethodGet, aclURL, nil, t.providerHeaders())
+	acl := storageFinding{Check: "acl", URL: aclURL, StatusCode: resp.status, Severity: "info", Evidence: storageEvidence(http.MethodGet, aclURL, resp, err)}
+	if err != nil || resp.status != http.StatusOK {
+		return acl
+	}
+
+	acl.Exposed, acl.Severity = true, "high"
+	var publicGrants []string
+	writable := false
+	switch t.Provider {
+	case StorageProviderS3:
+		var policy struct {
+			Grants []struct {
+				URI        string `xml:"Grantee>URI"`
+				Permission string `xml:"Permission"`
+			} `xml:"AccessControlList>Grant"`
+		}
+		if xml.Unmarshal(resp.body, &policy) == nil {
+			for _, grant := range policy.Grants {
+				if isPublicGrantee(grant.URI) {
+					publicGrants = append(publicGrants, grant.URI+":"+grant.Permission)
+					writable = writable || (grant.Permission != "READ" && grant.Permission != "READ_ACP")
+				}
+			}
+		}
+	case StorageProviderGCS:
+		var policy struct {
+			Bindings []struct {
+				Role    string   `json:"role"`
+				Members []string `json:"members"`
+			} `json:"bindings"`
+		}
+		if json.Unmarshal(resp.body, &policy) == nil {
+			for _, binding := range policy.Bindings {
+				for _, member := range binding.Members {
+					if isPublicGrantee(member) {
+						publicGrants = append(publicGrants, member+":"+binding.Role)
+						writable = writable || (!strings.Contains(binding.Role, "Viewer") && !strings.Contains(binding.Role, "Reader"))
+					}
+				}
+			}
+		}
+	case StorageProviderAzure:
+		if access := resp.header.Get("x-ms-blob-public-access"); access != "" {
+			publicGrants = append(publicGrants, "anonymous:"+access)
+		}
+	}
+	acl.Evidence["public_grants"] = publicGrants
+	if writable {
+		acl.Severity = "critical"
+	}
+	return acl
+}
+
+func storeStorageFindings(scanID, scopeTargetID string, t storageTarget, findings []storageFinding) {
+	for _, f := range findings {
+		evidence, _ := json.Marshal(f.Evidence)
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO cloud_exposure_findings (
+				scan_id, scope_target_id, provider, account, bucket, check_name, url, exposed, status_code,
+				severity, evidence, source, source_url
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), $10, $11, NULLIF($12, ''), NULLIF($13, ''))
+			ON CONFLICT (scope_target_id, provider, account, bucket, check_name) DO UPDATE SET
+				scan_id = EXCLUDED.scan_id, url = EXCLUDED.url, exposed = EXCLUDED.exposed,
+				status_code = EXCLUDED.status_code, severity = EXCLUDED.severity, evidence = EXCLUDED.evidence,
+				source = COALESCE(EXCLUDED.source, cloud_exposure_findings.source),
+				source_url = COALESCE(EXCLUDED.source_url, cloud_exposure_findings.source_url),
+				last_checked = NOW()`,
+			scanID, scopeTargetID, t.Provider, t.Account, t.Bucket, f.Check, f.URL, f.Exposed, f.StatusCode,
+			f.Severity, evidence, t.Source, t.SourceURL)
+		if err != nil {
+			log.Printf("[CLOUD-EXPOSURE] [ERROR] Failed to store %s finding for %s: %v", f.Check, t.Bucket, err)
+		}
+	}
+}
+
+// RunCloudExposureScan checks the buckets discovered for a scope target, plus any
+// explicitly supplied targets. Write and ACL checks only run when requested.
+func RunCloudExposureScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID     string          `json:"scope_target_id"`
+		AutoScanSessionID *string         `json:"auto_scan_session_id,omitempty"`
+		TestWrite         bool            `json:"test_write"`
+		TestACL           bool            `json:"test_acl"`
+		Targets           []storageTarget `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
+		http.Error(w, "Invalid request body. scope_target_id is required.", http.StatusBadRequest)
+		return
+	}
+	for i, t := range payload.Targets {
+		switch {
+		case t.Provider != StorageProviderS3 && t.Provider != StorageProviderGCS && t.Provider != StorageProviderAzure:
+			http.Error(w, "Target provider must be one of s3, gcs or azure", http.StatusBadRequest)
+			return
+		case t.Bucket == "" || (t.Provider == StorageProviderAzure && t.Account == "" && t.Endpoint == ""):
+			http.Error(w, "Targets require a bucket, and Azure targets an account or endpoint", http.StatusBadRequest)
+			return
+		case !storageBucketName.MatchString(t.Bucket) || (t.Account != "" && !storageAccountName.MatchString(t.Account)):
+			http.Error(w, "Target bucket or account name is not valid", http.StatusBadRequest)
+			return
+		case t.Endpoint != "" && !allowedStorageEndpoint(t.Provider, t.Endpoint):
+			http.Error(w, "Target endpoint must be the provider's public storage endpoint or the configured one", http.StatusBadRequest)
+			return
+		}
+		payload.Targets[i].Source = "manual"
+	}
+
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO cloud_exposure_scans (scan_id, scope_target_id, status, test_write, test_acl, auto_scan_session_id)
+		VALUES ($1, $2, 'pending', $3, $4, $5)`,
+		scanID, payload.ScopeTargetID, payload.TestWrite, payload.TestACL, autoScanSessionID)
+	if err != nil {
+		log.Printf("[CLOUD-EXPOSURE] [ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteCloudExposureScan(scanID, payload.ScopeTargetID, payload.Targets, cloudExposureOptions{write: payload.TestWrite, acl: payload.TestACL})
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateCloudExposureScan(scanID, status, errorMessage string, checked, exposed int, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE cloud_exposure_scans
+		SET status = $1, error_message = NULLIF($2, ''), buckets_checked = $3, exposed_count = $4, execution_time = $5
+		WHERE scan_id = $6`,
+		status, errorMessage, checked, exposed, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[CLOUD-EXPOSURE] [ERROR] Failed to update scan %s: %v", scanID, err)
+	}
+}
+
+func ExecuteCloudExposureScan(scanID, scopeTargetID string, extra []storageTarget, opts cloudExposureOptions) {
+	startTime := time.Now()
+	discovered, err := loadStorageTargets(scopeTargetID)
+	if err != nil {
+		updateCloudExposureScan(scanID, "error", fmt.Sprintf("Failed to load cloud assets: %v", err), 0, 0, startTime)
+		return
+	}
+
+	seen := make(map[string]bool)
+	var targets []storageTarget
+	for _, t := range append(extra, discovered...) {
+		if seen[t.key()] {
+			continue
+		}
+		seen[t.key()] = true
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		updateCloudExposureScan(scanID, "error", "No buckets or containers to check. Run Cloud Enum or Katana first.", 0, 0, startTime)
+		return
+	}
+	updateCloudExposureScan(scanID, "running", "", 0, 0, startTime)
+
+	client := egressClient(EgressScopeCloudStorage, &http.Client{Timeout: vhostRequestTimeout})
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	checked, exposed := 0, 0
+	sem := make(chan struct{}, defaultVhostConcurrency)
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t storageTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			findings := checkStorageTarget(client, t, opts)
+			storeStorageFindings(scanID, scopeTargetID, t, findings)
+
+			mu.Lock()
+			defer mu.Unlock()
+			checked++
+			for _, f := range findings {
+				if f.Exposed {
+					exposed++
+					break
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	updateCloudExposureScan(scanID, "success", "", checked, exposed, startTime)
+	log.Printf("[CLOUD-EXPOSURE] [INFO] Scan %s found %d exposed of %d buckets in %s", scanID, exposed, checked, time.Since(startTime))
+}
+
+func GetCloudExposureScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, status, test_write, test_acl, buckets_checked, exposed_count,
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM cloud_exposure_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, status, errorMessage, execTime string
+		var testWrite, testACL bool
+		var checked, exposed int
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &status, &testWrite, &testACL, &checked, &exposed, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scans = append(scans, map[string]interface{}{
+			"scan_id":         scanID,
+			"status":          status,
+			"test_write":      testWrite,
+			"test_acl":        testACL,
+			"buckets_checked": checked,
+			"exposed_count":   exposed,
+			"error_message":   errorMessage,
+			"execution_time":  execTime,
+			"created_at":      createdAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+// GetCloudExposureFindings lists the latest result of every check per bucket,
+// exposed findings first. ?exposed=true limits the list to exposures.
+func GetCloudExposureFindings(w http.ResponseWriter, r *http.Request) {
+	exposedOnly := r.URL.Query().Get("exposed") == "true"
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT provider, account, bucket, check_name, url, exposed, COALESCE(status_code, 0), severity,
+			evidence, COALESCE(source, ''), COALESCE(source_url, ''), first_seen, last_checked
+		FROM cloud_exposure_findings
+		WHERE scope_target_id = $1 AND (NOT $2 OR exposed)
+		ORDER BY exposed DESC,
+			CASE severity WHEN 'critical' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3 END,
+			provider, account, bucket, check_name`, mux.Vars(r)["id"], exposedOnly)
+	if err != nil {
+		http.Error(w, "Failed to get cloud exposure findings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	findings := []map[string]interface{}{}
+	for rows.Next() {
+		var provider, account, bucket, check, findingURL, severity, source, sourceURL string
+		var exposed bool
+		var statusCode int
+		var evidence json.RawMessage
+		var firstSeen, lastChecked time.Time
+		if err := rows.Scan(&provider, &account, &bucket, &check, &findingURL, &exposed, &statusCode, &severity,
+			&evidence, &source, &sourceURL, &firstSeen, &lastChecked); err != nil {
+			continue
+		}
+		findings = append(findings, map[string]interface{}{
+			"provider":     provider,
+			"account":      account,
+			"bucket":       bucket,
+			"check":        check,
+			"url":          findingURL,
+			"exposed":      exposed,
+			"status_code":  statusCode,
+			"severity":     severity,
+			"evidence":     evidence,
+			"source":       source,
+			"source_url":   sourceURL,
+			"first_seen":   firstSeen.Format(time.RFC3339),
+			"last_checked": lastChecked.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(findings)
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"ars0n-framework-v2-server/cloudranges"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const cloudRangesMaxAge = 7 * 24 * time.Hour
+
+func cloudRangesPath() string {
+	if path := os.Getenv("CLOUD_RANGES_PATH"); path != "" {
+		return path
+	}
+	return "/app/feeds/cloud-ranges"
+}
+
+func refreshCloudRanges() (*cloudranges.Index, []error) {
+	client := egressClient(EgressScopeDownload, &http.Client{Timeout: 120 * time.Second})
+	return cloudranges.Refresh(client, cloudRangesPath())
+}
+
+// refreshCloudRangesIfStale downloads the range files when none are loaded or
+// the oldest downloadable one is more than a week old.
+func refreshCloudRangesIfStale() {
+	index := cloudranges.Current()
+	if index.Ranges > 0 && time.Since(index.OldestSource()) < cloudRangesMaxAge {
+		return
+	}
+	index, errs := refreshCloudRanges()
+	for _, err := range errs {
+		log.Printf("[CLOUD-RANGES] [WARN] Refresh: %v", err)
+	}
+	if index != nil {
+		log.Printf("[CLOUD-RANGES] [INFO] Refreshed cloud provider ranges (%d prefixes)", index.Ranges)
+	}
+}
+
+// InitCloudRanges loads the provider range files from disk and, unless
+// CLOUD_RANGES_AUTO_REFRESH=false, keeps them fresh with a daily background job.
+func InitCloudRanges() {
+	path := cloudRangesPath()
+	index, err := cloudranges.Load(path)
+	switch {
+	case err == nil:
+		cloudranges.SetIndex(index)
+		log.Printf("[CLOUD-RANGES] [INFO] Loaded %d cloud provider prefixes from %s", index.Ranges, path)
+	case !os.IsNotExist(err):
+		log.Printf("[CLOUD-RANGES] [WARN] Ignoring cloud ranges at %s: %v", path, err)
+	}
+	if os.Getenv("CLOUD_RANGES_AUTO_REFRESH") == "false" {
+		return
+	}
+
+	go func() {
+		refreshCloudRangesIfStale()
+		for range time.Tick(24 * time.Hour) {
+			refreshCloudRangesIfStale()
+		}
+	}()
+}
+
+// GetCloudRangesStatus reports which range files are loaded.
+func GetCloudRangesStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"index": cloudranges.Current(),
+		"path":  cloudRangesPath(),
+	})
+}
+
+// RefreshCloudRanges downloads the published range files and makes them current.
+func RefreshCloudRanges(w http.ResponseWriter, r *http.Request) {
+	index, errs := refreshCloudRanges()
+	messages := []string{}
+	for _, err := range errs {
+		log.Printf("[CLOUD-RANGES] [WARN] Refresh: %v", err)
+		messages = append(messages, err.Error())
+	}
+	if index == nil {
+		http.Error(w, "Failed to refresh cloud ranges", http.StatusBadGateway)
+		return
+	}
+	log.Printf("[CLOUD-RANGES] [INFO] Refreshed cloud provider ranges (%d prefixes)", index.Ranges)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"index":  index,
+		"path":   cloudRangesPath(),
+		"errors": messages,
+	})
+}
+
+// ReloadCloudRanges re-reads the range files after they were updated on disk.
+func ReloadCloudRanges(w http.ResponseWriter, r *http.Request) {
+	index, err := cloudranges.Load(cloudRangesPath())
+	if err != nil {
+		log.Printf("[CLOUD-RANGES] [ERROR] Reload failed: %v", err)
+		http.Error(w, "Failed to load cloud ranges: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cloudranges.SetIndex(index)
+	log.Printf("[CLOUD-RANGES] [INFO] Loaded %d cloud provider prefixes", index.Ranges)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"index": index,
+		"path":  cloudRangesPath(),
+	})
+}
+
+// LookupCloudRange attributes a single ?ip= to a provider.
+func LookupCloudRange(w http.ResponseWriter, r *http.Request) {
+	ip := r.URL.Query().Get("ip")
+	if ip == "" {
+		http.Error(w, "ip is required", http.StatusBadRequest)
+		return
+	}
+	match, ok := cloudranges.Current().Lookup(ip)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ip":      ip,
+		"matched": ok,
+		"range":   match,
+	})
+}
+
+// enrichCloudAttribution tags consolidated IP and live web server assets with
+// the provider, region and service of the published range they fall in.
+func enrichCloudAttribution(scopeTargetID string) (int, error) {
+	index := cloudranges.Current()
+	if index.Ranges == 0 {
+		log.Printf("[CLOUD-RANGES] [INFO] No cloud provider ranges loaded, skipping attribution")
+		return 0, nil
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, ip_address FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1::uuid AND asset_type IN ('ip_address', 'live_web_server')
+			AND ip_address IS NOT NULL AND ip_address != ''`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var id, ip string
+		if rows.Scan(&id, &ip) != nil {
+			continue
+		}
+		if match, ok := index.Lookup(ip); ok {
+			batch.Queue(`
+				UPDATE consolidated_attack_surface_assets
+				SET cloud_provider = $1, cloud_region = NULLIF($2, ''), cloud_service_type = NULLIF($3, '')
+				WHERE id = $4`,
+				match.Provider, match.Region, match.Service, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := dbPool.SendBatch(context.Background(), batch).Close(); err != nil {
+		return 0, err
+	}
+	return batch.Len(), nil
+}
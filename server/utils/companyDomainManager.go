@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"log"
 	"strings"
+
+	"ars0n-framework-v2-server/domainutil"
 )
 
 // Google Dorking domain functions
@@ -701,7 +703,7 @@ func GetLiveWebServerDomainsForTool(scopeTargetID string) ([]string, error) {
 		var hostname, url string
 		if err := rows.Scan(&hostname, &url); err == nil {
 			// Add hostname if it's a valid domain (not an IP)
-			if hostname != "" && !isIPv4Address(hostname) {
+			if hostname != "" && domainutil.IsValid(hostname) {
 				if !domainSet[hostname] {
 					domainSet[hostname] = true
 					domains = append(domains, hostname)
@@ -710,7 +712,7 @@ func GetLiveWebServerDomainsForTool(scopeTargetID string) ([]string, error) {
 
 			// Extract domain from URL
 			if url != "" {
-				if domain := extractDomainFromURL(url); domain != "" && !isIPv4Address(domain) {
+				if domain := domainutil.DomainFromURL(url); domain != "" {
 					if !domainSet[domain] {
 						domainSet[domain] = true
 						domains = append(domains, domain)
@@ -779,27 +781,56 @@ func DeleteAllLiveWebServerDomainsFromTool(scopeTargetID string) (int64, error)
 	return result.RowsAffected(), nil
 }
 
-// Helper functions for live web server domain extraction
-func isIPv4Address(s string) bool {
-	return strings.Contains(s, ".") &&
-		len(strings.Split(s, ".")) == 4 &&
-		!strings.Contains(s, " ")
+// TLS certificate domain functions. These are root domains found on certificates
+// that belong to the company but fall outside the known company domains.
+func GetTLSCertificateDomainsForTool(scopeTargetID string) ([]string, error) {
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT DISTINCT root_domain FROM certificate_names
+		 WHERE scope_target_id = $1 AND classification = 'same_org' AND NOT dismissed
+		 AND root_domain IS NOT NULL
+		 ORDER BY root_domain`,
+		scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []string
+	for rows.Next() {
+		var domain string
+		if err := rows.Scan(&domain); err == nil {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
 }
 
-func extractDomainFromURL(urlStr string) string {
-	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-		urlStr = "http://" + urlStr
+// DeleteTLSCertificateDomainFromTool dismisses the domain rather than deleting
+// it, so the next certificate harvest does not bring it back.
+func DeleteTLSCertificateDomainFromTool(scopeTargetID, domainToDelete string) (bool, error) {
+	result, err := dbPool.Exec(context.Background(),
+		`UPDATE certificate_names SET dismissed = true
+		 WHERE scope_target_id = $1 AND root_domain = $2 AND classification = 'same_org' AND NOT dismissed`,
+		scopeTargetID, domainToDelete)
+	if err != nil {
+		log.Printf("[DOMAIN-MANAGER] [ERROR] Failed to dismiss TLS certificate domain: %v", err)
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("[DOMAIN-MANAGER] [WARNING] TLS certificate domain '%s' not found", domainToDelete)
+		return false, nil
 	}
+	log.Printf("[DOMAIN-MANAGER] [INFO] Dismissed TLS certificate domain '%s'", domainToDelete)
+	return true, nil
+}
 
-	// Simple domain extraction from URL
-	parts := strings.Split(urlStr, "/")
-	if len(parts) >= 3 {
-		hostPart := parts[2]
-		// Remove port if present
-		if colonIndex := strings.Index(hostPart, ":"); colonIndex != -1 {
-			hostPart = hostPart[:colonIndex]
-		}
-		return hostPart
+func DeleteAllTLSCertificateDomainsFromTool(scopeTargetID string) (int64, error) {
+	result, err := dbPool.Exec(context.Background(),
+		`UPDATE certificate_names SET dismissed = true
+		 WHERE scope_target_id = $1 AND classification = 'same_org' AND NOT dismissed`,
+		scopeTargetID)
+	if err != nil {
+		return 0, err
 	}
-	return ""
+	return result.RowsAffected(), nil
 }
@@ -118,7 +118,7 @@ func ExecuteAndParseCTLCompanyScan(scanID, companyName string) {
 	requestURL := fmt.Sprintf("https://crt.sh/?O=%s&output=json", encodedCompanyName)
 	log.Printf("[CTL-COMPANY] [DEBUG] Requesting URL: %s", requestURL)
 
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := egressClient(EgressScopeCTL, &http.Client{Timeout: 60 * time.Second})
 
 	req, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
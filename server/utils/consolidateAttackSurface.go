@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -59,6 +60,13 @@ type AttackSurfaceAsset struct {
 	CloudServiceType *string `json:"cloud_service_type,omitempty"`
 	CloudRegion      *string `json:"cloud_region,omitempty"`
 
+	// Service fields
+	ServiceProduct *string                `json:"service_product,omitempty"`
+	ServiceVersion *string                `json:"service_version,omitempty"`
+	ServiceBanner  *string                `json:"service_banner,omitempty"`
+	ServiceTLS     *bool                  `json:"service_tls,omitempty"`
+	ServiceDetails map[string]interface{} `json:"service_details,omitempty"`
+
 	// FQDN fields
 	FQDN           *string                `json:"fqdn,omitempty"`
 	RootDomain     *string                `json:"root_domain,omitempty"`
@@ -128,6 +136,7 @@ type ConsolidationResult struct {
 	NetworkRanges      int                  `json:"network_ranges"`
 	IPAddresses        int                  `json:"ip_addresses"`
 	LiveWebServers     int                  `json:"live_web_servers"`
+	NetworkServices    int                  `json:"network_services"`
 	CloudAssets        int                  `json:"cloud_assets"`
 	FQDNs              int                  `json:"fqdns"`
 	TotalRelationships int                  `json:"total_relationships"`
@@ -195,6 +204,23 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[ATTACK SURFACE] Consolidated %d live web servers", liveWebServers)
 
+	log.Printf("[ATTACK SURFACE] Consolidating network services...")
+	networkServices, err := consolidateNetworkServices(scopeTargetID)
+	if err != nil {
+		log.Printf("Error consolidating network services: %v", err)
+		http.Error(w, "Failed to consolidate network services", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[ATTACK SURFACE] Consolidated %d network services", networkServices)
+
+	log.Printf("[ATTACK SURFACE] Enriching IPs from the local ASN database...")
+	localASNs, err := enrichLocalASN(scopeTargetID)
+	if err != nil {
+		log.Printf("Error enriching IPs from the local ASN database: %v", err)
+	} else {
+		log.Printf("[ATTACK SURFACE] Enriched %d IP and live web server assets with local ASN data", localASNs)
+	}
+
 	// Consolidate FQDNs (before cloud assets so we can parse them for cloud domains)
 	log.Printf("[ATTACK SURFACE] Consolidating FQDNs...")
 	fqdns, err := consolidateFQDNs(scopeTargetID)
@@ -214,6 +240,15 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[ATTACK SURFACE] Consolidated %d cloud assets", cloudAssets)
 
+	log.Printf("[ATTACK SURFACE] Attributing IPs to cloud providers...")
+	cloudAttributed, err := enrichCloudAttribution(scopeTargetID)
+	if err != nil {
+		// Attribution is enrichment only, so a failure should not fail consolidation
+		log.Printf("Error attributing IPs to cloud providers: %v", err)
+	} else {
+		log.Printf("[ATTACK SURFACE] Attributed %d IP and live web server assets to cloud providers", cloudAttributed)
+	}
+
 	// Create comprehensive relationships between assets
 	log.Printf("[ATTACK SURFACE] Creating comprehensive asset relationships...")
 	relationshipCount, err := createComprehensiveAssetRelationships(scopeTargetID)
@@ -241,6 +276,7 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 		NetworkRanges:      networkRanges,
 		IPAddresses:        ipAddresses,
 		LiveWebServers:     liveWebServers,
+		NetworkServices:    networkServices,
 		CloudAssets:        cloudAssets,
 		FQDNs:              fqdns,
 		TotalRelationships: relationshipCount,
@@ -257,6 +293,7 @@ func ConsolidateAttackSurface(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[ATTACK SURFACE]   • Network Ranges: %d", networkRanges)
 	log.Printf("[ATTACK SURFACE]   • IP Addresses: %d", ipAddresses)
 	log.Printf("[ATTACK SURFACE]   • Live Web Servers: %d", liveWebServers)
+	log.Printf("[ATTACK SURFACE]   • Network Services: %d", networkServices)
 	log.Printf("[ATTACK SURFACE]   • Cloud Assets: %d", cloudAssets)
 	log.Printf("[ATTACK SURFACE]   • FQDNs: %d", fqdns)
 	log.Printf("[ATTACK SURFACE]   • Asset Relationships: %d", relationshipCount)
@@ -337,6 +374,7 @@ func GetAttackSurfaceAssetCounts(w http.ResponseWriter, r *http.Request) {
 		"live_web_servers": 0,
 		"cloud_assets":     0,
 		"fqdns":            0,
+		"services":         0,
 	}
 
 	for rows.Next() {
@@ -361,6 +399,8 @@ func GetAttackSurfaceAssetCounts(w http.ResponseWriter, r *http.Request) {
 			counts["cloud_assets"] = count
 		case "fqdn":
 			counts["fqdns"] = count
+		case "service":
+			counts["services"] = count
 		}
 	}
 
@@ -921,6 +961,15 @@ func consolidateIPAddresses(scopeTargetID string) (int, error) {
 			WHERE aecdr.scope_target_id = $1::uuid AND aecdr.record_type = 'A'
 			AND aecdr.record ~ '^(\d{1,3}\.){3}\d{1,3}$'
 			AND aecdr.record IS NOT NULL AND aecdr.record != ''
+
+			UNION
+
+			-- 8. IPv6 addresses from AAAA records (validated in Go before casting)
+			SELECT DISTINCT
+				aaaa.record::inet as ip_address,
+				'dns_aaaa_record' as source_type,
+				ARRAY[aaaa.record]::text[] as source_ips
+			FROM unnest($2::text[]) AS aaaa(record)
 		),
 		ip_enriched_data AS (
 			SELECT
@@ -976,7 +1025,14 @@ func consolidateIPAddresses(scopeTargetID string) (int, error) {
 			last_updated = NOW()
 	`
 
-	result, err := dbPool.Exec(context.Background(), query, scopeTargetID)
+	ipv6Addresses, err := collectIPv6Addresses(scopeTargetID)
+	if err != nil {
+		log.Printf("[IP CONSOLIDATION] Error collecting AAAA records: %v", err)
+		ipv6Addresses = []string{}
+	}
+	log.Printf("[IP CONSOLIDATION] Debug - dns_aaaa_records: %d addresses", len(ipv6Addresses))
+
+	result, err := dbPool.Exec(context.Background(), query, scopeTargetID, ipv6Addresses)
 	if err != nil {
 		log.Printf("[IP CONSOLIDATION] Error inserting consolidated IP addresses: %v", err)
 		return 0, err
@@ -2699,6 +2755,14 @@ func consolidateFQDNs(scopeTargetID string) (int, error) {
 				FROM censys_company_scans
 				WHERE scope_target_id = $1::uuid AND status = 'success' 
 				AND result IS NOT NULL AND result::jsonb ? 'names'
+				
+				UNION ALL
+				
+				-- In-scope names harvested from TLS certificate SANs
+				SELECT DISTINCT
+					name as domain_result
+				FROM certificate_names
+				WHERE scope_target_id = $1::uuid AND classification = 'in_scope'
 			) company_domains
 			WHERE domain_result IS NOT NULL 
 			AND domain_result != ''
@@ -3146,6 +3210,36 @@ func createComprehensiveAssetRelationships(scopeTargetID string) (int, error) {
 	totalRelationships += liveWebServerToCloudCount
 	log.Printf("[RELATIONSHIP MAPPING] Created %d Live Web Server -> Cloud Asset relationships", liveWebServerToCloudCount)
 
+	// 8. IP Addresses -> FQDNs served as virtual hosts
+	log.Printf("[RELATIONSHIP MAPPING] Creating IP Address -> virtual host FQDN relationships...")
+	vhostCount, err := createVhostRelationships(scopeTargetID)
+	if err != nil {
+		log.Printf("[RELATIONSHIP MAPPING] Error creating virtual host relationships: %v", err)
+		return totalRelationships, err
+	}
+	totalRelationships += vhostCount
+	log.Printf("[RELATIONSHIP MAPPING] Created %d IP Address -> virtual host relationships", vhostCount)
+
+	// 9. FQDNs -> likely origin IPs behind a CDN
+	log.Printf("[RELATIONSHIP MAPPING] Creating FQDN -> likely origin IP relationships...")
+	originCount, err := createOriginRelationships(scopeTargetID)
+	if err != nil {
+		log.Printf("[RELATIONSHIP MAPPING] Error creating likely origin relationships: %v", err)
+		return totalRelationships, err
+	}
+	totalRelationships += originCount
+	log.Printf("[RELATIONSHIP MAPPING] Created %d FQDN -> likely origin relationships", originCount)
+
+	// 10. IP Addresses -> network services found by IP/port scans
+	log.Printf("[RELATIONSHIP MAPPING] Creating IP Address -> Service relationships...")
+	serviceCount, err := createServiceRelationships(scopeTargetID)
+	if err != nil {
+		log.Printf("[RELATIONSHIP MAPPING] Error creating service relationships: %v", err)
+		return totalRelationships, err
+	}
+	totalRelationships += serviceCount
+	log.Printf("[RELATIONSHIP MAPPING] Created %d IP Address -> Service relationships", serviceCount)
+
 	// Log final summary
 	log.Printf("[RELATIONSHIP MAPPING] ✅ RELATIONSHIP MAPPING COMPLETE!")
 	log.Printf("[RELATIONSHIP MAPPING] Summary for scope target %s:", scopeTargetID)
@@ -3156,6 +3250,9 @@ func createComprehensiveAssetRelationships(scopeTargetID string) (int, error) {
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> FQDN: %d", liveWebServerToFQDNCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> IP Address: %d", liveWebServerToIPCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Live Web Server -> Cloud Asset: %d", liveWebServerToCloudCount)
+	log.Printf("[RELATIONSHIP MAPPING]   • IP Address -> Virtual Host: %d", vhostCount)
+	log.Printf("[RELATIONSHIP MAPPING]   • FQDN -> Likely Origin IP: %d", originCount)
+	log.Printf("[RELATIONSHIP MAPPING]   • IP Address -> Service: %d", serviceCount)
 	log.Printf("[RELATIONSHIP MAPPING]   • Total Relationships: %d", totalRelationships)
 
 	return totalRelationships, nil
@@ -3188,6 +3285,10 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 			COALESCE(cloud_provider, '') as cloud_provider, 
 			COALESCE(cloud_service_type, '') as cloud_service_type,
 			COALESCE(cloud_region, '') as cloud_region, 
+			COALESCE(service_product, '') as service_product,
+			COALESCE(service_version, '') as service_version,
+			COALESCE(service_banner, '') as service_banner,
+			service_tls, service_details,
 			COALESCE(fqdn, '') as fqdn, 
 			COALESCE(root_domain, '') as root_domain, 
 			COALESCE(subdomain, '') as subdomain, 
@@ -3246,6 +3347,8 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 		var nameServers, status, sslProtocols, resolvedIPs, mailServers []string
 		var caaRecords, txtRecords, mxRecords, nsRecords, aRecords, aaaaRecords []string
 		var cnameRecords, ptrRecords, srvRecords []string
+		var serviceProduct, serviceVersion, serviceBanner string
+		var serviceDetails []byte
 
 		err := rows.Scan(
 			&asset.ID, &asset.ScopeTargetID, &asset.AssetType, &asset.AssetIdentifier, &assetSubtype,
@@ -3254,7 +3357,8 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 			&asset.StatusCode, &title, &webServer, &technologies, &asset.ContentLength,
 			&asset.ResponseTime, &screenshotPath, &sslInfo, &httpHeaders,
 			&findings, &cloudProvider, &cloudServiceType,
-			&cloudRegion, &fqdn, &rootDomain, &subdomain, &registrar, &asset.CreationDate,
+			&cloudRegion, &serviceProduct, &serviceVersion, &serviceBanner, &asset.ServiceTLS, &serviceDetails,
+			&fqdn, &rootDomain, &subdomain, &registrar, &asset.CreationDate,
 			&asset.ExpirationDate, &asset.UpdatedDate, &nameServers, &status, &whoisInfo,
 			&sslCertificate, &asset.SSLExpiryDate, &sslIssuer, &sslSubject, &sslVersion,
 			&sslCipherSuite, &sslProtocols, &resolvedIPs, &mailServers, &spfRecord,
@@ -3316,6 +3420,15 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 		if cloudRegion != "" {
 			asset.CloudRegion = &cloudRegion
 		}
+		if serviceProduct != "" {
+			asset.ServiceProduct = &serviceProduct
+		}
+		if serviceVersion != "" {
+			asset.ServiceVersion = &serviceVersion
+		}
+		if serviceBanner != "" {
+			asset.ServiceBanner = &serviceBanner
+		}
 		if fqdn != "" {
 			asset.FQDN = &fqdn
 		}
@@ -3376,6 +3489,9 @@ func fetchConsolidatedAssets(scopeTargetID string) ([]AttackSurfaceAsset, error)
 		if len(findings) > 0 {
 			json.Unmarshal(findings, &asset.FindingsJSON)
 		}
+		if len(serviceDetails) > 0 {
+			json.Unmarshal(serviceDetails, &asset.ServiceDetails)
+		}
 		if len(whoisInfo) > 0 {
 			json.Unmarshal(whoisInfo, &asset.WhoisInfo)
 		}
@@ -3539,6 +3655,11 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 		companyName = ""
 	}
 
+	harvestScope, err := loadCertScope(scopeTargetID)
+	if err != nil {
+		log.Printf("[FQDN ENRICHMENT] Failed to load certificate scope, SANs will not be recorded: %v", err)
+	}
+
 	enrichedCount := 0
 
 	// Process each FQDN with optimized timeouts (5-7s per operation)
@@ -3566,8 +3687,9 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 		}
 
 		// Get SSL information (optimized version with 5s timeout)
-		if sslInfoFast := getSSLInfoFast(fqdn.FQDN); sslInfoFast != nil {
+		if sslInfoFast, cert := getSSLInfoFast(fqdn.FQDN); sslInfoFast != nil {
 			lastSSLScan = time.Now()
+			recordCertificateNames(harvestScope, "consolidation", newCertObservation(fqdn.FQDN, cert))
 			sslInfo = sslInfoFast
 
 			if expDate, ok := sslInfoFast["expiration"].(time.Time); ok {
@@ -3738,10 +3860,10 @@ func enrichFQDNsWithInvestigateData(scopeTargetID string) (int, error) {
 
 // Optimized enrichment functions with reasonable timeouts for attack surface consolidation
 
-func getSSLInfoFast(domain string) map[string]interface{} {
+func getSSLInfoFast(domain string) (map[string]interface{}, *x509.Certificate) {
 	// Skip obviously non-SSL domains to save time
 	if strings.Contains(domain, "_") || strings.HasPrefix(domain, "*.") {
-		return nil
+		return nil, nil
 	}
 
 	// 2 second timeout for SSL connections (faster)
@@ -3750,13 +3872,13 @@ func getSSLInfoFast(domain string) map[string]interface{} {
 		InsecureSkipVerify: true,
 	})
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 	defer conn.Close()
 
 	certs := conn.ConnectionState().PeerCertificates
 	if len(certs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	cert := certs[0]
@@ -3779,7 +3901,9 @@ func getSSLInfoFast(domain string) map[string]interface{} {
 		"is_expired":     isExpired,
 		"is_self_signed": isSelfSigned,
 		"is_mismatched":  isMismatched,
-	}
+		"sans":           cert.DNSNames,
+		"organization":   strings.Join(cert.Subject.Organization, ", "),
+	}, cert
 }
 
 func getASNInfoFast(domain string) (string, string) {
@@ -3787,16 +3911,19 @@ func getASNInfoFast(domain string) (string, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	resolver := &net.Resolver{}
+	resolver := PoolResolver(time.Second)
 	ips, err := resolver.LookupIPAddr(ctx, domain)
 	if err != nil || len(ips) == 0 {
 		return "", ""
 	}
 
 	ip := ips[0].IP.String()
+	if asn, org, _, ok := lookupLocalASN(ip); ok && asn != "" {
+		return "AS" + asn, org
+	}
 
 	// 2 second timeout for HTTP client
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := egressClient(EgressScopeASNLookup, &http.Client{Timeout: 2 * time.Second})
 
 	// Try ipapi.co first (faster and more reliable)
 	resp, err := client.Get(fmt.Sprintf("https://ipapi.co/%s/json/", ip))
@@ -3824,13 +3951,13 @@ func getHTTPInfoFast(domain string) (int, string, string) {
 	}
 
 	// 3 second timeout for HTTP requests (faster)
-	client := &http.Client{
+	client := egressClient(EgressScopeHTTPProbe, &http.Client{
 		Timeout: 3 * time.Second,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Don't follow redirects to save time
 			return http.ErrUseLastResponse
 		},
-	}
+	})
 
 	// Try HTTPS first, then HTTP
 	urls := []string{
@@ -3872,7 +3999,7 @@ func getDNSInfoFast(domain string) ([]string, map[string]interface{}) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	resolver := &net.Resolver{}
+	resolver := PoolResolver(time.Second)
 	var resolvedIPs []string
 	dnsInfo := make(map[string]interface{})
 
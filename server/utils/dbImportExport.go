@@ -639,9 +639,9 @@ func HandleDatabaseImportURL(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Downloading file from URL: %s", request.URL)
 
 	// Download the file from the URL with timeout
-	client := &http.Client{
+	client := egressClient(EgressScopeDownload, &http.Client{
 		Timeout: 30 * time.Second,
-	}
+	})
 
 	resp, err := client.Get(request.URL)
 	if err != nil {
@@ -1,14 +1,12 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -49,6 +47,10 @@ type DNSxRawResult struct {
 }
 
 func RunDNSxCompanyScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "dnsx") {
+		return
+	}
+
 	var payload struct {
 		Domains []string `json:"domains" binding:"required"`
 	}
@@ -84,6 +86,7 @@ func RunDNSxCompanyScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("dnsx_company_scans", scanID, "dnsx")
 	go ExecuteDNSxCompanyScan(scanID, payload.Domains, scopeTargetID)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -123,31 +126,26 @@ func ExecuteDNSxCompanyScan(scanID string, domains []string, scopeTargetID strin
 	for i, domain := range domains {
 		log.Printf("[DNSX-COMPANY] [INFO] Processing domain %d/%d: %s", i+1, len(domains), domain)
 
-		cmd := exec.Command(
-			"docker", "exec", "-i",
-			"ars0n-framework-v2-dnsx-1",
-			"dnsx",
-			"-a", "-aaaa", "-cname", "-mx", "-ns", "-txt", "-ptr", "-srv",
-			"-re", "-j",
-			"-retry", "3",
-		)
-
-		commandsExecuted = append(commandsExecuted, cmd.String())
-		log.Printf("[DNSX-COMPANY] [INFO] Executing command: %s with domain: %s", cmd.String(), domain)
+		execResult, err := ToolExecutor("dnsx").Run(context.Background(), ExecRequest{
+			Args: []string{
+				"-a", "-aaaa", "-cname", "-mx", "-ns", "-txt", "-ptr", "-srv",
+				"-re", "-j",
+				"-retry", "3",
+				"-r", strings.Join(resolverIPs(HealthyResolvers()), ","),
+			},
+			Stdin: strings.NewReader(domain + "\n"),
+		})
 
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		cmd.Stdin = strings.NewReader(domain + "\n")
+		commandsExecuted = append(commandsExecuted, execResult.Command)
+		log.Printf("[DNSX-COMPANY] [INFO] Executed command: %s with domain: %s", execResult.Command, domain)
 
-		err := cmd.Run()
 		if err != nil {
 			log.Printf("[DNSX-COMPANY] [ERROR] DNSx scan failed for domain %s: %v", domain, err)
-			log.Printf("[DNSX-COMPANY] [ERROR] stderr output: %s", stderr.String())
+			log.Printf("[DNSX-COMPANY] [ERROR] stderr output: %s", execResult.Stderr)
 			continue
 		}
 
-		result := stdout.String()
+		result := execResult.Stdout
 		log.Printf("[DNSX-COMPANY] [INFO] DNSx scan completed for domain %s", domain)
 		log.Printf("[DNSX-COMPANY] [DEBUG] Raw output length: %d bytes", len(result))
 
@@ -0,0 +1,626 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ars0n-framework-v2-server/cloudranges"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Edge categories stored in edge_category.
+const (
+	EdgeCategoryCDN    = "cdn"
+	EdgeCategoryWAF    = "waf"
+	EdgeCategoryCDNWAF = "cdn_waf"
+)
+
+// edgeProviderCategories says what each provider puts in front of an application.
+var edgeProviderCategories = map[string]string{
+	"cloudflare":       EdgeCategoryCDNWAF,
+	"akamai":           EdgeCategoryCDNWAF,
+	"imperva":          EdgeCategoryCDNWAF,
+	"sucuri":           EdgeCategoryCDNWAF,
+	"cloudfront":       EdgeCategoryCDN,
+	"fastly":           EdgeCategoryCDN,
+	"azure_front_door": EdgeCategoryCDN,
+	"aws_waf":          EdgeCategoryWAF,
+	"f5_bigip":         EdgeCategoryWAF,
+	"citrix_netscaler": EdgeCategoryWAF,
+	"barracuda":        EdgeCategoryWAF,
+	"modsecurity":      EdgeCategoryWAF,
+	"wordfence":        EdgeCategoryWAF,
+}
+
+type EdgeSignal struct {
+	Source   string `json:"source"`
+	Provider string `json:"provider"`
+	Detail   string `json:"detail"`
+}
+
+type EdgeClassification struct {
+	Provider    string       `json:"edge_provider,omitempty"`
+	Category    string       `json:"edge_category,omitempty"`
+	WAF         string       `json:"waf_provider,omitempty"`
+	Blocking    bool         `json:"edge_blocking"`
+	BlockStatus int          `json:"block_status,omitempty"`
+	Signals     []EdgeSignal `json:"edge_signals,omitempty"`
+}
+
+// edgeHeaderSignatures maps a response header to the provider that adds it. An
+// empty contains matches any value.
+var edgeHeaderSignatures = []struct {
+	header   string
+	contains string
+	provider string
+}{
+	{"Cf-Ray", "", "cloudflare"},
+	{"Server", "cloudflare", "cloudflare"},
+	{"X-Akamai-Transformed", "", "akamai"},
+	{"Akamai-Grn", "", "akamai"},
+	{"Server", "akamaighost", "akamai"},
+	{"X-Amz-Cf-Id", "", "cloudfront"},
+	{"Via", "cloudfront", "cloudfront"},
+	{"X-Served-By", "cache-", "fastly"},
+	{"X-Fastly-Request-Id", "", "fastly"},
+	{"X-Azure-Ref", "", "azure_front_door"},
+	{"X-Sucuri-Id", "", "sucuri"},
+	{"Server", "sucuri", "sucuri"},
+	{"X-Iinfo", "", "imperva"},
+	{"X-Cdn", "incapsula", "imperva"},
+	{"Server", "bigip", "f5_bigip"},
+	{"Via", "ns-cache", "citrix_netscaler"},
+	{"Cneonction", "", "citrix_netscaler"},
+	{"Server", "mod_security", "modsecurity"},
+}
+
+// edgeCookieSignatures are cookie name prefixes set by the edge rather than the app.
+var edgeCookieSignatures = []struct {
+	prefix   string
+	provider string
+}{
+	{"__cf_bm", "cloudflare"},
+	{"cf_clearance", "cloudflare"},
+	{"__cfruid", "cloudflare"},
+	{"ak_bmsc", "akamai"},
+	{"bm_sz", "akamai"},
+	{"_abck", "akamai"},
+	{"incap_ses_", "imperva"},
+	{"visid_incap_", "imperva"},
+	{"sucuri_cloudproxy_", "sucuri"},
+	{"aws-waf-token", "aws_waf"},
+	{"BIGipServer", "f5_bigip"},
+	{"TS01", "f5_bigip"},
+	{"citrix_ns_id", "citrix_netscaler"},
+	{"NSC_", "citrix_netscaler"},
+	{"barra_counter_session", "barracuda"},
+}
+
+// edgeBodySignatures recognise block and challenge pages.
+var edgeBodySignatures = []struct {
+	pattern  *regexp.Regexp
+	provider string
+}{
+	{regexp.MustCompile(`(?i)attention required! \| cloudflare|cf-error-details|cdn-cgi/challenge-platform`), "cloudflare"},
+	{regexp.MustCompile(`(?i)access denied.{0,400}reference #[0-9a-f.]+`), "akamai"},
+	{regexp.MustCompile(`(?i)incapsula incident id|_incapsula_resource`), "imperva"},
+	{regexp.MustCompile(`(?i)sucuri website firewall|cloudproxy@sucuri\.net`), "sucuri"},
+	{regexp.MustCompile(`(?i)generated by cloudfront \(cloudfront\)`), "cloudfront"},
+	{regexp.MustCompile(`(?i)the requested url was rejected\. please consult with your administrator`), "f5_bigip"},
+	{regexp.MustCompile(`(?i)ns_af=|appfw_session`), "citrix_netscaler"},
+	{regexp.MustCompile(`(?i)barracuda.{0,40}web application firewall`), "barracuda"},
+	{regexp.MustCompile(`(?i)mod_security|modsecurity|not acceptable!.{0,200}an appropriate representation`), "modsecurity"},
+	{regexp.MustCompile(`(?i)generated by wordfence|wordfence-blocked`), "wordfence"},
+	{regexp.MustCompile(`(?i)<title>403 forbidden</title>.{0,200}aws`), "aws_waf"},
+}
+
+// edgeProviderForIP attributes an address to an edge provider through the
+// published cloud ranges: Cloudflare and Fastly as a whole, and the CloudFront
+// and Front Door services of AWS and Azure. Addresses in them are edge nodes
+// whatever the response headers say.
+func edgeProviderForIP(ip string) string {
+	match, ok := cloudranges.Current().Lookup(ip)
+	if !ok {
+		return ""
+	}
+	switch {
+	case match.Provider == cloudranges.ProviderCloudflare || match.Provider == cloudranges.ProviderFastly:
+		return match.Provider
+	case match.Provider == cloudranges.ProviderAWS && match.Service == "CLOUDFRONT":
+		return "cloudfront"
+	case match.Provider == cloudranges.ProviderAzure && strings.HasPrefix(match.Service, "AzureFrontDoor"):
+		return "azure_front_door"
+	}
+	return ""
+}
+
+// classifyEdge looks at one response and the IP it came from. The provider is the
+// one with the most signals; WAF is set when a WAF-capable provider was seen.
+func classifyEdge(header http.Header, body []byte, ip string) EdgeClassification {
+	var signals []EdgeSignal
+	seen := make(map[string]bool)
+	add := func(source, provider, detail string) {
+		key := source + "|" + provider
+		if !seen[key] {
+			seen[key] = true
+			signals = append(signals, EdgeSignal{Source: source, Provider: provider, Detail: detail})
+		}
+	}
+
+	for _, sig := range edgeHeaderSignatures {
+		value := header.Get(sig.header)
+		if value != "" && (sig.contains == "" || strings.Contains(strings.ToLower(value), sig.contains)) {
+			add("header", sig.provider, sig.header+": "+value)
+		}
+	}
+	for name := range cookieNames(header) {
+		for _, sig := range edgeCookieSignatures {
+			if strings.HasPrefix(name, sig.prefix) {
+				add("cookie", sig.provider, name)
+			}
+		}
+	}
+	if len(body) > 0 {
+		for _, sig := range edgeBodySignatures {
+			if m := sig.pattern.Find(body); m != nil {
+				add("body", sig.provider, string(m))
+			}
+		}
+	}
+	if ip != "" {
+		if provider := edgeProviderForIP(ip); provider != "" {
+			add("ip_range", provider, ip)
+		}
+	}
+
+	return summarizeEdgeSignals(signals)
+}
+
+func summarizeEdgeSignals(signals []EdgeSignal) EdgeClassification {
+	result := EdgeClassification{Signals: signals}
+	counts := make(map[string]int)
+	for _, s := range signals {
+		counts[s.Provider]++
+		if result.Provider == "" || counts[s.Provider] > counts[result.Provider] {
+			result.Provider = s.Provider
+		}
+	}
+	if result.Provider == "" {
+		return result
+	}
+
+	result.Category = edgeProviderCategories[result.Provider]
+	for _, s := range signals {
+		category := edgeProviderCategories[s.Provider]
+		if category == EdgeCategoryWAF || category == EdgeCategoryCDNWAF {
+			result.WAF = s.Provider
+			if category == EdgeCategoryWAF && result.Category == EdgeCategoryCDN {
+				result.Category = EdgeCategoryCDNWAF
+			}
+			break
+		}
+	}
+	return result
+}
+
+// edgeBlockProbeQuery is harmless to the application but trips stock WAF rules
+// for SQL injection, XSS and path traversal.
+const edgeBlockProbeQuery = "id=1%27%20OR%20%271%27%3D%271&q=%3Cscript%3Ealert(1)%3C%2Fscript%3E&file=..%2F..%2F..%2Fetc%2Fpasswd"
+
+var edgeBlockStatuses = map[int]bool{403: true, 406: true, 419: true, 429: true, 501: true, 503: true}
+
+// probeEdgeBlocking sends one attack-looking request and updates the
+// classification if the edge answers with a block page.
+func probeEdgeBlocking(client *http.Client, rawURL string, baselineStatus int, headers []string, result *EdgeClassification) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	parsed.RawQuery = edgeBlockProbeQuery
+	req, err := http.NewRequest("GET", parsed.String(), nil)
+	if err != nil {
+		return
+	}
+	applyRequestHeaders(req, headers)
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, vhostBodyLimit))
+
+	blocked := classifyEdge(resp.Header, body, "")
+	bodyMatched := false
+	for _, s := range blocked.Signals {
+		if s.Source == "body" {
+			bodyMatched = true
+		}
+	}
+	// Challenge scripts can appear on normal pages too, so only a changed status counts
+	if resp.StatusCode == baselineStatus || !(bodyMatched || edgeBlockStatuses[resp.StatusCode]) {
+		return
+	}
+
+	result.Blocking = true
+	result.BlockStatus = resp.StatusCode
+	waf := blocked.WAF
+	if waf == "" {
+		waf = blocked.Provider
+	}
+	if waf == "" {
+		waf = result.WAF
+	}
+	if waf == "" {
+		waf = result.Provider
+	}
+	if waf == "" {
+		waf = "unknown"
+	}
+	result.Signals = append(result.Signals, EdgeSignal{
+		Source: "block_probe", Provider: waf, Detail: fmt.Sprintf("attack payload answered with %d (baseline %d)", resp.StatusCode, baselineStatus),
+	})
+	result.WAF = waf
+	if result.Provider == "" {
+		result.Provider = waf
+		result.Category = edgeProviderCategories[waf]
+		if result.Category == "" {
+			result.Category = EdgeCategoryWAF
+		}
+	} else if result.Category == EdgeCategoryCDN {
+		result.Category = EdgeCategoryCDNWAF
+	}
+}
+
+// headerFromStoredJSON turns http_response_headers back into an http.Header.
+// Single values are stored as strings and repeated ones as arrays.
+func headerFromStoredJSON(raw string) http.Header {
+	header := http.Header{}
+	var stored map[string]interface{}
+	if raw == "" || json.Unmarshal([]byte(raw), &stored) != nil {
+		return header
+	}
+	for name, value := range stored {
+		switch v := value.(type) {
+		case string:
+			header.Add(name, v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					header.Add(name, s)
+				}
+			}
+		}
+	}
+	return header
+}
+
+func edgeHTTPClient(scope string) *http.Client {
+	return egressClient(scope, &http.Client{
+		Timeout: vhostRequestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	})
+}
+
+// classifyLiveURL fetches a URL and classifies it, probing for a blocking WAF
+// when active is set. ok is false when the URL could not be reached.
+func classifyLiveURL(client *http.Client, rawURL, ip string, headers []string, active bool) (EdgeClassification, bool) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return EdgeClassification{}, false
+	}
+	applyRequestHeaders(req, headers)
+	resp, err := client.Do(req)
+	if err != nil {
+		return EdgeClassification{}, false
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, vhostBodyLimit))
+	resp.Body.Close()
+
+	result := classifyEdge(resp.Header, body, ip)
+	if active {
+		probeEdgeBlocking(client, rawURL, resp.StatusCode, headers, &result)
+	}
+	return result, true
+}
+
+// storeTargetURLEdge records a classification. edge_blocking only changes when
+// the blocking probe ran, so a passive pass keeps an earlier active verdict.
+func storeTargetURLEdge(id string, c EdgeClassification, probed bool) {
+	signals, _ := json.Marshal(c.Signals)
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE target_urls
+		SET edge_provider = NULLIF($1, ''), edge_category = NULLIF($2, ''), waf_provider = NULLIF($3, ''),
+			edge_blocking = CASE WHEN $7 THEN $4 ELSE edge_blocking END, edge_signals = $5, edge_checked_at = NOW()
+		WHERE id = $6`,
+		c.Provider, c.Category, c.WAF, c.Blocking, signals, id, probed)
+	if err != nil {
+		log.Printf("[EDGE] [ERROR] Failed to store classification for target URL %s: %v", id, err)
+	}
+}
+
+func storeLiveWebServerEdge(id string, c EdgeClassification, probed bool) {
+	signals, _ := json.Marshal(c.Signals)
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE live_web_servers
+		SET edge_provider = NULLIF($1, ''), edge_category = NULLIF($2, ''), waf_provider = NULLIF($3, ''),
+			edge_blocking = CASE WHEN $7 THEN $4 ELSE edge_blocking END, edge_signals = $5, edge_checked_at = NOW()
+		WHERE id = $6`,
+		c.Provider, c.Category, c.WAF, c.Blocking, signals, id, probed)
+	if err != nil {
+		log.Printf("[EDGE] [ERROR] Failed to store classification for live web server %s: %v", id, err)
+	}
+}
+
+func RunEdgeClassificationScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID     string  `json:"scope_target_id"`
+		ActiveProbe       bool    `json:"active_probe"`
+		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string  `json:"request_profile_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
+		http.Error(w, "Invalid request body. scope_target_id is required.", http.StatusBadRequest)
+		return
+	}
+	// The blocking probe sends attack-looking payloads, so it only runs when asked for
+	activeProbe := payload.ActiveProbe
+
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO edge_classification_scans (scan_id, scope_target_id, status, active_probe, auto_scan_session_id)
+		VALUES ($1, $2, 'pending', $3, $4)`,
+		scanID, payload.ScopeTargetID, activeProbe, autoScanSessionID)
+	if err != nil {
+		log.Printf("[EDGE] [ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteEdgeClassificationScan(scanID, payload.ScopeTargetID, payload.RequestProfileID, activeProbe)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateEdgeScan(scanID, status, errorMessage string, urls, servers, protected int, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE edge_classification_scans
+		SET status = $1, error_message = NULLIF($2, ''), urls_classified = $3, servers_classified = $4,
+			edge_protected = $5, execution_time = $6
+		WHERE scan_id = $7`,
+		status, errorMessage, urls, servers, protected, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[EDGE] [ERROR] Failed to update scan %s: %v", scanID, err)
+	}
+}
+
+type edgeJob struct {
+	id      string
+	url     string
+	ip      string
+	header  string
+	body    string
+	isURL   bool
+	storeFn func(string, EdgeClassification, bool)
+}
+
+// ExecuteEdgeClassificationScan classifies every target URL and every live web
+// server from the latest IP/Port scan. Unreachable target URLs fall back to the
+// response stored when they were last probed.
+func ExecuteEdgeClassificationScan(scanID, scopeTargetID, requestProfileID string, activeProbe bool) {
+	startTime := time.Now()
+	var jobs []edgeJob
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, url, COALESCE(ip_address, (dns_a_records)[1], ''), COALESCE(http_response_headers::text, ''), COALESCE(http_response, '')
+		FROM target_urls WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		updateEdgeScan(scanID, "error", fmt.Sprintf("Failed to load target URLs: %v", err), 0, 0, 0, startTime)
+		return
+	}
+	for rows.Next() {
+		j := edgeJob{isURL: true, storeFn: storeTargetURLEdge}
+		if rows.Scan(&j.id, &j.url, &j.ip, &j.header, &j.body) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT id, url, host(ip_address), COALESCE(http_response_headers::text, ''), ''
+		FROM live_web_servers
+		WHERE scan_id = (
+			SELECT scan_id FROM ip_port_scans
+			WHERE scope_target_id = $1 AND status = 'success'
+			ORDER BY created_at DESC LIMIT 1
+		)`, scopeTargetID)
+	if err != nil {
+		updateEdgeScan(scanID, "error", fmt.Sprintf("Failed to load live web servers: %v", err), 0, 0, 0, startTime)
+		return
+	}
+	for rows.Next() {
+		j := edgeJob{storeFn: storeLiveWebServerEdge}
+		if rows.Scan(&j.id, &j.url, &j.ip, &j.header, &j.body) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	if len(jobs) == 0 {
+		updateEdgeScan(scanID, "error", "No target URLs or live web servers to classify", 0, 0, 0, startTime)
+		return
+	}
+	updateEdgeScan(scanID, "running", "", 0, 0, 0, startTime)
+
+	headers := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	urls, servers, protected := 0, 0, 0
+	sem := make(chan struct{}, defaultVhostConcurrency)
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job edgeJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			scope := EgressScopeIPPortScan
+			if job.isURL {
+				scope = EgressScopeHTTPProbe
+			}
+			result, ok := classifyLiveURL(edgeHTTPClient(scope), job.url, job.ip, headers, activeProbe)
+			if !ok {
+				result = classifyEdge(headerFromStoredJSON(job.header), []byte(job.body), job.ip)
+			}
+			job.storeFn(job.id, result, ok && activeProbe)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if job.isURL {
+				urls++
+			} else {
+				servers++
+			}
+			if result.Provider != "" {
+				protected++
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	updateEdgeScan(scanID, "success", "", urls, servers, protected, startTime)
+	log.Printf("[EDGE] [INFO] Scan %s classified %d URLs and %d live web servers (%d behind an edge) in %s",
+		scanID, urls, servers, protected, time.Since(startTime))
+}
+
+func GetEdgeClassificationScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, status, active_probe, urls_classified, servers_classified, edge_protected,
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM edge_classification_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, status, errorMessage, execTime string
+		var activeProbe bool
+		var urls, servers, protected int
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &status, &activeProbe, &urls, &servers, &protected, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scans = append(scans, map[string]interface{}{
+			"scan_id":            scanID,
+			"status":             status,
+			"active_probe":       activeProbe,
+			"urls_classified":    urls,
+			"servers_classified": servers,
+			"edge_protected":     protected,
+			"error_message":      errorMessage,
+			"execution_time":     execTime,
+			"created_at":         createdAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+// GetEdgeControlsSummary feeds the security controls view: which providers sit
+// in front of the scope target's URLs and which URLs have nothing in front.
+func GetEdgeControlsSummary(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, url, COALESCE(edge_provider, ''), COALESCE(edge_category, ''), COALESCE(waf_provider, ''),
+			COALESCE(edge_blocking, false), edge_checked_at IS NOT NULL
+		FROM target_urls
+		WHERE scope_target_id = $1
+		ORDER BY roi_score DESC, url`, mux.Vars(r)["scope_target_id"])
+	if err != nil {
+		log.Printf("[EDGE] [ERROR] Failed to load edge summary: %v", err)
+		http.Error(w, "Failed to load edge summary", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type providerSummary struct {
+		Provider string   `json:"provider"`
+		Category string   `json:"category"`
+		Count    int      `json:"count"`
+		Blocking int      `json:"blocking"`
+		URLs     []string `json:"urls"`
+	}
+	providers := make(map[string]*providerSummary)
+	unprotected := []map[string]string{}
+	unclassified := 0
+	for rows.Next() {
+		var id, targetURL, provider, category, waf string
+		var blocking, checked bool
+		if err := rows.Scan(&id, &targetURL, &provider, &category, &waf, &blocking, &checked); err != nil {
+			continue
+		}
+		if !checked {
+			unclassified++
+			continue
+		}
+		if provider == "" {
+			unprotected = append(unprotected, map[string]string{"id": id, "url": targetURL})
+			continue
+		}
+		summary, ok := providers[provider]
+		if !ok {
+			summary = &providerSummary{Provider: provider, Category: category}
+			providers[provider] = summary
+		}
+		summary.Count++
+		if blocking {
+			summary.Blocking++
+		}
+		summary.URLs = append(summary.URLs, targetURL)
+	}
+
+	providerList := []*providerSummary{}
+	for _, summary := range providers {
+		providerList = append(providerList, summary)
+	}
+	sort.Slice(providerList, func(i, j int) bool { return providerList[i].Count > providerList[j].Count })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers":    providerList,
+		"unprotected":  unprotected,
+		"unclassified": unclassified,
+	})
+}
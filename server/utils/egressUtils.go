@@ -0,0 +1,559 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Egress scopes for traffic the server sends itself. Container tools use their
+// registry name as the scope.
+const (
+	EgressScopeCTL                   = "crtsh"
+	EgressScopeShodan                = "shodan"
+	EgressScopeCensys                = "censys"
+	EgressScopeSecurityTrails        = "securitytrails"
+	EgressScopeHackerOne             = "hackerone"
+	EgressScopeASNLookup             = "asn_lookup"
+	EgressScopeHTTPProbe             = "http_probe"
+	EgressScopeIPPortScan            = "ip_port_scan"
+	EgressScopeEndpointInvestigation = "endpoint_investigation"
+	EgressScopeDownload              = "download"
+	EgressScopeCloudStorage          = "cloud_storage"
+
+	egressRotationRoundRobin = "round_robin"
+	egressRotationRandom     = "random"
+	egressRotationSticky     = "sticky"
+
+	egressCacheTTL = 30 * time.Second
+)
+
+var egressGoScopes = []string{
+	EgressScopeCTL, EgressScopeShodan, EgressScopeCensys, EgressScopeSecurityTrails, EgressScopeHackerOne,
+	EgressScopeASNLookup, EgressScopeHTTPProbe, EgressScopeIPPortScan, EgressScopeEndpointInvestigation, EgressScopeDownload,
+	EgressScopeCloudStorage,
+}
+
+type EgressProxy struct {
+	ID          string     `json:"id"`
+	PoolID      string     `json:"pool_id"`
+	URL         string     `json:"url"`
+	Enabled     bool       `json:"enabled"`
+	Healthy     bool       `json:"healthy"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	ExitIP      string     `json:"exit_ip,omitempty"`
+}
+
+type EgressProxyPool struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Strategy string        `json:"strategy"`
+	Enabled  bool          `json:"enabled"`
+	Proxies  []EgressProxy `json:"proxies"`
+}
+
+type EgressPolicy struct {
+	Scope    string `json:"scope"`
+	PoolID   string `json:"pool_id"`
+	PoolName string `json:"pool_name,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type egressPoolState struct {
+	strategy string
+	proxies  []*url.URL
+}
+
+var (
+	egressMutex    sync.RWMutex
+	egressPools    map[string]*egressPoolState
+	egressPolicies map[string]string
+	egressLoadedAt time.Time
+
+	egressCounters sync.Map
+)
+
+func validateEgressProxyURL(raw string) (*url.URL, error) {
+	proxy, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q; use http, https, socks5 or socks5h", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+	return proxy, nil
+}
+
+func loadEgressState() {
+	pools := make(map[string]*egressPoolState)
+	policies := make(map[string]string)
+
+	if dbPool != nil {
+		rows, err := dbPool.Query(context.Background(), `
+			SELECT p.id, p.strategy, x.url
+			FROM egress_proxy_pools p
+			JOIN egress_proxies x ON x.pool_id = p.id
+			WHERE p.enabled AND x.enabled AND x.healthy
+			ORDER BY x.created_at`)
+		if err != nil {
+			log.Printf("[EGRESS] [ERROR] Failed to load proxy pools: %v", err)
+		} else {
+			for rows.Next() {
+				var poolID, strategy, rawURL string
+				if err := rows.Scan(&poolID, &strategy, &rawURL); err != nil {
+					continue
+				}
+				proxy, err := validateEgressProxyURL(rawURL)
+				if err != nil {
+					continue
+				}
+				if pools[poolID] == nil {
+					pools[poolID] = &egressPoolState{strategy: strategy}
+				}
+				pools[poolID].proxies = append(pools[poolID].proxies, proxy)
+			}
+			rows.Close()
+		}
+
+		rows, err = dbPool.Query(context.Background(), `SELECT scope, pool_id::text FROM egress_policies WHERE enabled`)
+		if err != nil {
+			log.Printf("[EGRESS] [ERROR] Failed to load egress policies: %v", err)
+		} else {
+			for rows.Next() {
+				var scope, poolID string
+				if err := rows.Scan(&scope, &poolID); err == nil {
+					policies[scope] = poolID
+				}
+			}
+			rows.Close()
+		}
+	}
+
+	egressMutex.Lock()
+	egressPools = pools
+	egressPolicies = policies
+	egressLoadedAt = time.Now()
+	egressMutex.Unlock()
+}
+
+func invalidateEgressState() {
+	egressMutex.Lock()
+	egressLoadedAt = time.Time{}
+	egressMutex.Unlock()
+}
+
+// EgressProxyFor picks the proxy a scope should use for a request to host, or nil
+// when the scope has not opted in. There is no catch-all policy: each tool or
+// client scope is proxied only through its own.
+func EgressProxyFor(scope, host string) *url.URL {
+	egressMutex.RLock()
+	stale := time.Since(egressLoadedAt) > egressCacheTTL
+	egressMutex.RUnlock()
+	if stale {
+		loadEgressState()
+	}
+
+	egressMutex.RLock()
+	poolID, ok := egressPolicies[scope]
+	pool := egressPools[poolID]
+	egressMutex.RUnlock()
+	if !ok || pool == nil || len(pool.proxies) == 0 {
+		return nil
+	}
+
+	switch pool.strategy {
+	case egressRotationRandom:
+		return pool.proxies[rand.Intn(len(pool.proxies))]
+	case egressRotationSticky:
+		h := fnv.New32a()
+		h.Write([]byte(scope + "|" + host))
+		return pool.proxies[int(h.Sum32()%uint32(len(pool.proxies)))]
+	default:
+		counter, _ := egressCounters.LoadOrStore(poolID, new(uint64))
+		next := atomic.AddUint64(counter.(*uint64), 1)
+		return pool.proxies[int((next-1)%uint64(len(pool.proxies)))]
+	}
+}
+
+// egressClient routes a client's requests through the scope's proxy pool, one
+// pick per request. Transports without a policy keep their previous proxy setting.
+func egressClient(scope string, client *http.Client) *http.Client {
+	base, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		base, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		return client
+	}
+
+	transport := base.Clone()
+	fallback := base.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if proxy := EgressProxyFor(scope, req.URL.Hostname()); proxy != nil {
+			return proxy, nil
+		}
+		if fallback != nil {
+			return fallback(req)
+		}
+		return nil, nil
+	}
+	client.Transport = transport
+	return client
+}
+
+func egressEnv(proxy *url.URL) []string {
+	value := proxy.String()
+	env := []string{"HTTP_PROXY=" + value, "HTTPS_PROXY=" + value, "http_proxy=" + value, "https_proxy=" + value,
+		"NO_PROXY=localhost,127.0.0.1", "no_proxy=localhost,127.0.0.1"}
+	if strings.HasPrefix(proxy.Scheme, "socks") {
+		env = append(env, "ALL_PROXY="+value, "all_proxy="+value)
+	}
+	return env
+}
+
+// egressExecutor applies the tool's egress policy to every command: proxy
+// environment variables always, plus the tool's own proxy flag on Run.
+type egressExecutor struct {
+	Executor
+	tool string
+}
+
+func (e *egressExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	proxy := EgressProxyFor(e.tool, "")
+	if proxy == nil {
+		return e.Executor.Run(ctx, req)
+	}
+	req.Env = append(egressEnv(proxy), req.Env...)
+	if flag := toolRegistry[e.tool].ProxyFlag; flag != "" {
+		req.Args = append(append([]string{}, req.Args...), flag, proxy.String())
+	}
+	result, err := e.Executor.Run(ctx, req)
+	if result != nil {
+		// Commands are stored with scans, so never keep proxy credentials in them.
+		result.Command = strings.ReplaceAll(result.Command, proxy.String(), proxy.Redacted())
+	}
+	return result, err
+}
+
+func (e *egressExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	if proxy := EgressProxyFor(e.tool, ""); proxy != nil {
+		req.Env = append(egressEnv(proxy), req.Env...)
+	}
+	return e.Executor.Exec(ctx, req)
+}
+
+func redactProxyURL(raw string) string {
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return proxy.Redacted()
+}
+
+func GetEgressPools(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `SELECT id, name, strategy, enabled FROM egress_proxy_pools ORDER BY name`)
+	if err != nil {
+		http.Error(w, "Failed to fetch proxy pools", http.StatusInternalServerError)
+		return
+	}
+	pools := []EgressProxyPool{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var pool EgressProxyPool
+		if err := rows.Scan(&pool.ID, &pool.Name, &pool.Strategy, &pool.Enabled); err != nil {
+			continue
+		}
+		pool.Proxies = []EgressProxy{}
+		index[pool.ID] = len(pools)
+		pools = append(pools, pool)
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT id, pool_id, url, enabled, healthy, last_checked, COALESCE(last_error, ''), COALESCE(exit_ip, '')
+		FROM egress_proxies ORDER BY created_at`)
+	if err != nil {
+		http.Error(w, "Failed to fetch proxies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var proxy EgressProxy
+		if err := rows.Scan(&proxy.ID, &proxy.PoolID, &proxy.URL, &proxy.Enabled, &proxy.Healthy, &proxy.LastChecked, &proxy.LastError, &proxy.ExitIP); err != nil {
+			continue
+		}
+		proxy.URL = redactProxyURL(proxy.URL)
+		if i, ok := index[proxy.PoolID]; ok {
+			pools[i].Proxies = append(pools[i].Proxies, proxy)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pools)
+}
+
+func decodeEgressPool(w http.ResponseWriter, r *http.Request) (*EgressProxyPool, bool) {
+	var pool EgressProxyPool
+	if err := json.NewDecoder(r.Body).Decode(&pool); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	pool.Name = strings.TrimSpace(pool.Name)
+	if pool.Name == "" {
+		http.Error(w, "Pool name is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if pool.Strategy == "" {
+		pool.Strategy = egressRotationRoundRobin
+	}
+	switch pool.Strategy {
+	case egressRotationRoundRobin, egressRotationRandom, egressRotationSticky:
+	default:
+		http.Error(w, "Strategy must be round_robin, random or sticky", http.StatusBadRequest)
+		return nil, false
+	}
+	return &pool, true
+}
+
+func CreateEgressPool(w http.ResponseWriter, r *http.Request) {
+	pool, ok := decodeEgressPool(w, r)
+	if !ok {
+		return
+	}
+	err := dbPool.QueryRow(context.Background(), `
+		INSERT INTO egress_proxy_pools (name, strategy, enabled) VALUES ($1, $2, TRUE) RETURNING id`,
+		pool.Name, pool.Strategy).Scan(&pool.ID)
+	if err != nil {
+		log.Printf("[EGRESS] [ERROR] Failed to create pool %s: %v", pool.Name, err)
+		http.Error(w, "Failed to create proxy pool", http.StatusInternalServerError)
+		return
+	}
+	pool.Enabled = true
+	pool.Proxies = []EgressProxy{}
+	invalidateEgressState()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pool)
+}
+
+func UpdateEgressPool(w http.ResponseWriter, r *http.Request) {
+	pool, ok := decodeEgressPool(w, r)
+	if !ok {
+		return
+	}
+	pool.ID = mux.Vars(r)["id"]
+	tag, err := dbPool.Exec(context.Background(), `
+		UPDATE egress_proxy_pools SET name = $1, strategy = $2, enabled = $3, updated_at = NOW() WHERE id = $4`,
+		pool.Name, pool.Strategy, pool.Enabled, pool.ID)
+	if err != nil {
+		http.Error(w, "Failed to update proxy pool", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Proxy pool not found", http.StatusNotFound)
+		return
+	}
+	invalidateEgressState()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pool)
+}
+
+func DeleteEgressPool(w http.ResponseWriter, r *http.Request) {
+	if _, err := dbPool.Exec(context.Background(), `DELETE FROM egress_proxy_pools WHERE id = $1`, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, "Failed to delete proxy pool", http.StatusInternalServerError)
+		return
+	}
+	invalidateEgressState()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func AddEgressProxy(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	proxyURL, err := validateEgressProxyURL(payload.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	proxy := EgressProxy{PoolID: mux.Vars(r)["id"], URL: proxyURL.String(), Enabled: true, Healthy: true}
+	err = dbPool.QueryRow(context.Background(), `
+		INSERT INTO egress_proxies (pool_id, url) VALUES ($1, $2) RETURNING id`,
+		proxy.PoolID, proxy.URL).Scan(&proxy.ID)
+	if err != nil {
+		log.Printf("[EGRESS] [ERROR] Failed to add proxy to pool %s: %v", proxy.PoolID, err)
+		http.Error(w, "Failed to add proxy", http.StatusInternalServerError)
+		return
+	}
+	invalidateEgressState()
+
+	proxy.URL = redactProxyURL(proxy.URL)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proxy)
+}
+
+func DeleteEgressProxy(w http.ResponseWriter, r *http.Request) {
+	if _, err := dbPool.Exec(context.Background(), `DELETE FROM egress_proxies WHERE id = $1`, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, "Failed to delete proxy", http.StatusInternalServerError)
+		return
+	}
+	invalidateEgressState()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestEgressProxy sends one request through a proxy and records whether it worked and
+// which address the target saw. Unhealthy proxies are skipped until they pass again.
+func TestEgressProxy(w http.ResponseWriter, r *http.Request) {
+	proxyID := mux.Vars(r)["id"]
+	var rawURL string
+	if err := dbPool.QueryRow(context.Background(), `SELECT url FROM egress_proxies WHERE id = $1`, proxyID).Scan(&rawURL); err != nil {
+		http.Error(w, "Proxy not found", http.StatusNotFound)
+		return
+	}
+
+	proxy := EgressProxy{ID: proxyID, URL: redactProxyURL(rawURL), Healthy: true}
+	exitIP, err := checkEgressProxy(rawURL)
+	if err != nil {
+		proxy.Healthy = false
+		proxy.LastError = err.Error()
+	}
+	proxy.ExitIP = exitIP
+	now := time.Now()
+	proxy.LastChecked = &now
+
+	_, dbErr := dbPool.Exec(context.Background(), `
+		UPDATE egress_proxies SET healthy = $1, last_checked = $2, last_error = NULLIF($3, ''), exit_ip = NULLIF($4, '')
+		WHERE id = $5`,
+		proxy.Healthy, now, proxy.LastError, proxy.ExitIP, proxyID)
+	if dbErr != nil {
+		log.Printf("[EGRESS] [ERROR] Failed to record check for proxy %s: %v", proxyID, dbErr)
+	}
+	invalidateEgressState()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proxy)
+}
+
+func checkEgressProxy(rawURL string) (string, error) {
+	proxyURL, err := validateEgressProxyURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	checkURL := os.Getenv("EGRESS_CHECK_URL")
+	if checkURL == "" {
+		checkURL = "https://api.ipify.org"
+	}
+
+	client := &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+	}
+	resp, err := client.Get(checkURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("check URL returned status %d", resp.StatusCode)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func GetEgressPolicies(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT e.scope, e.pool_id::text, COALESCE(p.name, ''), e.enabled
+		FROM egress_policies e
+		LEFT JOIN egress_proxy_pools p ON p.id = e.pool_id
+		ORDER BY e.scope`)
+	if err != nil {
+		http.Error(w, "Failed to fetch egress policies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	policies := []EgressPolicy{}
+	for rows.Next() {
+		var policy EgressPolicy
+		if err := rows.Scan(&policy.Scope, &policy.PoolID, &policy.PoolName, &policy.Enabled); err == nil {
+			policies = append(policies, policy)
+		}
+	}
+
+	scopes := append(append([]string{}, egressGoScopes...), sortedToolNames()...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policies": policies,
+		"scopes":   scopes,
+	})
+}
+
+func SaveEgressPolicy(w http.ResponseWriter, r *http.Request) {
+	scope := mux.Vars(r)["scope"]
+	_, known := toolRegistry[scope]
+	for _, s := range egressGoScopes {
+		if s == scope {
+			known = true
+		}
+	}
+	if !known {
+		http.Error(w, "Unknown egress scope", http.StatusNotFound)
+		return
+	}
+
+	var policy EgressPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil || policy.PoolID == "" {
+		http.Error(w, "pool_id is required", http.StatusBadRequest)
+		return
+	}
+	policy.Scope = scope
+
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO egress_policies (scope, pool_id, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (scope) DO UPDATE SET pool_id = $2, enabled = $3, updated_at = NOW()`,
+		policy.Scope, policy.PoolID, policy.Enabled)
+	if err != nil {
+		log.Printf("[EGRESS] [ERROR] Failed to save policy for %s: %v", scope, err)
+		http.Error(w, "Failed to save egress policy", http.StatusInternalServerError)
+		return
+	}
+	invalidateEgressState()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func DeleteEgressPolicy(w http.ResponseWriter, r *http.Request) {
+	if _, err := dbPool.Exec(context.Background(), `DELETE FROM egress_policies WHERE scope = $1`, mux.Vars(r)["scope"]); err != nil {
+		http.Error(w, "Failed to delete egress policy", http.StatusInternalServerError)
+		return
+	}
+	invalidateEgressState()
+	w.WriteHeader(http.StatusNoContent)
+}
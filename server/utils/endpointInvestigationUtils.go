@@ -115,6 +115,10 @@ func RunEndpointInvestigation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "scope_target_id is required", http.StatusBadRequest)
 		return
 	}
+	requestProfileID := r.URL.Query().Get("request_profile_id")
+	if !CheckScanRequestProfile(w, scopeTargetID, requestProfileID) {
+		return
+	}
 
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO endpoint_investigation_scans (scan_id, scope_target_id, status) VALUES ($1, $2, $3)`
@@ -125,13 +129,13 @@ func RunEndpointInvestigation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteEndpointInvestigation(scanID, scopeTargetID)
+	go ExecuteEndpointInvestigation(scanID, scopeTargetID, requestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteEndpointInvestigation(scanID, scopeTargetID string) {
+func ExecuteEndpointInvestigation(scanID, scopeTargetID, requestProfileID string) {
 	log.Printf("[INFO] Starting endpoint investigation for scope target %s (scan ID: %s)", scopeTargetID, scanID)
 	startTime := time.Now()
 
@@ -170,11 +174,12 @@ func ExecuteEndpointInvestigation(scanID, scopeTargetID string) {
 
 	UpdateEndpointInvestigationStatus(scanID, "running", len(endpoints), 0, "", "", "")
 
+	requestHeaders := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
 	var results []EndpointInvestigationResult
 	for i, ep := range endpoints {
 		log.Printf("[INFO] Investigating endpoint %d/%d: %s", i+1, len(endpoints), ep.URL)
 		
-		result := investigateEndpoint(ep.ID, ep.URL, ep.Method)
+		result := investigateEndpoint(ep.ID, ep.URL, ep.Method, requestHeaders)
 		results = append(results, result)
 		
 		UpdateEndpointInvestigationStatus(scanID, "running", len(endpoints), i+1, "", "", "")
@@ -191,7 +196,7 @@ func ExecuteEndpointInvestigation(scanID, scopeTargetID string) {
 	log.Printf("[INFO] Endpoint investigation completed for scope target %s", scopeTargetID)
 }
 
-func investigateEndpoint(endpointID, urlStr, method string) EndpointInvestigationResult {
+func investigateEndpoint(endpointID, urlStr, method string, requestHeaders []string) EndpointInvestigationResult {
 	result := EndpointInvestigationResult{
 		EndpointID:      endpointID,
 		URL:             urlStr,
@@ -207,7 +212,7 @@ func investigateEndpoint(endpointID, urlStr, method string) EndpointInvestigatio
 		Cookies:         []CookieInfo{},
 	}
 
-	client := &http.Client{
+	client := egressClient(EgressScopeEndpointInvestigation, &http.Client{
 		Timeout: 30 * time.Second,
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -228,7 +233,7 @@ func investigateEndpoint(endpointID, urlStr, method string) EndpointInvestigatio
 			}
 			return nil
 		},
-	}
+	})
 
 	reqStart := time.Now()
 	req, err := http.NewRequest(method, urlStr, nil)
@@ -238,6 +243,7 @@ func investigateEndpoint(endpointID, urlStr, method string) EndpointInvestigatio
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	applyRequestHeaders(req, requestHeaders)
 	
 	resp, err := client.Do(req)
 	if err != nil {
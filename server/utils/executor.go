@@ -0,0 +1,787 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	ToolModeLocal = "local"
+	ToolModeFake  = "fake"
+)
+
+var ErrCopyNotSupported = errors.New("file copy is not supported by this executor")
+
+// ExecRequest describes a single command to run in a tool's environment.
+// When Stdout or Stderr are set, output is streamed to them as well as captured.
+// Env entries (KEY=value) are set for the tool process, not the docker CLI.
+type ExecRequest struct {
+	Args   []string
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type ExecResult struct {
+	Command  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Executor runs a tool regardless of where it lives: a running container, a
+// throwaway container, a binary on PATH, or a recording fake.
+type Executor interface {
+	Mode() string
+	Target() string
+	// Run invokes the tool itself with the given arguments.
+	Run(ctx context.Context, req ExecRequest) (*ExecResult, error)
+	// Exec runs an arbitrary command (cat, rm, sh -c ...) next to the tool.
+	Exec(ctx context.Context, req ExecRequest) (*ExecResult, error)
+	CopyTo(ctx context.Context, localPath, toolPath string) error
+	CopyFrom(ctx context.Context, toolPath, localPath string) error
+	// Path maps a path used by the tool to where it actually lives for this executor.
+	Path(toolPath string) string
+	Check(ctx context.Context) error
+}
+
+type ToolExecutorConfig struct {
+	Tool       string   `json:"tool"`
+	Mode       string   `json:"mode"`
+	Container  string   `json:"container,omitempty"`
+	Image      string   `json:"image,omitempty"`
+	Binary     []string `json:"binary,omitempty"`
+	DockerHost string   `json:"docker_host,omitempty"`
+	WorkDir    string   `json:"work_dir,omitempty"`
+	RunArgs    []string `json:"run_args,omitempty"`
+}
+
+func runCommand(ctx context.Context, argv []string, env []string, dir string, req ExecRequest) (*ExecResult, error) {
+	result := &ExecResult{Command: strings.Join(redactCommandArgs(argv), " ")}
+	if len(argv) == 0 {
+		return result, fmt.Errorf("empty command")
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Dir = dir
+	cmd.Stdin = req.Stdin
+	cmd.WaitDelay = 30 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if req.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, req.Stdout)
+	}
+	if req.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, req.Stderr)
+	}
+
+	err := cmd.Run()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return result, err
+}
+
+// dockerEnvFlags turns request environment into -e flags for docker exec/run.
+func dockerEnvFlags(env []string) []string {
+	flags := make([]string, 0, len(env)*2)
+	for _, kv := range env {
+		flags = append(flags, "-e", kv)
+	}
+	return flags
+}
+
+func dockerEnv(dockerHost string) []string {
+	if dockerHost == "" {
+		return nil
+	}
+	return []string{"DOCKER_HOST=" + dockerHost}
+}
+
+type DockerExecExecutor struct {
+	Container  string
+	Binary     []string
+	DockerHost string
+}
+
+func (e *DockerExecExecutor) Mode() string   { return ToolModeDockerExec }
+func (e *DockerExecExecutor) Target() string { return e.Container }
+
+func (e *DockerExecExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	req.Args = append(append([]string{}, e.Binary...), req.Args...)
+	return e.Exec(ctx, req)
+}
+
+func (e *DockerExecExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	argv := []string{"docker", "exec"}
+	if req.Stdin != nil {
+		argv = append(argv, "-i")
+	}
+	argv = append(argv, dockerEnvFlags(req.Env)...)
+	argv = append(argv, e.Container)
+	argv = append(argv, req.Args...)
+	return runCommand(ctx, argv, dockerEnv(e.DockerHost), "", req)
+}
+
+func (e *DockerExecExecutor) CopyTo(ctx context.Context, localPath, toolPath string) error {
+	result, err := runCommand(ctx, []string{"docker", "cp", localPath, e.Container + ":" + toolPath}, dockerEnv(e.DockerHost), "", ExecRequest{})
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+func (e *DockerExecExecutor) CopyFrom(ctx context.Context, toolPath, localPath string) error {
+	result, err := runCommand(ctx, []string{"docker", "cp", e.Container + ":" + toolPath, localPath}, dockerEnv(e.DockerHost), "", ExecRequest{})
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+func (e *DockerExecExecutor) Path(toolPath string) string { return toolPath }
+
+func (e *DockerExecExecutor) Check(ctx context.Context) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker CLI not found on PATH; the API container needs the docker client and /var/run/docker.sock mounted")
+	}
+	result, err := runCommand(ctx, []string{"docker", "inspect", "--format", "{{.State.Running}}", e.Container}, dockerEnv(e.DockerHost), "", ExecRequest{})
+	if err != nil {
+		return fmt.Errorf("container %s does not exist", e.Container)
+	}
+	if strings.TrimSpace(result.Stdout) != "true" {
+		return fmt.Errorf("container %s is not running", e.Container)
+	}
+	return nil
+}
+
+type DockerRunExecutor struct {
+	Image      string
+	RunArgs    []string
+	DockerHost string
+}
+
+func (e *DockerRunExecutor) Mode() string   { return ToolModeDockerRun }
+func (e *DockerRunExecutor) Target() string { return e.Image }
+
+func (e *DockerRunExecutor) argv(req ExecRequest, entrypoint string) []string {
+	argv := []string{"docker", "run", "--rm"}
+	if req.Stdin != nil {
+		argv = append(argv, "-i")
+	}
+	argv = append(argv, e.RunArgs...)
+	argv = append(argv, dockerEnvFlags(req.Env)...)
+	if entrypoint != "" {
+		argv = append(argv, "--entrypoint", entrypoint)
+	}
+	return append(argv, e.Image)
+}
+
+func (e *DockerRunExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	argv := append(e.argv(req, ""), req.Args...)
+	return runCommand(ctx, argv, dockerEnv(e.DockerHost), "", req)
+}
+
+func (e *DockerRunExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	if len(req.Args) == 0 {
+		return &ExecResult{}, fmt.Errorf("empty command")
+	}
+	argv := append(e.argv(req, req.Args[0]), req.Args[1:]...)
+	return runCommand(ctx, argv, dockerEnv(e.DockerHost), "", req)
+}
+
+func (e *DockerRunExecutor) CopyTo(ctx context.Context, localPath, toolPath string) error {
+	return ErrCopyNotSupported
+}
+
+func (e *DockerRunExecutor) CopyFrom(ctx context.Context, toolPath, localPath string) error {
+	return ErrCopyNotSupported
+}
+
+func (e *DockerRunExecutor) Path(toolPath string) string { return toolPath }
+
+func (e *DockerRunExecutor) Check(ctx context.Context) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker CLI not found on PATH; the API container needs the docker client and /var/run/docker.sock mounted")
+	}
+	if _, err := runCommand(ctx, []string{"docker", "image", "inspect", "--format", "{{.Id}}", e.Image}, dockerEnv(e.DockerHost), "", ExecRequest{}); err != nil {
+		return fmt.Errorf("image %s is not available locally; run `docker pull %s`", e.Image, e.Image)
+	}
+	return nil
+}
+
+// LocalExecutor runs tools installed on the host. Tool paths are rooted under
+// WorkDir so that absolute container paths like /output.json stay writable.
+type LocalExecutor struct {
+	Binary  []string
+	WorkDir string
+}
+
+func (e *LocalExecutor) Mode() string { return ToolModeLocal }
+
+func (e *LocalExecutor) Target() string {
+	if len(e.Binary) == 0 {
+		return ""
+	}
+	return e.Binary[0]
+}
+
+func (e *LocalExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	req.Args = append(append([]string{}, e.Binary...), req.Args...)
+	return e.Exec(ctx, req)
+}
+
+func (e *LocalExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	if err := os.MkdirAll(e.WorkDir, 0755); err != nil {
+		return &ExecResult{}, fmt.Errorf("failed to create work dir %s: %v", e.WorkDir, err)
+	}
+	return runCommand(ctx, req.Args, req.Env, e.WorkDir, req)
+}
+
+func (e *LocalExecutor) CopyTo(ctx context.Context, localPath, toolPath string) error {
+	return copyLocalFile(localPath, e.Path(toolPath))
+}
+
+func (e *LocalExecutor) CopyFrom(ctx context.Context, toolPath, localPath string) error {
+	return copyLocalFile(e.Path(toolPath), localPath)
+}
+
+func (e *LocalExecutor) Path(toolPath string) string {
+	if !filepath.IsAbs(toolPath) {
+		return toolPath
+	}
+	return filepath.Join(e.WorkDir, toolPath)
+}
+
+func (e *LocalExecutor) Check(ctx context.Context) error {
+	if len(e.Binary) == 0 {
+		return fmt.Errorf("no binary configured")
+	}
+	if _, err := exec.LookPath(e.Binary[0]); err != nil {
+		return fmt.Errorf("%s not found on PATH", e.Binary[0])
+	}
+	return nil
+}
+
+func copyLocalFile(src, dst string) error {
+	if src == dst {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// RecordedCall is one invocation captured by a FakeExecutor.
+type RecordedCall struct {
+	Kind  string
+	Args  []string
+	Env   []string
+	Stdin string
+}
+
+// FakeExecutor records every call and answers from Handler, for tests and dry runs.
+// Files copied in with CopyTo are kept in memory and can be read back with CopyFrom.
+type FakeExecutor struct {
+	Handler func(call RecordedCall) (*ExecResult, error)
+
+	mu    sync.Mutex
+	calls []RecordedCall
+	files map[string][]byte
+}
+
+func (e *FakeExecutor) Mode() string   { return ToolModeFake }
+func (e *FakeExecutor) Target() string { return "fake" }
+
+func (e *FakeExecutor) record(kind string, req ExecRequest) (*ExecResult, error) {
+	call := RecordedCall{Kind: kind, Args: append([]string{}, req.Args...), Env: append([]string{}, req.Env...)}
+	if req.Stdin != nil {
+		data, _ := io.ReadAll(req.Stdin)
+		call.Stdin = string(data)
+	}
+
+	e.mu.Lock()
+	e.calls = append(e.calls, call)
+	e.mu.Unlock()
+
+	result := &ExecResult{Command: strings.Join(redactCommandArgs(call.Args), " ")}
+	var err error
+	if e.Handler != nil {
+		var handled *ExecResult
+		handled, err = e.Handler(call)
+		if handled != nil {
+			handled.Command = result.Command
+			result = handled
+		}
+	}
+	if req.Stdout != nil {
+		io.WriteString(req.Stdout, result.Stdout)
+	}
+	if req.Stderr != nil {
+		io.WriteString(req.Stderr, result.Stderr)
+	}
+	return result, err
+}
+
+func (e *FakeExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	return e.record("run", req)
+}
+
+func (e *FakeExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	return e.record("exec", req)
+}
+
+func (e *FakeExecutor) CopyTo(ctx context.Context, localPath, toolPath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.files == nil {
+		e.files = make(map[string][]byte)
+	}
+	e.files[toolPath] = data
+	e.calls = append(e.calls, RecordedCall{Kind: "copy_to", Args: []string{localPath, toolPath}})
+	return nil
+}
+
+func (e *FakeExecutor) CopyFrom(ctx context.Context, toolPath, localPath string) error {
+	e.mu.Lock()
+	data, ok := e.files[toolPath]
+	e.calls = append(e.calls, RecordedCall{Kind: "copy_from", Args: []string{toolPath, localPath}})
+	e.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s does not exist", toolPath)
+	}
+	return os.WriteFile(localPath, data, 0644)
+}
+
+// SetFile seeds a file the tool is expected to produce.
+func (e *FakeExecutor) SetFile(toolPath string, data []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.files == nil {
+		e.files = make(map[string][]byte)
+	}
+	e.files[toolPath] = data
+}
+
+func (e *FakeExecutor) Calls() []RecordedCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]RecordedCall{}, e.calls...)
+}
+
+func (e *FakeExecutor) Path(toolPath string) string     { return toolPath }
+func (e *FakeExecutor) Check(ctx context.Context) error { return nil }
+
+var (
+	executorMutex     sync.RWMutex
+	executorCache     = make(map[string]Executor)
+	executorOverrides = make(map[string]Executor)
+)
+
+// defaultExecutorConfig derives a config from the tool registry. TOOL_EXECUTOR_MODE=local
+// switches every tool to binaries on PATH, e.g. for a bare Linux host or a Kubernetes pod.
+func defaultExecutorConfig(def ToolDefinition) ToolExecutorConfig {
+	config := ToolExecutorConfig{
+		Tool:       def.Name,
+		Mode:       def.Mode,
+		Container:  def.Container,
+		Image:      def.Image,
+		Binary:     def.Binary,
+		DockerHost: os.Getenv("TOOL_DOCKER_HOST"),
+		WorkDir:    filepath.Join(os.TempDir(), "ars0n-tools", def.Name),
+	}
+	if mode := os.Getenv("TOOL_EXECUTOR_MODE"); mode != "" {
+		config.Mode = mode
+	}
+	if config.Mode == ToolModeLocal && len(def.LocalBinary) > 0 {
+		config.Binary = def.LocalBinary
+	}
+	return config
+}
+
+func loadExecutorConfig(tool string) ToolExecutorConfig {
+	def := toolRegistry[tool]
+	config := defaultExecutorConfig(def)
+	if dbPool == nil {
+		return config
+	}
+
+	var stored ToolExecutorConfig
+	var binaryJSON, runArgsJSON []byte
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT mode, COALESCE(container, ''), COALESCE(image, ''), COALESCE(command, '[]'),
+		       COALESCE(docker_host, ''), COALESCE(work_dir, ''), COALESCE(run_args, '[]')
+		FROM tool_executor_configs WHERE tool_name = $1`, tool).Scan(
+		&stored.Mode, &stored.Container, &stored.Image, &binaryJSON, &stored.DockerHost, &stored.WorkDir, &runArgsJSON)
+	if err != nil {
+		return config
+	}
+	json.Unmarshal(binaryJSON, &stored.Binary)
+	json.Unmarshal(runArgsJSON, &stored.RunArgs)
+
+	if stored.Mode != "" {
+		config.Mode = stored.Mode
+		config.Binary = def.Binary
+		if stored.Mode == ToolModeLocal && len(def.LocalBinary) > 0 {
+			config.Binary = def.LocalBinary
+		}
+	}
+	if stored.Container != "" {
+		config.Container = stored.Container
+	}
+	if stored.Image != "" {
+		config.Image = stored.Image
+	}
+	if len(stored.Binary) > 0 {
+		config.Binary = stored.Binary
+	}
+	if stored.DockerHost != "" {
+		config.DockerHost = stored.DockerHost
+	}
+	if stored.WorkDir != "" {
+		config.WorkDir = stored.WorkDir
+	}
+	if len(stored.RunArgs) > 0 {
+		config.RunArgs = stored.RunArgs
+	}
+	// Rows saved before the API checked configs, or under settings since
+	// turned off, must not run anything the API would refuse now
+	if err := validateExecutorConfig(def, config); err != nil {
+		log.Printf("[EXECUTOR] [WARN] Ignoring stored config for %s: %v", tool, err)
+		return defaultExecutorConfig(def)
+	}
+	return config
+}
+
+func NewExecutor(config ToolExecutorConfig) (Executor, error) {
+	switch config.Mode {
+	case ToolModeDockerExec:
+		if config.Container == "" {
+			return nil, fmt.Errorf("%s: docker_exec mode requires a container", config.Tool)
+		}
+		return &DockerExecExecutor{Container: config.Container, Binary: config.Binary, DockerHost: config.DockerHost}, nil
+	case ToolModeDockerRun:
+		if config.Image == "" {
+			return nil, fmt.Errorf("%s: docker_run mode requires an image", config.Tool)
+		}
+		return &DockerRunExecutor{Image: config.Image, RunArgs: config.RunArgs, DockerHost: config.DockerHost}, nil
+	case ToolModeLocal:
+		return &LocalExecutor{Binary: config.Binary, WorkDir: config.WorkDir}, nil
+	case ToolModeFake:
+		return &FakeExecutor{}, nil
+	case ToolModeWorker:
+		return NewWorkerExecutor(config.Tool), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown executor mode %q", config.Tool, config.Mode)
+	}
+}
+
+// ToolExecutor returns the configured executor for a tool. Misconfigured tools fall
+// back to the registry default so a bad row never takes a scan type down entirely.
+func ToolExecutor(tool string) Executor {
+	executorMutex.RLock()
+	if ex, ok := executorOverrides[tool]; ok {
+		executorMutex.RUnlock()
+		return ex
+	}
+	if ex, ok := executorCache[tool]; ok {
+		executorMutex.RUnlock()
+		return perCallExecutor(tool, ex)
+	}
+	executorMutex.RUnlock()
+
+	ex, err := NewExecutor(loadExecutorConfig(tool))
+	if err != nil {
+		log.Printf("[EXECUTOR] [ERROR] %v; falling back to default", err)
+		ex, err = NewExecutor(defaultExecutorConfig(toolRegistry[tool]))
+		if err != nil {
+			ex = &LocalExecutor{Binary: []string{tool}, WorkDir: filepath.Join(os.TempDir(), "ars0n-tools", tool)}
+		}
+	}
+
+	ex = &egressExecutor{Executor: ex, tool: tool}
+
+	executorMutex.Lock()
+	executorCache[tool] = ex
+	executorMutex.Unlock()
+	return perCallExecutor(tool, ex)
+}
+
+// perCallExecutor gives worker-mode tools a fresh WorkerExecutor per caller, as
+// its staged and returned files are scoped to the scan holding the handle.
+func perCallExecutor(tool string, ex Executor) Executor {
+	if egress, ok := ex.(*egressExecutor); ok {
+		if _, ok := egress.Executor.(*WorkerExecutor); ok {
+			return &egressExecutor{Executor: NewWorkerExecutor(tool), tool: tool}
+		}
+	}
+	return ex
+}
+
+// SetToolExecutor pins an executor for a tool, e.g. a FakeExecutor in tests.
+// Passing nil removes the override.
+func SetToolExecutor(tool string, ex Executor) {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	if ex == nil {
+		delete(executorOverrides, tool)
+		return
+	}
+	executorOverrides[tool] = ex
+}
+
+func resetExecutorCache(tool string) {
+	executorMutex.Lock()
+	delete(executorCache, tool)
+	executorMutex.Unlock()
+}
+
+// ToolExecOutput runs a raw command in the tool's environment and returns its stdout.
+func ToolExecOutput(ctx context.Context, ex Executor, args ...string) ([]byte, error) {
+	result, err := ex.Exec(ctx, ExecRequest{Args: args})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result.Stdout), nil
+}
+
+// ReadToolFile returns the contents of a file the tool wrote, copying it out when
+// possible and falling back to cat for executors that cannot copy.
+func ReadToolFile(ctx context.Context, ex Executor, toolPath string) ([]byte, error) {
+	tempFile, err := os.CreateTemp("", "tool_output_*")
+	if err != nil {
+		return nil, err
+	}
+	tempFile.Close()
+	defer os.Remove(tempFile.Name())
+
+	if err := ex.CopyFrom(ctx, toolPath, tempFile.Name()); err == nil {
+		return os.ReadFile(tempFile.Name())
+	}
+
+	result, err := ex.Exec(ctx, ExecRequest{Args: []string{"cat", ex.Path(toolPath)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", toolPath, err)
+	}
+	return []byte(result.Stdout), nil
+}
+
+// WriteToolFile places content at toolPath in the tool's environment.
+func WriteToolFile(ctx context.Context, ex Executor, toolPath string, content []byte) error {
+	tempFile, err := os.CreateTemp("", "tool_input_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+	tempFile.Close()
+
+	if err := ex.CopyTo(ctx, tempFile.Name(), toolPath); err == nil {
+		return nil
+	}
+
+	_, err = ex.Exec(ctx, ExecRequest{
+		Args:  []string{"sh", "-c", "cat > " + shellQuote(ex.Path(toolPath))},
+		Stdin: bytes.NewReader(content),
+	})
+	return err
+}
+
+func GetToolExecutorConfigs(w http.ResponseWriter, r *http.Request) {
+	configs := make([]ToolExecutorConfig, 0, len(toolRegistry))
+	for _, name := range sortedToolNames() {
+		configs = append(configs, loadExecutorConfig(name))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(configs)
+}
+
+// customExecutorsEnabled lets the executor API set arbitrary binaries, docker
+// run arguments, docker hosts and work dirs. Off by default, as any of them
+// lets a caller run commands on the host.
+func customExecutorsEnabled() bool {
+	return os.Getenv("TOOL_EXECUTOR_CUSTOM_COMMANDS") == "true"
+}
+
+// allowedExecutorBinaries is the operator's TOOL_EXECUTOR_ALLOWED_BINARIES, a
+// comma-separated list of binaries the API may point a tool at.
+func allowedExecutorBinaries() map[string]bool {
+	allowed := make(map[string]bool)
+	for _, binary := range strings.Split(os.Getenv("TOOL_EXECUTOR_ALLOWED_BINARIES"), ",") {
+		if binary = strings.TrimSpace(binary); binary != "" {
+			allowed[binary] = true
+		}
+	}
+	return allowed
+}
+
+func sameArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateExecutorConfig checks a config sent to the API. Unless custom
+// executors are enabled, only the registry's binary for the mode (or one on
+// the operator's allowlist, with no extra arguments), the default docker host,
+// no extra docker run arguments and a work dir under the default root are
+// accepted.
+func validateExecutorConfig(def ToolDefinition, config ToolExecutorConfig) error {
+	if _, err := NewExecutor(config); err != nil {
+		return err
+	}
+	if config.WorkDir != "" && !filepath.IsAbs(config.WorkDir) {
+		return fmt.Errorf("work_dir must be an absolute path")
+	}
+	if customExecutorsEnabled() {
+		return nil
+	}
+
+	if config.Mode == ToolModeFake {
+		return fmt.Errorf("fake mode is only available to tests")
+	}
+	defaults := defaultExecutorConfig(def)
+	registryBinary := def.Binary
+	if config.Mode == ToolModeLocal && len(def.LocalBinary) > 0 {
+		registryBinary = def.LocalBinary
+	}
+	if !sameArgs(config.Binary, registryBinary) && !(len(config.Binary) == 1 && allowedExecutorBinaries()[config.Binary[0]]) {
+		return fmt.Errorf("binary must be the tool's default or listed in TOOL_EXECUTOR_ALLOWED_BINARIES")
+	}
+	if len(config.RunArgs) > 0 {
+		return fmt.Errorf("run_args require TOOL_EXECUTOR_CUSTOM_COMMANDS=true")
+	}
+	if config.DockerHost != defaults.DockerHost {
+		return fmt.Errorf("docker_host requires TOOL_EXECUTOR_CUSTOM_COMMANDS=true")
+	}
+	root := filepath.Dir(defaults.WorkDir)
+	if rel, err := filepath.Rel(root, filepath.Clean(config.WorkDir)); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("work_dir must be inside %s", root)
+	}
+	return nil
+}
+
+func SaveToolExecutorConfig(w http.ResponseWriter, r *http.Request) {
+	tool := mux.Vars(r)["tool"]
+	def, ok := toolRegistry[tool]
+	if !ok {
+		http.Error(w, "Unknown tool", http.StatusNotFound)
+		return
+	}
+
+	var config ToolExecutorConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Start from the current config, or from the defaults when switching
+	// modes, so a binary meant for one mode never carries over to another
+	merged := loadExecutorConfig(tool)
+	if config.Mode != "" && config.Mode != merged.Mode {
+		merged = defaultExecutorConfig(def)
+		merged.Mode = config.Mode
+		merged.Binary = def.Binary
+		if config.Mode == ToolModeLocal && len(def.LocalBinary) > 0 {
+			merged.Binary = def.LocalBinary
+		}
+	}
+	merged.Tool = tool
+	if config.Container != "" {
+		merged.Container = config.Container
+	}
+	if config.Image != "" {
+		merged.Image = config.Image
+	}
+	if len(config.Binary) > 0 {
+		merged.Binary = config.Binary
+	}
+	if config.DockerHost != "" {
+		merged.DockerHost = config.DockerHost
+	}
+	if config.WorkDir != "" {
+		merged.WorkDir = config.WorkDir
+	}
+	if len(config.RunArgs) > 0 {
+		merged.RunArgs = config.RunArgs
+	}
+	if err := validateExecutorConfig(def, merged); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	binaryJSON, _ := json.Marshal(merged.Binary)
+	runArgsJSON, _ := json.Marshal(merged.RunArgs)
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO tool_executor_configs (tool_name, mode, container, image, command, docker_host, work_dir, run_args)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tool_name)
+		DO UPDATE SET mode = $2, container = $3, image = $4, command = $5, docker_host = $6,
+		              work_dir = $7, run_args = $8, updated_at = NOW()`,
+		tool, merged.Mode, merged.Container, merged.Image, binaryJSON, merged.DockerHost, merged.WorkDir, runArgsJSON)
+	if err != nil {
+		log.Printf("[EXECUTOR] [ERROR] Failed to save config for %s: %v", tool, err)
+		http.Error(w, "Failed to save executor config", http.StatusInternalServerError)
+		return
+	}
+
+	resetExecutorCache(tool)
+	status := CheckTool(tool, true)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"config": loadExecutorConfig(tool),
+		"status": status,
+	})
+}
+
+func DeleteToolExecutorConfig(w http.ResponseWriter, r *http.Request) {
+	tool := mux.Vars(r)["tool"]
+	if _, err := dbPool.Exec(context.Background(), `DELETE FROM tool_executor_configs WHERE tool_name = $1`, tool); err != nil {
+		http.Error(w, "Failed to reset executor config", http.StatusInternalServerError)
+		return
+	}
+	resetExecutorCache(tool)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loadExecutorConfig(tool))
+}
@@ -0,0 +1,752 @@
+package utils
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	faviconBodyLimit  = 1 << 20
+	faviconPivotLimit = 100
+)
+
+// knownFavicons maps Shodan favicon hashes to the product shipping that icon.
+// Extra or corrected entries can be supplied as a {"hash": "product"} JSON file
+// at FAVICON_HASHES_PATH.
+var knownFavicons = map[int32]string{
+	116323821:  "Spring Boot",
+	81586312:   "Jenkins",
+	1278323681: "GitLab",
+	1485257654: "SonarQube",
+	-335242539: "F5 BIG-IP",
+	945408572:  "Fortinet FortiGate",
+	999357577:  "Hikvision",
+}
+
+var loadFaviconMappingOnce sync.Once
+
+func faviconProduct(hash int32) string {
+	loadFaviconMappingOnce.Do(func() {
+		path := os.Getenv("FAVICON_HASHES_PATH")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[FAVICON] [WARN] Failed to read favicon mapping %s: %v", path, err)
+			return
+		}
+		var extra map[string]string
+		if err := json.Unmarshal(data, &extra); err != nil {
+			log.Printf("[FAVICON] [WARN] Failed to parse favicon mapping %s: %v", path, err)
+			return
+		}
+		for key, product := range extra {
+			if n, err := strconv.ParseInt(key, 10, 32); err == nil {
+				knownFavicons[int32(n)] = product
+			}
+		}
+		log.Printf("[FAVICON] [INFO] Loaded %d favicon mappings from %s", len(extra), path)
+	})
+	return knownFavicons[hash]
+}
+
+// murmur3 is the 32-bit MurmurHash3 with seed 0, as returned by Python's mmh3.hash.
+func murmur3(data []byte) int32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	var h uint32
+	n := len(data) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	var k uint32
+	switch len(data) & 3 {
+	case 3:
+		k ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[n])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return int32(h)
+}
+
+// faviconHash computes the hash Shodan indexes as http.favicon.hash: mmh3 over
+// the base64 encoding with a newline every 76 characters and at the end.
+func faviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return murmur3([]byte(b.String()))
+}
+
+var faviconLinkRegex = regexp.MustCompile(`(?i)<link[^>]+rel\s*=\s*["']?(?:shortcut\s+)?icon["']?[^>]*>`)
+var faviconHrefRegex = regexp.MustCompile(`(?i)\bhref\s*=\s*["']?([^"'\s>]+)`)
+
+// faviconCandidates lists where to look for a page's icon: <link rel="icon">
+// entries from the stored body first, then /favicon.ico.
+func faviconCandidates(pageURL, body string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil || base.Host == "" {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(ref string) {
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			candidates = append(candidates, s)
+		}
+	}
+	for _, link := range faviconLinkRegex.FindAllString(body, 5) {
+		if m := faviconHrefRegex.FindStringSubmatch(link); m != nil {
+			add(m[1])
+		}
+	}
+	add("/favicon.ico")
+	return candidates
+}
+
+func faviconHTTPClient(scope string) *http.Client {
+	return egressClient(scope, &http.Client{
+		Timeout: vhostRequestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	})
+}
+
+type Favicon struct {
+	URL     string `json:"favicon_url"`
+	Hash    int32  `json:"favicon_hash"`
+	MD5     string `json:"favicon_md5"`
+	Product string `json:"favicon_product,omitempty"`
+}
+
+// fetchFavicon returns the first candidate that serves an actual image. Soft 404
+// pages are skipped by rejecting HTML responses.
+func fetchFavicon(client *http.Client, pageURL, body string, headers []string) (Favicon, bool) {
+	for _, candidate := range faviconCandidates(pageURL, body) {
+		req, err := http.NewRequest("GET", candidate, nil)
+		if err != nil {
+			continue
+		}
+		applyRequestHeaders(req, headers)
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, faviconBodyLimit))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(data) == 0 {
+			continue
+		}
+		if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") ||
+			strings.Contains(strings.ToLower(string(data[:min(len(data), 512)])), "<html") {
+			continue
+		}
+		sum := md5.Sum(data)
+		hash := faviconHash(data)
+		return Favicon{URL: candidate, Hash: hash, MD5: hex.EncodeToString(sum[:]), Product: faviconProduct(hash)}, true
+	}
+	return Favicon{}, false
+}
+
+func storeFavicon(table, id string, f Favicon) {
+	_, err := dbPool.Exec(context.Background(), fmt.Sprintf(`
+		UPDATE %s
+		SET favicon_url = $1, favicon_hash = $2, favicon_md5 = $3, favicon_product = NULLIF($4, ''), favicon_checked_at = NOW()
+		WHERE id = $5`, table),
+		f.URL, f.Hash, f.MD5, f.Product, id)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to store favicon for %s %s: %v", table, id, err)
+	}
+}
+
+func RunFaviconScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID     string  `json:"scope_target_id"`
+		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string  `json:"request_profile_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
+		http.Error(w, "Invalid request body. scope_target_id is required.", http.StatusBadRequest)
+		return
+	}
+
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO favicon_scans (scan_id, scope_target_id, status, auto_scan_session_id)
+		VALUES ($1, $2, 'pending', $3)`,
+		scanID, payload.ScopeTargetID, autoScanSessionID)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteFaviconScan(scanID, payload.ScopeTargetID, payload.RequestProfileID)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateFaviconScan(scanID, status, errorMessage string, checked, found int, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE favicon_scans
+		SET status = $1, error_message = NULLIF($2, ''), targets_checked = $3, favicons_found = $4, execution_time = $5
+		WHERE scan_id = $6`,
+		status, errorMessage, checked, found, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to update scan %s: %v", scanID, err)
+	}
+}
+
+type faviconJob struct {
+	table string
+	id    string
+	url   string
+	body  string
+	scope string
+}
+
+// ExecuteFaviconScan hashes the favicon of every target URL and every live web
+// server from the latest IP/Port scan.
+func ExecuteFaviconScan(scanID, scopeTargetID, requestProfileID string) {
+	startTime := time.Now()
+	var jobs []faviconJob
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, url, COALESCE(http_response, '') FROM target_urls WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		updateFaviconScan(scanID, "error", fmt.Sprintf("Failed to load target URLs: %v", err), 0, 0, startTime)
+		return
+	}
+	for rows.Next() {
+		j := faviconJob{table: "target_urls", scope: EgressScopeHTTPProbe}
+		if rows.Scan(&j.id, &j.url, &j.body) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT id, url, ''
+		FROM live_web_servers
+		WHERE scan_id = (
+			SELECT scan_id FROM ip_port_scans
+			WHERE scope_target_id = $1 AND status = 'success'
+			ORDER BY created_at DESC LIMIT 1
+		)`, scopeTargetID)
+	if err != nil {
+		updateFaviconScan(scanID, "error", fmt.Sprintf("Failed to load live web servers: %v", err), 0, 0, startTime)
+		return
+	}
+	for rows.Next() {
+		j := faviconJob{table: "live_web_servers", scope: EgressScopeIPPortScan}
+		if rows.Scan(&j.id, &j.url, &j.body) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	if len(jobs) == 0 {
+		updateFaviconScan(scanID, "error", "No target URLs or live web servers to check", 0, 0, startTime)
+		return
+	}
+	updateFaviconScan(scanID, "running", "", 0, 0, startTime)
+
+	headers := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	checked, found := 0, 0
+	sem := make(chan struct{}, defaultVhostConcurrency)
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job faviconJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			favicon, ok := fetchFavicon(faviconHTTPClient(job.scope), job.url, job.body, headers)
+			if ok {
+				storeFavicon(job.table, job.id, favicon)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			checked++
+			if ok {
+				found++
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	updateFaviconScan(scanID, "success", "", checked, found, startTime)
+	log.Printf("[FAVICON] [INFO] Scan %s hashed %d favicons across %d targets in %s", scanID, found, checked, time.Since(startTime))
+}
+
+func GetFaviconScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, status, targets_checked, favicons_found,
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM favicon_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, status, errorMessage, execTime string
+		var checked, found int
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &status, &checked, &found, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scans = append(scans, map[string]interface{}{
+			"scan_id":         scanID,
+			"status":          status,
+			"targets_checked": checked,
+			"favicons_found":  found,
+			"error_message":   errorMessage,
+			"execution_time":  execTime,
+			"created_at":      createdAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+func faviconShodanQuery(hash int32) string {
+	return fmt.Sprintf("http.favicon.hash:%d", hash)
+}
+
+func faviconCensysQuery(md5Hash string) string {
+	return fmt.Sprintf("services.http.response.favicons.md5_hash:%s", md5Hash)
+}
+
+// GetFaviconGroups groups a scope target's URLs and live web servers by favicon.
+// Hosts sharing an icon usually run the same application, which makes each
+// group a single pivot.
+func GetFaviconGroups(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT 'target_url', id, url, favicon_hash, favicon_md5, COALESCE(favicon_product, ''), favicon_url
+		FROM target_urls
+		WHERE scope_target_id = $1 AND favicon_hash IS NOT NULL
+		UNION ALL
+		SELECT 'live_web_server', lws.id, lws.url, lws.favicon_hash, lws.favicon_md5, COALESCE(lws.favicon_product, ''), lws.favicon_url
+		FROM live_web_servers lws
+		JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id
+		WHERE ips.scope_target_id = $1 AND lws.favicon_hash IS NOT NULL
+		ORDER BY 4, 3`, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to load favicons: %v", err)
+		http.Error(w, "Failed to get favicon groups", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type faviconTarget struct {
+		Kind       string `json:"kind"`
+		ID         string `json:"id"`
+		URL        string `json:"url"`
+		FaviconURL string `json:"favicon_url"`
+	}
+	type faviconGroup struct {
+		Hash        int32           `json:"favicon_hash"`
+		MD5         string          `json:"favicon_md5"`
+		Product     string          `json:"favicon_product,omitempty"`
+		ShodanQuery string          `json:"shodan_query"`
+		CensysQuery string          `json:"censys_query"`
+		Targets     []faviconTarget `json:"targets"`
+	}
+	groups := []*faviconGroup{}
+	byHash := make(map[int32]*faviconGroup)
+	for rows.Next() {
+		var t faviconTarget
+		var hash int32
+		var md5Hash, product string
+		if err := rows.Scan(&t.Kind, &t.ID, &t.URL, &hash, &md5Hash, &product, &t.FaviconURL); err != nil {
+			continue
+		}
+		g, ok := byHash[hash]
+		if !ok {
+			g = &faviconGroup{Hash: hash, MD5: md5Hash, Product: product,
+				ShodanQuery: faviconShodanQuery(hash), CensysQuery: faviconCensysQuery(md5Hash)}
+			byHash[hash] = g
+			groups = append(groups, g)
+		}
+		g.Targets = append(g.Targets, t)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Targets) > len(groups[j].Targets) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+type FaviconPivotHit struct {
+	Source    string   `json:"source"`
+	IP        string   `json:"ip"`
+	Ports     []int    `json:"ports,omitempty"`
+	Hostnames []string `json:"hostnames,omitempty"`
+	Org       string   `json:"org,omitempty"`
+	Known     bool     `json:"known"`
+}
+
+// RunFaviconPivotScan searches Shodan and/or Censys for every host serving a
+// favicon already seen in the scope target.
+func RunFaviconPivotScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID string   `json:"scope_target_id"`
+		FaviconHash   *int32   `json:"favicon_hash"`
+		Sources       []string `json:"sources,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" || payload.FaviconHash == nil {
+		http.Error(w, "Invalid request body. scope_target_id and favicon_hash are required.", http.StatusBadRequest)
+		return
+	}
+	if len(payload.Sources) == 0 {
+		payload.Sources = []string{"shodan", "censys"}
+	}
+	for _, source := range payload.Sources {
+		if source != "shodan" && source != "censys" {
+			http.Error(w, "sources may only contain shodan and censys", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Censys indexes the md5 of the icon, so pivots need a stored favicon
+	var md5Hash string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT favicon_md5 FROM target_urls WHERE scope_target_id = $1 AND favicon_hash = $2
+		UNION ALL
+		SELECT lws.favicon_md5 FROM live_web_servers lws JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id
+		WHERE ips.scope_target_id = $1 AND lws.favicon_hash = $2
+		LIMIT 1`, payload.ScopeTargetID, *payload.FaviconHash).Scan(&md5Hash)
+	if err != nil {
+		http.Error(w, "No target in this scope serves that favicon. Run a favicon scan first.", http.StatusBadRequest)
+		return
+	}
+
+	scanID := uuid.New().String()
+	_, err = dbPool.Exec(context.Background(), `
+		INSERT INTO favicon_pivot_scans (scan_id, scope_target_id, favicon_hash, favicon_md5, sources, status)
+		VALUES ($1, $2, $3, $4, $5, 'pending')`,
+		scanID, payload.ScopeTargetID, *payload.FaviconHash, md5Hash, payload.Sources)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to create pivot scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteFaviconPivotScan(scanID, payload.ScopeTargetID, *payload.FaviconHash, md5Hash, payload.Sources)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateFaviconPivotScan(scanID, status, result, errorMessage string, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE favicon_pivot_scans
+		SET status = $1, result = NULLIF($2, ''), error_message = NULLIF($3, ''), execution_time = $4
+		WHERE scan_id = $5`,
+		status, result, errorMessage, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[FAVICON] [ERROR] Failed to update pivot scan %s: %v", scanID, err)
+	}
+}
+
+func ExecuteFaviconPivotScan(scanID, scopeTargetID string, hash int32, md5Hash string, sources []string) {
+	startTime := time.Now()
+	updateFaviconPivotScan(scanID, "running", "", "", startTime)
+
+	var hits []FaviconPivotHit
+	var errs []string
+	for _, source := range sources {
+		var found []FaviconPivotHit
+		var err error
+		switch source {
+		case "shodan":
+			found, err = shodanFaviconSearch(hash)
+		case "censys":
+			found, err = censysFaviconSearch(md5Hash)
+		}
+		if err != nil {
+			log.Printf("[FAVICON] [WARN] %s pivot for %d failed: %v", source, hash, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+		hits = append(hits, found...)
+	}
+	if len(hits) == 0 && len(errs) > 0 {
+		updateFaviconPivotScan(scanID, "error", "", strings.Join(errs, "; "), startTime)
+		return
+	}
+
+	// Flag hits the scope target already knows about
+	known := make(map[string]bool)
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT ip FROM (
+			SELECT unnest(dns_a_records) AS ip FROM target_urls WHERE scope_target_id = $1
+			UNION SELECT ip_address FROM target_urls WHERE scope_target_id = $1
+			UNION SELECT host(lws.ip_address) FROM live_web_servers lws
+				JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id WHERE ips.scope_target_id = $1
+		) known WHERE ip IS NOT NULL`, scopeTargetID)
+	if err == nil {
+		for rows.Next() {
+			var ip string
+			if rows.Scan(&ip) == nil {
+				known[ip] = true
+			}
+		}
+		rows.Close()
+	}
+	newHosts := 0
+	for i := range hits {
+		hits[i].Known = known[hits[i].IP]
+		if !hits[i].Known {
+			newHosts++
+		}
+	}
+
+	result, _ := json.Marshal(map[string]interface{}{
+		"favicon_hash": hash,
+		"favicon_md5":  md5Hash,
+		"shodan_query": faviconShodanQuery(hash),
+		"censys_query": faviconCensysQuery(md5Hash),
+		"hits":         hits,
+		"new_hosts":    newHosts,
+		"errors":       errs,
+	})
+	updateFaviconPivotScan(scanID, "success", string(result), strings.Join(errs, "; "), startTime)
+	log.Printf("[FAVICON] [INFO] Pivot scan %s found %d hosts for favicon %d (%d not yet known)", scanID, len(hits), hash, newHosts)
+}
+
+func shodanFaviconSearch(hash int32) ([]FaviconPivotHit, error) {
+	var apiKey string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT (api_key_value::json->>'api_key')::text
+		FROM api_keys WHERE tool_name = 'Shodan'
+		ORDER BY created_at DESC LIMIT 1`).Scan(&apiKey)
+	if err != nil || apiKey == "" {
+		return nil, fmt.Errorf("no Shodan API key configured")
+	}
+
+	client := egressClient(EgressScopeShodan, &http.Client{Timeout: 60 * time.Second})
+	params := url.Values{"key": {apiKey}, "query": {faviconShodanQuery(hash)}}
+	resp, err := client.Get("https://api.shodan.io/shodan/host/search?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var searchResp struct {
+		Matches []struct {
+			IPStr     string   `json:"ip_str"`
+			Port      int      `json:"port"`
+			Hostnames []string `json:"hostnames"`
+			Org       string   `json:"org"`
+		} `json:"matches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, err
+	}
+
+	byIP := make(map[string]*FaviconPivotHit)
+	var hits []FaviconPivotHit
+	for _, m := range searchResp.Matches {
+		if m.IPStr == "" {
+			continue
+		}
+		hit, ok := byIP[m.IPStr]
+		if !ok {
+			if len(byIP) >= faviconPivotLimit {
+				continue
+			}
+			hit = &FaviconPivotHit{Source: "shodan", IP: m.IPStr, Org: m.Org}
+			byIP[m.IPStr] = hit
+		}
+		hit.Ports = append(hit.Ports, m.Port)
+		hit.Hostnames = append(hit.Hostnames, m.Hostnames...)
+	}
+	for _, hit := range byIP {
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
+
+func censysFaviconSearch(md5Hash string) ([]FaviconPivotHit, error) {
+	var apiID, apiSecret string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT (api_key_value::json->>'app_id')::text, (api_key_value::json->>'app_secret')::text
+		FROM api_keys WHERE tool_name = 'Censys'
+		ORDER BY created_at DESC LIMIT 1`).Scan(&apiID, &apiSecret)
+	if err != nil || apiID == "" || apiSecret == "" {
+		return nil, fmt.Errorf("no Censys API credentials configured")
+	}
+
+	client := egressClient(EgressScopeCensys, &http.Client{Timeout: 60 * time.Second})
+	params := url.Values{"q": {faviconCensysQuery(md5Hash)}, "per_page": {strconv.Itoa(faviconPivotLimit)}}
+	req, err := http.NewRequest("GET", "https://search.censys.io/api/v2/hosts/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(apiID, apiSecret)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var searchResp struct {
+		Result struct {
+			Hits []struct {
+				IP       string `json:"ip"`
+				Services []struct {
+					Port int `json:"port"`
+				} `json:"services"`
+				DNS struct {
+					ReverseDNS struct {
+						Names []string `json:"names"`
+					} `json:"reverse_dns"`
+				} `json:"dns"`
+				AutonomousSystem struct {
+					Name string `json:"name"`
+				} `json:"autonomous_system"`
+			} `json:"hits"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, err
+	}
+
+	hits := make([]FaviconPivotHit, 0, len(searchResp.Result.Hits))
+	for _, h := range searchResp.Result.Hits {
+		hit := FaviconPivotHit{Source: "censys", IP: h.IP, Hostnames: h.DNS.ReverseDNS.Names, Org: h.AutonomousSystem.Name}
+		for _, s := range h.Services {
+			hit.Ports = append(hit.Ports, s.Port)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func GetFaviconPivotScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, favicon_hash, favicon_md5, sources, status, COALESCE(result, ''),
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM favicon_pivot_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, md5Hash, status, result, errorMessage, execTime string
+		var hash int32
+		var sources []string
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &hash, &md5Hash, &sources, &status, &result, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scan := map[string]interface{}{
+			"scan_id":        scanID,
+			"favicon_hash":   hash,
+			"favicon_md5":    md5Hash,
+			"sources":        sources,
+			"status":         status,
+			"error_message":  errorMessage,
+			"execution_time": execTime,
+			"created_at":     createdAt.Format(time.RFC3339),
+		}
+		if result != "" {
+			scan["result"] = json.RawMessage(result)
+		}
+		scans = append(scans, scan)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
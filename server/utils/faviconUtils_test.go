@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	// The published MurmurHash3 x86_32 seed 0 vectors, which cover every tail
+	// length and the byte order of a block, plus values from Python's mmh3.hash.
+	tests := []struct {
+		data string
+		want uint32
+	}{
+		{"", 0},
+		{"\x00", 0x514e28b7},
+		{"\x00\x00", 0x30f4c306},
+		{"\x00\x00\x00", 0x85f0b427},
+		{"\x00\x00\x00\x00", 0x2362f9de},
+		{"\xff\xff\xff\xff", 0x76293b50},
+		{"!", 0x72661cf4},
+		{"!C", 0xa0f7b07a},
+		{"!Ce", 0x7e4a8634},
+		{"!Ce\x87", 0xf55b516b},
+		{"abc", 0xb3dd93fa},
+		{"hello", 613153351},
+		{"foo", 0xf6a5c420},
+	}
+	for _, tt := range tests {
+		if got := murmur3([]byte(tt.data)); got != int32(tt.want) {
+			t.Errorf("murmur3(%q) = %d, want %d", tt.data, got, int32(tt.want))
+		}
+	}
+}
+
+func TestFaviconHash(t *testing.T) {
+	// 57 bytes encode to exactly one 76 character line. The expected strings are
+	// what Python's base64.encodebytes, which Shodan hashes, returns.
+	line := strings.Repeat("YWFh", 19)
+	tests := []struct {
+		name    string
+		size    int
+		encoded string
+	}{
+		{"short", 1, "YQ==\n"},
+		{"one byte under a line", 56, line[:72] + "YWE=\n"},
+		{"exactly one line", 57, line + "\n"},
+		{"one byte over a line", 58, line + "\nYQ==\n"},
+		{"exactly two lines", 114, line + "\n" + line + "\n"},
+		{"one byte over two lines", 115, line + "\n" + line + "\nYQ==\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(strings.Repeat("a", tt.size))
+			if got, want := faviconHash(data), murmur3([]byte(tt.encoded)); got != want {
+				t.Errorf("faviconHash(%d bytes) = %d, want mmh3 of %q = %d", tt.size, got, tt.encoded, want)
+			}
+		})
+	}
+}
+
+func TestFaviconProduct(t *testing.T) {
+	if got := faviconProduct(116323821); got != "Spring Boot" {
+		t.Errorf("faviconProduct(116323821) = %q, want Spring Boot", got)
+	}
+	if got := faviconProduct(0); got != "" {
+		t.Errorf("faviconProduct(0) = %q, want no product", got)
+	}
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"ars0n-framework-v2-server/fingerprint"
+
+	"github.com/gorilla/mux"
+)
+
+func fingerprintRulesPath() string {
+	if path := os.Getenv("FINGERPRINT_RULES_PATH"); path != "" {
+		return path
+	}
+	return "/app/fingerprints"
+}
+
+// InitFingerprintRules loads local Wappalyzer-format rules if present. The small
+// embedded ruleset is used otherwise.
+func InitFingerprintRules() {
+	path := fingerprintRulesPath()
+	rules, err := fingerprint.LoadRules(path)
+	switch {
+	case err == nil:
+		fingerprint.SetRules(rules)
+	case !os.IsNotExist(err):
+		log.Printf("[FINGERPRINT] [WARN] Ignoring rules at %s: %v", path, err)
+	}
+	current := fingerprint.Current()
+	log.Printf("[FINGERPRINT] [INFO] Using %d technology rules from %s (version %s, %d patterns skipped)",
+		current.Technologies, current.Source, current.Version, current.SkippedPatterns)
+}
+
+// GetFingerprintRules reports which ruleset is in use.
+func GetFingerprintRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules":      fingerprint.Current(),
+		"rules_path": fingerprintRulesPath(),
+	})
+}
+
+// ReloadFingerprintRules re-reads the rules after they were updated on disk.
+func ReloadFingerprintRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := fingerprint.LoadRules(fingerprintRulesPath())
+	if err != nil {
+		log.Printf("[FINGERPRINT] [ERROR] Reload failed: %v", err)
+		http.Error(w, "Failed to load fingerprint rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	fingerprint.SetRules(rules)
+	log.Printf("[FINGERPRINT] [INFO] Loaded %d technology rules (version %s)", rules.Technologies, rules.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules":      rules,
+		"rules_path": fingerprintRulesPath(),
+	})
+}
+
+// fingerprintTargetURL runs the rules over a target URL's stored response,
+// replaces its detections and merges the names into target_urls.technologies.
+func fingerprintTargetURL(scopeTargetID, targetURLID, rawURL string, header http.Header, body string) ([]fingerprint.Detection, error) {
+	rules := fingerprint.Current()
+	detections := rules.Analyze(fingerprint.Input{URL: rawURL, Headers: header, Body: body})
+
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM technology_detections WHERE target_url_id = $1`, targetURLID); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(detections))
+	for _, d := range detections {
+		evidence, _ := json.Marshal(d.Evidence)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO technology_detections (
+				scope_target_id, target_url_id, url, name, version, categories, confidence, evidence, rules_version, cpe
+			) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, NULLIF($10, ''))`,
+			scopeTargetID, targetURLID, rawURL, d.Name, d.Version, d.Categories, d.Confidence, evidence, rules.Version, d.CPE)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, d.Name)
+	}
+	if len(names) > 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE target_urls
+			SET technologies = ARRAY(SELECT DISTINCT unnest(COALESCE(technologies, '{}') || $1::text[]))
+			WHERE id = $2`, names, targetURLID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return detections, tx.Commit(ctx)
+}
+
+// RunTechnologyFingerprint fingerprints every target URL of a scope target from
+// its stored response. Nothing is re-requested, so it is safe to rerun whenever
+// the rules change.
+func RunTechnologyFingerprint(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	startTime := time.Now()
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, url, COALESCE(http_response_headers::text, ''), COALESCE(http_response, '')
+		FROM target_urls
+		WHERE scope_target_id = $1 AND (http_response IS NOT NULL OR http_response_headers IS NOT NULL)`, scopeTargetID)
+	if err != nil {
+		log.Printf("[FINGERPRINT] [ERROR] Failed to load target URLs: %v", err)
+		http.Error(w, "Failed to load target URLs", http.StatusInternalServerError)
+		return
+	}
+	type storedResponse struct {
+		id, url, headers, body string
+	}
+	var responses []storedResponse
+	for rows.Next() {
+		var s storedResponse
+		if rows.Scan(&s.id, &s.url, &s.headers, &s.body) == nil {
+			responses = append(responses, s)
+		}
+	}
+	rows.Close()
+
+	analyzed, detected := 0, 0
+	for _, s := range responses {
+		detections, err := fingerprintTargetURL(scopeTargetID, s.id, s.url, headerFromStoredJSON(s.headers), s.body)
+		if err != nil {
+			log.Printf("[FINGERPRINT] [ERROR] Failed to fingerprint %s: %v", s.url, err)
+			continue
+		}
+		analyzed++
+		detected += len(detections)
+	}
+	log.Printf("[FINGERPRINT] [INFO] Fingerprinted %d target URLs for scope target %s: %d detections in %s",
+		analyzed, scopeTargetID, detected, time.Since(startTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"urls_analyzed":  analyzed,
+		"detections":     detected,
+		"rules_version":  fingerprint.Current().Version,
+		"execution_time": time.Since(startTime).String(),
+	})
+}
+
+func GetTechnologyDetections(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT target_url_id, url, name, COALESCE(version, ''), COALESCE(categories, '{}'), confidence,
+			evidence, rules_version, detected_at
+		FROM technology_detections
+		WHERE scope_target_id = $1
+		ORDER BY url, confidence DESC, name`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get technology detections", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	detections := []map[string]interface{}{}
+	for rows.Next() {
+		var targetURLID, url, name, version, rulesVersion string
+		var categories []string
+		var confidence int
+		var evidence []byte
+		var detectedAt time.Time
+		if err := rows.Scan(&targetURLID, &url, &name, &version, &categories, &confidence, &evidence, &rulesVersion, &detectedAt); err != nil {
+			continue
+		}
+		var evidenceList []string
+		json.Unmarshal(evidence, &evidenceList)
+		detections = append(detections, map[string]interface{}{
+			"target_url_id": targetURLID,
+			"url":           url,
+			"name":          name,
+			"version":       version,
+			"categories":    categories,
+			"confidence":    confidence,
+			"evidence":      evidenceList,
+			"rules_version": rulesVersion,
+			"detected_at":   detectedAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detections)
+}
@@ -1,14 +1,12 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -35,6 +33,10 @@ type GitHubReconScanStatus struct {
 }
 
 func RunGitHubReconScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "github-recon") {
+		return
+	}
+
 	log.Printf("[GITHUB-RECON] [INFO] Starting GitHub Recon scan request handling")
 	var payload struct {
 		CompanyName       string  `json:"company_name" binding:"required"`
@@ -103,6 +105,7 @@ func RunGitHubReconScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[GITHUB-RECON] [INFO] Successfully created GitHub Recon scan record in database")
 
+	RecordScanToolVersion("github_recon_scans", scanID, "github-recon")
 	go ExecuteGitHubReconScan(scanID, companyName)
 
 	log.Printf("[GITHUB-RECON] [INFO] GitHub Recon scan initiated successfully, returning scan ID: %s", scanID)
@@ -154,78 +157,44 @@ func ExecuteGitHubReconScan(scanID, companyName string) {
 	domainName = reg.ReplaceAllString(domainName, "")
 	log.Printf("[GITHUB-RECON] [INFO] Transformed company name '%s' to domain format '%s'", companyName, domainName)
 
-	// First, check if the GitHub recon container is running
-	checkCmd := exec.Command("docker", "ps", "--filter", "name=ars0n-framework-v2-github-recon-1", "--format", "{{.Status}}")
-	checkOutput, err := checkCmd.Output()
-	if err != nil {
-		log.Printf("[GITHUB-RECON] [ERROR] Failed to check container status: %v", err)
-		UpdateGitHubReconScanStatus(scanID, "error", "", "", fmt.Sprintf("Failed to check container status: %v", err), "", time.Since(startTime).String())
-		return
-	}
-
-	containerStatus := strings.TrimSpace(string(checkOutput))
-	log.Printf("[GITHUB-RECON] [DEBUG] Container status: %s", containerStatus)
-
-	if containerStatus == "" {
-		log.Printf("[GITHUB-RECON] [ERROR] GitHub recon container is not running")
-		UpdateGitHubReconScanStatus(scanID, "error", "", "", "GitHub recon container is not running", "", time.Since(startTime).String())
+	// First, check if the GitHub recon tool is reachable
+	executor := ToolExecutor("github-recon")
+	if err := executor.Check(context.Background()); err != nil {
+		log.Printf("[GITHUB-RECON] [ERROR] GitHub recon tool is not available: %v", err)
+		UpdateGitHubReconScanStatus(scanID, "error", "", "", fmt.Sprintf("GitHub recon tool is not available: %v", err), "", time.Since(startTime).String())
 		return
 	}
 
 	// Debug: Check what's in the container
-	debugCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "ls", "-la", "/app/github-search")
-	debugOutput, debugErr := debugCmd.Output()
+	debugResult, debugErr := executor.Exec(context.Background(), ExecRequest{Args: []string{"ls", "-la", executor.Path("/app/github-search")}})
 	if debugErr != nil {
 		log.Printf("[GITHUB-RECON] [DEBUG] Failed to list directory contents: %v", debugErr)
 	} else {
-		log.Printf("[GITHUB-RECON] [DEBUG] Container /app/github-search contents:\n%s", string(debugOutput))
-	}
-
-	// Debug: Check if the Python script exists
-	pythonCheckCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "ls", "-la", "/app/github-search/github-endpoints.py")
-	pythonCheckOutput, pythonCheckErr := pythonCheckCmd.Output()
-	if pythonCheckErr != nil {
-		log.Printf("[GITHUB-RECON] [DEBUG] Python script check failed: %v", pythonCheckErr)
-	} else {
-		log.Printf("[GITHUB-RECON] [DEBUG] Python script exists: %s", string(pythonCheckOutput))
+		log.Printf("[GITHUB-RECON] [DEBUG] Container /app/github-search contents:\n%s", debugResult.Stdout)
 	}
 
 	// Debug: Check the script help to see available parameters
-	helpCmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "/app/github-search/github-endpoints.py", "-h")
-	helpOutput, helpErr := helpCmd.Output()
+	helpResult, helpErr := executor.Run(context.Background(), ExecRequest{Args: []string{"-h"}})
 	if helpErr != nil {
 		log.Printf("[GITHUB-RECON] [DEBUG] Failed to get help output: %v", helpErr)
 	} else {
-		log.Printf("[GITHUB-RECON] [DEBUG] Script help output:\n%s", string(helpOutput))
+		log.Printf("[GITHUB-RECON] [DEBUG] Script help output:\n%s", helpResult.Stdout)
 	}
 
-	// Construct the command with unbuffered Python output
-	cmd := exec.Command("docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "-u", "/app/github-search/github-endpoints.py", "-d", domainName, "-t", apiKey)
-	log.Printf("[GITHUB-RECON] [DEBUG] Executing command: %s", cmd.String())
-
-	// Set up separate stdout and stderr pipes
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Add timeout context
+	// Run with unbuffered Python output and a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
-	cmd = exec.CommandContext(ctx, "docker", "exec", "ars0n-framework-v2-github-recon-1", "python3", "-u", "/app/github-search/github-endpoints.py", "-d", domainName, "-t", apiKey)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err = cmd.Run()
-	stdoutStr := stdout.String()
-	stderrStr := stderr.String()
+	execResult, err := executor.Run(ctx, ExecRequest{Args: []string{"-d", domainName, "-t", apiKey}})
+	stdoutStr := execResult.Stdout
+	stderrStr := execResult.Stderr
 
 	log.Printf("[GITHUB-RECON] [DEBUG] Command stdout: %s", stdoutStr)
 	log.Printf("[GITHUB-RECON] [DEBUG] Command stderr: %s", stderrStr)
 
 	if err != nil {
 		log.Printf("[GITHUB-RECON] [ERROR] Failed to execute GitHub Recon scan: %v", err)
-		log.Printf("[GITHUB-RECON] [ERROR] Command that failed: %s", cmd.String())
-		UpdateGitHubReconScanStatus(scanID, "error", "", "", stderrStr, cmd.String(), time.Since(startTime).String())
+		log.Printf("[GITHUB-RECON] [ERROR] Command that failed: %s", execResult.Command)
+		UpdateGitHubReconScanStatus(scanID, "error", "", "", stderrStr, execResult.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -27,7 +27,7 @@ func TestHackerOneAPIKey(w http.ResponseWriter, r *http.Request) {
 	username := parts[0]
 	token := parts[1]
 
-	client := &http.Client{}
+	client := egressClient(EgressScopeHackerOne, &http.Client{})
 	req, err := http.NewRequest("GET", "https://api.hackerone.com/v1/hackers/programs?page[size]=1", nil)
 	if err != nil {
 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
@@ -82,7 +82,7 @@ func GetHackerOneProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := &http.Client{}
+	client := egressClient(EgressScopeHackerOne, &http.Client{})
 	url := fmt.Sprintf("https://api.hackerone.com/v1/hackers/programs/%s?include=structured_scopes", programHandle)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -133,7 +133,7 @@ func ListHackerOnePrograms(w http.ResponseWriter, r *http.Request) {
 		pageSize = "100"
 	}
 
-	client := &http.Client{}
+	client := egressClient(EgressScopeHackerOne, &http.Client{})
 	url := fmt.Sprintf("https://api.hackerone.com/v1/hackers/programs?page[size]=%s", pageSize)
 	if pageNumber != "" {
 		url += fmt.Sprintf("&page[number]=%s", pageNumber)
@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -47,6 +48,10 @@ type SSLInfo struct {
 	IsExpired    bool      `json:"is_expired"`
 	IsSelfSigned bool      `json:"is_self_signed"`
 	IsMismatched bool      `json:"is_mismatched"`
+	SANs         []string  `json:"sans,omitempty"`
+	Organization string    `json:"organization,omitempty"`
+
+	certificate *x509.Certificate
 }
 
 type InvestigateASN struct {
@@ -126,13 +131,18 @@ func ExecuteInvestigateScan(scanID, scopeTargetID string) {
 		companyName = ""
 	}
 
+	harvestScope, err := loadCertScope(scopeTargetID)
+	if err != nil {
+		log.Printf("[WARN] Failed to load certificate scope, SANs will not be recorded: %v", err)
+	}
+
 	var results []InvestigateResult
 	for _, domain := range domains {
 		log.Printf("[INFO] Investigating domain: %s", domain)
 		result := InvestigateResult{Domain: domain}
 
 		// Get IP address
-		if ips, err := net.LookupIP(domain); err == nil && len(ips) > 0 {
+		if ips, err := lookupIPWithPool(domain); err == nil && len(ips) > 0 {
 			result.IPAddress = ips[0].String()
 		} else {
 			log.Printf("[WARN] Failed to resolve IP for %s: %v", domain, err)
@@ -142,6 +152,7 @@ func ExecuteInvestigateScan(scanID, scopeTargetID string) {
 		// Get SSL info
 		if sslInfo := getSSLInfo(domain); sslInfo != nil {
 			result.SSL = sslInfo
+			recordCertificateNames(harvestScope, "investigate", newCertObservation(domain, sslInfo.certificate))
 		}
 
 		// Get ASN info
@@ -193,6 +204,9 @@ func getSSLInfo(domain string) *SSLInfo {
 		Expiration:   cert.NotAfter,
 		IsExpired:    time.Now().After(cert.NotAfter),
 		IsSelfSigned: cert.Issuer.String() == cert.Subject.String(),
+		SANs:         cert.DNSNames,
+		Organization: strings.Join(cert.Subject.Organization, ", "),
+		certificate:  cert,
 	}
 
 	// Check for domain mismatch
@@ -208,16 +222,19 @@ func getSSLInfo(domain string) *SSLInfo {
 }
 
 func getASNInfo(domain string) *InvestigateASN {
-	ips, err := net.LookupIP(domain)
+	ips, err := lookupIPWithPool(domain)
 	if err != nil || len(ips) == 0 {
 		log.Printf("[WARN] Failed to resolve IP for %s: %v", domain, err)
 		return nil
 	}
 
 	ip := ips[0].String()
+	if asn, org, _, ok := lookupLocalASN(ip); ok && asn != "" {
+		return &InvestigateASN{ASN: "AS" + asn, Provider: org}
+	}
 
 	// Try multiple APIs for better reliability
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := egressClient(EgressScopeASNLookup, &http.Client{Timeout: 10 * time.Second})
 
 	// Try ipapi.co first
 	resp, err := client.Get(fmt.Sprintf("https://ipapi.co/%s/json/", ip))
@@ -262,7 +279,7 @@ func getASNInfo(domain string) *InvestigateASN {
 }
 
 func getHTTPInfo(domain, companyName string) (*HTTPInfo, bool) {
-	client := &http.Client{
+	client := egressClient(EgressScopeHTTPProbe, &http.Client{
 		Timeout: 10 * time.Second,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 3 {
@@ -270,7 +287,7 @@ func getHTTPInfo(domain, companyName string) (*HTTPInfo, bool) {
 			}
 			return nil
 		},
-	}
+	})
 
 	url := "https://" + domain
 	resp, err := client.Get(url)
@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// An IP/port scan checkpoints each network range once its addresses have been
+// probed, and each live IP once its ports have been scanned, so a paused or
+// interrupted scan picks up where it stopped instead of starting over.
+
+const ipPortScanPausePoll = 3 * time.Second
+
+var errIPPortScanPaused = errors.New("scan paused")
+
+// ipPortScanRunningStatuses are the statuses of a scan that is still working.
+var ipPortScanRunningStatuses = []string{"pending", "discovering_ips", "port_scanning"}
+
+type IPPortScanRangeCheckpoint struct {
+	CIDRBlock   string     `json:"cidr_block"`
+	Status      string     `json:"status"`
+	IPsPlanned  int        `json:"ips_planned"`
+	LiveIPs     int        `json:"live_ips"`
+	SkipReason  string     `json:"skip_reason,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// watchIPPortScanPause returns a channel that is closed once a pause has been
+// requested for the scan, and a function that stops watching.
+func watchIPPortScanPause(scanID string) (<-chan struct{}, func()) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ipPortScanPausePoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				var pauseRequested bool
+				err := dbPool.QueryRow(context.Background(),
+					`SELECT COALESCE(pause_requested, false) FROM ip_port_scans WHERE scan_id = $1`, scanID).Scan(&pauseRequested)
+				if err != nil {
+					log.Printf("[IP-PORT-SCAN] [ERROR] Failed to check pause state for scan %s: %v", scanID, err)
+					continue
+				}
+				if pauseRequested {
+					log.Printf("[IP-PORT-SCAN] [INFO] Pause requested for scan %s", scanID)
+					close(stop)
+					return
+				}
+			}
+		}
+	}()
+	return stop, func() { close(done) }
+}
+
+func stopRequested(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// seedRangeCheckpoints records the ranges of a scan that have no checkpoint
+// yet and returns the status of every range. When the scan asks to rescan only
+// stale ranges, those a previous successful scan of the same target covered
+// within that many days are skipped.
+func seedRangeCheckpoints(scanID, scopeTargetID string, networkRanges []ConsolidatedNetworkRange) map[string]string {
+	ctx := context.Background()
+	for _, networkRange := range networkRanges {
+		_, err := dbPool.Exec(ctx, `
+			INSERT INTO ip_port_scan_range_checkpoints (scan_id, cidr_block, status)
+			VALUES ($1, $2, 'pending')
+			ON CONFLICT (scan_id, cidr_block) DO NOTHING`, scanID, networkRange.CIDRBlock)
+		if err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Failed to record checkpoint for range %s: %v", networkRange.CIDRBlock, err)
+		}
+	}
+
+	var rescanAfterDays *int
+	if err := dbPool.QueryRow(ctx, `SELECT rescan_after_days FROM ip_port_scans WHERE scan_id = $1`, scanID).Scan(&rescanAfterDays); err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to read rescan window for scan %s: %v", scanID, err)
+	}
+	if rescanAfterDays != nil && *rescanAfterDays > 0 {
+		result, err := dbPool.Exec(ctx, `
+			UPDATE ip_port_scan_range_checkpoints c
+			SET status = 'skipped', completed_at = NOW(),
+				skip_reason = 'scanned ' || to_char(prev.completed_at, 'YYYY-MM-DD') || ' by scan ' || prev.scan_id
+			FROM (
+				SELECT DISTINCT ON (pc.cidr_block) pc.cidr_block, pc.scan_id, pc.completed_at
+				FROM ip_port_scan_range_checkpoints pc
+				JOIN ip_port_scans s ON s.scan_id = pc.scan_id
+				WHERE s.scope_target_id = $2 AND s.status = 'success' AND pc.scan_id <> $1
+					AND pc.status = 'discovered' AND pc.completed_at > NOW() - make_interval(days => $3)
+				ORDER BY pc.cidr_block, pc.completed_at DESC
+			) prev
+			WHERE c.scan_id = $1 AND c.status = 'pending' AND c.cidr_block = prev.cidr_block`,
+			scanID, scopeTargetID, *rescanAfterDays)
+		if err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Failed to skip recently scanned ranges: %v", err)
+		} else if result.RowsAffected() > 0 {
+			log.Printf("[IP-PORT-SCAN] [INFO] Skipping %d ranges scanned within the last %d days", result.RowsAffected(), *rescanAfterDays)
+		}
+	}
+
+	statuses := make(map[string]string)
+	rows, err := dbPool.Query(ctx, `SELECT cidr_block, status FROM ip_port_scan_range_checkpoints WHERE scan_id = $1`, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to load range checkpoints: %v", err)
+		return statuses
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cidr, status string
+		if err := rows.Scan(&cidr, &status); err == nil {
+			statuses[cidr] = status
+		}
+	}
+	return statuses
+}
+
+// completeRangeCheckpoint marks a range as fully probed and refreshes the
+// scan's processed range count.
+func completeRangeCheckpoint(scanID, cidr string, ipsPlanned, liveIPs int) {
+	ctx := context.Background()
+	_, err := dbPool.Exec(ctx, `
+		UPDATE ip_port_scan_range_checkpoints
+		SET status = 'discovered', ips_planned = $3, live_ips = $4, completed_at = NOW()
+		WHERE scan_id = $1 AND cidr_block = $2`, scanID, cidr, ipsPlanned, liveIPs)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to checkpoint range %s: %v", cidr, err)
+		return
+	}
+	_, err = dbPool.Exec(ctx, `
+		UPDATE ip_port_scans SET last_checkpoint_at = NOW(),
+			processed_network_ranges = (SELECT COUNT(*) FROM ip_port_scan_range_checkpoints WHERE scan_id = $1 AND status <> 'pending')
+		WHERE scan_id = $1`, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update processed ranges: %v", err)
+	}
+}
+
+func countFinishedRanges(statuses map[string]string) int {
+	finished := 0
+	for _, status := range statuses {
+		if status != "pending" {
+			finished++
+		}
+	}
+	return finished
+}
+
+// loadScanLiveIPs returns the live IPs an earlier run of the scan found, and
+// which of them already had their ports scanned.
+func loadScanLiveIPs(scanID string) ([]string, map[string]bool) {
+	var liveIPs []string
+	portsScanned := make(map[string]bool)
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT host(ip_address), bool_or(ports_scanned_at IS NOT NULL)
+		FROM discovered_live_ips WHERE scan_id = $1
+		GROUP BY ip_address`, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to load live IPs for scan %s: %v", scanID, err)
+		return nil, portsScanned
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ip string
+		var scanned bool
+		if err := rows.Scan(&ip, &scanned); err != nil {
+			continue
+		}
+		liveIPs = append(liveIPs, ip)
+		if scanned {
+			portsScanned[ip] = true
+		}
+	}
+	return liveIPs, portsScanned
+}
+
+// completePortScanCheckpoint marks a live IP's ports as scanned.
+func completePortScanCheckpoint(scanID, ip string) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE discovered_live_ips SET ports_scanned_at = NOW() WHERE scan_id = $1 AND ip_address = $2::inet`, scanID, ip)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to checkpoint ports of %s: %v", ip, err)
+	}
+}
+
+// loadScanWebEndpoints lists the web servers found across every run of a scan.
+func loadScanWebEndpoints(scanID string) []LiveWebServer {
+	var servers []LiveWebServer
+	rows, err := dbPool.Query(context.Background(), `SELECT host(ip_address), port FROM live_web_servers WHERE scan_id = $1`, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to load web servers for scan %s: %v", scanID, err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		server := LiveWebServer{ScanID: scanID}
+		if err := rows.Scan(&server.IPAddress, &server.Port); err == nil {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+func countScanServices(scanID string) int {
+	var count int
+	dbPool.QueryRow(context.Background(), `SELECT COUNT(*) FROM discovered_services WHERE scan_id = $1`, scanID).Scan(&count)
+	return count
+}
+
+// MarkInterruptedIPPortScans flags scans that were running when the server
+// stopped, so they can be resumed from their checkpoints.
+func MarkInterruptedIPPortScans() {
+	result, err := dbPool.Exec(context.Background(), `
+		UPDATE ip_port_scans SET status = 'interrupted', error_message = 'Server restarted during the scan; resume it to continue'
+		WHERE status = ANY($1)`, ipPortScanRunningStatuses)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to mark interrupted scans: %v", err)
+		return
+	}
+	if result.RowsAffected() > 0 {
+		log.Printf("[IP-PORT-SCAN] [INFO] Marked %d interrupted IP/Port scans as resumable", result.RowsAffected())
+	}
+}
+
+// PauseIPPortScan asks a running scan to stop at its next checkpoint.
+func PauseIPPortScan(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	result, err := dbPool.Exec(context.Background(),
+		`UPDATE ip_port_scans SET pause_requested = true WHERE scan_id = $1 AND status = ANY($2)`, scanID, ipPortScanRunningStatuses)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to pause scan %s: %v", scanID, err)
+		http.Error(w, "Failed to pause scan", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected() == 0 {
+		http.Error(w, "Scan is not running", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Scan pause requested"})
+}
+
+// ResumeIPPortScan continues a paused or interrupted scan from its checkpoints.
+func ResumeIPPortScan(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	var scopeTargetID string
+	err := dbPool.QueryRow(context.Background(), `
+		UPDATE ip_port_scans
+		SET status = 'pending', pause_requested = false, error_message = NULL, resume_count = COALESCE(resume_count, 0) + 1
+		WHERE scan_id = $1 AND status IN ('paused', 'interrupted')
+		RETURNING scope_target_id`, scanID).Scan(&scopeTargetID)
+	if err != nil {
+		http.Error(w, "Scan is not paused or interrupted", http.StatusConflict)
+		return
+	}
+
+	log.Printf("[IP-PORT-SCAN] [INFO] Resuming scan %s", scanID)
+	go ExecuteIPPortScan(scanID, scopeTargetID)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+// GetIPPortScanCheckpoints lists the progress of each range in a scan.
+func GetIPPortScanCheckpoints(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT cidr_block, status, COALESCE(ips_planned, 0), COALESCE(live_ips, 0), COALESCE(skip_reason, ''), completed_at
+		FROM ip_port_scan_range_checkpoints WHERE scan_id = $1 ORDER BY cidr_block`, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to get checkpoints for scan %s: %v", scanID, err)
+		http.Error(w, "Failed to get checkpoints", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	checkpoints := []IPPortScanRangeCheckpoint{}
+	for rows.Next() {
+		var c IPPortScanRangeCheckpoint
+		if err := rows.Scan(&c.CIDRBlock, &c.Status, &c.IPsPlanned, &c.LiveIPs, &c.SkipReason, &c.CompletedAt); err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning checkpoint row: %v", err)
+			continue
+		}
+		checkpoints = append(checkpoints, c)
+	}
+
+	var portsScanned, liveIPs int
+	dbPool.QueryRow(context.Background(), `
+		SELECT COUNT(DISTINCT ip_address) FILTER (WHERE ports_scanned_at IS NOT NULL), COUNT(DISTINCT ip_address)
+		FROM discovered_live_ips WHERE scan_id = $1`, scanID).Scan(&portsScanned, &liveIPs)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ranges":           checkpoints,
+		"live_ips":         liveIPs,
+		"live_ips_scanned": portsScanned,
+	})
+}
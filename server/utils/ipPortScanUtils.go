@@ -3,15 +3,20 @@ package utils
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/netip"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,6 +33,8 @@ type IPPortScan struct {
 	TotalIPsDiscovered  int       `json:"total_ips_discovered"`
 	TotalPortsScanned   int       `json:"total_ports_scanned"`
 	LiveWebServersFound int       `json:"live_web_servers_found"`
+	ServicesFound       int       `json:"services_found"`
+	PortProfile         string    `json:"port_profile,omitempty"`
 	ErrorMessage        string    `json:"error_message,omitempty"`
 	ExecutionTime       string    `json:"execution_time,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
@@ -49,6 +56,15 @@ type LiveWebServer struct {
 	Technologies  []string  `json:"technologies,omitempty"`
 	ResponseTime  *float64  `json:"response_time_ms,omitempty"`
 	LastChecked   time.Time `json:"last_checked"`
+	// AddressFamily is ipv4 or ipv6; StackExposure compares the two stacks of
+	// a dual-stack host: dual_stack, ipv4_only or ipv6_only
+	AddressFamily string `json:"address_family,omitempty"`
+	StackExposure string `json:"stack_exposure,omitempty"`
+	IPASN
+	EdgeClassification
+
+	// certificate is the leaf served over https, kept for SAN harvesting
+	certificate *x509.Certificate
 }
 
 type DiscoveredIP struct {
@@ -59,12 +75,26 @@ type DiscoveredIP struct {
 	NetworkRange string    `json:"network_range"`
 	PingTime     *float64  `json:"ping_time_ms,omitempty"`
 	DiscoveredAt time.Time `json:"discovered_at"`
+	IPASN
+}
+
+// IPASN is the origin of an IP according to the local ASN database.
+type IPASN struct {
+	ASNNumber       string `json:"asn_number,omitempty"`
+	ASNOrganization string `json:"asn_organization,omitempty"`
+	ASNCountry      string `json:"asn_country,omitempty"`
+}
+
+func localIPASN(ip string) IPASN {
+	asn, org, country, _ := lookupLocalASN(ip)
+	return IPASN{ASNNumber: asn, ASNOrganization: org, ASNCountry: country}
 }
 
 type ScanConfig struct {
 	MaxIPsPerRange     int           `json:"max_ips_per_range"`
 	MaxConcurrentIPs   int           `json:"max_concurrent_ips"`
 	MaxConcurrentPorts int           `json:"max_concurrent_ports"`
+	MaxFingerprints    int           `json:"max_fingerprints"`
 	HostProbeTimeout   time.Duration `json:"host_probe_timeout"`
 	PortScanTimeout    time.Duration `json:"port_scan_timeout"`
 	WebServiceTimeout  time.Duration `json:"web_service_timeout"`
@@ -87,6 +117,7 @@ func getDefaultScanConfig() ScanConfig {
 		MaxIPsPerRange:     254,             // Limit IPs per CIDR
 		MaxConcurrentIPs:   50,              // Max concurrent IP probes
 		MaxConcurrentPorts: 20,              // Max concurrent port scans
+		MaxFingerprints:    10,              // Max concurrent service fingerprints per IP
 		HostProbeTimeout:   1 * time.Second, // Per port connection timeout
 		PortScanTimeout:    1 * time.Second, // Per port connection timeout
 		WebServiceTimeout:  5 * time.Second, // Per HTTP request timeout
@@ -99,6 +130,9 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		ScopeTargetID     string  `json:"scope_target_id" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		PortProfile       string  `json:"port_profile,omitempty"`
+		Ports             string  `json:"ports,omitempty"`
+		RescanAfterDays   int     `json:"rescan_after_days,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
@@ -109,6 +143,12 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Processing IP/Port scan for scope target: %s", payload.ScopeTargetID)
 
+	scanPorts, err := resolveScanPorts(payload.PortProfile, payload.Ports)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid port selection: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	scanID := uuid.New().String()
 	log.Printf("[IP-PORT-SCAN] [INFO] Generated new scan ID: %s", scanID)
 
@@ -119,14 +159,14 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 	var insertQuery string
 	var args []interface{}
 	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
-		insertQuery = `INSERT INTO ip_port_scans (scan_id, scope_target_id, status, auto_scan_session_id) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{scanID, payload.ScopeTargetID, "pending", *payload.AutoScanSessionID}
+		insertQuery = `INSERT INTO ip_port_scans (scan_id, scope_target_id, status, port_profile, port_spec, discovery_port_spec, rescan_after_days, auto_scan_session_id) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), $8)`
+		args = []interface{}{scanID, payload.ScopeTargetID, "pending", scanPorts.profile, formatPortSpec(scanPorts.ports), formatPortSpec(scanPorts.discovery), payload.RescanAfterDays, *payload.AutoScanSessionID}
 	} else {
-		insertQuery = `INSERT INTO ip_port_scans (scan_id, scope_target_id, status) VALUES ($1, $2, $3)`
-		args = []interface{}{scanID, payload.ScopeTargetID, "pending"}
+		insertQuery = `INSERT INTO ip_port_scans (scan_id, scope_target_id, status, port_profile, port_spec, discovery_port_spec, rescan_after_days) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0))`
+		args = []interface{}{scanID, payload.ScopeTargetID, "pending", scanPorts.profile, formatPortSpec(scanPorts.ports), formatPortSpec(scanPorts.discovery), payload.RescanAfterDays}
 	}
 
-	_, err := dbPool.Exec(context.Background(), insertQuery, args...)
+	_, err = dbPool.Exec(context.Background(), insertQuery, args...)
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to create scan record: %v", err)
 		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
@@ -140,30 +180,51 @@ func RunIPPortScan(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-// Execute the complete IP/Port scan process
+// Execute the complete IP/Port scan process. A resumed scan runs through the
+// same steps and skips whatever its checkpoints show as done.
 func ExecuteIPPortScan(scanID, scopeTargetID string) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Starting IP/Port scan execution for scope target: %s", scopeTargetID)
 	startTime := time.Now()
 
+	stop, stopWatching := watchIPPortScanPause(scanID)
+	defer stopWatching()
+
+	ports, err := loadScanPorts(scanID)
+	if err != nil {
+		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Failed to resolve port profile: %v", err))
+		return
+	}
+	log.Printf("[IP-PORT-SCAN] [INFO] Using port profile %s (%d ports)", ports.profile, len(ports.ports))
+
 	// Get consolidated network ranges
-	networkRanges, err := getConsolidatedNetworkRanges(scopeTargetID)
+	consolidatedRanges, err := getConsolidatedNetworkRanges(scopeTargetID)
 	if err != nil {
 		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Failed to get network ranges: %v", err))
 		return
 	}
 
+	// AAAA records and dual-stack hosts become targets alongside the ranges
+	targets := buildIPScanTargets(scopeTargetID, consolidatedRanges)
+	networkRanges := targets.ranges
+
 	if len(networkRanges) == 0 {
 		updateIPPortScanStatus(scanID, "error", "No consolidated network ranges found. Run Amass Intel and Metabigor scans first, then consolidate.")
 		return
 	}
 
-	log.Printf("[IP-PORT-SCAN] [INFO] Found %d consolidated network ranges", len(networkRanges))
+	log.Printf("[IP-PORT-SCAN] [INFO] Found %d consolidated network ranges, %d added from address records",
+		len(consolidatedRanges), len(networkRanges)-len(consolidatedRanges))
 
 	// Update scan with total ranges
-	updateIPPortScanProgress(scanID, "discovering_ips", len(networkRanges), 0, 0, 0, 0)
+	checkpoints := seedRangeCheckpoints(scanID, scopeTargetID, networkRanges)
+	updateIPPortScanProgress(scanID, "discovering_ips", len(networkRanges), countFinishedRanges(checkpoints), 0, 0, 0)
 
 	// Phase 1: Discover live IPs
-	liveIPs, err := discoverLiveIPs(scanID, networkRanges)
+	liveIPs, err := discoverLiveIPs(scanID, networkRanges, targets.known, ports.discovery, checkpoints, stop)
+	if errors.Is(err, errIPPortScanPaused) {
+		pauseIPPortScan(scanID, startTime, nil)
+		return
+	}
 	if err != nil {
 		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("IP discovery failed: %v", err))
 		return
@@ -172,23 +233,88 @@ func ExecuteIPPortScan(scanID, scopeTargetID string) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Discovered %d live IPs", len(liveIPs))
 	updateIPPortScanProgress(scanID, "port_scanning", len(networkRanges), len(networkRanges), len(liveIPs), 0, 0)
 
-	// Phase 2: Port scan for web services
-	liveWebServers, err := discoverLiveWebServers(scanID, liveIPs)
+	// Phase 2: Port scan for web and other services
+	liveWebServers, err := discoverLiveWebServers(scanID, liveIPs, targets.hostnames, ports.ports, stop)
+	if errors.Is(err, errIPPortScanPaused) {
+		pauseIPPortScan(scanID, startTime, func() { harvestLiveWebServerCerts(scopeTargetID, liveWebServers) })
+		return
+	}
 	if err != nil {
 		updateIPPortScanStatus(scanID, "error", fmt.Sprintf("Port scanning failed: %v", err))
 		return
 	}
 
-	log.Printf("[IP-PORT-SCAN] [INFO] Found %d live web servers", len(liveWebServers))
+	// Earlier runs of a resumed scan found web servers too
+	allWebServers := loadScanWebEndpoints(scanID)
+	servicesFound := countScanServices(scanID)
+	markStackExposure(scanID, allWebServers, targets.dualStack)
+
+	log.Printf("[IP-PORT-SCAN] [INFO] Found %d live web servers and %d services", len(allWebServers), servicesFound)
+
+	// Certificates on raw IPs often name hosts no other source has found.
+	// Only this run's connections kept theirs.
+	harvestLiveWebServerCerts(scopeTargetID, liveWebServers)
 
 	// Update final status
-	totalPortsScanned := len(liveIPs) * len(webPorts)
-	updateIPPortScanProgress(scanID, "success", len(networkRanges), len(networkRanges), len(liveIPs), totalPortsScanned, len(liveWebServers))
+	totalPortsScanned := len(liveIPs) * len(ports.ports)
+	updateIPPortScanServices(scanID, servicesFound)
+	updateIPPortScanProgress(scanID, "success", len(networkRanges), len(networkRanges), len(liveIPs), totalPortsScanned, len(allWebServers))
 	updateIPPortScanExecutionTime(scanID, time.Since(startTime).String())
 
 	log.Printf("[IP-PORT-SCAN] [INFO] IP/Port scan completed in %s", time.Since(startTime).String())
 }
 
+// pauseIPPortScan records that a scan stopped at a pause request, after
+// running any work that should not wait for the resume.
+func pauseIPPortScan(scanID string, startTime time.Time, beforePause func()) {
+	if beforePause != nil {
+		beforePause()
+	}
+	updateIPPortScanServices(scanID, countScanServices(scanID))
+	updateIPPortScanStatus(scanID, "paused", "")
+	updateIPPortScanExecutionTime(scanID, time.Since(startTime).String())
+	log.Printf("[IP-PORT-SCAN] [INFO] IP/Port scan %s paused after %s", scanID, time.Since(startTime).String())
+}
+
+// loadScanPorts returns the ports a scan was started with, as stored on the scan,
+// so a resumed scan is not affected by later edits to its profile. Scans from
+// before the specs were stored resolve their profile, or the web profile.
+func loadScanPorts(scanID string) (*scanPorts, error) {
+	var profile, spec, discoverySpec string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT COALESCE(port_profile, ''), COALESCE(port_spec, ''), COALESCE(discovery_port_spec, '')
+		FROM ip_port_scans WHERE scan_id = $1`, scanID).Scan(&profile, &spec, &discoverySpec)
+	if err != nil {
+		return nil, err
+	}
+	if spec == "" {
+		return resolveScanPorts(profile, "")
+	}
+	if discoverySpec == "" {
+		if profile == "custom" {
+			return resolveScanPorts("", spec)
+		}
+		resolved, err := resolveScanPorts(profile, "")
+		if err != nil {
+			return resolveScanPorts("", spec)
+		}
+		discoverySpec = formatPortSpec(resolved.discovery)
+	}
+
+	ports, err := parsePortSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	discovery, err := parsePortSpec(discoverySpec)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = "custom"
+	}
+	return &scanPorts{profile: profile, ports: ports, discovery: discovery}, nil
+}
+
 // Get consolidated network ranges for a scope target
 func getConsolidatedNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange, error) {
 	query := `SELECT cidr_block, asn, organization, description, country, source, scan_type 
@@ -220,7 +346,9 @@ func getConsolidatedNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRa
 }
 
 // Discover live IPs using TCP connect probes
-func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([]string, error) {
+// Ranges checkpointed as done are not probed again, and live IPs an earlier
+// run found are carried over. Closing stop pauses discovery.
+func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange, known []netip.Addr, discoveryPorts []int, checkpoints map[string]string, stop <-chan struct{}) ([]string, error) {
 	log.Printf("[IP-PORT-SCAN] [INFO] Starting IP discovery for %d network ranges", len(networkRanges))
 
 	config := getDefaultScanConfig()
@@ -232,41 +360,66 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 	// Semaphore to limit concurrent operations
 	semaphore := make(chan struct{}, config.MaxConcurrentIPs)
 
+	// Overlapping ranges share addresses; probe each once, from the most
+	// specific range listing it
+	scheduled := make(map[string]bool)
+	networkRanges = orderRangesForScan(networkRanges)
+
+	previousLiveIPs, _ := loadScanLiveIPs(scanID)
+	for _, ip := range previousLiveIPs {
+		scheduled[ip] = true
+	}
+	allLiveIPs = append(allLiveIPs, previousLiveIPs...)
+
 	for rangeIdx, networkRange := range networkRanges {
+		if stopRequested(stop) {
+			break
+		}
 		log.Printf("[IP-PORT-SCAN] [DEBUG] Processing network range %d/%d: %s", rangeIdx+1, len(networkRanges), networkRange.CIDRBlock)
 
-		// Parse CIDR
-		_, ipNet, err := net.ParseCIDR(networkRange.CIDRBlock)
-		if err != nil {
-			log.Printf("[IP-PORT-SCAN] [ERROR] Invalid CIDR %s: %v", networkRange.CIDRBlock, err)
+		var ips []string
+		for _, ip := range rangeProbeIPs(networkRange.CIDRBlock, config.MaxIPsPerRange, known) {
+			if !scheduled[ip] {
+				scheduled[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+		if status := checkpoints[networkRange.CIDRBlock]; status != "" && status != "pending" {
+			log.Printf("[IP-PORT-SCAN] [DEBUG] Range %s already %s, not probing it again", networkRange.CIDRBlock, status)
 			continue
 		}
-
-		// Generate all IPs in the range
-		ips := generateIPsFromCIDR(ipNet)
-		log.Printf("[IP-PORT-SCAN] [DEBUG] Generated %d IPs from CIDR %s", len(ips), networkRange.CIDRBlock)
-
-		// Limit the number of IPs to scan per range
-		if len(ips) > config.MaxIPsPerRange {
-			log.Printf("[IP-PORT-SCAN] [INFO] Limiting scan to first %d IPs in range %s", config.MaxIPsPerRange, networkRange.CIDRBlock)
-			ips = ips[:config.MaxIPsPerRange]
+		if len(ips) == 0 {
+			log.Printf("[IP-PORT-SCAN] [DEBUG] No new IPs to probe in range %s", networkRange.CIDRBlock)
+			completeRangeCheckpoint(scanID, networkRange.CIDRBlock, 0, 0)
+			continue
 		}
 
-		// Probe each IP
+		// Probe each IP; the range is checkpointed once all of them have been
+		// probed, and left pending if a pause cuts it short
 		totalIPsToScan += len(ips)
 		log.Printf("[IP-PORT-SCAN] [DEBUG] Starting to probe %d IPs in range %s", len(ips), networkRange.CIDRBlock)
+		var rangeWG sync.WaitGroup
+		var rangeLive, rangeProbed int32
 		for ipIdx, ip := range ips {
 			wg.Add(1)
+			rangeWG.Add(1)
 			go func(ipAddr string, cidr string, idx int) {
 				defer wg.Done()
+				defer rangeWG.Done()
 				semaphore <- struct{}{}        // Acquire
 				defer func() { <-semaphore }() // Release
 
+				if stopRequested(stop) {
+					return
+				}
 				if idx%50 == 0 {
 					log.Printf("[IP-PORT-SCAN] [DEBUG] Probing IP %d/%d in range %s: %s", idx+1, len(ips), cidr, ipAddr)
 				}
 
-				if isHostAlive(ipAddr, config.HostProbeTimeout) {
+				alive := isHostAlive(ipAddr, discoveryPorts, config.HostProbeTimeout)
+				atomic.AddInt32(&rangeProbed, 1)
+				if alive {
+					atomic.AddInt32(&rangeLive, 1)
 					mu.Lock()
 					allLiveIPs = append(allLiveIPs, ipAddr)
 					mu.Unlock()
@@ -277,6 +430,15 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 				}
 			}(ip, networkRange.CIDRBlock, ipIdx)
 		}
+
+		wg.Add(1)
+		go func(cidr string, planned int) {
+			defer wg.Done()
+			rangeWG.Wait()
+			if int(atomic.LoadInt32(&rangeProbed)) == planned {
+				completeRangeCheckpoint(scanID, cidr, planned, int(atomic.LoadInt32(&rangeLive)))
+			}
+		}(networkRange.CIDRBlock, len(ips))
 	}
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Total IPs to scan across all ranges: %d", totalIPsToScan)
@@ -284,6 +446,10 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 	wg.Wait()
 	log.Printf("[IP-PORT-SCAN] [DEBUG] All IP discovery goroutines completed. Found %d live IPs before deduplication", len(allLiveIPs))
 
+	if stopRequested(stop) {
+		return nil, errIPPortScanPaused
+	}
+
 	// Remove duplicates
 	uniqueIPs := removeDuplicateIPs(allLiveIPs)
 	log.Printf("[IP-PORT-SCAN] [INFO] Total unique live IPs discovered: %d", len(uniqueIPs))
@@ -291,13 +457,41 @@ func discoverLiveIPs(scanID string, networkRanges []ConsolidatedNetworkRange) ([
 	return uniqueIPs, nil
 }
 
-// Check if a host is alive by trying to connect to common ports
-func isHostAlive(ip string, timeout time.Duration) bool {
+// rangeProbeIPs lists the addresses probed for a CIDR block, limited to
+// maxIPs: known record addresses inside it first, then the generated ones.
+// Wide IPv6 prefixes get sparse candidates around the known hosts.
+func rangeProbeIPs(cidr string, maxIPs int, known []netip.Addr) []string {
+	prefix, ok := parseRangePrefix(cidr)
+	if !ok {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Invalid CIDR %s", cidr)
+		return nil
+	}
+
+	var ips []string
+	if prefix.Addr().Is6() && prefix.Bits() < ipv6SweepBits {
+		ips = ipv6Candidates(prefix, known)
+	} else {
+		for _, addr := range known {
+			if prefix.Contains(addr) {
+				ips = append(ips, addr.String())
+			}
+		}
+		_, ipNet, _ := net.ParseCIDR(prefix.String())
+		ips = removeDuplicateIPs(append(ips, generateIPsFromCIDR(ipNet)...))
+	}
+	if len(ips) > maxIPs {
+		ips = ips[:maxIPs]
+	}
+	return ips
+}
+
+// Check if a host is alive by trying to connect to the discovery ports
+func isHostAlive(ip string, ports []int, timeout time.Duration) bool {
 	// Use the timeout directly per port - no division needed
 	// Each port gets the full timeout (1 second)
 
-	for _, port := range hostDiscoveryPorts {
-		address := fmt.Sprintf("%s:%d", ip, port)
+	for _, port := range ports {
+		address := net.JoinHostPort(ip, strconv.Itoa(port))
 		conn, err := net.DialTimeout("tcp", address, timeout)
 		if err == nil {
 			conn.Close()
@@ -314,10 +508,9 @@ func generateIPsFromCIDR(ipNet *net.IPNet) []string {
 	// Get the network address
 	ip := ipNet.IP
 
-	// Convert to 4-byte representation
+	// IPv6 prefixes are swept only when small; wider ones get sparse candidates
 	if ip.To4() == nil {
-		// IPv6 not supported for now
-		return ips
+		return generateIPv6FromCIDR(ipNet)
 	}
 
 	ip = ip.To4()
@@ -329,6 +522,17 @@ func generateIPsFromCIDR(ipNet *net.IPNet) []string {
 		return ips // Invalid mask
 	}
 
+	// /31 and /32 have no network or broadcast address to skip
+	if ones >= 31 {
+		for i := 0; i < 1<<(bits-ones); i++ {
+			newIP := make(net.IP, 4)
+			copy(newIP, ip)
+			newIP[3] += byte(i)
+			ips = append(ips, newIP.String())
+		}
+		return ips
+	}
+
 	// For large networks, limit to avoid memory issues
 	networkSize := 1 << (bits - ones)
 	maxIPs := 65536 // Limit to ~65k IPs max
@@ -358,9 +562,49 @@ func generateIPsFromCIDR(ipNet *net.IPNet) []string {
 	return ips
 }
 
-// Port scan live IPs for web services
-func discoverLiveWebServers(scanID string, liveIPs []string) ([]LiveWebServer, error) {
-	log.Printf("[IP-PORT-SCAN] [INFO] Starting port scanning for %d live IPs", len(liveIPs))
+// generateIPv6FromCIDR enumerates prefixes of /112 or smaller, skipping the
+// subnet-router anycast address, and returns sparse candidates for wider ones.
+func generateIPv6FromCIDR(ipNet *net.IPNet) []string {
+	ones, bits := ipNet.Mask.Size()
+	addr, ok := netip.AddrFromSlice(ipNet.IP.To16())
+	if !ok || bits != 128 {
+		return nil
+	}
+	prefix := netip.PrefixFrom(addr, ones).Masked()
+	if ones < ipv6SweepBits {
+		return ipv6Candidates(prefix, nil)
+	}
+
+	var ips []string
+	start := 1
+	if ones == 128 {
+		start = 0
+	}
+	hi, lo := addrHiLo(prefix.Addr())
+	for i := start; i < 1<<(bits-ones); i++ {
+		ips = append(ips, addrFromHiLo(hi, lo+uint64(i)).String())
+	}
+	return ips
+}
+
+// Port scan live IPs and identify what answers on each open port: web
+// services first, then banner and protocol fingerprints for the rest.
+// hostnames names the record a live IP came from, when it came from one.
+// IPs already checkpointed as scanned are skipped; closing stop pauses the
+// scan, returning the web servers this run found so far.
+func discoverLiveWebServers(scanID string, liveIPs []string, hostnames map[string]string, ports []int, stop <-chan struct{}) ([]LiveWebServer, error) {
+	_, portsScanned := loadScanLiveIPs(scanID)
+	var pending []string
+	for _, ip := range liveIPs {
+		if !portsScanned[ip] {
+			pending = append(pending, ip)
+		}
+	}
+	if skipped := len(liveIPs) - len(pending); skipped > 0 {
+		log.Printf("[IP-PORT-SCAN] [INFO] %d live IPs were port scanned before the scan was resumed", skipped)
+	}
+	liveIPs = pending
+	log.Printf("[IP-PORT-SCAN] [INFO] Starting port scanning for %d live IPs across %d ports", len(liveIPs), len(ports))
 
 	config := getDefaultScanConfig()
 	var allWebServers []LiveWebServer
@@ -377,23 +621,40 @@ func discoverLiveWebServers(scanID string, liveIPs []string) ([]LiveWebServer, e
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
+			if stopRequested(stop) {
+				return
+			}
 			log.Printf("[IP-PORT-SCAN] [DEBUG] Port scanning IP %d/%d: %s", idx+1, len(liveIPs), ipAddr)
 
-			// Scan web ports
-			openPorts := scanTCPPorts(ipAddr, webPorts, config.PortScanTimeout)
+			openPorts := scanTCPPorts(ipAddr, ports, config.PortScanTimeout)
 
-			// Check each open port for web services
+			// Fingerprint open ports in a bounded pool, so a host answering on
+			// every port does not cost a probe timeout per port in turn
+			var portWG sync.WaitGroup
+			fingerprints := make(chan struct{}, config.MaxFingerprints)
 			for _, port := range openPorts {
-				webServer := checkForWebService(scanID, ipAddr, port, config.WebServiceTimeout)
-				if webServer != nil {
-					mu.Lock()
-					allWebServers = append(allWebServers, *webServer)
-					mu.Unlock()
-
-					// Store in database
-					insertLiveWebServer(scanID, *webServer)
-				}
+				portWG.Add(1)
+				go func(p int) {
+					defer portWG.Done()
+					fingerprints <- struct{}{}        // Acquire
+					defer func() { <-fingerprints }() // Release
+
+					webServer, service := identifyOpenPort(scanID, ipAddr, p, config.WebServiceTimeout)
+					if webServer != nil {
+						webServer.Hostname = hostnames[ipAddr]
+						mu.Lock()
+						allWebServers = append(allWebServers, *webServer)
+						mu.Unlock()
+
+						// Store in database
+						insertLiveWebServer(scanID, *webServer)
+					}
+					service.Hostname = hostnames[ipAddr]
+					insertDiscoveredService(service)
+				}(port)
 			}
+			portWG.Wait()
+			completePortScanCheckpoint(scanID, ipAddr)
 
 		}(ipIdx, ip)
 	}
@@ -401,9 +662,40 @@ func discoverLiveWebServers(scanID string, liveIPs []string) ([]LiveWebServer, e
 	wg.Wait()
 
 	log.Printf("[IP-PORT-SCAN] [INFO] Total live web servers found: %d", len(allWebServers))
+	if stopRequested(stop) {
+		return allWebServers, errIPPortScanPaused
+	}
 	return allWebServers, nil
 }
 
+// identifyOpenPort asks HTTP first on the usual web ports and fingerprints
+// the rest, falling back to HTTP when nothing else recognises the port.
+func identifyOpenPort(scanID, ipAddr string, port int, timeout time.Duration) (*LiveWebServer, DiscoveredService) {
+	likelyWeb := false
+	for _, webPort := range webPorts {
+		if port == webPort {
+			likelyWeb = true
+			break
+		}
+	}
+	if hint, hinted := servicePortHints[port]; hinted && hint != "tls" {
+		likelyWeb = false
+	}
+
+	if likelyWeb {
+		if webServer := checkForWebService(scanID, ipAddr, port, timeout); webServer != nil {
+			return webServer, webService(*webServer)
+		}
+	}
+	service := fingerprintService(scanID, ipAddr, port)
+	if !likelyWeb && (service.Service == "unknown" || service.Service == "tls") {
+		if webServer := checkForWebService(scanID, ipAddr, port, timeout); webServer != nil {
+			return webServer, webService(*webServer)
+		}
+	}
+	return nil, service
+}
+
 // TCP port scanner using connect() method
 func scanTCPPorts(ip string, ports []int, timeout time.Duration) []int {
 	var openPorts []int
@@ -420,7 +712,7 @@ func scanTCPPorts(ip string, ports []int, timeout time.Duration) []int {
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
-			address := fmt.Sprintf("%s:%d", ip, p)
+			address := net.JoinHostPort(ip, strconv.Itoa(p))
 			conn, err := net.DialTimeout("tcp", address, timeout)
 			if err == nil {
 				conn.Close()
@@ -440,10 +732,10 @@ func checkForWebService(scanID, ipAddr string, port int, timeout time.Duration)
 	protocols := []string{"http", "https"}
 
 	for _, protocol := range protocols {
-		url := fmt.Sprintf("%s://%s:%d", protocol, ipAddr, port)
+		url := fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(ipAddr, strconv.Itoa(port)))
 
 		// Custom HTTP client with short timeout
-		client := &http.Client{
+		client := egressClient(EgressScopeIPPortScan, &http.Client{
 			Timeout: timeout,
 			Transport: &http.Transport{
 				TLSClientConfig: &tls.Config{
@@ -451,7 +743,7 @@ func checkForWebService(scanID, ipAddr string, port int, timeout time.Duration)
 				},
 				DisableKeepAlives: true,
 			},
-		}
+		})
 
 		startTime := time.Now()
 		req, err := http.NewRequest("GET", url, nil)
@@ -503,6 +795,13 @@ func checkForWebService(scanID, ipAddr string, port int, timeout time.Duration)
 			webServer.Technologies = technologies
 		}
 
+		// Classify any CDN/WAF in front from headers, cookies and the IP itself
+		webServer.EdgeClassification = classifyEdge(resp.Header, nil, ipAddr)
+
+		if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+			webServer.certificate = resp.TLS.PeerCertificates[0]
+		}
+
 		return webServer
 	}
 
@@ -631,9 +930,12 @@ func createIPPortScanTables() {
 func insertDiscoveredIP(scanID, ipAddress, networkRange string) {
 	// Resolve hostname for the IP address
 	hostname := resolveHostname(ipAddress)
+	origin := localIPASN(ipAddress)
 
-	query := `INSERT INTO discovered_live_ips (scan_id, ip_address, hostname, network_range) VALUES ($1, $2, $3, $4)`
-	_, err := dbPool.Exec(context.Background(), query, scanID, ipAddress, hostname, networkRange)
+	query := `INSERT INTO discovered_live_ips (scan_id, ip_address, hostname, network_range, asn_number, asn_organization, asn_country)
+			  VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))`
+	_, err := dbPool.Exec(context.Background(), query, scanID, ipAddress, hostname, networkRange,
+		origin.ASNNumber, origin.ASNOrganization, origin.ASNCountry)
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to insert discovered IP: %v", err)
 	} else if hostname != "" {
@@ -646,20 +948,30 @@ func insertLiveWebServer(scanID string, webServer LiveWebServer) {
 	if webServer.Hostname == "" {
 		webServer.Hostname = resolveHostname(webServer.IPAddress)
 	}
+	webServer.IPASN = localIPASN(webServer.IPAddress)
+	webServer.AddressFamily = addressFamily(webServer.IPAddress)
 
-	query := `INSERT INTO live_web_servers (scan_id, ip_address, hostname, port, protocol, url, status_code, title, server_header, content_length, technologies, response_time_ms) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	query := `INSERT INTO live_web_servers (scan_id, ip_address, hostname, port, protocol, url, status_code, title, server_header, content_length, technologies, response_time_ms,
+			  edge_provider, edge_category, waf_provider, edge_signals, edge_checked_at, asn_number, asn_organization, asn_country, address_family) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), $16, NOW(),
+			  NULLIF($17, ''), NULLIF($18, ''), NULLIF($19, ''), $20)
 			  ON CONFLICT (scan_id, ip_address, port, protocol) DO UPDATE SET
 			  hostname = EXCLUDED.hostname, status_code = EXCLUDED.status_code, title = EXCLUDED.title, server_header = EXCLUDED.server_header,
 			  content_length = EXCLUDED.content_length, technologies = EXCLUDED.technologies, 
-			  response_time_ms = EXCLUDED.response_time_ms, last_checked = NOW()`
+			  response_time_ms = EXCLUDED.response_time_ms, edge_provider = EXCLUDED.edge_provider, edge_category = EXCLUDED.edge_category,
+			  waf_provider = EXCLUDED.waf_provider, edge_signals = EXCLUDED.edge_signals, edge_checked_at = NOW(),
+			  asn_number = EXCLUDED.asn_number, asn_organization = EXCLUDED.asn_organization, asn_country = EXCLUDED.asn_country,
+			  address_family = EXCLUDED.address_family, last_checked = NOW()`
 
 	technologiesJSON, _ := json.Marshal(webServer.Technologies)
+	edgeSignalsJSON, _ := json.Marshal(webServer.Signals)
 
 	_, err := dbPool.Exec(context.Background(), query,
 		scanID, webServer.IPAddress, webServer.Hostname, webServer.Port, webServer.Protocol, webServer.URL,
 		webServer.StatusCode, webServer.Title, webServer.ServerHeader, webServer.ContentLength,
-		technologiesJSON, webServer.ResponseTime)
+		technologiesJSON, webServer.ResponseTime,
+		webServer.Provider, webServer.Category, webServer.WAF, edgeSignalsJSON,
+		webServer.ASNNumber, webServer.ASNOrganization, webServer.ASNCountry, webServer.AddressFamily)
 	if err != nil {
 		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to insert live web server: %v", err)
 	} else if webServer.Hostname != "" {
@@ -691,6 +1003,13 @@ func updateIPPortScanProgress(scanID string, status string, totalRanges, process
 	}
 }
 
+func updateIPPortScanServices(scanID string, servicesFound int) {
+	_, err := dbPool.Exec(context.Background(), `UPDATE ip_port_scans SET services_found = $1 WHERE scan_id = $2`, servicesFound, scanID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update services found: %v", err)
+	}
+}
+
 func updateIPPortScanExecutionTime(scanID, executionTime string) {
 	query := `UPDATE ip_port_scans SET execution_time = $1 WHERE scan_id = $2`
 	_, err := dbPool.Exec(context.Background(), query, executionTime, scanID)
@@ -699,6 +1018,17 @@ func updateIPPortScanExecutionTime(scanID, executionTime string) {
 	}
 }
 
+func addressFamily(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	switch {
+	case err != nil:
+		return ""
+	case addr.Unmap().Is4():
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
 func removeDuplicateIPs(ips []string) []string {
 	keys := make(map[string]bool)
 	var unique []string
@@ -716,15 +1046,7 @@ func removeDuplicateIPs(ips []string) []string {
 // Resolve hostname for an IP address with timeout
 func resolveHostname(ipAddr string) string {
 	// Set a timeout for DNS resolution
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 2 * time.Second, // 2 second timeout for DNS resolution
-			}
-			return d.DialContext(ctx, network, address)
-		},
-	}
+	resolver := PoolResolver(2 * time.Second)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -753,8 +1075,9 @@ func GetIPPortScanStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := `SELECT scan_id, scope_target_id, status, total_network_ranges, processed_network_ranges, 
-			  total_ips_discovered, total_ports_scanned, live_web_servers_found, error_message, 
-			  execution_time, created_at, auto_scan_session_id FROM ip_port_scans WHERE scan_id = $1`
+			  total_ips_discovered, total_ports_scanned, live_web_servers_found, COALESCE(services_found, 0),
+			  COALESCE(port_profile, ''), error_message, execution_time, created_at, auto_scan_session_id
+			  FROM ip_port_scans WHERE scan_id = $1`
 
 	var scan IPPortScan
 	var autoScanSessionID *string
@@ -763,7 +1086,7 @@ func GetIPPortScanStatus(w http.ResponseWriter, r *http.Request) {
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ScanID, &scan.ScopeTargetID, &scan.Status, &scan.TotalNetworkRanges,
 		&scan.ProcessedRanges, &scan.TotalIPsDiscovered, &scan.TotalPortsScanned,
-		&scan.LiveWebServersFound, &errorMessage, &executionTime,
+		&scan.LiveWebServersFound, &scan.ServicesFound, &scan.PortProfile, &errorMessage, &executionTime,
 		&scan.CreatedAt, &autoScanSessionID)
 
 	if err != nil {
@@ -798,7 +1121,10 @@ func GetLiveWebServers(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[IP-PORT-SCAN] [DEBUG] Fetching live web servers for scan ID: %s", scanID)
 
 	query := `SELECT scan_id, ip_address, hostname, port, protocol, url, status_code, title, 
-			  server_header, content_length, technologies, response_time_ms, last_checked 
+			  server_header, content_length, technologies, response_time_ms, last_checked,
+			  COALESCE(edge_provider, ''), COALESCE(edge_category, ''), COALESCE(waf_provider, ''), COALESCE(edge_blocking, false),
+			  COALESCE(asn_number, ''), COALESCE(asn_organization, ''), COALESCE(asn_country, ''),
+			  COALESCE(address_family, ''), COALESCE(stack_exposure, '')
 			  FROM live_web_servers WHERE scan_id = $1 ORDER BY ip_address, port`
 
 	rows, err := dbPool.Query(context.Background(), query, scanID)
@@ -818,7 +1144,10 @@ func GetLiveWebServers(w http.ResponseWriter, r *http.Request) {
 
 		err := rows.Scan(&ws.ScanID, &ipAddress, &hostname, &ws.Port, &ws.Protocol, &ws.URL,
 			&ws.StatusCode, &ws.Title, &ws.ServerHeader, &ws.ContentLength,
-			&technologiesJSON, &ws.ResponseTime, &ws.LastChecked)
+			&technologiesJSON, &ws.ResponseTime, &ws.LastChecked,
+			&ws.Provider, &ws.Category, &ws.WAF, &ws.Blocking,
+			&ws.ASNNumber, &ws.ASNOrganization, &ws.ASNCountry,
+			&ws.AddressFamily, &ws.StackExposure)
 		if err != nil {
 			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning web server row: %v", err)
 			continue
@@ -856,9 +1185,9 @@ func GetIPPortScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := `SELECT scan_id, scope_target_id, status, total_network_ranges, processed_network_ranges,
-			  total_ips_discovered, total_ports_scanned, live_web_servers_found, error_message,
-			  execution_time, created_at, auto_scan_session_id FROM ip_port_scans 
-			  WHERE scope_target_id = $1 ORDER BY created_at DESC`
+			  total_ips_discovered, total_ports_scanned, live_web_servers_found, COALESCE(services_found, 0),
+			  COALESCE(port_profile, ''), error_message, execution_time, created_at, auto_scan_session_id
+			  FROM ip_port_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
@@ -876,7 +1205,7 @@ func GetIPPortScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		var executionTime *string
 		err := rows.Scan(&scan.ScanID, &scan.ScopeTargetID, &scan.Status, &scan.TotalNetworkRanges,
 			&scan.ProcessedRanges, &scan.TotalIPsDiscovered, &scan.TotalPortsScanned,
-			&scan.LiveWebServersFound, &errorMessage, &executionTime,
+			&scan.LiveWebServersFound, &scan.ServicesFound, &scan.PortProfile, &errorMessage, &executionTime,
 			&scan.CreatedAt, &autoScanSessionID)
 		if err != nil {
 			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning IP/Port scan row: %v", err)
@@ -917,7 +1246,8 @@ func GetDiscoveredIPs(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[IP-PORT-SCAN] [DEBUG] Fetching discovered IPs for scan ID: %s", scanID)
 
-	query := `SELECT scan_id, ip_address, hostname, network_range, ping_time_ms, discovered_at 
+	query := `SELECT scan_id, ip_address, hostname, network_range, ping_time_ms, discovered_at,
+			  COALESCE(asn_number, ''), COALESCE(asn_organization, ''), COALESCE(asn_country, '')
 			  FROM discovered_live_ips WHERE scan_id = $1 ORDER BY ip_address`
 
 	rows, err := dbPool.Query(context.Background(), query, scanID)
@@ -934,7 +1264,8 @@ func GetDiscoveredIPs(w http.ResponseWriter, r *http.Request) {
 		var ipAddress net.IP
 		var hostname *string
 
-		err := rows.Scan(&ip.ScanID, &ipAddress, &hostname, &ip.NetworkRange, &ip.PingTime, &ip.DiscoveredAt)
+		err := rows.Scan(&ip.ScanID, &ipAddress, &hostname, &ip.NetworkRange, &ip.PingTime, &ip.DiscoveredAt,
+			&ip.ASNNumber, &ip.ASNOrganization, &ip.ASNCountry)
 		if err != nil {
 			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning discovered IP row: %v", err)
 			continue
@@ -0,0 +1,392 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"log"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+
+	"ars0n-framework-v2-server/iptrie"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IPv6 prefixes are far too large to sweep, so scans probe sparse candidates:
+// hosts already seen in AAAA records, their neighbours, and interface IDs
+// operators commonly hand out by hand.
+var ipv6LowByteIIDs = []uint64{
+	0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf, 0x10,
+	0x20, 0x53, 0x80, 0x100, 0x443, 0x1000, 0x8080,
+}
+
+const (
+	// ipv6SweepBits is the smallest prefix length enumerated address by address
+	ipv6SweepBits = 112
+	// ipv6CandidateSubnets is how many /64s of a wider prefix get low-byte candidates
+	ipv6CandidateSubnets = 16
+	ipv6NeighbourSpan    = 4
+	eui64NeighbourSpan   = 8
+)
+
+// addressRecordHost is a hostname and the addresses its A and AAAA records
+// resolved to.
+type addressRecordHost struct {
+	Hostname string
+	IPv4     []netip.Addr
+	IPv6     []netip.Addr
+}
+
+func (h *addressRecordHost) add(addr netip.Addr) {
+	addr = addr.Unmap()
+	list := &h.IPv6
+	if addr.Is4() {
+		list = &h.IPv4
+	}
+	for _, a := range *list {
+		if a == addr {
+			return
+		}
+	}
+	*list = append(*list, addr)
+}
+
+func (h *addressRecordHost) dualStack() bool {
+	return len(h.IPv4) > 0 && len(h.IPv6) > 0
+}
+
+// ipScanTargets is what an IP/port scan probes: the consolidated ranges plus
+// ranges added for address records they do not cover.
+type ipScanTargets struct {
+	ranges []ConsolidatedNetworkRange
+	// known are record addresses probed ahead of any generated candidates
+	known []netip.Addr
+	// hostnames maps record addresses to the hostname that resolved to them
+	hostnames map[string]string
+	dualStack []*addressRecordHost
+}
+
+// recordAddrs pulls every IP address out of a DNS record, which for Amass is
+// a whole "host (FQDN) --> aaaa_record --> addr (IPAddress)" line.
+func recordAddrs(record string) []netip.Addr {
+	var addrs []netip.Addr
+	for _, field := range strings.Fields(record) {
+		if addr, err := netip.ParseAddr(strings.Trim(field, "()[],")); err == nil {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+	return addrs
+}
+
+// collectAddressRecords gathers A and AAAA records for a scope target from
+// target URL lookups, DNSx, Amass Enum company scans and Amass scans, keyed by
+// lower-cased hostname.
+func collectAddressRecords(scopeTargetID string) (map[string]*addressRecordHost, error) {
+	hosts := make(map[string]*addressRecordHost)
+	add := func(hostname, record string) {
+		hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+		if hostname == "" {
+			return
+		}
+		for _, addr := range recordAddrs(record) {
+			host, ok := hosts[hostname]
+			if !ok {
+				host = &addressRecordHost{Hostname: hostname}
+				hosts[hostname] = host
+			}
+			host.add(addr)
+		}
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT url, COALESCE(dns_a_records, ARRAY[]::text[]), COALESCE(dns_aaaa_records, ARRAY[]::text[])
+		FROM target_urls WHERE scope_target_id = $1::uuid`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var rawURL string
+		var aRecords, aaaaRecords []string
+		if rows.Scan(&rawURL, &aRecords, &aaaaRecords) != nil {
+			continue
+		}
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		for _, record := range append(aRecords, aaaaRecords...) {
+			add(parsed.Hostname(), record)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, query := range []string{
+		`SELECT root_domain, record FROM dnsx_company_dns_records
+		 WHERE scope_target_id = $1::uuid AND record_type IN ('A', 'AAAA')`,
+		`SELECT root_domain, record FROM amass_enum_company_dns_records
+		 WHERE scope_target_id = $1::uuid AND record_type IN ('A', 'AAAA')`,
+		`SELECT split_part(dr.record, ' ', 1), dr.record FROM dns_records dr
+		 JOIN amass_scans ams ON dr.scan_id = ams.scan_id
+		 WHERE ams.scope_target_id = $1::uuid AND ams.status = 'success' AND dr.record_type IN ('A', 'AAAA')`,
+	} {
+		rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var hostname, record string
+			if rows.Scan(&hostname, &record) == nil {
+				add(hostname, record)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return hosts, nil
+}
+
+// collectIPv6Addresses lists the distinct IPv6 addresses of a scope target's
+// AAAA records, validated so they can be cast to INET.
+func collectIPv6Addresses(scopeTargetID string) ([]string, error) {
+	hosts, err := collectAddressRecords(scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[netip.Addr]bool)
+	addrs := []string{}
+	for _, host := range hosts {
+		for _, addr := range host.IPv6 {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr.String())
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// buildIPScanTargets adds to the consolidated ranges every IPv6 address from
+// AAAA records, and the IPv4 addresses of dual-stack hosts so both stacks get
+// compared. Addresses outside the consolidated ranges are probed on their own:
+// the rest of their /64 is only swept when a consolidated range covers it.
+func buildIPScanTargets(scopeTargetID string, consolidated []ConsolidatedNetworkRange) *ipScanTargets {
+	targets := &ipScanTargets{
+		ranges:    append([]ConsolidatedNetworkRange(nil), consolidated...),
+		hostnames: make(map[string]string),
+	}
+
+	hosts, err := collectAddressRecords(scopeTargetID)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [WARN] Failed to collect address records: %v", err)
+		return targets
+	}
+
+	var covered iptrie.Trie[bool]
+	for _, networkRange := range consolidated {
+		if prefix, ok := parseRangePrefix(networkRange.CIDRBlock); ok {
+			covered.Insert(prefix, true)
+		}
+	}
+	addTarget := func(host *addressRecordHost, addr netip.Addr) {
+		targets.known = append(targets.known, addr)
+		if _, ok := targets.hostnames[addr.String()]; !ok {
+			targets.hostnames[addr.String()] = host.Hostname
+		}
+		if _, _, ok := covered.Lookup(addr); ok {
+			return
+		}
+		prefix, source := netip.PrefixFrom(addr, addr.BitLen()), "dns_a"
+		if addr.Is6() {
+			source = "dns_aaaa"
+		}
+		covered.Insert(prefix, true)
+		targets.ranges = append(targets.ranges, ConsolidatedNetworkRange{
+			CIDRBlock:   prefix.String(),
+			Description: "Added for " + host.Hostname,
+			Source:      source,
+		})
+	}
+
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		host := hosts[name]
+		for _, addr := range host.IPv6 {
+			addTarget(host, addr)
+		}
+		if host.dualStack() {
+			for _, addr := range host.IPv4 {
+				addTarget(host, addr)
+			}
+			targets.dualStack = append(targets.dualStack, host)
+		}
+	}
+	return targets
+}
+
+func addrHiLo(addr netip.Addr) (uint64, uint64) {
+	b := addr.As16()
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+}
+
+func addrFromHiLo(hi, lo uint64) netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+	return netip.AddrFrom16(b)
+}
+
+// isEUI64 reports whether the interface ID was derived from a MAC address,
+// which puts ff:fe in its middle.
+func isEUI64(addr netip.Addr) bool {
+	b := addr.As16()
+	return b[11] == 0xff && b[12] == 0xfe
+}
+
+// ipv6Candidates lists the addresses worth probing in a large IPv6 prefix:
+// the known hosts inside it, their neighbours (the same low bytes give or
+// take a few, or for EUI-64 IDs the same vendor with nearby serials), then
+// low-byte interface IDs in every /64 a known host sits in and in the first
+// /64s of the prefix.
+func ipv6Candidates(prefix netip.Prefix, known []netip.Addr) []string {
+	var candidates []string
+	seen := make(map[netip.Addr]bool)
+	add := func(addr netip.Addr) {
+		if prefix.Contains(addr) && !seen[addr] {
+			seen[addr] = true
+			candidates = append(candidates, addr.String())
+		}
+	}
+
+	var inPrefix []netip.Addr
+	for _, addr := range known {
+		if addr.Is6() && prefix.Contains(addr) {
+			inPrefix = append(inPrefix, addr)
+			add(addr)
+		}
+	}
+
+	for _, addr := range inPrefix {
+		hi, lo := addrHiLo(addr)
+		for d := uint64(1); d <= ipv6NeighbourSpan; d++ {
+			if lo+d > lo {
+				add(addrFromHiLo(hi, lo+d))
+			}
+			if lo-d < lo {
+				add(addrFromHiLo(hi, lo-d))
+			}
+		}
+		if isEUI64(addr) {
+			nic := lo & 0xffffff
+			for d := uint64(1); d <= eui64NeighbourSpan; d++ {
+				if nic+d <= 0xffffff {
+					add(addrFromHiLo(hi, lo+d))
+				}
+				if nic >= d {
+					add(addrFromHiLo(hi, lo-d))
+				}
+			}
+		}
+	}
+
+	baseHi, baseLo := addrHiLo(prefix.Masked().Addr())
+	type subnet struct{ hi, lo uint64 }
+	// The /64s known hosts live in come first, as the likeliest to hold more
+	var subnets []subnet
+	if prefix.Bits() <= 64 {
+		for _, addr := range inPrefix {
+			hi, _ := addrHiLo(addr)
+			subnets = append(subnets, subnet{hi, 0})
+		}
+		count := uint64(ipv6CandidateSubnets)
+		if free := uint(64 - prefix.Bits()); free < 64 && uint64(1)<<free < count {
+			count = uint64(1) << free
+		}
+		for i := uint64(0); i < count; i++ {
+			subnets = append(subnets, subnet{baseHi + i, 0})
+		}
+	} else {
+		subnets = append(subnets, subnet{baseHi, baseLo})
+	}
+	for _, s := range subnets {
+		for _, iid := range ipv6LowByteIIDs {
+			add(addrFromHiLo(s.hi, s.lo+iid))
+		}
+	}
+	return candidates
+}
+
+// markStackExposure compares the web ports open on each address family of
+// dual-stack hosts. A port answering only over IPv6 usually means the IPv4
+// firewall rules were never mirrored.
+func markStackExposure(scanID string, servers []LiveWebServer, hosts []*addressRecordHost) {
+	if len(hosts) == 0 || len(servers) == 0 {
+		return
+	}
+
+	type endpoint struct {
+		addr netip.Addr
+		port int
+	}
+	open := make(map[endpoint]bool)
+	for _, server := range servers {
+		if addr, err := netip.ParseAddr(server.IPAddress); err == nil {
+			open[endpoint{addr.Unmap(), server.Port}] = true
+		}
+	}
+
+	exposure := make(map[endpoint]string)
+	for _, host := range hosts {
+		for _, stack := range [][2][]netip.Addr{{host.IPv4, host.IPv6}, {host.IPv6, host.IPv4}} {
+			for _, addr := range stack[0] {
+				for e := range open {
+					if e.addr != addr {
+						continue
+					}
+					otherOpen := false
+					for _, other := range stack[1] {
+						otherOpen = otherOpen || open[endpoint{other, e.port}]
+					}
+					switch {
+					case otherOpen:
+						exposure[e] = "dual_stack"
+					case exposure[e] == "" && addr.Is4():
+						exposure[e] = "ipv4_only"
+					case exposure[e] == "":
+						exposure[e] = "ipv6_only"
+					}
+				}
+			}
+		}
+	}
+
+	batch := &pgx.Batch{}
+	ipv6Only := 0
+	for e, value := range exposure {
+		if value == "ipv6_only" {
+			ipv6Only++
+		}
+		batch.Queue(`UPDATE live_web_servers SET stack_exposure = $1 WHERE scan_id = $2 AND ip_address = $3 AND port = $4`,
+			value, scanID, e.addr.String(), e.port)
+	}
+	if batch.Len() == 0 {
+		return
+	}
+	if err := dbPool.SendBatch(context.Background(), batch).Close(); err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to record stack exposure: %v", err)
+		return
+	}
+	if ipv6Only > 0 {
+		log.Printf("[IP-PORT-SCAN] [WARN] %d web ports on dual-stack hosts answer over IPv6 only", ipv6Only)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
@@ -53,9 +52,14 @@ type SubdomainizerScanStatus struct {
 }
 
 func RunGoSpiderScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "gospider") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string  `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
 		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
@@ -74,6 +78,10 @@ func RunGoSpiderScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	var insertQuery string
 	var args []interface{}
@@ -91,20 +99,20 @@ func RunGoSpiderScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go executeAndParseGoSpiderScan(scanID, domain)
+	RecordScanToolVersion("gospider_scans", scanID, "gospider")
+	go executeAndParseGoSpiderScan(scanID, domain, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func executeAndParseGoSpiderScan(scanID, domain string) {
+func executeAndParseGoSpiderScan(scanID, domain, requestProfileID string) {
 	log.Printf("[INFO] Starting GoSpider scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
-	// Get custom HTTP settings
-	customUserAgent, customHeader := GetCustomHTTPSettings()
-	log.Printf("[DEBUG] Custom User Agent: %s", customUserAgent)
-	log.Printf("[DEBUG] Custom Header: %s", customHeader)
+	var scopeTargetID string
+	dbPool.QueryRow(context.Background(), `SELECT scope_target_id FROM gospider_scans WHERE scan_id = $1`, scanID).Scan(&scopeTargetID)
+	profileHeaderArgs := RequestHeaderArgs("gospider", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))
 
 	var httpxResults string
 	err := dbPool.QueryRow(context.Background(), `
@@ -152,11 +160,7 @@ func executeAndParseGoSpiderScan(scanID, domain string) {
 		log.Printf("[INFO] Running GoSpider against URL: %s", httpxResult.URL)
 		scanStartTime := time.Now()
 
-		cmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-gospider-1",
-			"timeout", "300",
-			"gospider",
+		args := []string{
 			"-s", httpxResult.URL,
 			"-c", "10",
 			"-d", "3",
@@ -174,26 +178,18 @@ func executeAndParseGoSpiderScan(scanID, domain string) {
 			"--debug",
 			"--json",
 			"-v",
-		)
-
-		// Add custom user agent if specified
-		if customUserAgent != "" {
-			cmd.Args = append(cmd.Args, "--user-agent", customUserAgent)
 		}
 
-		// Add custom header if specified
-		if customHeader != "" {
-			cmd.Args = append(cmd.Args, "--header", customHeader)
-		}
-
-		commands = append(commands, cmd.String())
-		log.Printf("[DEBUG] Executing command: %s", cmd.String())
+		// Add request profile headers for this target
+		args = append(args, profileHeaderArgs...)
 
 		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+		execResult, err := ToolExecutor("gospider").Run(ctx, ExecRequest{Args: args, Stdout: &stdout, Stderr: &stderr})
+		cancel()
 
-		err := cmd.Run()
+		commands = append(commands, execResult.Command)
+		log.Printf("[DEBUG] Executed command: %s", execResult.Command)
 		scanDuration := time.Since(scanStartTime)
 		log.Printf("[DEBUG] GoSpider scan for %s completed in %s", httpxResult.URL, scanDuration)
 
@@ -344,7 +340,7 @@ func GetGoSpiderScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan GoSpiderScanStatus
-	query := `SELECT * FROM gospider_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM gospider_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -401,7 +397,7 @@ func GetGoSpiderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM gospider_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM gospider_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -455,6 +451,10 @@ func GetGoSpiderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunSubdomainizerScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "subdomainizer") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -493,6 +493,7 @@ func RunSubdomainizerScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("subdomainizer_scans", scanID, "subdomainizer")
 	go executeAndParseSubdomainizerScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -519,23 +520,15 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 		return
 	}
 
-	mkdirCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
-		"mkdir", "-p", "/tmp/subdomainizer-mounts",
-	)
-	if err := mkdirCmd.Run(); err != nil {
+	executor := ToolExecutor("subdomainizer")
+	mountDir := executor.Path("/tmp/subdomainizer-mounts")
+	if _, err := executor.Exec(context.Background(), ExecRequest{Args: []string{"mkdir", "-p", mountDir}}); err != nil {
 		log.Printf("[ERROR] Failed to create mount directory in container: %v", err)
 		updateSubdomainizerScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create mount directory: %v", err), "", time.Since(startTime).String(), "")
 		return
 	}
 
-	chmodCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
-		"chmod", "777", "/tmp/subdomainizer-mounts",
-	)
-	if err := chmodCmd.Run(); err != nil {
+	if _, err := executor.Exec(context.Background(), ExecRequest{Args: []string{"chmod", "777", mountDir}}); err != nil {
 		log.Printf("[ERROR] Failed to set permissions on mount directory: %v", err)
 		updateSubdomainizerScanStatus(scanID, "error", "", fmt.Sprintf("Failed to set permissions: %v", err), "", time.Since(startTime).String(), "")
 		return
@@ -568,45 +561,35 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 
 		log.Printf("[INFO] Running Subdomainizer against URL: %s", httpxResult.URL)
 
-		cmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-subdomainizer-1",
-			"timeout", "300",
-			"python3", "SubDomainizer.py",
-			"-u", httpxResult.URL,
-			"-k",
-			"-o", "/tmp/subdomainizer-mounts/output.txt",
-			"-sop", "/tmp/subdomainizer-mounts/secrets.txt",
-		)
-
-		commands = append(commands, cmd.String())
-		log.Printf("[INFO] Executing command: %s", cmd.String())
-
 		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+		execResult, err := executor.Run(ctx, ExecRequest{
+			Args: []string{
+				"-u", httpxResult.URL,
+				"-k",
+				"-o", mountDir + "/output.txt",
+				"-sop", mountDir + "/secrets.txt",
+			},
+			Stdout: &stdout,
+			Stderr: &stderr,
+		})
+		cancel()
 
-		err := cmd.Run()
+		commands = append(commands, execResult.Command)
+		log.Printf("[INFO] Executed command: %s", execResult.Command)
 		if err != nil {
 			log.Printf("[WARN] Subdomainizer scan failed for %s: %v", httpxResult.URL, err)
 			log.Printf("[WARN] stderr output: %s", stderr.String())
 			continue
 		}
 
-		catCmd := exec.Command(
-			"docker", "exec",
-			"ars0n-framework-v2-subdomainizer-1",
-			"cat", "/tmp/subdomainizer-mounts/output.txt",
-		)
-
-		var outputContent bytes.Buffer
-		catCmd.Stdout = &outputContent
-		if err := catCmd.Run(); err != nil {
+		outputContent, err := ReadToolFile(context.Background(), executor, "/tmp/subdomainizer-mounts/output.txt")
+		if err != nil {
 			log.Printf("[WARN] Failed to read output file for %s: %v", httpxResult.URL, err)
 			continue
 		}
 
-		lines := strings.Split(outputContent.String(), "\n")
+		lines := strings.Split(string(outputContent), "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
 			if line != "" && strings.Contains(line, domain) && !seen[line] {
@@ -635,12 +618,7 @@ func executeAndParseSubdomainizerScan(scanID, domain string) {
 		updateSubdomainizerScanStatus(scanID, "success", result, allStderr.String(), strings.Join(commands, "\n"), execTime, allStdout.String())
 	}
 
-	cleanupCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subdomainizer-1",
-		"rm", "-rf", "/tmp/subdomainizer-mounts",
-	)
-	if err := cleanupCmd.Run(); err != nil {
+	if _, err := executor.Exec(context.Background(), ExecRequest{Args: []string{"rm", "-rf", mountDir}}); err != nil {
 		log.Printf("[WARN] Failed to cleanup files in container: %v", err)
 	}
 
@@ -663,7 +641,7 @@ func GetSubdomainizerScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan SubdomainizerScanStatus
-	query := `SELECT * FROM subdomainizer_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM subdomainizer_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -720,7 +698,7 @@ func GetSubdomainizerScansForScopeTarget(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	query := `SELECT * FROM subdomainizer_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM subdomainizer_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
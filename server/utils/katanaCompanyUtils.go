@@ -1,13 +1,11 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -43,10 +41,15 @@ type KatanaCloudAsset struct {
 }
 
 func RunKatanaCompanyScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "katana") {
+		return
+	}
+
 	log.Printf("[KATANA-COMPANY] [INFO] Starting Katana Company scan request handling")
 	var payload struct {
 		Domains           []string `json:"domains" binding:"required"`
 		AutoScanSessionID *string  `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string   `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || len(payload.Domains) == 0 {
 		log.Printf("[KATANA-COMPANY] [ERROR] Invalid request body: %v", err)
@@ -71,6 +74,10 @@ func RunKatanaCompanyScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[KATANA-COMPANY] [INFO] Found company scope target: %s", scopeTarget)
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	log.Printf("[KATANA-COMPANY] [INFO] Generated new scan ID: %s", scanID)
 
@@ -104,14 +111,15 @@ func RunKatanaCompanyScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[KATANA-COMPANY] [INFO] Scan record verified in database with ID: %s", verifyID)
 
-	go ExecuteKatanaCompanyScan(scanID, payload.Domains, scopeTargetID)
+	RecordScanToolVersion("katana_company_scans", scanID, "katana")
+	go ExecuteKatanaCompanyScan(scanID, payload.Domains, scopeTargetID, payload.RequestProfileID)
 
 	log.Printf("[KATANA-COMPANY] [INFO] Katana Company scan initiated successfully, returning scan ID: %s", scanID)
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID string) {
+func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID, requestProfileID string) {
 	log.Printf("[KATANA-COMPANY] [INFO] Starting Katana Company scan execution (scan ID: %s) for %d domains", scanID, len(domains))
 	startTime := time.Now()
 
@@ -156,9 +164,7 @@ func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID str
 			targetURL = "https://" + domain
 		}
 
-		cmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-katana-1",
-			"katana",
+		katanaArgs := []string{
 			"-u", targetURL,
 			"-d", "3",
 			"-jc",
@@ -170,23 +176,20 @@ func ExecuteKatanaCompanyScan(scanID string, domains []string, scopeTargetID str
 			"-retry", "3",
 			"-rd", "1",
 			"-rl", "10",
-		)
-
-		commandsExecuted = append(commandsExecuted, cmd.String())
-		log.Printf("[KATANA-COMPANY] [INFO] Executing command: %s", cmd.String())
+		}
+		katanaArgs = append(katanaArgs, RequestHeaderArgs("katana", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
+		execResult, err := ToolExecutor("katana").Run(context.Background(), ExecRequest{Args: katanaArgs})
 
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		commandsExecuted = append(commandsExecuted, execResult.Command)
+		log.Printf("[KATANA-COMPANY] [INFO] Executed command: %s", execResult.Command)
 
-		err := cmd.Run()
 		if err != nil {
 			log.Printf("[KATANA-COMPANY] [ERROR] Katana scan failed for domain %s: %v", domain, err)
-			log.Printf("[KATANA-COMPANY] [ERROR] stderr output: %s", stderr.String())
+			log.Printf("[KATANA-COMPANY] [ERROR] stderr output: %s", execResult.Stderr)
 			continue
 		}
 
-		result := stdout.String()
+		result := execResult.Stdout
 		log.Printf("[KATANA-COMPANY] [INFO] Katana scan completed for domain %s", domain)
 		log.Printf("[KATANA-COMPANY] [DEBUG] Raw output length: %d bytes", len(result))
 
@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -10,13 +9,14 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"ars0n-framework-v2-server/domainutil"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5"
@@ -137,10 +137,15 @@ type HttpxScanConfig struct {
 
 // RunHttpxScan handles the HTTP request to start a new httpx scan
 func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "httpx") {
+		return
+	}
+
 	log.Printf("[DEBUG] Received httpx scan request")
 	var payload struct {
-		FQDN   string          `json:"fqdn" binding:"required"`
-		Config *HttpxScanConfig `json:"config"`
+		FQDN             string           `json:"fqdn" binding:"required"`
+		Config           *HttpxScanConfig `json:"config"`
+		RequestProfileID string           `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
 		log.Printf("[ERROR] Invalid request body: %v", err)
@@ -162,6 +167,10 @@ func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[DEBUG] Found scope target ID: %s", scopeTargetID)
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	log.Printf("[DEBUG] Generated new scan ID: %s", scanID)
 
@@ -174,7 +183,8 @@ func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[DEBUG] Created new scan record in database")
 
-	go ExecuteAndParseHttpxScan(scanID, domain, payload.Config)
+	RecordScanToolVersion("httpx_scans", scanID, "httpx")
+	go ExecuteAndParseHttpxScan(scanID, domain, payload.RequestProfileID, payload.Config)
 	log.Printf("[DEBUG] Started httpx scan execution in background")
 
 	w.WriteHeader(http.StatusAccepted)
@@ -183,17 +193,13 @@ func RunHttpxScan(w http.ResponseWriter, r *http.Request) {
 }
 
 // ExecuteAndParseHttpxScan runs the httpx scan and processes its results
-func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig) {
+func ExecuteAndParseHttpxScan(scanID, domain, requestProfileID string, scanConfig *HttpxScanConfig) {
 	log.Printf("[INFO] Starting httpx scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
 	rateLimit := GetHttpxRateLimit()
 	log.Printf("[INFO] Using rate limit of %d for HTTPX scan", rateLimit)
 
-	customUserAgent, customHeader := GetCustomHTTPSettings()
-	log.Printf("[DEBUG] Custom User Agent: %s", customUserAgent)
-	log.Printf("[DEBUG] Custom Header: %s", customHeader)
-
 	var scopeTargetID string
 	err := dbPool.QueryRow(context.Background(),
 		`SELECT scope_target_id FROM httpx_scans WHERE scan_id = $1`,
@@ -286,10 +292,7 @@ func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig
 	}
 	portsFlag := strings.Join(portStrings, ",")
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-httpx-1",
-		"httpx",
+	httpxArgs := []string{
 		"-l", filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID), "domains.txt"),
 		"-json",
 		"-no-color",
@@ -299,194 +302,194 @@ func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig
 	}
 
 	if len(ports) > 0 {
-		dockerCmd = append(dockerCmd, "-ports", portsFlag)
+		httpxArgs = append(httpxArgs, "-ports", portsFlag)
 	}
 
 	if threads > 0 {
-		dockerCmd = append(dockerCmd, "-t", fmt.Sprintf("%d", threads))
+		httpxArgs = append(httpxArgs, "-t", fmt.Sprintf("%d", threads))
 	}
 
 	if matchCodes != "" {
-		dockerCmd = append(dockerCmd, "-mc", matchCodes)
+		httpxArgs = append(httpxArgs, "-mc", matchCodes)
 	}
 
 	if scanConfig != nil && scanConfig.Probes != nil {
 		if val, ok := scanConfig.Probes["statusCode"]; ok && val {
-			dockerCmd = append(dockerCmd, "-status-code")
+			httpxArgs = append(httpxArgs, "-status-code")
 		}
 		if val, ok := scanConfig.Probes["title"]; ok && val {
-			dockerCmd = append(dockerCmd, "-title")
+			httpxArgs = append(httpxArgs, "-title")
 		}
 		if val, ok := scanConfig.Probes["techDetect"]; ok && val {
-			dockerCmd = append(dockerCmd, "-tech-detect")
+			httpxArgs = append(httpxArgs, "-tech-detect")
 		}
 		if val, ok := scanConfig.Probes["server"]; ok && val {
-			dockerCmd = append(dockerCmd, "-server")
+			httpxArgs = append(httpxArgs, "-server")
 		}
 		if val, ok := scanConfig.Probes["contentLength"]; ok && val {
-			dockerCmd = append(dockerCmd, "-content-length")
+			httpxArgs = append(httpxArgs, "-content-length")
 		}
 		if val, ok := scanConfig.Probes["location"]; ok && val {
-			dockerCmd = append(dockerCmd, "-location")
+			httpxArgs = append(httpxArgs, "-location")
 		}
 		if val, ok := scanConfig.Probes["favicon"]; ok && val {
-			dockerCmd = append(dockerCmd, "-favicon")
+			httpxArgs = append(httpxArgs, "-favicon")
 		}
 		if val, ok := scanConfig.Probes["jarm"]; ok && val {
-			dockerCmd = append(dockerCmd, "-jarm")
+			httpxArgs = append(httpxArgs, "-jarm")
 		}
 		if val, ok := scanConfig.Probes["responseTime"]; ok && val {
-			dockerCmd = append(dockerCmd, "-response-time")
+			httpxArgs = append(httpxArgs, "-response-time")
 		}
 		if val, ok := scanConfig.Probes["lineCount"]; ok && val {
-			dockerCmd = append(dockerCmd, "-line-count")
+			httpxArgs = append(httpxArgs, "-line-count")
 		}
 		if val, ok := scanConfig.Probes["wordCount"]; ok && val {
-			dockerCmd = append(dockerCmd, "-word-count")
+			httpxArgs = append(httpxArgs, "-word-count")
 		}
 		if val, ok := scanConfig.Probes["method"]; ok && val {
-			dockerCmd = append(dockerCmd, "-method")
+			httpxArgs = append(httpxArgs, "-method")
 		}
 		if val, ok := scanConfig.Probes["websocket"]; ok && val {
-			dockerCmd = append(dockerCmd, "-websocket")
+			httpxArgs = append(httpxArgs, "-websocket")
 		}
 		if val, ok := scanConfig.Probes["ip"]; ok && val {
-			dockerCmd = append(dockerCmd, "-ip")
+			httpxArgs = append(httpxArgs, "-ip")
 		}
 		if val, ok := scanConfig.Probes["cname"]; ok && val {
-			dockerCmd = append(dockerCmd, "-cname")
+			httpxArgs = append(httpxArgs, "-cname")
 		}
 		if val, ok := scanConfig.Probes["asn"]; ok && val {
-			dockerCmd = append(dockerCmd, "-asn")
+			httpxArgs = append(httpxArgs, "-asn")
 		}
 		if val, ok := scanConfig.Probes["probe"]; ok && val {
-			dockerCmd = append(dockerCmd, "-probe")
+			httpxArgs = append(httpxArgs, "-probe")
 		}
 	} else {
-		dockerCmd = append(dockerCmd, "-status-code", "-title", "-tech-detect", "-server", "-content-length")
+		httpxArgs = append(httpxArgs, "-status-code", "-title", "-tech-detect", "-server", "-content-length")
 	}
 
 	if scanConfig != nil {
 		if scanConfig.Filters != nil {
 			if val, ok := scanConfig.Filters["filterCode"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fc", val)
+				httpxArgs = append(httpxArgs, "-fc", val)
 			}
 			if val, ok := scanConfig.Filters["filterLength"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fl", val)
+				httpxArgs = append(httpxArgs, "-fl", val)
 			}
 			if val, ok := scanConfig.Filters["filterLineCount"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-flc", val)
+				httpxArgs = append(httpxArgs, "-flc", val)
 			}
 			if val, ok := scanConfig.Filters["filterWordCount"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fwc", val)
+				httpxArgs = append(httpxArgs, "-fwc", val)
 			}
 			if val, ok := scanConfig.Filters["filterString"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fs", val)
+				httpxArgs = append(httpxArgs, "-fs", val)
 			}
 			if val, ok := scanConfig.Filters["filterRegex"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fe", val)
+				httpxArgs = append(httpxArgs, "-fe", val)
 			}
 			if val, ok := scanConfig.Filters["filterCdn"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fcdn", val)
+				httpxArgs = append(httpxArgs, "-fcdn", val)
 			}
 			if val, ok := scanConfig.Filters["filterResponseTime"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-frt", val)
+				httpxArgs = append(httpxArgs, "-frt", val)
 			}
 			if val, ok := scanConfig.Filters["filterCondition"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-fdc", val)
+				httpxArgs = append(httpxArgs, "-fdc", val)
 			}
 			if val, ok := scanConfig.Filters["filterDuplicates"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-fd")
+				httpxArgs = append(httpxArgs, "-fd")
 			}
 		}
 
 		if scanConfig.Matchers != nil {
 			if val, ok := scanConfig.Matchers["matchLength"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-ml", val)
+				httpxArgs = append(httpxArgs, "-ml", val)
 			}
 			if val, ok := scanConfig.Matchers["matchLineCount"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mlc", val)
+				httpxArgs = append(httpxArgs, "-mlc", val)
 			}
 			if val, ok := scanConfig.Matchers["matchWordCount"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mwc", val)
+				httpxArgs = append(httpxArgs, "-mwc", val)
 			}
 			if val, ok := scanConfig.Matchers["matchString"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-ms", val)
+				httpxArgs = append(httpxArgs, "-ms", val)
 			}
 			if val, ok := scanConfig.Matchers["matchRegex"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mr", val)
+				httpxArgs = append(httpxArgs, "-mr", val)
 			}
 			if val, ok := scanConfig.Matchers["matchCdn"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mcdn", val)
+				httpxArgs = append(httpxArgs, "-mcdn", val)
 			}
 			if val, ok := scanConfig.Matchers["matchResponseTime"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mrt", val)
+				httpxArgs = append(httpxArgs, "-mrt", val)
 			}
 			if val, ok := scanConfig.Matchers["matchCondition"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mdc", val)
+				httpxArgs = append(httpxArgs, "-mdc", val)
 			}
 			if val, ok := scanConfig.Matchers["matchFavicon"]; ok && val != "" {
-				dockerCmd = append(dockerCmd, "-mfc", val)
+				httpxArgs = append(httpxArgs, "-mfc", val)
 			}
 		}
 
 		if scanConfig.Misc != nil {
 			if val, ok := scanConfig.Misc["followRedirects"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-follow-redirects")
+				httpxArgs = append(httpxArgs, "-follow-redirects")
 			}
 			if val, ok := scanConfig.Misc["maxRedirects"].(float64); ok && int(val) != 10 {
-				dockerCmd = append(dockerCmd, "-max-redirects", fmt.Sprintf("%d", int(val)))
+				httpxArgs = append(httpxArgs, "-max-redirects", fmt.Sprintf("%d", int(val)))
 			}
 			if val, ok := scanConfig.Misc["probeAllIps"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-probe-all-ips")
+				httpxArgs = append(httpxArgs, "-probe-all-ips")
 			}
 			if val, ok := scanConfig.Misc["tlsProbe"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-tls-probe")
+				httpxArgs = append(httpxArgs, "-tls-probe")
 			}
 			if val, ok := scanConfig.Misc["cspProbe"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-csp-probe")
+				httpxArgs = append(httpxArgs, "-csp-probe")
 			}
 			if val, ok := scanConfig.Misc["tlsGrab"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-tls-grab")
+				httpxArgs = append(httpxArgs, "-tls-grab")
 			}
 			if val, ok := scanConfig.Misc["pipeline"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-pipeline")
+				httpxArgs = append(httpxArgs, "-pipeline")
 			}
 			if val, ok := scanConfig.Misc["http2"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-http2")
+				httpxArgs = append(httpxArgs, "-http2")
 			}
 			if val, ok := scanConfig.Misc["vhost"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-vhost")
+				httpxArgs = append(httpxArgs, "-vhost")
 			}
 			if val, ok := scanConfig.Misc["noFallback"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-no-fallback")
+				httpxArgs = append(httpxArgs, "-no-fallback")
 			}
 			if val, ok := scanConfig.Misc["noFallbackScheme"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-no-fallback-scheme")
+				httpxArgs = append(httpxArgs, "-no-fallback-scheme")
 			}
 			if val, ok := scanConfig.Misc["maxHostError"].(float64); ok && int(val) != 30 {
-				dockerCmd = append(dockerCmd, "-max-host-error", fmt.Sprintf("%d", int(val)))
+				httpxArgs = append(httpxArgs, "-max-host-error", fmt.Sprintf("%d", int(val)))
 			}
 		}
 
 		if scanConfig.Headless != nil {
 			if val, ok := scanConfig.Headless["screenshot"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-screenshot")
+				httpxArgs = append(httpxArgs, "-screenshot")
 				if st, ok := scanConfig.Headless["screenshotTimeout"].(float64); ok && int(st) != 10 {
-					dockerCmd = append(dockerCmd, "-screenshot-timeout", fmt.Sprintf("%d", int(st)))
+					httpxArgs = append(httpxArgs, "-screenshot-timeout", fmt.Sprintf("%d", int(st)))
 				}
 			}
 		}
 
 		if scanConfig.Extractors != nil {
 			if val, ok := scanConfig.Extractors["extractRegex"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-extract-regex", val)
+				httpxArgs = append(httpxArgs, "-extract-regex", val)
 			}
 			if val, ok := scanConfig.Extractors["extractPreset"].(string); ok && val != "" {
-				dockerCmd = append(dockerCmd, "-extract-preset", val)
+				httpxArgs = append(httpxArgs, "-extract-preset", val)
 			}
 			if val, ok := scanConfig.Extractors["extractFqdn"].(bool); ok && val {
-				dockerCmd = append(dockerCmd, "-extract-fqdn")
+				httpxArgs = append(httpxArgs, "-extract-fqdn")
 			}
 		}
 
@@ -494,55 +497,45 @@ func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig
 			for _, header := range strings.Split(scanConfig.CustomHeaders, "\n") {
 				header = strings.TrimSpace(header)
 				if header != "" {
-					dockerCmd = append(dockerCmd, "-H", header)
+					httpxArgs = append(httpxArgs, "-H", header)
 				}
 			}
 		}
 
 		if scanConfig.HttpProxy != "" {
-			dockerCmd = append(dockerCmd, "-http-proxy", scanConfig.HttpProxy)
+			httpxArgs = append(httpxArgs, "-http-proxy", scanConfig.HttpProxy)
 		}
 
 		if scanConfig.Resolvers != "" {
-			dockerCmd = append(dockerCmd, "-resolvers", scanConfig.Resolvers)
+			httpxArgs = append(httpxArgs, "-resolvers", scanConfig.Resolvers)
 		}
 
 		if scanConfig.Path != "" {
-			dockerCmd = append(dockerCmd, "-path", scanConfig.Path)
+			httpxArgs = append(httpxArgs, "-path", scanConfig.Path)
 		}
 
 		if scanConfig.RequestMethods != "" {
-			dockerCmd = append(dockerCmd, "-x", scanConfig.RequestMethods)
+			httpxArgs = append(httpxArgs, "-x", scanConfig.RequestMethods)
 		}
 
 		if scanConfig.Body != "" {
-			dockerCmd = append(dockerCmd, "-body", scanConfig.Body)
+			httpxArgs = append(httpxArgs, "-body", scanConfig.Body)
 		}
 	}
 
-	if customUserAgent != "" {
-		dockerCmd = append(dockerCmd, "-H", fmt.Sprintf("User-Agent: %s", customUserAgent))
-	}
-	if customHeader != "" {
-		dockerCmd = append(dockerCmd, "-H", customHeader)
-	}
+	httpxArgs = append(httpxArgs, RequestHeaderArgs("httpx", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
 
-	dockerCmd = append(dockerCmd, "-o", filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID), "httpx-output.json"))
+	httpxArgs = append(httpxArgs, "-o", filepath.Join("/tmp", fmt.Sprintf("httpx-%s", scanID), "httpx-output.json"))
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	log.Printf("[DEBUG] Running command: %s", strings.Join(dockerCmd, " "))
-	err = cmd.Run()
+	execResult, err := ToolExecutor("httpx").Run(context.Background(), ExecRequest{Args: httpxArgs})
+	log.Printf("[DEBUG] Ran command: %s", execResult.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
-		errMsg := stderr.String()
+		errMsg := execResult.Stderr
 		log.Printf("[ERROR] httpx scan failed for %s: %v\nStderr: %s", domain, err, errMsg)
-		log.Printf("[DEBUG] Command stdout: %s", stdout.String())
-		UpdateHttpxScanStatus(scanID, "error", "", errMsg, strings.Join(dockerCmd, " "), execTime)
+		log.Printf("[DEBUG] Command stdout: %s", execResult.Stdout)
+		UpdateHttpxScanStatus(scanID, "error", "", errMsg, execResult.Command, execTime)
 		return
 	}
 	log.Printf("[DEBUG] httpx scan completed successfully in %s", execTime)
@@ -552,14 +545,14 @@ func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig
 	result, err := os.ReadFile(outputFile)
 	if err != nil {
 		log.Printf("[ERROR] Failed to read output file: %v", err)
-		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), strings.Join(dockerCmd, " "), execTime)
+		UpdateHttpxScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), execResult.Command, execTime)
 		return
 	}
 
 	resultStr := string(result)
 	if resultStr == "" {
 		log.Printf("[INFO] No results found in output file")
-		UpdateHttpxScanStatus(scanID, "completed", "", "No results found", strings.Join(dockerCmd, " "), execTime)
+		UpdateHttpxScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
 		return
 	}
 	// Process results and update target URLs
@@ -590,7 +583,7 @@ func ExecuteAndParseHttpxScan(scanID, domain string, scanConfig *HttpxScanConfig
 		log.Printf("[WARN] Failed to mark old target URLs as no longer live: %v", err)
 	}
 
-	UpdateHttpxScanStatus(scanID, "success", resultStr, stderr.String(), strings.Join(dockerCmd, " "), execTime)
+	UpdateHttpxScanStatus(scanID, "success", resultStr, execResult.Stderr, execResult.Command, execTime)
 	log.Printf("[INFO] HTTPX scan completed in %s", execTime)
 }
 
@@ -729,6 +722,15 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 	}
 	log.Printf("[INFO] Base domain for consolidation: %s", baseDomain)
 
+	// inScope normalizes a tool result and drops anything outside the base domain
+	inScope := func(host string) string {
+		subdomain, err := domainutil.Normalize(host)
+		if err != nil || !domainutil.IsSubdomainOf(subdomain, baseDomain) {
+			return ""
+		}
+		return subdomain
+	}
+
 	uniqueSubdomains := make(map[string]bool)
 	toolResults := make(map[string]int)
 
@@ -758,7 +760,7 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 				log.Printf("[ERROR] Failed to scan Amass subdomain: %v", err)
 				continue
 			}
-			if strings.HasSuffix(subdomain, baseDomain) {
+			if subdomain = inScope(subdomain); subdomain != "" {
 				if !uniqueSubdomains[subdomain] {
 					log.Printf("[DEBUG] Found new subdomain from amass: %s", subdomain)
 					count++
@@ -860,6 +862,18 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 				LIMIT 1`,
 			table: "shuffledns_custom",
 		},
+		{
+			query: `
+				SELECT result 
+				FROM subdomain_permutation_scans 
+				WHERE scope_target_id = $1 
+					AND status = 'success' 
+					AND result IS NOT NULL 
+					AND result != '' 
+				ORDER BY created_at DESC 
+				LIMIT 1`,
+			table: "subdomain_permutation",
+		},
 		{
 			query: `
 				SELECT result 
@@ -884,6 +898,16 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 				LIMIT 1`,
 			table: "subdomainizer",
 		},
+		{
+			query: `
+				SELECT string_agg(result, E'\n')
+				FROM imported_scans
+				WHERE scope_target_id = $1
+					AND format = 'subdomains'
+					AND status = 'success'
+					AND result IS NOT NULL`,
+			table: "imported",
+		},
 	}
 
 	for _, q := range queries {
@@ -927,8 +951,7 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 					log.Printf("[ERROR] Failed to parse URL %s: %v", gauResult.URL, err)
 					continue
 				}
-				hostname := parsedURL.Hostname()
-				if strings.HasSuffix(hostname, baseDomain) {
+				if hostname := inScope(parsedURL.Hostname()); hostname != "" {
 					if !uniqueSubdomains[hostname] {
 						log.Printf("[DEBUG] Found new subdomain from GAU: %s", hostname)
 						count++
@@ -944,7 +967,7 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 				if subdomain == "" {
 					continue
 				}
-				if strings.HasSuffix(subdomain, baseDomain) {
+				if subdomain = inScope(subdomain); subdomain != "" {
 					if !uniqueSubdomains[subdomain] {
 						log.Printf("[DEBUG] Found new subdomain from %s: %s", q.table, subdomain)
 						count++
@@ -957,6 +980,31 @@ func ConsolidateSubdomains(scopeTargetID string) ([]string, error) {
 		log.Printf("[INFO] Found %d new unique subdomains from %s", count, q.table)
 	}
 
+	// Names harvested from TLS certificate SANs
+	certRows, err := dbPool.Query(context.Background(),
+		`SELECT name FROM certificate_names WHERE scope_target_id = $1 AND classification = 'in_scope'`,
+		scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get certificate names: %v", err)
+	} else {
+		count := 0
+		for certRows.Next() {
+			var name string
+			if certRows.Scan(&name) != nil {
+				continue
+			}
+			if subdomain := inScope(name); subdomain != "" {
+				if !uniqueSubdomains[subdomain] {
+					count++
+				}
+				uniqueSubdomains[subdomain] = true
+			}
+		}
+		certRows.Close()
+		toolResults["tls_certificates"] = count
+		log.Printf("[INFO] Found %d new unique subdomains from tls_certificates", count)
+	}
+
 	var consolidatedSubdomains []string
 	for subdomain := range uniqueSubdomains {
 		consolidatedSubdomains = append(consolidatedSubdomains, subdomain)
@@ -1242,7 +1290,22 @@ func GetTargetURLsForScopeTarget(w http.ResponseWriter, r *http.Request) {
 			dns_srv_records,
 			roi_score,
 			created_at,
-			screenshot
+			screenshot,
+			COALESCE(edge_provider, ''),
+			COALESCE(edge_category, ''),
+			COALESCE(waf_provider, ''),
+			COALESCE(edge_blocking, false),
+			edge_checked_at IS NOT NULL,
+			(SELECT COUNT(DISTINCT tv.cve_id) FROM technology_vulnerabilities tv
+				WHERE tv.asset_kind = 'target_url' AND tv.asset_id = target_urls.id),
+			(SELECT COALESCE(MAX(c.cvss_score), 0)::float8 FROM technology_vulnerabilities tv
+				JOIN cve_entries c ON c.cve_id = tv.cve_id
+				WHERE tv.asset_kind = 'target_url' AND tv.asset_id = target_urls.id),
+			(SELECT COUNT(DISTINCT tv.cve_id) FROM technology_vulnerabilities tv
+				JOIN cisa_kev k ON k.cve_id = tv.cve_id
+				WHERE tv.asset_kind = 'target_url' AND tv.asset_id = target_urls.id),
+			favicon_hash,
+			COALESCE(favicon_product, '')
 		FROM target_urls 
 		WHERE scope_target_id = $1 
 		ORDER BY roi_score DESC, created_at DESC`
@@ -1288,6 +1351,16 @@ func GetTargetURLsForScopeTarget(w http.ResponseWriter, r *http.Request) {
 			roiScore            float64
 			createdAt           time.Time
 			screenshot          sql.NullString
+			edgeProvider        string
+			edgeCategory        string
+			wafProvider         string
+			edgeBlocking        bool
+			edgeChecked         bool
+			vulnCount           int
+			maxCVSS             float64
+			kevCount            int
+			faviconHash         *int32
+			faviconProduct      string
 		)
 
 		err := rows.Scan(
@@ -1321,6 +1394,16 @@ func GetTargetURLsForScopeTarget(w http.ResponseWriter, r *http.Request) {
 			&roiScore,
 			&createdAt,
 			&screenshot,
+			&edgeProvider,
+			&edgeCategory,
+			&wafProvider,
+			&edgeBlocking,
+			&edgeChecked,
+			&vulnCount,
+			&maxCVSS,
+			&kevCount,
+			&faviconHash,
+			&faviconProduct,
 		)
 		if err != nil {
 			log.Printf("[ERROR] Failed to scan row: %v", err)
@@ -1358,6 +1441,16 @@ func GetTargetURLsForScopeTarget(w http.ResponseWriter, r *http.Request) {
 			"roi_score":              roiScore,
 			"created_at":             createdAt.Format(time.RFC3339),
 			"screenshot":             nullStringToString(screenshot),
+			"edge_provider":          edgeProvider,
+			"edge_category":          edgeCategory,
+			"waf_provider":           wafProvider,
+			"edge_blocking":          edgeBlocking,
+			"edge_checked":           edgeChecked,
+			"vuln_count":             vulnCount,
+			"max_cvss":               maxCVSS,
+			"kev_count":              kevCount,
+			"favicon_hash":           faviconHash,
+			"favicon_product":        faviconProduct,
 		}
 
 		targetURLs = append(targetURLs, targetURL)
@@ -1624,7 +1717,7 @@ func ConsolidateCompanyDomains(scopeTargetID string) ([]string, error) {
 			if err := liveRows.Scan(&url); err == nil {
 				// Extract domain from URL
 				if url != "" {
-					if domain := extractDomainFromURLInConsolidation(url); domain != "" && !isIPv4AddressInConsolidation(domain) {
+					if domain := domainutil.DomainFromURL(url); domain != "" {
 						if _, exists := domainMap[domain]; !exists {
 							domainMap[domain] = "live_web_servers"
 						}
@@ -1634,6 +1727,32 @@ func ConsolidateCompanyDomains(scopeTargetID string) ([]string, error) {
 		}
 	}
 
+	// 9. Get domains from TLS certificates issued to the company
+	log.Printf("[INFO] Fetching TLS certificate domains...")
+	certDomains, err := GetTLSCertificateDomainsForTool(scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get TLS certificate domains: %v", err)
+	}
+	for _, domain := range certDomains {
+		if _, exists := domainMap[domain]; !exists {
+			domainMap[domain] = "tls_certificates"
+		}
+	}
+
+	// Normalize case, trailing dots and IDNs so the same domain from two tools is stored once
+	normalizedMap := make(map[string]string)
+	for domain, source := range domainMap {
+		normalized, err := domainutil.Normalize(domain)
+		if err != nil {
+			log.Printf("[WARN] Skipping invalid company domain %q: %v", domain, err)
+			continue
+		}
+		if _, exists := normalizedMap[normalized]; !exists {
+			normalizedMap[normalized] = source
+		}
+	}
+	domainMap = normalizedMap
+
 	// Convert to sorted slice
 	var consolidatedDomains []string
 	for domain := range domainMap {
@@ -1719,28 +1838,3 @@ func GetConsolidatedCompanyDomains(w http.ResponseWriter, r *http.Request) {
 		"domains": domains,
 	})
 }
-
-// Helper functions for live web server domain extraction in consolidation
-func isIPv4AddressInConsolidation(s string) bool {
-	return strings.Contains(s, ".") &&
-		len(strings.Split(s, ".")) == 4 &&
-		!strings.Contains(s, " ")
-}
-
-func extractDomainFromURLInConsolidation(urlStr string) string {
-	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-		urlStr = "http://" + urlStr
-	}
-
-	// Simple domain extraction from URL
-	parts := strings.Split(urlStr, "/")
-	if len(parts) >= 3 {
-		hostPart := parts[2]
-		// Remove port if present
-		if colonIndex := strings.Index(hostPart, ":"); colonIndex != -1 {
-			hostPart = hostPart[:colonIndex]
-		}
-		return hostPart
-	}
-	return ""
-}
@@ -0,0 +1,586 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Proxy history formats accepted by the manual crawl importer.
+const (
+	historyFormatHAR  = "har"
+	historyFormatBurp = "burp"
+	historyFormatZAP  = "zap"
+)
+
+// manualCrawlImportBodyLimit caps the request and response bodies kept per capture.
+const manualCrawlImportBodyLimit = 64 * 1024
+
+// manualCrawlStaticExtensions are skipped unless static files are requested,
+// matching the browser extension's default.
+var manualCrawlStaticExtensions = []string{".css", ".js", ".jpg", ".jpeg", ".png", ".gif", ".svg", ".ico", ".woff", ".woff2", ".ttf", ".eot"}
+
+// historyEntry is one request/response pair from an imported proxy history.
+type historyEntry struct {
+	Method          string
+	URL             string
+	RequestHeaders  http.Header
+	RequestBody     string
+	StatusCode      int
+	ResponseHeaders http.Header
+	ResponseBody    string
+	MimeType        string
+	Timestamp       time.Time
+}
+
+// ImportManualCrawlHistory loads a HAR file or a Burp Suite or ZAP proxy
+// history export into a new manual crawl session, so traffic collected
+// outside the browser extension is consolidated like a live capture.
+func ImportManualCrawlHistory(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["scope_target_id"]
+	if scopeTargetID == "" {
+		http.Error(w, "scope_target_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var scopeTargetExists bool
+	err := dbPool.QueryRow(context.Background(),
+		"SELECT EXISTS(SELECT 1 FROM scope_targets WHERE id = $1)", scopeTargetID).Scan(&scopeTargetExists)
+	if err != nil || !scopeTargetExists {
+		http.Error(w, "Invalid scope_target_id - target not found", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(100 << 20); err != nil {
+		log.Printf("[MANUAL-CRAWL] Error parsing import form: %v", err)
+		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(r.FormValue("format")))
+	if format == "" {
+		format = detectHistoryFormat(data)
+	}
+
+	var entries []historyEntry
+	switch format {
+	case historyFormatHAR:
+		entries, err = parseHARHistory(data)
+	case historyFormatBurp:
+		entries, err = parseBurpHistory(data)
+	case historyFormatZAP:
+		entries, err = parseZAPHistory(data)
+	default:
+		http.Error(w, "Could not detect the file format; set format to har, burp or zap", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error parsing %s history %s: %v", format, header.Filename, err)
+		http.Error(w, fmt.Sprintf("Failed to parse %s file: %v", format, err), http.StatusBadRequest)
+		return
+	}
+
+	label := strings.TrimSpace(r.FormValue("label"))
+	includeStatic := r.FormValue("include_static") == "true"
+	includeSubdomains := r.FormValue("include_subdomains") != "false"
+	targetDomain := extractDomainFromScopeTarget(scopeTargetID)
+
+	captures := make([]CaptureRequest, 0, len(entries))
+	timestamps := make([]time.Time, 0, len(entries))
+	skipped := 0
+	for _, entry := range entries {
+		if !shouldImportHistoryEntry(entry, targetDomain, includeSubdomains, includeStatic) {
+			skipped++
+			continue
+		}
+		captures = append(captures, historyEntryCapture(entry))
+		timestamps = append(timestamps, entry.Timestamp)
+	}
+	if len(captures) == 0 {
+		http.Error(w, fmt.Sprintf("No in-scope requests found in %s file (%d skipped)", format, skipped), http.StatusBadRequest)
+		return
+	}
+
+	startedAt, endedAt := timestamps[0], timestamps[0]
+	for _, timestamp := range timestamps {
+		if timestamp.Before(startedAt) {
+			startedAt = timestamp
+		}
+		if timestamp.After(endedAt) {
+			endedAt = timestamp
+		}
+	}
+	targetURL := captures[0].URL
+	if parsed, err := url.Parse(targetURL); err == nil {
+		targetURL = parsed.Scheme + "://" + parsed.Host
+	}
+
+	sessionID := uuid.New().String()
+	_, err = dbPool.Exec(context.Background(), `
+		INSERT INTO manual_crawl_sessions (id, scope_target_id, target_url, label, status, started_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`, sessionID, scopeTargetID, targetURL, label, "importing", startedAt)
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error creating import session: %v", err)
+		http.Error(w, "Failed to create capture session", http.StatusInternalServerError)
+		return
+	}
+
+	stored := 0
+	endpoints := make(map[string]bool)
+	for i, capture := range captures {
+		if _, err := insertManualCrawlCapture(sessionID, scopeTargetID, capture, timestamps[i]); err != nil {
+			log.Printf("[MANUAL-CRAWL] Error storing imported capture %s: %v", capture.URL, err)
+			continue
+		}
+		stored++
+		endpoints[capture.Method+":"+capture.Endpoint] = true
+	}
+
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE manual_crawl_sessions
+		SET status = $1, ended_at = $2, request_count = $3, endpoint_count = $4
+		WHERE id = $5`, "imported", endedAt, stored, len(endpoints), sessionID)
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error updating import session: %v", err)
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[MANUAL-CRAWL] Imported %d requests (%d endpoints, %d skipped) from %s history %s into session %s",
+		stored, len(endpoints), skipped, format, header.Filename, sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"sessionId":     sessionID,
+		"scopeTargetId": scopeTargetID,
+		"format":        format,
+		"label":         label,
+		"requestCount":  stored,
+		"endpointCount": len(endpoints),
+		"skipped":       skipped,
+	})
+}
+
+func detectHistoryFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return historyFormatHAR
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("<items")):
+		return historyFormatBurp
+	case zapMessageSeparator.Match(trimmed):
+		return historyFormatZAP
+	}
+	return ""
+}
+
+// shouldImportHistoryEntry applies the extension's capture rules: same host as
+// the target (or a subdomain of it), and no static files unless requested.
+func shouldImportHistoryEntry(entry historyEntry, targetDomain string, includeSubdomains, includeStatic bool) bool {
+	parsed, err := url.Parse(entry.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return false
+	}
+	if targetDomain != "" {
+		host := strings.ToLower(parsed.Hostname())
+		domain := strings.ToLower(strings.TrimPrefix(targetDomain, "*."))
+		if includeSubdomains && !domainutil.IsSubdomainOf(host, domain) {
+			return false
+		}
+		if !includeSubdomains && host != domain {
+			return false
+		}
+	}
+	if !includeStatic {
+		path := strings.ToLower(parsed.Path)
+		for _, extension := range manualCrawlStaticExtensions {
+			if strings.HasSuffix(path, extension) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var (
+	numericSegmentPattern  = regexp.MustCompile(`/\d+`)
+	uuidSegmentPattern     = regexp.MustCompile(`(?i)/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	objectIDSegmentPattern = regexp.MustCompile(`(?i)/[0-9a-f]{24}`)
+)
+
+// manualCrawlEndpoint templates a URL the way the browser extension does:
+// numeric, UUID and object ID path segments become placeholders and query
+// values are dropped.
+func manualCrawlEndpoint(parsed *url.URL) string {
+	endpoint := numericSegmentPattern.ReplaceAllString(parsed.Path, "/{id}")
+	endpoint = uuidSegmentPattern.ReplaceAllString(endpoint, "/{uuid}")
+	endpoint = objectIDSegmentPattern.ReplaceAllString(endpoint, "/{objectid}")
+
+	var params []string
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(name); err == nil && name != "" {
+			params = append(params, name+"={value}")
+		}
+	}
+	if len(params) > 0 {
+		endpoint += "?" + strings.Join(params, "&")
+	}
+	return endpoint
+}
+
+// paramsMap flattens url.Values the way the extension does: a single value
+// stays a string and repeated names become a list.
+func paramsMap(values url.Values) map[string]interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	params := make(map[string]interface{}, len(values))
+	for name, list := range values {
+		if len(list) == 1 {
+			params[name] = list[0]
+		} else {
+			params[name] = list
+		}
+	}
+	return params
+}
+
+func headersMap(header http.Header) map[string]interface{} {
+	headers := make(map[string]interface{}, len(header))
+	for name, values := range header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// historyEntryCapture converts an imported entry to the capture the browser
+// extension would have sent for it, including the extracted parameters.
+func historyEntryCapture(entry historyEntry) CaptureRequest {
+	parsed, _ := url.Parse(entry.URL)
+	bodyType := entry.RequestHeaders.Get("Content-Type")
+	capture := CaptureRequest{
+		URL:             entry.URL,
+		Endpoint:        manualCrawlEndpoint(parsed),
+		Method:          strings.ToUpper(entry.Method),
+		StatusCode:      entry.StatusCode,
+		Headers:         headersMap(entry.RequestHeaders),
+		ResponseHeaders: headersMap(entry.ResponseHeaders),
+		PostData:        entry.RequestBody,
+		ResponseBody:    entry.ResponseBody,
+		GetParams:       paramsMap(parsed.Query()),
+		BodyType:        bodyType,
+		MimeType:        entry.MimeType,
+	}
+	if capture.MimeType == "" {
+		capture.MimeType = "unknown"
+	}
+
+	if entry.RequestBody != "" {
+		switch {
+		case strings.Contains(bodyType, "application/json"):
+			var postParams map[string]interface{}
+			if json.Unmarshal([]byte(entry.RequestBody), &postParams) == nil {
+				capture.PostParams = postParams
+			}
+		case strings.Contains(bodyType, "application/x-www-form-urlencoded"):
+			if values, err := url.ParseQuery(entry.RequestBody); err == nil {
+				capture.PostParams = paramsMap(values)
+			}
+		}
+	}
+	return capture
+}
+
+// historyBody keeps a message body only when it is text, capped in size.
+func historyBody(body []byte) string {
+	if len(body) > manualCrawlImportBodyLimit {
+		body = body[:manualCrawlImportBodyLimit]
+	}
+	if !utf8.Valid(body) {
+		return ""
+	}
+	return string(body)
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime string `json:"startedDateTime"`
+			Request         struct {
+				Method   string      `json:"method"`
+				URL      string      `json:"url"`
+				Headers  []harHeader `json:"headers"`
+				PostData *struct {
+					MimeType string      `json:"mimeType"`
+					Text     string      `json:"text"`
+					Params   []harHeader `json:"params"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status  int         `json:"status"`
+				Headers []harHeader `json:"headers"`
+				Content struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func harHeaders(list []harHeader) http.Header {
+	header := make(http.Header)
+	for _, h := range list {
+		// HTTP/2 pseudo-headers such as :authority are not request headers
+		if h.Name == "" || strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	return header
+}
+
+// parseHARHistory reads an HTTP Archive, as exported by browsers, Burp Suite
+// and ZAP.
+func parseHARHistory(data []byte) ([]historyEntry, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %v", err)
+	}
+
+	entries := make([]historyEntry, 0, len(har.Log.Entries))
+	for _, e := range har.Log.Entries {
+		entry := historyEntry{
+			Method:          e.Request.Method,
+			URL:             e.Request.URL,
+			RequestHeaders:  harHeaders(e.Request.Headers),
+			StatusCode:      e.Response.Status,
+			ResponseHeaders: harHeaders(e.Response.Headers),
+			MimeType:        e.Response.Content.MimeType,
+		}
+		if timestamp, err := time.Parse(time.RFC3339, e.StartedDateTime); err == nil {
+			entry.Timestamp = timestamp
+		} else {
+			entry.Timestamp = time.Now()
+		}
+		if postData := e.Request.PostData; postData != nil {
+			entry.RequestBody = postData.Text
+			if entry.RequestBody == "" && len(postData.Params) > 0 {
+				values := url.Values{}
+				for _, param := range postData.Params {
+					values.Add(param.Name, param.Value)
+				}
+				entry.RequestBody = values.Encode()
+			}
+			if entry.RequestHeaders.Get("Content-Type") == "" && postData.MimeType != "" {
+				entry.RequestHeaders.Set("Content-Type", postData.MimeType)
+			}
+			entry.RequestBody = historyBody([]byte(entry.RequestBody))
+		}
+		body := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			body, _ = base64.StdEncoding.DecodeString(e.Response.Content.Text)
+		}
+		entry.ResponseBody = historyBody(body)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type burpHistory struct {
+	Items []struct {
+		Time     string      `xml:"time"`
+		URL      string      `xml:"url"`
+		Method   string      `xml:"method"`
+		Status   string      `xml:"status"`
+		MimeType string      `xml:"mimetype"`
+		Request  burpMessage `xml:"request"`
+		Response burpMessage `xml:"response"`
+	} `xml:"item"`
+}
+
+// burpTimeLayouts are the formats Burp writes item times in.
+var burpTimeLayouts = []string{"Mon Jan 02 15:04:05 MST 2006", "Mon Jan 2 15:04:05 MST 2006"}
+
+// parseBurpHistory reads a Burp Suite "Save items" XML export of proxy
+// history, with requests and responses stored raw or base64 encoded.
+func parseBurpHistory(data []byte) ([]historyEntry, error) {
+	var history burpHistory
+	if err := xml.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("invalid Burp XML: %v", err)
+	}
+
+	entries := make([]historyEntry, 0, len(history.Items))
+	for _, item := range history.Items {
+		entry, err := rawHistoryEntry(item.Request.raw(), item.Response.raw(), item.URL)
+		if err != nil {
+			log.Printf("[MANUAL-CRAWL] Skipping Burp item %s: %v", item.URL, err)
+			continue
+		}
+		if entry.Method == "" {
+			entry.Method = item.Method
+		}
+		if entry.StatusCode == 0 {
+			entry.StatusCode, _ = strconv.Atoi(item.Status)
+		}
+		if entry.MimeType == "" {
+			entry.MimeType = item.MimeType
+		}
+		entry.Timestamp = time.Now()
+		for _, layout := range burpTimeLayouts {
+			if timestamp, err := time.Parse(layout, item.Time); err == nil {
+				entry.Timestamp = timestamp
+				break
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// raw returns the whole message; unlike text it is not truncated, because the
+// request has to be parsed.
+func (m burpMessage) raw() string {
+	if !m.Base64 {
+		return m.Body
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(m.Body))
+	if err != nil {
+		return ""
+	}
+	return string(decoded)
+}
+
+var (
+	zapMessageSeparator = regexp.MustCompile(`(?m)^==== \d+ ==========\s*$`)
+	zapResponseStart    = regexp.MustCompile(`(?m)^HTTP/\d(\.\d)? \d{3}`)
+)
+
+// parseZAPHistory reads ZAP's "Export Messages to File" text format: raw
+// requests and responses, one pair per numbered section.
+func parseZAPHistory(data []byte) ([]historyEntry, error) {
+	sections := zapMessageSeparator.Split(string(data), -1)
+	entries := make([]historyEntry, 0, len(sections))
+	for _, section := range sections {
+		section = strings.TrimLeft(section, "\r\n")
+		if section == "" {
+			continue
+		}
+		rawRequest, rawResponse := section, ""
+		headerEnd := strings.Index(section, "\n\n")
+		if crlf := strings.Index(section, "\r\n\r\n"); crlf >= 0 && (headerEnd < 0 || crlf < headerEnd) {
+			headerEnd = crlf
+		}
+		if headerEnd >= 0 {
+			if loc := zapResponseStart.FindStringIndex(section[headerEnd:]); loc != nil {
+				rawRequest = section[:headerEnd+loc[0]]
+				rawResponse = section[headerEnd+loc[0]:]
+			}
+		}
+		entry, err := rawHistoryEntry(rawRequest, rawResponse, "")
+		if err != nil {
+			log.Printf("[MANUAL-CRAWL] Skipping ZAP message: %v", err)
+			continue
+		}
+		entry.Timestamp = time.Now()
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no messages found")
+	}
+	return entries, nil
+}
+
+// normalizeHTTPVersion rewrites an HTTP/2 or HTTP/3 start line as HTTP/1.1,
+// which is all net/http will parse from raw text; the framing is the same.
+func normalizeHTTPVersion(raw string) string {
+	line, rest, _ := strings.Cut(raw, "\n")
+	for _, version := range []string{"HTTP/2", "HTTP/3"} {
+		if strings.HasPrefix(line, version+" ") {
+			line = "HTTP/1.1" + strings.TrimPrefix(line, version)
+		} else if trimmed := strings.TrimRight(line, "\r"); strings.HasSuffix(trimmed, " "+version) {
+			line = strings.TrimSuffix(trimmed, version) + "HTTP/1.1" + line[len(trimmed):]
+		}
+	}
+	return line + "\n" + rest
+}
+
+// rawHistoryEntry parses a raw request and response. fallbackURL is used
+// when the request line has only a path, as Burp writes it.
+func rawHistoryEntry(rawRequest, rawResponse, fallbackURL string) (historyEntry, error) {
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(normalizeHTTPVersion(rawRequest))))
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("invalid request: %v", err)
+	}
+	requestBody, _ := io.ReadAll(req.Body)
+
+	entry := historyEntry{
+		Method:         req.Method,
+		URL:            fallbackURL,
+		RequestHeaders: req.Header,
+		RequestBody:    historyBody(requestBody),
+	}
+	if entry.URL == "" {
+		requestURL := *req.URL
+		if !requestURL.IsAbs() {
+			requestURL.Scheme = "http"
+			requestURL.Host = req.Host
+		}
+		entry.URL = requestURL.String()
+	}
+	if req.Host != "" && entry.RequestHeaders.Get("Host") == "" {
+		entry.RequestHeaders.Set("Host", req.Host)
+	}
+
+	entry.ResponseHeaders = make(http.Header)
+	if strings.TrimSpace(rawResponse) != "" {
+		resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(normalizeHTTPVersion(rawResponse))), req)
+		if err == nil {
+			responseBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			entry.StatusCode = resp.StatusCode
+			entry.ResponseHeaders = resp.Header
+			entry.ResponseBody = historyBody(responseBody)
+			entry.MimeType = resp.Header.Get("Content-Type")
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testHistoryUsers and testHistoryLogin are the pairs every fixture in testdata
+// holds in some form: an HTTP/2 JSON request and a form login that redirects.
+var (
+	testHistoryUsers = historyEntry{
+		Method:          "GET",
+		URL:             "https://app.example.com/api/users?id=7",
+		RequestHeaders:  http.Header{"Host": {"app.example.com"}, "Accept": {"application/json"}},
+		StatusCode:      200,
+		ResponseHeaders: http.Header{"Content-Type": {"application/json"}, "Content-Length": {"8"}},
+		ResponseBody:    `{"id":7}`,
+		MimeType:        "application/json",
+	}
+	testHistoryLogin = historyEntry{
+		Method: "POST",
+		URL:    "https://app.example.com/login",
+		RequestHeaders: http.Header{
+			"Host":           {"app.example.com"},
+			"Content-Type":   {"application/x-www-form-urlencoded"},
+			"Content-Length": {"17"},
+		},
+		RequestBody:     "user=admin&pass=x",
+		StatusCode:      302,
+		ResponseHeaders: http.Header{"Location": {"/home"}, "Content-Length": {"0"}},
+	}
+)
+
+func readHistoryFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkHistoryEntries compares entries without their timestamps, which are
+// checked separately because some formats fall back to the import time.
+func checkHistoryEntries(t *testing.T, got []historyEntry, want []historyEntry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("parsed %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		entry := got[i]
+		entry.Timestamp = time.Time{}
+		if !reflect.DeepEqual(entry, want[i]) {
+			t.Errorf("entry %d = %+v\nwant %+v", i, entry, want[i])
+		}
+	}
+}
+
+func TestParseHARHistory(t *testing.T) {
+	data := readHistoryFixture(t, "history.har")
+	if format := detectHistoryFormat(data); format != historyFormatHAR {
+		t.Errorf("detectHistoryFormat() = %q, want %q", format, historyFormatHAR)
+	}
+	entries, err := parseHARHistory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := testHistoryUsers
+	// HTTP/2 pseudo-headers such as :authority are dropped, so there is no Host
+	users.RequestHeaders = http.Header{"Accept": {"application/json"}, "Cookie": {"sid=abc"}}
+	users.ResponseHeaders = http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"a=1", "b=2"}}
+	checkHistoryEntries(t, entries, []historyEntry{
+		users,
+		{
+			Method:          "POST",
+			URL:             "https://app.example.com/login",
+			RequestHeaders:  http.Header{"Host": {"app.example.com"}, "Content-Type": {"application/x-www-form-urlencoded"}},
+			RequestBody:     "next=%2Fhome+page&user=admin",
+			StatusCode:      302,
+			ResponseHeaders: http.Header{"Location": {"/home"}},
+		},
+		{
+			Method:          "GET",
+			URL:             "https://app.example.com/",
+			RequestHeaders:  http.Header{},
+			StatusCode:      200,
+			ResponseHeaders: http.Header{"Content-Type": {"text/html"}},
+			ResponseBody:    "<html>ok</html>",
+			MimeType:        "text/html",
+		},
+		{
+			// A base64 body that is not UTF-8 is not kept
+			Method:          "GET",
+			URL:             "https://app.example.com/logo.png",
+			RequestHeaders:  http.Header{},
+			StatusCode:      200,
+			ResponseHeaders: http.Header{"Content-Type": {"image/png"}},
+			MimeType:        "image/png",
+		},
+	})
+
+	wantTimes := []time.Time{
+		time.Date(2024, 3, 1, 10, 15, 30, 123e6, time.UTC),
+		time.Date(2024, 3, 1, 9, 15, 31, 0, time.UTC),
+		time.Date(2024, 3, 1, 10, 15, 32, 0, time.UTC),
+	}
+	for i, want := range wantTimes {
+		if !entries[i].Timestamp.Equal(want) {
+			t.Errorf("entry %d timestamp = %v, want %v", i, entries[i].Timestamp, want)
+		}
+	}
+	if entries[3].Timestamp.IsZero() {
+		t.Error("an unparseable startedDateTime left a zero timestamp")
+	}
+
+	if _, err := parseHARHistory([]byte("{")); err == nil {
+		t.Error("parseHARHistory accepted truncated JSON")
+	}
+}
+
+func TestParseBurpHistory(t *testing.T) {
+	data := readHistoryFixture(t, "burp-history.xml")
+	if format := detectHistoryFormat(data); format != historyFormatBurp {
+		t.Errorf("detectHistoryFormat() = %q, want %q", format, historyFormatBurp)
+	}
+	// The third item has an empty request and is skipped
+	entries, err := parseBurpHistory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHistoryEntries(t, entries, []historyEntry{testHistoryUsers, testHistoryLogin})
+
+	// Burp writes the day of the month both padded and unpadded
+	wantTimes := []time.Time{
+		time.Date(2024, 3, 1, 10, 15, 30, 0, time.UTC),
+		time.Date(2024, 3, 1, 10, 16, 0, 0, time.UTC),
+	}
+	for i, want := range wantTimes {
+		if !entries[i].Timestamp.Equal(want) {
+			t.Errorf("entry %d timestamp = %v, want %v", i, entries[i].Timestamp, want)
+		}
+	}
+}
+
+func TestParseZAPHistory(t *testing.T) {
+	data := readHistoryFixture(t, "zap-history.txt")
+	if format := detectHistoryFormat(data); format != historyFormatZAP {
+		t.Errorf("detectHistoryFormat() = %q, want %q", format, historyFormatZAP)
+	}
+	entries, err := parseZAPHistory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHistoryEntries(t, entries, []historyEntry{
+		testHistoryUsers,
+		testHistoryLogin,
+		{
+			// A request with no response
+			Method:          "GET",
+			URL:             "http://app.example.com/health",
+			RequestHeaders:  http.Header{"Host": {"app.example.com"}},
+			ResponseHeaders: http.Header{},
+		},
+	})
+	for i, entry := range entries {
+		if entry.Timestamp.IsZero() {
+			t.Errorf("entry %d has a zero timestamp", i)
+		}
+	}
+
+	if _, err := parseZAPHistory([]byte("==== 1 ==========\nnot a request\n")); err == nil {
+		t.Error("parseZAPHistory accepted a file with no valid messages")
+	}
+}
+
+func TestNormalizeHTTPVersion(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"GET / HTTP/2\r\nHost: a\r\n\r\n", "GET / HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{"GET / HTTP/3\nHost: a\n", "GET / HTTP/1.1\nHost: a\n"},
+		{"HTTP/2 200\r\n\r\n", "HTTP/1.1 200\r\n\r\n"},
+		{"HTTP/3 404 Not Found\r\n", "HTTP/1.1 404 Not Found\r\n"},
+		{"GET / HTTP/1.1\r\nX-Version: HTTP/2\r\n", "GET / HTTP/1.1\r\nX-Version: HTTP/2\r\n"},
+		{"HTTP/1.0 200 OK\r\n", "HTTP/1.0 200 OK\r\n"},
+		{"GET /HTTP/2 HTTP/1.1\r\n", "GET /HTTP/2 HTTP/1.1\r\n"},
+	}
+	for _, tt := range tests {
+		if got := normalizeHTTPVersion(tt.raw); got != tt.want {
+			t.Errorf("normalizeHTTPVersion(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package utils
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,14 +38,18 @@ type ManualCrawlSession struct {
 	ID            string    `json:"id"`
 	ScopeTargetID string    `json:"scope_target_id"`
 	TargetURL     string    `json:"target_url"`
+	Label         string    `json:"label,omitempty"`
 	Status        string    `json:"status"`
 	StartedAt     time.Time `json:"started_at"`
 	EndedAt       *time.Time `json:"ended_at,omitempty"`
 	RequestCount  int       `json:"request_count"`
 	EndpointCount int       `json:"endpoint_count"`
+	CaptureToken  string    `json:"-"`
 }
 
 type CaptureRequest struct {
+	CaptureToken    string                 `json:"captureToken,omitempty"`
+	ScopeTargetID   string                 `json:"scopeTargetId,omitempty"`
 	URL             string                 `json:"url"`
 	Endpoint        string                 `json:"endpoint"`
 	Method          string                 `json:"method"`
@@ -61,16 +68,69 @@ type CaptureRequest struct {
 type StartCaptureRequest struct {
 	TargetURL     string `json:"targetUrl"`
 	ScopeTargetID string `json:"scopeTargetId"`
+	Label         string `json:"label,omitempty"`
 }
 
 type StatsRequest struct {
+	CaptureToken string `json:"captureToken,omitempty"`
 	Stats struct {
 		RequestCount  int `json:"requestCount"`
 		EndpointCount int `json:"endpointCount"`
 	} `json:"stats"`
 }
 
-var activeManualCrawlSession *ManualCrawlSession
+// Active capture sessions, keyed by the capture token StartManualCrawl issues.
+// Each browser capturing at the same time holds its own token, so captures
+// always land in the session that started them.
+var activeManualCrawlSessions sync.Map
+
+// manualCrawlStaleAfter is how long a session may go without a capture
+// before cleanup ends it; sessions that never captured anything end sooner.
+const (
+	manualCrawlStaleAfter      = time.Hour
+	manualCrawlEmptyStaleAfter = 5 * time.Minute
+)
+
+func newCaptureToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// requestCaptureToken reads the capture token from the X-Capture-Token header,
+// falling back to the one in the request body.
+func requestCaptureToken(r *http.Request, bodyToken string) string {
+	if token := strings.TrimSpace(r.Header.Get("X-Capture-Token")); token != "" {
+		return token
+	}
+	return strings.TrimSpace(bodyToken)
+}
+
+// lookupManualCrawlSession finds the active session for a capture token. A
+// token unknown to this process is looked up in the database, so sessions
+// survive a server restart.
+func lookupManualCrawlSession(token string) (*ManualCrawlSession, bool) {
+	if token == "" {
+		return nil, false
+	}
+	if session, ok := activeManualCrawlSessions.Load(token); ok {
+		return session.(*ManualCrawlSession), true
+	}
+
+	session := &ManualCrawlSession{CaptureToken: token}
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT id, scope_target_id, target_url, COALESCE(label, ''), status, started_at
+		FROM manual_crawl_sessions
+		WHERE capture_token = $1 AND status = 'active'`, token).Scan(
+		&session.ID, &session.ScopeTargetID, &session.TargetURL, &session.Label, &session.Status, &session.StartedAt)
+	if err != nil {
+		return nil, false
+	}
+	loaded, _ := activeManualCrawlSessions.LoadOrStore(token, session)
+	return loaded.(*ManualCrawlSession), true
+}
 
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -114,46 +174,52 @@ func StartManualCrawl(w http.ResponseWriter, r *http.Request) {
 
 	scopeTargetID := req.ScopeTargetID
 	sessionID := uuid.New().String()
+	captureToken, err := newCaptureToken()
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error generating capture token: %v", err)
+		http.Error(w, "Failed to create capture session", http.StatusInternalServerError)
+		return
+	}
+	label := strings.TrimSpace(req.Label)
 	
 	query := `
-		INSERT INTO manual_crawl_sessions (id, scope_target_id, target_url, status, started_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO manual_crawl_sessions (id, scope_target_id, target_url, label, status, started_at, capture_token)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
 	`
 	
-	_, err = dbPool.Exec(context.Background(), query, sessionID, scopeTargetID, req.TargetURL, "active", time.Now())
+	_, err = dbPool.Exec(context.Background(), query, sessionID, scopeTargetID, req.TargetURL, label, "active", time.Now(), captureToken)
 	if err != nil {
 		log.Printf("[MANUAL-CRAWL] Error creating session: %v", err)
 		http.Error(w, "Failed to create capture session", http.StatusInternalServerError)
 		return
 	}
 
-	activeManualCrawlSession = &ManualCrawlSession{
+	activeManualCrawlSessions.Store(captureToken, &ManualCrawlSession{
 		ID:            sessionID,
 		ScopeTargetID: scopeTargetID,
 		TargetURL:     req.TargetURL,
+		Label:         label,
 		Status:        "active",
 		StartedAt:     time.Now(),
 		RequestCount:  0,
 		EndpointCount: 0,
-	}
+		CaptureToken:  captureToken,
+	})
 
-	log.Printf("[MANUAL-CRAWL] Started session %s for target %s", sessionID, req.TargetURL)
+	log.Printf("[MANUAL-CRAWL] Started session %s (%s) for target %s", sessionID, label, req.TargetURL)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":   true,
 		"sessionId": sessionID,
 		"scopeTargetId": scopeTargetID,
+		"captureToken": captureToken,
+		"label": label,
 	})
 }
 
 func CaptureManualCrawlRequest(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if activeManualCrawlSession == nil {
-		http.Error(w, "No active capture session", http.StatusBadRequest)
-		return
-	}
-
 	var req CaptureRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("[MANUAL-CRAWL] Error decoding capture request: %v", err)
@@ -161,30 +227,76 @@ func CaptureManualCrawlRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	token := requestCaptureToken(r, req.CaptureToken)
+	if token == "" {
+		http.Error(w, "captureToken is required", http.StatusUnauthorized)
+		return
+	}
+	session, ok := lookupManualCrawlSession(token)
+	if !ok {
+		http.Error(w, "No active capture session for this token", http.StatusBadRequest)
+		return
+	}
+	if req.ScopeTargetID != "" && req.ScopeTargetID != session.ScopeTargetID {
+		http.Error(w, "Capture token does not belong to this scope target", http.StatusForbidden)
+		return
+	}
+
+	// Touching the session row first means a concurrent cleanup either sees
+	// this capture or has already ended the session, never half of each.
+	result, err := dbPool.Exec(context.Background(),
+		"UPDATE manual_crawl_sessions SET last_capture_at = NOW() WHERE id = $1 AND status = 'active'", session.ID)
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error updating session %s: %v", session.ID, err)
+		http.Error(w, "Failed to store capture", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected() == 0 {
+		activeManualCrawlSessions.Delete(token)
+		http.Error(w, "Capture session has ended", http.StatusConflict)
+		return
+	}
+
 	timestamp, err := time.Parse(time.RFC3339, req.Timestamp)
 	if err != nil {
 		timestamp = time.Now()
 	}
 
+	captureID, err := insertManualCrawlCapture(session.ID, session.ScopeTargetID, req, timestamp)
+	if err != nil {
+		log.Printf("[MANUAL-CRAWL] Error storing capture: %v", err)
+		http.Error(w, "Failed to store capture", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"captureId": captureID,
+	})
+}
+
+// insertManualCrawlCapture stores one captured request under a session and
+// returns the new capture ID.
+func insertManualCrawlCapture(sessionID, scopeTargetID string, req CaptureRequest, timestamp time.Time) (string, error) {
 	headersJSON, _ := json.Marshal(req.Headers)
 	responseHeadersJSON, _ := json.Marshal(req.ResponseHeaders)
 	getParamsJSON, _ := json.Marshal(req.GetParams)
 	postParamsJSON, _ := json.Marshal(req.PostParams)
 
 	captureID := uuid.New().String()
-	
+
 	query := `
 		INSERT INTO manual_crawl_captures 
 		(id, session_id, scope_target_id, url, endpoint, method, status_code, headers, response_headers, post_data, response_body, get_params, post_params, body_type, timestamp, mime_type)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
-	
-	_, err = dbPool.Exec(
+
+	_, err := dbPool.Exec(
 		context.Background(),
 		query,
 		captureID,
-		activeManualCrawlSession.ID,
-		activeManualCrawlSession.ScopeTargetID,
+		sessionID,
+		scopeTargetID,
 		req.URL,
 		req.Endpoint,
 		req.Method,
@@ -199,38 +311,31 @@ func CaptureManualCrawlRequest(w http.ResponseWriter, r *http.Request) {
 		timestamp,
 		req.MimeType,
 	)
-
-	if err != nil {
-		log.Printf("[MANUAL-CRAWL] Error storing capture: %v", err)
-		http.Error(w, "Failed to store capture", http.StatusInternalServerError)
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"captureId": captureID,
-	})
+	return captureID, err
 }
 
 func StopManualCrawl(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if activeManualCrawlSession == nil {
-		http.Error(w, "No active capture session", http.StatusBadRequest)
-		return
-	}
-
 	var req StatsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("[MANUAL-CRAWL] Error decoding stats: %v", err)
 	}
 
+	token := requestCaptureToken(r, req.CaptureToken)
+	session, ok := lookupManualCrawlSession(token)
+	if !ok {
+		http.Error(w, "No active capture session for this token", http.StatusBadRequest)
+		return
+	}
+	activeManualCrawlSessions.Delete(token)
+
 	endTime := time.Now()
 	
 	query := `
 		UPDATE manual_crawl_sessions 
 		SET status = $1, ended_at = $2, request_count = $3, endpoint_count = $4
-		WHERE id = $5
+		WHERE id = $5 AND status = 'active'
 	`
 	
 	_, err := dbPool.Exec(
@@ -240,7 +345,7 @@ func StopManualCrawl(w http.ResponseWriter, r *http.Request) {
 		endTime,
 		req.Stats.RequestCount,
 		req.Stats.EndpointCount,
-		activeManualCrawlSession.ID,
+		session.ID,
 	)
 
 	if err != nil {
@@ -250,14 +355,11 @@ func StopManualCrawl(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[MANUAL-CRAWL] Stopped session %s. Requests: %d, Endpoints: %d", 
-		activeManualCrawlSession.ID, req.Stats.RequestCount, req.Stats.EndpointCount)
-
-	sessionID := activeManualCrawlSession.ID
-	activeManualCrawlSession = nil
+		session.ID, req.Stats.RequestCount, req.Stats.EndpointCount)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"sessionId": sessionID,
+		"sessionId": session.ID,
 	})
 }
 
@@ -272,7 +374,7 @@ func GetManualCrawlSessions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := `
-		SELECT id, scope_target_id, target_url, status, started_at, ended_at, request_count, endpoint_count
+		SELECT id, scope_target_id, target_url, COALESCE(label, ''), status, started_at, ended_at, request_count, endpoint_count
 		FROM manual_crawl_sessions
 		WHERE scope_target_id = $1
 		ORDER BY started_at DESC
@@ -295,6 +397,7 @@ func GetManualCrawlSessions(w http.ResponseWriter, r *http.Request) {
 			&session.ID,
 			&session.ScopeTargetID,
 			&session.TargetURL,
+			&session.Label,
 			&session.Status,
 			&session.StartedAt,
 			&endedAt,
@@ -317,18 +420,21 @@ func GetManualCrawlSessions(w http.ResponseWriter, r *http.Request) {
 func CleanupStaleSessions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
-	
+	now := time.Now()
+
+	// Each session row is updated on its own, so cleanup never blocks
+	// captures for other sessions; a capture racing with cleanup finds its
+	// session ended and is rejected.
 	query := `
 		UPDATE manual_crawl_sessions
-		SET status = 'completed', ended_at = NOW()
+		SET status = 'completed', ended_at = NOW(),
+			request_count = (SELECT COUNT(*) FROM manual_crawl_captures WHERE session_id = manual_crawl_sessions.id)
 		WHERE status = 'active' 
-		AND request_count = 0 
-		AND started_at < $1
-		RETURNING id
+		AND ((last_capture_at IS NULL AND started_at < $1) OR last_capture_at < $2)
+		RETURNING id, COALESCE(capture_token, '')
 	`
 
-	rows, err := dbPool.Query(context.Background(), query, fiveMinutesAgo)
+	rows, err := dbPool.Query(context.Background(), query, now.Add(-manualCrawlEmptyStaleAfter), now.Add(-manualCrawlStaleAfter))
 	if err != nil {
 		log.Printf("[MANUAL-CRAWL] Error cleaning up stale sessions: %v", err)
 		http.Error(w, "Failed to cleanup sessions", http.StatusInternalServerError)
@@ -338,8 +444,9 @@ func CleanupStaleSessions(w http.ResponseWriter, r *http.Request) {
 
 	var cleanedCount int
 	for rows.Next() {
-		var id string
-		rows.Scan(&id)
+		var id, token string
+		rows.Scan(&id, &token)
+		activeManualCrawlSessions.Delete(token)
 		cleanedCount++
 		log.Printf("[MANUAL-CRAWL] Cleaned up stale session: %s", id)
 	}
@@ -355,7 +462,7 @@ func GetAllManualCrawlSessions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	query := `
-		SELECT id, scope_target_id, target_url, status, started_at, ended_at, request_count, endpoint_count
+		SELECT id, scope_target_id, target_url, COALESCE(label, ''), status, started_at, ended_at, request_count, endpoint_count
 		FROM manual_crawl_sessions
 		ORDER BY started_at DESC
 		LIMIT 100
@@ -378,6 +485,7 @@ func GetAllManualCrawlSessions(w http.ResponseWriter, r *http.Request) {
 			&session.ID,
 			&session.ScopeTargetID,
 			&session.TargetURL,
+			&session.Label,
 			&session.Status,
 			&session.StartedAt,
 			&endedAt,
@@ -10,11 +10,9 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -175,9 +173,14 @@ func updateScanProgress(scanID, currentStep, currentURL string, totalURLs, proce
 }
 
 func RunMetaDataScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "nuclei", "katana") {
+		return
+	}
+
 	var payload struct {
 		ScopeTargetID     string  `json:"scope_target_id" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string  `json:"request_profile_id,omitempty"`
 		Config            *struct {
 			URLIds []string          `json:"url_ids,omitempty"`
 			Steps  map[string]bool   `json:"steps,omitempty"`
@@ -187,6 +190,9 @@ func RunMetaDataScan(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body. `scope_target_id` is required.", http.StatusBadRequest)
 		return
 	}
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
 
 	var domain string
 	err := dbPool.QueryRow(context.Background(),
@@ -231,13 +237,14 @@ func RunMetaDataScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseMetaDataScan(scanID, domain)
+	RecordScanToolVersion("metadata_scans", scanID, "nuclei")
+	go ExecuteAndParseMetaDataScan(scanID, domain, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseMetaDataScan(scanID, domain string) {
+func ExecuteAndParseMetaDataScan(scanID, domain, requestProfileID string) {
 	log.Printf("[INFO] Starting metadata scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
@@ -388,46 +395,31 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 		log.Printf("[INFO] Starting screenshot capture - %d URLs", len(urls))
 		updateScanProgress(scanID, "screenshots", "", len(urls), 0)
 		
-		// Get custom HTTP settings
-		customUserAgent, customHeader := GetCustomHTTPSettings()
-		
 		// Build nuclei command for screenshots
-		screenshotCmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-nuclei-1",
+		screenshotArgs := []string{
 			"bash", "-c",
-			fmt.Sprintf("echo '%s' > /urls.txt && nuclei -t /root/nuclei-templates/headless/screenshot.yaml -list /urls.txt -headless -c 25 -rl 150 -timeout 10 -retries 1 -bs 25%s%s",
+			fmt.Sprintf("echo '%s' > /urls.txt && nuclei -t /root/nuclei-templates/headless/screenshot.yaml -list /urls.txt -headless -c 25 -rl 150 -timeout 10 -retries 1 -bs 25%s",
 				strings.Join(urls, "\n"),
-				func() string {
-					if customHeader != "" {
-						return fmt.Sprintf(" -H '%s'", customHeader)
-					}
-					return ""
-				}(),
-				func() string {
-					if customUserAgent != "" {
-						return fmt.Sprintf(" -H 'User-Agent: %s'", customUserAgent)
-					}
-					return ""
-				}(),
+				requestHeaderShellArgs("nuclei", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)),
 			),
-		)
+		}
 		
-		var screenshotStdout, screenshotStderr bytes.Buffer
 		screenshotStdoutWriter := &ScreenshotLogWriter{prefix: "[NUCLEI-SCREENSHOT]"}
 		screenshotStderrWriter := &ScreenshotLogWriter{prefix: "[NUCLEI-SCREENSHOT-ERR]"}
 		
-		screenshotCmd.Stdout = io.MultiWriter(&screenshotStdout, screenshotStdoutWriter)
-		screenshotCmd.Stderr = io.MultiWriter(&screenshotStderr, screenshotStderrWriter)
-		
 		log.Printf("[INFO] Executing screenshot command for scan ID: %s", scanID)
-		err = screenshotCmd.Run()
+		_, err = ToolExecutor("nuclei").Exec(context.Background(), ExecRequest{
+			Args:   screenshotArgs,
+			Stdout: screenshotStdoutWriter,
+			Stderr: screenshotStderrWriter,
+		})
 		if err != nil {
 			log.Printf("[WARN] Screenshot command failed for scan ID %s: %v (continuing with other steps)", scanID, err)
 		} else {
 			log.Printf("[INFO] Screenshots captured successfully for scan ID: %s, processing files...", scanID)
 			
 			// Process screenshot files
-			screenshotFiles, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "ls", "/app/screenshots/").Output()
+			screenshotFiles, err := ToolExecOutput(context.Background(), ToolExecutor("nuclei"), "ls", "/app/screenshots/")
 			if err != nil {
 				log.Printf("[WARN] Failed to list screenshot files for scan ID %s: %v (continuing with other steps)", scanID, err)
 			} else {
@@ -441,7 +433,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 					}
 					
 					// Read the screenshot file
-					imgData, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/app/screenshots/"+file).Output()
+					imgData, err := ToolExecOutput(context.Background(), ToolExecutor("nuclei"), "cat", "/app/screenshots/"+file)
 					if err != nil {
 						log.Printf("[WARN] Failed to read screenshot file %s: %v", file, err)
 						continue
@@ -470,7 +462,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 				log.Printf("[INFO] Successfully processed %d screenshots for scan ID: %s", processedCount, scanID)
 				
 				// Clean up screenshots in the container
-				exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-rf", "/app/screenshots/*").Run()
+				ToolExecutor("nuclei").Exec(context.Background(), ExecRequest{Args: []string{"rm", "-rf", "/app/screenshots/*"}})
 			}
 		}
 	} else {
@@ -503,9 +495,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		katanaCmd := exec.CommandContext(ctx,
-			"docker", "exec", "ars0n-framework-v2-katana-1",
-			"katana",
+		katanaArgs := []string{
 			"-u", url,
 			"-jc",
 			"-d", "2",
@@ -514,28 +504,24 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 			"-timeout", "30",
 			"-c", "15",
 			"-p", "15",
-		)
-
-		katanaCmd.WaitDelay = 30 * time.Second
-
-		var stdout, stderr bytes.Buffer
-		katanaCmd.Stdout = &stdout
-		katanaCmd.Stderr = &stderr
+		}
+		katanaArgs = append(katanaArgs, RequestHeaderArgs("katana", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
+		katanaResult, err := ToolExecutor("katana").Run(ctx, ExecRequest{Args: katanaArgs})
 
-		log.Printf("[DEBUG] Executing Katana command: %s", katanaCmd.String())
-		if err := katanaCmd.Run(); err != nil {
+		log.Printf("[DEBUG] Executed Katana command: %s", katanaResult.Command)
+		if err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
 				log.Printf("[WARN] Katana scan timed out for URL %s (%d/%d)", url, completedKatana, len(urls))
 				continue
 			}
-			log.Printf("[WARN] Katana scan failed for URL %s (%d/%d): %v\nStderr: %s", url, completedKatana, len(urls), err, stderr.String())
+			log.Printf("[WARN] Katana scan failed for URL %s (%d/%d): %v\nStderr: %s", url, completedKatana, len(urls), err, katanaResult.Stderr)
 			continue
 		}
 
 		var crawledURLs []string
 		seenURLs := make(map[string]bool)
 
-		for _, line := range strings.Split(stdout.String(), "\n") {
+		for _, line := range strings.Split(katanaResult.Stdout, "\n") {
 			if line == "" {
 				continue
 			}
@@ -685,53 +671,41 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	if runSSL {
 		updateScanProgress(scanID, "ssl", "", len(urls), 0)
 		// Copy the URLs file into the container for SSL scan
-		copyCmd := exec.Command(
-			"docker", "cp",
-			tempFile.Name(),
-			"ars0n-framework-v2-nuclei-1:/urls.txt",
-		)
-		if err := copyCmd.Run(); err != nil {
+		nucleiExecutor := ToolExecutor("nuclei")
+		if err := nucleiExecutor.CopyTo(context.Background(), tempFile.Name(), "/urls.txt"); err != nil {
 			log.Printf("[ERROR] Failed to copy URLs file to container: %v", err)
 			UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to copy URLs file: %v", err), "", time.Since(startTime).String())
 			return
 		}
 
 		// Run all templates in one scan with JSON output
-		cmd := exec.Command(
-			"docker", "exec", "ars0n-framework-v2-nuclei-1",
-			"nuclei",
-			"-t", "/root/nuclei-templates/ssl/",
-			"-list", "/urls.txt",
-			"-j",
-			"-o", "/output.json",
-		)
-		log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stderr bytes.Buffer
-	stdoutWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL]"}
-	stderrWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL-ERR]"}
-	
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = io.MultiWriter(&stderr, stderrWriter)
+		stdoutWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL]"}
+		stderrWriter := &NucleiLogWriter{prefix: "[NUCLEI-SSL-ERR]"}
 
 	log.Printf("[INFO] Nuclei SSL scan started, streaming output...")
-	err = cmd.Run()
+	cmd, err := nucleiExecutor.Run(context.Background(), ExecRequest{
+		Args: []string{
+			"-t", "/root/nuclei-templates/ssl/",
+			"-list", nucleiExecutor.Path("/urls.txt"),
+			"-j",
+			"-o", nucleiExecutor.Path("/output.json"),
+		},
+		Stdout: stdoutWriter,
+		Stderr: stderrWriter,
+	})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	if err != nil {
 		log.Printf("[ERROR] Nuclei scan failed: %v", err)
-		UpdateMetaDataScanStatus(scanID, "error", "", stderr.String(), cmd.String(), time.Since(startTime).String())
+		UpdateMetaDataScanStatus(scanID, "error", "", cmd.Stderr, cmd.Command, time.Since(startTime).String())
 		return
 	}
 	log.Printf("[INFO] Nuclei SSL scan completed")
 
 	// Read the JSON output file
-	outputCmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
-		"cat", "/output.json",
-	)
-	output, err := outputCmd.Output()
+	output, err := ReadToolFile(context.Background(), nucleiExecutor, "/output.json")
 	if err != nil {
 		log.Printf("[ERROR] Failed to read output file: %v", err)
-		UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), cmd.String(), time.Since(startTime).String())
+		UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Failed to read output file: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -801,13 +775,13 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 			scanID,
 			"running",
 			string(output),
-			stderr.String(),
-			cmd.String(),
+			cmd.Stderr,
+			cmd.Command,
 			time.Since(startTime).String(),
 		)
 
 		// Clean up the output file
-		exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "/output.json").Run()
+		ToolExecutor("nuclei").Exec(context.Background(), ExecRequest{Args: []string{"rm", "/output.json"}})
 
 		log.Printf("[INFO] SSL scan completed for scan ID: %s", scanID)
 	} else {
@@ -825,7 +799,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	if runTech {
 		log.Printf("[INFO] Starting technology detection scan")
 		updateScanProgress(scanID, "technology", "", len(urls), 0)
-		if err := ExecuteAndParseNucleiTechScan(urls, scopeTargetID); err != nil {
+		if err := ExecuteAndParseNucleiTechScan(urls, scopeTargetID, requestProfileID); err != nil {
 			log.Printf("[ERROR] Failed to run HTTP/technologies scan: %v", err)
 			UpdateMetaDataScanStatus(scanID, "error", "", fmt.Sprintf("Tech scan failed: %v", err), "", time.Since(startTime).String())
 			return
@@ -855,7 +829,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 			}
 			
 			updateScanProgress(scanID, "ffuf", url, len(urls), i+1)
-			if err := ExecuteFfufScan(url, scopeTargetID); err != nil {
+			if err := ExecuteFfufScan(url, scopeTargetID, requestProfileID); err != nil {
 				log.Printf("[ERROR] Failed to run ffuf scan for URL %s: %v", url, err)
 				continue
 			}
@@ -878,7 +852,7 @@ func ExecuteAndParseMetaDataScan(scanID, domain string) {
 	log.Printf("[INFO] All enabled scans completed successfully for scan ID: %s", scanID)
 }
 
-func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
+func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID, requestProfileID string) error {
 	log.Printf("[INFO] Starting Nuclei HTTP/technologies scan")
 	startTime := time.Now()
 
@@ -887,7 +861,7 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	failedRequests := 0
 
 	// Create an HTTP client with reasonable timeouts and TLS config
-	client := &http.Client{
+	client := egressClient(EgressScopeHTTPProbe, &http.Client{
 		Timeout: 30 * time.Second,
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -896,7 +870,9 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 			MaxIdleConns:        100,
 			MaxIdleConnsPerHost: 100,
 		},
-	}
+	})
+
+	requestHeaders := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
 
 	// Process each URL first to get response headers and body
 	for _, urlStr := range urls {
@@ -909,6 +885,7 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 		}
 
 		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+		applyRequestHeaders(req, requestHeaders)
 
 		resp, err := client.Do(req)
 		if err != nil {
@@ -945,10 +922,20 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 			continue
 		}
 
+		// Passive CDN/WAF classification; block-page probing is left to the edge classification scan.
+		// Nothing observed leaves the stored classification alone.
+		edge := classifyEdge(resp.Header, body, "")
+		var edgeSignals []byte
+		if len(edge.Signals) > 0 {
+			edgeSignals, _ = json.Marshal(edge.Signals)
+		}
+
 		// Store response data in database using UPSERT
-		_, err = dbPool.Exec(context.Background(),
-			`INSERT INTO target_urls (url, scope_target_id, status_code, title, content_length, http_response, http_response_headers, created_at)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, NOW())
+		var targetURLID string
+		err = dbPool.QueryRow(context.Background(),
+			`INSERT INTO target_urls (url, scope_target_id, status_code, title, content_length, http_response, http_response_headers,
+			     edge_provider, edge_category, waf_provider, edge_signals, edge_checked_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, NOW(), NOW())
 			 ON CONFLICT (url, scope_target_id)
 			 DO UPDATE SET 
 			     status_code = EXCLUDED.status_code,
@@ -956,19 +943,36 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 			     content_length = EXCLUDED.content_length,
 			     http_response = EXCLUDED.http_response,
 			     http_response_headers = EXCLUDED.http_response_headers,
-			     updated_at = NOW()`,
+			     edge_provider = COALESCE(EXCLUDED.edge_provider, target_urls.edge_provider),
+			     edge_category = COALESCE(EXCLUDED.edge_category, target_urls.edge_category),
+			     waf_provider = COALESCE(EXCLUDED.waf_provider, target_urls.waf_provider),
+			     edge_signals = COALESCE(EXCLUDED.edge_signals, target_urls.edge_signals),
+			     edge_blocking = CASE
+			         WHEN EXCLUDED.edge_provider IS DISTINCT FROM target_urls.edge_provider AND EXCLUDED.edge_provider IS NOT NULL THEN false
+			         ELSE target_urls.edge_blocking
+			     END,
+			     edge_checked_at = NOW(),
+			     updated_at = NOW()
+			 RETURNING id`,
 			urlStr,
 			scopeTargetID,
 			resp.StatusCode,
 			extractTitle(sanitizedBody),
 			len(body),
 			sanitizedBody,
-			string(headersJSON))
+			string(headersJSON),
+			edge.Provider,
+			edge.Category,
+			edge.WAF,
+			edgeSignals).Scan(&targetURLID)
 		if err != nil {
 			failedRequests++
 			log.Printf("[ERROR] Failed to store metadata for URL %s: %v", urlStr, err)
 			continue
 		}
+		if _, err := fingerprintTargetURL(scopeTargetID, targetURLID, urlStr, resp.Header, string(body)); err != nil {
+			log.Printf("[WARN] Failed to fingerprint technologies for URL %s: %v", urlStr, err)
+		}
 		successfulRequests++
 		log.Printf("[STATUS_CODE] URL: %s | Status: %d | Stored successfully", urlStr, resp.StatusCode)
 	}
@@ -992,46 +996,34 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	}
 
 	// Copy the URLs file into the container
-	copyCmd := exec.Command(
-		"docker", "cp",
-		tempFile.Name(),
-		"ars0n-framework-v2-nuclei-1:/urls.txt",
-	)
-	if err := copyCmd.Run(); err != nil {
+	nucleiExecutor := ToolExecutor("nuclei")
+	if err := nucleiExecutor.CopyTo(context.Background(), tempFile.Name(), "/urls.txt"); err != nil {
 		return fmt.Errorf("failed to copy URLs file to container: %v", err)
 	}
 
 	// Run HTTP/technologies templates
-	cmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
-		"nuclei",
-		"-t", "/root/nuclei-templates/http/technologies/",
-		"-list", "/urls.txt",
-		"-j",
-		"-o", "/tech-output.json",
-	)
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stderr bytes.Buffer
 	stdoutWriter := &NucleiLogWriter{prefix: "[NUCLEI-TECH]"}
 	stderrWriter := &NucleiLogWriter{prefix: "[NUCLEI-TECH-ERR]"}
-	
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = io.MultiWriter(&stderr, stderrWriter)
 
 	log.Printf("[INFO] Nuclei tech scan started, streaming output...")
-	err = cmd.Run()
+	cmd, err := nucleiExecutor.Run(context.Background(), ExecRequest{
+		Args: []string{
+			"-t", "/root/nuclei-templates/http/technologies/",
+			"-list", nucleiExecutor.Path("/urls.txt"),
+			"-j",
+			"-o", nucleiExecutor.Path("/tech-output.json"),
+		},
+		Stdout: stdoutWriter,
+		Stderr: stderrWriter,
+	})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	if err != nil {
-		return fmt.Errorf("nuclei tech scan failed: %v\nstderr: %s", err, stderr.String())
+		return fmt.Errorf("nuclei tech scan failed: %v\nstderr: %s", err, cmd.Stderr)
 	}
 	log.Printf("[INFO] Nuclei tech scan completed")
 
 	// Read the JSON output file
-	outputCmd := exec.Command(
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
-		"cat", "/tech-output.json",
-	)
-	output, err := outputCmd.Output()
+	output, err := ReadToolFile(context.Background(), nucleiExecutor, "/tech-output.json")
 	if err != nil {
 		return fmt.Errorf("failed to read output file: %v", err)
 	}
@@ -1133,7 +1125,7 @@ func ExecuteAndParseNucleiTechScan(urls []string, scopeTargetID string) error {
 	}
 
 	// Clean up the output file
-	exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "/tech-output.json").Run()
+	ToolExecutor("nuclei").Exec(context.Background(), ExecRequest{Args: []string{"rm", "/tech-output.json"}})
 
 	log.Printf("[INFO] HTTP/technologies scan completed in %s", time.Since(startTime))
 	return nil
@@ -1145,22 +1137,8 @@ func PerformDNSLookups(hostname string) DNSResults {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Create a custom resolver with shorter timeout
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 500 * time.Millisecond,
-			}
-			// Try Docker's internal DNS first
-			conn, err := d.DialContext(ctx, network, "127.0.0.11:53")
-			if err == nil {
-				return conn, nil
-			}
-			// Fall back to Google DNS only
-			return d.DialContext(ctx, network, "8.8.8.8:53")
-		},
-	}
+	// Query the validated resolver pool with a short dial timeout
+	resolver := PoolResolver(500 * time.Millisecond)
 
 	// A and AAAA records
 	log.Printf("[DEBUG] Looking up A/AAAA records for %s", hostname)
@@ -1472,7 +1450,7 @@ func GanitizeResponse(input []byte) string {
 	return str
 }
 
-func ExecuteFfufScan(url string, scopeTargetID string) error {
+func ExecuteFfufScan(url string, scopeTargetID, requestProfileID string) error {
 	log.Printf("[INFO] Starting ffuf scan for URL: %s", url)
 	startTime := time.Now()
 
@@ -1484,60 +1462,47 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 	defer os.RemoveAll(tempDir)
 
 	// Copy wordlist to container
-	copyCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
+	ffufExecutor := ToolExecutor("ffuf")
+	copyResult, err := ffufExecutor.Exec(context.Background(), ExecRequest{Args: []string{
 		"cp",
-		"/wordlists/ffuf-wordlist-5000.txt",
-		"/wordlist.txt",
-	)
-
-	var copyStderr bytes.Buffer
-	copyCmd.Stderr = &copyStderr
-	if err := copyCmd.Run(); err != nil {
+		ffufExecutor.Path("/wordlists/ffuf-wordlist-5000.txt"),
+		ffufExecutor.Path("/wordlist.txt"),
+	}})
+	if err != nil {
 		log.Printf("[ERROR] Failed to copy wordlist in container. Command: %s, Error: %v, Stderr: %s",
-			copyCmd.String(), err, copyStderr.String())
-		return fmt.Errorf("failed to copy wordlist in container: %v (stderr: %s)", err, copyStderr.String())
+			copyResult.Command, err, copyResult.Stderr)
+		return fmt.Errorf("failed to copy wordlist in container: %v (stderr: %s)", err, copyResult.Stderr)
 	}
 	log.Printf("[DEBUG] Successfully copied wordlist in container")
 
 	// Verify wordlist exists in container
-	checkCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
-		"ls", "-l", "/wordlist.txt",
-	)
-	if out, err := checkCmd.CombinedOutput(); err != nil {
-		log.Printf("[ERROR] Wordlist not found in container. Output: %s, Error: %v", string(out), err)
+	if checkResult, err := ffufExecutor.Exec(context.Background(), ExecRequest{Args: []string{"ls", "-l", ffufExecutor.Path("/wordlist.txt")}}); err != nil {
+		log.Printf("[ERROR] Wordlist not found in container. Output: %s, Error: %v", checkResult.Stdout+checkResult.Stderr, err)
 		return fmt.Errorf("wordlist not found in container: %v", err)
 	} else {
-		log.Printf("[DEBUG] Wordlist verified in container: %s", string(out))
+		log.Printf("[DEBUG] Wordlist verified in container: %s", checkResult.Stdout)
 	}
 
 	// Run ffuf scan only on the base target URL
 	fuzzyURL := fmt.Sprintf("%s/FUZZ", url)
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
-		"ffuf",
-		"-w", "/wordlist.txt",
+	ffufArgs := []string{
+		"-w", ffufExecutor.Path("/wordlist.txt"),
 		"-u", fuzzyURL,
 		"-mc", "all",
-		"-o", "/output.json",
+		"-o", ffufExecutor.Path("/output.json"),
 		"-of", "json",
 		"-ac",
 		"-c",
 		"-r",
 		"-t", "50",
 		"-sa",
-	)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	}
+	ffufArgs = append(ffufArgs, RequestHeaderArgs("ffuf", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
+	cmd, err := ffufExecutor.Run(context.Background(), ExecRequest{Args: ffufArgs})
 
-	log.Printf("[DEBUG] Running ffuf command: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		stderrOutput := stderr.String()
+	log.Printf("[DEBUG] Ran ffuf command: %s", cmd.Command)
+	if err != nil {
+		stderrOutput := cmd.Stderr
 		log.Printf("[ERROR] ffuf scan failed for URL %s: %v\nStderr: %s", url, err, stderrOutput)
 		
 		if strings.Contains(stderrOutput, "403 Forbidden") || strings.Contains(stderrOutput, "95%") {
@@ -1548,7 +1513,7 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 		return fmt.Errorf("ffuf scan failed: %v", err)
 	}
 	
-	stderrOutput := stderr.String()
+	stderrOutput := cmd.Stderr
 	if strings.Contains(stderrOutput, "stopped") || strings.Contains(stderrOutput, "403") {
 		log.Printf("[WARN] FFUF may have stopped early for URL %s: %s", url, stderrOutput)
 	}
@@ -1556,12 +1521,7 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 	log.Printf("[INFO] Completed ffuf scan for URL: %s", url)
 
 	// Read and parse results
-	outputCmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
-		"cat", "/output.json",
-	)
-	resultBytes, err := outputCmd.Output()
+	resultBytes, err := ReadToolFile(context.Background(), ffufExecutor, "/output.json")
 	if err != nil {
 		log.Printf("[ERROR] Failed to read ffuf results file: %v", err)
 		return fmt.Errorf("failed to read ffuf results: %v", err)
@@ -1617,14 +1577,22 @@ func ExecuteFfufScan(url string, scopeTargetID string) error {
 }
 
 func RunCompanyMetaDataScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "nuclei", "katana") {
+		return
+	}
+
 	var payload struct {
-		ScopeTargetID string `json:"scope_target_id" binding:"required"`
-		IPPortScanID  string `json:"ip_port_scan_id" binding:"required"`
+		ScopeTargetID    string `json:"scope_target_id" binding:"required"`
+		IPPortScanID     string `json:"ip_port_scan_id" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" || payload.IPPortScanID == "" {
 		http.Error(w, "Invalid request body. `scope_target_id` and `ip_port_scan_id` are required.", http.StatusBadRequest)
 		return
 	}
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
 
 	scanID := uuid.New().String()
 
@@ -1639,13 +1607,13 @@ func RunCompanyMetaDataScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseCompanyMetaDataScan(scanID, payload.ScopeTargetID, payload.IPPortScanID)
+	go ExecuteAndParseCompanyMetaDataScan(scanID, payload.ScopeTargetID, payload.IPPortScanID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID string) {
+func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID, requestProfileID string) {
 	log.Printf("[INFO] Starting Company metadata scan for IP/Port scan ID %s (scan ID: %s)", ipPortScanID, scanID)
 	startTime := time.Now()
 
@@ -1699,9 +1667,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		katanaCmd := exec.CommandContext(ctx,
-			"docker", "exec", "ars0n-framework-v2-katana-1",
-			"katana",
+		katanaArgs := []string{
 			"-u", url,
 			"-jc",
 			"-d", "2",
@@ -1710,21 +1676,17 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 			"-timeout", "30",
 			"-c", "15",
 			"-p", "15",
-		)
-
-		katanaCmd.WaitDelay = 30 * time.Second
-
-		var stdout, stderr bytes.Buffer
-		katanaCmd.Stdout = &stdout
-		katanaCmd.Stderr = &stderr
+		}
+		katanaArgs = append(katanaArgs, RequestHeaderArgs("katana", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
+		katanaResult, err := ToolExecutor("katana").Run(ctx, ExecRequest{Args: katanaArgs})
 
-		log.Printf("[DEBUG] Executing Katana command: %s", katanaCmd.String())
-		if err := katanaCmd.Run(); err != nil {
+		log.Printf("[DEBUG] Executed Katana command: %s", katanaResult.Command)
+		if err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
 				log.Printf("[WARN] Katana scan timed out for URL %s (%d/%d)", url, completedKatana, len(liveWebServers))
 				continue
 			}
-			log.Printf("[WARN] Katana scan failed for URL %s (%d/%d): %v\nStderr: %s", url, completedKatana, len(liveWebServers), err, stderr.String())
+			log.Printf("[WARN] Katana scan failed for URL %s (%d/%d): %v\nStderr: %s", url, completedKatana, len(liveWebServers), err, katanaResult.Stderr)
 			continue
 		}
 		log.Printf("[INFO] Completed Katana scan for URL: %s (%d/%d)", url, completedKatana, len(liveWebServers))
@@ -1732,7 +1694,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 		var crawledURLs []string
 		seenURLs := make(map[string]bool)
 
-		for _, line := range strings.Split(stdout.String(), "\n") {
+		for _, line := range strings.Split(katanaResult.Stdout, "\n") {
 			if line == "" {
 				continue
 			}
@@ -1823,7 +1785,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 	for _, url := range liveWebServers {
 		completedFfuf++
 		log.Printf("[INFO] Running Ffuf scan for URL: %s (%d/%d)", url, completedFfuf, len(liveWebServers))
-		if err := ExecuteFfufScan(url, scopeTargetID); err != nil {
+		if err := ExecuteFfufScan(url, scopeTargetID, requestProfileID); err != nil {
 			log.Printf("[WARN] Ffuf scan failed for URL %s (%d/%d): %v", url, completedFfuf, len(liveWebServers), err)
 			continue
 		}
@@ -1831,7 +1793,7 @@ func ExecuteAndParseCompanyMetaDataScan(scanID, scopeTargetID, ipPortScanID stri
 	}
 
 	// Execute Nuclei tech scan using the same logic as regular metadata scan
-	err = ExecuteAndParseNucleiTechScan(liveWebServers, scopeTargetID)
+	err = ExecuteAndParseNucleiTechScan(liveWebServers, scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to execute Nuclei tech scan: %v", err)
 		UpdateCompanyMetaDataScanStatus(scanID, "error", fmt.Sprintf("Failed to execute Nuclei tech scan: %v", err), time.Since(startTime).String())
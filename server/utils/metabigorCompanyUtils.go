@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -66,6 +65,10 @@ type MetabigorIPIntelligence struct {
 }
 
 func RunMetabigorCompanyScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "metabigor") {
+		return
+	}
+
 	log.Printf("[METABIGOR-COMPANY] [INFO] Starting Metabigor Company scan request handling")
 	var payload struct {
 		CompanyName       string  `json:"company_name" binding:"required"`
@@ -134,6 +137,7 @@ func RunMetabigorCompanyScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[METABIGOR-COMPANY] [INFO] Successfully created Metabigor Company scan record in database")
 
+	RecordScanToolVersion("metabigor_company_scans", scanID, "metabigor")
 	go ExecuteMetabigorCompanyScan(scanID, companyName)
 
 	log.Printf("[METABIGOR-COMPANY] [INFO] Metabigor Company scan initiated successfully, returning scan ID: %s", scanID)
@@ -141,6 +145,15 @@ func RunMetabigorCompanyScan(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
+// runMetabigor feeds input to metabigor on stdin and returns its combined output and the command run.
+func runMetabigor(input string, args ...string) (string, string, error) {
+	result, err := ToolExecutor("metabigor").Run(context.Background(), ExecRequest{
+		Args:  args,
+		Stdin: strings.NewReader(input + "\n"),
+	})
+	return result.Stdout + result.Stderr, result.Command, err
+}
+
 func ExecuteMetabigorCompanyScan(scanID, companyName string) {
 	log.Printf("[METABIGOR-COMPANY] [INFO] Starting Metabigor Company scan execution for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
@@ -150,16 +163,14 @@ func ExecuteMetabigorCompanyScan(scanID, companyName string) {
 
 	// Helper function to execute the scan and count results
 	executeScan := func(name string) (string, int, error) {
-		command := fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor net --org -v", name)
-		log.Printf("[METABIGOR-COMPANY] [DEBUG] Executing command: %s", command)
-
-		output, err := exec.Command("sh", "-c", command).CombinedOutput()
+		output, command, err := runMetabigor(name, "net", "--org", "-v")
+		log.Printf("[METABIGOR-COMPANY] [DEBUG] Executed command: %s", command)
 		if err != nil {
-			return string(output), 0, err
+			return output, 0, err
 		}
 
 		// Count valid result lines (lines that match the verbose pattern)
-		lines := strings.Split(string(output), "\n")
+		lines := strings.Split(output, "\n")
 		verbosePattern := regexp.MustCompile(`^(\d+)\s*-\s*([0-9a-fA-F:.\/]+)\s*-\s*(.+?)\s*-\s*([A-Z]{2})$`)
 		resultCount := 0
 
@@ -172,7 +183,7 @@ func ExecuteMetabigorCompanyScan(scanID, companyName string) {
 			}
 		}
 
-		return string(output), resultCount, nil
+		return output, resultCount, nil
 	}
 
 	// First attempt with original company name
@@ -611,6 +622,10 @@ func GetMetabigorASNData(w http.ResponseWriter, r *http.Request) {
 // Phase 2: Enhanced Network Discovery Functions
 
 func RunMetabigorNetdScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "metabigor") {
+		return
+	}
+
 	log.Printf("[METABIGOR-NETD] [INFO] Starting Metabigor Dynamic Network scan request handling")
 
 	var req struct {
@@ -650,6 +665,7 @@ func RunMetabigorNetdScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("metabigor_company_scans", scanID, "metabigor")
 	go ExecuteMetabigorNetdScan(scanID, companyName)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -660,25 +676,26 @@ func ExecuteMetabigorNetdScan(scanID, companyName string) {
 	log.Printf("[METABIGOR-NETD] [INFO] Starting dynamic network scan for company %s (scan ID: %s)", companyName, scanID)
 	startTime := time.Now()
 
-	command := fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor netd --org", companyName)
-
-	log.Printf("[METABIGOR-NETD] [DEBUG] Executing command: %s", command)
-
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, command, err := runMetabigor(companyName, "netd", "--org")
+	log.Printf("[METABIGOR-NETD] [DEBUG] Executed command: %s", command)
 	if err != nil {
 		log.Printf("[METABIGOR-NETD] [ERROR] Command failed: %v", err)
-		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
+		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, output), command, time.Since(startTime).String())
 		return
 	}
 
-	log.Printf("[METABIGOR-NETD] [DEBUG] Raw output: %s", string(output))
-	ParseAndStoreMetabigorResults(scanID, companyName, string(output), "netd")
+	log.Printf("[METABIGOR-NETD] [DEBUG] Raw output: %s", output)
+	ParseAndStoreMetabigorResults(scanID, companyName, output, "netd")
 
 	UpdateMetabigorCompanyScanStatus(scanID, "success", "{}", "", command, time.Since(startTime).String())
 	log.Printf("[METABIGOR-NETD] [INFO] Dynamic network scan completed for company %s", companyName)
 }
 
 func RunMetabigorASNScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "metabigor") {
+		return
+	}
+
 	log.Printf("[METABIGOR-ASN] [INFO] Starting Metabigor ASN scan request handling")
 
 	var req struct {
@@ -722,6 +739,7 @@ func RunMetabigorASNScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("metabigor_company_scans", scanID, "metabigor")
 	go ExecuteMetabigorASNScan(scanID, asnNumber, scanType)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -732,30 +750,31 @@ func ExecuteMetabigorASNScan(scanID, asnNumber, scanType string) {
 	log.Printf("[METABIGOR-ASN] [INFO] Starting ASN scan for %s using %s (scan ID: %s)", asnNumber, scanType, scanID)
 	startTime := time.Now()
 
-	var command string
+	args := []string{"net", "--asn"}
 	if scanType == "netd" {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor netd --asn", asnNumber)
-	} else {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor net --asn", asnNumber)
+		args = []string{"netd", "--asn"}
 	}
 
-	log.Printf("[METABIGOR-ASN] [DEBUG] Executing command: %s", command)
-
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, command, err := runMetabigor(asnNumber, args...)
+	log.Printf("[METABIGOR-ASN] [DEBUG] Executed command: %s", command)
 	if err != nil {
 		log.Printf("[METABIGOR-ASN] [ERROR] Command failed: %v", err)
-		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
+		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, output), command, time.Since(startTime).String())
 		return
 	}
 
-	log.Printf("[METABIGOR-ASN] [DEBUG] Raw output: %s", string(output))
-	ParseAndStoreMetabigorResults(scanID, asnNumber, string(output), scanType+"_asn")
+	log.Printf("[METABIGOR-ASN] [DEBUG] Raw output: %s", output)
+	ParseAndStoreMetabigorResults(scanID, asnNumber, output, scanType+"_asn")
 
 	UpdateMetabigorCompanyScanStatus(scanID, "success", "{}", "", command, time.Since(startTime).String())
 	log.Printf("[METABIGOR-ASN] [INFO] ASN scan completed for %s", asnNumber)
 }
 
 func RunMetabigorIPIntelligence(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "metabigor") {
+		return
+	}
+
 	log.Printf("[METABIGOR-IP] [INFO] Starting Metabigor IP Intelligence scan")
 
 	var req struct {
@@ -800,6 +819,7 @@ func RunMetabigorIPIntelligence(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("metabigor_company_scans", scanID, "metabigor")
 	go ExecuteMetabigorIPIntelligence(scanID, ipList, scanType)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -810,28 +830,25 @@ func ExecuteMetabigorIPIntelligence(scanID, ipList, scanType string) {
 	log.Printf("[METABIGOR-IP] [INFO] Starting IP intelligence scan (scan ID: %s)", scanID)
 	startTime := time.Now()
 
-	var command string
+	args := []string{"ipc", "--json"}
 	if scanType == "open" {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor ip -open", ipList)
-	} else {
-		command = fmt.Sprintf("echo '%s' | /usr/bin/docker exec -i ars0n-framework-v2-metabigor-1 metabigor ipc --json", ipList)
+		args = []string{"ip", "-open"}
 	}
 
-	log.Printf("[METABIGOR-IP] [DEBUG] Executing command: %s", command)
-
-	output, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output, command, err := runMetabigor(ipList, args...)
+	log.Printf("[METABIGOR-IP] [DEBUG] Executed command: %s", command)
 	if err != nil {
 		log.Printf("[METABIGOR-IP] [ERROR] Command failed: %v", err)
-		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, string(output)), command, time.Since(startTime).String())
+		UpdateMetabigorCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Command failed: %v\nOutput: %s", err, output), command, time.Since(startTime).String())
 		return
 	}
 
-	log.Printf("[METABIGOR-IP] [DEBUG] Raw output: %s", string(output))
+	log.Printf("[METABIGOR-IP] [DEBUG] Raw output: %s", output)
 
 	if scanType == "ipc" {
-		ParseAndStoreIPIntelligence(scanID, string(output))
+		ParseAndStoreIPIntelligence(scanID, output)
 	} else {
-		ParseAndStoreOpenPorts(scanID, string(output))
+		ParseAndStoreOpenPorts(scanID, output)
 	}
 
 	UpdateMetabigorCompanyScanStatus(scanID, "success", "{}", "", command, time.Since(startTime).String())
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net"
+	"math/big"
 	"net/http"
+	"net/netip"
 	"sort"
+	"strings"
+
+	"ars0n-framework-v2-server/iptrie"
 
 	"github.com/gorilla/mux"
 )
@@ -21,25 +25,194 @@ type ConsolidatedNetworkRange struct {
 	Country      string `json:"country"`
 	Source       string `json:"source"`
 	ScanType     string `json:"scan_type,omitempty"`
+	ContainedIn  string `json:"contained_in,omitempty"`
+	CoveringCIDR string `json:"covering_cidr,omitempty"`
 }
 
-// ConsolidateNetworkRanges consolidates network ranges from Amass Intel and Metabigor sources
-func ConsolidateNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange, error) {
-	log.Printf("[NETWORK-CONSOLIDATION] [INFO] Starting network range consolidation for scope target: %s", scopeTargetID)
+// CoveringPrefix is one block of the minimal covering set and the listed
+// ranges it stands for.
+type CoveringPrefix struct {
+	CIDR         string   `json:"cidr"`
+	AddressCount string   `json:"address_count"`
+	Ranges       []string `json:"ranges"`
+	Sources      []string `json:"sources"`
+	ASNs         []string `json:"asns"`
+}
 
-	// Start a transaction
-	tx, err := dbPool.Begin(context.Background())
+// NetworkRangeCoverage summarizes what an IP scan over the consolidated ranges
+// would actually cover. Address counts are decimal strings since IPv6 blocks
+// overflow any integer type.
+type NetworkRangeCoverage struct {
+	Ranges          int              `json:"ranges"`
+	UniquePrefixes  int              `json:"unique_prefixes"`
+	DuplicateRanges int              `json:"duplicate_ranges"`
+	ContainedRanges int              `json:"contained_ranges"`
+	InvalidRanges   []string         `json:"invalid_ranges"`
+	CoveringSet     []CoveringPrefix `json:"covering_set"`
+	TotalAddresses  string           `json:"total_addresses"`
+	IPv4Addresses   string           `json:"ipv4_addresses"`
+	IPv6Addresses   string           `json:"ipv6_addresses"`
+	RecordRanges    int              `json:"record_ranges"`
+	PlannedProbes   int              `json:"planned_probes"`
+	MaxIPsPerRange  int              `json:"max_ips_per_range"`
+}
+
+// parseRangePrefix reads a CIDR block, or a bare address as a single-host
+// prefix, normalized to its network address.
+func parseRangePrefix(cidr string) (netip.Prefix, bool) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	prefix, err := netip.ParsePrefix(cidr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %v", err)
+		return netip.Prefix{}, false
+	}
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), true
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// analyzeNetworkRanges sets ContainedIn to the smallest other listed range
+// strictly containing each range and CoveringCIDR to the block of the minimal
+// covering set it falls in, and returns the coverage summary.
+func analyzeNetworkRanges(ranges []ConsolidatedNetworkRange) NetworkRangeCoverage {
+	coverage := NetworkRangeCoverage{
+		Ranges:         len(ranges),
+		InvalidRanges:  []string{},
+		CoveringSet:    []CoveringPrefix{},
+		MaxIPsPerRange: getDefaultScanConfig().MaxIPsPerRange,
 	}
-	defer tx.Rollback(context.Background())
+
+	var listed iptrie.Trie[string]
+	var prefixes []netip.Prefix
+	parsed := make([]netip.Prefix, len(ranges))
+	for i, networkRange := range ranges {
+		prefix, ok := parseRangePrefix(networkRange.CIDRBlock)
+		if !ok {
+			coverage.InvalidRanges = append(coverage.InvalidRanges, networkRange.CIDRBlock)
+			continue
+		}
+		parsed[i] = prefix
+		if _, seen := listed.Get(prefix); seen {
+			coverage.DuplicateRanges++
+			continue
+		}
+		listed.Insert(prefix, prefix.String())
+		prefixes = append(prefixes, prefix)
+	}
+	coverage.UniquePrefixes = listed.Len()
+
+	var covering iptrie.Trie[int]
+	ipv4, ipv6 := new(big.Int), new(big.Int)
+	for i, prefix := range iptrie.Aggregate(prefixes) {
+		covering.Insert(prefix, i)
+		count := iptrie.AddressCount(prefix)
+		if prefix.Addr().Is4() {
+			ipv4.Add(ipv4, count)
+		} else {
+			ipv6.Add(ipv6, count)
+		}
+		coverage.CoveringSet = append(coverage.CoveringSet, CoveringPrefix{
+			CIDR:         prefix.String(),
+			AddressCount: count.String(),
+			Ranges:       []string{},
+			Sources:      []string{},
+			ASNs:         []string{},
+		})
+	}
+	coverage.IPv4Addresses = ipv4.String()
+	coverage.IPv6Addresses = ipv6.String()
+	coverage.TotalAddresses = new(big.Int).Add(ipv4, ipv6).String()
+
+	for i := range ranges {
+		prefix := parsed[i]
+		if !prefix.IsValid() {
+			continue
+		}
+		if _, parent, ok := listed.Parent(prefix); ok {
+			ranges[i].ContainedIn = parent
+		}
+		_, index, ok := covering.Lookup(prefix.Addr())
+		if !ok {
+			continue
+		}
+		block := &coverage.CoveringSet[index]
+		ranges[i].CoveringCIDR = block.CIDR
+		block.Ranges = appendUnique(block.Ranges, prefix.String())
+		for _, source := range strings.Split(ranges[i].Source, ",") {
+			block.Sources = appendUnique(block.Sources, strings.TrimSpace(source))
+		}
+		block.ASNs = appendUnique(block.ASNs, ranges[i].ASN)
+	}
+	for _, networkRange := range ranges {
+		if networkRange.ContainedIn != "" {
+			coverage.ContainedRanges++
+		}
+	}
+
+	return coverage
+}
+
+// orderRangesForScan puts nested ranges before the ranges containing them, so
+// an address probed once is attributed to the most specific range listing it.
+func orderRangesForScan(ranges []ConsolidatedNetworkRange) []ConsolidatedNetworkRange {
+	ordered := append([]ConsolidatedNetworkRange(nil), ranges...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, okA := parseRangePrefix(ordered[i].CIDRBlock)
+		b, okB := parseRangePrefix(ordered[j].CIDRBlock)
+		if !okA || !okB {
+			return okA
+		}
+		return a.Addr().BitLen()-a.Bits() < b.Addr().BitLen()-b.Bits()
+	})
+	return ordered
+}
+
+// plannedProbeIPs lists the addresses discoverLiveIPs probes for ranges: each
+// range capped at maxPerRange, every address once.
+func plannedProbeIPs(ranges []ConsolidatedNetworkRange, maxPerRange int, known []netip.Addr) []string {
+	var planned []string
+	seen := make(map[string]bool)
+	for _, networkRange := range orderRangesForScan(ranges) {
+		for _, ip := range rangeProbeIPs(networkRange.CIDRBlock, maxPerRange, known) {
+			if !seen[ip] {
+				seen[ip] = true
+				planned = append(planned, ip)
+			}
+		}
+	}
+	return planned
+}
+
+// ConsolidateNetworkRanges consolidates network ranges from Amass Intel and Metabigor sources
+func ConsolidateNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange, error) {
+	log.Printf("[NETWORK-CONSOLIDATION] [INFO] Starting network range consolidation for scope target: %s", scopeTargetID)
 
 	// Map to store unique network ranges by CIDR+ASN combination
 	rangeMap := make(map[string]ConsolidatedNetworkRange)
 
 	// 1. Get network ranges from Amass Intel scans (most recent only)
 	log.Printf("[NETWORK-CONSOLIDATION] [INFO] Fetching Amass Intel network ranges...")
-	amassRows, err := tx.Query(context.Background(), `
+	amassRows, err := dbPool.Query(context.Background(), `
 		SELECT inr.id, inr.cidr_block, inr.asn, inr.organization, inr.description, inr.country, inr.scan_id
 		FROM intel_network_ranges inr
 		JOIN amass_intel_scans ais ON inr.scan_id = ais.scan_id
@@ -70,7 +243,7 @@ func ConsolidateNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange,
 
 	// 2. Get network ranges from Metabigor scans (most recent only)
 	log.Printf("[NETWORK-CONSOLIDATION] [INFO] Fetching Metabigor network ranges...")
-	metabigorRows, err := tx.Query(context.Background(), `
+	metabigorRows, err := dbPool.Query(context.Background(), `
 		SELECT mnr.id, mnr.cidr_block, mnr.asn, mnr.organization, mnr.country, mnr.scan_type, mnr.scan_id
 		FROM metabigor_network_ranges mnr
 		JOIN metabigor_company_scans mcs ON mnr.scan_id = mcs.scan_id
@@ -113,22 +286,35 @@ func ConsolidateNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange,
 		consolidatedRanges = append(consolidatedRanges, networkRange)
 	}
 
-	// Sort by CIDR block for consistent ordering
+	// Sort by network address, then widest first, for consistent ordering
 	sort.Slice(consolidatedRanges, func(i, j int) bool {
-		// Try to parse as IP networks for proper sorting
-		_, netA, errA := net.ParseCIDR(consolidatedRanges[i].CIDRBlock)
-		_, netB, errB := net.ParseCIDR(consolidatedRanges[j].CIDRBlock)
+		netA, okA := parseRangePrefix(consolidatedRanges[i].CIDRBlock)
+		netB, okB := parseRangePrefix(consolidatedRanges[j].CIDRBlock)
 
-		if errA != nil || errB != nil {
+		if !okA || !okB {
 			// Fall back to string comparison if parsing fails
 			return consolidatedRanges[i].CIDRBlock < consolidatedRanges[j].CIDRBlock
 		}
-
-		// Compare by network address
-		return netA.String() < netB.String()
+		if c := netA.Addr().Compare(netB.Addr()); c != 0 {
+			return c < 0
+		}
+		return netA.Bits() < netB.Bits()
 	})
 
+	// Flag ranges nested in others and map each to its covering block
+	coverage := analyzeNetworkRanges(consolidatedRanges)
+
 	log.Printf("[NETWORK-CONSOLIDATION] [INFO] Total unique network ranges found: %d", len(consolidatedRanges))
+	log.Printf("[NETWORK-CONSOLIDATION] [INFO] %d ranges nested in others, minimal covering set is %d prefixes (%s addresses)",
+		coverage.ContainedRanges, len(coverage.CoveringSet), coverage.TotalAddresses)
+
+	// Only the replacement runs in a transaction; the reads and the coverage
+	// analysis above need no locks
+	tx, err := dbPool.Begin(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback(context.Background())
 
 	// Clear old consolidated network ranges and insert new ones
 	_, err = tx.Exec(context.Background(), `DELETE FROM consolidated_network_ranges WHERE scope_target_id = $1`, scopeTargetID)
@@ -138,16 +324,19 @@ func ConsolidateNetworkRanges(scopeTargetID string) ([]ConsolidatedNetworkRange,
 
 	for _, networkRange := range consolidatedRanges {
 		_, err = tx.Exec(context.Background(), `
-			INSERT INTO consolidated_network_ranges (scope_target_id, cidr_block, asn, organization, description, country, source, scan_type) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			INSERT INTO consolidated_network_ranges (scope_target_id, cidr_block, asn, organization, description, country, source, scan_type, contained_in, covering_cidr) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''))
 			ON CONFLICT (scope_target_id, cidr_block, source) DO UPDATE SET
 				asn = EXCLUDED.asn,
 				organization = EXCLUDED.organization,
 				description = EXCLUDED.description,
 				country = EXCLUDED.country,
-				scan_type = EXCLUDED.scan_type`,
+				scan_type = EXCLUDED.scan_type,
+				contained_in = EXCLUDED.contained_in,
+				covering_cidr = EXCLUDED.covering_cidr`,
 			scopeTargetID, networkRange.CIDRBlock, networkRange.ASN, networkRange.Organization,
-			networkRange.Description, networkRange.Country, networkRange.Source, networkRange.ScanType)
+			networkRange.Description, networkRange.Country, networkRange.Source, networkRange.ScanType,
+			networkRange.ContainedIn, networkRange.CoveringCIDR)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert consolidated network range: %v", err)
 		}
@@ -192,7 +381,8 @@ func GetConsolidatedNetworkRanges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT cidr_block, asn, organization, description, country, source, scan_type 
+	query := `SELECT cidr_block, asn, organization, description, country, source, scan_type,
+				COALESCE(contained_in, ''), COALESCE(covering_cidr, '')
 			  FROM consolidated_network_ranges 
 			  WHERE scope_target_id = $1 
 			  ORDER BY cidr_block ASC`
@@ -208,7 +398,8 @@ func GetConsolidatedNetworkRanges(w http.ResponseWriter, r *http.Request) {
 		var networkRange ConsolidatedNetworkRange
 		var scanType *string
 		if err := rows.Scan(&networkRange.CIDRBlock, &networkRange.ASN, &networkRange.Organization,
-			&networkRange.Description, &networkRange.Country, &networkRange.Source, &scanType); err != nil {
+			&networkRange.Description, &networkRange.Country, &networkRange.Source, &scanType,
+			&networkRange.ContainedIn, &networkRange.CoveringCIDR); err != nil {
 			continue
 		}
 		if scanType != nil {
@@ -223,3 +414,33 @@ func GetConsolidatedNetworkRanges(w http.ResponseWriter, r *http.Request) {
 		"network_ranges": networkRanges,
 	})
 }
+
+// GetNetworkRangeCoverage reports the minimal covering set of the consolidated
+// ranges, how many listed ranges are duplicates or nested in others, and how
+// many addresses an IP/port scan would probe, counting the ranges it adds for
+// AAAA and dual-stack records.
+func GetNetworkRangeCoverage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	scopeTargetID := vars["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	networkRanges, err := getConsolidatedNetworkRanges(scopeTargetID)
+	if err != nil {
+		log.Printf("[NETWORK-CONSOLIDATION] [ERROR] Failed to get consolidated network ranges: %v", err)
+		http.Error(w, "Failed to get consolidated network ranges", http.StatusInternalServerError)
+		return
+	}
+	coverage := analyzeNetworkRanges(networkRanges)
+	targets := buildIPScanTargets(scopeTargetID, networkRanges)
+	coverage.RecordRanges = len(targets.ranges) - len(networkRanges)
+	coverage.PlannedProbes = len(plannedProbeIPs(targets.ranges, coverage.MaxIPsPerRange, targets.known))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"coverage":       coverage,
+		"network_ranges": networkRanges,
+	})
+}
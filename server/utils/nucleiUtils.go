@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -187,7 +186,7 @@ func getStringSliceConfig(config map[string]interface{}, key string) []string {
 func executeNucleiScan(targets []string, templates []string, severities []string,
 	templateIDs []string, excludeIDs []string, excludeTags []string,
 	uploadedTemplates []map[string]interface{}, advancedConfig map[string]interface{},
-	outputFile string, capturedStdout *bytes.Buffer) error {
+	requestHeaders []string, outputFile string, capturedStdout *bytes.Buffer) error {
 
 	log.Printf("[DEBUG] Starting Nuclei scan with %d targets", len(targets))
 	log.Printf("[DEBUG] Targets: %v", targets)
@@ -212,17 +211,13 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 
 	log.Printf("[DEBUG] Created temp targets file: %s with %d targets", tempFile.Name(), len(targets))
 
-	copyCmd := exec.Command(
-		"docker", "cp",
-		tempFile.Name(),
-		"ars0n-framework-v2-nuclei-1:/targets.txt",
-	)
-	if err := copyCmd.Run(); err != nil {
+	executor := ToolExecutor("nuclei")
+	if err := executor.CopyTo(context.Background(), tempFile.Name(), "/targets.txt"); err != nil {
 		return fmt.Errorf("failed to copy targets file to container: %v", err)
 	}
 
 	var args []string
-	args = append(args, "-list", "/targets.txt", "-jsonl", "-nh", "-o", "/output.jsonl")
+	args = append(args, "-list", executor.Path("/targets.txt"), "-jsonl", "-nh", "-o", executor.Path("/output.jsonl"))
 
 	rateLimit := int(getFloatConfig(advancedConfig, "rate_limit", 150))
 	bulkSize := int(getFloatConfig(advancedConfig, "bulk_size", 25))
@@ -256,6 +251,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 			args = append(args, "-H", header)
 		}
 	}
+	args = append(args, RequestHeaderArgs("nuclei", requestHeaders)...)
 
 	proxy := getStringConfig(advancedConfig, "proxy", "")
 	if proxy != "" {
@@ -368,8 +364,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 	}
 
 	if len(uploadedTemplates) > 0 {
-		mkdirCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "mkdir", "-p", "/custom_templates")
-		if err := mkdirCmd.Run(); err != nil {
+		if _, err := executor.Exec(context.Background(), ExecRequest{Args: []string{"mkdir", "-p", executor.Path("/custom_templates")}}); err != nil {
 			return fmt.Errorf("failed to create custom templates directory: %v", err)
 		}
 
@@ -390,8 +385,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 				tempTemplateFile.Close()
 
 				templatePath := fmt.Sprintf("/custom_templates/custom_%d.yaml", i)
-				copyTemplateCmd := exec.Command("docker", "cp", tempTemplateFile.Name(), "ars0n-framework-v2-nuclei-1:"+templatePath)
-				if err := copyTemplateCmd.Run(); err != nil {
+				if err := executor.CopyTo(context.Background(), tempTemplateFile.Name(), templatePath); err != nil {
 					log.Printf("[WARN] Failed to copy custom template %d to container: %v", i, err)
 				}
 
@@ -399,29 +393,17 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 			}
 		}
 
-		args = append(args, "-t", "/custom_templates")
+		args = append(args, "-t", executor.Path("/custom_templates"))
 	}
 
-	dockerArgs := []string{"exec", "-i", "ars0n-framework-v2-nuclei-1", "nuclei"}
-	dockerArgs = append(dockerArgs, args...)
-
-	log.Printf("[INFO] Executing Nuclei command: docker %s", strings.Join(dockerArgs, " "))
-	dockerCmd := exec.Command("docker", dockerArgs...)
+	log.Printf("[INFO] Executing Nuclei command via %s executor: nuclei %s", executor.Mode(), strings.Join(redactCommandArgs(args), " "))
 
 	stdoutWriter := &capturingLogWriter{prefix: "[NUCLEI]"}
 	stderrWriter := &logWriter{prefix: "[NUCLEI-ERR]"}
 
-	dockerCmd.Stdout = stdoutWriter
-	dockerCmd.Stderr = stderrWriter
-
-	if err = dockerCmd.Start(); err != nil {
-		log.Printf("[ERROR] Failed to start Nuclei command: %v", err)
-		return fmt.Errorf("failed to start nuclei: %v", err)
-	}
-
 	log.Printf("[INFO] Nuclei scan started, streaming output...")
 
-	if err = dockerCmd.Wait(); err != nil {
+	if _, err = executor.Run(context.Background(), ExecRequest{Args: args, Stdout: stdoutWriter, Stderr: stderrWriter}); err != nil {
 		log.Printf("[ERROR] Nuclei command failed: %v", err)
 		return fmt.Errorf("nuclei execution failed: %v", err)
 	}
@@ -432,15 +414,9 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 		capturedStdout.Write(stdoutWriter.buf.Bytes())
 	}
 
-	copyOutputCmd := exec.Command(
-		"docker", "cp",
-		"ars0n-framework-v2-nuclei-1:/output.jsonl",
-		outputFile,
-	)
-	if err := copyOutputCmd.Run(); err != nil {
+	if err := executor.CopyFrom(context.Background(), "/output.jsonl", outputFile); err != nil {
 		log.Printf("[WARN] Failed to copy output file from container: %v", err)
-		readOutputCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/output.jsonl")
-		if outputContent, readErr := readOutputCmd.Output(); readErr == nil {
+		if outputContent, readErr := ToolExecOutput(context.Background(), executor, "cat", executor.Path("/output.jsonl")); readErr == nil {
 			if writeErr := os.WriteFile(outputFile, outputContent, 0644); writeErr != nil {
 				return fmt.Errorf("failed to copy output from container and write to host: %v", writeErr)
 			}
@@ -474,8 +450,7 @@ func executeNucleiScan(targets []string, templates []string, severities []string
 		log.Printf("[DEBUG] Output file does not exist: %v", err)
 	}
 
-	cleanupCmd := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-f", "/targets.txt", "/output.jsonl")
-	cleanupCmd.Run()
+	executor.Exec(context.Background(), ExecRequest{Args: []string{"rm", "-f", executor.Path("/targets.txt"), executor.Path("/output.jsonl")}})
 
 	return nil
 }
@@ -519,7 +494,7 @@ func parseNucleiResults(outputFile string) ([]NucleiFinding, error) {
 	return findings, nil
 }
 
-func ExecuteNucleiScanForScopeTarget(scopeTargetID string, selectedTargets []string, selectedTemplates []string,
+func ExecuteNucleiScanForScopeTarget(scopeTargetID, requestProfileID string, selectedTargets []string, selectedTemplates []string,
 	selectedSeverities []string, templateIDs []string, excludeIDs []string, excludeTags []string,
 	uploadedTemplates []map[string]interface{}, advancedConfig map[string]interface{},
 	dbPool *pgxpool.Pool) (string, []NucleiFinding, error) {
@@ -542,7 +517,7 @@ func ExecuteNucleiScanForScopeTarget(scopeTargetID string, selectedTargets []str
 
 	if err := executeNucleiScan(targets, selectedTemplates, selectedSeverities,
 		templateIDs, excludeIDs, excludeTags,
-		uploadedTemplates, advancedConfig, outputFile, nil); err != nil {
+		uploadedTemplates, advancedConfig, RequestHeadersForScopeTarget(scopeTargetID, requestProfileID), outputFile, nil); err != nil {
 		return "", nil, fmt.Errorf("scan execution failed: %v", err)
 	}
 
@@ -554,7 +529,7 @@ func ExecuteNucleiScanForScopeTarget(scopeTargetID string, selectedTargets []str
 	return outputFile, findings, nil
 }
 
-func ExecuteNucleiScanDirect(targets []string, selectedTemplates []string,
+func ExecuteNucleiScanDirect(scopeTargetID, requestProfileID string, targets []string, selectedTemplates []string,
 	selectedSeverities []string, templateIDs []string, excludeIDs []string, excludeTags []string,
 	uploadedTemplates []map[string]interface{}, advancedConfig map[string]interface{}) (string, []NucleiFinding, error) {
 
@@ -571,7 +546,7 @@ func ExecuteNucleiScanDirect(targets []string, selectedTemplates []string,
 
 	if err := executeNucleiScan(targets, selectedTemplates, selectedSeverities,
 		templateIDs, excludeIDs, excludeTags,
-		uploadedTemplates, advancedConfig, outputFile, nil); err != nil {
+		uploadedTemplates, advancedConfig, RequestHeadersForScopeTarget(scopeTargetID, requestProfileID), outputFile, nil); err != nil {
 		return "", nil, fmt.Errorf("scan execution failed: %v", err)
 	}
 
@@ -0,0 +1,607 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultOriginMaxTargets    = 25
+	defaultOriginMaxCandidates = 200
+	defaultOriginConcurrency   = 20
+	defaultOriginMinScore      = 40
+)
+
+type OriginScanConfig struct {
+	TargetURLIDs  []string `json:"target_url_ids,omitempty"`
+	MaxTargets    int      `json:"max_targets"`
+	MaxCandidates int      `json:"max_candidates"`
+	Concurrency   int      `json:"concurrency"`
+	MinScore      int      `json:"min_score"`
+}
+
+type OriginEvidence struct {
+	Signal string `json:"signal"`
+	Detail string `json:"detail,omitempty"`
+	Weight int    `json:"weight"`
+}
+
+type OriginDiscoveryResult struct {
+	URL         string           `json:"url"`
+	Hostname    string           `json:"hostname"`
+	CDNProvider string           `json:"cdn_provider"`
+	IPAddress   string           `json:"ip_address"`
+	Port        int              `json:"port"`
+	Protocol    string           `json:"protocol"`
+	Score       int              `json:"score"`
+	Evidence    []OriginEvidence `json:"evidence"`
+	Sources     []string         `json:"sources"`
+	CreatedAt   time.Time        `json:"created_at,omitempty"`
+}
+
+type originTarget struct {
+	id       string
+	url      string
+	hostname string
+	aRecords map[string]bool
+}
+
+// originComparedHeaders are set by the application rather than the edge, so a
+// matching value on a direct connection points at the same backend.
+var originComparedHeaders = []string{
+	"X-Powered-By", "X-Generator", "X-AspNet-Version", "X-AspNetMvc-Version",
+	"Content-Security-Policy", "X-Frame-Options", "Strict-Transport-Security",
+}
+
+func certNameCovers(name, host string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == host {
+		return true
+	}
+	if strings.HasPrefix(name, "*.") {
+		parent := name[2:]
+		if dot := strings.IndexByte(host, '.'); dot > 0 && host[dot+1:] == parent {
+			return true
+		}
+	}
+	return false
+}
+
+func cookieNames(header http.Header) map[string]bool {
+	names := make(map[string]bool)
+	for _, c := range (&http.Response{Header: header}).Cookies() {
+		names[c.Name] = true
+	}
+	return names
+}
+
+// scoreOrigin weighs how strongly a direct response from a candidate IP matches
+// the CDN-fronted response. The score is capped at 100.
+func scoreOrigin(hostname string, fronted, candidate vhostResponse, sources []string) (int, []OriginEvidence) {
+	var evidence []OriginEvidence
+	add := func(signal, detail string, weight int) {
+		evidence = append(evidence, OriginEvidence{Signal: signal, Detail: detail, Weight: weight})
+	}
+
+	switch {
+	case candidate.certSHA256 != "" && candidate.certSHA256 == fronted.certSHA256:
+		add("cert_identical", candidate.certSHA256, 40)
+	case candidate.certSHA256 != "":
+		for _, name := range candidate.certNames {
+			if certNameCovers(name, hostname) {
+				add("cert_covers_host", name, 25)
+				break
+			}
+		}
+	}
+
+	if fronted.length > 0 && candidate.bodyHash == fronted.bodyHash {
+		add("body_hash_match", candidate.bodyHash, 35)
+	} else if fronted.length > 0 {
+		diff := candidate.length - fronted.length
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff*20 <= fronted.length {
+			add("length_similar", fmt.Sprintf("%d vs %d bytes", candidate.length, fronted.length), 10)
+		}
+	}
+	if fronted.title != "" && candidate.title == fronted.title {
+		add("title_match", candidate.title, 20)
+	}
+	if candidate.status == fronted.status {
+		add("status_match", strconv.Itoa(candidate.status), 5)
+	}
+
+	headerWeight := 0
+	for _, name := range originComparedHeaders {
+		if v := fronted.header.Get(name); v != "" && v == candidate.header.Get(name) && headerWeight < 15 {
+			add("header_match", name, 5)
+			headerWeight += 5
+		}
+	}
+	frontedCookies := cookieNames(fronted.header)
+	for name := range cookieNames(candidate.header) {
+		if frontedCookies[name] && headerWeight < 15 {
+			add("cookie_match", name, 5)
+			headerWeight += 5
+		}
+	}
+
+	for _, source := range sources {
+		if source == "securitytrails_history" {
+			add("historical_dns", "host previously resolved here", 10)
+			break
+		}
+	}
+
+	score := 0
+	for _, e := range evidence {
+		score += e.Weight
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, evidence
+}
+
+func loadOriginTargets(scopeTargetID string, config OriginScanConfig) ([]originTarget, error) {
+	query := `
+		SELECT id, url, COALESCE(dns_a_records, '{}')
+		FROM target_urls
+		WHERE scope_target_id = $1 AND NOT COALESCE(no_longer_live, false)`
+	args := []interface{}{scopeTargetID}
+	if len(config.TargetURLIDs) > 0 {
+		query += ` AND id = ANY($2::uuid[])`
+		args = append(args, config.TargetURLIDs)
+	}
+	query += ` ORDER BY roi_score DESC NULLS LAST, url`
+
+	rows, err := dbPool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []originTarget
+	for rows.Next() {
+		var t originTarget
+		var records []string
+		if err := rows.Scan(&t.id, &t.url, &records); err != nil {
+			continue
+		}
+		host, err := domainutil.Normalize(domainutil.HostFromURL(t.url))
+		if err != nil || domainutil.IsIP(host) {
+			continue
+		}
+		t.hostname = host
+		t.aRecords = make(map[string]bool)
+		for _, ip := range records {
+			t.aRecords[ip] = true
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+// loadOriginCandidatePool collects every IP the framework already knows for the
+// scope target, keyed by IP with the places it came from.
+func loadOriginCandidatePool(scopeTargetID string) (map[string][]string, map[string][]vhostTarget, error) {
+	sources := make(map[string][]string)
+	ports := make(map[string][]vhostTarget)
+	addSource := func(ip, source string) {
+		parsed := net.ParseIP(strings.TrimSpace(ip))
+		if parsed == nil || parsed.IsPrivate() || parsed.IsLoopback() || parsed.IsUnspecified() {
+			return
+		}
+		ip = parsed.String()
+		for _, s := range sources[ip] {
+			if s == source {
+				return
+			}
+		}
+		sources[ip] = append(sources[ip], source)
+	}
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT host(lws.ip_address), lws.port, lws.protocol
+		FROM live_web_servers lws
+		JOIN ip_port_scans ips ON ips.scan_id = lws.scan_id
+		WHERE ips.scope_target_id = $1 AND ips.status = 'success'`, scopeTargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var t vhostTarget
+		if err := rows.Scan(&t.ip, &t.port, &t.protocol); err != nil || (t.protocol != "http" && t.protocol != "https") {
+			continue
+		}
+		addSource(t.ip, "ip_port_scan")
+		ports[t.ip] = append(ports[t.ip], t)
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT ip_address FROM target_urls WHERE scope_target_id = $1 AND ip_address IS NOT NULL
+		UNION
+		SELECT unnest(dns_a_records) FROM target_urls WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var ip string
+		if rows.Scan(&ip) == nil {
+			addSource(ip, "target_urls")
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT DISTINCT ip_address FROM consolidated_attack_surface_assets
+		WHERE scope_target_id = $1 AND asset_type = 'ip_address' AND ip_address IS NOT NULL`, scopeTargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ip string
+		if rows.Scan(&ip) == nil {
+			addSource(ip, "attack_surface")
+		}
+	}
+	return sources, ports, rows.Err()
+}
+
+// securityTrailsHistoricalIPs returns every A record SecurityTrails has seen for
+// the host. Missing keys and API errors just mean no historical candidates.
+func securityTrailsHistoricalIPs(apiKey, hostname string) ([]string, error) {
+	client := egressClient(EgressScopeSecurityTrails, &http.Client{Timeout: 30 * time.Second})
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.securitytrails.com/v1/history/%s/dns/a", url.PathEscape(hostname)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("APIKEY", apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SecurityTrails returned status %d", resp.StatusCode)
+	}
+
+	var history struct {
+		Records []struct {
+			Values []struct {
+				IP string `json:"ip"`
+			} `json:"values"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, record := range history.Records {
+		for _, v := range record.Values {
+			ips = append(ips, v.IP)
+		}
+	}
+	return ips, nil
+}
+
+func fetchFrontedResponse(rawURL string, headers []string) (vhostResponse, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+	applyRequestHeaders(req, headers)
+	return fetchVhostResponse(edgeHTTPClient(EgressScopeHTTPProbe), req)
+}
+
+func RunOriginDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID     string           `json:"scope_target_id"`
+		Config            OriginScanConfig `json:"config"`
+		AutoScanSessionID *string          `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string           `json:"request_profile_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
+		http.Error(w, "Invalid request body. scope_target_id is required.", http.StatusBadRequest)
+		return
+	}
+	config := payload.Config
+	if config.MaxTargets <= 0 {
+		config.MaxTargets = defaultOriginMaxTargets
+	}
+	if config.MaxCandidates <= 0 {
+		config.MaxCandidates = defaultOriginMaxCandidates
+	}
+	if config.Concurrency <= 0 || config.Concurrency > 200 {
+		config.Concurrency = defaultOriginConcurrency
+	}
+	if config.MinScore <= 0 {
+		config.MinScore = defaultOriginMinScore
+	}
+
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO origin_discovery_scans (scan_id, scope_target_id, status, auto_scan_session_id) VALUES ($1, $2, 'pending', $3)`,
+		scanID, payload.ScopeTargetID, autoScanSessionID)
+	if err != nil {
+		log.Printf("[ORIGIN] [ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteOriginDiscoveryScan(scanID, payload.ScopeTargetID, payload.RequestProfileID, config)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateOriginScan(scanID, status, errorMessage string, fronted, tested, found int, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE origin_discovery_scans
+		SET status = $1, error_message = NULLIF($2, ''), fronted_targets = $3, candidates_tested = $4,
+			origins_found = $5, execution_time = $6
+		WHERE scan_id = $7`,
+		status, errorMessage, fronted, tested, found, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[ORIGIN] [ERROR] Failed to update scan %s: %v", scanID, err)
+	}
+}
+
+func ExecuteOriginDiscoveryScan(scanID, scopeTargetID, requestProfileID string, config OriginScanConfig) {
+	startTime := time.Now()
+	targets, err := loadOriginTargets(scopeTargetID, config)
+	if err != nil {
+		updateOriginScan(scanID, "error", fmt.Sprintf("Failed to load target URLs: %v", err), 0, 0, 0, startTime)
+		return
+	}
+	pool, knownPorts, err := loadOriginCandidatePool(scopeTargetID)
+	if err != nil {
+		updateOriginScan(scanID, "error", fmt.Sprintf("Failed to load candidate IPs: %v", err), 0, 0, 0, startTime)
+		return
+	}
+	apiKey, err := getSecurityTrailsAPIKey()
+	if err != nil {
+		log.Printf("[ORIGIN] [INFO] Skipping SecurityTrails DNS history: %v", err)
+	}
+	updateOriginScan(scanID, "running", "", 0, 0, 0, startTime)
+
+	headers := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
+	fronted, tested, found := 0, 0, 0
+	for _, target := range targets {
+		if fronted >= config.MaxTargets {
+			break
+		}
+		frontedResp, err := fetchFrontedResponse(target.url, headers)
+		if err != nil {
+			continue
+		}
+		provider := classifyEdge(frontedResp.header, nil, "").Provider
+		if provider == "" {
+			continue
+		}
+		fronted++
+		log.Printf("[ORIGIN] [INFO] %s is behind %s, looking for its origin", target.url, provider)
+
+		candidates := make(map[string][]string)
+		for ip, sources := range pool {
+			candidates[ip] = append([]string{}, sources...)
+		}
+		if apiKey != "" {
+			ips, err := securityTrailsHistoricalIPs(apiKey, target.hostname)
+			if err != nil {
+				log.Printf("[ORIGIN] [WARN] SecurityTrails history for %s failed: %v", target.hostname, err)
+			}
+			for _, ip := range ips {
+				if net.ParseIP(ip) != nil {
+					candidates[ip] = append(candidates[ip], "securitytrails_history")
+				}
+			}
+		}
+
+		// Current A records point at the edge, not the origin. Historical IPs
+		// go first since they are the most common way origins leak.
+		var ordered []string
+		for ip := range candidates {
+			if !target.aRecords[ip] {
+				ordered = append(ordered, ip)
+			}
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			if len(candidates[ordered[i]]) != len(candidates[ordered[j]]) {
+				return len(candidates[ordered[i]]) > len(candidates[ordered[j]])
+			}
+			return ordered[i] < ordered[j]
+		})
+		if len(ordered) > config.MaxCandidates {
+			ordered = ordered[:config.MaxCandidates]
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, config.Concurrency)
+		for _, ip := range ordered {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(ip string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				probes := knownPorts[ip]
+				if len(probes) == 0 {
+					probes = []vhostTarget{{ip: ip, port: 443, protocol: "https"}, {ip: ip, port: 80, protocol: "http"}}
+				}
+
+				// probeVhost goes through the HTTP probe egress policy, so a
+				// candidate origin never sees the scanner's own address
+				var best *OriginDiscoveryResult
+				for _, probe := range probes {
+					resp, err := probeVhost(probe, target.hostname, true, headers)
+					if err != nil || classifyEdge(resp.header, nil, ip).Provider != "" {
+						continue
+					}
+					score, evidence := scoreOrigin(target.hostname, frontedResp, resp, candidates[ip])
+					if best == nil || score > best.Score {
+						best = &OriginDiscoveryResult{
+							URL: target.url, Hostname: target.hostname, CDNProvider: provider,
+							IPAddress: ip, Port: probe.port, Protocol: probe.protocol,
+							Score: score, Evidence: evidence, Sources: candidates[ip],
+						}
+					}
+				}
+
+				mu.Lock()
+				tested++
+				if best != nil && best.Score >= config.MinScore {
+					found++
+				}
+				mu.Unlock()
+				if best != nil && best.Score >= config.MinScore {
+					storeOriginCandidate(scanID, scopeTargetID, target.id, *best)
+				}
+			}(ip)
+		}
+		wg.Wait()
+		updateOriginScan(scanID, "running", "", fronted, tested, found, startTime)
+	}
+
+	if _, err := createOriginRelationships(scopeTargetID); err != nil {
+		log.Printf("[ORIGIN] [WARN] Failed to link origins into the attack surface: %v", err)
+	}
+	updateOriginScan(scanID, "success", "", fronted, tested, found, startTime)
+	log.Printf("[ORIGIN] [INFO] Scan %s finished: %d likely origins across %d CDN-fronted targets in %s", scanID, found, fronted, time.Since(startTime))
+}
+
+func storeOriginCandidate(scanID, scopeTargetID, targetURLID string, res OriginDiscoveryResult) {
+	log.Printf("[ORIGIN] [INFO] %s may be the origin of %s (score %d)", res.IPAddress, res.Hostname, res.Score)
+	evidence, _ := json.Marshal(res.Evidence)
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO origin_discovery_results (
+			scan_id, scope_target_id, target_url_id, url, hostname, cdn_provider,
+			ip_address, port, protocol, score, evidence, sources
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (scan_id, hostname, ip_address) DO NOTHING`,
+		scanID, scopeTargetID, targetURLID, res.URL, res.Hostname, res.CDNProvider,
+		res.IPAddress, res.Port, res.Protocol, res.Score, evidence, res.Sources)
+	if err != nil {
+		log.Printf("[ORIGIN] [ERROR] Failed to store origin %s for %s: %v", res.IPAddress, res.Hostname, err)
+	}
+}
+
+// createOriginRelationships links CDN-fronted FQDNs to their likely origin IPs,
+// using each pair's most recent result. Attack surface consolidation calls it too.
+func createOriginRelationships(scopeTargetID string) (int, error) {
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_relationships (
+			parent_asset_id, child_asset_id, relationship_type, relationship_data
+		)
+		SELECT DISTINCT ON (fqdn.id, ip.id)
+			fqdn.id, ip.id, 'likely_origin',
+			jsonb_build_object('score', o.score, 'cdn_provider', o.cdn_provider, 'port', o.port,
+				'protocol', o.protocol, 'evidence', o.evidence)
+		FROM origin_discovery_results o
+		JOIN consolidated_attack_surface_assets fqdn
+			ON fqdn.scope_target_id = o.scope_target_id AND fqdn.asset_type = 'fqdn' AND fqdn.fqdn = o.hostname
+		JOIN consolidated_attack_surface_assets ip
+			ON ip.scope_target_id = o.scope_target_id AND ip.asset_type = 'ip_address' AND ip.ip_address = o.ip_address
+		WHERE o.scope_target_id = $1::uuid
+		ORDER BY fqdn.id, ip.id, o.created_at DESC
+		ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO NOTHING`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func GetOriginDiscoveryScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, status, fronted_targets, candidates_tested, origins_found,
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM origin_discovery_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, status, errorMessage, execTime string
+		var fronted, tested, found int
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &status, &fronted, &tested, &found, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scans = append(scans, map[string]interface{}{
+			"scan_id":           scanID,
+			"status":            status,
+			"fronted_targets":   fronted,
+			"candidates_tested": tested,
+			"origins_found":     found,
+			"error_message":     errorMessage,
+			"execution_time":    execTime,
+			"created_at":        createdAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+func GetOriginDiscoveryResults(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT url, hostname, cdn_provider, ip_address, port, protocol, score, evidence,
+			COALESCE(sources, '{}'), created_at
+		FROM origin_discovery_results
+		WHERE scan_id = $1
+		ORDER BY hostname, score DESC`, mux.Vars(r)["scan_id"])
+	if err != nil {
+		http.Error(w, "Failed to get results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []OriginDiscoveryResult{}
+	for rows.Next() {
+		var res OriginDiscoveryResult
+		var evidence []byte
+		if err := rows.Scan(&res.URL, &res.Hostname, &res.CDNProvider, &res.IPAddress, &res.Port, &res.Protocol,
+			&res.Score, &evidence, &res.Sources, &res.CreatedAt); err != nil {
+			continue
+		}
+		json.Unmarshal(evidence, &res.Evidence)
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
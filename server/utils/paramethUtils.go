@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -83,14 +82,22 @@ func GetParamethConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunParamethScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "parameth") {
+		return
+	}
+
 	var req struct {
-		ScopeTargetID string `json:"scope_target_id"`
+		ScopeTargetID    string `json:"scope_target_id"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !CheckScanRequestProfile(w, req.ScopeTargetID, req.RequestProfileID) {
+		return
+	}
 
 	scanID := uuid.New().String()
 
@@ -104,7 +111,8 @@ func RunParamethScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteParamethScan(scanID, req.ScopeTargetID)
+	RecordScanToolVersion("parameth_scans", scanID, "parameth")
+	go ExecuteParamethScan(scanID, req.ScopeTargetID, req.RequestProfileID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -113,7 +121,7 @@ func RunParamethScan(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func ExecuteParamethScan(scanID, scopeTargetID string) {
+func ExecuteParamethScan(scanID, scopeTargetID, requestProfileID string) {
 	startTime := time.Now()
 
 	UpdateParamethScanStatus(scanID, "running", "", "")
@@ -165,6 +173,8 @@ func ExecuteParamethScan(scanID, scopeTargetID string) {
 	updateTotalQuery := `UPDATE parameth_scans SET total_endpoints = $1 WHERE scan_id = $2`
 	dbPool.Exec(context.Background(), updateTotalQuery, len(endpoints), scanID)
 
+	executor := ToolExecutor("parameth")
+	profileHeaderArgs := RequestHeaderArgs("parameth", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))
 	var allOutput strings.Builder
 	parametersFound := 0
 	processedEndpoints := 0
@@ -202,15 +212,16 @@ func ExecuteParamethScan(scanID, scopeTargetID string) {
 				}
 			}
 		}
+		args = append(args, profileHeaderArgs...)
 
-		cmd := exec.Command("docker", append([]string{"exec", "ars0n-framework-v2-parameth-1", "python3", "parameth.py"}, args...)...)
-		output, err := cmd.CombinedOutput()
+		execResult, err := executor.Run(context.Background(), ExecRequest{Args: args})
+		output := execResult.Stdout + execResult.Stderr
 
-		allOutput.WriteString(string(output))
+		allOutput.WriteString(output)
 		allOutput.WriteString("\n")
 
 		if err == nil {
-			outputLines := strings.Split(string(output), "\n")
+			outputLines := strings.Split(output, "\n")
 			for _, line := range outputLines {
 				line = strings.TrimSpace(line)
 				if strings.Contains(line, "Found parameter:") || strings.Contains(line, "[+]") {
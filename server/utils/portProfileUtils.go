@@ -0,0 +1,381 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultPortProfile = "web"
+
+// PortProfile is the set of TCP ports an IP/port scan probes. DiscoveryPorts
+// decide whether a host is alive; Ports are scanned on every live host.
+// Built-in profiles have no ID and cannot be edited.
+type PortProfile struct {
+	ID             string    `json:"id,omitempty"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	Ports          string    `json:"ports"`
+	DiscoveryPorts string    `json:"discovery_ports,omitempty"`
+	PortCount      int       `json:"port_count"`
+	BuiltIn        bool      `json:"built_in"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// nmapTop100Ports is the nmap --top-ports 100 list, most common first.
+var nmapTop100Ports = []int{
+	80, 23, 443, 21, 22, 25, 3389, 110, 445, 139, 143, 53, 135, 3306, 8080, 1723, 111, 995, 993, 5900,
+	1025, 587, 8888, 199, 1720, 465, 548, 113, 81, 6001, 10000, 514, 5060, 179, 1026, 2000, 8443, 8000, 32768, 554,
+	26, 1433, 49152, 2001, 515, 8008, 49154, 1027, 5666, 646, 5000, 5631, 631, 49153, 8081, 2049, 88, 79, 5800, 106,
+	2121, 1110, 49155, 6000, 513, 990, 5357, 427, 49156, 543, 544, 5101, 144, 7, 389, 8009, 3128, 444, 9999, 5009,
+	7070, 5190, 3000, 5432, 1900, 3986, 13, 1029, 9, 5051, 6646, 49157, 1028, 873, 1755, 2717, 4899, 9100, 119, 37,
+}
+
+// serviceDiscoveryPorts extend host discovery for the wider profiles, so hosts
+// exposing only a database or remote desktop are not missed.
+var serviceDiscoveryPorts = []int{3389, 445, 139, 135, 23, 3306, 5432, 1433, 6379, 27017, 5900, 8080, 8443}
+
+// commonServicePorts are frequently exposed services above 1024 that pad the
+// top-1000 fallback ahead of the well-known range.
+var commonServicePorts = []int{
+	1080, 1194, 1434, 1521, 1883, 2082, 2083, 2086, 2087, 2181, 2375, 2376, 2379, 2380, 3268, 3269,
+	3690, 4369, 4443, 4848, 5222, 5601, 5672, 5901, 5902, 5984, 5985, 5986, 6379, 6443, 6667, 7001,
+	7199, 7474, 8001, 8086, 8089, 8161, 8200, 8500, 8834, 9000, 9042, 9090, 9092, 9200, 9300, 9418,
+	10250, 11211, 15672, 27017, 27018, 50000, 50070,
+}
+
+var (
+	nmapServicesOnce  sync.Once
+	nmapServicesPorts []int
+)
+
+// nmapServicesTopPorts ranks TCP ports by the open frequency in nmap-services
+// (NMAP_SERVICES_PATH, or nmap's install path), which is exactly how nmap picks
+// --top-ports. It is empty when no such file exists.
+func nmapServicesTopPorts(n int) []int {
+	nmapServicesOnce.Do(func() {
+		path := os.Getenv("NMAP_SERVICES_PATH")
+		if path == "" {
+			path = "/usr/share/nmap/nmap-services"
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		type ranked struct {
+			port int
+			freq float64
+		}
+		var ports []ranked
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || !strings.HasSuffix(fields[1], "/tcp") {
+				continue
+			}
+			port, err1 := strconv.Atoi(strings.TrimSuffix(fields[1], "/tcp"))
+			freq, err2 := strconv.ParseFloat(fields[2], 64)
+			if err1 == nil && err2 == nil && freq > 0 {
+				ports = append(ports, ranked{port, freq})
+			}
+		}
+		sort.SliceStable(ports, func(i, j int) bool { return ports[i].freq > ports[j].freq })
+		for _, p := range ports {
+			nmapServicesPorts = append(nmapServicesPorts, p.port)
+		}
+		log.Printf("[IP-PORT-SCAN] [INFO] Ranked %d TCP ports from %s", len(nmapServicesPorts), path)
+	})
+	if len(nmapServicesPorts) < n {
+		return nil
+	}
+	return nmapServicesPorts[:n]
+}
+
+// topPorts returns the n most common TCP ports and whether they are nmap's
+// exact ranking. Without nmap-services the list is only an approximation: the
+// top 100, common service ports, then the well-known range.
+func topPorts(n int) ([]int, bool) {
+	if ports := nmapServicesTopPorts(n); ports != nil {
+		return ports, true
+	}
+	ports := append([]int{}, nmapTop100Ports...)
+	ports = append(ports, commonServicePorts...)
+	for port := 1; port <= 1024; port++ {
+		ports = append(ports, port)
+	}
+	ports = uniquePorts(ports)
+	if len(ports) > n {
+		ports = ports[:n]
+	}
+	return ports, false
+}
+
+func builtInPortProfiles() []PortProfile {
+	wide := formatPortSpec(uniquePorts(append(append([]int{}, hostDiscoveryPorts...), serviceDiscoveryPorts...)))
+	top1000, exact := topPorts(1000)
+	top1000Description := "The 1000 most common TCP ports"
+	if !exact {
+		top1000Description = "An approximation of the 1000 most common TCP ports; set NMAP_SERVICES_PATH for nmap's exact list"
+	}
+	profiles := []PortProfile{
+		{Name: "web", Description: "Common web ports", Ports: formatPortSpec(webPorts), DiscoveryPorts: formatPortSpec(hostDiscoveryPorts)},
+		{Name: "top-100", Description: "The 100 most common TCP ports", Ports: formatPortSpec(nmapTop100Ports), DiscoveryPorts: wide},
+		{Name: "top-1000", Description: top1000Description, Ports: formatPortSpec(top1000), DiscoveryPorts: wide},
+	}
+	for i := range profiles {
+		profiles[i].BuiltIn = true
+		ports, _ := parsePortSpec(profiles[i].Ports)
+		profiles[i].PortCount = len(ports)
+	}
+	return profiles
+}
+
+func uniquePorts(ports []int) []int {
+	seen := make(map[int]bool)
+	unique := make([]int, 0, len(ports))
+	for _, port := range ports {
+		if !seen[port] {
+			seen[port] = true
+			unique = append(unique, port)
+		}
+	}
+	return unique
+}
+
+// parsePortSpec reads a list such as "22,80,443,8000-8100". Commas, spaces and
+// newlines all separate entries; duplicates are dropped, order is kept.
+func parsePortSpec(spec string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\t' || r == '\r' }) {
+		low, high, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(low)
+		if err != nil || start < 1 || start > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(high)
+			if err != nil || end < start || end > 65535 {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+		}
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return uniquePorts(ports), nil
+}
+
+// formatPortSpec writes ports back as a list, collapsing consecutive runs.
+func formatPortSpec(ports []int) string {
+	var parts []string
+	for i := 0; i < len(ports); {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", ports[i], ports[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(ports[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// scanPorts is a profile resolved for a scan.
+type scanPorts struct {
+	profile   string
+	ports     []int
+	discovery []int
+}
+
+// resolveScanPorts picks the ports for a scan: a custom list wins over a
+// named profile, and no choice means the web profile. Custom lists are
+// discovered with the wider host discovery ports.
+func resolveScanPorts(profileName, customPorts string) (*scanPorts, error) {
+	if strings.TrimSpace(customPorts) != "" {
+		ports, err := parsePortSpec(customPorts)
+		if err != nil {
+			return nil, err
+		}
+		discovery := uniquePorts(append(append([]int{}, hostDiscoveryPorts...), serviceDiscoveryPorts...))
+		return &scanPorts{profile: "custom", ports: ports, discovery: discovery}, nil
+	}
+
+	if profileName == "" {
+		profileName = defaultPortProfile
+	}
+	profile, err := getPortProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := parsePortSpec(profile.Ports)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %v", profile.Name, err)
+	}
+	discovery := hostDiscoveryPorts
+	if profile.DiscoveryPorts != "" {
+		if discovery, err = parsePortSpec(profile.DiscoveryPorts); err != nil {
+			return nil, fmt.Errorf("profile %s: %v", profile.Name, err)
+		}
+	}
+	return &scanPorts{profile: profile.Name, ports: ports, discovery: discovery}, nil
+}
+
+// getPortProfile finds a profile by name among the built-ins, then by name or
+// ID among the saved ones.
+func getPortProfile(nameOrID string) (*PortProfile, error) {
+	for _, profile := range builtInPortProfiles() {
+		if profile.Name == nameOrID {
+			return &profile, nil
+		}
+	}
+	var profile PortProfile
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT id, name, COALESCE(description, ''), ports, COALESCE(discovery_ports, ''), created_at, updated_at
+		FROM port_scan_profiles WHERE name = $1 OR id::text = $1`, nameOrID).Scan(
+		&profile.ID, &profile.Name, &profile.Description, &profile.Ports, &profile.DiscoveryPorts,
+		&profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("unknown port profile %q", nameOrID)
+	}
+	ports, _ := parsePortSpec(profile.Ports)
+	profile.PortCount = len(ports)
+	return &profile, nil
+}
+
+func GetPortProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles := builtInPortProfiles()
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, name, COALESCE(description, ''), ports, COALESCE(discovery_ports, ''), created_at, updated_at
+		FROM port_scan_profiles ORDER BY name`)
+	if err != nil {
+		http.Error(w, "Failed to fetch port profiles", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var profile PortProfile
+		if err := rows.Scan(&profile.ID, &profile.Name, &profile.Description, &profile.Ports, &profile.DiscoveryPorts,
+			&profile.CreatedAt, &profile.UpdatedAt); err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Failed to scan port profile: %v", err)
+			continue
+		}
+		ports, _ := parsePortSpec(profile.Ports)
+		profile.PortCount = len(ports)
+		profiles = append(profiles, profile)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+func decodePortProfile(w http.ResponseWriter, r *http.Request) (*PortProfile, bool) {
+	var profile PortProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		http.Error(w, "Profile name is required", http.StatusBadRequest)
+		return nil, false
+	}
+	for _, builtIn := range builtInPortProfiles() {
+		if builtIn.Name == profile.Name {
+			http.Error(w, fmt.Sprintf("%s is a built-in profile", profile.Name), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	ports, err := parsePortSpec(profile.Ports)
+	if err != nil {
+		http.Error(w, "Invalid ports: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	profile.Ports = formatPortSpec(ports)
+	profile.PortCount = len(ports)
+	if strings.TrimSpace(profile.DiscoveryPorts) != "" {
+		discovery, err := parsePortSpec(profile.DiscoveryPorts)
+		if err != nil {
+			http.Error(w, "Invalid discovery ports: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		profile.DiscoveryPorts = formatPortSpec(discovery)
+	}
+	return &profile, true
+}
+
+func CreatePortProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := decodePortProfile(w, r)
+	if !ok {
+		return
+	}
+	err := dbPool.QueryRow(context.Background(), `
+		INSERT INTO port_scan_profiles (name, description, ports, discovery_ports)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''))
+		RETURNING id, created_at, updated_at`,
+		profile.Name, profile.Description, profile.Ports, profile.DiscoveryPorts).Scan(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to create port profile %s: %v", profile.Name, err)
+		http.Error(w, "Failed to create port profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func UpdatePortProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := decodePortProfile(w, r)
+	if !ok {
+		return
+	}
+	profile.ID = mux.Vars(r)["profile_id"]
+	err := dbPool.QueryRow(context.Background(), `
+		UPDATE port_scan_profiles
+		SET name = $1, description = NULLIF($2, ''), ports = $3, discovery_ports = NULLIF($4, ''), updated_at = NOW()
+		WHERE id = $5
+		RETURNING created_at, updated_at`,
+		profile.Name, profile.Description, profile.Ports, profile.DiscoveryPorts, profile.ID).Scan(&profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to update port profile %s: %v", profile.ID, err)
+		http.Error(w, "Failed to update port profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func DeletePortProfile(w http.ResponseWriter, r *http.Request) {
+	result, err := dbPool.Exec(context.Background(), `DELETE FROM port_scan_profiles WHERE id = $1`, mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Failed to delete port profile", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected() == 0 {
+		http.Error(w, "Port profile not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+)
+
+const publicSuffixMaxAge = 7 * 24 * time.Hour
+
+func publicSuffixCachePath() string {
+	if path := os.Getenv("PSL_CACHE_PATH"); path != "" {
+		return path
+	}
+	return filepath.Join(os.TempDir(), "ars0n-psl", "public_suffix_list.dat")
+}
+
+func refreshPublicSuffixList() (*domainutil.List, error) {
+	client := egressClient(EgressScopeDownload, &http.Client{Timeout: 60 * time.Second})
+	return domainutil.RefreshList(client, os.Getenv("PSL_URL"), publicSuffixCachePath())
+}
+
+// InitPublicSuffixList swaps in the cached list if one exists and refreshes it in
+// the background when it was downloaded more than a week ago or never downloaded.
+// The embedded copy is used until then.
+func InitPublicSuffixList() {
+	cachePath := publicSuffixCachePath()
+	if err := domainutil.LoadCachedList(cachePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[PSL] [WARN] Ignoring cached public suffix list %s: %v", cachePath, err)
+	}
+
+	list := domainutil.Current()
+	fetched := "never downloaded"
+	if !list.FetchedAt.IsZero() {
+		fetched = "downloaded " + list.FetchedAt.Format(time.RFC3339)
+	}
+	log.Printf("[PSL] [INFO] Using public suffix list from %s (%d rules, %s)", list.Source, list.Rules, fetched)
+	if time.Since(list.FetchedAt) < publicSuffixMaxAge || os.Getenv("PSL_AUTO_REFRESH") == "false" {
+		return
+	}
+
+	go func() {
+		list, err := refreshPublicSuffixList()
+		if err != nil {
+			log.Printf("[PSL] [WARN] Background refresh failed, keeping %s: %v", domainutil.Current().Source, err)
+			return
+		}
+		log.Printf("[PSL] [INFO] Refreshed public suffix list (%d rules)", list.Rules)
+	}()
+}
+
+// GetPublicSuffixListStatus reports which list is in use.
+func GetPublicSuffixListStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"list":       domainutil.Current(),
+		"cache_path": publicSuffixCachePath(),
+	})
+}
+
+// RefreshPublicSuffixList downloads the latest list and makes it current.
+func RefreshPublicSuffixList(w http.ResponseWriter, r *http.Request) {
+	list, err := refreshPublicSuffixList()
+	if err != nil {
+		log.Printf("[PSL] [ERROR] Refresh failed: %v", err)
+		http.Error(w, "Failed to refresh public suffix list: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[PSL] [INFO] Refreshed public suffix list (%d rules)", list.Rules)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"list":       list,
+		"cache_path": publicSuffixCachePath(),
+	})
+}
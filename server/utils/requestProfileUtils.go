@@ -0,0 +1,707 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// requestProfileLoginTool is the tool container login scripts run in; its
+	// image ships sh and curl.
+	requestProfileLoginTool = "nuclei"
+
+	requestProfileCacheTTL      = 30 * time.Second
+	requestProfileLoginTimeout  = 2 * time.Minute
+	defaultRequestProfileTTLSec = 3600
+
+	// noRequestProfile as a scan's request_profile_id runs it without any profile.
+	noRequestProfile = "none"
+)
+
+// RequestProfile is a named set of request headers for one scope target. The
+// default profile is applied to every HTTP tool and Go probe for that target;
+// a scan can pick another with request_profile_id, or "none" to send no profile.
+// LoginScript runs through `sh -c` in a tool container and prints "Name: value"
+// lines (Set-Cookie lines are folded into the Cookie header); its output is
+// cached for SessionTTLSeconds. Login scripts are off unless the server sets
+// REQUEST_PROFILE_LOGIN_SCRIPTS=true.
+type RequestProfile struct {
+	ID                 string            `json:"id"`
+	ScopeTargetID      string            `json:"scope_target_id"`
+	Name               string            `json:"name"`
+	Headers            map[string]string `json:"headers"`
+	Cookies            map[string]string `json:"cookies"`
+	BearerToken        string            `json:"bearer_token,omitempty"`
+	UserAgent          string            `json:"user_agent,omitempty"`
+	LoginScript        string            `json:"login_script,omitempty"`
+	SessionTTLSeconds  int               `json:"session_ttl_seconds"`
+	IsDefault          bool              `json:"is_default"`
+	SessionHeaders     map[string]string `json:"session_headers,omitempty"`
+	SessionRefreshedAt *time.Time        `json:"session_refreshed_at,omitempty"`
+	SessionError       string            `json:"session_error,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+type cachedRequestHeaders struct {
+	headers  []string
+	loadedAt time.Time
+}
+
+var (
+	requestHeaderMutex sync.Mutex
+	requestHeaderCache = make(map[string]cachedRequestHeaders)
+	requestLoginMutex  sync.Mutex
+)
+
+const requestProfileColumns = `id, scope_target_id, name, headers, cookies, COALESCE(bearer_token, ''), COALESCE(user_agent, ''),
+	COALESCE(login_script, ''), session_ttl_seconds, is_default, session_headers, session_refreshed_at,
+	COALESCE(session_error, ''), created_at, updated_at`
+
+type requestProfileScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRequestProfile(row requestProfileScanner) (*RequestProfile, error) {
+	var profile RequestProfile
+	var headersJSON, cookiesJSON, sessionJSON []byte
+	err := row.Scan(&profile.ID, &profile.ScopeTargetID, &profile.Name, &headersJSON, &cookiesJSON, &profile.BearerToken,
+		&profile.UserAgent, &profile.LoginScript, &profile.SessionTTLSeconds, &profile.IsDefault, &sessionJSON,
+		&profile.SessionRefreshedAt, &profile.SessionError, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(headersJSON, &profile.Headers)
+	json.Unmarshal(cookiesJSON, &profile.Cookies)
+	json.Unmarshal(sessionJSON, &profile.SessionHeaders)
+	return &profile, nil
+}
+
+func getRequestProfile(profileID string) (*RequestProfile, error) {
+	return scanRequestProfile(dbPool.QueryRow(context.Background(),
+		`SELECT `+requestProfileColumns+` FROM http_request_profiles WHERE id = $1`, profileID))
+}
+
+func getDefaultRequestProfile(scopeTargetID string) *RequestProfile {
+	profile, err := scanRequestProfile(dbPool.QueryRow(context.Background(),
+		`SELECT `+requestProfileColumns+` FROM http_request_profiles WHERE scope_target_id = $1 AND is_default`, scopeTargetID))
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// RequestHeadersForScopeTarget returns the "Name: value" headers every HTTP tool
+// should send for a target: the global custom user agent and header from settings,
+// overridden by the scan's request profile. An empty requestProfileID picks the
+// target's default profile and noRequestProfile picks none.
+func RequestHeadersForScopeTarget(scopeTargetID, requestProfileID string) []string {
+	cacheKey := scopeTargetID + "|" + requestProfileID
+	requestHeaderMutex.Lock()
+	cached, ok := requestHeaderCache[cacheKey]
+	requestHeaderMutex.Unlock()
+	if ok && time.Since(cached.loadedAt) < requestProfileCacheTTL {
+		return cached.headers
+	}
+
+	headers := make(map[string]string)
+	var order []string
+	set := func(name, value string) {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" {
+			return
+		}
+		if _, exists := headers[name]; !exists {
+			order = append(order, name)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+
+	customUserAgent, customHeader := GetCustomHTTPSettings()
+	if customUserAgent != "" {
+		set("User-Agent", customUserAgent)
+	}
+	if name, value, found := strings.Cut(customHeader, ":"); found {
+		set(name, value)
+	}
+
+	if profile := scanRequestProfileFor(scopeTargetID, requestProfileID); profile != nil {
+		for _, header := range profile.resolvedHeaders() {
+			name, value, _ := strings.Cut(header, ":")
+			set(name, value)
+		}
+	}
+
+	result := make([]string, 0, len(order))
+	for _, name := range order {
+		result = append(result, name+": "+headers[name])
+	}
+
+	requestHeaderMutex.Lock()
+	requestHeaderCache[cacheKey] = cachedRequestHeaders{headers: result, loadedAt: time.Now()}
+	requestHeaderMutex.Unlock()
+	return result
+}
+
+// scanRequestProfileFor resolves the profile a scan asked for, falling back to
+// the target's default when the ID is unknown or belongs to another target.
+func scanRequestProfileFor(scopeTargetID, requestProfileID string) *RequestProfile {
+	if scopeTargetID == "" || requestProfileID == noRequestProfile {
+		return nil
+	}
+	if requestProfileID != "" {
+		profile, err := getRequestProfile(requestProfileID)
+		if err == nil && profile.ScopeTargetID == scopeTargetID {
+			return profile
+		}
+		log.Printf("[REQUEST-PROFILE] [WARN] Profile %s is not a profile of scope target %s; using the default", requestProfileID, scopeTargetID)
+	}
+	return getDefaultRequestProfile(scopeTargetID)
+}
+
+// CheckScanRequestProfile rejects a scan whose request_profile_id does not
+// belong to its scope target. It writes the error and returns false.
+func CheckScanRequestProfile(w http.ResponseWriter, scopeTargetID, requestProfileID string) bool {
+	if requestProfileID == "" || requestProfileID == noRequestProfile {
+		return true
+	}
+	profile, err := getRequestProfile(requestProfileID)
+	if err != nil || profile.ScopeTargetID != scopeTargetID {
+		http.Error(w, "request_profile_id is not a request profile of this scope target", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func invalidateRequestHeaders(scopeTargetID string) {
+	requestHeaderMutex.Lock()
+	for key := range requestHeaderCache {
+		if strings.HasPrefix(key, scopeTargetID+"|") {
+			delete(requestHeaderCache, key)
+		}
+	}
+	requestHeaderMutex.Unlock()
+}
+
+// resolvedHeaders flattens the profile, refreshing the login session when it has expired.
+func (p *RequestProfile) resolvedHeaders() []string {
+	headers := make(map[string]string)
+	if p.UserAgent != "" {
+		headers["User-Agent"] = p.UserAgent
+	}
+	if p.BearerToken != "" {
+		headers["Authorization"] = "Bearer " + p.BearerToken
+	}
+	// A Cookie header, the cookie list and the login session's cookies all
+	// combine into one header, later sources winning per cookie name
+	cookies := make(map[string]string)
+	for name, value := range p.Headers {
+		if strings.EqualFold(name, "Cookie") {
+			mergeCookiePairs(cookies, value)
+			continue
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range p.Cookies {
+		cookies[name] = value
+	}
+
+	if p.LoginScript != "" && loginScriptsEnabled() {
+		if p.sessionExpired() {
+			if err := p.refreshSession(); err != nil {
+				log.Printf("[REQUEST-PROFILE] [WARN] Login script for profile %s failed: %v", p.Name, err)
+			}
+		}
+		for name, value := range p.SessionHeaders {
+			if strings.EqualFold(name, "Cookie") {
+				mergeCookiePairs(cookies, value)
+				continue
+			}
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	if len(cookies) > 0 {
+		names := make([]string, 0, len(cookies))
+		for name := range cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pairs := make([]string, 0, len(names))
+		for _, name := range names {
+			pairs = append(pairs, name+"="+cookies[name])
+		}
+		headers["Cookie"] = strings.Join(pairs, "; ")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, name+": "+headers[name])
+	}
+	return result
+}
+
+// mergeCookiePairs adds the name=value pairs of a Cookie header to cookies.
+func mergeCookiePairs(cookies map[string]string, header string) {
+	for _, pair := range strings.Split(header, ";") {
+		if k, v, found := strings.Cut(strings.TrimSpace(pair), "="); found {
+			cookies[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+}
+
+func (p *RequestProfile) sessionExpired() bool {
+	if p.SessionRefreshedAt == nil {
+		return true
+	}
+	ttl := p.SessionTTLSeconds
+	if ttl <= 0 {
+		ttl = defaultRequestProfileTTLSec
+	}
+	return time.Since(*p.SessionRefreshedAt) > time.Duration(ttl)*time.Second
+}
+
+func loginScriptsEnabled() bool {
+	return os.Getenv("REQUEST_PROFILE_LOGIN_SCRIPTS") == "true"
+}
+
+// refreshSession runs the login script and stores the session headers it prints.
+// The script runs in a tool container, never in the server process.
+func (p *RequestProfile) refreshSession() error {
+	if !loginScriptsEnabled() {
+		return fmt.Errorf("login scripts are disabled on this server (set REQUEST_PROFILE_LOGIN_SCRIPTS=true to enable)")
+	}
+
+	requestLoginMutex.Lock()
+	defer requestLoginMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestProfileLoginTimeout)
+	defer cancel()
+
+	result, err := ToolExecutor(requestProfileLoginTool).Exec(ctx, ExecRequest{
+		Args: []string{"sh", "-c", p.LoginScript},
+		Env:  []string{"SCOPE_TARGET_ID=" + p.ScopeTargetID, "REQUEST_PROFILE=" + p.Name},
+	})
+	now := time.Now()
+	p.SessionRefreshedAt = &now
+
+	if err != nil {
+		p.SessionError = err.Error()
+		if result != nil && strings.TrimSpace(result.Stderr) != "" {
+			p.SessionError = fmt.Sprintf("%v: %s", err, strings.TrimSpace(result.Stderr))
+		}
+	} else {
+		p.SessionError = ""
+		p.SessionHeaders = parseLoginScriptOutput(result.Stdout)
+	}
+
+	sessionJSON, _ := json.Marshal(p.SessionHeaders)
+	_, dbErr := dbPool.Exec(context.Background(), `
+		UPDATE http_request_profiles
+		SET session_headers = $1, session_refreshed_at = $2, session_error = NULLIF($3, '')
+		WHERE id = $4`,
+		sessionJSON, now, p.SessionError, p.ID)
+	if dbErr != nil {
+		log.Printf("[REQUEST-PROFILE] [ERROR] Failed to store session for profile %s: %v", p.ID, dbErr)
+	}
+
+	if p.SessionError != "" {
+		return fmt.Errorf("%s", p.SessionError)
+	}
+	log.Printf("[REQUEST-PROFILE] [INFO] Refreshed session for profile %s (%d headers)", p.Name, len(p.SessionHeaders))
+	return nil
+}
+
+func parseLoginScriptOutput(output string) map[string]string {
+	headers := make(map[string]string)
+	var cookies []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if name == "" || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "set-cookie":
+			if pair, _, _ := strings.Cut(value, ";"); strings.Contains(pair, "=") {
+				cookies = append(cookies, strings.TrimSpace(pair))
+			}
+		case "cookie":
+			cookies = append(cookies, value)
+		default:
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	if len(cookies) > 0 {
+		headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+	return headers
+}
+
+// RequestHeaderArgs renders headers as the tool's repeated header flag, or as a
+// single flag for tools with a HeaderJoin separator.
+func RequestHeaderArgs(tool string, headers []string) []string {
+	definition := toolRegistry[tool]
+	flag := definition.HeaderFlag
+	if flag == "" || len(headers) == 0 {
+		return nil
+	}
+	if definition.HeaderJoin != "" {
+		return []string{flag, strings.Join(headers, definition.HeaderJoin)}
+	}
+	args := make([]string, 0, len(headers)*2)
+	for _, header := range headers {
+		args = append(args, flag, header)
+	}
+	return args
+}
+
+// sensitiveHeaderName matches request headers whose values are credentials.
+const sensitiveHeaderName = `[a-z0-9-]*(?:authorization|cookie|token|secret|api-?key|session)[a-z0-9-]*`
+
+var (
+	sensitiveHeaderLine   = regexp.MustCompile(`(?im)^(\s*` + sensitiveHeaderName + `\s*:)[^\r\n]*`)
+	sensitiveHeaderQuoted = regexp.MustCompile(`(?i)'(` + sensitiveHeaderName + `\s*:)[^']*'`)
+)
+
+// redactCommandArgs hides credential header values in a tool's arguments, whether
+// passed as header flag values or inside a quoted `sh -c` string, so commands can
+// be stored with scans and logged without leaking a target's session.
+func redactCommandArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		arg = sensitiveHeaderQuoted.ReplaceAllString(arg, "'${1} [REDACTED]'")
+		redacted[i] = sensitiveHeaderLine.ReplaceAllString(arg, "${1} [REDACTED]")
+	}
+	return redacted
+}
+
+// shellQuote quotes a value for commands that run through `sh -c`.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// requestHeaderShellArgs is RequestHeaderArgs for tools launched through a shell string.
+func requestHeaderShellArgs(tool string, headers []string) string {
+	var b strings.Builder
+	for _, arg := range RequestHeaderArgs(tool, headers) {
+		b.WriteString(" ")
+		if strings.HasPrefix(arg, "-") {
+			b.WriteString(arg)
+		} else {
+			b.WriteString(shellQuote(arg))
+		}
+	}
+	return b.String()
+}
+
+// applyRequestHeaders sets profile headers on a Go request.
+func applyRequestHeaders(req *http.Request, headers []string) {
+	for _, header := range headers {
+		if name, value, found := strings.Cut(header, ":"); found {
+			req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+	}
+}
+
+// maskedSecret stands in for credentials in profile listings.
+const maskedSecret = "********"
+
+var sensitiveHeaderExact = regexp.MustCompile(`(?i)^` + sensitiveHeaderName + `$`)
+
+// masked returns a copy of the profile that is safe to list: credential
+// header values, cookie values, the bearer token, the login script and the
+// session it produced are replaced with maskedSecret.
+func (p *RequestProfile) masked() *RequestProfile {
+	masked := *p
+	masked.Headers = make(map[string]string, len(p.Headers))
+	for name, value := range p.Headers {
+		if sensitiveHeaderExact.MatchString(name) {
+			value = maskedSecret
+		}
+		masked.Headers[name] = value
+	}
+	masked.Cookies = make(map[string]string, len(p.Cookies))
+	for name := range p.Cookies {
+		masked.Cookies[name] = maskedSecret
+	}
+	if p.SessionHeaders != nil {
+		masked.SessionHeaders = make(map[string]string, len(p.SessionHeaders))
+		for name := range p.SessionHeaders {
+			masked.SessionHeaders[name] = maskedSecret
+		}
+	}
+	if p.BearerToken != "" {
+		masked.BearerToken = maskedSecret
+	}
+	if p.LoginScript != "" {
+		masked.LoginScript = maskedSecret
+	}
+	return &masked
+}
+
+// keepMaskedSecrets puts back the stored value wherever an update sent a
+// masked one, so saving a profile read from the list keeps its secrets.
+func (p *RequestProfile) keepMaskedSecrets(existing *RequestProfile) {
+	for name, value := range p.Headers {
+		if value == maskedSecret {
+			p.Headers[name] = existing.Headers[name]
+		}
+	}
+	for name, value := range p.Cookies {
+		if value == maskedSecret {
+			p.Cookies[name] = existing.Cookies[name]
+		}
+	}
+	if p.BearerToken == maskedSecret {
+		p.BearerToken = existing.BearerToken
+	}
+	if p.LoginScript == maskedSecret {
+		p.LoginScript = existing.LoginScript
+	}
+}
+
+// GetRequestProfiles lists a target's profiles with their secrets masked;
+// GetRequestProfile returns one profile in full.
+func GetRequestProfiles(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT `+requestProfileColumns+` FROM http_request_profiles WHERE scope_target_id = $1 ORDER BY is_default DESC, name`,
+		scopeTargetID)
+	if err != nil {
+		http.Error(w, "Failed to fetch request profiles", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	profiles := []*RequestProfile{}
+	for rows.Next() {
+		profile, err := scanRequestProfile(rows)
+		if err != nil {
+			log.Printf("[REQUEST-PROFILE] [ERROR] Failed to scan profile: %v", err)
+			continue
+		}
+		profiles = append(profiles, profile.masked())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+func GetRequestProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := getRequestProfile(mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Request profile not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func decodeRequestProfile(w http.ResponseWriter, r *http.Request) (*RequestProfile, bool) {
+	var profile RequestProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		http.Error(w, "Profile name is required", http.StatusBadRequest)
+		return nil, false
+	}
+	for name, value := range profile.Headers {
+		if strings.ContainsAny(name, ": \t\r\n") || strings.ContainsAny(value, "\r\n") {
+			http.Error(w, fmt.Sprintf("Invalid header %q", name), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	if profile.Headers == nil {
+		profile.Headers = map[string]string{}
+	}
+	if profile.Cookies == nil {
+		profile.Cookies = map[string]string{}
+	}
+	if profile.LoginScript != "" && !loginScriptsEnabled() {
+		http.Error(w, "Login scripts are disabled on this server", http.StatusForbidden)
+		return nil, false
+	}
+	if profile.SessionTTLSeconds <= 0 {
+		profile.SessionTTLSeconds = defaultRequestProfileTTLSec
+	}
+	return &profile, true
+}
+
+// clearOtherDefaults keeps at most one default profile per target.
+func clearOtherDefaults(ctx context.Context, scopeTargetID, profileID string) error {
+	_, err := dbPool.Exec(ctx, `
+		UPDATE http_request_profiles SET is_default = FALSE WHERE scope_target_id = $1 AND id <> $2 AND is_default`,
+		scopeTargetID, profileID)
+	return err
+}
+
+func CreateRequestProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := decodeRequestProfile(w, r)
+	if !ok {
+		return
+	}
+	profile.ScopeTargetID = mux.Vars(r)["id"]
+
+	headersJSON, _ := json.Marshal(profile.Headers)
+	cookiesJSON, _ := json.Marshal(profile.Cookies)
+	err := dbPool.QueryRow(context.Background(), `
+		INSERT INTO http_request_profiles
+			(scope_target_id, name, headers, cookies, bearer_token, user_agent, login_script, session_ttl_seconds, is_default)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+		RETURNING id, created_at, updated_at`,
+		profile.ScopeTargetID, profile.Name, headersJSON, cookiesJSON, profile.BearerToken, profile.UserAgent,
+		profile.LoginScript, profile.SessionTTLSeconds, profile.IsDefault).Scan(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		log.Printf("[REQUEST-PROFILE] [ERROR] Failed to create profile %s: %v", profile.Name, err)
+		http.Error(w, "Failed to create request profile", http.StatusInternalServerError)
+		return
+	}
+	if profile.IsDefault {
+		clearOtherDefaults(context.Background(), profile.ScopeTargetID, profile.ID)
+	}
+	invalidateRequestHeaders(profile.ScopeTargetID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func UpdateRequestProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := decodeRequestProfile(w, r)
+	if !ok {
+		return
+	}
+	existing, err := getRequestProfile(mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Request profile not found", http.StatusNotFound)
+		return
+	}
+	profile.keepMaskedSecrets(existing)
+
+	headersJSON, _ := json.Marshal(profile.Headers)
+	cookiesJSON, _ := json.Marshal(profile.Cookies)
+	// A changed login script invalidates the cached session.
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE http_request_profiles
+		SET name = $1, headers = $2, cookies = $3, bearer_token = NULLIF($4, ''), user_agent = NULLIF($5, ''),
+		    login_script = NULLIF($6, ''), session_ttl_seconds = $7, is_default = $8, updated_at = NOW(),
+		    session_refreshed_at = CASE WHEN COALESCE(login_script, '') = $6 THEN session_refreshed_at END
+		WHERE id = $9`,
+		profile.Name, headersJSON, cookiesJSON, profile.BearerToken, profile.UserAgent, profile.LoginScript,
+		profile.SessionTTLSeconds, profile.IsDefault, existing.ID)
+	if err != nil {
+		log.Printf("[REQUEST-PROFILE] [ERROR] Failed to update profile %s: %v", existing.ID, err)
+		http.Error(w, "Failed to update request profile", http.StatusInternalServerError)
+		return
+	}
+	if profile.IsDefault {
+		clearOtherDefaults(context.Background(), existing.ScopeTargetID, existing.ID)
+	}
+	invalidateRequestHeaders(existing.ScopeTargetID)
+
+	updated, err := getRequestProfile(existing.ID)
+	if err != nil {
+		http.Error(w, "Failed to reload request profile", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func DeleteRequestProfile(w http.ResponseWriter, r *http.Request) {
+	existing, err := getRequestProfile(mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Request profile not found", http.StatusNotFound)
+		return
+	}
+	if _, err := dbPool.Exec(context.Background(), `DELETE FROM http_request_profiles WHERE id = $1`, existing.ID); err != nil {
+		http.Error(w, "Failed to delete request profile", http.StatusInternalServerError)
+		return
+	}
+	invalidateRequestHeaders(existing.ScopeTargetID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func SetDefaultRequestProfile(w http.ResponseWriter, r *http.Request) {
+	existing, err := getRequestProfile(mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Request profile not found", http.StatusNotFound)
+		return
+	}
+	ctx := context.Background()
+	if err := clearOtherDefaults(ctx, existing.ScopeTargetID, existing.ID); err == nil {
+		_, err = dbPool.Exec(ctx, `UPDATE http_request_profiles SET is_default = TRUE, updated_at = NOW() WHERE id = $1`, existing.ID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to set default request profile", http.StatusInternalServerError)
+		return
+	}
+	invalidateRequestHeaders(existing.ScopeTargetID)
+	existing.IsDefault = true
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existing.masked())
+}
+
+// RefreshRequestProfileSession runs the login script now and returns the header
+// names it produced, so the session can be checked before a scan.
+func RefreshRequestProfileSession(w http.ResponseWriter, r *http.Request) {
+	profile, err := getRequestProfile(mux.Vars(r)["profile_id"])
+	if err != nil {
+		http.Error(w, "Request profile not found", http.StatusNotFound)
+		return
+	}
+	if profile.LoginScript == "" {
+		http.Error(w, "Profile has no login script", http.StatusBadRequest)
+		return
+	}
+	if !loginScriptsEnabled() {
+		http.Error(w, "Login scripts are disabled on this server", http.StatusForbidden)
+		return
+	}
+
+	err = profile.refreshSession()
+	invalidateRequestHeaders(profile.ScopeTargetID)
+
+	names := make([]string, 0, len(profile.SessionHeaders))
+	for name := range profile.SessionHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := map[string]interface{}{
+		"success":              err == nil,
+		"session_headers":      names,
+		"session_refreshed_at": profile.SessionRefreshedAt,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolvedHeadersCookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile RequestProfile
+		want    []string
+	}{
+		{
+			"cookie header only",
+			RequestProfile{Headers: map[string]string{"Cookie": "a=1; b=2"}},
+			[]string{"Cookie: a=1; b=2"},
+		},
+		{
+			"cookie header and cookie list combine",
+			RequestProfile{
+				Headers: map[string]string{"cookie": "a=1; b=2", "X-Trace": "1"},
+				Cookies: map[string]string{"c": "3"},
+			},
+			[]string{"Cookie: a=1; b=2; c=3", "X-Trace: 1"},
+		},
+		{
+			"cookie list wins per name",
+			RequestProfile{
+				Headers: map[string]string{"Cookie": "a=1"},
+				Cookies: map[string]string{"a": "2"},
+			},
+			[]string{"Cookie: a=2"},
+		},
+		{
+			"bearer token and user agent",
+			RequestProfile{BearerToken: "t", UserAgent: "ua"},
+			[]string{"Authorization: Bearer t", "User-Agent: ua"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.resolvedHeaders(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolvedHeaders() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestProfileMasking(t *testing.T) {
+	profile := &RequestProfile{
+		Name:           "admin",
+		Headers:        map[string]string{"X-Api-Key": "k", "Accept": "*/*"},
+		Cookies:        map[string]string{"session": "s"},
+		BearerToken:    "t",
+		LoginScript:    "curl -d 'pw=x' https://example.com/login",
+		SessionHeaders: map[string]string{"Cookie": "sid=1"},
+	}
+	masked := profile.masked()
+	want := &RequestProfile{
+		Name:           "admin",
+		Headers:        map[string]string{"X-Api-Key": maskedSecret, "Accept": "*/*"},
+		Cookies:        map[string]string{"session": maskedSecret},
+		BearerToken:    maskedSecret,
+		LoginScript:    maskedSecret,
+		SessionHeaders: map[string]string{"Cookie": maskedSecret},
+	}
+	if !reflect.DeepEqual(masked, want) {
+		t.Errorf("masked() = %+v, want %+v", masked, want)
+	}
+	if profile.Headers["X-Api-Key"] != "k" || profile.Cookies["session"] != "s" {
+		t.Error("masked() changed the original profile")
+	}
+
+	update := masked.masked()
+	update.Headers["Accept"] = "application/json"
+	update.Cookies["theme"] = "dark"
+	update.keepMaskedSecrets(profile)
+	if update.Headers["X-Api-Key"] != "k" || update.Headers["Accept"] != "application/json" {
+		t.Errorf("headers after keepMaskedSecrets = %v", update.Headers)
+	}
+	if update.Cookies["session"] != "s" || update.Cookies["theme"] != "dark" {
+		t.Errorf("cookies after keepMaskedSecrets = %v", update.Cookies)
+	}
+	if update.BearerToken != "t" || update.LoginScript != profile.LoginScript {
+		t.Errorf("bearer token %q and login script %q were not kept", update.BearerToken, update.LoginScript)
+	}
+}
+
+func TestScanRequestProfileForNone(t *testing.T) {
+	if profile := scanRequestProfileFor("target", noRequestProfile); profile != nil {
+		t.Errorf("scanRequestProfileFor(%q) = %+v, want no profile", noRequestProfile, profile)
+	}
+	if profile := scanRequestProfileFor("", "profile"); profile != nil {
+		t.Errorf("scanRequestProfileFor without a scope target = %+v, want no profile", profile)
+	}
+}
+
+func TestInvalidateRequestHeaders(t *testing.T) {
+	requestHeaderMutex.Lock()
+	for _, key := range []string{"a|", "a|p1", "a|none", "ab|"} {
+		requestHeaderCache[key] = cachedRequestHeaders{}
+	}
+	requestHeaderMutex.Unlock()
+	t.Cleanup(func() { invalidateRequestHeaders("ab") })
+
+	invalidateRequestHeaders("a")
+	requestHeaderMutex.Lock()
+	defer requestHeaderMutex.Unlock()
+	for _, key := range []string{"a|", "a|p1", "a|none"} {
+		if _, ok := requestHeaderCache[key]; ok {
+			t.Errorf("cache entry %q survived invalidation", key)
+		}
+	}
+	if _, ok := requestHeaderCache["ab|"]; !ok {
+		t.Error("invalidating target a dropped target ab's entry")
+	}
+}
@@ -0,0 +1,518 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// defaultResolvers seeds an empty pool; they are the resolvers amass used to be
+// launched with.
+var defaultResolvers = []struct{ Address, Label string }{
+	{"8.8.8.8", "Google"}, {"8.8.4.4", "Google Secondary"},
+	{"1.1.1.1", "Cloudflare"}, {"1.0.0.1", "Cloudflare Secondary"},
+	{"9.9.9.9", "Quad9"}, {"149.112.112.112", "Quad9 Secondary"},
+	{"64.6.64.6", "Verisign"}, {"64.6.65.6", "Verisign Secondary"},
+	{"208.67.222.222", "OpenDNS"}, {"208.67.220.220", "OpenDNS Secondary"},
+	{"76.76.19.19", "Alternate DNS"}, {"76.223.122.150", "Alternate DNS Secondary"},
+	{"8.26.56.26", "Comodo Secure DNS"}, {"8.20.247.20", "Comodo Secure DNS Secondary"},
+	{"185.228.168.9", "CleanBrowsing"}, {"185.228.169.9", "CleanBrowsing Secondary"},
+	{"94.140.14.14", "AdGuard"}, {"94.140.15.15", "AdGuard Secondary"},
+	{"77.88.8.8", "Yandex DNS"}, {"77.88.8.1", "Yandex DNS Secondary"},
+}
+
+// resolverBaseline maps names with long-stable answers to the addresses a
+// truthful resolver must return. Any other answer means the resolver rewrites
+// responses.
+var resolverBaseline = map[string][]string{
+	"one.one.one.one": {"1.1.1.1", "1.0.0.1"},
+	"dns.google":      {"8.8.8.8", "8.8.4.4"},
+}
+
+// resolverNXDomainZone has no wildcard record, so a random label below it must
+// come back NXDOMAIN. Resolvers that answer it hijack NXDOMAIN responses.
+const resolverNXDomainZone = "example.com"
+
+const (
+	resolverCheckTimeout     = 3 * time.Second
+	resolverMaxLatency       = 1500 * time.Millisecond
+	defaultResolverInterval  = 30 * time.Minute
+	resolverCheckConcurrency = 16
+)
+
+type DNSResolver struct {
+	ID                  string     `json:"id"`
+	Address             string     `json:"address"`
+	Label               string     `json:"label"`
+	Enabled             bool       `json:"enabled"`
+	Healthy             bool       `json:"healthy"`
+	LatencyMs           *int       `json:"latency_ms"`
+	LastChecked         *time.Time `json:"last_checked"`
+	LastError           string     `json:"last_error"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+var (
+	resolverMutex    sync.RWMutex
+	resolverHealthy  []string
+	resolverLoadedAt time.Time
+
+	resolverCounter uint64
+)
+
+// normalizeResolverAddress accepts "ip" or "ip:port" and returns "ip:port".
+func normalizeResolverAddress(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	host, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		host, port = strings.Trim(raw, "[]"), "53"
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("resolver address must be an IP, got %q", raw)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func loadResolverPool() {
+	var healthy []string
+	if dbPool != nil {
+		rows, err := dbPool.Query(context.Background(), `
+			SELECT address FROM dns_resolvers
+			WHERE enabled AND healthy
+			ORDER BY latency_ms ASC NULLS LAST, address`)
+		if err != nil {
+			log.Printf("[RESOLVERS] [ERROR] Failed to load resolver pool: %v", err)
+		} else {
+			for rows.Next() {
+				var address string
+				if err := rows.Scan(&address); err == nil {
+					healthy = append(healthy, address)
+				}
+			}
+			rows.Close()
+		}
+	}
+
+	resolverMutex.Lock()
+	resolverHealthy = healthy
+	resolverLoadedAt = time.Now()
+	resolverMutex.Unlock()
+}
+
+func invalidateResolverPool() {
+	resolverMutex.Lock()
+	resolverLoadedAt = time.Time{}
+	resolverMutex.Unlock()
+}
+
+// HealthyResolvers returns the validated resolvers, fastest first, as "ip:port".
+// Until the pool has been validated it falls back to the built-in defaults.
+func HealthyResolvers() []string {
+	resolverMutex.RLock()
+	stale := time.Since(resolverLoadedAt) > 30*time.Second
+	resolverMutex.RUnlock()
+	if stale {
+		loadResolverPool()
+	}
+
+	resolverMutex.RLock()
+	defer resolverMutex.RUnlock()
+	if len(resolverHealthy) > 0 {
+		return append([]string(nil), resolverHealthy...)
+	}
+	fallback := make([]string, len(defaultResolvers))
+	for i, r := range defaultResolvers {
+		fallback[i] = net.JoinHostPort(r.Address, "53")
+	}
+	return fallback
+}
+
+// resolverIPs strips the default port, since most tools only accept bare IPs.
+func resolverIPs(resolvers []string) []string {
+	out := make([]string, 0, len(resolvers))
+	for _, r := range resolvers {
+		if host, port, err := net.SplitHostPort(r); err == nil && port == "53" {
+			out = append(out, host)
+		} else {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ResolverArgs repeats flag once per healthy resolver, e.g. amass "-r".
+func ResolverArgs(flag string, limit int) []string {
+	resolvers := resolverIPs(HealthyResolvers())
+	if limit > 0 && len(resolvers) > limit {
+		resolvers = resolvers[:limit]
+	}
+	args := make([]string, 0, len(resolvers)*2)
+	for _, r := range resolvers {
+		args = append(args, flag, r)
+	}
+	return args
+}
+
+// WriteResolverFile writes the healthy pool (plus any extra lines) into the
+// tool's filesystem and returns the path to pass on its command line, with a
+// cleanup func that removes the file once the tool has run. When the file cannot
+// be written it returns fallback, the resolver list baked into the image.
+func WriteResolverFile(ctx context.Context, ex Executor, extra []string, fallback string) (string, func()) {
+	lines := resolverIPs(HealthyResolvers())
+	for _, line := range extra {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	toolPath := fmt.Sprintf("/tmp/resolvers_%s.txt", uuid.New().String())
+	if err := WriteToolFile(ctx, ex, toolPath, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		log.Printf("[RESOLVERS] [WARN] Failed to write resolver file, using %s: %v", fallback, err)
+		return fallback, func() {}
+	}
+	return ex.Path(toolPath), func() {
+		ex.Exec(context.Background(), ExecRequest{Args: []string{"rm", "-f", ex.Path(toolPath)}})
+	}
+}
+
+// PoolResolver is a Go resolver that spreads queries over the healthy pool.
+func PoolResolver(dialTimeout time.Duration) *net.Resolver {
+	resolvers := HealthyResolvers()
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: dialTimeout}
+			n := atomic.AddUint64(&resolverCounter, 1)
+			return d.DialContext(ctx, network, resolvers[n%uint64(len(resolvers))])
+		},
+	}
+}
+
+// lookupIPWithPool is net.LookupIP through the resolver pool.
+func lookupIPWithPool(host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return PoolResolver(2*time.Second).LookupIP(ctx, "ip", host)
+}
+
+func singleResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: resolverCheckTimeout}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// checkResolver verifies address answers the baseline names truthfully, returns
+// NXDOMAIN for names that do not exist, and does so quickly.
+func checkResolver(address string) (time.Duration, error) {
+	resolver := singleResolver(address)
+	var slowest time.Duration
+
+	for name, expected := range resolverBaseline {
+		ctx, cancel := context.WithTimeout(context.Background(), resolverCheckTimeout)
+		start := time.Now()
+		ips, err := resolver.LookupIP(ctx, "ip4", name)
+		elapsed := time.Since(start)
+		cancel()
+		if err != nil {
+			return 0, fmt.Errorf("baseline %s: %v", name, err)
+		}
+		if elapsed > slowest {
+			slowest = elapsed
+		}
+		for _, ip := range ips {
+			if !containsToken(expected, ip.String()) {
+				return slowest, fmt.Errorf("baseline %s answered %s, expected one of %s", name, ip, strings.Join(expected, ", "))
+			}
+		}
+	}
+
+	label := make([]byte, 8)
+	rand.Read(label)
+	probe := "ars0n-" + hex.EncodeToString(label) + "." + resolverNXDomainZone
+	ctx, cancel := context.WithTimeout(context.Background(), resolverCheckTimeout)
+	ips, err := resolver.LookupIP(ctx, "ip4", probe)
+	cancel()
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(ips) > 0:
+		return slowest, fmt.Errorf("NXDOMAIN hijack: %s answered %s", probe, ips[0])
+	case err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound):
+		return slowest, fmt.Errorf("NXDOMAIN probe: %v", err)
+	}
+
+	if slowest > resolverMaxLatency {
+		return slowest, fmt.Errorf("latency %s exceeds %s", slowest.Round(time.Millisecond), resolverMaxLatency)
+	}
+	return slowest, nil
+}
+
+func recordResolverCheck(id string, latency time.Duration, checkErr error) {
+	var latencyMs interface{}
+	if latency > 0 {
+		latencyMs = int(latency / time.Millisecond)
+	}
+	lastError := ""
+	if checkErr != nil {
+		lastError = checkErr.Error()
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE dns_resolvers
+		SET healthy = $1, latency_ms = $2, last_checked = NOW(), last_error = NULLIF($3, ''),
+			consecutive_failures = CASE WHEN $1 THEN 0 ELSE consecutive_failures + 1 END
+		WHERE id = $4`,
+		checkErr == nil, latencyMs, lastError, id)
+	if err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to record check for resolver %s: %v", id, err)
+	}
+}
+
+// ValidateResolverPool checks every enabled resolver and returns how many passed.
+func ValidateResolverPool() (int, int) {
+	rows, err := dbPool.Query(context.Background(), `SELECT id, address FROM dns_resolvers WHERE enabled`)
+	if err != nil {
+		log.Printf("[RESOLVERS] [ERROR] Failed to list resolvers: %v", err)
+		return 0, 0
+	}
+	type target struct{ id, address string }
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.address); err == nil {
+			targets = append(targets, t)
+		}
+	}
+	rows.Close()
+
+	var healthy int32
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, resolverCheckConcurrency)
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			latency, err := checkResolver(t.address)
+			if err != nil {
+				log.Printf("[RESOLVERS] [WARN] Resolver %s failed validation: %v", t.address, err)
+			} else {
+				atomic.AddInt32(&healthy, 1)
+			}
+			recordResolverCheck(t.id, latency, err)
+		}(t)
+	}
+	wg.Wait()
+	invalidateResolverPool()
+
+	log.Printf("[RESOLVERS] [INFO] Validated %d resolvers, %d healthy", len(targets), healthy)
+	return len(targets), int(healthy)
+}
+
+func seedResolverPool() {
+	var count int
+	if err := dbPool.QueryRow(context.Background(), `SELECT COUNT(*) FROM dns_resolvers`).Scan(&count); err != nil || count > 0 {
+		return
+	}
+	for _, r := range defaultResolvers {
+		address, _ := normalizeResolverAddress(r.Address)
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO dns_resolvers (address, label) VALUES ($1, $2) ON CONFLICT (address) DO NOTHING`,
+			address, r.Label)
+		if err != nil {
+			log.Printf("[RESOLVERS] [ERROR] Failed to seed resolver %s: %v", address, err)
+		}
+	}
+	log.Printf("[RESOLVERS] [INFO] Seeded resolver pool with %d defaults", len(defaultResolvers))
+}
+
+// StartResolverPoolValidator seeds an empty pool and revalidates it on
+// RESOLVER_VALIDATE_INTERVAL (default 30m).
+func StartResolverPoolValidator() {
+	interval := defaultResolverInterval
+	if raw := os.Getenv("RESOLVER_VALIDATE_INTERVAL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			interval = d
+		}
+	}
+
+	go func() {
+		seedResolverPool()
+		for {
+			ValidateResolverPool()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func GetDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, address, COALESCE(label, ''), enabled, healthy, latency_ms, last_checked,
+			COALESCE(last_error, ''), consecutive_failures
+		FROM dns_resolvers
+		ORDER BY healthy DESC, latency_ms ASC NULLS LAST, address`)
+	if err != nil {
+		http.Error(w, "Failed to fetch resolvers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resolvers := []DNSResolver{}
+	for rows.Next() {
+		var res DNSResolver
+		if err := rows.Scan(&res.ID, &res.Address, &res.Label, &res.Enabled, &res.Healthy, &res.LatencyMs,
+			&res.LastChecked, &res.LastError, &res.ConsecutiveFailures); err != nil {
+			continue
+		}
+		resolvers = append(resolvers, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"resolvers": resolvers,
+		"in_use":    HealthyResolvers(),
+	})
+}
+
+// AddDNSResolvers accepts one address or a newline-separated list and validates
+// each one before it is used, resolverCheckConcurrency at a time.
+func AddDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Address   string `json:"address"`
+		Addresses string `json:"addresses"`
+		Label     string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var added []DNSResolver
+	var rejected []string
+	for _, raw := range strings.FieldsFunc(payload.Address+"\n"+payload.Addresses, func(c rune) bool {
+		return c == '\n' || c == ',' || c == ' '
+	}) {
+		address, err := normalizeResolverAddress(raw)
+		if err != nil {
+			rejected = append(rejected, raw)
+			continue
+		}
+		res := DNSResolver{Address: address, Label: payload.Label, Enabled: true}
+		err = dbPool.QueryRow(context.Background(), `
+			INSERT INTO dns_resolvers (address, label) VALUES ($1, NULLIF($2, ''))
+			ON CONFLICT (address) DO UPDATE SET enabled = TRUE
+			RETURNING id`, address, payload.Label).Scan(&res.ID)
+		if err != nil {
+			log.Printf("[RESOLVERS] [ERROR] Failed to add resolver %s: %v", address, err)
+			rejected = append(rejected, raw)
+			continue
+		}
+		added = append(added, res)
+	}
+	if len(added) == 0 {
+		http.Error(w, "No valid resolver addresses provided", http.StatusBadRequest)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, resolverCheckConcurrency)
+	for i := range added {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *DNSResolver) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			latency, checkErr := checkResolver(res.Address)
+			recordResolverCheck(res.ID, latency, checkErr)
+			res.Healthy = checkErr == nil
+			if checkErr != nil {
+				res.LastError = checkErr.Error()
+			}
+			if latency > 0 {
+				ms := int(latency / time.Millisecond)
+				res.LatencyMs = &ms
+			}
+		}(&added[i])
+	}
+	wg.Wait()
+	invalidateResolverPool()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"added":    added,
+		"rejected": rejected,
+	})
+}
+
+// UpdateDNSResolver changes the label and/or enabled flag; fields left out of the
+// body keep their value.
+func UpdateDNSResolver(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Label   *string `json:"label"`
+		Enabled *bool   `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || (payload.Label == nil && payload.Enabled == nil) {
+		http.Error(w, "Invalid request body. label or enabled is required.", http.StatusBadRequest)
+		return
+	}
+	tag, err := dbPool.Exec(context.Background(), `
+		UPDATE dns_resolvers
+		SET label = CASE WHEN $1::text IS NULL THEN label ELSE NULLIF($1, '') END,
+			enabled = COALESCE($2, enabled)
+		WHERE id = $3`,
+		payload.Label, payload.Enabled, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to update resolver", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Resolver not found", http.StatusNotFound)
+		return
+	}
+	invalidateResolverPool()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func DeleteDNSResolver(w http.ResponseWriter, r *http.Request) {
+	tag, err := dbPool.Exec(context.Background(), `DELETE FROM dns_resolvers WHERE id = $1`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to delete resolver", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Resolver not found", http.StatusNotFound)
+		return
+	}
+	invalidateResolverPool()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ValidateDNSResolvers(w http.ResponseWriter, r *http.Request) {
+	checked, healthy := ValidateResolverPool()
+	in := HealthyResolvers()
+	sort.Strings(in)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"checked": checked,
+		"healthy": healthy,
+		"in_use":  in,
+	})
+}
@@ -0,0 +1,598 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Import formats accepted by the result importer.
+const (
+	importFormatNmap       = "nmap"
+	importFormatMasscan    = "masscan"
+	importFormatNuclei     = "nuclei"
+	importFormatFfuf       = "ffuf"
+	importFormatHttpx      = "httpx"
+	importFormatSubdomains = "subdomains"
+	importFormatBurp       = "burp"
+)
+
+var importFormats = []string{
+	importFormatNmap, importFormatMasscan, importFormatNuclei, importFormatFfuf,
+	importFormatHttpx, importFormatSubdomains, importFormatBurp,
+}
+
+// importedResults is what a parsed file contributes, in tool-neutral form.
+type importedResults struct {
+	Format     string
+	Hosts      []importedHost
+	HTTP       []importedHTTPResult
+	Endpoints  []importedEndpoint
+	Findings   []NucleiFinding
+	Subdomains []string
+}
+
+type importedHost struct {
+	IP        string
+	Hostnames []string
+	Ports     []importedPort
+}
+
+type importedPort struct {
+	Port    int
+	Service string
+	Product string
+	Version string
+	Banner  string
+	TLS     bool
+}
+
+type importedHTTPResult struct {
+	URL           string
+	IP            string
+	StatusCode    int
+	Title         string
+	WebServer     string
+	ContentLength int
+	Technologies  []string
+}
+
+type importedEndpoint struct {
+	URL        string
+	StatusCode int
+}
+
+// detectImportFormat guesses the tool that wrote data from its structure.
+func detectImportFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		if bytes.Contains(trimmed, []byte("<issues")) {
+			return importFormatBurp
+		}
+		if bytes.Contains(trimmed, []byte(`scanner="masscan"`)) {
+			return importFormatMasscan
+		}
+		if bytes.Contains(trimmed, []byte("<nmaprun")) {
+			return importFormatNmap
+		}
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return importFormatMasscan
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		line, _, _ := bytes.Cut(trimmed, []byte("\n"))
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(line, &fields) != nil {
+			// A single pretty-printed document
+			if json.Unmarshal(trimmed, &fields) == nil && fields["results"] != nil {
+				return importFormatFfuf
+			}
+			if bytes.Contains(trimmed, []byte(`"ports"`)) && bytes.Contains(trimmed, []byte(`"ip"`)) {
+				return importFormatMasscan
+			}
+			return ""
+		}
+		switch {
+		case fields["results"] != nil:
+			return importFormatFfuf
+		case fields["template-id"] != nil || fields["template_id"] != nil:
+			return importFormatNuclei
+		case fields["ip"] != nil && fields["ports"] != nil:
+			return importFormatMasscan
+		case fields["url"] != nil:
+			return importFormatHttpx
+		}
+	default:
+		return importFormatSubdomains
+	}
+	return ""
+}
+
+func parseImportFile(format string, data []byte) (*importedResults, error) {
+	var results *importedResults
+	var err error
+	switch format {
+	case importFormatNmap, importFormatMasscan:
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			results, err = parseNmapXML(data)
+		} else {
+			results, err = parseMasscanJSON(data)
+		}
+	case importFormatNuclei:
+		results, err = parseNucleiJSONL(data)
+	case importFormatFfuf:
+		results, err = parseFfufJSON(data)
+	case importFormatHttpx:
+		results, err = parseHttpxJSONL(data)
+	case importFormatSubdomains:
+		results, err = parseSubdomainList(data)
+	case importFormatBurp:
+		results, err = parseBurpXML(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	results.Format = format
+	return results, nil
+}
+
+// jsonLines calls fn for every non-empty line of a JSON lines file.
+func jsonLines(data []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+type nmapRun struct {
+	Hosts []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   int    `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name    string `xml:"name,attr"`
+			Product string `xml:"product,attr"`
+			Version string `xml:"version,attr"`
+			Tunnel  string `xml:"tunnel,attr"`
+			Banner  string `xml:"banner,attr"`
+		} `xml:"service"`
+		Scripts []struct {
+			ID     string `xml:"id,attr"`
+			Output string `xml:"output,attr"`
+		} `xml:"script"`
+	} `xml:"ports>port"`
+}
+
+// parseNmapXML reads nmap -oX output, and masscan -oX, which uses the same
+// layout. Hosts count when they were up or had an open TCP port.
+func parseNmapXML(data []byte) (*importedResults, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("invalid nmap XML: %v", err)
+	}
+
+	byIP := make(map[string]*importedHost)
+	var order []string
+	for _, h := range run.Hosts {
+		var ip string
+		for _, address := range h.Addresses {
+			if address.AddrType == "ipv4" || address.AddrType == "ipv6" {
+				ip = address.Addr
+			}
+		}
+		if _, err := netip.ParseAddr(ip); err != nil {
+			continue
+		}
+
+		// masscan writes one host element per open port
+		host := byIP[ip]
+		if host == nil {
+			host = &importedHost{IP: ip}
+		}
+		for _, hostname := range h.Hostnames {
+			host.Hostnames = appendUnique(host.Hostnames, strings.ToLower(hostname.Name))
+		}
+		for _, p := range h.Ports {
+			if p.State.State != "open" || (p.Protocol != "" && p.Protocol != "tcp") {
+				continue
+			}
+			port := importedPort{
+				Port:    p.PortID,
+				Service: p.Service.Name,
+				Product: p.Service.Product,
+				Version: p.Service.Version,
+				Banner:  p.Service.Banner,
+				TLS:     p.Service.Tunnel == "ssl" || p.Service.Name == "https",
+			}
+			for _, script := range p.Scripts {
+				if script.ID == "banner" && port.Banner == "" {
+					port.Banner = script.Output
+				}
+			}
+			if port.Service == "" {
+				port.Service = "unknown"
+			}
+			host.Ports = append(host.Ports, port)
+		}
+		if byIP[ip] == nil && (h.Status.State == "up" || len(host.Ports) > 0) {
+			byIP[ip] = host
+			order = append(order, ip)
+		}
+	}
+
+	var results importedResults
+	for _, ip := range order {
+		results.Hosts = append(results.Hosts, *byIP[ip])
+	}
+	return &results, nil
+}
+
+var trailingCommaPattern = regexp.MustCompile(`,\s*\]\s*$`)
+
+// parseMasscanJSON reads masscan -oJ output: an array of per-port records,
+// which older masscan versions end with a trailing comma, or one record per line.
+func parseMasscanJSON(data []byte) (*importedResults, error) {
+	type masscanRecord struct {
+		IP    string `json:"ip"`
+		Ports []struct {
+			Port    int    `json:"port"`
+			Proto   string `json:"proto"`
+			Status  string `json:"status"`
+			Service struct {
+				Name   string `json:"name"`
+				Banner string `json:"banner"`
+			} `json:"service"`
+		} `json:"ports"`
+	}
+
+	var records []masscanRecord
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trailingCommaPattern.ReplaceAll(trimmed, []byte("]")), &records); err != nil {
+			return nil, fmt.Errorf("invalid masscan JSON: %v", err)
+		}
+	} else {
+		err := jsonLines(trimmed, func(line []byte) error {
+			var record masscanRecord
+			if err := json.Unmarshal(bytes.TrimSuffix(line, []byte(",")), &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid masscan JSON: %v", err)
+		}
+	}
+
+	byIP := make(map[string]int)
+	var results importedResults
+	for _, record := range records {
+		if _, err := netip.ParseAddr(record.IP); err != nil {
+			continue
+		}
+		idx, seen := byIP[record.IP]
+		if !seen {
+			idx = len(results.Hosts)
+			byIP[record.IP] = idx
+			results.Hosts = append(results.Hosts, importedHost{IP: record.IP})
+		}
+		host := &results.Hosts[idx]
+		for _, p := range record.Ports {
+			if p.Proto != "tcp" || (p.Status != "" && p.Status != "open") {
+				continue
+			}
+			// Banner records repeat the port; merge them into the open one
+			merged := false
+			for i := range host.Ports {
+				if host.Ports[i].Port == p.Port {
+					if p.Service.Name != "" {
+						host.Ports[i].Service = p.Service.Name
+					}
+					if p.Service.Banner != "" {
+						host.Ports[i].Banner = p.Service.Banner
+					}
+					merged = true
+				}
+			}
+			if !merged {
+				service := p.Service.Name
+				if service == "" {
+					service = "unknown"
+				}
+				host.Ports = append(host.Ports, importedPort{Port: p.Port, Service: service, Banner: p.Service.Banner})
+			}
+		}
+	}
+	return &results, nil
+}
+
+// parseNucleiJSONL reads nuclei -jsonl output. Newer nuclei versions write
+// template_id and matched_at; both spellings are accepted.
+func parseNucleiJSONL(data []byte) (*importedResults, error) {
+	var results importedResults
+	err := jsonLines(data, func(line []byte) error {
+		var finding NucleiFinding
+		if err := json.Unmarshal(line, &finding); err != nil {
+			return err
+		}
+		var alternate struct {
+			TemplateID string `json:"template_id"`
+			MatchedAt  string `json:"matched_at"`
+		}
+		json.Unmarshal(line, &alternate)
+		if finding.TemplateID == "" {
+			finding.TemplateID = alternate.TemplateID
+		}
+		if finding.MatchedAt == "" {
+			finding.MatchedAt = alternate.MatchedAt
+		}
+		if finding.TemplateID == "" {
+			return fmt.Errorf("not a nuclei result")
+		}
+		results.Findings = append(results.Findings, finding)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid nuclei JSONL: %v", err)
+	}
+	return &results, nil
+}
+
+// parseFfufJSON reads ffuf -of json output.
+func parseFfufJSON(data []byte) (*importedResults, error) {
+	var output struct {
+		Results []struct {
+			URL    string `json:"url"`
+			Status int    `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid ffuf JSON: %v", err)
+	}
+	var results importedResults
+	for _, r := range output.Results {
+		if parsed, err := url.Parse(r.URL); err == nil && parsed.Host != "" {
+			results.Endpoints = append(results.Endpoints, importedEndpoint{URL: r.URL, StatusCode: r.Status})
+		}
+	}
+	return &results, nil
+}
+
+// parseHttpxJSONL reads httpx -json output. Field names changed from
+// dashes to underscores across httpx releases; both are accepted.
+func parseHttpxJSONL(data []byte) (*importedResults, error) {
+	var results importedResults
+	err := jsonLines(data, func(line []byte) error {
+		var r struct {
+			URL              string   `json:"url"`
+			Title            string   `json:"title"`
+			WebServer        string   `json:"webserver"`
+			Tech             []string `json:"tech"`
+			Technologies     []string `json:"technologies"`
+			StatusCode       int      `json:"status_code"`
+			StatusCodeOld    int      `json:"status-code"`
+			ContentLength    int      `json:"content_length"`
+			ContentLengthOld int      `json:"content-length"`
+			Host             string   `json:"host"`
+			A                []string `json:"a"`
+		}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		if r.URL == "" {
+			return nil
+		}
+		result := importedHTTPResult{
+			URL:           r.URL,
+			StatusCode:    r.StatusCode,
+			Title:         r.Title,
+			WebServer:     r.WebServer,
+			ContentLength: r.ContentLength,
+			Technologies:  append(r.Tech, r.Technologies...),
+		}
+		if result.StatusCode == 0 {
+			result.StatusCode = r.StatusCodeOld
+		}
+		if result.ContentLength == 0 {
+			result.ContentLength = r.ContentLengthOld
+		}
+		if _, err := netip.ParseAddr(r.Host); err == nil {
+			result.IP = r.Host
+		} else if len(r.A) > 0 {
+			result.IP = r.A[0]
+		}
+		results.HTTP = append(results.HTTP, result)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid httpx JSONL: %v", err)
+	}
+	return &results, nil
+}
+
+var hostnamePattern = regexp.MustCompile(`^(?:[a-z0-9_](?:[a-z0-9_-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,62}$`)
+
+// parseSubdomainList reads one hostname per line, as written by subfinder,
+// amass and most enumeration tools. URLs and wildcard prefixes are reduced to
+// the hostname; anything else that is not a hostname is ignored.
+func parseSubdomainList(data []byte) (*importedResults, error) {
+	var results importedResults
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		name := strings.ToLower(strings.TrimSpace(line))
+		if fields := strings.Fields(name); len(fields) > 0 {
+			name = fields[0]
+		}
+		if strings.Contains(name, "://") {
+			if parsed, err := url.Parse(name); err == nil {
+				name = parsed.Hostname()
+			}
+		}
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "*."), ".")
+		if !hostnamePattern.MatchString(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		results.Subdomains = append(results.Subdomains, name)
+	}
+	if len(results.Subdomains) == 0 {
+		return nil, fmt.Errorf("no hostnames found")
+	}
+	return &results, nil
+}
+
+type burpIssues struct {
+	Issues []struct {
+		Type string `xml:"type"`
+		Name string `xml:"name"`
+		Host struct {
+			IP   string `xml:"ip,attr"`
+			Host string `xml:",chardata"`
+		} `xml:"host"`
+		Path             string `xml:"path"`
+		Location         string `xml:"location"`
+		Severity         string `xml:"severity"`
+		Confidence       string `xml:"confidence"`
+		IssueBackground  string `xml:"issueBackground"`
+		IssueDetail      string `xml:"issueDetail"`
+		Remediation      string `xml:"remediationBackground"`
+		References       string `xml:"references"`
+		RequestResponses []struct {
+			Request  burpMessage `xml:"request"`
+			Response burpMessage `xml:"response"`
+		} `xml:"requestresponse"`
+	} `xml:"issue"`
+}
+
+type burpMessage struct {
+	Base64 bool   `xml:"base64,attr"`
+	Body   string `xml:",chardata"`
+}
+
+// burpMessageLimit caps the request and response kept with each finding.
+const burpMessageLimit = 64 * 1024
+
+func (m burpMessage) text() string {
+	body := m.Body
+	if m.Base64 {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(body))
+		if err != nil {
+			return ""
+		}
+		body = string(decoded)
+	}
+	if len(body) > burpMessageLimit {
+		body = body[:burpMessageLimit]
+	}
+	return body
+}
+
+var burpSeverities = map[string]string{
+	"high": "high", "medium": "medium", "low": "low", "information": "info", "info": "info", "critical": "critical",
+}
+
+// parseBurpXML reads a Burp Suite "Report issues" XML export. Issues become
+// findings in the nuclei result format, and their URLs become endpoints.
+func parseBurpXML(data []byte) (*importedResults, error) {
+	var export burpIssues
+	if err := xml.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid Burp XML: %v", err)
+	}
+
+	var results importedResults
+	seenEndpoints := make(map[string]bool)
+	for _, issue := range export.Issues {
+		host := strings.TrimRight(strings.TrimSpace(issue.Host.Host), "/")
+		target := host + issue.Path
+		severity := burpSeverities[strings.ToLower(issue.Severity)]
+		if severity == "" {
+			severity = "unknown"
+		}
+		description := issue.IssueDetail
+		if description == "" {
+			description = issue.IssueBackground
+		}
+
+		finding := NucleiFinding{
+			TemplateID: "burp-" + issue.Type,
+			Info: NucleiInfo{
+				Name:        issue.Name,
+				Author:      []string{"burp"},
+				Tags:        []string{"burp", strings.ToLower(issue.Confidence)},
+				Severity:    severity,
+				Description: description,
+			},
+			Type:      "http",
+			Host:      host,
+			MatchedAt: target,
+			URL:       target,
+			IP:        issue.Host.IP,
+		}
+		if parsed, err := url.Parse(host); err == nil {
+			finding.Port = parsed.Port()
+			if finding.Port == "" && parsed.Scheme == "https" {
+				finding.Port = "443"
+			} else if finding.Port == "" {
+				finding.Port = "80"
+			}
+		}
+		if len(issue.RequestResponses) > 0 {
+			finding.Request = issue.RequestResponses[0].Request.text()
+			finding.Response = issue.RequestResponses[0].Response.text()
+		}
+		results.Findings = append(results.Findings, finding)
+
+		if parsed, err := url.Parse(target); err == nil && parsed.Host != "" && !seenEndpoints[target] {
+			seenEndpoints[target] = true
+			results.Endpoints = append(results.Endpoints, importedEndpoint{URL: target, StatusCode: burpResponseStatus(finding.Response)})
+		}
+	}
+	return &results, nil
+}
+
+// burpResponseStatus reads the status code from a raw HTTP response.
+func burpResponseStatus(response string) int {
+	line, _, _ := strings.Cut(response, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return 0
+	}
+	status, _ := strconv.Atoi(fields[1])
+	return status
+}
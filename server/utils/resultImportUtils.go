@@ -0,0 +1,527 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Results from tools run outside the framework are imported into the same
+// tables the framework's own scans fill. Every import is logged in
+// imported_scans, and rows that need a parent scan get a synthetic one whose
+// command names the imported file.
+
+type ImportedScan struct {
+	ID            string         `json:"id"`
+	ScanID        string         `json:"scan_id"`
+	ScopeTargetID string         `json:"scope_target_id"`
+	Format        string         `json:"format"`
+	Filename      string         `json:"filename"`
+	Status        string         `json:"status"`
+	LinkedScanIDs []string       `json:"linked_scan_ids,omitempty"`
+	Summary       map[string]int `json:"summary"`
+	Error         string         `json:"error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ImportScanResults accepts a results file from nmap, masscan, nuclei, ffuf,
+// httpx, Burp Suite or a subdomain list. The format is detected from the
+// content unless the "format" field names one.
+func ImportScanResults(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	if scopeTargetID == "" {
+		http.Error(w, "Scope target ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(100 << 20); err != nil {
+		log.Printf("[IMPORT] [ERROR] Failed to parse multipart form: %v", err)
+		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(r.FormValue("format")))
+	if format == "" {
+		format = detectImportFormat(data)
+		if format == "" {
+			http.Error(w, fmt.Sprintf("Could not detect the file format; set format to one of: %s", strings.Join(importFormats, ", ")), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := parseImportFile(format, data)
+	if err != nil {
+		log.Printf("[IMPORT] [ERROR] Failed to parse %s file %s: %v", format, header.Filename, err)
+		http.Error(w, fmt.Sprintf("Failed to parse %s file: %v", format, err), http.StatusBadRequest)
+		return
+	}
+	if len(results.Hosts)+len(results.HTTP)+len(results.Endpoints)+len(results.Findings)+len(results.Subdomains) == 0 {
+		http.Error(w, fmt.Sprintf("No results found in %s file", format), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[IMPORT] [INFO] Importing %s results from %s for scope target %s", format, header.Filename, scopeTargetID)
+	imported, err := storeImportedResults(scopeTargetID, header.Filename, results)
+	if err != nil {
+		log.Printf("[IMPORT] [ERROR] Failed to import %s: %v", header.Filename, err)
+		http.Error(w, fmt.Sprintf("Failed to import results: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[IMPORT] [INFO] Imported %s: %v", header.Filename, imported.Summary)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(imported)
+}
+
+func storeImportedResults(scopeTargetID, filename string, results *importedResults) (*ImportedScan, error) {
+	imported := &ImportedScan{
+		ScanID:        uuid.New().String(),
+		ScopeTargetID: scopeTargetID,
+		Format:        results.Format,
+		Filename:      filename,
+		Status:        "success",
+		Summary:       make(map[string]int),
+	}
+	command := fmt.Sprintf("imported from %s: %s", results.Format, filename)
+
+	scope, err := loadImportScope(scopeTargetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scope: %v", err)
+	}
+	if skipped := scope.filter(results); skipped > 0 {
+		imported.Summary["out_of_scope"] = skipped
+	}
+
+	err = dbPool.QueryRow(context.Background(), `
+		INSERT INTO imported_scans (scan_id, scope_target_id, format, filename, status, result)
+		VALUES ($1, $2, $3, $4, 'processing', NULLIF($5, ''))
+		RETURNING id, created_at`,
+		imported.ScanID, scopeTargetID, results.Format, filename, strings.Join(results.Subdomains, "\n")).Scan(&imported.ID, &imported.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record import: %v", err)
+	}
+
+	var storeErr error
+	if len(results.Hosts) > 0 && storeErr == nil {
+		var scanID string
+		scanID, storeErr = storeImportedHosts(scopeTargetID, results.Format, command, results.Hosts, imported.Summary)
+		if scanID != "" {
+			imported.LinkedScanIDs = append(imported.LinkedScanIDs, scanID)
+		}
+	}
+	if len(results.HTTP) > 0 && storeErr == nil {
+		imported.Summary["target_urls"], storeErr = storeImportedHTTP(scopeTargetID, results.HTTP)
+	}
+	if len(results.Endpoints) > 0 && storeErr == nil {
+		imported.Summary["endpoints"], storeErr = storeImportedEndpoints(imported.ScanID, "imported_"+results.Format, scopeTargetID, results.Endpoints)
+	}
+	if len(results.Findings) > 0 && storeErr == nil {
+		var scanID string
+		scanID, storeErr = storeImportedFindings(scopeTargetID, command, results.Findings)
+		if scanID != "" {
+			imported.LinkedScanIDs = append(imported.LinkedScanIDs, scanID)
+			imported.Summary["findings"] = len(results.Findings)
+		}
+	}
+	if len(results.Subdomains) > 0 && storeErr == nil {
+		imported.Summary["subdomains"], storeErr = storeImportedSubdomains(scopeTargetID, results.Subdomains)
+	}
+
+	if storeErr != nil {
+		imported.Status = "error"
+		imported.Error = storeErr.Error()
+	}
+	summaryJSON, _ := json.Marshal(imported.Summary)
+	_, err = dbPool.Exec(context.Background(), `
+		UPDATE imported_scans SET status = $1, summary = $2, linked_scan_ids = $3, error = NULLIF($4, '')
+		WHERE scan_id = $5`,
+		imported.Status, summaryJSON, imported.LinkedScanIDs, imported.Error, imported.ScanID)
+	if err != nil {
+		log.Printf("[IMPORT] [ERROR] Failed to update import %s: %v", imported.ScanID, err)
+	}
+	if storeErr != nil {
+		return nil, storeErr
+	}
+	return imported, nil
+}
+
+// importScope is what imported results have to fall inside: the wildcard
+// base, the URL target's host or a Company's consolidated domains, plus the
+// consolidated network ranges. A Company's scope_target is its name, not a
+// domain, so it never counts on its own.
+type importScope struct {
+	domains  []string
+	networks []netip.Prefix
+}
+
+func loadImportScope(scopeTargetID string) (*importScope, error) {
+	scope := &importScope{}
+	var targetType, target string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT type, scope_target FROM scope_targets WHERE id = $1`, scopeTargetID).Scan(&targetType, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	switch targetType {
+	case "Wildcard":
+		scope.domains = append(scope.domains, strings.TrimPrefix(target, "*."))
+	case "URL":
+		host := domainutil.HostFromURL(target)
+		if prefix, ok := parseRangePrefix(host); ok {
+			scope.networks = append(scope.networks, prefix)
+		} else if host != "" {
+			scope.domains = append(scope.domains, host)
+		}
+	case "Company":
+		rows, err := dbPool.Query(context.Background(),
+			`SELECT domain FROM consolidated_company_domains WHERE scope_target_id = $1`, scopeTargetID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var domain string
+			if rows.Scan(&domain) == nil {
+				scope.domains = append(scope.domains, domain)
+			}
+		}
+		rows.Close()
+	}
+
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT cidr_block FROM consolidated_network_ranges WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cidr string
+		if rows.Scan(&cidr) != nil {
+			continue
+		}
+		if prefix, ok := parseRangePrefix(cidr); ok {
+			scope.networks = append(scope.networks, prefix)
+		}
+	}
+	return scope, rows.Err()
+}
+
+// allows reports whether a host name, IP or URL is in scope. IPs are matched
+// against the network ranges only.
+func (s *importScope) allows(raw string) bool {
+	host := domainutil.HostFromURL(raw)
+	if host == "" {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range s.networks {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, domain := range s.domains {
+		if domainutil.IsSubdomainOf(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter drops everything outside the scope from results and returns how
+// many entries it dropped.
+func (s *importScope) filter(results *importedResults) int {
+	skipped := 0
+
+	hosts := results.Hosts[:0]
+	for _, host := range results.Hosts {
+		inScope := s.allows(host.IP)
+		for _, hostname := range host.Hostnames {
+			inScope = inScope || s.allows(hostname)
+		}
+		if inScope {
+			hosts = append(hosts, host)
+		} else {
+			skipped++
+		}
+	}
+	results.Hosts = hosts
+
+	httpResults := results.HTTP[:0]
+	for _, result := range results.HTTP {
+		if s.allows(result.URL) || (result.IP != "" && s.allows(result.IP)) {
+			httpResults = append(httpResults, result)
+		} else {
+			skipped++
+		}
+	}
+	results.HTTP = httpResults
+
+	endpoints := results.Endpoints[:0]
+	for _, endpoint := range results.Endpoints {
+		if s.allows(endpoint.URL) {
+			endpoints = append(endpoints, endpoint)
+		} else {
+			skipped++
+		}
+	}
+	results.Endpoints = endpoints
+
+	findings := results.Findings[:0]
+	for _, finding := range results.Findings {
+		if s.allows(finding.Host) || (finding.IP != "" && s.allows(finding.IP)) {
+			findings = append(findings, finding)
+		} else {
+			skipped++
+		}
+	}
+	results.Findings = findings
+
+	subdomains := results.Subdomains[:0]
+	for _, subdomain := range results.Subdomains {
+		if s.allows(subdomain) {
+			subdomains = append(subdomains, subdomain)
+		} else {
+			skipped++
+		}
+	}
+	results.Subdomains = subdomains
+	return skipped
+}
+
+// storeImportedHosts records port scan results under a synthetic IP/port scan:
+// each host as a live IP, each open port as a service, and ports the tool
+// identified as HTTP as live web servers.
+func storeImportedHosts(scopeTargetID, format, command string, hosts []importedHost, summary map[string]int) (string, error) {
+	scanID := uuid.New().String()
+	ctx := context.Background()
+	_, err := dbPool.Exec(ctx, `
+		INSERT INTO ip_port_scans (scan_id, scope_target_id, status, port_profile, command, execution_time)
+		VALUES ($1, $2, 'importing', 'imported', $3, '0s')`, scanID, scopeTargetID, command)
+	if err != nil {
+		return "", fmt.Errorf("failed to create IP/port scan record: %v", err)
+	}
+
+	ports, webServers := 0, 0
+	for _, host := range hosts {
+		hostname := ""
+		if len(host.Hostnames) > 0 {
+			hostname = host.Hostnames[0]
+		}
+		networkRange := host.IP
+		if addr, err := netip.ParseAddr(host.IP); err == nil {
+			networkRange = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		origin := localIPASN(host.IP)
+		_, err := dbPool.Exec(ctx, `
+			INSERT INTO discovered_live_ips (scan_id, ip_address, hostname, network_range, asn_number, asn_organization, asn_country, ports_scanned_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NOW())`,
+			scanID, host.IP, hostname, networkRange, origin.ASNNumber, origin.ASNOrganization, origin.ASNCountry)
+		if err != nil {
+			log.Printf("[IMPORT] [ERROR] Failed to insert live IP %s: %v", host.IP, err)
+			continue
+		}
+
+		for _, port := range host.Ports {
+			ports++
+			insertDiscoveredService(DiscoveredService{
+				ScanID:    scanID,
+				IPAddress: host.IP,
+				Hostname:  hostname,
+				Port:      port.Port,
+				Transport: "tcp",
+				Service:   port.Service,
+				Product:   port.Product,
+				Version:   port.Version,
+				Banner:    truncateBanner([]byte(port.Banner)),
+				TLS:       port.TLS,
+				Details:   map[string]interface{}{"source": format},
+			})
+
+			if !strings.Contains(port.Service, "http") {
+				continue
+			}
+			protocol := "http"
+			if port.TLS || strings.Contains(port.Service, "https") {
+				protocol = "https"
+			}
+			insertLiveWebServer(scanID, LiveWebServer{
+				ScanID:       scanID,
+				IPAddress:    host.IP,
+				Hostname:     hostname,
+				Port:         port.Port,
+				Protocol:     protocol,
+				URL:          fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(host.IP, strconv.Itoa(port.Port))),
+				ServerHeader: strings.TrimSpace(port.Product + " " + port.Version),
+			})
+			webServers++
+		}
+	}
+
+	_, err = dbPool.Exec(ctx, `
+		UPDATE ip_port_scans SET status = 'success', total_ips_discovered = $1, total_ports_scanned = $2,
+			live_web_servers_found = $3, services_found = $2
+		WHERE scan_id = $4`, len(hosts), ports, webServers, scanID)
+	if err != nil {
+		return scanID, fmt.Errorf("failed to finish IP/port scan record: %v", err)
+	}
+	summary["live_ips"] = len(hosts)
+	summary["services"] = ports
+	summary["live_web_servers"] = webServers
+	return scanID, nil
+}
+
+// storeImportedHTTP adds probed URLs to the target URLs, refreshing any that
+// are already known.
+func storeImportedHTTP(scopeTargetID string, results []importedHTTPResult) (int, error) {
+	stored := 0
+	for _, result := range results {
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO target_urls (url, scope_target_id, status_code, title, web_server, technologies, content_length, ip_address, newly_discovered, roi_score)
+			VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), NULLIF($5, ''), $6::text[], NULLIF($7, 0), NULLIF($8, ''), true, 50)
+			ON CONFLICT (url, scope_target_id) DO UPDATE SET
+				status_code = COALESCE(EXCLUDED.status_code, target_urls.status_code),
+				title = COALESCE(EXCLUDED.title, target_urls.title),
+				web_server = COALESCE(EXCLUDED.web_server, target_urls.web_server),
+				technologies = CASE WHEN cardinality(EXCLUDED.technologies) > 0 THEN EXCLUDED.technologies ELSE target_urls.technologies END,
+				content_length = COALESCE(EXCLUDED.content_length, target_urls.content_length),
+				ip_address = COALESCE(target_urls.ip_address, EXCLUDED.ip_address),
+				no_longer_live = false,
+				updated_at = NOW()`,
+			result.URL, scopeTargetID, result.StatusCode, result.Title, result.WebServer,
+			result.Technologies, result.ContentLength, result.IP)
+		if err != nil {
+			log.Printf("[IMPORT] [ERROR] Failed to store target URL %s: %v", result.URL, err)
+			continue
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+func storeImportedEndpoints(scanID, scanType, scopeTargetID string, imported []importedEndpoint) (int, error) {
+	endpoints := make([]DiscoveredEndpoint, 0, len(imported))
+	for _, e := range imported {
+		endpoint := createEndpoint(e.URL, scanID, scanType, scopeTargetID, true)
+		endpoint.NormalizedPath = endpoint.Path
+		endpoint.StatusCode = e.StatusCode
+		endpoint.Parameters = append(endpoint.Parameters, extractQueryParameters(e.URL)...)
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := storeDiscoveredEndpoints(endpoints); err != nil {
+		return 0, err
+	}
+	return len(endpoints), nil
+}
+
+// storeImportedFindings records findings as a completed nuclei scan, so they
+// show up wherever nuclei results do.
+func storeImportedFindings(scopeTargetID, command string, findings []NucleiFinding) (string, error) {
+	targetSet := make(map[string]bool)
+	templateSet := make(map[string]bool)
+	for _, finding := range findings {
+		if finding.Host != "" {
+			targetSet[finding.Host] = true
+		}
+		templateSet[finding.TemplateID] = true
+	}
+	targets := sortedKeys(targetSet)
+	templates := sortedKeys(templateSet)
+
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		return "", err
+	}
+	scanID := uuid.New().String()
+	_, err = dbPool.Exec(context.Background(), `
+		INSERT INTO nuclei_scans (scan_id, scope_target_id, targets, templates, status, result, command, execution_time, created_at)
+		VALUES ($1, $2, $3, $4, 'success', $5, $6, '0s', NOW())`,
+		scanID, scopeTargetID, targets, templates, string(findingsJSON), command)
+	if err != nil {
+		return "", fmt.Errorf("failed to create nuclei scan record: %v", err)
+	}
+	return scanID, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// storeImportedSubdomains adds the names to the consolidated subdomains now;
+// later consolidations read them back from imported_scans. Out-of-scope names
+// have already been filtered out.
+func storeImportedSubdomains(scopeTargetID string, subdomains []string) (int, error) {
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_subdomains (scope_target_id, subdomain)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (scope_target_id, subdomain) DO NOTHING`, scopeTargetID, subdomains)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store subdomains: %v", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// GetImportedScans lists the result files imported for a scope target.
+func GetImportedScans(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, scan_id, scope_target_id, format, filename, status,
+			COALESCE(linked_scan_ids, '{}'), summary, COALESCE(error, ''), created_at
+		FROM imported_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`, scopeTargetID)
+	if err != nil {
+		log.Printf("[IMPORT] [ERROR] Failed to get imported scans: %v", err)
+		http.Error(w, "Failed to get imported scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	imports := []ImportedScan{}
+	for rows.Next() {
+		var imported ImportedScan
+		var summaryJSON []byte
+		if err := rows.Scan(&imported.ID, &imported.ScanID, &imported.ScopeTargetID, &imported.Format, &imported.Filename,
+			&imported.Status, &imported.LinkedScanIDs, &summaryJSON, &imported.Error, &imported.CreatedAt); err != nil {
+			log.Printf("[IMPORT] [ERROR] Error scanning imported scan row: %v", err)
+			continue
+		}
+		json.Unmarshal(summaryJSON, &imported.Summary)
+		imports = append(imports, imported)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imports)
+}
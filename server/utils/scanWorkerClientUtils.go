@@ -0,0 +1,422 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	workerPollInterval    = 3 * time.Second
+	workerStreamInterval  = 2 * time.Second
+	workerMaxReturnedFile = 64 << 20
+)
+
+// WorkerConfig configures worker mode (`server worker`).
+type WorkerConfig struct {
+	ServerURL         string
+	RegistrationToken string
+	Name              string
+	Tools             []string
+	Concurrency       int
+}
+
+// WorkerConfigFromEnv reads WORKER_SERVER_URL, WORKER_REGISTRATION_TOKEN, WORKER_NAME,
+// WORKER_TOOLS (comma separated) and WORKER_CONCURRENCY.
+func WorkerConfigFromEnv() WorkerConfig {
+	config := WorkerConfig{
+		ServerURL:         strings.TrimRight(os.Getenv("WORKER_SERVER_URL"), "/"),
+		RegistrationToken: os.Getenv("WORKER_REGISTRATION_TOKEN"),
+		Name:              os.Getenv("WORKER_NAME"),
+		Concurrency:       2,
+	}
+	if config.Name == "" {
+		config.Name, _ = os.Hostname()
+	}
+	if tools := os.Getenv("WORKER_TOOLS"); tools != "" {
+		for _, tool := range strings.Split(tools, ",") {
+			if tool = strings.TrimSpace(tool); tool != "" {
+				config.Tools = append(config.Tools, tool)
+			}
+		}
+	}
+	if value, err := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY")); err == nil && value > 0 {
+		config.Concurrency = value
+	}
+	return config
+}
+
+type workerClient struct {
+	config   WorkerConfig
+	http     *http.Client
+	tools    []string
+	mu       sync.Mutex
+	workerID string
+	token    string
+	running  map[string]context.CancelFunc
+}
+
+// RunWorker registers with the server and processes jobs until the process exits.
+func RunWorker(config WorkerConfig) error {
+	if config.ServerURL == "" {
+		return fmt.Errorf("WORKER_SERVER_URL is not set")
+	}
+	if config.RegistrationToken == "" {
+		return fmt.Errorf("WORKER_REGISTRATION_TOKEN is not set")
+	}
+
+	client := &workerClient{
+		config:  config,
+		http:    &http.Client{Timeout: 60 * time.Second},
+		tools:   config.Tools,
+		running: make(map[string]context.CancelFunc),
+	}
+	if len(client.tools) == 0 {
+		client.tools = detectWorkerTools()
+	}
+	if len(client.tools) == 0 {
+		return fmt.Errorf("no tools are available on this worker")
+	}
+
+	for {
+		if err := client.register(); err != nil {
+			log.Printf("[WORKER] [ERROR] Registration failed: %v; retrying in 10s", err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+		break
+	}
+
+	go client.heartbeatLoop()
+	for i := 0; i < config.Concurrency; i++ {
+		go client.pollLoop()
+	}
+	select {}
+}
+
+// workerToolExecutor returns the executor a worker uses to run a tool itself. Worker
+// mode is never valid here, so it falls back to binaries on PATH.
+func workerToolExecutor(tool string) Executor {
+	ex := ToolExecutor(tool)
+	if ex.Mode() != ToolModeWorker {
+		return ex
+	}
+	def := toolRegistry[tool]
+	config := defaultExecutorConfig(def)
+	config.Mode = ToolModeLocal
+	if len(def.LocalBinary) > 0 {
+		config.Binary = def.LocalBinary
+	}
+	local, _ := NewExecutor(config)
+	SetToolExecutor(tool, local)
+	return local
+}
+
+func detectWorkerTools() []string {
+	var tools []string
+	for _, name := range sortedToolNames() {
+		ctx, cancel := context.WithTimeout(context.Background(), toolProbeTimeout)
+		err := workerToolExecutor(name).Check(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("[WORKER] [INFO] Skipping %s: %v", name, err)
+			continue
+		}
+		tools = append(tools, name)
+	}
+	return tools
+}
+
+func (c *workerClient) do(method, path, token string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.config.ServerURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// call sends an authenticated request, re-registering once if the server no
+// longer recognises this worker's token.
+func (c *workerClient) call(method, path string, body interface{}, out interface{}) (int, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	status, err := c.do(method, path, token, body, out)
+	if status == http.StatusUnauthorized {
+		if regErr := c.register(); regErr != nil {
+			return status, regErr
+		}
+		c.mu.Lock()
+		token = c.token
+		c.mu.Unlock()
+		status, err = c.do(method, path, token, body, out)
+	}
+	return status, err
+}
+
+func (c *workerClient) register() error {
+	var response struct {
+		WorkerID string `json:"worker_id"`
+		Token    string `json:"token"`
+	}
+	_, err := c.do("POST", "/workers/register", c.config.RegistrationToken, map[string]interface{}{
+		"name":        c.config.Name,
+		"tools":       c.tools,
+		"concurrency": c.config.Concurrency,
+	}, &response)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.workerID = response.WorkerID
+	c.token = response.Token
+	c.mu.Unlock()
+	log.Printf("[WORKER] [INFO] Registered with %s as %s (tools: %s)", c.config.ServerURL, response.WorkerID, strings.Join(c.tools, ", "))
+	return nil
+}
+
+func (c *workerClient) heartbeatLoop() {
+	ticker := time.NewTicker(workerHeartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mu.Lock()
+		running := make([]string, 0, len(c.running))
+		for jobID := range c.running {
+			running = append(running, jobID)
+		}
+		c.mu.Unlock()
+
+		var response struct {
+			Cancel []string `json:"cancel"`
+		}
+		if _, err := c.call("POST", "/workers/heartbeat", map[string]interface{}{"running_jobs": running}, &response); err != nil {
+			log.Printf("[WORKER] [WARN] Heartbeat failed: %v", err)
+			continue
+		}
+
+		c.mu.Lock()
+		for _, jobID := range response.Cancel {
+			if cancel, ok := c.running[jobID]; ok {
+				log.Printf("[WORKER] [INFO] Server cancelled job %s", jobID)
+				cancel()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *workerClient) pollLoop() {
+	for {
+		var job WorkerJob
+		status, err := c.call("POST", "/workers/jobs/claim", map[string]interface{}{"tools": c.tools}, &job)
+		if err != nil {
+			log.Printf("[WORKER] [WARN] Failed to claim job: %v", err)
+			time.Sleep(workerPollInterval)
+			continue
+		}
+		if status == http.StatusNoContent {
+			time.Sleep(workerPollInterval)
+			continue
+		}
+		c.runJob(job)
+	}
+}
+
+// rewriteWorkerPaths maps marked tool paths onto the executor and records them.
+func rewriteWorkerPaths(value string, ex Executor, referenced map[string]bool) string {
+	return workerPathPattern.ReplaceAllStringFunc(value, func(match string) string {
+		toolPath := strings.TrimPrefix(match, workerPathMarker)
+		referenced[toolPath] = true
+		return ex.Path(toolPath)
+	})
+}
+
+func (c *workerClient) runJob(job WorkerJob) {
+	log.Printf("[WORKER] [INFO] Running %s job %s", job.Tool, job.ID)
+	ex := workerToolExecutor(job.Tool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if job.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(job.TimeoutSeconds)*time.Second)
+	}
+	c.mu.Lock()
+	c.running[job.ID] = cancel
+	c.mu.Unlock()
+	defer func() {
+		cancel()
+		c.mu.Lock()
+		delete(c.running, job.ID)
+		c.mu.Unlock()
+	}()
+
+	result := WorkerJobResult{ExitCode: -1}
+	for toolPath, data := range job.Files {
+		if err := WriteToolFile(ctx, ex, toolPath, data); err != nil {
+			result.Error = fmt.Sprintf("failed to stage %s: %v", toolPath, err)
+			c.complete(job.ID, result)
+			return
+		}
+	}
+
+	referenced := make(map[string]bool)
+	args := make([]string, len(job.Args))
+	for i, arg := range job.Args {
+		args[i] = rewriteWorkerPaths(arg, ex, referenced)
+	}
+	req := ExecRequest{Args: args, Env: job.Env}
+	if job.Stdin != "" {
+		req.Stdin = strings.NewReader(rewriteWorkerPaths(job.Stdin, ex, referenced))
+	}
+
+	stream := newWorkerOutputStream(c, job.ID)
+	req.Stdout = stream.writer(false)
+	req.Stderr = stream.writer(true)
+
+	var execResult *ExecResult
+	var err error
+	if job.Kind == "exec" {
+		execResult, err = ex.Exec(ctx, req)
+	} else {
+		execResult, err = ex.Run(ctx, req)
+	}
+	stream.close()
+
+	result.Stdout = execResult.Stdout
+	result.Stderr = execResult.Stderr
+	result.ExitCode = execResult.ExitCode
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	result.Files = make(map[string][]byte)
+	for toolPath := range referenced {
+		if _, isInput := job.Files[toolPath]; isInput {
+			continue
+		}
+		data, readErr := ReadToolFile(context.Background(), ex, toolPath)
+		if readErr != nil || len(data) > workerMaxReturnedFile {
+			continue
+		}
+		result.Files[toolPath] = data
+	}
+
+	c.complete(job.ID, result)
+}
+
+func (c *workerClient) complete(jobID string, result WorkerJobResult) {
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, err := c.call("POST", "/workers/jobs/"+jobID+"/complete", result, nil)
+		if err == nil {
+			log.Printf("[WORKER] [INFO] Finished job %s (exit %d)", jobID, result.ExitCode)
+			return
+		}
+		log.Printf("[WORKER] [WARN] Failed to report job %s (attempt %d): %v", jobID, attempt, err)
+		time.Sleep(time.Duration(attempt) * 2 * time.Second)
+	}
+}
+
+// workerOutputStream forwards tool output to the server in periodic chunks so
+// log writers on the server side see progress while the tool runs.
+type workerOutputStream struct {
+	client *workerClient
+	jobID  string
+	mu     sync.Mutex
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+type workerStreamWriter struct {
+	stream *workerOutputStream
+	stderr bool
+}
+
+func (w workerStreamWriter) Write(p []byte) (int, error) {
+	w.stream.mu.Lock()
+	defer w.stream.mu.Unlock()
+	if w.stderr {
+		return w.stream.stderr.Write(p)
+	}
+	return w.stream.stdout.Write(p)
+}
+
+func newWorkerOutputStream(client *workerClient, jobID string) *workerOutputStream {
+	stream := &workerOutputStream{client: client, jobID: jobID, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(stream.done)
+		ticker := time.NewTicker(workerStreamInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stream.flush()
+			case <-stream.stop:
+				stream.flush()
+				return
+			}
+		}
+	}()
+	return stream
+}
+
+func (s *workerOutputStream) writer(stderr bool) io.Writer {
+	return workerStreamWriter{stream: s, stderr: stderr}
+}
+
+func (s *workerOutputStream) flush() {
+	s.mu.Lock()
+	chunk := map[string]string{"stdout": s.stdout.String(), "stderr": s.stderr.String()}
+	s.stdout.Reset()
+	s.stderr.Reset()
+	s.mu.Unlock()
+
+	if chunk["stdout"] == "" && chunk["stderr"] == "" {
+		return
+	}
+	if _, err := s.client.call("POST", "/workers/jobs/"+s.jobID+"/output", chunk, nil); err != nil {
+		log.Printf("[WORKER] [WARN] Failed to stream output for job %s: %v", s.jobID, err)
+	}
+}
+
+func (s *workerOutputStream) close() {
+	close(s.stop)
+	<-s.done
+}
@@ -0,0 +1,696 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	ToolModeWorker = "worker"
+
+	workerHeartbeatInterval = 15 * time.Second
+	workerStaleAfter        = 90 * time.Second
+	workerJobMaxAttempts    = 3
+	workerMaxResultBytes    = 256 << 20
+
+	// workerPathMarker prefixes absolute tool paths in job arguments so the worker
+	// can root them in its own environment and ship the files back afterwards.
+	workerPathMarker = "ars0n-worker-path:"
+)
+
+var workerPathPattern = regexp.MustCompile(regexp.QuoteMeta(workerPathMarker) + `(/[^\s'"]*)`)
+
+// WorkerJob is handed to a worker when it claims a queued job. Files holds the
+// inputs the server copied in with CopyTo, keyed by tool path.
+type WorkerJob struct {
+	ID             string            `json:"id"`
+	Tool           string            `json:"tool"`
+	Kind           string            `json:"kind"`
+	Args           []string          `json:"args"`
+	Env            []string          `json:"env,omitempty"`
+	Stdin          string            `json:"stdin,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	Files          map[string][]byte `json:"files,omitempty"`
+}
+
+// WorkerJobResult is posted back by a worker when a job finishes. Files holds
+// every referenced tool path that existed after the run.
+type WorkerJobResult struct {
+	Stdout   string            `json:"stdout"`
+	Stderr   string            `json:"stderr"`
+	ExitCode int               `json:"exit_code"`
+	Error    string            `json:"error,omitempty"`
+	Files    map[string][]byte `json:"files,omitempty"`
+}
+
+type ScanWorker struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Tools         []string  `json:"tools"`
+	Status        string    `json:"status"`
+	Address       string    `json:"address"`
+	Version       string    `json:"version"`
+	Concurrency   int       `json:"concurrency"`
+	RunningJobs   int       `json:"running_jobs"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	RegisteredAt  time.Time `json:"registered_at"`
+}
+
+type ScanWorkerJob struct {
+	ID          string     `json:"id"`
+	Tool        string     `json:"tool"`
+	Kind        string     `json:"kind"`
+	Args        []string   `json:"args"`
+	Status      string     `json:"status"`
+	WorkerID    *string    `json:"worker_id"`
+	Attempts    int        `json:"attempts"`
+	ExitCode    *int       `json:"exit_code"`
+	Error       *string    `json:"error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+type workerJobWaiter struct {
+	stdout io.Writer
+	stderr io.Writer
+	files  map[string][]byte
+	env    []string
+	done   chan WorkerJobResult
+}
+
+var (
+	workerJobsMutex  sync.Mutex
+	workerJobWaiters = make(map[string]*workerJobWaiter)
+)
+
+func registerWorkerJobWaiter(jobID string, waiter *workerJobWaiter) {
+	workerJobsMutex.Lock()
+	workerJobWaiters[jobID] = waiter
+	workerJobsMutex.Unlock()
+}
+
+func getWorkerJobWaiter(jobID string) *workerJobWaiter {
+	workerJobsMutex.Lock()
+	defer workerJobsMutex.Unlock()
+	return workerJobWaiters[jobID]
+}
+
+// finishWorkerJob delivers a result to the scan waiting on the job, at most once.
+func finishWorkerJob(jobID string, result WorkerJobResult) {
+	workerJobsMutex.Lock()
+	waiter, ok := workerJobWaiters[jobID]
+	delete(workerJobWaiters, jobID)
+	workerJobsMutex.Unlock()
+	if ok {
+		waiter.done <- result
+	}
+}
+
+// WorkerExecutor queues tool runs for remote scan workers and blocks until one of
+// them reports back. The calling scan code is unchanged: results come back as an
+// ExecResult and files written by the tool are available through CopyFrom.
+// ToolExecutor hands every caller its own WorkerExecutor, so the files staged
+// with CopyTo and returned by its jobs belong to that one scan and never mix
+// with a concurrent scan using the same tool paths.
+type WorkerExecutor struct {
+	Tool string
+
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func NewWorkerExecutor(tool string) *WorkerExecutor {
+	return &WorkerExecutor{Tool: tool, files: make(map[string][]byte)}
+}
+
+func (e *WorkerExecutor) Mode() string   { return ToolModeWorker }
+func (e *WorkerExecutor) Target() string { return "scan workers" }
+
+func (e *WorkerExecutor) Run(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	return e.submit(ctx, "run", req)
+}
+
+func (e *WorkerExecutor) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	return e.submit(ctx, "exec", req)
+}
+
+func (e *WorkerExecutor) CopyTo(ctx context.Context, localPath, toolPath string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	e.storeFile(toolPath, data)
+	return nil
+}
+
+func (e *WorkerExecutor) CopyFrom(ctx context.Context, toolPath, localPath string) error {
+	e.mu.Lock()
+	data, ok := e.files[toolPath]
+	e.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s was not returned by a worker", toolPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(localPath, data, 0644)
+}
+
+func (e *WorkerExecutor) Path(toolPath string) string {
+	if !filepath.IsAbs(toolPath) {
+		return toolPath
+	}
+	return workerPathMarker + toolPath
+}
+
+func (e *WorkerExecutor) Check(ctx context.Context) error {
+	if dbPool == nil {
+		return fmt.Errorf("worker mode requires the server database")
+	}
+	var online int
+	err := dbPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM scan_workers WHERE status = 'online' AND tools ? $1`, e.Tool).Scan(&online)
+	if err != nil {
+		return fmt.Errorf("failed to query scan workers: %v", err)
+	}
+	if online == 0 {
+		return fmt.Errorf("no online scan worker advertises %s", e.Tool)
+	}
+	return nil
+}
+
+func (e *WorkerExecutor) storeFile(toolPath string, data []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.files[toolPath] = data
+}
+
+// referencedFiles returns the staged files whose marked paths appear in the job.
+func (e *WorkerExecutor) referencedFiles(values ...string) map[string][]byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	files := make(map[string][]byte)
+	for _, value := range values {
+		for _, match := range workerPathPattern.FindAllStringSubmatch(value, -1) {
+			if data, ok := e.files[match[1]]; ok {
+				files[match[1]] = data
+			}
+		}
+	}
+	return files
+}
+
+func (e *WorkerExecutor) submit(ctx context.Context, kind string, req ExecRequest) (*ExecResult, error) {
+	command := strings.Join(redactCommandArgs(req.Args), " ")
+	if kind == "run" {
+		command = strings.TrimSpace(e.Tool + " " + command)
+	}
+	result := &ExecResult{Command: command}
+	if dbPool == nil {
+		return result, fmt.Errorf("worker mode requires the server database")
+	}
+
+	var stdin string
+	if req.Stdin != nil {
+		data, err := io.ReadAll(req.Stdin)
+		if err != nil {
+			return result, fmt.Errorf("failed to read stdin: %v", err)
+		}
+		stdin = string(data)
+	}
+
+	timeoutSeconds := 0
+	if deadline, ok := ctx.Deadline(); ok {
+		timeoutSeconds = int(time.Until(deadline).Seconds()) + 1
+	}
+
+	jobID := uuid.New().String()
+	waiter := &workerJobWaiter{
+		stdout: req.Stdout,
+		stderr: req.Stderr,
+		files:  e.referencedFiles(append([]string{stdin}, req.Args...)...),
+		env:    req.Env,
+		done:   make(chan WorkerJobResult, 1),
+	}
+	registerWorkerJobWaiter(jobID, waiter)
+
+	argsJSON, _ := json.Marshal(req.Args)
+	_, err := dbPool.Exec(ctx, `
+		INSERT INTO scan_worker_jobs (id, tool, kind, args, stdin, timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		jobID, e.Tool, kind, argsJSON, stdin, timeoutSeconds)
+	if err != nil {
+		finishWorkerJob(jobID, WorkerJobResult{})
+		return result, fmt.Errorf("failed to queue worker job: %v", err)
+	}
+	log.Printf("[WORKER] [INFO] Queued %s job %s: %s", e.Tool, jobID, command)
+
+	select {
+	case jobResult := <-waiter.done:
+		result.Stdout = jobResult.Stdout
+		result.Stderr = jobResult.Stderr
+		result.ExitCode = jobResult.ExitCode
+		for path, data := range jobResult.Files {
+			e.storeFile(path, data)
+		}
+		if jobResult.Error != "" {
+			return result, fmt.Errorf("%s", jobResult.Error)
+		}
+		return result, nil
+	case <-ctx.Done():
+		workerJobsMutex.Lock()
+		delete(workerJobWaiters, jobID)
+		workerJobsMutex.Unlock()
+		dbPool.Exec(context.Background(), `
+			UPDATE scan_worker_jobs SET status = 'cancelled', completed_at = NOW()
+			WHERE id = $1 AND status IN ('queued', 'running')`, jobID)
+		return result, ctx.Err()
+	}
+}
+
+// StartScanWorkerReaper abandons jobs left over from a previous server process and
+// starts re-queueing jobs held by workers that stopped heartbeating.
+func StartScanWorkerReaper() {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_worker_jobs SET status = 'abandoned', completed_at = NOW()
+		WHERE status IN ('queued', 'running')`)
+	if err != nil {
+		log.Printf("[WORKER] [ERROR] Failed to abandon stale worker jobs: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(workerHeartbeatInterval * 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			reapStaleWorkers()
+		}
+	}()
+}
+
+func reapStaleWorkers() {
+	staleBefore := time.Now().Add(-workerStaleAfter)
+	tag, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_workers SET status = 'offline'
+		WHERE status = 'online' AND last_heartbeat < $1`, staleBefore)
+	if err != nil {
+		log.Printf("[WORKER] [ERROR] Failed to mark stale workers offline: %v", err)
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		log.Printf("[WORKER] [WARN] Marked %d scan workers offline", tag.RowsAffected())
+	}
+
+	requeueWorkerJobs(`worker_id IN (SELECT id FROM scan_workers WHERE status = 'offline')`)
+}
+
+// requeueWorkerJobs puts running jobs matching the condition back on the queue, or
+// fails them once they have used up their attempts.
+func requeueWorkerJobs(condition string, args ...interface{}) {
+	maxAttemptsArg := len(args) + 1
+	rows, err := dbPool.Query(context.Background(), fmt.Sprintf(`
+		UPDATE scan_worker_jobs
+		SET status = CASE WHEN attempts >= $%d THEN 'failed' ELSE 'queued' END,
+		    error = CASE WHEN attempts >= $%d THEN 'worker lost after maximum attempts' ELSE error END,
+		    completed_at = CASE WHEN attempts >= $%d THEN NOW() ELSE NULL END,
+		    worker_id = NULL
+		WHERE status = 'running' AND %s
+		RETURNING id, status`, maxAttemptsArg, maxAttemptsArg, maxAttemptsArg, condition),
+		append(args, workerJobMaxAttempts)...)
+	if err != nil {
+		log.Printf("[WORKER] [ERROR] Failed to requeue worker jobs: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jobID, status string
+		if err := rows.Scan(&jobID, &status); err != nil {
+			continue
+		}
+		if status == "failed" {
+			log.Printf("[WORKER] [ERROR] Job %s failed after %d attempts", jobID, workerJobMaxAttempts)
+			finishWorkerJob(jobID, WorkerJobResult{ExitCode: -1, Error: "worker lost after maximum attempts"})
+		} else {
+			log.Printf("[WORKER] [WARN] Re-queued job %s from a lost worker", jobID)
+		}
+	}
+}
+
+func hashWorkerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+}
+
+// authenticateWorker resolves the worker ID for a request, writing a 401 if the
+// bearer token does not belong to a registered worker.
+func authenticateWorker(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "Missing worker token", http.StatusUnauthorized)
+		return "", false
+	}
+	var workerID string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT id FROM scan_workers WHERE token_hash = $1`, hashWorkerToken(token)).Scan(&workerID)
+	if err != nil {
+		http.Error(w, "Invalid worker token", http.StatusUnauthorized)
+		return "", false
+	}
+	return workerID, true
+}
+
+func RegisterScanWorker(w http.ResponseWriter, r *http.Request) {
+	expected := os.Getenv("WORKER_REGISTRATION_TOKEN")
+	if expected == "" {
+		http.Error(w, "Worker registration is disabled; set WORKER_REGISTRATION_TOKEN on the server", http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(expected)) != 1 {
+		http.Error(w, "Invalid registration token", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Name        string   `json:"name"`
+		Tools       []string `json:"tools"`
+		Version     string   `json:"version"`
+		Concurrency int      `json:"concurrency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		payload.Name = r.RemoteAddr
+	}
+	if payload.Concurrency < 1 {
+		payload.Concurrency = 1
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		http.Error(w, "Failed to generate worker token", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(secret)
+	toolsJSON, _ := json.Marshal(payload.Tools)
+
+	var workerID string
+	err := dbPool.QueryRow(context.Background(), `
+		INSERT INTO scan_workers (name, token_hash, tools, address, version, concurrency)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		payload.Name, hashWorkerToken(token), toolsJSON, r.RemoteAddr, payload.Version, payload.Concurrency).Scan(&workerID)
+	if err != nil {
+		log.Printf("[WORKER] [ERROR] Failed to register worker %s: %v", payload.Name, err)
+		http.Error(w, "Failed to register worker", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[WORKER] [INFO] Registered worker %s (%s) with tools %v", payload.Name, workerID, payload.Tools)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"worker_id":                  workerID,
+		"token":                      token,
+		"heartbeat_interval_seconds": int(workerHeartbeatInterval.Seconds()),
+	})
+}
+
+func ScanWorkerHeartbeat(w http.ResponseWriter, r *http.Request) {
+	workerID, ok := authenticateWorker(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		RunningJobs []string `json:"running_jobs"`
+	}
+	json.NewDecoder(r.Body).Decode(&payload)
+	if payload.RunningJobs == nil {
+		payload.RunningJobs = []string{}
+	}
+
+	_, err := dbPool.Exec(context.Background(),
+		`UPDATE scan_workers SET last_heartbeat = NOW(), status = 'online', address = $2 WHERE id = $1`,
+		workerID, r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "Failed to record heartbeat", http.StatusInternalServerError)
+		return
+	}
+
+	// Jobs we think the worker holds but it no longer reports were lost in a restart.
+	requeueWorkerJobs(`worker_id = $1 AND started_at < NOW() - INTERVAL '30 seconds' AND NOT (id::text = ANY($2::text[]))`,
+		workerID, payload.RunningJobs)
+
+	cancel := []string{}
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id FROM scan_worker_jobs
+		WHERE id::text = ANY($2::text[]) AND (status <> 'running' OR worker_id IS DISTINCT FROM $1)`,
+		workerID, payload.RunningJobs)
+	if err == nil {
+		for rows.Next() {
+			var jobID string
+			if rows.Scan(&jobID) == nil {
+				cancel = append(cancel, jobID)
+			}
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"cancel": cancel})
+}
+
+func ClaimScanWorkerJob(w http.ResponseWriter, r *http.Request) {
+	workerID, ok := authenticateWorker(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Tools []string `json:"tools"`
+	}
+	json.NewDecoder(r.Body).Decode(&payload)
+	if len(payload.Tools) == 0 {
+		var toolsJSON []byte
+		dbPool.QueryRow(context.Background(), `SELECT tools FROM scan_workers WHERE id = $1`, workerID).Scan(&toolsJSON)
+		json.Unmarshal(toolsJSON, &payload.Tools)
+	}
+
+	var job WorkerJob
+	var argsJSON []byte
+	err := dbPool.QueryRow(context.Background(), `
+		UPDATE scan_worker_jobs
+		SET status = 'running', worker_id = $1, started_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM scan_worker_jobs
+			WHERE status = 'queued' AND tool = ANY($2::text[])
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tool, kind, args, COALESCE(stdin, ''), timeout_seconds`,
+		workerID, payload.Tools).Scan(&job.ID, &job.Tool, &job.Kind, &argsJSON, &job.Stdin, &job.TimeoutSeconds)
+	if err == pgx.ErrNoRows {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Printf("[WORKER] [ERROR] Failed to claim job for worker %s: %v", workerID, err)
+		http.Error(w, "Failed to claim job", http.StatusInternalServerError)
+		return
+	}
+	json.Unmarshal(argsJSON, &job.Args)
+	if waiter := getWorkerJobWaiter(job.ID); waiter != nil {
+		job.Files = waiter.files
+		job.Env = waiter.env
+	}
+
+	log.Printf("[WORKER] [INFO] Worker %s claimed %s job %s", workerID, job.Tool, job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// workerOwnsJob reports whether the job is currently running on the worker.
+func workerOwnsJob(workerID, jobID string) bool {
+	var owns bool
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT EXISTS(SELECT 1 FROM scan_worker_jobs WHERE id = $1 AND worker_id = $2 AND status = 'running')`,
+		jobID, workerID).Scan(&owns)
+	return err == nil && owns
+}
+
+func PostScanWorkerJobOutput(w http.ResponseWriter, r *http.Request) {
+	workerID, ok := authenticateWorker(w, r)
+	if !ok {
+		return
+	}
+	jobID := mux.Vars(r)["job_id"]
+	if !workerOwnsJob(workerID, jobID) {
+		http.Error(w, "Job is not assigned to this worker", http.StatusConflict)
+		return
+	}
+
+	var chunk struct {
+		Stdout string `json:"stdout"`
+		Stderr string `json:"stderr"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, workerMaxResultBytes)).Decode(&chunk); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if waiter := getWorkerJobWaiter(jobID); waiter != nil {
+		if waiter.stdout != nil && chunk.Stdout != "" {
+			io.WriteString(waiter.stdout, chunk.Stdout)
+		}
+		if waiter.stderr != nil && chunk.Stderr != "" {
+			io.WriteString(waiter.stderr, chunk.Stderr)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func CompleteScanWorkerJob(w http.ResponseWriter, r *http.Request) {
+	workerID, ok := authenticateWorker(w, r)
+	if !ok {
+		return
+	}
+	jobID := mux.Vars(r)["job_id"]
+
+	var result WorkerJobResult
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, workerMaxResultBytes)).Decode(&result); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	status := "completed"
+	if result.Error != "" {
+		status = "failed"
+	}
+	tag, err := dbPool.Exec(context.Background(), `
+		UPDATE scan_worker_jobs SET status = $3, exit_code = $4, error = NULLIF($5, ''), completed_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = 'running'`,
+		jobID, workerID, status, result.ExitCode, result.Error)
+	if err != nil {
+		http.Error(w, "Failed to record job result", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Job is not assigned to this worker", http.StatusConflict)
+		return
+	}
+
+	log.Printf("[WORKER] [INFO] Worker %s finished job %s (%s, exit %d)", workerID, jobID, status, result.ExitCode)
+	finishWorkerJob(jobID, result)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetScanWorkers(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT w.id, w.name, w.tools, w.status, COALESCE(w.address, ''), COALESCE(w.version, ''),
+		       w.concurrency, w.last_heartbeat, w.registered_at,
+		       (SELECT COUNT(*) FROM scan_worker_jobs j WHERE j.worker_id = w.id AND j.status = 'running')
+		FROM scan_workers w
+		ORDER BY w.registered_at DESC`)
+	if err != nil {
+		http.Error(w, "Failed to fetch workers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	workers := []ScanWorker{}
+	for rows.Next() {
+		var worker ScanWorker
+		var toolsJSON []byte
+		if err := rows.Scan(&worker.ID, &worker.Name, &toolsJSON, &worker.Status, &worker.Address, &worker.Version,
+			&worker.Concurrency, &worker.LastHeartbeat, &worker.RegisteredAt, &worker.RunningJobs); err != nil {
+			log.Printf("[WORKER] [ERROR] Failed to scan worker row: %v", err)
+			continue
+		}
+		json.Unmarshal(toolsJSON, &worker.Tools)
+		workers = append(workers, worker)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workers)
+}
+
+func GetScanWorkerJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if value, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && value > 0 && value <= 1000 {
+		limit = value
+	}
+	status := r.URL.Query().Get("status")
+
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, tool, kind, args, status, worker_id::text, attempts, exit_code, error, created_at, started_at, completed_at
+		FROM scan_worker_jobs
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch worker jobs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	jobs := []ScanWorkerJob{}
+	for rows.Next() {
+		var job ScanWorkerJob
+		var argsJSON []byte
+		if err := rows.Scan(&job.ID, &job.Tool, &job.Kind, &argsJSON, &job.Status, &job.WorkerID, &job.Attempts,
+			&job.ExitCode, &job.Error, &job.CreatedAt, &job.StartedAt, &job.CompletedAt); err != nil {
+			log.Printf("[WORKER] [ERROR] Failed to scan worker job row: %v", err)
+			continue
+		}
+		json.Unmarshal(argsJSON, &job.Args)
+		jobs = append(jobs, job)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+func DeleteScanWorker(w http.ResponseWriter, r *http.Request) {
+	workerID := mux.Vars(r)["id"]
+	requeueWorkerJobs(`worker_id = $1`, workerID)
+
+	tag, err := dbPool.Exec(context.Background(), `DELETE FROM scan_workers WHERE id = $1`, workerID)
+	if err != nil {
+		http.Error(w, "Failed to delete worker", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Worker not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
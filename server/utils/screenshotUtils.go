@@ -1,16 +1,13 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -54,6 +51,10 @@ type NucleiScreenshotStatus struct {
 
 // RunNucleiScreenshotScan handles the HTTP request to start a new Nuclei screenshot scan
 func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "nuclei") {
+		return
+	}
+
 	vars := mux.Vars(r)
 	scopeTargetID := vars["id"]
 
@@ -64,8 +65,12 @@ func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
 
 	var payload struct {
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string  `json:"request_profile_id,omitempty"`
 	}
 	_ = json.NewDecoder(r.Body).Decode(&payload)
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
 
 	log.Printf("[INFO] Starting Nuclei screenshot scan for scope target ID: %s", scopeTargetID)
 
@@ -99,7 +104,8 @@ func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[INFO] Successfully inserted initial scan record for scan ID: %s", scanID)
 
-	go ExecuteAndParseNucleiScreenshotScan(scanID, domain)
+	RecordScanToolVersion("nuclei_screenshots", scanID, "nuclei")
+	go ExecuteAndParseNucleiScreenshotScan(scanID, domain, payload.RequestProfileID)
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"scan_id": scanID,
@@ -107,15 +113,10 @@ func RunNucleiScreenshotScan(w http.ResponseWriter, r *http.Request) {
 }
 
 // ExecuteAndParseNucleiScreenshotScan runs the Nuclei screenshot scan and processes its results
-func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
+func ExecuteAndParseNucleiScreenshotScan(scanID, domain, requestProfileID string) {
 	log.Printf("[INFO] Starting Nuclei screenshot scan execution for scan ID: %s", scanID)
 	startTime := time.Now()
 
-	// Get custom HTTP settings
-	customUserAgent, customHeader := GetCustomHTTPSettings()
-	log.Printf("[DEBUG] Custom User Agent: %s", customUserAgent)
-	log.Printf("[DEBUG] Custom Header: %s", customHeader)
-
 	// Get scope target ID and latest httpx results
 	var scopeTargetID string
 	err := dbPool.QueryRow(context.Background(),
@@ -170,45 +171,33 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 
 	// Build base command
 	cmdArgs := []string{
-		"docker", "exec", "ars0n-framework-v2-nuclei-1",
 		"bash", "-c",
 	}
 
 	nucleiCmd := fmt.Sprintf("echo '%s' > /urls.txt && nuclei -t /root/nuclei-templates/headless/screenshot.yaml -list /urls.txt -headless -c 25 -rl 150 -timeout 10 -retries 1 -bs 25", strings.Join(urls, "\n"))
 
-	// Add custom headers if specified
-	if customHeader != "" {
-		nucleiCmd += fmt.Sprintf(" -H '%s'", customHeader)
-	}
-
-	// Add custom user agent via header if specified
-	if customUserAgent != "" {
-		nucleiCmd += fmt.Sprintf(" -H 'User-Agent: %s'", customUserAgent)
-	}
+	// Add request profile headers for this target
+	nucleiCmd += requestHeaderShellArgs("nuclei", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))
 
 	cmdArgs = append(cmdArgs, nucleiCmd)
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	log.Printf("[INFO] Prepared Nuclei command for scan ID %s: %s", scanID, cmd.String())
+	log.Printf("[INFO] Prepared Nuclei command for scan ID %s: %s", scanID, strings.Join(redactCommandArgs(cmdArgs), " "))
 
-	var stdout, stderr bytes.Buffer
 	stdoutWriter := &ScreenshotLogWriter{prefix: "[NUCLEI-SCREENSHOT]"}
 	stderrWriter := &ScreenshotLogWriter{prefix: "[NUCLEI-SCREENSHOT-ERR]"}
-	
-	cmd.Stdout = io.MultiWriter(&stdout, stdoutWriter)
-	cmd.Stderr = io.MultiWriter(&stderr, stderrWriter)
 
 	// Execute the command
 	log.Printf("[INFO] Executing Nuclei screenshot command for scan ID: %s", scanID)
 	log.Printf("[INFO] Nuclei screenshot scan started, streaming output...")
-	err = cmd.Run()
+	executor := ToolExecutor("nuclei")
+	cmd, err := executor.Exec(context.Background(), ExecRequest{Args: cmdArgs, Stdout: stdoutWriter, Stderr: stderrWriter})
 	if err != nil {
 		log.Printf("[ERROR] Nuclei command failed for scan ID %s: %v", scanID, err)
 		UpdateNucleiScreenshotScanStatus(
 			scanID,
 			"error",
-			stdout.String(),
-			fmt.Sprintf("Command failed: %v\nStderr: %s", err, stderr.String()),
-			cmd.String(),
+			cmd.Stdout,
+			fmt.Sprintf("Command failed: %v\nStderr: %s", err, cmd.Stderr),
+			cmd.Command,
 			time.Since(startTime).String(),
 		)
 		return
@@ -219,7 +208,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 
 	// Read and process screenshot files
 	var results []string
-	screenshotFiles, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "ls", "/app/screenshots/").Output()
+	screenshotFiles, err := ToolExecOutput(context.Background(), executor, "ls", "/app/screenshots/")
 	if err != nil {
 		log.Printf("[ERROR] Failed to list screenshot files for scan ID %s: %v", scanID, err)
 		UpdateNucleiScreenshotScanStatus(
@@ -227,7 +216,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 			"error",
 			"",
 			fmt.Sprintf("Failed to list screenshot files: %v", err),
-			cmd.String(),
+			cmd.Command,
 			time.Since(startTime).String(),
 		)
 		return
@@ -246,7 +235,7 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 		log.Printf("[DEBUG] Processing screenshot file: %s", file)
 
 		// Read the screenshot file
-		imgData, err := exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "cat", "/app/screenshots/"+file).Output()
+		imgData, err := ToolExecOutput(context.Background(), executor, "cat", "/app/screenshots/"+file)
 		if err != nil {
 			log.Printf("[WARN] Failed to read screenshot file %s: %v", file, err)
 			continue
@@ -301,13 +290,13 @@ func ExecuteAndParseNucleiScreenshotScan(scanID, domain string) {
 		scanID,
 		"success",
 		strings.Join(results, "\n"),
-		stderr.String(),
-		cmd.String(),
+		cmd.Stderr,
+		cmd.Command,
 		time.Since(startTime).String(),
 	)
 
 	// Clean up screenshots in the container
-	exec.Command("docker", "exec", "ars0n-framework-v2-nuclei-1", "rm", "-rf", "/app/screenshots/*").Run()
+	executor.Exec(context.Background(), ExecRequest{Args: []string{"rm", "-rf", "/app/screenshots/*"}})
 }
 
 // UpdateNucleiScreenshotScanStatus updates the status of a Nuclei screenshot scan
@@ -328,7 +317,7 @@ func GetNucleiScreenshotScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan NucleiScreenshotStatus
-	query := `SELECT * FROM nuclei_screenshots WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM nuclei_screenshots WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -380,7 +369,7 @@ func GetNucleiScreenshotScansForScopeTarget(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	scopeTargetID := vars["id"]
 
-	query := `SELECT * FROM nuclei_screenshots WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM nuclei_screenshots WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -133,11 +133,8 @@ func RunSecurityTrailsCompanyScan(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteSecurityTrailsCompanyScan(scanID, companyName string) {
-	log.Printf("[SECURITYTRAILS-COMPANY] [INFO] Starting SecurityTrails Company scan execution for company %s (scan ID: %s)", companyName, scanID)
-	startTime := time.Now()
-
-	// Get SecurityTrails API key from database
+// getSecurityTrailsAPIKey returns the most recently saved SecurityTrails key.
+func getSecurityTrailsAPIKey() (string, error) {
 	var apiKeyJSON string
 	err := dbPool.QueryRow(context.Background(), `
 		SELECT api_key_value 
@@ -148,34 +145,39 @@ func ExecuteSecurityTrailsCompanyScan(scanID, companyName string) {
 	`).Scan(&apiKeyJSON)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			log.Printf("[SECURITYTRAILS-COMPANY] [ERROR] No SecurityTrails API key found in database")
-			UpdateSecurityTrailsCompanyScanStatus(scanID, "error", "", "No SecurityTrails API key found. Please configure your API key in the settings.", "", time.Since(startTime).String())
-		} else {
-			log.Printf("[SECURITYTRAILS-COMPANY] [ERROR] Failed to get SecurityTrails API key: %v", err)
-			UpdateSecurityTrailsCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Failed to get SecurityTrails API key: %v", err), "", time.Since(startTime).String())
+			return "", fmt.Errorf("No SecurityTrails API key found. Please configure your API key in the settings.")
 		}
-		return
+		return "", fmt.Errorf("Failed to get SecurityTrails API key: %v", err)
 	}
 
 	// Parse the API key JSON to extract the actual key
 	var keyData map[string]interface{}
 	if err := json.Unmarshal([]byte(apiKeyJSON), &keyData); err != nil {
-		log.Printf("[SECURITYTRAILS-COMPANY] [ERROR] Failed to parse API key JSON: %v", err)
-		UpdateSecurityTrailsCompanyScanStatus(scanID, "error", "", fmt.Sprintf("Failed to parse API key JSON: %v", err), "", time.Since(startTime).String())
-		return
+		return "", fmt.Errorf("Failed to parse API key JSON: %v", err)
 	}
 
 	apiKey, ok := keyData["api_key"].(string)
 	if !ok || apiKey == "" {
-		log.Printf("[SECURITYTRAILS-COMPANY] [ERROR] SecurityTrails API key is empty or invalid")
-		UpdateSecurityTrailsCompanyScanStatus(scanID, "error", "", "SecurityTrails API key is empty or invalid. Please configure your API key in the settings.", "", time.Since(startTime).String())
+		return "", fmt.Errorf("SecurityTrails API key is empty or invalid. Please configure your API key in the settings.")
+	}
+	return apiKey, nil
+}
+
+func ExecuteSecurityTrailsCompanyScan(scanID, companyName string) {
+	log.Printf("[SECURITYTRAILS-COMPANY] [INFO] Starting SecurityTrails Company scan execution for company %s (scan ID: %s)", companyName, scanID)
+	startTime := time.Now()
+
+	apiKey, err := getSecurityTrailsAPIKey()
+	if err != nil {
+		log.Printf("[SECURITYTRAILS-COMPANY] [ERROR] %v", err)
+		UpdateSecurityTrailsCompanyScanStatus(scanID, "error", "", err.Error(), "", time.Since(startTime).String())
 		return
 	}
 
 	log.Printf("[SECURITYTRAILS-COMPANY] [INFO] Successfully retrieved SecurityTrails API key")
 
 	// Create HTTP client with timeout
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := egressClient(EgressScopeSecurityTrails, &http.Client{Timeout: 60 * time.Second})
 
 	// Create request to SecurityTrails API
 	url := fmt.Sprintf("https://api.securitytrails.com/v1/domains/list?whois_organization=%s", url.QueryEscape(companyName))
@@ -0,0 +1,478 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	serviceProbeTimeout = 3 * time.Second
+	serviceBannerLimit  = 512
+)
+
+// DiscoveredService is an open TCP port and what answers on it.
+type DiscoveredService struct {
+	ID           string                 `json:"id,omitempty"`
+	ScanID       string                 `json:"scan_id"`
+	IPAddress    string                 `json:"ip_address"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	Port         int                    `json:"port"`
+	Transport    string                 `json:"transport"`
+	Service      string                 `json:"service"`
+	Product      string                 `json:"product,omitempty"`
+	Version      string                 `json:"version,omitempty"`
+	Banner       string                 `json:"banner,omitempty"`
+	TLS          bool                   `json:"tls"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	DiscoveredAt time.Time              `json:"discovered_at"`
+}
+
+// Probes for services that wait for the client to speak, tried in this order
+// unless the port suggests one.
+var serviceProbeOrder = []string{"tls", "redis", "postgresql", "mongodb", "rdp"}
+
+var servicePortHints = map[int]string{
+	443: "tls", 465: "tls", 636: "tls", 853: "tls", 990: "tls", 993: "tls", 995: "tls", 5986: "tls", 8443: "tls",
+	6379: "redis", 5432: "postgresql", 27017: "mongodb", 27018: "mongodb", 27019: "mongodb", 3389: "rdp",
+}
+
+var bannerProductPattern = regexp.MustCompile(`(?i)(vsFTPd|ProFTPD|Pure-FTPd|FileZilla Server|Microsoft FTP Service|Exim|Sendmail|Postfix|Microsoft ESMTP MAIL Service|Dovecot|Courier|OpenSMTPD)[ /_]?v?([0-9][\w.\-]*)?`)
+
+func probeAddress(ip string, port int) string {
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+func truncateBanner(banner []byte) string {
+	if len(banner) > serviceBannerLimit {
+		banner = banner[:serviceBannerLimit]
+	}
+	// Binary handshakes are kept readable, like nmap's service banners
+	printable := strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\r' && r != '\n' && r != '\t' || r == 0x7f {
+			return '.'
+		}
+		return r
+	}, strings.ToValidUTF8(string(banner), ""))
+	return strings.TrimSpace(printable)
+}
+
+// readBanner waits briefly for a greeting from services that speak first.
+func readBanner(conn net.Conn, timeout time.Duration) []byte {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 2048)
+	n, _ := conn.Read(buf)
+	return buf[:n]
+}
+
+// exchange sends a probe on a fresh connection and returns the first reply.
+func exchange(ip string, port int, probe []byte, timeout time.Duration) []byte {
+	conn, err := net.DialTimeout("tcp", probeAddress(ip, port), timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(probe); err != nil {
+		return nil
+	}
+	return readBanner(conn, timeout)
+}
+
+// classifyBanner identifies services that greet the client on connect.
+func classifyBanner(service *DiscoveredService, banner []byte) bool {
+	text := string(banner)
+	switch {
+	case strings.HasPrefix(text, "SSH-"):
+		service.Service = "ssh"
+		// SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1
+		line, _, _ := strings.Cut(text, "\n")
+		parts := strings.SplitN(strings.TrimSpace(line), "-", 3)
+		if len(parts) == 3 {
+			software, _, _ := strings.Cut(parts[2], " ")
+			service.Product, service.Version, _ = strings.Cut(software, "_")
+			service.Details = map[string]interface{}{"protocol_version": parts[1]}
+		}
+	case strings.HasPrefix(text, "220"):
+		lower := strings.ToLower(text)
+		switch {
+		case strings.Contains(lower, "ftp"):
+			service.Service = "ftp"
+		case strings.Contains(lower, "smtp") || strings.Contains(lower, "mail"):
+			service.Service = "smtp"
+		case service.Port == 21 || service.Port == 990:
+			service.Service = "ftp"
+		default:
+			service.Service = "smtp"
+		}
+	case strings.HasPrefix(text, "+OK"):
+		service.Service = "pop3"
+	case strings.HasPrefix(text, "* OK"):
+		service.Service = "imap"
+	case strings.HasPrefix(text, "RFB "):
+		service.Service = "vnc"
+		service.Version = strings.TrimSpace(strings.TrimPrefix(text, "RFB "))
+	case len(banner) > 0 && banner[0] == 0xff && service.Port != 3306:
+		service.Service = "telnet"
+	case len(banner) > 5 && int(banner[0])|int(banner[1])<<8|int(banner[2])<<16 == len(banner)-4 && (banner[4] == 0x0a || banner[4] == 0xff):
+		// MySQL handshake: 3-byte length, sequence, then protocol 10 and a
+		// NUL-terminated version, or an error packet when the host is refused
+		service.Service = "mysql"
+		service.Product = "MySQL"
+		if banner[4] == 0x0a {
+			version, _, _ := bytes.Cut(banner[5:], []byte{0})
+			service.Version = string(version)
+			if strings.Contains(strings.ToLower(service.Version), "mariadb") {
+				service.Product = "MariaDB"
+			}
+		} else if len(banner) > 7 {
+			service.Details = map[string]interface{}{"error": truncateBanner(banner[7:])}
+		}
+		return true
+	default:
+		return false
+	}
+	if service.Product == "" {
+		if m := bannerProductPattern.FindStringSubmatch(text); m != nil {
+			service.Product, service.Version = m[1], m[2]
+		}
+	}
+	return true
+}
+
+func probeTLS(service *DiscoveredService, timeout time.Duration) bool {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", probeAddress(service.IPAddress, service.Port), &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	})
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	service.TLS = true
+	service.Service = "tls"
+	service.Details = map[string]interface{}{
+		"tls_version":  tls.VersionName(state.Version),
+		"cipher_suite": tls.CipherSuiteName(state.CipherSuite),
+	}
+	if state.NegotiatedProtocol != "" {
+		service.Details["alpn"] = state.NegotiatedProtocol
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		service.Details["subject"] = cert.Subject.CommonName
+		service.Details["issuer"] = cert.Issuer.CommonName
+		service.Details["not_after"] = cert.NotAfter
+		if len(cert.DNSNames) > 0 {
+			service.Details["dns_names"] = cert.DNSNames
+		}
+	}
+
+	// Implicit TLS mail and file services still greet once the tunnel is up
+	if banner := readBanner(conn, timeout); len(banner) > 0 {
+		details := service.Details
+		inner := *service
+		if classifyBanner(&inner, banner) {
+			*service = inner
+			service.Banner = truncateBanner(banner)
+			for k, v := range details {
+				if _, exists := service.Details[k]; !exists {
+					if service.Details == nil {
+						service.Details = map[string]interface{}{}
+					}
+					service.Details[k] = v
+				}
+			}
+		}
+	}
+	return true
+}
+
+func probeRedis(service *DiscoveredService, timeout time.Duration) bool {
+	reply := string(exchange(service.IPAddress, service.Port, []byte("PING\r\n"), timeout))
+	switch {
+	case strings.HasPrefix(reply, "+PONG"):
+		service.Details = map[string]interface{}{"auth_required": false}
+		info := string(exchange(service.IPAddress, service.Port, []byte("INFO server\r\n"), timeout))
+		for _, line := range strings.Split(info, "\r\n") {
+			if version, found := strings.CutPrefix(line, "redis_version:"); found {
+				service.Version = version
+			}
+		}
+	case strings.HasPrefix(reply, "-NOAUTH"), strings.Contains(reply, "uthentication"):
+		service.Details = map[string]interface{}{"auth_required": true}
+	case strings.HasPrefix(reply, "-DENIED"):
+		service.Details = map[string]interface{}{"protected_mode": true}
+	default:
+		return false
+	}
+	service.Service = "redis"
+	service.Product = "Redis"
+	service.Banner = truncateBanner([]byte(reply))
+	return true
+}
+
+// probePostgres sends an SSLRequest, which a server answers with a single S or N.
+func probePostgres(service *DiscoveredService, timeout time.Duration) bool {
+	probe := make([]byte, 8)
+	binary.BigEndian.PutUint32(probe[0:4], 8)
+	binary.BigEndian.PutUint32(probe[4:8], 80877103)
+	reply := exchange(service.IPAddress, service.Port, probe, timeout)
+	if len(reply) != 1 || (reply[0] != 'S' && reply[0] != 'N') {
+		return false
+	}
+	service.Service = "postgresql"
+	service.Product = "PostgreSQL"
+	service.Details = map[string]interface{}{"ssl_supported": reply[0] == 'S'}
+	return true
+}
+
+// probeMongoDB sends a legacy isMaster query, which every server version still
+// answers for the connection handshake.
+func probeMongoDB(service *DiscoveredService, timeout time.Duration) bool {
+	doc := []byte{19, 0, 0, 0, 0x10}
+	doc = append(doc, "isMaster\x00"...)
+	doc = append(doc, 1, 0, 0, 0, 0)
+
+	body := []byte{0, 0, 0, 0}
+	body = append(body, "admin.$cmd\x00"...)
+	body = append(body, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff)
+	body = append(body, doc...)
+
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(16+len(body)))
+	binary.LittleEndian.PutUint32(header[4:8], 1)
+	binary.LittleEndian.PutUint32(header[12:16], 2004)
+
+	reply := exchange(service.IPAddress, service.Port, append(header, body...), timeout)
+	if len(reply) < 16 {
+		return false
+	}
+	if opCode := binary.LittleEndian.Uint32(reply[12:16]); opCode != 1 && opCode != 2013 {
+		return false
+	}
+	service.Service = "mongodb"
+	service.Product = "MongoDB"
+	if i := bytes.Index(reply, []byte("maxWireVersion\x00")); i >= 0 && i+19 <= len(reply) {
+		service.Details = map[string]interface{}{
+			"max_wire_version": binary.LittleEndian.Uint32(reply[i+15 : i+19]),
+		}
+	}
+	return true
+}
+
+// rdpSecurityProtocols names the protocol selected in an RDP negotiation response.
+var rdpSecurityProtocols = map[uint32]string{0: "rdp", 1: "tls", 2: "credssp", 8: "rdstls"}
+
+// probeRDP sends an X.224 connection request offering TLS and CredSSP.
+func probeRDP(service *DiscoveredService, timeout time.Duration) bool {
+	probe := []byte{
+		0x03, 0x00, 0x00, 0x13, // TPKT
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, // X.224 connection request
+		0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00, // RDP_NEG_REQ: TLS | CredSSP
+	}
+	reply := exchange(service.IPAddress, service.Port, probe, timeout)
+	if len(reply) < 11 || reply[0] != 0x03 || reply[1] != 0x00 || reply[5] != 0xd0 {
+		return false
+	}
+	service.Service = "rdp"
+	service.Details = map[string]interface{}{}
+	if len(reply) >= 19 {
+		switch reply[11] {
+		case 0x02:
+			selected := binary.LittleEndian.Uint32(reply[15:19])
+			service.Details["security_protocol"] = rdpSecurityProtocols[selected]
+			service.Details["nla_required"] = selected == 2 || selected == 8
+		case 0x03:
+			service.Details["negotiation_failure"] = binary.LittleEndian.Uint32(reply[15:19])
+		}
+	}
+	return true
+}
+
+var serviceProbes = map[string]func(*DiscoveredService, time.Duration) bool{
+	"tls":        probeTLS,
+	"redis":      probeRedis,
+	"postgresql": probePostgres,
+	"mongodb":    probeMongoDB,
+	"rdp":        probeRDP,
+}
+
+// fingerprintService identifies what listens on an open port that did not
+// answer HTTP: first from any greeting it sends, then by trying the probes
+// for client-first protocols, the port's likely service first.
+func fingerprintService(scanID, ip string, port int) DiscoveredService {
+	service := DiscoveredService{
+		ScanID:       scanID,
+		IPAddress:    ip,
+		Port:         port,
+		Transport:    "tcp",
+		Service:      "unknown",
+		DiscoveredAt: time.Now(),
+	}
+
+	conn, err := net.DialTimeout("tcp", probeAddress(ip, port), serviceProbeTimeout)
+	if err != nil {
+		return service
+	}
+	banner := readBanner(conn, serviceProbeTimeout)
+	conn.Close()
+	if len(banner) > 0 {
+		service.Banner = truncateBanner(banner)
+		classifyBanner(&service, banner)
+		return service
+	}
+
+	order := serviceProbeOrder
+	if hint, ok := servicePortHints[port]; ok {
+		order = append([]string{hint}, serviceProbeOrder...)
+	}
+	tried := make(map[string]bool)
+	for _, name := range order {
+		if tried[name] {
+			continue
+		}
+		tried[name] = true
+		if serviceProbes[name](&service, serviceProbeTimeout) {
+			break
+		}
+	}
+	return service
+}
+
+// webService records an open port that answered HTTP as a service.
+func webService(webServer LiveWebServer) DiscoveredService {
+	service := DiscoveredService{
+		ScanID:       webServer.ScanID,
+		IPAddress:    webServer.IPAddress,
+		Hostname:     webServer.Hostname,
+		Port:         webServer.Port,
+		Transport:    "tcp",
+		Service:      webServer.Protocol,
+		TLS:          webServer.Protocol == "https",
+		DiscoveredAt: time.Now(),
+	}
+	service.Product, service.Version, _ = strings.Cut(webServer.ServerHeader, "/")
+	if webServer.Title != "" {
+		service.Details = map[string]interface{}{"title": webServer.Title}
+	}
+	return service
+}
+
+func insertDiscoveredService(service DiscoveredService) {
+	detailsJSON, _ := json.Marshal(service.Details)
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO discovered_services (scan_id, ip_address, hostname, port, transport, service, product, version, banner, tls, details)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+		ON CONFLICT (scan_id, ip_address, port, transport) DO UPDATE SET
+			hostname = EXCLUDED.hostname, service = EXCLUDED.service, product = EXCLUDED.product,
+			version = EXCLUDED.version, banner = EXCLUDED.banner, tls = EXCLUDED.tls,
+			details = EXCLUDED.details, discovered_at = NOW()`,
+		service.ScanID, service.IPAddress, service.Hostname, service.Port, service.Transport, service.Service,
+		service.Product, service.Version, service.Banner, service.TLS, detailsJSON)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to insert discovered service: %v", err)
+	}
+}
+
+// GetDiscoveredServices lists the services found by a scan, optionally
+// filtered with ?service=.
+func GetDiscoveredServices(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	service := r.URL.Query().Get("service")
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, scan_id, host(ip_address), COALESCE(hostname, ''), port, transport, service,
+			COALESCE(product, ''), COALESCE(version, ''), COALESCE(banner, ''), tls, details, discovered_at
+		FROM discovered_services
+		WHERE scan_id = $1 AND ($2 = '' OR service = $2)
+		ORDER BY ip_address, port`, scanID, service)
+	if err != nil {
+		log.Printf("[IP-PORT-SCAN] [ERROR] Failed to get discovered services: %v", err)
+		http.Error(w, "Failed to get discovered services", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	services := []DiscoveredService{}
+	for rows.Next() {
+		var s DiscoveredService
+		var detailsJSON []byte
+		if err := rows.Scan(&s.ID, &s.ScanID, &s.IPAddress, &s.Hostname, &s.Port, &s.Transport, &s.Service,
+			&s.Product, &s.Version, &s.Banner, &s.TLS, &detailsJSON, &s.DiscoveredAt); err != nil {
+			log.Printf("[IP-PORT-SCAN] [ERROR] Error scanning service row: %v", err)
+			continue
+		}
+		json.Unmarshal(detailsJSON, &s.Details)
+		services = append(services, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// consolidateNetworkServices adds a service asset per address and port from
+// each address's most recent successful IP/port scan.
+func consolidateNetworkServices(scopeTargetID string) (int, error) {
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_assets (
+			scope_target_id, asset_type, asset_identifier, asset_subtype, ip_address, port, protocol,
+			domain, service_product, service_version, service_banner, service_tls, service_details
+		)
+		SELECT DISTINCT ON (ds.ip_address, ds.port, ds.transport)
+			$1::uuid, 'service',
+			CASE WHEN family(ds.ip_address) = 6 THEN '[' || host(ds.ip_address) || ']' ELSE host(ds.ip_address) END
+				|| ':' || ds.port || '/' || ds.transport,
+			ds.service, host(ds.ip_address), ds.port, ds.transport,
+			ds.hostname, ds.product, ds.version, ds.banner, ds.tls, ds.details
+		FROM discovered_services ds
+		JOIN ip_port_scans ips ON ds.scan_id = ips.scan_id
+		WHERE ips.scope_target_id = $1::uuid AND ips.status = 'success'
+		ORDER BY ds.ip_address, ds.port, ds.transport, ds.discovered_at DESC
+		ON CONFLICT (scope_target_id, asset_type, asset_identifier) DO UPDATE SET
+			asset_subtype = EXCLUDED.asset_subtype,
+			domain = EXCLUDED.domain,
+			service_product = EXCLUDED.service_product,
+			service_version = EXCLUDED.service_version,
+			service_banner = EXCLUDED.service_banner,
+			service_tls = EXCLUDED.service_tls,
+			service_details = EXCLUDED.service_details,
+			last_updated = NOW()`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// createServiceRelationships links IP addresses to the services they expose.
+func createServiceRelationships(scopeTargetID string) (int, error) {
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_relationships (
+			parent_asset_id, child_asset_id, relationship_type, relationship_data
+		)
+		SELECT DISTINCT
+			ip.id, svc.id, 'exposes_service',
+			jsonb_build_object('port', svc.port, 'service', svc.asset_subtype)
+		FROM consolidated_attack_surface_assets svc
+		JOIN consolidated_attack_surface_assets ip
+			ON ip.scope_target_id = svc.scope_target_id AND ip.asset_type = 'ip_address' AND ip.ip_address = svc.ip_address
+		WHERE svc.scope_target_id = $1::uuid AND svc.asset_type = 'service'
+		ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO NOTHING`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
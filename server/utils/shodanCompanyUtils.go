@@ -7,12 +7,11 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
+	"ars0n-framework-v2-server/domainutil"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5"
@@ -214,12 +213,14 @@ func searchShodanForCompany(companyName, apiKey string) ([]string, error) {
 		fmt.Sprintf(`org:"%s"`, companyName),
 	}
 
+	client := egressClient(EgressScopeShodan, &http.Client{Timeout: 60 * time.Second})
+
 	for _, query := range queries {
 		log.Printf("[SHODAN-COMPANY] [INFO] Executing Shodan query: %s", query)
 
 		url := fmt.Sprintf("https://api.shodan.io/shodan/host/search?key=%s&query=%s", apiKey, query)
 
-		resp, err := http.Get(url)
+		resp, err := client.Get(url)
 		if err != nil {
 			log.Printf("[SHODAN-COMPANY] [WARN] HTTP request failed for query '%s': %v", query, err)
 			continue
@@ -254,26 +255,26 @@ func searchShodanForCompany(companyName, apiKey string) ([]string, error) {
 		for _, match := range searchResp.Matches {
 			if match.SSL != nil && match.SSL.Cert != nil {
 				if match.SSL.Cert.Subject != nil && match.SSL.Cert.Subject.CN != "" {
-					if domain := extractRootDomain(match.SSL.Cert.Subject.CN); domain != "" {
+					if domain := domainutil.RootDomain(match.SSL.Cert.Subject.CN); domain != "" {
 						domainSet[domain] = true
 					}
 				}
 
 				for _, san := range match.SSL.Cert.SubjectAltName {
-					if domain := extractRootDomain(san); domain != "" {
+					if domain := domainutil.RootDomain(san); domain != "" {
 						domainSet[domain] = true
 					}
 				}
 			}
 
 			for _, hostname := range match.Hostnames {
-				if domain := extractRootDomain(hostname); domain != "" {
+				if domain := domainutil.RootDomain(hostname); domain != "" {
 					domainSet[domain] = true
 				}
 			}
 
 			if match.HTTP != nil && match.HTTP.Host != "" {
-				if domain := extractRootDomain(match.HTTP.Host); domain != "" {
+				if domain := domainutil.RootDomain(match.HTTP.Host); domain != "" {
 					domainSet[domain] = true
 				}
 			}
@@ -291,93 +292,6 @@ func searchShodanForCompany(companyName, apiKey string) ([]string, error) {
 	return domains, nil
 }
 
-func extractRootDomain(hostname string) string {
-	if hostname == "" || !strings.Contains(hostname, ".") {
-		return ""
-	}
-
-	hostname = strings.ToLower(hostname)
-
-	if strings.HasPrefix(hostname, "*.") {
-		hostname = hostname[2:]
-	}
-
-	if isIPAddress(hostname) {
-		return ""
-	}
-
-	if !isValidDomain(hostname) {
-		return ""
-	}
-
-	parts := strings.Split(hostname, ".")
-	if len(parts) < 2 {
-		return ""
-	}
-
-	return strings.Join(parts[len(parts)-2:], ".")
-}
-
-func isIPAddress(str string) bool {
-	if net.ParseIP(str) != nil {
-		return true
-	}
-
-	parts := strings.Split(str, ".")
-	if len(parts) != 4 {
-		return false
-	}
-
-	for _, part := range parts {
-		if num, err := strconv.Atoi(part); err != nil || num < 0 || num > 255 {
-			return false
-		}
-	}
-	return true
-}
-
-func isValidDomain(domain string) bool {
-	if len(domain) == 0 || len(domain) > 253 {
-		return false
-	}
-
-	if domain[len(domain)-1] == '.' {
-		domain = domain[:len(domain)-1]
-	}
-
-	parts := strings.Split(domain, ".")
-	if len(parts) < 2 {
-		return false
-	}
-
-	for _, part := range parts {
-		if len(part) == 0 || len(part) > 63 {
-			return false
-		}
-
-		if part[0] == '-' || part[len(part)-1] == '-' {
-			return false
-		}
-
-		for _, char := range part {
-			if !((char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '-') {
-				return false
-			}
-		}
-	}
-
-	lastPart := parts[len(parts)-1]
-	hasLetter := false
-	for _, char := range lastPart {
-		if char >= 'a' && char <= 'z' {
-			hasLetter = true
-			break
-		}
-	}
-
-	return hasLetter
-}
-
 func UpdateShodanCompanyScanStatus(scanID, status, result, stderr, command, execTime string) {
 	log.Printf("[SHODAN-COMPANY] [INFO] Updating scan status for scan ID %s to: %s", scanID, status)
 
@@ -0,0 +1,725 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultPermutationBudget   = 5000
+	maxPermutationBudget       = 200000
+	defaultPermutationMaxWords = 40
+)
+
+var permutationEnvTokens = []string{
+	"dev", "development", "stg", "stage", "staging", "uat", "qa", "test", "testing",
+	"preprod", "prod", "production", "sandbox", "demo", "int", "beta", "perf",
+}
+
+var permutationRegionTokens = []string{
+	"us", "eu", "uk", "ap", "apac", "emea", "na", "latam", "au", "ca", "de", "fr", "jp", "sg", "br",
+	"east", "west", "north", "south", "central",
+	"use1", "use2", "usw1", "usw2", "euw1", "euw2", "euc1", "apse1", "apse2", "apne1",
+}
+
+// PermutationOptions bounds how much the engine generates.
+type PermutationOptions struct {
+	Budget   int `json:"budget"`
+	MaxWords int `json:"max_words"`
+}
+
+func (o PermutationOptions) normalized() PermutationOptions {
+	if o.Budget <= 0 {
+		o.Budget = defaultPermutationBudget
+	}
+	if o.Budget > maxPermutationBudget {
+		o.Budget = maxPermutationBudget
+	}
+	if o.MaxWords <= 0 {
+		o.MaxWords = defaultPermutationMaxWords
+	}
+	return o
+}
+
+// PermutationPatterns summarizes what the engine learned from the observed names.
+type PermutationPatterns struct {
+	Observed     int      `json:"observed"`
+	Numeric      int      `json:"numeric"`
+	Environments []string `json:"environments"`
+	Regions      []string `json:"regions"`
+	DashJoins    int      `json:"dash_joins"`
+	DotJoins     int      `json:"dot_joins"`
+	TopWords     []string `json:"top_words"`
+}
+
+// PermutationCandidate is a generated name. Prefix is the part below the base
+// domain, which is what shuffledns expects in a brute-force wordlist.
+type PermutationCandidate struct {
+	Subdomain string  `json:"subdomain"`
+	Prefix    string  `json:"prefix"`
+	Score     float64 `json:"score"`
+	Pattern   string  `json:"pattern"`
+}
+
+// permutationName is a prefix split into dot labels, each split into dash tokens.
+type permutationName [][]string
+
+func parsePermutationName(prefix string) permutationName {
+	var name permutationName
+	for _, label := range strings.Split(prefix, ".") {
+		name = append(name, strings.Split(label, "-"))
+	}
+	return name
+}
+
+func (n permutationName) String() string {
+	labels := make([]string, len(n))
+	for i, tokens := range n {
+		labels[i] = strings.Join(tokens, "-")
+	}
+	return strings.Join(labels, ".")
+}
+
+func (n permutationName) clone() permutationName {
+	out := make(permutationName, len(n))
+	for i, tokens := range n {
+		out[i] = append([]string(nil), tokens...)
+	}
+	return out
+}
+
+// withToken returns a copy of n with one token replaced.
+func (n permutationName) withToken(label, token int, value string) permutationName {
+	out := n.clone()
+	out[label][token] = value
+	return out
+}
+
+type permutationEngine struct {
+	base       string
+	known      map[string]bool
+	names      []permutationName
+	words      map[string]int
+	envs       map[string]int
+	regions    map[string]int
+	numeric    int
+	dashShare  float64
+	dotShare   float64
+	candidates map[string]*PermutationCandidate
+	patterns   PermutationPatterns
+}
+
+func newPermutationEngine(baseDomain string, observed []string) *permutationEngine {
+	e := &permutationEngine{
+		base:       baseDomain,
+		known:      make(map[string]bool),
+		words:      make(map[string]int),
+		envs:       make(map[string]int),
+		regions:    make(map[string]int),
+		candidates: make(map[string]*PermutationCandidate),
+	}
+	envSet := tokenSet(permutationEnvTokens)
+	regionSet := tokenSet(permutationRegionTokens)
+	dashJoins, dotJoins := 0, 0
+
+	for _, raw := range observed {
+		subdomain, err := domainutil.Normalize(raw)
+		if err != nil || subdomain == baseDomain || !strings.HasSuffix(subdomain, "."+baseDomain) {
+			continue
+		}
+		prefix := strings.TrimSuffix(subdomain, "."+baseDomain)
+		if e.known[prefix] {
+			continue
+		}
+		e.known[prefix] = true
+
+		name := parsePermutationName(prefix)
+		e.names = append(e.names, name)
+		dotJoins += len(name) - 1
+		for _, tokens := range name {
+			dashJoins += len(tokens) - 1
+			for _, token := range tokens {
+				word := token
+				if stem, _, ok := splitNumericToken(token); ok {
+					e.numeric++
+					word = stem
+				}
+				switch {
+				case envSet[token]:
+					e.envs[token]++
+				case regionSet[token]:
+					e.regions[token]++
+				case len(word) >= 2 && !isAllDigits(word):
+					e.words[word]++
+				}
+			}
+		}
+	}
+
+	// Laplace smoothing keeps both join styles in play when one has not been seen
+	e.dashShare = float64(dashJoins+1) / float64(dashJoins+dotJoins+2)
+	e.dotShare = 1 - e.dashShare
+
+	e.patterns = PermutationPatterns{
+		Observed:     len(e.names),
+		Numeric:      e.numeric,
+		Environments: rankedTokens(e.envs, 0),
+		Regions:      rankedTokens(e.regions, 0),
+		DashJoins:    dashJoins,
+		DotJoins:     dotJoins,
+	}
+	return e
+}
+
+func tokenSet(tokens []string) map[string]bool {
+	set := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		set[t] = true
+	}
+	return set
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// splitNumericToken splits "web01" into ("web", "01").
+func splitNumericToken(token string) (string, string, bool) {
+	i := len(token)
+	for i > 0 && token[i-1] >= '0' && token[i-1] <= '9' {
+		i--
+	}
+	if i == len(token) || len(token)-i > 6 {
+		return "", "", false
+	}
+	return token[:i], token[i:], true
+}
+
+// rankedTokens orders tokens by frequency, then name, keeping at most limit (0 = all).
+func rankedTokens(counts map[string]int, limit int) []string {
+	tokens := make([]string, 0, len(counts))
+	for t := range counts {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if counts[tokens[i]] != counts[tokens[j]] {
+			return counts[tokens[i]] > counts[tokens[j]]
+		}
+		return tokens[i] < tokens[j]
+	})
+	if limit > 0 && len(tokens) > limit {
+		tokens = tokens[:limit]
+	}
+	return tokens
+}
+
+func (e *permutationEngine) add(name permutationName, score float64, pattern string) {
+	prefix := name.String()
+	if prefix == "" || e.known[prefix] {
+		return
+	}
+	if _, exists := e.candidates[prefix]; !exists {
+		subdomain := prefix + "." + e.base
+		if !domainutil.IsValid(subdomain) {
+			return
+		}
+		e.candidates[prefix] = &PermutationCandidate{Subdomain: subdomain, Prefix: prefix, Pattern: pattern}
+	}
+	// Names reached by several patterns are more likely to exist, so scores add up
+	c := e.candidates[prefix]
+	if score > c.Score {
+		c.Pattern = pattern
+	}
+	c.Score += score
+}
+
+// numericIncrements walks around every numbered token, keeping zero padding: web01 -> web02, web00.
+func (e *permutationEngine) numericIncrements() {
+	for _, name := range e.names {
+		for li, tokens := range name {
+			for ti, token := range tokens {
+				stem, digits, ok := splitNumericToken(token)
+				if !ok {
+					continue
+				}
+				n, err := strconv.Atoi(digits)
+				if err != nil {
+					continue
+				}
+				for _, delta := range []int{1, 2, -1, 3, -2, 4, 5} {
+					v := n + delta
+					if v < 0 {
+						continue
+					}
+					value := fmt.Sprintf("%s%0*d", stem, len(digits), v)
+					distance := delta
+					if distance < 0 {
+						distance = -distance
+					}
+					e.add(name.withToken(li, ti, value), 0.9/float64(distance), "numeric_increment")
+				}
+			}
+		}
+	}
+}
+
+// swapTokens replaces tokens from a vocabulary (environments, regions) with the
+// other members seen in this target first and the generic vocabulary second.
+func (e *permutationEngine) swapTokens(observed map[string]int, vocabulary []string, seenWeight, genericWeight float64, pattern string) {
+	if len(observed) == 0 {
+		return
+	}
+	total := 0
+	for _, count := range observed {
+		total += count
+	}
+	for _, name := range e.names {
+		for li, tokens := range name {
+			for ti, token := range tokens {
+				if _, ok := observed[token]; !ok {
+					continue
+				}
+				for _, replacement := range vocabulary {
+					if containsToken(tokens, replacement) {
+						continue
+					}
+					score := genericWeight
+					if count, seen := observed[replacement]; seen {
+						score = seenWeight * (0.5 + float64(count)/float64(total))
+					}
+					e.add(name.withToken(li, ti, replacement), score, pattern)
+				}
+			}
+		}
+	}
+}
+
+// insertEnvironments adds environment tokens to names that have none, using the
+// join styles this target already uses: dev-api, api-dev and dev.api.
+func (e *permutationEngine) insertEnvironments() {
+	envs := rankedTokens(e.envs, 5)
+	weight := 0.7
+	if len(envs) == 0 {
+		envs = []string{"dev", "staging", "test"}
+		weight = 0.2
+	}
+	envSet := tokenSet(permutationEnvTokens)
+
+	for _, name := range e.names {
+		hasEnv := false
+		for _, tokens := range name {
+			for _, token := range tokens {
+				if envSet[token] {
+					hasEnv = true
+				}
+			}
+		}
+		if hasEnv {
+			continue
+		}
+		for rank, env := range envs {
+			score := weight / float64(rank+1)
+			prepended := name.clone()
+			prepended[0] = append([]string{env}, prepended[0]...)
+			e.add(prepended, score*e.dashShare, "environment_insert")
+
+			appended := name.clone()
+			appended[0] = append(appended[0], env)
+			e.add(appended, score*e.dashShare*0.8, "environment_insert")
+
+			e.add(append(permutationName{{env}}, name...), score*e.dotShare, "environment_insert")
+		}
+	}
+}
+
+// joinWords combines the most frequent words with the first label of each name.
+func (e *permutationEngine) joinWords(maxWords int) {
+	words := rankedTokens(e.words, maxWords)
+	e.patterns.TopWords = words
+	if len(words) == 0 {
+		return
+	}
+	top := float64(e.words[words[0]])
+
+	firstLabels := make(map[string]bool)
+	for _, name := range e.names {
+		if len(name) == 1 && len(name[0]) == 1 {
+			firstLabels[name[0][0]] = true
+		}
+	}
+	for label := range firstLabels {
+		for _, word := range words {
+			if word == label {
+				continue
+			}
+			score := 0.3 * float64(e.words[word]) / top
+			e.add(permutationName{{word, label}}, score*e.dashShare, "word_join")
+			e.add(permutationName{{label, word}}, score*e.dashShare*0.8, "word_join")
+			e.add(permutationName{{word}, {label}}, score*e.dotShare, "word_join")
+		}
+	}
+}
+
+// GenerateSubdomainPermutations learns naming patterns from observed subdomains of
+// baseDomain and returns the best-ranked unseen candidates within the budget.
+func GenerateSubdomainPermutations(baseDomain string, observed []string, opts PermutationOptions) ([]PermutationCandidate, PermutationPatterns) {
+	opts = opts.normalized()
+	base, err := domainutil.Normalize(baseDomain)
+	if err != nil {
+		return nil, PermutationPatterns{}
+	}
+
+	e := newPermutationEngine(base, observed)
+	e.numericIncrements()
+	e.swapTokens(e.envs, permutationEnvTokens, 1.0, 0.4, "environment_swap")
+	e.swapTokens(e.regions, permutationRegionTokens, 0.8, 0.3, "region_swap")
+	e.insertEnvironments()
+	e.joinWords(opts.MaxWords)
+
+	candidates := make([]PermutationCandidate, 0, len(e.candidates))
+	for _, c := range e.candidates {
+		candidates = append(candidates, *c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Subdomain < candidates[j].Subdomain
+	})
+	if len(candidates) > opts.Budget {
+		candidates = candidates[:opts.Budget]
+	}
+	return candidates, e.patterns
+}
+
+func loadPermutationInput(scopeTargetID string) (string, []string, error) {
+	var baseDomain string
+	err := dbPool.QueryRow(context.Background(), `
+		SELECT TRIM(LEADING '*.' FROM scope_target)
+		FROM scope_targets
+		WHERE id = $1`, scopeTargetID).Scan(&baseDomain)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get base domain: %v", err)
+	}
+	// Resolved names are normalized before they are matched against the base
+	if baseDomain, err = domainutil.Normalize(baseDomain); err != nil {
+		return "", nil, fmt.Errorf("invalid base domain: %v", err)
+	}
+
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get consolidated subdomains: %v", err)
+	}
+	defer rows.Close()
+
+	var subdomains []string
+	for rows.Next() {
+		var subdomain string
+		if err := rows.Scan(&subdomain); err == nil {
+			subdomains = append(subdomains, subdomain)
+		}
+	}
+	return baseDomain, subdomains, rows.Err()
+}
+
+func RunSubdomainPermutationScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "shuffledns") {
+		return
+	}
+
+	var payload struct {
+		FQDN              string  `json:"fqdn" binding:"required"`
+		Budget            int     `json:"budget"`
+		MaxWords          int     `json:"max_words"`
+		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.FQDN == "" {
+		http.Error(w, "Invalid request body. `fqdn` is required.", http.StatusBadRequest)
+		return
+	}
+
+	domain := payload.FQDN
+	wildcardDomain := fmt.Sprintf("*.%s", domain)
+
+	var scopeTargetID string
+	err := dbPool.QueryRow(context.Background(),
+		`SELECT id FROM scope_targets WHERE type = 'Wildcard' AND scope_target = $1`, wildcardDomain).Scan(&scopeTargetID)
+	if err != nil {
+		log.Printf("[ERROR] No matching wildcard scope target found for domain %s", domain)
+		http.Error(w, "No matching wildcard scope target found.", http.StatusBadRequest)
+		return
+	}
+
+	opts := PermutationOptions{Budget: payload.Budget, MaxWords: payload.MaxWords}.normalized()
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err = dbPool.Exec(context.Background(), `
+		INSERT INTO subdomain_permutation_scans (scan_id, domain, status, budget, scope_target_id, auto_scan_session_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		scanID, domain, "pending", opts.Budget, scopeTargetID, autoScanSessionID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record.", http.StatusInternalServerError)
+		return
+	}
+
+	RecordScanToolVersion("subdomain_permutation_scans", scanID, "shuffledns")
+	go ExecuteSubdomainPermutationScan(scanID, scopeTargetID, opts)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func ExecuteSubdomainPermutationScan(scanID, scopeTargetID string, opts PermutationOptions) {
+	log.Printf("[PERMUTATION] [INFO] Starting permutation scan %s for scope target %s", scanID, scopeTargetID)
+	startTime := time.Now()
+
+	baseDomain, observed, err := loadPermutationInput(scopeTargetID)
+	if err != nil {
+		log.Printf("[PERMUTATION] [ERROR] %v", err)
+		UpdateSubdomainPermutationScanStatus(scanID, "error", "", err.Error(), "", time.Since(startTime).String())
+		return
+	}
+	if len(observed) == 0 {
+		UpdateSubdomainPermutationScanStatus(scanID, "error", "", "No consolidated subdomains to learn from; run consolidation first", "", time.Since(startTime).String())
+		return
+	}
+
+	candidates, patterns := GenerateSubdomainPermutations(baseDomain, observed, opts)
+	log.Printf("[PERMUTATION] [INFO] Learned from %d subdomains of %s, generated %d candidates", patterns.Observed, baseDomain, len(candidates))
+	patternsJSON, _ := json.Marshal(patterns)
+	if _, err := dbPool.Exec(context.Background(),
+		`UPDATE subdomain_permutation_scans SET candidates = $1, patterns = $2, status = 'running' WHERE scan_id = $3`,
+		len(candidates), patternsJSON, scanID); err != nil {
+		log.Printf("[PERMUTATION] [ERROR] Failed to record patterns for scan %s: %v", scanID, err)
+	}
+	if len(candidates) == 0 {
+		UpdateSubdomainPermutationScanStatus(scanID, "completed", "", "No candidates generated", "", time.Since(startTime).String())
+		return
+	}
+
+	tempDir, err := os.MkdirTemp("", "permutation-")
+	if err != nil {
+		UpdateSubdomainPermutationScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create temp directory: %v", err), "", time.Since(startTime).String())
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	byPrefix := make(map[string]PermutationCandidate, len(candidates))
+	prefixes := make([]string, len(candidates))
+	for i, c := range candidates {
+		prefixes[i] = c.Prefix
+		byPrefix[c.Prefix] = c
+	}
+	wordlistFile := filepath.Join(tempDir, "permutations.txt")
+	if err := os.WriteFile(wordlistFile, []byte(strings.Join(prefixes, "\n")), 0644); err != nil {
+		UpdateSubdomainPermutationScanStatus(scanID, "error", "", fmt.Sprintf("Failed to write wordlist: %v", err), "", time.Since(startTime).String())
+		return
+	}
+
+	execResult, err := runShuffleDNSWordlist(context.Background(), baseDomain, wordlistFile, GetShuffleDNSRateLimit())
+	execTime := time.Since(startTime).String()
+	if err != nil {
+		log.Printf("[PERMUTATION] [ERROR] ShuffleDNS failed for scan %s: %v", scanID, err)
+		UpdateSubdomainPermutationScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
+		return
+	}
+
+	var resolved []string
+	for _, line := range strings.Split(execResult.Stdout, "\n") {
+		subdomain, err := domainutil.Normalize(line)
+		if err != nil || !strings.HasSuffix(subdomain, "."+baseDomain) {
+			continue
+		}
+		c, ok := byPrefix[strings.TrimSuffix(subdomain, "."+baseDomain)]
+		if !ok {
+			continue
+		}
+		resolved = append(resolved, subdomain)
+		_, err = dbPool.Exec(context.Background(), `
+			INSERT INTO subdomain_permutation_results (scan_id, scope_target_id, subdomain, pattern, score)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (scan_id, subdomain) DO NOTHING`,
+			scanID, scopeTargetID, subdomain, c.Pattern, c.Score)
+		if err != nil {
+			log.Printf("[PERMUTATION] [ERROR] Failed to store result %s: %v", subdomain, err)
+		}
+	}
+	sort.Strings(resolved)
+
+	log.Printf("[PERMUTATION] [INFO] Scan %s resolved %d of %d candidates in %s", scanID, len(resolved), len(candidates), execTime)
+	if len(resolved) == 0 {
+		UpdateSubdomainPermutationScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
+		return
+	}
+	UpdateSubdomainPermutationScanStatus(scanID, "success", strings.Join(resolved, "\n"), execResult.Stderr, execResult.Command, execTime)
+}
+
+func UpdateSubdomainPermutationScanStatus(scanID, status, result, stderr, command, execTime string) {
+	query := `UPDATE subdomain_permutation_scans SET status = $1, result = $2, stderr = $3, command = $4, execution_time = $5 WHERE scan_id = $6`
+	if _, err := dbPool.Exec(context.Background(), query, status, result, stderr, command, execTime, scanID); err != nil {
+		log.Printf("[PERMUTATION] [ERROR] Failed to update scan status for %s: %v", scanID, err)
+	}
+}
+
+type SubdomainPermutationScan struct {
+	ID                string          `json:"id"`
+	ScanID            string          `json:"scan_id"`
+	Domain            string          `json:"domain"`
+	Status            string          `json:"status"`
+	Result            string          `json:"result"`
+	Error             string          `json:"error"`
+	StdErr            string          `json:"stderr"`
+	Command           string          `json:"command"`
+	ExecTime          string          `json:"execution_time"`
+	Budget            int             `json:"budget"`
+	Candidates        int             `json:"candidates"`
+	Patterns          json.RawMessage `json:"patterns,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	ScopeTargetID     string          `json:"scope_target_id"`
+	AutoScanSessionID string          `json:"auto_scan_session_id"`
+}
+
+const subdomainPermutationScanColumns = `id, scan_id, domain, status, result, error, stderr, command, execution_time,
+	budget, candidates, patterns, created_at, scope_target_id, auto_scan_session_id`
+
+func scanSubdomainPermutationRow(row pgx.Row) (SubdomainPermutationScan, error) {
+	var scan SubdomainPermutationScan
+	var result, errMsg, stderr, command, execTime, autoScanSessionID sql.NullString
+	var patterns []byte
+	err := row.Scan(&scan.ID, &scan.ScanID, &scan.Domain, &scan.Status, &result, &errMsg, &stderr, &command, &execTime,
+		&scan.Budget, &scan.Candidates, &patterns, &scan.CreatedAt, &scan.ScopeTargetID, &autoScanSessionID)
+	if err != nil {
+		return scan, err
+	}
+	scan.Result = nullStringToString(result)
+	scan.Error = nullStringToString(errMsg)
+	scan.StdErr = nullStringToString(stderr)
+	scan.Command = nullStringToString(command)
+	scan.ExecTime = nullStringToString(execTime)
+	scan.AutoScanSessionID = nullStringToString(autoScanSessionID)
+	if len(patterns) > 0 {
+		scan.Patterns = patterns
+	}
+	return scan, nil
+}
+
+func GetSubdomainPermutationScanStatus(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	scan, err := scanSubdomainPermutationRow(dbPool.QueryRow(context.Background(),
+		`SELECT `+subdomainPermutationScanColumns+` FROM subdomain_permutation_scans WHERE scan_id = $1`, scanID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			http.Error(w, "Scan not found", http.StatusNotFound)
+		} else {
+			log.Printf("[PERMUTATION] [ERROR] Failed to get scan %s: %v", scanID, err)
+			http.Error(w, "Failed to get scan status", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scan)
+}
+
+func GetSubdomainPermutationScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	rows, err := dbPool.Query(context.Background(),
+		`SELECT `+subdomainPermutationScanColumns+` FROM subdomain_permutation_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`,
+		scopeTargetID)
+	if err != nil {
+		log.Printf("[PERMUTATION] [ERROR] Failed to get scans: %v", err)
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []SubdomainPermutationScan{}
+	for rows.Next() {
+		scan, err := scanSubdomainPermutationRow(rows)
+		if err != nil {
+			log.Printf("[PERMUTATION] [ERROR] Failed to scan row: %v", err)
+			continue
+		}
+		scans = append(scans, scan)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+func GetSubdomainPermutationResults(w http.ResponseWriter, r *http.Request) {
+	scanID := mux.Vars(r)["scan_id"]
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT subdomain, pattern, score
+		FROM subdomain_permutation_results
+		WHERE scan_id = $1
+		ORDER BY score DESC, subdomain ASC`, scanID)
+	if err != nil {
+		log.Printf("[PERMUTATION] [ERROR] Failed to get results for scan %s: %v", scanID, err)
+		http.Error(w, "Failed to get results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []PermutationCandidate{}
+	for rows.Next() {
+		var c PermutationCandidate
+		if err := rows.Scan(&c.Subdomain, &c.Pattern, &c.Score); err != nil {
+			continue
+		}
+		results = append(results, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// PreviewSubdomainPermutations returns the ranked candidates without resolving them.
+func PreviewSubdomainPermutations(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	var opts PermutationOptions
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&opts)
+	}
+
+	baseDomain, observed, err := loadPermutationInput(scopeTargetID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	candidates, patterns := GenerateSubdomainPermutations(baseDomain, observed, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"domain":     baseDomain,
+		"patterns":   patterns,
+		"count":      len(candidates),
+		"candidates": candidates,
+	})
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
@@ -102,6 +101,10 @@ type SubfinderScanStatus struct {
 }
 
 func RunSublist3rScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "sublist3r") {
+		return
+	}
+
 	log.Printf("[INFO] Received request to run Sublist3r scan")
 	var requestData struct {
 		FQDN              string  `json:"fqdn"`
@@ -148,6 +151,7 @@ func RunSublist3rScan(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("[INFO] Successfully created Sublist3r scan record in database")
 
+	RecordScanToolVersion("sublist3r_scans", scanID, "sublist3r")
 	go ExecuteAndParseSublist3rScan(scanID, domain)
 
 	log.Printf("[INFO] Initiated Sublist3r scan with ID: %s for domain: %s", scanID, domain)
@@ -160,43 +164,27 @@ func ExecuteAndParseSublist3rScan(scanID, domain string) {
 	log.Printf("[DEBUG] Initializing scan variables and preparing command")
 	startTime := time.Now()
 
-	log.Printf("[DEBUG] Constructing docker command for Sublist3r")
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-sublist3r-1",
-		"python", "/app/sublist3r.py",
+	executor := ToolExecutor("sublist3r")
+	log.Printf("[INFO] Executing Sublist3r command via %s executor at %s", executor.Mode(), time.Now().Format(time.RFC3339))
+
+	cmd, err := executor.Run(context.Background(), ExecRequest{Args: []string{
 		"-d", domain,
 		"-v",
 		"-t", "50",
 		"-o", "/dev/stdout",
-	)
-
-	log.Printf("[DEBUG] Docker command constructed: %s", cmd.String())
-	log.Printf("[DEBUG] Setting up stdout and stderr buffers")
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	log.Printf("[INFO] Executing Sublist3r command at %s", time.Now().Format(time.RFC3339))
-	log.Printf("[DEBUG] Command working directory: %s", cmd.Dir)
-	log.Printf("[DEBUG] Command environment variables: %v", cmd.Env)
-
-	err := cmd.Run()
+	}})
 	execTime := time.Since(startTime).String()
-	log.Printf("[INFO] Command execution completed in %s", execTime)
+	log.Printf("[INFO] Command execution completed in %s: %s", execTime, cmd.Command)
 
 	if err != nil {
 		log.Printf("[ERROR] Sublist3r scan failed with error: %v", err)
 		log.Printf("[ERROR] Error type: %T", err)
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			log.Printf("[ERROR] Exit code: %d", exitErr.ExitCode())
-		}
-		log.Printf("[ERROR] Stderr output length: %d bytes", stderr.Len())
-		log.Printf("[ERROR] Stderr output content: %s", stderr.String())
-		log.Printf("[ERROR] Stdout output length: %d bytes", stdout.Len())
+		log.Printf("[ERROR] Exit code: %d", cmd.ExitCode)
+		log.Printf("[ERROR] Stderr output length: %d bytes", len(cmd.Stderr))
+		log.Printf("[ERROR] Stderr output content: %s", cmd.Stderr)
+		log.Printf("[ERROR] Stdout output length: %d bytes", len(cmd.Stdout))
 		log.Printf("[DEBUG] Updating scan status to error state")
-		UpdateSublist3rScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		UpdateSublist3rScanStatus(scanID, "error", "", cmd.Stderr, cmd.Command, execTime)
 		return
 	}
 
@@ -204,7 +192,7 @@ func ExecuteAndParseSublist3rScan(scanID, domain string) {
 	log.Printf("[DEBUG] Processing scan output")
 
 	// Process the output
-	lines := strings.Split(stdout.String(), "\n")
+	lines := strings.Split(cmd.Stdout, "\n")
 	log.Printf("[INFO] Processing %d lines of output", len(lines))
 
 	// Use a map to handle deduplication
@@ -249,7 +237,7 @@ func ExecuteAndParseSublist3rScan(scanID, domain string) {
 	log.Printf("[DEBUG] Final result string length: %d bytes", len(result))
 
 	log.Printf("[INFO] Updating scan status in database for scan ID: %s", scanID)
-	UpdateSublist3rScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+	UpdateSublist3rScanStatus(scanID, "success", result, cmd.Stderr, cmd.Command, execTime)
 
 	log.Printf("[INFO] Sublist3r scan completed successfully for domain %s (scan ID: %s)", domain, scanID)
 	log.Printf("[INFO] Total execution time including processing: %s", time.Since(startTime))
@@ -271,7 +259,7 @@ func GetSublist3rScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan Sublist3rScanStatus
-	query := `SELECT * FROM sublist3r_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM sublist3r_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -328,7 +316,7 @@ func GetSublist3rScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM sublist3r_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM sublist3r_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -383,6 +371,10 @@ func GetSublist3rScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunAssetfinderScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "assetfinder") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -421,6 +413,7 @@ func RunAssetfinderScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("assetfinder_scans", scanID, "assetfinder")
 	go ExecuteAndParseAssetfinderScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -431,40 +424,31 @@ func ExecuteAndParseAssetfinderScan(scanID, domain string) {
 	log.Printf("[INFO] Starting Assetfinder scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-assetfinder-1",
-		"assetfinder",
+	execResult, err := ToolExecutor("assetfinder").Run(context.Background(), ExecRequest{Args: []string{
 		"--subs-only",
 		domain,
-	)
-
-	log.Printf("[INFO] Executing command: %s", cmd.String())
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	}})
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] Assetfinder scan failed for %s: %v", domain, err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateAssetfinderScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateAssetfinderScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] Assetfinder scan completed in %s for domain %s", execTime, domain)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
 	if result == "" {
 		log.Printf("[WARN] No output from Assetfinder scan")
-		UpdateAssetfinderScanStatus(scanID, "completed", "", "No results found", cmd.String(), execTime)
+		UpdateAssetfinderScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
 	} else {
 		log.Printf("[DEBUG] Assetfinder output: %s", result)
-		UpdateAssetfinderScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+		UpdateAssetfinderScanStatus(scanID, "success", result, execResult.Stderr, execResult.Command, execTime)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -486,7 +470,7 @@ func GetAssetfinderScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan AssetfinderScanStatus
-	query := `SELECT * FROM assetfinder_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM assetfinder_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -543,7 +527,7 @@ func GetAssetfinderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM assetfinder_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM assetfinder_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -598,6 +582,10 @@ func GetAssetfinderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunGauScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "gau") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -637,6 +625,7 @@ func RunGauScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("gau_scans", scanID, "gau")
 	go ExecuteAndParseGauScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -654,9 +643,7 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 	log.Printf("[DEBUG] Note: GAU does not support custom headers or user agent")
 
 	// Build base command
-	dockerCmd := []string{
-		"docker", "run", "--rm",
-		"sxcurity/gau:latest",
+	gauArgs := []string{
 		domain,
 		"--providers", "wayback",
 		"--json",
@@ -668,21 +655,18 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 	}
 
 	// Note: GAU does not support custom headers or user agent
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
-
+	executor := ToolExecutor("gau")
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd, err := executor.Run(context.Background(), ExecRequest{Args: gauArgs, Stdout: &stdout, Stderr: &stderr})
+	command := cmd.Command
+	log.Printf("[INFO] Executed command: %s", command)
 
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] GAU scan failed for %s: %v", domain, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateGauScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateGauScanStatus(scanID, "error", "", stderr.String(), command, execTime)
 		return
 	}
 
@@ -696,9 +680,7 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 	// Check if we have actual results
 	if result == "" {
 		// Try a second attempt with different flags
-		dockerCmd = []string{
-			"docker", "run", "--rm",
-			"sxcurity/gau:latest",
+		gauArgs = []string{
 			domain,
 			"--providers", "wayback,otx,urlscan",
 			"--subs",
@@ -707,14 +689,12 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 			"--retries", "3",
 		}
 
-		log.Printf("[INFO] No results from first attempt, trying second attempt with command: %s", strings.Join(dockerCmd, " "))
+		log.Printf("[INFO] No results from first attempt, trying second attempt with args: %s", strings.Join(gauArgs, " "))
 
 		stdout.Reset()
 		stderr.Reset()
-		cmd = exec.Command(dockerCmd[0], dockerCmd[1:]...)
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		err = cmd.Run()
+		cmd, err = executor.Run(context.Background(), ExecRequest{Args: gauArgs, Stdout: &stdout, Stderr: &stderr})
+		command = cmd.Command
 
 		if err == nil {
 			result = stdout.String()
@@ -732,7 +712,7 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 			log.Printf("[INFO] Results exceed 1000 URLs, setting status to 'processing' while reducing to unique subdomains")
 
 			// Update status to "processing" to let the frontend know we're still working
-			UpdateGauScanStatus(scanID, "processing", "", "Processing large result set...", strings.Join(dockerCmd, " "), execTime)
+			UpdateGauScanStatus(scanID, "processing", "", "Processing large result set...", command, execTime)
 
 			// Map to store unique subdomains and their representative URL
 			uniqueSubdomains := make(map[string]string)
@@ -797,14 +777,14 @@ func ExecuteAndParseGauScan(scanID, domain string) {
 			log.Printf("[INFO] Reduced %d URLs to %d unique subdomain URLs", lineCount, len(uniqueResults))
 
 			// Now update with the final result and set status to success
-			UpdateGauScanStatus(scanID, "success", result, stderr.String(), strings.Join(dockerCmd, " "), execTime)
+			UpdateGauScanStatus(scanID, "success", result, stderr.String(), command, execTime)
 		} else {
 			// If results don't exceed 1000, just update with success directly
-			UpdateGauScanStatus(scanID, "success", result, stderr.String(), strings.Join(dockerCmd, " "), execTime)
+			UpdateGauScanStatus(scanID, "success", result, stderr.String(), command, execTime)
 		}
 	} else {
 		// Empty result, update with success status
-		UpdateGauScanStatus(scanID, "success", result, stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateGauScanStatus(scanID, "success", result, stderr.String(), command, execTime)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -826,7 +806,7 @@ func GetGauScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scanID"]
 
 	var scan GauScanStatus
-	query := `SELECT * FROM gau_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM gau_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -883,7 +863,7 @@ func GetGauScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM gau_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM gau_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -996,7 +976,7 @@ func ExecuteAndParseCTLScan(scanID, domain string) {
 
 	// Make HTTP request to crt.sh
 	url := fmt.Sprintf("https://crt.sh/?q=%%.%s&output=json", domain)
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := egressClient(EgressScopeCTL, &http.Client{Timeout: 30 * time.Second})
 
 	resp, err := client.Get(url)
 	if err != nil {
@@ -1189,6 +1169,10 @@ func GetCTLScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunSubfinderScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "subfinder") {
+		return
+	}
+
 	var payload struct {
 		FQDN              string  `json:"fqdn" binding:"required"`
 		AutoScanSessionID *string `json:"auto_scan_session_id,omitempty"`
@@ -1227,6 +1211,7 @@ func RunSubfinderScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	RecordScanToolVersion("subfinder_scans", scanID, "subfinder")
 	go ExecuteAndParseSubfinderScan(scanID, domain)
 
 	w.WriteHeader(http.StatusAccepted)
@@ -1237,40 +1222,31 @@ func ExecuteAndParseSubfinderScan(scanID, domain string) {
 	log.Printf("[INFO] Starting Subfinder scan for domain %s (scan ID: %s)", domain, scanID)
 	startTime := time.Now()
 
-	cmd := exec.Command(
-		"docker", "exec",
-		"ars0n-framework-v2-subfinder-1",
-		"subfinder",
+	execResult, err := ToolExecutor("subfinder").Run(context.Background(), ExecRequest{Args: []string{
 		"-d", domain,
 		"-silent",
-	)
-
-	log.Printf("[INFO] Executing command: %s", cmd.String())
+	}})
+	log.Printf("[INFO] Executed command: %s", execResult.Command)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] Subfinder scan failed for %s: %v", domain, err)
-		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateSubfinderScanStatus(scanID, "error", "", stderr.String(), cmd.String(), execTime)
+		log.Printf("[ERROR] stderr output: %s", execResult.Stderr)
+		UpdateSubfinderScanStatus(scanID, "error", "", execResult.Stderr, execResult.Command, execTime)
 		return
 	}
 
-	result := stdout.String()
+	result := execResult.Stdout
 	log.Printf("[INFO] Subfinder scan completed in %s for domain %s", execTime, domain)
 	log.Printf("[DEBUG] Raw output length: %d bytes", len(result))
 
 	if result == "" {
 		log.Printf("[WARN] No output from Subfinder scan")
-		UpdateSubfinderScanStatus(scanID, "completed", "", "No results found", cmd.String(), execTime)
+		UpdateSubfinderScanStatus(scanID, "completed", "", "No results found", execResult.Command, execTime)
 	} else {
 		log.Printf("[DEBUG] Subfinder output: %s", result)
-		UpdateSubfinderScanStatus(scanID, "success", result, stderr.String(), cmd.String(), execTime)
+		UpdateSubfinderScanStatus(scanID, "success", result, execResult.Stderr, execResult.Command, execTime)
 	}
 
 	log.Printf("[INFO] Scan status updated for scan %s", scanID)
@@ -1292,7 +1268,7 @@ func GetSubfinderScanStatus(w http.ResponseWriter, r *http.Request) {
 	scanID := vars["scan_id"]
 
 	var scan SubfinderScanStatus
-	query := `SELECT * FROM subfinder_scans WHERE scan_id = $1`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM subfinder_scans WHERE scan_id = $1`
 	err := dbPool.QueryRow(context.Background(), query, scanID).Scan(
 		&scan.ID,
 		&scan.ScanID,
@@ -1349,7 +1325,7 @@ func GetSubfinderScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT * FROM subfinder_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, scan_id, domain, status, result, error, stdout, stderr, command, execution_time, created_at, scope_target_id, auto_scan_session_id FROM subfinder_scans WHERE scope_target_id = $1 ORDER BY created_at DESC`
 	rows, err := dbPool.Query(context.Background(), query, scopeTargetID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get scans: %v", err)
@@ -0,0 +1,465 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ToolDefinition describes the default way a scanning tool is reached and how its version is read.
+// Binary is the command inside the tool's container; LocalBinary is used in local mode.
+// ProxyFlag is the tool's own proxy option, appended when an egress policy applies;
+// HeaderFlag is the repeated "Name: value" option used for request profiles;
+// tools that take all headers in one value set HeaderJoin to their separator.
+type ToolDefinition struct {
+	Name        string   `json:"name"`
+	Mode        string   `json:"mode"`
+	Service     string   `json:"service"`
+	Container   string   `json:"container,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Binary      []string `json:"binary,omitempty"`
+	LocalBinary []string `json:"local_binary,omitempty"`
+	ProxyFlag   string   `json:"proxy_flag,omitempty"`
+	HeaderFlag  string   `json:"header_flag,omitempty"`
+	HeaderJoin  string   `json:"header_join,omitempty"`
+	VersionArgs []string `json:"version_args"`
+	ScanTables  []string `json:"scan_tables"`
+}
+
+type ToolStatus struct {
+	Tool      string    `json:"tool"`
+	Mode      string    `json:"mode"`
+	Target    string    `json:"target"`
+	Available bool      `json:"available"`
+	Version   string    `json:"version"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+const (
+	ToolModeDockerExec = "docker_exec"
+	ToolModeDockerRun  = "docker_run"
+
+	toolStatusTTL    = 5 * time.Minute
+	toolProbeTimeout = 30 * time.Second
+)
+
+var toolRegistry = map[string]ToolDefinition{
+	"amass": {
+		Name: "amass", Mode: ToolModeDockerRun, Service: "amass", Image: "caffix/amass",
+		LocalBinary: []string{"amass"},
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"amass_scans", "amass_intel_scans", "amass_enum_company_scans"},
+	},
+	"gau": {
+		Name: "gau", Mode: ToolModeDockerRun, Service: "gau", Image: "sxcurity/gau:latest",
+		LocalBinary: []string{"gau"},
+		ProxyFlag:   "--proxy",
+		VersionArgs: []string{"--version"},
+		ScanTables:  []string{"gau_scans", "gau_url_scans"},
+	},
+	"httpx": {
+		Name: "httpx", Mode: ToolModeDockerExec, Service: "httpx", Container: "ars0n-framework-v2-httpx-1",
+		Binary:      []string{"httpx"},
+		ProxyFlag:   "-http-proxy",
+		HeaderFlag:  "-H",
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"httpx_scans"},
+	},
+	"subfinder": {
+		Name: "subfinder", Mode: ToolModeDockerExec, Service: "subfinder", Container: "ars0n-framework-v2-subfinder-1",
+		Binary:      []string{"subfinder"},
+		ProxyFlag:   "-proxy",
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"subfinder_scans"},
+	},
+	"sublist3r": {
+		Name: "sublist3r", Mode: ToolModeDockerExec, Service: "sublist3r", Container: "ars0n-framework-v2-sublist3r-1",
+		Binary: []string{"python", "/app/sublist3r.py"}, LocalBinary: []string{"sublist3r"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"sublist3r_scans"},
+	},
+	"assetfinder": {
+		Name: "assetfinder", Mode: ToolModeDockerExec, Service: "assetfinder", Container: "ars0n-framework-v2-assetfinder-1",
+		Binary:      []string{"assetfinder"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"assetfinder_scans"},
+	},
+	"shuffledns": {
+		Name: "shuffledns", Mode: ToolModeDockerExec, Service: "shuffledns", Container: "ars0n-framework-v2-shuffledns-1",
+		Binary:      []string{"shuffledns"},
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"shuffledns_scans", "shufflednscustom_scans", "subdomain_permutation_scans"},
+	},
+	"cewl": {
+		Name: "cewl", Mode: ToolModeDockerExec, Service: "cewl", Container: "ars0n-framework-v2-cewl-1",
+		Binary: []string{"ruby", "/app/cewl.rb"}, LocalBinary: []string{"cewl"},
+		VersionArgs: []string{"--version"},
+		ScanTables:  []string{"cewl_scans"},
+	},
+	"gospider": {
+		Name: "gospider", Mode: ToolModeDockerExec, Service: "gospider", Container: "ars0n-framework-v2-gospider-1",
+		Binary:      []string{"gospider"},
+		ProxyFlag:   "--proxy",
+		HeaderFlag:  "--header",
+		VersionArgs: []string{"--version"},
+		ScanTables:  []string{"gospider_scans", "gospider_url_scans"},
+	},
+	"subdomainizer": {
+		Name: "subdomainizer", Mode: ToolModeDockerExec, Service: "subdomainizer", Container: "ars0n-framework-v2-subdomainizer-1",
+		Binary: []string{"python3", "SubDomainizer.py"}, LocalBinary: []string{"subdomainizer"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"subdomainizer_scans"},
+	},
+	"nuclei": {
+		Name: "nuclei", Mode: ToolModeDockerExec, Service: "nuclei", Container: "ars0n-framework-v2-nuclei-1",
+		Binary:      []string{"nuclei"},
+		ProxyFlag:   "-proxy",
+		HeaderFlag:  "-H",
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"nuclei_scans", "nuclei_screenshots", "metadata_scans"},
+	},
+	"katana": {
+		Name: "katana", Mode: ToolModeDockerExec, Service: "katana", Container: "ars0n-framework-v2-katana-1",
+		Binary:      []string{"katana"},
+		ProxyFlag:   "-proxy",
+		HeaderFlag:  "-H",
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"katana_company_scans", "katana_url_scans"},
+	},
+	"dnsx": {
+		Name: "dnsx", Mode: ToolModeDockerExec, Service: "dnsx", Container: "ars0n-framework-v2-dnsx-1",
+		Binary:      []string{"dnsx"},
+		VersionArgs: []string{"-version"},
+		ScanTables:  []string{"dnsx_company_scans"},
+	},
+	"ffuf": {
+		Name: "ffuf", Mode: ToolModeDockerExec, Service: "ffuf", Container: "ars0n-framework-v2-ffuf-1",
+		Binary:      []string{"ffuf"},
+		ProxyFlag:   "-x",
+		HeaderFlag:  "-H",
+		VersionArgs: []string{"-V"},
+		ScanTables:  []string{"ffuf_url_scans"},
+	},
+	"github-recon": {
+		Name: "github-recon", Mode: ToolModeDockerExec, Service: "github-recon", Container: "ars0n-framework-v2-github-recon-1",
+		Binary: []string{"python3", "-u", "/app/github-search/github-endpoints.py"}, LocalBinary: []string{"github-endpoints"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"github_recon_scans"},
+	},
+	"cloud_enum": {
+		Name: "cloud_enum", Mode: ToolModeDockerExec, Service: "cloud_enum", Container: "ars0n-framework-v2-cloud_enum-1",
+		Binary: []string{"python", "cloud_enum.py"}, LocalBinary: []string{"cloud_enum"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"cloud_enum_scans"},
+	},
+	"metabigor": {
+		Name: "metabigor", Mode: ToolModeDockerExec, Service: "metabigor", Container: "ars0n-framework-v2-metabigor-1",
+		Binary:      []string{"metabigor"},
+		VersionArgs: []string{"version"},
+		ScanTables:  []string{"metabigor_company_scans"},
+	},
+	"linkfinder": {
+		Name: "linkfinder", Mode: ToolModeDockerExec, Service: "linkfinder", Container: "ars0n-framework-v2-linkfinder-1",
+		Binary: []string{"python3", "linkfinder.py"}, LocalBinary: []string{"linkfinder"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"linkfinder_url_scans"},
+	},
+	"waybackurls": {
+		Name: "waybackurls", Mode: ToolModeDockerExec, Service: "waybackurls", Container: "ars0n-framework-v2-waybackurls-1",
+		Binary:      []string{"waybackurls"},
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"waybackurls_scans"},
+	},
+	"arjun": {
+		Name: "arjun", Mode: ToolModeDockerExec, Service: "arjun", Container: "ars0n-framework-v2-arjun-1",
+		Binary:      []string{"arjun"},
+		HeaderFlag:  "--headers",
+		HeaderJoin:  "\n",
+		VersionArgs: []string{"--help"},
+		ScanTables:  []string{"arjun_scans"},
+	},
+	"parameth": {
+		Name: "parameth", Mode: ToolModeDockerExec, Service: "parameth", Container: "ars0n-framework-v2-parameth-1",
+		Binary: []string{"python3", "parameth.py"}, LocalBinary: []string{"parameth"},
+		HeaderFlag:  "-H",
+		VersionArgs: []string{"-h"},
+		ScanTables:  []string{"parameth_scans"},
+	},
+	"x8": {
+		Name: "x8", Mode: ToolModeDockerExec, Service: "x8", Container: "ars0n-framework-v2-x8-1",
+		Binary:      []string{"x8"},
+		ProxyFlag:   "--proxy",
+		HeaderFlag:  "--headers",
+		VersionArgs: []string{"--version"},
+		ScanTables:  []string{"x8_scans"},
+	},
+}
+
+var (
+	toolStatusMutex sync.RWMutex
+	toolStatusCache = make(map[string]ToolStatus)
+	versionPattern  = regexp.MustCompile(`v?\d+\.\d+(\.\d+)?([-.][0-9A-Za-z]+)*`)
+)
+
+func GetToolDefinition(tool string) (ToolDefinition, bool) {
+	def, ok := toolRegistry[tool]
+	return def, ok
+}
+
+func sortedToolNames() []string {
+	names := make([]string, 0, len(toolRegistry))
+	for name := range toolRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkToolReachable(def ToolDefinition, ex Executor) error {
+	ctx, cancel := context.WithTimeout(context.Background(), toolProbeTimeout)
+	defer cancel()
+
+	if err := ex.Check(ctx); err != nil {
+		switch ex.Mode() {
+		case ToolModeDockerExec:
+			return fmt.Errorf("%v; start it with `docker compose up -d %s`", err, def.Service)
+		case ToolModeLocal:
+			return fmt.Errorf("%v; install %s or switch its executor mode via PUT /tools/executors/%s", err, def.Name, def.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+func probeToolVersion(ex Executor, def ToolDefinition) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolProbeTimeout)
+	defer cancel()
+
+	result, err := ex.Run(ctx, ExecRequest{Args: def.VersionArgs})
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("`%s` timed out after %s", result.Command, toolProbeTimeout)
+	}
+	output := result.Stdout + result.Stderr
+	version := parseToolVersion(output)
+	if err != nil && version == "" && strings.TrimSpace(output) == "" {
+		return "", fmt.Errorf("`%s` failed: %v", result.Command, err)
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	return version, nil
+}
+
+func parseToolVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "version") && !strings.HasPrefix(strings.TrimSpace(lower), "v") {
+			continue
+		}
+		if match := versionPattern.FindString(line); match != "" {
+			return match
+		}
+	}
+	if match := versionPattern.FindString(output); match != "" {
+		return match
+	}
+	return ""
+}
+
+// CheckTool probes a single tool and refreshes both the cache and the tool_inventory table.
+func CheckTool(tool string, withVersion bool) ToolStatus {
+	def, ok := toolRegistry[tool]
+	if !ok {
+		return ToolStatus{Tool: tool, Error: fmt.Sprintf("unknown tool %q", tool), CheckedAt: time.Now()}
+	}
+
+	ex := ToolExecutor(tool)
+	status := ToolStatus{Tool: def.Name, Mode: ex.Mode(), Target: ex.Target(), CheckedAt: time.Now()}
+
+	toolStatusMutex.RLock()
+	previous, hadPrevious := toolStatusCache[tool]
+	toolStatusMutex.RUnlock()
+
+	if err := checkToolReachable(def, ex); err != nil {
+		status.Error = err.Error()
+	} else if withVersion || !hadPrevious || previous.Version == "" {
+		version, err := probeToolVersion(ex, def)
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Available = true
+			status.Version = version
+		}
+	} else {
+		status.Available = true
+		status.Version = previous.Version
+	}
+
+	toolStatusMutex.Lock()
+	toolStatusCache[tool] = status
+	toolStatusMutex.Unlock()
+
+	saveToolStatus(status)
+	return status
+}
+
+func saveToolStatus(status ToolStatus) {
+	if dbPool == nil {
+		return
+	}
+	query := `
+		INSERT INTO tool_inventory (tool_name, mode, target, available, version, error, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tool_name)
+		DO UPDATE SET mode = $2, target = $3, available = $4, version = $5, error = $6, checked_at = $7
+	`
+	_, err := dbPool.Exec(context.Background(), query, status.Tool, status.Mode, status.Target,
+		status.Available, status.Version, status.Error, status.CheckedAt)
+	if err != nil {
+		log.Printf("[PREFLIGHT] [ERROR] Failed to store status for %s: %v", status.Tool, err)
+	}
+}
+
+// RunToolPreflight checks every registered tool in parallel and records its version.
+func RunToolPreflight() []ToolStatus {
+	log.Printf("[PREFLIGHT] [INFO] Checking %d tools", len(toolRegistry))
+
+	var wg sync.WaitGroup
+	results := make(chan ToolStatus, len(toolRegistry))
+	for name := range toolRegistry {
+		wg.Add(1)
+		go func(tool string) {
+			defer wg.Done()
+			results <- CheckTool(tool, true)
+		}(name)
+	}
+	wg.Wait()
+	close(results)
+
+	var statuses []ToolStatus
+	for status := range results {
+		if status.Available {
+			log.Printf("[PREFLIGHT] [INFO] %s ready (%s, version %s)", status.Tool, status.Target, status.Version)
+		} else {
+			log.Printf("[PREFLIGHT] [WARN] %s unavailable: %s", status.Tool, status.Error)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Tool < statuses[j].Tool })
+	return statuses
+}
+
+// EnsureToolAvailable returns an actionable error when a tool cannot be used.
+// Cached results are reused for toolStatusTTL before the tool is probed again.
+func EnsureToolAvailable(tool string) error {
+	toolStatusMutex.RLock()
+	status, ok := toolStatusCache[tool]
+	toolStatusMutex.RUnlock()
+
+	if !ok || time.Since(status.CheckedAt) > toolStatusTTL || !status.Available {
+		status = CheckTool(tool, false)
+	}
+	if !status.Available {
+		return fmt.Errorf("%s is unavailable: %s", tool, status.Error)
+	}
+	return nil
+}
+
+// RequireTools writes a 503 response and returns false when any tool is unavailable.
+func RequireTools(w http.ResponseWriter, tools ...string) bool {
+	for _, tool := range tools {
+		if err := EnsureToolAvailable(tool); err != nil {
+			log.Printf("[PREFLIGHT] [ERROR] Blocking scan: %v", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return false
+		}
+	}
+	return true
+}
+
+func GetToolVersion(tool string) string {
+	toolStatusMutex.RLock()
+	defer toolStatusMutex.RUnlock()
+	return toolStatusCache[tool].Version
+}
+
+// RecordScanToolVersion stores the version of the tool that produced a scan row.
+func RecordScanToolVersion(table, scanID, tool string) {
+	def, ok := toolRegistry[tool]
+	if !ok {
+		return
+	}
+	allowed := false
+	for _, t := range def.ScanTables {
+		if t == table {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		log.Printf("[PREFLIGHT] [WARN] Table %s is not registered for tool %s", table, tool)
+		return
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET tool_version = $1 WHERE scan_id = $2`, table)
+	if _, err := dbPool.Exec(context.Background(), query, GetToolVersion(tool), scanID); err != nil {
+		log.Printf("[PREFLIGHT] [ERROR] Failed to record %s version for scan %s: %v", tool, scanID, err)
+	}
+}
+
+func GetToolStatuses(w http.ResponseWriter, r *http.Request) {
+	toolStatusMutex.RLock()
+	statuses := make([]ToolStatus, 0, len(toolRegistry))
+	for name, def := range toolRegistry {
+		status, ok := toolStatusCache[name]
+		if !ok {
+			status = ToolStatus{Tool: name, Mode: def.Mode, Error: "not checked yet"}
+		}
+		statuses = append(statuses, status)
+	}
+	toolStatusMutex.RUnlock()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Tool < statuses[j].Tool })
+
+	ready := 0
+	for _, status := range statuses {
+		if status.Available {
+			ready++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tools": statuses,
+		"ready": ready,
+		"total": len(statuses),
+	})
+}
+
+func GetToolStatus(w http.ResponseWriter, r *http.Request) {
+	tool := mux.Vars(r)["tool"]
+	if _, ok := toolRegistry[tool]; !ok {
+		http.Error(w, "Unknown tool", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CheckTool(tool, r.URL.Query().Get("version") == "true"))
+}
+
+func RunToolPreflightHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := RunToolPreflight()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tools": statuses,
+	})
+}
@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -138,13 +137,13 @@ func normalizePathParameters(urlStr string) string {
 	return parsedURL.String()
 }
 
-func fetchStatusCode(urlStr string) int {
-	client := &http.Client{
+func fetchStatusCode(urlStr string, requestHeaders []string) int {
+	client := egressClient(EgressScopeHTTPProbe, &http.Client{
 		Timeout: 10 * time.Second,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
-	}
+	})
 	
 	req, err := http.NewRequest("HEAD", urlStr, nil)
 	if err != nil {
@@ -155,6 +154,7 @@ func fetchStatusCode(urlStr string) int {
 	}
 	
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	applyRequestHeaders(req, requestHeaders)
 	
 	resp, err := client.Do(req)
 	if err != nil {
@@ -175,7 +175,7 @@ func enrichURLsWithStatusCodes(urls []string) map[string]int {
 			log.Printf("[INFO] Progress: %d/%d URLs checked", i, len(urls))
 		}
 		
-		statusCode := fetchStatusCode(urlStr)
+		statusCode := fetchStatusCode(urlStr, nil)
 		statusCodes[urlStr] = statusCode
 		
 		time.Sleep(100 * time.Millisecond)
@@ -595,7 +595,7 @@ func extractPathParameters(urlStr string, normalizedPath string) []EndpointParam
 	return params
 }
 
-func processURLsWithParameters(urls []string, targetDomain, scanID, scanType, scopeTargetID string) ([]DiscoveredEndpoint, error) {
+func processURLsWithParameters(urls []string, targetDomain, scanID, scanType, scopeTargetID, requestProfileID string) ([]DiscoveredEndpoint, error) {
 	log.Printf("[INFO] Processing %d URLs with parameter detection", len(urls))
 	
 	directURLs := make([]string, 0)
@@ -614,13 +614,13 @@ func processURLsWithParameters(urls []string, targetDomain, scanID, scanType, sc
 	
 	allEndpoints := make([]DiscoveredEndpoint, 0)
 	
-	allEndpoints = append(allEndpoints, processURLGroup(directURLs, true, scanID, scanType, scopeTargetID)...)
-	allEndpoints = append(allEndpoints, processURLGroup(adjacentURLs, false, scanID, scanType, scopeTargetID)...)
+	allEndpoints = append(allEndpoints, processURLGroup(directURLs, true, scanID, scanType, scopeTargetID, requestProfileID)...)
+	allEndpoints = append(allEndpoints, processURLGroup(adjacentURLs, false, scanID, scanType, scopeTargetID, requestProfileID)...)
 	
 	return allEndpoints, nil
 }
 
-func processURLGroup(urls []string, isDirect bool, scanID, scanType, scopeTargetID string) []DiscoveredEndpoint {
+func processURLGroup(urls []string, isDirect bool, scanID, scanType, scopeTargetID, requestProfileID string) []DiscoveredEndpoint {
 	if len(urls) == 0 {
 		return nil
 	}
@@ -668,8 +668,9 @@ func processURLGroup(urls []string, isDirect bool, scanID, scanType, scopeTarget
 	
 	log.Printf("[INFO] Fetching status codes for %d endpoints (isDirect=%v)", len(endpoints), isDirect)
 	
+	requestHeaders := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
 	for i := range endpoints {
-		endpoints[i].StatusCode = fetchStatusCode(endpoints[i].URL)
+		endpoints[i].StatusCode = fetchStatusCode(endpoints[i].URL, requestHeaders)
 		if i > 0 && i%10 == 0 {
 			log.Printf("[INFO] Status code progress: %d/%d", i, len(endpoints))
 		}
@@ -768,8 +769,13 @@ func storeDiscoveredEndpoints(endpoints []DiscoveredEndpoint) error {
 }
 
 func RunKatanaURLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "katana") {
+		return
+	}
+
 	var payload struct {
-		URL string `json:"url" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
 		http.Error(w, "Invalid request body. `url` is required.", http.StatusBadRequest)
@@ -787,6 +793,10 @@ func RunKatanaURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO katana_url_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -796,13 +806,14 @@ func RunKatanaURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("katana_url_scans", scanID, "katana")
+	go ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[INFO] Starting Katana URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -814,10 +825,7 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID string) {
 	}
 	log.Printf("[INFO] Target domain for filtering: %s", targetDomain)
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-katana-1",
-		"katana",
+	toolArgs := []string{
 		"-u", targetURL,
 		"-d", "5",
 		"-jc",
@@ -827,20 +835,17 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID string) {
 		"-p", "15",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
+	toolArgs = append(toolArgs, RequestHeaderArgs("katana", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	cmd, err := ToolExecutor("katana").Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] Katana URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateKatanaURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateKatanaURLScanStatus(scanID, "error", "", stderr.String(), cmd.Command, execTime)
 		return
 	}
 
@@ -861,17 +866,17 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID string) {
 
 	log.Printf("[INFO] Processing %d URLs (no filtering applied)", len(cleanURLs))
 
-	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "katana", scopeTargetID)
+	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "katana", scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to process URLs with parameters: %v", err)
-		UpdateKatanaURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateKatanaURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
 	err = storeDiscoveredEndpoints(endpoints)
 	if err != nil {
 		log.Printf("[ERROR] Failed to store discovered endpoints: %v", err)
-		UpdateKatanaURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateKatanaURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -890,7 +895,7 @@ func ExecuteAndParseKatanaURLScan(scanID, targetURL, scopeTargetID string) {
 	execTime = time.Since(startTime).String()
 	log.Printf("[INFO] Katana URL scan completed in %s for %s", execTime, targetURL)
 
-	UpdateKatanaURLScanStatus(scanID, "success", resultSummary, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateKatanaURLScanStatus(scanID, "success", resultSummary, "", cmd.Command, execTime)
 }
 
 func UpdateKatanaURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -995,8 +1000,13 @@ func GetKatanaURLScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunLinkFinderURLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "linkfinder") {
+		return
+	}
+
 	var payload struct {
-		URL string `json:"url" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
 		http.Error(w, "Invalid request body. `url` is required.", http.StatusBadRequest)
@@ -1014,6 +1024,10 @@ func RunLinkFinderURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO linkfinder_url_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -1023,13 +1037,14 @@ func RunLinkFinderURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("linkfinder_url_scans", scanID, "linkfinder")
+	go ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[INFO] Starting LinkFinder URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -1041,28 +1056,20 @@ func ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID string) {
 	}
 	log.Printf("[INFO] Target domain for filtering: %s", targetDomain)
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-linkfinder-1",
-		"python3", "linkfinder.py",
+	toolArgs := []string{
 		"-i", targetURL,
 		"-o", "cli",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
-
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	cmd, err := ToolExecutor("linkfinder").Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] LinkFinder URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateLinkFinderURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateLinkFinderURLScanStatus(scanID, "error", "", stderr.String(), cmd.Command, execTime)
 		return
 	}
 
@@ -1092,17 +1099,17 @@ func ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID string) {
 
 	log.Printf("[INFO] Processing %d URLs (no filtering applied)", len(cleanURLs))
 
-	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "linkfinder", scopeTargetID)
+	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "linkfinder", scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to process URLs with parameters: %v", err)
-		UpdateLinkFinderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateLinkFinderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
 	err = storeDiscoveredEndpoints(endpoints)
 	if err != nil {
 		log.Printf("[ERROR] Failed to store discovered endpoints: %v", err)
-		UpdateLinkFinderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateLinkFinderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -1121,7 +1128,7 @@ func ExecuteAndParseLinkFinderURLScan(scanID, targetURL, scopeTargetID string) {
 	execTime = time.Since(startTime).String()
 	log.Printf("[INFO] LinkFinder URL scan completed in %s for %s", execTime, targetURL)
 
-	UpdateLinkFinderURLScanStatus(scanID, "success", resultSummary, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateLinkFinderURLScanStatus(scanID, "success", resultSummary, "", cmd.Command, execTime)
 }
 
 func UpdateLinkFinderURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -1226,8 +1233,13 @@ func GetLinkFinderURLScansForScopeTarget(w http.ResponseWriter, r *http.Request)
 }
 
 func RunWaybackURLsScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "waybackurls") {
+		return
+	}
+
 	var payload struct {
-		URL string `json:"url" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
 		http.Error(w, "Invalid request body. `url` is required.", http.StatusBadRequest)
@@ -1245,6 +1257,10 @@ func RunWaybackURLsScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO waybackurls_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -1254,13 +1270,14 @@ func RunWaybackURLsScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("waybackurls_scans", scanID, "waybackurls")
+	go ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[INFO] Starting WaybackURLs scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -1272,27 +1289,19 @@ func ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID string) {
 	}
 	log.Printf("[INFO] Target domain for filtering: %s", targetDomain)
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-waybackurls-1",
-		"waybackurls",
+	toolArgs := []string{
 		targetURL,
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
-
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	cmd, err := ToolExecutor("waybackurls").Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] WaybackURLs scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateWaybackURLsScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateWaybackURLsScanStatus(scanID, "error", "", stderr.String(), cmd.Command, execTime)
 		return
 	}
 
@@ -1313,17 +1322,17 @@ func ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID string) {
 
 	log.Printf("[INFO] Processing %d URLs (no filtering applied)", len(cleanURLs))
 
-	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "waybackurls", scopeTargetID)
+	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "waybackurls", scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to process URLs with parameters: %v", err)
-		UpdateWaybackURLsScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateWaybackURLsScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
 	err = storeDiscoveredEndpoints(endpoints)
 	if err != nil {
 		log.Printf("[ERROR] Failed to store discovered endpoints: %v", err)
-		UpdateWaybackURLsScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateWaybackURLsScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -1342,7 +1351,7 @@ func ExecuteAndParseWaybackURLsScan(scanID, targetURL, scopeTargetID string) {
 	execTime = time.Since(startTime).String()
 	log.Printf("[INFO] WaybackURLs scan completed in %s for %s", execTime, targetURL)
 
-	UpdateWaybackURLsScanStatus(scanID, "success", resultSummary, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateWaybackURLsScanStatus(scanID, "success", resultSummary, "", cmd.Command, execTime)
 }
 
 func UpdateWaybackURLsScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -1447,8 +1456,13 @@ func GetWaybackURLsScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunGAUURLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "gau") {
+		return
+	}
+
 	var payload struct {
-		URL string `json:"url" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
 		http.Error(w, "Invalid request body. `url` is required.", http.StatusBadRequest)
@@ -1466,6 +1480,10 @@ func RunGAUURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO gau_url_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -1475,13 +1493,14 @@ func RunGAUURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("gau_url_scans", scanID, "gau")
+	go ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[INFO] Starting GAU URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -1496,29 +1515,22 @@ func ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID string) {
 	domain := strings.TrimPrefix(strings.TrimPrefix(targetURL, "https://"), "http://")
 	domain = strings.Split(domain, "/")[0]
 
-	dockerCmd := []string{
-		"docker", "run", "--rm",
-		"sxcurity/gau:latest",
+	toolArgs := []string{
 		domain,
 		"--providers", "wayback,commoncrawl,otx",
 		"--json",
 		"--threads", "10",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[INFO] Executing command: %s", strings.Join(dockerCmd, " "))
-
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	cmd, err := ToolExecutor("gau").Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[INFO] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[ERROR] GAU URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[ERROR] stderr output: %s", stderr.String())
-		UpdateGAUURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateGAUURLScanStatus(scanID, "error", "", stderr.String(), cmd.Command, execTime)
 		return
 	}
 
@@ -1546,17 +1558,17 @@ func ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID string) {
 
 	log.Printf("[INFO] Processing %d URLs (no filtering applied)", len(cleanURLs))
 
-	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "gau", scopeTargetID)
+	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "gau", scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[ERROR] Failed to process URLs with parameters: %v", err)
-		UpdateGAUURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateGAUURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
 	err = storeDiscoveredEndpoints(endpoints)
 	if err != nil {
 		log.Printf("[ERROR] Failed to store discovered endpoints: %v", err)
-		UpdateGAUURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateGAUURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -1575,7 +1587,7 @@ func ExecuteAndParseGAUURLScan(scanID, targetURL, scopeTargetID string) {
 	execTime = time.Since(startTime).String()
 	log.Printf("[INFO] GAU URL scan completed in %s for %s", execTime, targetURL)
 
-	UpdateGAUURLScanStatus(scanID, "success", resultSummary, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateGAUURLScanStatus(scanID, "success", resultSummary, "", cmd.Command, execTime)
 }
 
 func UpdateGAUURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -1798,9 +1810,14 @@ func GetDiscoveredEndpoints(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunFFUFURLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "ffuf") {
+		return
+	}
+
 	var payload struct {
-		URL           string `json:"url" binding:"required"`
-		ScopeTargetID string `json:"scope_target_id" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		ScopeTargetID    string `json:"scope_target_id" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" || payload.ScopeTargetID == "" {
 		http.Error(w, "Invalid request body. `url` and `scope_target_id` are required.", http.StatusBadRequest)
@@ -1819,6 +1836,10 @@ func RunFFUFURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO ffuf_url_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -1828,13 +1849,14 @@ func RunFFUFURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("ffuf_url_scans", scanID, "ffuf")
+	go ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[FFUF-URL] Starting FFUF URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -1925,14 +1947,12 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 		fuzzyURL = strings.TrimSuffix(fuzzyURL, "/") + "/FUZZ"
 	}
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-ffuf-1",
-		"ffuf",
+	ffufExecutor := ToolExecutor("ffuf")
+	toolArgs := []string{
 		"-w", wordlistPath,
 		"-u", fuzzyURL,
 		"-mc", matchCodes,
-		"-o", "/tmp/ffuf-output.json",
+		"-o", ffufExecutor.Path("/tmp/ffuf-output.json"),
 		"-of", "json",
 		"-ac",
 		"-c",
@@ -1942,24 +1962,21 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 	}
 
 	if stopOnAll {
-		dockerCmd = append(dockerCmd, "-sa")
+		toolArgs = append(toolArgs, "-sa")
 	} else {
 		if stopOn403 {
-			dockerCmd = append(dockerCmd, "-sf")
+			toolArgs = append(toolArgs, "-sf")
 		}
 		if stopOnErrors {
-			dockerCmd = append(dockerCmd, "-se")
+			toolArgs = append(toolArgs, "-se")
 		}
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[FFUF-URL] Executing command: %s", strings.Join(dockerCmd, " "))
+	toolArgs = append(toolArgs, RequestHeaderArgs("ffuf", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err = cmd.Run()
+	cmd, err := ffufExecutor.Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[FFUF-URL] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 	stderrOutput := stderr.String()
 
@@ -1976,7 +1993,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 			errorMsg = "SCAN STOPPED: " + stderrOutput
 		}
 		
-		UpdateFFUFURLScanStatus(scanID, "error", "", errorMsg, strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", errorMsg, cmd.Command, execTime)
 		return
 	}
 
@@ -1984,11 +2001,10 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 		log.Printf("[FFUF-URL] Warning: FFUF may have stopped early: %s", stderrOutput)
 	}
 
-	outputCmd := exec.Command("docker", "exec", "ars0n-framework-v2-ffuf-1", "cat", "/tmp/ffuf-output.json")
-	resultBytes, err := outputCmd.Output()
+	resultBytes, err := ReadToolFile(context.Background(), ffufExecutor, "/tmp/ffuf-output.json")
 	if err != nil {
 		log.Printf("[FFUF-URL] Failed to read FFUF results file: %v", err)
-		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to read results file", strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to read results file", cmd.Command, execTime)
 		return
 	}
 
@@ -2004,7 +2020,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 
 	if err := json.Unmarshal(resultBytes, &ffufOutput); err != nil {
 		log.Printf("[FFUF-URL] Failed to parse FFUF results JSON: %v", err)
-		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to parse results JSON", strings.Join(dockerCmd, " "), execTime)
+		UpdateFFUFURLScanStatus(scanID, "error", "", "Failed to parse results JSON", cmd.Command, execTime)
 		return
 	}
 
@@ -2028,7 +2044,7 @@ func ExecuteAndParseFFUFURLScan(scanID, targetURL, scopeTargetID string) {
 	log.Printf("[FFUF-URL] FFUF URL scan completed in %s for %s", execTime, targetURL)
 	log.Printf("[FFUF-URL] Found %d endpoints", len(endpoints))
 
-	UpdateFFUFURLScanStatus(scanID, "success", string(resultJSON), "", strings.Join(dockerCmd, " "), execTime)
+	UpdateFFUFURLScanStatus(scanID, "success", string(resultJSON), "", cmd.Command, execTime)
 }
 
 func UpdateFFUFURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
@@ -2122,8 +2138,13 @@ func GetFFUFURLScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunGoSpiderURLScan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "gospider") {
+		return
+	}
+
 	var payload struct {
-		URL string `json:"url" binding:"required"`
+		URL              string `json:"url" binding:"required"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.URL == "" {
 		http.Error(w, "Invalid request body. `url` is required.", http.StatusBadRequest)
@@ -2141,6 +2162,10 @@ func RunGoSpiderURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !CheckScanRequestProfile(w, scopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
 	scanID := uuid.New().String()
 	insertQuery := `INSERT INTO gospider_url_scans (scan_id, url, status, scope_target_id) VALUES ($1, $2, $3, $4)`
 	_, err = dbPool.Exec(context.Background(), insertQuery, scanID, targetURL, "pending", scopeTargetID)
@@ -2150,13 +2175,14 @@ func RunGoSpiderURLScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID)
+	RecordScanToolVersion("gospider_url_scans", scanID, "gospider")
+	go ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID, payload.RequestProfileID)
 
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
 }
 
-func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID string) {
+func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID, requestProfileID string) {
 	log.Printf("[GOSPIDER-URL] Starting GoSpider URL scan for %s (scan ID: %s)", targetURL, scanID)
 	startTime := time.Now()
 
@@ -2168,10 +2194,7 @@ func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID string) {
 	}
 	log.Printf("[GOSPIDER-URL] Target domain for filtering: %s", targetDomain)
 
-	dockerCmd := []string{
-		"docker", "exec",
-		"ars0n-framework-v2-gospider-1",
-		"gospider",
+	toolArgs := []string{
 		"-s", targetURL,
 		"-d", "5",
 		"-c", "10",
@@ -2183,20 +2206,17 @@ func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID string) {
 		"--json",
 	}
 
-	cmd := exec.Command(dockerCmd[0], dockerCmd[1:]...)
-	log.Printf("[GOSPIDER-URL] Executing command: %s", strings.Join(dockerCmd, " "))
+	toolArgs = append(toolArgs, RequestHeaderArgs("gospider", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	cmd, err := ToolExecutor("gospider").Run(context.Background(), ExecRequest{Args: toolArgs, Stdout: &stdout, Stderr: &stderr})
+	log.Printf("[GOSPIDER-URL] Executed command: %s", cmd.Command)
 	execTime := time.Since(startTime).String()
 
 	if err != nil {
 		log.Printf("[GOSPIDER-URL] GoSpider URL scan failed for %s: %v", targetURL, err)
 		log.Printf("[GOSPIDER-URL] stderr output: %s", stderr.String())
-		UpdateGoSpiderURLScanStatus(scanID, "error", "", stderr.String(), strings.Join(dockerCmd, " "), execTime)
+		UpdateGoSpiderURLScanStatus(scanID, "error", "", stderr.String(), cmd.Command, execTime)
 		return
 	}
 
@@ -2222,17 +2242,17 @@ func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID string) {
 
 	log.Printf("[GOSPIDER-URL] Processing %d URLs (no filtering applied)", len(cleanURLs))
 
-	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "gospider", scopeTargetID)
+	endpoints, err := processURLsWithParameters(cleanURLs, targetDomain, scanID, "gospider", scopeTargetID, requestProfileID)
 	if err != nil {
 		log.Printf("[GOSPIDER-URL] Failed to process URLs with parameters: %v", err)
-		UpdateGoSpiderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateGoSpiderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to process URLs: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
 	err = storeDiscoveredEndpoints(endpoints)
 	if err != nil {
 		log.Printf("[GOSPIDER-URL] Failed to store discovered endpoints: %v", err)
-		UpdateGoSpiderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), strings.Join(dockerCmd, " "), time.Since(startTime).String())
+		UpdateGoSpiderURLScanStatus(scanID, "error", "", fmt.Sprintf("Failed to store endpoints: %v", err), cmd.Command, time.Since(startTime).String())
 		return
 	}
 
@@ -2251,7 +2271,7 @@ func ExecuteAndParseGoSpiderURLScan(scanID, targetURL, scopeTargetID string) {
 	execTime = time.Since(startTime).String()
 	log.Printf("[GOSPIDER-URL] GoSpider URL scan completed in %s for %s", execTime, targetURL)
 
-	UpdateGoSpiderURLScanStatus(scanID, "success", resultSummary, "", strings.Join(dockerCmd, " "), execTime)
+	UpdateGoSpiderURLScanStatus(scanID, "success", resultSummary, "", cmd.Command, execTime)
 }
 
 func UpdateGoSpiderURLScanStatus(scanID, status, result, errorMsg, command, execTime string) {
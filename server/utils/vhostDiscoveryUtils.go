@@ -0,0 +1,468 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ars0n-framework-v2-server/domainutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultVhostMaxCandidates = 2000
+	defaultVhostConcurrency   = 20
+	vhostRequestTimeout       = 8 * time.Second
+	vhostBodyLimit            = 256 << 10
+)
+
+var vhostTitleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+type VhostScanConfig struct {
+	MaxCandidates int  `json:"max_candidates"`
+	Concurrency   int  `json:"concurrency"`
+	SkipSNI       bool `json:"skip_sni"`
+}
+
+type VhostDiscoveryResult struct {
+	IPAddress      string    `json:"ip_address"`
+	Port           int       `json:"port"`
+	Protocol       string    `json:"protocol"`
+	Hostname       string    `json:"hostname"`
+	URL            string    `json:"url"`
+	StatusCode     int       `json:"status_code"`
+	ContentLength  int       `json:"content_length"`
+	Title          string    `json:"title"`
+	BaselineStatus int       `json:"baseline_status"`
+	BaselineLength int       `json:"baseline_length"`
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+type vhostTarget struct {
+	ip       string
+	port     int
+	protocol string
+}
+
+// vhostResponse is the part of a response used to tell virtual hosts apart.
+type vhostResponse struct {
+	status   int
+	length   int
+	title    string
+	location string
+	bodyHash string
+	header   http.Header
+	// certNames and certSHA256 describe the leaf certificate on TLS connections
+	certNames  []string
+	certSHA256 string
+}
+
+func (t vhostTarget) address() string {
+	return net.JoinHostPort(t.ip, strconv.Itoa(t.port))
+}
+
+// vhostClient is for requests addressed to a target IP. On TLS it sends
+// serverName as SNI, so SNI and Host can name a candidate without DNS getting
+// involved. Requests follow the HTTP probe egress policy; a proxy is asked to
+// CONNECT to the IP, so it never resolves the candidate either.
+func vhostClient(serverName string) *http.Client {
+	return egressClient(EgressScopeHTTPProbe, &http.Client{
+		Timeout: vhostRequestTimeout,
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true, ServerName: serverName},
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	})
+}
+
+// probeVhost requests / from the target IP with Host set to host, and with
+// sni also presents host as the TLS server name.
+func probeVhost(target vhostTarget, host string, sni bool, headers []string) (vhostResponse, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s/", target.protocol, target.address()), nil)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+	applyRequestHeaders(req, headers)
+	if host != "" {
+		req.Host = host
+	}
+
+	serverName := ""
+	if sni && host != "" && target.protocol == "https" {
+		serverName = host
+	}
+	return fetchVhostResponse(vhostClient(serverName), req)
+}
+
+func fetchVhostResponse(client *http.Client, req *http.Request) (vhostResponse, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return vhostResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, vhostBodyLimit))
+
+	sum := sha256.Sum256(body)
+	res := vhostResponse{
+		status:   resp.StatusCode,
+		length:   len(body),
+		location: resp.Header.Get("Location"),
+		bodyHash: hex.EncodeToString(sum[:]),
+		header:   resp.Header,
+	}
+	if m := vhostTitleRegex.FindSubmatch(body); m != nil {
+		res.title = strings.TrimSpace(string(m[1]))
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		leaf := resp.TLS.PeerCertificates[0]
+		certSum := sha256.Sum256(leaf.Raw)
+		res.certSHA256 = hex.EncodeToString(certSum[:])
+		res.certNames = append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+	}
+	return res, nil
+}
+
+// vhostDiffers explains why candidate does not look like baseline, or returns "".
+func vhostDiffers(candidate, baseline vhostResponse) string {
+	switch {
+	case candidate.status != baseline.status:
+		return fmt.Sprintf("status %d vs %d", candidate.status, baseline.status)
+	case candidate.title != baseline.title:
+		return "title differs"
+	case candidate.location != baseline.location && candidate.location != "":
+		return "redirect differs"
+	}
+	diff := candidate.length - baseline.length
+	if diff < 0 {
+		diff = -diff
+	}
+	threshold := baseline.length / 10
+	if threshold < 100 {
+		threshold = 100
+	}
+	if diff > threshold {
+		return fmt.Sprintf("length %d vs %d", candidate.length, baseline.length)
+	}
+	return ""
+}
+
+func loadVhostTargets(scopeTargetID string) ([]vhostTarget, error) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT DISTINCT host(lws.ip_address), lws.port, lws.protocol
+		FROM live_web_servers lws
+		WHERE lws.scan_id = (
+			SELECT scan_id FROM ip_port_scans
+			WHERE scope_target_id = $1 AND status = 'success'
+			ORDER BY created_at DESC LIMIT 1
+		)`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []vhostTarget
+	for rows.Next() {
+		var t vhostTarget
+		if err := rows.Scan(&t.ip, &t.port, &t.protocol); err == nil && (t.protocol == "http" || t.protocol == "https") {
+			targets = append(targets, t)
+		}
+	}
+	return targets, rows.Err()
+}
+
+func loadVhostCandidates(scopeTargetID string, limit int) ([]string, error) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT subdomain FROM consolidated_subdomains WHERE scope_target_id = $1
+		UNION
+		SELECT domain FROM consolidated_company_domains WHERE scope_target_id = $1`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var candidates []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			continue
+		}
+		if name, err = domainutil.Normalize(name); err == nil && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	// Shorter names first: apex and first-level names are the most likely vhosts
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i]) != len(candidates[j]) {
+			return len(candidates[i]) < len(candidates[j])
+		}
+		return candidates[i] < candidates[j]
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, rows.Err()
+}
+
+func RunVhostDiscoveryScan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ScopeTargetID     string          `json:"scope_target_id"`
+		Config            VhostScanConfig `json:"config"`
+		AutoScanSessionID *string         `json:"auto_scan_session_id,omitempty"`
+		RequestProfileID  string          `json:"request_profile_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ScopeTargetID == "" {
+		http.Error(w, "Invalid request body. scope_target_id is required.", http.StatusBadRequest)
+		return
+	}
+	config := payload.Config
+	if config.MaxCandidates <= 0 {
+		config.MaxCandidates = defaultVhostMaxCandidates
+	}
+	if config.Concurrency <= 0 || config.Concurrency > 200 {
+		config.Concurrency = defaultVhostConcurrency
+	}
+
+	if !CheckScanRequestProfile(w, payload.ScopeTargetID, payload.RequestProfileID) {
+		return
+	}
+
+	scanID := uuid.New().String()
+	var autoScanSessionID interface{}
+	if payload.AutoScanSessionID != nil && *payload.AutoScanSessionID != "" {
+		autoScanSessionID = *payload.AutoScanSessionID
+	}
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO vhost_discovery_scans (scan_id, scope_target_id, status, auto_scan_session_id) VALUES ($1, $2, 'pending', $3)`,
+		scanID, payload.ScopeTargetID, autoScanSessionID)
+	if err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to create scan record: %v", err)
+		http.Error(w, "Failed to create scan record", http.StatusInternalServerError)
+		return
+	}
+
+	go ExecuteVhostDiscoveryScan(scanID, payload.ScopeTargetID, payload.RequestProfileID, config)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"scan_id": scanID})
+}
+
+func updateVhostScan(scanID, status, errorMessage string, targets, candidates, hits int, startTime time.Time) {
+	_, err := dbPool.Exec(context.Background(), `
+		UPDATE vhost_discovery_scans
+		SET status = $1, error_message = NULLIF($2, ''), total_targets = $3, total_candidates = $4,
+			hits_found = $5, execution_time = $6
+		WHERE scan_id = $7`,
+		status, errorMessage, targets, candidates, hits, time.Since(startTime).String(), scanID)
+	if err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to update scan %s: %v", scanID, err)
+	}
+}
+
+func ExecuteVhostDiscoveryScan(scanID, scopeTargetID, requestProfileID string, config VhostScanConfig) {
+	startTime := time.Now()
+	targets, err := loadVhostTargets(scopeTargetID)
+	if err != nil {
+		updateVhostScan(scanID, "error", fmt.Sprintf("Failed to load live web servers: %v", err), 0, 0, 0, startTime)
+		return
+	}
+	candidates, err := loadVhostCandidates(scopeTargetID, config.MaxCandidates)
+	if err != nil {
+		updateVhostScan(scanID, "error", fmt.Sprintf("Failed to load candidate hostnames: %v", err), len(targets), 0, 0, startTime)
+		return
+	}
+	if len(targets) == 0 || len(candidates) == 0 {
+		updateVhostScan(scanID, "error", "Need live web servers from an IP/Port scan and consolidated subdomains or company domains", len(targets), len(candidates), 0, startTime)
+		return
+	}
+	updateVhostScan(scanID, "running", "", len(targets), len(candidates), 0, startTime)
+	log.Printf("[VHOST] [INFO] Scan %s: %d candidates against %d IP:port targets", scanID, len(candidates), len(targets))
+
+	headers := RequestHeadersForScopeTarget(scopeTargetID, requestProfileID)
+	var hits int32
+	for _, target := range targets {
+		// Two baselines: the IP itself and a name the server cannot know. A real
+		// vhost has to differ from both.
+		noise := make([]byte, 6)
+		rand.Read(noise)
+		defaultResp, err := probeVhost(target, "", false, headers)
+		if err != nil {
+			log.Printf("[VHOST] [WARN] %s unreachable, skipping: %v", target.address(), err)
+			continue
+		}
+		randomResp, err := probeVhost(target, "ars0n-"+hex.EncodeToString(noise)+".invalid", false, headers)
+		if err != nil {
+			randomResp = defaultResp
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, config.Concurrency)
+		for _, candidate := range candidates {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(candidate string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				resp, err := probeVhost(target, candidate, !config.SkipSNI, headers)
+				if err != nil {
+					return
+				}
+				reason := vhostDiffers(resp, defaultResp)
+				if reason == "" || vhostDiffers(resp, randomResp) == "" {
+					return
+				}
+				atomic.AddInt32(&hits, 1)
+				storeVhostHit(scanID, scopeTargetID, target, candidate, resp, defaultResp, reason)
+			}(candidate)
+		}
+		wg.Wait()
+		updateVhostScan(scanID, "running", "", len(targets), len(candidates), int(hits), startTime)
+	}
+
+	if _, err := createVhostRelationships(scopeTargetID); err != nil {
+		log.Printf("[VHOST] [WARN] Failed to link vhosts into the attack surface: %v", err)
+	}
+	updateVhostScan(scanID, "success", "", len(targets), len(candidates), int(hits), startTime)
+	log.Printf("[VHOST] [INFO] Scan %s finished with %d hits in %s", scanID, hits, time.Since(startTime))
+}
+
+func storeVhostHit(scanID, scopeTargetID string, target vhostTarget, hostname string, resp, baseline vhostResponse, reason string) {
+	url := fmt.Sprintf("%s://%s", target.protocol, hostname)
+	if !(target.protocol == "http" && target.port == 80) && !(target.protocol == "https" && target.port == 443) {
+		url = fmt.Sprintf("%s:%d", url, target.port)
+	}
+	log.Printf("[VHOST] [INFO] %s serves %s (%s)", target.address(), hostname, reason)
+
+	_, err := dbPool.Exec(context.Background(), `
+		INSERT INTO vhost_discovery_results (
+			scan_id, scope_target_id, ip_address, port, protocol, hostname, url,
+			status_code, content_length, title, baseline_status, baseline_length, reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (scan_id, ip_address, port, hostname) DO NOTHING`,
+		scanID, scopeTargetID, target.ip, target.port, target.protocol, hostname, url,
+		resp.status, resp.length, resp.title, baseline.status, baseline.length, reason)
+	if err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to store hit %s on %s: %v", hostname, target.address(), err)
+	}
+
+	_, err = dbPool.Exec(context.Background(), `
+		INSERT INTO target_urls (url, scope_target_id, status_code, title, content_length, ip_address, newly_discovered, roi_score)
+		VALUES ($1, $2, $3, $4, $5, $6, true, 50)
+		ON CONFLICT (url, scope_target_id) DO UPDATE SET ip_address = COALESCE(target_urls.ip_address, EXCLUDED.ip_address)`,
+		url, scopeTargetID, resp.status, resp.title, resp.length, target.ip)
+	if err != nil {
+		log.Printf("[VHOST] [ERROR] Failed to add target URL %s: %v", url, err)
+	}
+}
+
+// createVhostRelationships links IP assets to the FQDNs found on them. Attack
+// surface consolidation rebuilds relationships from scratch, so it calls this too.
+func createVhostRelationships(scopeTargetID string) (int, error) {
+	result, err := dbPool.Exec(context.Background(), `
+		INSERT INTO consolidated_attack_surface_relationships (
+			parent_asset_id, child_asset_id, relationship_type, relationship_data
+		)
+		SELECT DISTINCT ON (ip.id, fqdn.id)
+			ip.id, fqdn.id, 'virtual_host',
+			jsonb_build_object('port', v.port, 'protocol', v.protocol, 'status_code', v.status_code, 'url', v.url)
+		FROM vhost_discovery_results v
+		JOIN consolidated_attack_surface_assets ip
+			ON ip.scope_target_id = v.scope_target_id AND ip.asset_type = 'ip_address' AND ip.ip_address = v.ip_address
+		JOIN consolidated_attack_surface_assets fqdn
+			ON fqdn.scope_target_id = v.scope_target_id AND fqdn.asset_type = 'fqdn' AND fqdn.fqdn = v.hostname
+		WHERE v.scope_target_id = $1::uuid
+		ORDER BY ip.id, fqdn.id, v.created_at DESC
+		ON CONFLICT (parent_asset_id, child_asset_id, relationship_type) DO NOTHING`, scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func GetVhostDiscoveryScansForScopeTarget(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT scan_id, status, total_targets, total_candidates, hits_found,
+			COALESCE(error_message, ''), COALESCE(execution_time, ''), created_at
+		FROM vhost_discovery_scans
+		WHERE scope_target_id = $1
+		ORDER BY created_at DESC`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get scans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	scans := []map[string]interface{}{}
+	for rows.Next() {
+		var scanID, status, errorMessage, execTime string
+		var targets, candidates, hits int
+		var createdAt time.Time
+		if err := rows.Scan(&scanID, &status, &targets, &candidates, &hits, &errorMessage, &execTime, &createdAt); err != nil {
+			continue
+		}
+		scans = append(scans, map[string]interface{}{
+			"scan_id":          scanID,
+			"status":           status,
+			"total_targets":    targets,
+			"total_candidates": candidates,
+			"hits_found":       hits,
+			"error_message":    errorMessage,
+			"execution_time":   execTime,
+			"created_at":       createdAt.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scans)
+}
+
+func GetVhostDiscoveryResults(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT ip_address, port, protocol, hostname, url, status_code, content_length,
+			COALESCE(title, ''), baseline_status, baseline_length, reason, created_at
+		FROM vhost_discovery_results
+		WHERE scan_id = $1
+		ORDER BY ip_address, port, hostname`, mux.Vars(r)["scan_id"])
+	if err != nil {
+		http.Error(w, "Failed to get results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []VhostDiscoveryResult{}
+	for rows.Next() {
+		var res VhostDiscoveryResult
+		if err := rows.Scan(&res.IPAddress, &res.Port, &res.Protocol, &res.Hostname, &res.URL, &res.StatusCode,
+			&res.ContentLength, &res.Title, &res.BaselineStatus, &res.BaselineLength, &res.Reason, &res.CreatedAt); err != nil {
+			continue
+		}
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// connectProxy is a CONNECT-only proxy that records the addresses asked for.
+type connectProxy struct {
+	mu      sync.Mutex
+	targets []string
+}
+
+func (p *connectProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, r.Host)
+	p.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	client, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+func TestProbeVhostThroughEgressProxy(t *testing.T) {
+	var mu sync.Mutex
+	var host, serverName string
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		host, serverName = r.Host, r.TLS.ServerName
+		mu.Unlock()
+		w.Write([]byte("<title>origin</title>"))
+	}))
+	defer origin.Close()
+
+	proxy := &connectProxy{}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	withEgressProxy(t, EgressScopeHTTPProbe, proxyServer.URL)
+
+	ip, portText, _ := net.SplitHostPort(origin.Listener.Addr().String())
+	port, _ := strconv.Atoi(portText)
+	target := vhostTarget{ip: ip, port: port, protocol: "https"}
+
+	tests := []struct {
+		name           string
+		host           string
+		sni            bool
+		wantHost       string
+		wantServerName string
+	}{
+		{"candidate with SNI", "app.example.com", true, "app.example.com", "app.example.com"},
+		{"candidate without SNI", "app.example.com", false, "app.example.com", ""},
+		{"bare IP", "", false, target.address(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := probeVhost(target, tt.host, tt.sni, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.status != http.StatusOK || resp.title != "origin" {
+				t.Errorf("got status %d title %q", resp.status, resp.title)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if host != tt.wantHost || serverName != tt.wantServerName {
+				t.Errorf("origin saw Host %q SNI %q, want %q and %q", host, serverName, tt.wantHost, tt.wantServerName)
+			}
+		})
+	}
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if len(proxy.targets) != len(tests) {
+		t.Fatalf("proxy saw %d CONNECTs, want %d", len(proxy.targets), len(tests))
+	}
+	for _, connected := range proxy.targets {
+		if connected != target.address() {
+			t.Errorf("proxy was asked to CONNECT to %q, want the IP %q", connected, target.address())
+		}
+	}
+}
@@ -0,0 +1,851 @@
+package utils
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ars0n-framework-v2-server/fingerprint"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+const cveImportBatchSize = 250
+
+func nvdFeedPath() string {
+	if path := os.Getenv("NVD_FEED_PATH"); path != "" {
+		return path
+	}
+	return "/app/feeds/nvd"
+}
+
+func kevFeedPath() string {
+	if path := os.Getenv("KEV_FEED_PATH"); path != "" {
+		return path
+	}
+	return "/app/feeds/known_exploited_vulnerabilities.json"
+}
+
+// cpeRange is one vulnerable cpeMatch entry: a product and the versions affected.
+type cpeRange struct {
+	vendor, product      string
+	version              string
+	startIncl, startExcl string
+	endIncl, endExcl     string
+}
+
+type cveRecord struct {
+	id          string
+	description string
+	score       float64
+	severity    string
+	published   string
+	modified    string
+	ranges      []cpeRange
+}
+
+// parseCPE splits a CPE 2.3 formatted string into vendor, product and version.
+func parseCPE(cpe string) (vendor, product, version string, ok bool) {
+	parts := strings.Split(cpe, ":")
+	if len(parts) < 6 || parts[0] != "cpe" || parts[1] != "2.3" {
+		return "", "", "", false
+	}
+	return parts[3], parts[4], parts[5], true
+}
+
+func newCPERange(criteria string, vulnerable bool, startIncl, startExcl, endIncl, endExcl string) (cpeRange, bool) {
+	if !vulnerable {
+		return cpeRange{}, false
+	}
+	vendor, product, version, ok := parseCPE(criteria)
+	if !ok {
+		return cpeRange{}, false
+	}
+	return cpeRange{
+		vendor: vendor, product: product, version: version,
+		startIncl: startIncl, startExcl: startExcl, endIncl: endIncl, endExcl: endExcl,
+	}, true
+}
+
+// NVD 1.1 feeds (nvdcve-1.1-*.json)
+type nvd11Node struct {
+	Children []nvd11Node `json:"children"`
+	CPEMatch []struct {
+		Vulnerable            bool   `json:"vulnerable"`
+		CPE23URI              string `json:"cpe23Uri"`
+		VersionStartIncluding string `json:"versionStartIncluding"`
+		VersionStartExcluding string `json:"versionStartExcluding"`
+		VersionEndIncluding   string `json:"versionEndIncluding"`
+		VersionEndExcluding   string `json:"versionEndExcluding"`
+	} `json:"cpe_match"`
+}
+
+type nvd11Feed struct {
+	CVEItems []struct {
+		CVE struct {
+			Meta struct {
+				ID string `json:"ID"`
+			} `json:"CVE_data_meta"`
+			Description struct {
+				Data []struct {
+					Lang  string `json:"lang"`
+					Value string `json:"value"`
+				} `json:"description_data"`
+			} `json:"description"`
+		} `json:"cve"`
+		Configurations struct {
+			Nodes []nvd11Node `json:"nodes"`
+		} `json:"configurations"`
+		Impact struct {
+			V3 struct {
+				CVSS struct {
+					BaseScore    float64 `json:"baseScore"`
+					BaseSeverity string  `json:"baseSeverity"`
+				} `json:"cvssV3"`
+			} `json:"baseMetricV3"`
+			V2 struct {
+				CVSS struct {
+					BaseScore float64 `json:"baseScore"`
+				} `json:"cvssV2"`
+				Severity string `json:"severity"`
+			} `json:"baseMetricV2"`
+		} `json:"impact"`
+		PublishedDate    string `json:"publishedDate"`
+		LastModifiedDate string `json:"lastModifiedDate"`
+	} `json:"CVE_Items"`
+}
+
+func (n nvd11Node) ranges() []cpeRange {
+	var out []cpeRange
+	for _, m := range n.CPEMatch {
+		if r, ok := newCPERange(m.CPE23URI, m.Vulnerable, m.VersionStartIncluding, m.VersionStartExcluding, m.VersionEndIncluding, m.VersionEndExcluding); ok {
+			out = append(out, r)
+		}
+	}
+	for _, child := range n.Children {
+		out = append(out, child.ranges()...)
+	}
+	return out
+}
+
+// NVD API 2.0 responses and the 2.0 JSON feeds
+type nvd2Metric struct {
+	CVSSData struct {
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"`
+}
+
+type nvd2Feed struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Published    string `json:"published"`
+			LastModified string `json:"lastModified"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			Metrics struct {
+				V31 []nvd2Metric `json:"cvssMetricV31"`
+				V30 []nvd2Metric `json:"cvssMetricV30"`
+				V2  []nvd2Metric `json:"cvssMetricV2"`
+			} `json:"metrics"`
+			Configurations []struct {
+				Nodes []struct {
+					CPEMatch []struct {
+						Vulnerable            bool   `json:"vulnerable"`
+						Criteria              string `json:"criteria"`
+						VersionStartIncluding string `json:"versionStartIncluding"`
+						VersionStartExcluding string `json:"versionStartExcluding"`
+						VersionEndIncluding   string `json:"versionEndIncluding"`
+						VersionEndExcluding   string `json:"versionEndExcluding"`
+					} `json:"cpeMatch"`
+				} `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// parseNVDFeed streams either NVD JSON layout into normalized records, decoding
+// the document once.
+func parseNVDFeed(r io.Reader) ([]cveRecord, error) {
+	var feed struct {
+		nvd11Feed
+		nvd2Feed
+	}
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var records []cveRecord
+	switch {
+	case feed.CVEItems != nil:
+		for _, item := range feed.CVEItems {
+			rec := cveRecord{id: item.CVE.Meta.ID, published: item.PublishedDate, modified: item.LastModifiedDate}
+			for _, d := range item.CVE.Description.Data {
+				if d.Lang == "en" {
+					rec.description = d.Value
+					break
+				}
+			}
+			if item.Impact.V3.CVSS.BaseScore > 0 {
+				rec.score, rec.severity = item.Impact.V3.CVSS.BaseScore, item.Impact.V3.CVSS.BaseSeverity
+			} else {
+				rec.score, rec.severity = item.Impact.V2.CVSS.BaseScore, item.Impact.V2.Severity
+			}
+			for _, node := range item.Configurations.Nodes {
+				rec.ranges = append(rec.ranges, node.ranges()...)
+			}
+			records = append(records, rec)
+		}
+	case feed.Vulnerabilities != nil:
+		for _, v := range feed.Vulnerabilities {
+			c := v.CVE
+			rec := cveRecord{id: c.ID, published: c.Published, modified: c.LastModified}
+			for _, d := range c.Descriptions {
+				if d.Lang == "en" {
+					rec.description = d.Value
+					break
+				}
+			}
+			for _, metrics := range [][]nvd2Metric{c.Metrics.V31, c.Metrics.V30, c.Metrics.V2} {
+				if len(metrics) > 0 {
+					rec.score = metrics[0].CVSSData.BaseScore
+					rec.severity = metrics[0].CVSSData.BaseSeverity
+					if rec.severity == "" {
+						rec.severity = metrics[0].BaseSeverity
+					}
+					break
+				}
+			}
+			for _, config := range c.Configurations {
+				for _, node := range config.Nodes {
+					for _, m := range node.CPEMatch {
+						if r, ok := newCPERange(m.Criteria, m.Vulnerable, m.VersionStartIncluding, m.VersionStartExcluding, m.VersionEndIncluding, m.VersionEndExcluding); ok {
+							rec.ranges = append(rec.ranges, r)
+						}
+					}
+				}
+			}
+			records = append(records, rec)
+		}
+	default:
+		return nil, fmt.Errorf("not an NVD JSON feed")
+	}
+	return records, nil
+}
+
+// decodeFeedFile streams a feed file, gunzipping .gz files, through decode.
+func decodeFeedFile(path string, decode func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return decode(r)
+}
+
+// nvdFeedFiles returns the feed itself or every .json/.json.gz file in a directory.
+func nvdFeedFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	for _, pattern := range []string{"*.json", "*.json.gz"} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func storeCVERecords(records []cveRecord) error {
+	for start := 0; start < len(records); start += cveImportBatchSize {
+		end := start + cveImportBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		batch := &pgx.Batch{}
+		for _, rec := range records[start:end] {
+			batch.Queue(`
+				INSERT INTO cve_entries (cve_id, description, cvss_score, severity, published_at, last_modified_at)
+				VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, '')::timestamp, NULLIF($6, '')::timestamp)
+				ON CONFLICT (cve_id) DO UPDATE SET
+					description = EXCLUDED.description, cvss_score = EXCLUDED.cvss_score, severity = EXCLUDED.severity,
+					published_at = EXCLUDED.published_at, last_modified_at = EXCLUDED.last_modified_at`,
+				rec.id, rec.description, rec.score, strings.ToUpper(rec.severity), rec.published, rec.modified)
+			batch.Queue(`DELETE FROM cve_cpe_matches WHERE cve_id = $1`, rec.id)
+			for _, r := range rec.ranges {
+				batch.Queue(`
+					INSERT INTO cve_cpe_matches (
+						cve_id, vendor, product, version, version_start_including, version_start_excluding,
+						version_end_including, version_end_excluding
+					) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))`,
+					rec.id, r.vendor, r.product, r.version, r.startIncl, r.startExcl, r.endIncl, r.endExcl)
+			}
+		}
+		if err := dbPool.SendBatch(context.Background(), batch).Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importNVDFeed(path string) (int, error) {
+	files, err := nvdFeedFiles(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("no .json or .json.gz feeds in %s", path)
+	}
+	total := 0
+	for _, file := range files {
+		var records []cveRecord
+		err := decodeFeedFile(file, func(r io.Reader) (err error) {
+			records, err = parseNVDFeed(r)
+			return err
+		})
+		if err != nil {
+			return total, fmt.Errorf("%s: %v", file, err)
+		}
+		if err := storeCVERecords(records); err != nil {
+			return total, fmt.Errorf("%s: %v", file, err)
+		}
+		total += len(records)
+		log.Printf("[VULN-FEED] [INFO] Imported %d CVEs from %s", len(records), file)
+	}
+	return total, nil
+}
+
+// importKEVFeed loads CISA's known_exploited_vulnerabilities.json.
+func importKEVFeed(path string) (int, error) {
+	var feed struct {
+		Vulnerabilities []struct {
+			CVEID            string `json:"cveID"`
+			VendorProject    string `json:"vendorProject"`
+			Product          string `json:"product"`
+			Name             string `json:"vulnerabilityName"`
+			DateAdded        string `json:"dateAdded"`
+			DueDate          string `json:"dueDate"`
+			RansomwareUse    string `json:"knownRansomwareCampaignUse"`
+			ShortDescription string `json:"shortDescription"`
+			RequiredAction   string `json:"requiredAction"`
+		} `json:"vulnerabilities"`
+	}
+	err := decodeFeedFile(path, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&feed)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(feed.Vulnerabilities) == 0 {
+		return 0, fmt.Errorf("not a CISA KEV feed")
+	}
+
+	batch := &pgx.Batch{}
+	for _, v := range feed.Vulnerabilities {
+		batch.Queue(`
+			INSERT INTO cisa_kev (cve_id, vendor_project, product, vulnerability_name, date_added, due_date,
+				known_ransomware_use, short_description, required_action)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')::date, NULLIF($6, '')::date, $7, $8, $9)
+			ON CONFLICT (cve_id) DO UPDATE SET
+				vendor_project = EXCLUDED.vendor_project, product = EXCLUDED.product,
+				vulnerability_name = EXCLUDED.vulnerability_name, date_added = EXCLUDED.date_added,
+				due_date = EXCLUDED.due_date, known_ransomware_use = EXCLUDED.known_ransomware_use,
+				short_description = EXCLUDED.short_description, required_action = EXCLUDED.required_action`,
+			v.CVEID, v.VendorProject, v.Product, v.Name, v.DateAdded, v.DueDate,
+			strings.EqualFold(v.RansomwareUse, "Known"), v.ShortDescription, v.RequiredAction)
+	}
+	if err := dbPool.SendBatch(context.Background(), batch).Close(); err != nil {
+		return 0, err
+	}
+	return len(feed.Vulnerabilities), nil
+}
+
+// ImportVulnerabilityFeeds loads the NVD and KEV feeds found at NVD_FEED_PATH and
+// KEV_FEED_PATH, or the feeds uploaded as the multipart files "nvd" and "kev".
+// Uploads take the place of the configured path for their feed type only.
+func ImportVulnerabilityFeeds(w http.ResponseWriter, r *http.Request) {
+	sources := map[string]string{"nvd": nvdFeedPath(), "kev": kevFeedPath()}
+	uploads := map[string]string{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(100 << 20); err != nil {
+			http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		for feedType := range sources {
+			path, err := saveUploadedFeed(r, feedType)
+			if err != nil {
+				for _, uploaded := range uploads {
+					os.Remove(uploaded)
+				}
+				http.Error(w, fmt.Sprintf("Failed to read uploaded %s feed", feedType), http.StatusBadRequest)
+				return
+			}
+			if path != "" {
+				uploads[feedType] = path
+			}
+		}
+		if len(uploads) == 0 {
+			http.Error(w, "Upload an nvd or kev feed file", http.StatusBadRequest)
+			return
+		}
+		sources = uploads
+	}
+
+	ids := map[string]string{}
+	for feedType, path := range sources {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		source := path
+		if _, uploaded := uploads[feedType]; uploaded {
+			source = "upload"
+		}
+		id := uuid.New().String()
+		_, err := dbPool.Exec(context.Background(), `
+			INSERT INTO cve_feed_imports (id, feed_type, source, status) VALUES ($1, $2, $3, 'running')`,
+			id, feedType, source)
+		if err != nil {
+			log.Printf("[VULN-FEED] [ERROR] Failed to record import: %v", err)
+			for _, uploaded := range uploads {
+				os.Remove(uploaded)
+			}
+			http.Error(w, "Failed to start import", http.StatusInternalServerError)
+			return
+		}
+		ids[feedType] = id
+		go func(feedType, path string, uploaded bool) {
+			runFeedImport(id, feedType, path)
+			if uploaded {
+				os.Remove(path)
+			}
+		}(feedType, path, source == "upload")
+	}
+	if len(ids) == 0 {
+		http.Error(w, fmt.Sprintf("No feeds found at %s or %s", nvdFeedPath(), kevFeedPath()), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"imports": ids})
+}
+
+// saveUploadedFeed copies an uploaded feed to a temporary file that outlives the
+// request, keeping the .gz suffix so it is decompressed. It returns "" when the
+// form has no file for the feed type.
+func saveUploadedFeed(r *http.Request, feedType string) (string, error) {
+	file, header, err := r.FormFile(feedType)
+	if err == http.ErrMissingFile {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	suffix := ".json"
+	if strings.HasSuffix(strings.ToLower(header.Filename), ".gz") {
+		suffix = ".json.gz"
+	}
+	tempFile, err := os.CreateTemp("", "vuln-feed-"+feedType+"-*"+suffix)
+	if err != nil {
+		return "", err
+	}
+	defer tempFile.Close()
+	if _, err := io.Copy(tempFile, file); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+	return tempFile.Name(), nil
+}
+
+func runFeedImport(id, feedType, path string) {
+	startTime := time.Now()
+	var count int
+	var err error
+	if feedType == "kev" {
+		count, err = importKEVFeed(path)
+	} else {
+		count, err = importNVDFeed(path)
+	}
+	status, errorMessage := "success", ""
+	if err != nil {
+		status, errorMessage = "error", err.Error()
+		log.Printf("[VULN-FEED] [ERROR] %s import from %s failed: %v", feedType, path, err)
+	} else {
+		log.Printf("[VULN-FEED] [INFO] %s import from %s loaded %d entries in %s", feedType, path, count, time.Since(startTime))
+	}
+	_, dbErr := dbPool.Exec(context.Background(), `
+		UPDATE cve_feed_imports SET status = $1, entries = $2, error_message = NULLIF($3, ''), finished_at = NOW()
+		WHERE id = $4`, status, count, errorMessage, id)
+	if dbErr != nil {
+		log.Printf("[VULN-FEED] [ERROR] Failed to update import %s: %v", id, dbErr)
+	}
+}
+
+func GetVulnerabilityFeedImports(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT id, feed_type, source, status, entries, COALESCE(error_message, ''), started_at, finished_at
+		FROM cve_feed_imports ORDER BY started_at DESC LIMIT 50`)
+	if err != nil {
+		http.Error(w, "Failed to get feed imports", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	imports := []map[string]interface{}{}
+	for rows.Next() {
+		var id, feedType, source, status, errorMessage string
+		var entries int
+		var startedAt time.Time
+		var finishedAt *time.Time
+		if err := rows.Scan(&id, &feedType, &source, &status, &entries, &errorMessage, &startedAt, &finishedAt); err != nil {
+			continue
+		}
+		imports = append(imports, map[string]interface{}{
+			"id":            id,
+			"feed_type":     feedType,
+			"source":        source,
+			"status":        status,
+			"entries":       entries,
+			"error_message": errorMessage,
+			"started_at":    startedAt.Format(time.RFC3339),
+			"finished_at":   finishedAt,
+		})
+	}
+
+	var cves, kev int
+	dbPool.QueryRow(context.Background(), `SELECT (SELECT COUNT(*) FROM cve_entries), (SELECT COUNT(*) FROM cisa_kev)`).Scan(&cves, &kev)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cve_count": cves,
+		"kev_count": kev,
+		"imports":   imports,
+	})
+}
+
+var versionTokenRegex = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+
+// compareVersions orders dotted versions numerically segment by segment, so
+// 1.10.0 sorts after 1.9.2. Alphabetic segments compare as strings.
+func compareVersions(a, b string) int {
+	ta := versionTokenRegex.FindAllString(strings.ToLower(a), -1)
+	tb := versionTokenRegex.FindAllString(strings.ToLower(b), -1)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		if i >= len(ta) {
+			return -1
+		}
+		if i >= len(tb) {
+			return 1
+		}
+		na, errA := strconv.Atoi(ta[i])
+		nb, errB := strconv.Atoi(tb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case ta[i] != tb[i]:
+			if ta[i] < tb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionAffected checks a detected version against one cpeMatch entry. Entries
+// with neither an exact version nor a range would flag every version and are
+// skipped.
+func versionAffected(version, exact, startIncl, startExcl, endIncl, endExcl string) bool {
+	if exact != "*" && exact != "-" && exact != "" {
+		return compareVersions(version, exact) == 0
+	}
+	if startIncl == "" && startExcl == "" && endIncl == "" && endExcl == "" {
+		return false
+	}
+	if startIncl != "" && compareVersions(version, startIncl) < 0 {
+		return false
+	}
+	if startExcl != "" && compareVersions(version, startExcl) <= 0 {
+		return false
+	}
+	if endIncl != "" && compareVersions(version, endIncl) > 0 {
+		return false
+	}
+	if endExcl != "" && compareVersions(version, endExcl) >= 0 {
+		return false
+	}
+	return true
+}
+
+// techVersionRegex splits strings like "nginx/1.18.0", "PHP:7.4.3" or
+// "Apache 2.4.41" into a name and a version.
+var techVersionRegex = regexp.MustCompile(`^\s*([A-Za-z][\w .+-]*?)\s*[:/ ]\s*v?(\d+(?:\.[\w-]+)*)`)
+
+// serverNameAliases maps common Server header names onto rule names.
+var serverNameAliases = map[string]string{
+	"apache":        "Apache HTTP Server",
+	"microsoft-iis": "Microsoft IIS",
+	"iis":           "Microsoft IIS",
+	"tomcat":        "Apache Tomcat",
+	"apache-coyote": "Apache Tomcat",
+	"litespeed":     "LiteSpeed",
+	"express":       "Express",
+}
+
+type techAsset struct {
+	kind, id, label string
+	tech, version   string
+	vendor, product string
+}
+
+// techAssetFromString resolves a "name/version" string to a CPE product.
+func techAssetFromString(kind, id, label, raw string) (techAsset, bool) {
+	m := techVersionRegex.FindStringSubmatch(raw)
+	if m == nil {
+		return techAsset{}, false
+	}
+	name := m[1]
+	if alias, ok := serverNameAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	cpe, canonical := fingerprint.Current().CPE(name)
+	vendor, product, _, ok := parseCPE(cpe)
+	if !ok {
+		return techAsset{}, false
+	}
+	return techAsset{kind: kind, id: id, label: label, tech: canonical, version: m[2], vendor: vendor, product: product}, true
+}
+
+func loadTechAssets(scopeTargetID string) ([]techAsset, error) {
+	var assets []techAsset
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT target_url_id, url, name, version, cpe
+		FROM technology_detections
+		WHERE scope_target_id = $1 AND version IS NOT NULL AND cpe IS NOT NULL`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a techAsset
+		var cpe string
+		if rows.Scan(&a.id, &a.label, &a.tech, &a.version, &cpe) != nil {
+			continue
+		}
+		var ok bool
+		if a.vendor, a.product, _, ok = parseCPE(cpe); ok {
+			a.kind = "target_url"
+			assets = append(assets, a)
+		}
+	}
+	rows.Close()
+
+	rows, err = dbPool.Query(context.Background(), `
+		SELECT 'target_url', id, url, COALESCE(web_server, ''), COALESCE(technologies, '{}')
+		FROM target_urls WHERE scope_target_id = $1
+		UNION ALL
+		SELECT 'attack_surface_asset', id, COALESCE(url, fqdn, ip_address, ''), COALESCE(web_server, ''), COALESCE(technologies, '{}')
+		FROM consolidated_attack_surface_assets WHERE scope_target_id = $1 AND asset_type = 'live_web_server'`, scopeTargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, id, label, webServer string
+		var technologies []string
+		if rows.Scan(&kind, &id, &label, &webServer, &technologies) != nil {
+			continue
+		}
+		for _, raw := range append([]string{webServer}, technologies...) {
+			if a, ok := techAssetFromString(kind, id, label, raw); ok {
+				assets = append(assets, a)
+			}
+		}
+	}
+	return assets, rows.Err()
+}
+
+// MatchScopeTargetVulnerabilities matches every detected technology version of a
+// scope target against the imported CVE data and replaces its stored matches.
+func MatchScopeTargetVulnerabilities(scopeTargetID string) (int, error) {
+	assets, err := loadTechAssets(scopeTargetID)
+	if err != nil {
+		return 0, err
+	}
+
+	type product struct{ vendor, product string }
+	type rangeRow struct {
+		cveID                                string
+		exact, startIncl, startExcl, endIncl string
+		endExcl                              string
+	}
+	cache := make(map[product][]rangeRow)
+	ctx := context.Background()
+	tx, err := dbPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM technology_vulnerabilities WHERE scope_target_id = $1`, scopeTargetID); err != nil {
+		return 0, err
+	}
+
+	matches := 0
+	seen := make(map[string]bool)
+	for _, a := range assets {
+		key := product{a.vendor, a.product}
+		ranges, ok := cache[key]
+		if !ok {
+			rows, err := dbPool.Query(ctx, `
+				SELECT cve_id, version, COALESCE(version_start_including, ''), COALESCE(version_start_excluding, ''),
+					COALESCE(version_end_including, ''), COALESCE(version_end_excluding, '')
+				FROM cve_cpe_matches WHERE vendor = $1 AND product = $2`, a.vendor, a.product)
+			if err != nil {
+				return matches, err
+			}
+			for rows.Next() {
+				var r rangeRow
+				if rows.Scan(&r.cveID, &r.exact, &r.startIncl, &r.startExcl, &r.endIncl, &r.endExcl) == nil {
+					ranges = append(ranges, r)
+				}
+			}
+			rows.Close()
+			cache[key] = ranges
+		}
+
+		for _, r := range ranges {
+			dedupe := strings.Join([]string{a.kind, a.id, a.tech, r.cveID}, "|")
+			if seen[dedupe] || !versionAffected(a.version, r.exact, r.startIncl, r.startExcl, r.endIncl, r.endExcl) {
+				continue
+			}
+			seen[dedupe] = true
+			_, err := tx.Exec(ctx, `
+				INSERT INTO technology_vulnerabilities (scope_target_id, asset_kind, asset_id, asset_label, technology, version, cve_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (asset_kind, asset_id, technology, cve_id) DO NOTHING`,
+				scopeTargetID, a.kind, a.id, a.label, a.tech, a.version, r.cveID)
+			if err != nil {
+				return matches, err
+			}
+			matches++
+		}
+	}
+	return matches, tx.Commit(ctx)
+}
+
+func RunVulnerabilityMatch(w http.ResponseWriter, r *http.Request) {
+	scopeTargetID := mux.Vars(r)["id"]
+	startTime := time.Now()
+	matches, err := MatchScopeTargetVulnerabilities(scopeTargetID)
+	if err != nil {
+		log.Printf("[VULN-FEED] [ERROR] Matching failed for scope target %s: %v", scopeTargetID, err)
+		http.Error(w, "Failed to match vulnerabilities", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[VULN-FEED] [INFO] %d technology/CVE matches for scope target %s in %s", matches, scopeTargetID, time.Since(startTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"matches":        matches,
+		"execution_time": time.Since(startTime).String(),
+	})
+}
+
+// GetScopeTargetVulnerabilities lists matched CVEs, each with the assets that may
+// be affected. KEV entries come first, then by CVSS score.
+func GetScopeTargetVulnerabilities(w http.ResponseWriter, r *http.Request) {
+	rows, err := dbPool.Query(context.Background(), `
+		SELECT tv.cve_id, COALESCE(c.description, ''), COALESCE(c.cvss_score, 0), COALESCE(c.severity, ''),
+			k.cve_id IS NOT NULL, COALESCE(k.vulnerability_name, ''), k.date_added, COALESCE(k.known_ransomware_use, false),
+			tv.asset_kind, tv.asset_id, tv.asset_label, tv.technology, tv.version
+		FROM technology_vulnerabilities tv
+		LEFT JOIN cve_entries c ON c.cve_id = tv.cve_id
+		LEFT JOIN cisa_kev k ON k.cve_id = tv.cve_id
+		WHERE tv.scope_target_id = $1
+		ORDER BY (k.cve_id IS NOT NULL) DESC, c.cvss_score DESC NULLS LAST, tv.cve_id, tv.asset_label`, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to get vulnerabilities", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type affectedAsset struct {
+		Kind       string `json:"kind"`
+		ID         string `json:"id"`
+		Label      string `json:"label"`
+		Technology string `json:"technology"`
+		Version    string `json:"version"`
+	}
+	type cveSummary struct {
+		CVEID         string          `json:"cve_id"`
+		Description   string          `json:"description"`
+		CVSSScore     float64         `json:"cvss_score"`
+		Severity      string          `json:"severity"`
+		IsKEV         bool            `json:"is_kev"`
+		KEVName       string          `json:"kev_name,omitempty"`
+		KEVDateAdded  *time.Time      `json:"kev_date_added,omitempty"`
+		RansomwareUse bool            `json:"known_ransomware_use"`
+		Assets        []affectedAsset `json:"assets"`
+	}
+	var order []string
+	byCVE := make(map[string]*cveSummary)
+	for rows.Next() {
+		var s cveSummary
+		var a affectedAsset
+		if err := rows.Scan(&s.CVEID, &s.Description, &s.CVSSScore, &s.Severity, &s.IsKEV, &s.KEVName, &s.KEVDateAdded,
+			&s.RansomwareUse, &a.Kind, &a.ID, &a.Label, &a.Technology, &a.Version); err != nil {
+			continue
+		}
+		existing, ok := byCVE[s.CVEID]
+		if !ok {
+			existing = &s
+			byCVE[s.CVEID] = existing
+			order = append(order, s.CVEID)
+		}
+		existing.Assets = append(existing.Assets, a)
+	}
+
+	results := make([]*cveSummary, 0, len(order))
+	for _, id := range order {
+		results = append(results, byCVE[id])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -89,14 +87,22 @@ func GetX8Config(w http.ResponseWriter, r *http.Request) {
 }
 
 func RunX8Scan(w http.ResponseWriter, r *http.Request) {
+	if !RequireTools(w, "x8") {
+		return
+	}
+
 	var req struct {
-		ScopeTargetID string `json:"scope_target_id"`
+		ScopeTargetID    string `json:"scope_target_id"`
+		RequestProfileID string `json:"request_profile_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !CheckScanRequestProfile(w, req.ScopeTargetID, req.RequestProfileID) {
+		return
+	}
 
 	scanID := uuid.New().String()
 
@@ -110,7 +116,8 @@ func RunX8Scan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go ExecuteX8Scan(scanID, req.ScopeTargetID)
+	RecordScanToolVersion("x8_scans", scanID, "x8")
+	go ExecuteX8Scan(scanID, req.ScopeTargetID, req.RequestProfileID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -119,7 +126,7 @@ func RunX8Scan(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func ExecuteX8Scan(scanID, scopeTargetID string) {
+func ExecuteX8Scan(scanID, scopeTargetID, requestProfileID string) {
 	startTime := time.Now()
 
 	UpdateX8ScanStatus(scanID, "running", "", "")
@@ -174,20 +181,22 @@ func ExecuteX8Scan(scanID, scopeTargetID string) {
 	updateTotalQuery := `UPDATE x8_scans SET total_endpoints = $1 WHERE scan_id = $2`
 	dbPool.Exec(context.Background(), updateTotalQuery, len(endpoints), scanID)
 
+	ctx := context.Background()
+	executor := ToolExecutor("x8")
+
 	urlsFile := fmt.Sprintf("/tmp/x8_urls_%s.txt", scanID)
 	urlsContent := strings.Join(endpoints, "\n")
-	if err := os.WriteFile(urlsFile, []byte(urlsContent), 0644); err != nil {
+	if err := WriteToolFile(ctx, executor, urlsFile, []byte(urlsContent)); err != nil {
 		UpdateX8ScanStatus(scanID, "error", "", fmt.Sprintf("Failed to create URLs file: %v", err))
 		return
 	}
-	defer os.Remove(urlsFile)
 
 	outputFile := fmt.Sprintf("/tmp/x8_output_%s.txt", scanID)
-	defer os.Remove(outputFile)
+	defer executor.Exec(ctx, ExecRequest{Args: []string{"rm", "-f", executor.Path(urlsFile), executor.Path(outputFile)}})
 
 	args := []string{
-		"-u", urlsFile,
-		"-o", outputFile,
+		"-u", executor.Path(urlsFile),
+		"-o", executor.Path(outputFile),
 		"--workers", fmt.Sprintf("%d", config.Concurrency),
 		"--learn-requests-count", fmt.Sprintf("%d", config.LearnRequests),
 		"--verify-requests-count", fmt.Sprintf("%d", config.VerifyRequests),
@@ -237,17 +246,16 @@ func ExecuteX8Scan(scanID, scopeTargetID string) {
 			}
 		}
 	}
+	args = append(args, RequestHeaderArgs("x8", RequestHeadersForScopeTarget(scopeTargetID, requestProfileID))...)
 
-	cmdStr := fmt.Sprintf("docker exec ars0n-framework-v2-x8-1 x8 %s", strings.Join(args, " "))
-
-	cmd := exec.Command("docker", append([]string{"exec", "ars0n-framework-v2-x8-1", "x8"}, args...)...)
-	output, err := cmd.CombinedOutput()
+	execResult, err := executor.Run(ctx, ExecRequest{Args: args})
+	cmdStr := execResult.Command
+	output := execResult.Stdout + execResult.Stderr
 
 	executionTime := time.Since(startTime).String()
 
 	parametersFound := 0
-	if _, err := os.Stat(outputFile); err == nil {
-		outputData, _ := os.ReadFile(outputFile)
+	if outputData, readErr := ReadToolFile(ctx, executor, outputFile); readErr == nil {
 		if len(outputData) > 0 {
 			lines := strings.Split(string(outputData), "\n")
 			for _, line := range lines {
@@ -287,7 +295,7 @@ func ExecuteX8Scan(scanID, scopeTargetID string) {
 	}
 
 	if err != nil {
-		UpdateX8ScanStatus(scanID, "error", string(output), fmt.Sprintf("x8 execution failed: %v", err))
+		UpdateX8ScanStatus(scanID, "error", output, fmt.Sprintf("x8 execution failed: %v", err))
 		return
 	}
 
@@ -297,7 +305,7 @@ func ExecuteX8Scan(scanID, scopeTargetID string) {
 		    execution_time = $3, command = $4, stdout = $5
 		WHERE scan_id = $6
 	`
-	dbPool.Exec(context.Background(), updateQuery, len(endpoints), parametersFound, executionTime, cmdStr, string(output), scanID)
+	dbPool.Exec(context.Background(), updateQuery, len(endpoints), parametersFound, executionTime, cmdStr, output, scanID)
 }
 
 func UpdateX8ScanStatus(scanID, status, stdout, errorMsg string) {